@@ -0,0 +1,220 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"model-test/models"
+)
+
+const (
+	// minDiscriminationModels is the fewest models a discrimination index is computed from
+	minDiscriminationModels = 3
+
+	// lowDiscrimination is the discrimination index below which a test barely separates
+	// strong models from weak ones
+	lowDiscrimination = 0.2
+)
+
+// ItemFlag marks a test case as a candidate for suite curation
+type ItemFlag string
+
+const (
+	ItemAlwaysPassed           ItemFlag = "always_passed"           // Every model passed every run
+	ItemNeverPassed            ItemFlag = "never_passed"            // No model passed any run
+	ItemLowDiscrimination      ItemFlag = "low_discrimination"      // Passing it says little about overall ability
+	ItemNegativeDiscrimination ItemFlag = "negative_discrimination" // Weaker models pass it more often than stronger ones
+)
+
+// DifficultyCalibration holds the item statistics of every test case across all models
+type DifficultyCalibration struct {
+	Models        int              `json:"models"`
+	Items         []ItemStatistics `json:"items"`
+	Uninformative []string         `json:"uninformative,omitempty"` // Test cases every model passed, or none did
+}
+
+// ItemStatistics describes how hard a test case is and how well it separates models
+type ItemStatistics struct {
+	TestCase string  `json:"test_case"`
+	Models   int     `json:"models"`
+	Runs     int     `json:"runs"`
+	Passed   int     `json:"passed"`
+	PassRate float64 `json:"pass_rate"` // Item difficulty: the share of all runs that passed
+
+	// Correlation across models between their pass rate on this test and on the rest of
+	// the suite; nil with fewer than 3 models or when either does not vary
+	Discrimination *float64 `json:"discrimination,omitempty"`
+	Flag           ItemFlag `json:"flag,omitempty"`
+}
+
+// calculateDifficultyCalibration computes, for every test case, its pass rate over all
+// models and runs and its corrected item-total discrimination index across models
+func calculateDifficultyCalibration(outcomes *outcomeMatrix) DifficultyCalibration {
+	byModel, testCases := outcomes.byModel, outcomes.testCases
+	totals := make(map[string]*outcomeTally)
+	for modelName, items := range byModel {
+		totals[modelName] = &outcomeTally{}
+		for _, item := range items {
+			totals[modelName].runs += item.runs
+			totals[modelName].passed += item.passed
+		}
+	}
+
+	calibration := DifficultyCalibration{Models: len(byModel)}
+	for name := range testCases {
+		stats := ItemStatistics{TestCase: name}
+		var itemRates, restRates []float64
+		for modelName, items := range byModel {
+			item := items[name]
+			if item == nil {
+				continue
+			}
+			stats.Models++
+			stats.Runs += item.runs
+			stats.Passed += item.passed
+
+			// The rest score leaves the item out so it does not correlate with itself
+			rest := outcomeTally{runs: totals[modelName].runs - item.runs, passed: totals[modelName].passed - item.passed}
+			if rest.runs > 0 {
+				itemRates = append(itemRates, float64(item.passed)/float64(item.runs))
+				restRates = append(restRates, float64(rest.passed)/float64(rest.runs))
+			}
+		}
+		stats.PassRate = float64(stats.Passed) / float64(stats.Runs)
+		if len(itemRates) >= minDiscriminationModels {
+			stats.Discrimination = correlation(itemRates, restRates)
+		}
+
+		switch {
+		case stats.Passed == stats.Runs:
+			stats.Flag = ItemAlwaysPassed
+		case stats.Passed == 0:
+			stats.Flag = ItemNeverPassed
+		case stats.Discrimination != nil && *stats.Discrimination < 0:
+			stats.Flag = ItemNegativeDiscrimination
+		case stats.Discrimination != nil && *stats.Discrimination < lowDiscrimination:
+			stats.Flag = ItemLowDiscrimination
+		}
+		if stats.Flag == ItemAlwaysPassed || stats.Flag == ItemNeverPassed {
+			calibration.Uninformative = append(calibration.Uninformative, name)
+		}
+		calibration.Items = append(calibration.Items, stats)
+	}
+
+	sort.Slice(calibration.Items, func(i, j int) bool {
+		return calibration.Items[i].TestCase < calibration.Items[j].TestCase
+	})
+	sort.Strings(calibration.Uninformative)
+
+	return calibration
+}
+
+// outcomeTally counts the runs of a test case, or of a whole suite, and how many passed
+type outcomeTally struct{ runs, passed int }
+
+// outcomeMatrix tallies every model's runs of every test case by model and test case,
+// along with the set of test cases, leaving out tests that failed against the server
+// since they never reached the model
+type outcomeMatrix struct {
+	byModel   map[string]map[string]*outcomeTally
+	testCases map[string]bool
+}
+
+// newOutcomeMatrix returns an empty outcome matrix
+func newOutcomeMatrix() *outcomeMatrix {
+	return &outcomeMatrix{byModel: make(map[string]map[string]*outcomeTally), testCases: make(map[string]bool)}
+}
+
+// add tallies the results of one of a model's result files
+func (m *outcomeMatrix) add(modelName string, results []models.AgentTestResult) {
+	for _, result := range results {
+		if isInfrastructureFailure(result) {
+			continue
+		}
+		if m.byModel[modelName] == nil {
+			m.byModel[modelName] = make(map[string]*outcomeTally)
+		}
+		item := m.byModel[modelName][result.TestCase.Name]
+		if item == nil {
+			item = &outcomeTally{}
+			m.byModel[modelName][result.TestCase.Name] = item
+		}
+		item.runs++
+		if result.Success {
+			item.passed++
+		}
+		m.testCases[result.TestCase.Name] = true
+	}
+}
+
+// correlation returns the Pearson correlation of two samples, or nil when either is constant
+func correlation(x, y []float64) *float64 {
+	n := float64(len(x))
+	var meanX, meanY float64
+	for i := range x {
+		meanX += x[i] / n
+		meanY += y[i] / n
+	}
+
+	var covariance, varianceX, varianceY float64
+	for i := range x {
+		dx, dy := x[i]-meanX, y[i]-meanY
+		covariance += dx * dy
+		varianceX += dx * dx
+		varianceY += dy * dy
+	}
+	if varianceX == 0 || varianceY == 0 {
+		return nil
+	}
+
+	r := covariance / math.Sqrt(varianceX*varianceY)
+	return &r
+}
+
+// generateCalibrationSection lists each test case's pass rate (p) and discrimination
+// index (D) and flags the tests worth revisiting
+func generateCalibrationSection(calibration DifficultyCalibration) string {
+	var sb strings.Builder
+
+	header := fmt.Sprintf("Test Case Difficulty (%d models):", calibration.Models)
+	sb.WriteString(header + "\n")
+	sb.WriteString(strings.Repeat("-", len(header)) + "\n")
+
+	flagged := 0
+	for _, item := range calibration.Items {
+		discrimination := "n/a"
+		if item.Discrimination != nil {
+			discrimination = fmt.Sprintf("%.2f", *item.Discrimination)
+		}
+		marker := ""
+		switch item.Flag {
+		case ItemAlwaysPassed:
+			marker = "  <- every model passed"
+		case ItemNeverPassed:
+			marker = "  <- no model passed"
+		case ItemNegativeDiscrimination:
+			marker = "  <- weaker models pass more often"
+		case ItemLowDiscrimination:
+			marker = "  <- low discrimination"
+		}
+		if marker != "" {
+			flagged++
+		}
+		sb.WriteString(fmt.Sprintf("%s: p=%.2f D=%s (%d/%d runs, %d models)%s\n",
+			item.TestCase, item.PassRate, discrimination, item.Passed, item.Runs, item.Models, marker))
+	}
+
+	if len(calibration.Uninformative) > 0 {
+		sb.WriteString(fmt.Sprintf("Uninformative: %d of %d test cases give every model the same outcome\n",
+			len(calibration.Uninformative), len(calibration.Items)))
+	}
+	if flagged > len(calibration.Uninformative) {
+		sb.WriteString(fmt.Sprintf("Weak discrimination: %d test cases (D < %.1f)\n",
+			flagged-len(calibration.Uninformative), lowDiscrimination))
+	}
+	sb.WriteString("\n")
+
+	return sb.String()
+}
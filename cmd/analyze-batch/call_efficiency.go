@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// generateCallEfficiencySection compares how many tool calls the models made against
+// the fewest their tests needed, most efficient first by the ratio on passed tests,
+// so models that pass by brute-forcing extra calls stand out
+func generateCallEfficiencySection(analyses []ModelAnalysis) string {
+	var measured []ModelAnalysis
+	for _, model := range analyses {
+		if model.CallEfficiency != nil {
+			measured = append(measured, model)
+		}
+	}
+	if len(measured) == 0 {
+		return ""
+	}
+	sort.SliceStable(measured, func(i, j int) bool {
+		a, b := measured[i].CallEfficiency, measured[j].CallEfficiency
+		if (a.PassedTests == 0) != (b.PassedTests == 0) {
+			return b.PassedTests == 0 // Models that passed nothing go last
+		}
+		if a.PassedMeanRatio != b.PassedMeanRatio {
+			return a.PassedMeanRatio > b.PassedMeanRatio
+		}
+		return a.MeanRatio > b.MeanRatio
+	})
+
+	var sb strings.Builder
+	sb.WriteString("Tool-Call Efficiency (fewest needed / made):\n")
+	sb.WriteString("--------------------------------------------\n")
+	sb.WriteString(fmt.Sprintf("%-30s %8s %8s %10s %12s\n", "Model", "All", "Passed", "Redundant", "Inefficient"))
+	for _, model := range measured {
+		efficiency := model.CallEfficiency
+		passed := "-"
+		if efficiency.PassedTests > 0 {
+			passed = fmt.Sprintf("%.3f", efficiency.PassedMeanRatio)
+		}
+		inefficient := fmt.Sprintf("%d/%d", efficiency.InefficientTests, efficiency.Tests)
+		sb.WriteString(fmt.Sprintf("%-30s %8.3f %8s %10d %12s\n", model.ModelName,
+			efficiency.MeanRatio, passed, efficiency.RedundantCalls, inefficient))
+	}
+	sb.WriteString("\n")
+
+	return sb.String()
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"model-test/models"
+)
+
+// InfrastructureFailures counts the tests of a model that failed against the server
+// (API errors, timeouts) rather than on the model's answer. They are excluded from
+// the model's metrics so flaky serving hardware does not count against the model.
+type InfrastructureFailures struct {
+	Tests   int            `json:"tests"`
+	Rate    float64        `json:"rate"` // Share of all the model's tests
+	Reasons map[string]int `json:"reasons"`
+}
+
+// isInfrastructureFailure reports whether a result failed because of the server
+func isInfrastructureFailure(result models.AgentTestResult) bool {
+	return !result.Success && result.FailureReason.IsInfrastructure()
+}
+
+// excludeInfrastructureFailures removes infrastructure failures from each run and
+// counts them; the count is nil when there were none
+func excludeInfrastructureFailures(runs [][]models.AgentTestResult) ([][]models.AgentTestResult, *InfrastructureFailures) {
+	scored := make([][]models.AgentTestResult, 0, len(runs))
+	failures := &InfrastructureFailures{Reasons: make(map[string]int)}
+	total := 0
+
+	for _, results := range runs {
+		var kept []models.AgentTestResult
+		for _, result := range results {
+			total++
+			if isInfrastructureFailure(result) {
+				failures.Tests++
+				failures.Reasons[string(result.FailureReason)]++
+				continue
+			}
+			kept = append(kept, result)
+		}
+		scored = append(scored, kept)
+	}
+
+	if failures.Tests == 0 {
+		return scored, nil
+	}
+	failures.Rate = float64(failures.Tests) / float64(total)
+	return scored, failures
+}
+
+// formatInfrastructureFailures describes the excluded tests and their reasons
+func formatInfrastructureFailures(failures *InfrastructureFailures) string {
+	reasons := make([]string, 0, len(failures.Reasons))
+	for reason := range failures.Reasons {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+
+	parts := make([]string, 0, len(reasons))
+	for _, reason := range reasons {
+		parts = append(parts, fmt.Sprintf("%s: %d", reason, failures.Reasons[reason]))
+	}
+	return fmt.Sprintf("  Infrastructure Failures: %d excluded from metrics (%.1f%% of tests; %s)\n",
+		failures.Tests, failures.Rate*100, strings.Join(parts, ", "))
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+const (
+	// irtPriorVariance is the variance of the normal priors on abilities and
+	// difficulties, which keeps the estimates finite for models or tests with a perfect
+	// or zero score
+	irtPriorVariance = 4.0
+
+	// irtMaxIterations and irtTolerance bound the alternating Newton updates of the fit
+	irtMaxIterations = 500
+	irtTolerance     = 1e-6
+)
+
+// AbilityScores are Rasch (one-parameter IRT) estimates fitted over the model × test
+// case outcome matrix: P(pass) = 1 / (1 + exp(difficulty - ability)). Unlike the raw
+// pass rate, an ability accounts for how hard the tests a model passed are.
+type AbilityScores struct {
+	Models       []ModelAbility     `json:"models"` // By ability, highest first
+	Difficulties map[string]float64 `json:"difficulties"`
+	Iterations   int                `json:"iterations"`
+	Converged    bool               `json:"converged"`
+}
+
+// ModelAbility is one model's ability estimate on the logit scale
+type ModelAbility struct {
+	Model    string  `json:"model"`
+	Ability  float64 `json:"ability"`
+	StdErr   float64 `json:"std_err"`
+	Lower    float64 `json:"lower"` // 95% interval
+	Upper    float64 `json:"upper"`
+	PassRate float64 `json:"pass_rate"`
+	Runs     int     `json:"runs"`
+}
+
+// calculateAbilityScores fits a Rasch model by maximizing the posterior under normal
+// priors, alternating Newton steps over abilities and difficulties. Each run of a test
+// is one binomial trial. It returns nil with fewer than two models or test cases.
+func calculateAbilityScores(outcomes *outcomeMatrix) *AbilityScores {
+	byModel, testCases := outcomes.byModel, outcomes.testCases
+	if len(byModel) < 2 || len(testCases) < 2 {
+		return nil
+	}
+
+	// Updating in a fixed order keeps repeated analyses of the same files identical
+	modelNames := make([]string, 0, len(byModel))
+	for modelName := range byModel {
+		modelNames = append(modelNames, modelName)
+	}
+	sort.Strings(modelNames)
+	testNames := make([]string, 0, len(testCases))
+	for name := range testCases {
+		testNames = append(testNames, name)
+	}
+	sort.Strings(testNames)
+
+	abilities := make(map[string]float64, len(byModel))
+	difficulties := make(map[string]float64, len(testCases))
+
+	scores := &AbilityScores{}
+	for scores.Iterations < irtMaxIterations && !scores.Converged {
+		scores.Iterations++
+		change := 0.0
+
+		for _, modelName := range modelNames {
+			gradient, information := -abilities[modelName]/irtPriorVariance, 1/irtPriorVariance
+			for _, name := range testNames {
+				item := byModel[modelName][name]
+				if item == nil {
+					continue
+				}
+				p := raschProbability(abilities[modelName], difficulties[name])
+				gradient += float64(item.passed) - float64(item.runs)*p
+				information += float64(item.runs) * p * (1 - p)
+			}
+			step := gradient / information
+			abilities[modelName] += step
+			change = math.Max(change, math.Abs(step))
+		}
+
+		for _, name := range testNames {
+			gradient, information := -difficulties[name]/irtPriorVariance, 1/irtPriorVariance
+			for _, modelName := range modelNames {
+				item := byModel[modelName][name]
+				if item == nil {
+					continue
+				}
+				p := raschProbability(abilities[modelName], difficulties[name])
+				gradient -= float64(item.passed) - float64(item.runs)*p
+				information += float64(item.runs) * p * (1 - p)
+			}
+			step := gradient / information
+			difficulties[name] += step
+			change = math.Max(change, math.Abs(step))
+		}
+
+		scores.Converged = change < irtTolerance
+	}
+
+	// Standard errors come from the posterior's curvature at the estimate
+	for modelName, items := range byModel {
+		information := 1 / irtPriorVariance
+		ability := ModelAbility{Model: modelName, Ability: abilities[modelName]}
+		passed := 0
+		for name, item := range items {
+			p := raschProbability(abilities[modelName], difficulties[name])
+			information += float64(item.runs) * p * (1 - p)
+			ability.Runs += item.runs
+			passed += item.passed
+		}
+		ability.StdErr = 1 / math.Sqrt(information)
+		ability.Lower = ability.Ability - tierZScore*ability.StdErr
+		ability.Upper = ability.Ability + tierZScore*ability.StdErr
+		ability.PassRate = float64(passed) / float64(ability.Runs)
+		scores.Models = append(scores.Models, ability)
+	}
+	sort.Slice(scores.Models, func(i, j int) bool {
+		if scores.Models[i].Ability != scores.Models[j].Ability {
+			return scores.Models[i].Ability > scores.Models[j].Ability
+		}
+		return scores.Models[i].Model < scores.Models[j].Model
+	})
+	scores.Difficulties = difficulties
+
+	return scores
+}
+
+// raschProbability is the chance that a model of the given ability passes a test of
+// the given difficulty
+func raschProbability(ability, difficulty float64) float64 {
+	return 1 / (1 + math.Exp(difficulty-ability))
+}
+
+// generateAbilitySection ranks the models by IRT ability with 95% intervals next to
+// their raw pass rates
+func generateAbilitySection(scores *AbilityScores) string {
+	var sb strings.Builder
+
+	sb.WriteString("Model Ability (Rasch IRT, logits with 95% intervals):\n")
+	sb.WriteString("-----------------------------------------------------\n")
+	for i, model := range scores.Models {
+		sb.WriteString(fmt.Sprintf("%d. %s: %+.2f ± %.2f [%+.2f, %+.2f] (pass rate %.3f, %d runs)\n",
+			i+1, model.Model, model.Ability, tierZScore*model.StdErr, model.Lower, model.Upper, model.PassRate, model.Runs))
+	}
+	if !scores.Converged {
+		sb.WriteString(fmt.Sprintf("Warning: the fit did not converge in %d iterations\n", scores.Iterations))
+	}
+	sb.WriteString("\n")
+
+	return sb.String()
+}
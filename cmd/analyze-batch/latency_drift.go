@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"model-test/models"
+)
+
+const (
+	// latencySlices is how many equal slices of each run the requests are bucketed into
+	latencySlices = 10
+
+	// latencyDriftThreshold is the relative slowdown from the first to the last slice
+	// that flags a model as degrading during the run
+	latencyDriftThreshold = 0.25
+)
+
+// LatencySlice is the LLM latency of the requests in one slice of the run
+type LatencySlice struct {
+	Slice       int     `json:"slice"` // 1-based; slice 1 holds the first 10% of requests
+	Requests    int     `json:"requests"`
+	MeanLatency float64 `json:"mean_latency"` // Seconds
+}
+
+// LatencyOverRun shows how LLM latency changed with position in the run, to expose
+// server-side degradation such as KV cache pressure or memory leaks
+type LatencyOverRun struct {
+	Slices    []LatencySlice `json:"slices"`
+	FirstMean float64        `json:"first_mean"` // Seconds, first slice
+	LastMean  float64        `json:"last_mean"`  // Seconds, last slice
+	Change    float64        `json:"change"`     // Relative change from first to last slice
+	Degraded  bool           `json:"degraded"`   // Change exceeds latencyDriftThreshold
+}
+
+// timedRequest is a single LLM request placed on the run's timeline
+type timedRequest struct {
+	startedAt time.Time
+	iteration int
+	duration  time.Duration
+}
+
+// calculateLatencyOverRun orders every LLM request of each run by when it was sent,
+// buckets them by relative position in the run and averages each bucket across runs.
+// Requests from result files that predate per-request start times are ordered by
+// their test's start time and iteration.
+func calculateLatencyOverRun(runs [][]models.AgentTestResult) *LatencyOverRun {
+	totals := make([]time.Duration, latencySlices)
+	counts := make([]int, latencySlices)
+
+	for _, results := range runs {
+		var requests []timedRequest
+		for _, result := range results {
+			if result.Response == nil {
+				continue
+			}
+			for _, iteration := range result.Response.Iterations {
+				startedAt := iteration.StartedAt
+				if startedAt.IsZero() {
+					startedAt = result.Timestamp
+				}
+				requests = append(requests, timedRequest{startedAt: startedAt, iteration: iteration.Iteration, duration: iteration.Duration})
+			}
+		}
+
+		sort.SliceStable(requests, func(i, j int) bool {
+			if !requests[i].startedAt.Equal(requests[j].startedAt) {
+				return requests[i].startedAt.Before(requests[j].startedAt)
+			}
+			return requests[i].iteration < requests[j].iteration
+		})
+
+		for i, request := range requests {
+			slice := i * latencySlices / len(requests)
+			totals[slice] += request.duration
+			counts[slice]++
+		}
+	}
+
+	if counts[0] == 0 || counts[latencySlices-1] == 0 {
+		return nil // Too few requests to compare the start and end of a run
+	}
+
+	drift := &LatencyOverRun{}
+	for i := range totals {
+		slice := LatencySlice{Slice: i + 1, Requests: counts[i]}
+		if counts[i] > 0 {
+			slice.MeanLatency = totals[i].Seconds() / float64(counts[i])
+		}
+		drift.Slices = append(drift.Slices, slice)
+	}
+
+	drift.FirstMean = drift.Slices[0].MeanLatency
+	drift.LastMean = drift.Slices[latencySlices-1].MeanLatency
+	if drift.FirstMean > 0 {
+		drift.Change = (drift.LastMean - drift.FirstMean) / drift.FirstMean
+	}
+	drift.Degraded = drift.Change > latencyDriftThreshold
+
+	return drift
+}
+
+// latencySparkline renders the slice means as a one-line bar chart scaled to the
+// slowest slice
+func latencySparkline(slices []LatencySlice) string {
+	bars := []rune("▁▂▃▄▅▆▇█")
+
+	maxLatency := 0.0
+	for _, slice := range slices {
+		if slice.MeanLatency > maxLatency {
+			maxLatency = slice.MeanLatency
+		}
+	}
+
+	var sb strings.Builder
+	for _, slice := range slices {
+		level := 0
+		if maxLatency > 0 {
+			level = int(slice.MeanLatency / maxLatency * float64(len(bars)-1))
+		}
+		sb.WriteRune(bars[level])
+	}
+	return sb.String()
+}
+
+// formatLatencyOverRun describes the latency drift of one model for the text report
+func formatLatencyOverRun(drift *LatencyOverRun) string {
+	line := fmt.Sprintf("  Latency over Run: %s first 10%%: %.2fs, last 10%%: %.2fs (%+.0f%%)",
+		latencySparkline(drift.Slices), drift.FirstMean, drift.LastMean, drift.Change*100)
+	if drift.Degraded {
+		line += " <- degrading"
+	}
+	return line + "\n"
+}
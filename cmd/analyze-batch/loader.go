@@ -0,0 +1,219 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"model-test/models"
+	"model-test/services"
+)
+
+// fileSummary is what a resultStore keeps of a result file: how many results it holds,
+// its tags and run ID, or the error that kept it from being read
+type fileSummary struct {
+	results int
+	tags    models.RunTags
+	runID   string
+	err     error
+}
+
+// fileResults holds the decoded results of result files by file name
+type fileResults map[string][]models.AgentTestResult
+
+// resultStore reads the result files of an analysis. Loading reads every file once, in
+// parallel, decoding only the summary the integrity check and tag filter need; the
+// passes that need results decode them a model at a time and drop them, so memory is
+// bounded by the largest model rather than the whole batch.
+type resultStore struct {
+	files    map[string]fileSummary
+	jobs     int
+	aliases  models.ToolAliases
+	bytes    int64
+	duration time.Duration
+}
+
+// loadResultStore summarizes the result files with up to jobs files in flight. Later
+// decodes rename aliased tools to their canonical names so every pass compares tools
+// alike.
+func loadResultStore(files []string, jobs int, aliases models.ToolAliases) *resultStore {
+	if jobs < 1 {
+		jobs = 1
+	}
+	store := &resultStore{files: make(map[string]fileSummary, len(files)), jobs: jobs, aliases: aliases}
+	start := time.Now()
+
+	var mutex sync.Mutex
+	store.forEach(files, func(file string) {
+		summary, size := summarizeResultFile(file)
+		mutex.Lock()
+		store.files[file] = summary
+		store.bytes += size
+		mutex.Unlock()
+	})
+
+	store.duration = time.Since(start)
+	return store
+}
+
+// forEach calls visit for every file with up to s.jobs files in flight
+func (s *resultStore) forEach(files []string, visit func(file string)) {
+	var wg sync.WaitGroup
+	queue := make(chan string)
+	for i := 0; i < s.jobs; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for file := range queue {
+				visit(file)
+			}
+		}()
+	}
+	for _, file := range files {
+		queue <- file
+	}
+	close(queue)
+	wg.Wait()
+}
+
+// decode decodes the result files with up to s.jobs files in flight, handing each
+// decoded report to visit from the goroutine that decoded it. Nothing is kept after
+// visit returns.
+func (s *resultStore) decode(files []string, visit func(file string, report *models.AgentReport, err error)) {
+	s.forEach(files, func(file string) {
+		report, _, err := decodeResultFile(file, s.aliases)
+		visit(file, report, err)
+	})
+}
+
+// runs decodes the results of each result file, in the order of files
+func (s *resultStore) runs(files []string) ([][]models.AgentTestResult, error) {
+	runs := make([][]models.AgentTestResult, len(files))
+	errs := make([]error, len(files))
+	index := make(map[string]int, len(files))
+	for i, file := range files {
+		index[file] = i
+	}
+
+	s.decode(files, func(file string, report *models.AgentReport, err error) {
+		if err != nil {
+			errs[index[file]] = err
+			return
+		}
+		runs[index[file]] = report.Results
+	})
+
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("failed to load file %s: %w", files[i], err)
+		}
+	}
+	return runs, nil
+}
+
+// resultCount returns the number of test results in a result file
+func (s *resultStore) resultCount(file string) (int, error) {
+	summary, ok := s.files[file]
+	if !ok {
+		return 0, fmt.Errorf("result file %s was not loaded", file)
+	}
+	return summary.results, summary.err
+}
+
+// tags returns the run tags recorded in a result file
+func (s *resultStore) tags(file string) (models.RunTags, error) {
+	summary, ok := s.files[file]
+	if !ok {
+		return nil, fmt.Errorf("result file %s was not loaded", file)
+	}
+	return summary.tags, summary.err
+}
+
+// runID returns the run ID recorded in a result file
+func (s *resultStore) runID(file string) string {
+	return s.files[file].runID
+}
+
+// throughput returns the decoded megabytes per second
+func (s *resultStore) throughput() float64 {
+	if s.duration <= 0 {
+		return 0
+	}
+	return float64(s.bytes) / (1 << 20) / s.duration.Seconds()
+}
+
+// resultSummary is the part of a result file the summary pass decodes. Results are
+// decoded into empty structs, which the decoder skips through without building them.
+type resultSummary struct {
+	SchemaVersion int            `json:"schema_version"`
+	RunID         string         `json:"run_id"`
+	Tags          models.RunTags `json:"tags"`
+	Results       []struct{}     `json:"results"`
+}
+
+// summarizeResultFile reads a result file's run ID, tags and number of results,
+// returning the summary and the file's size in bytes
+func summarizeResultFile(filename string) (fileSummary, int64) {
+	var summary resultSummary
+	size, err := decodeJSONFile(filename, &summary)
+	if err != nil {
+		return fileSummary{err: err}, size
+	}
+	if summary.SchemaVersion > models.ResultSchemaVersion {
+		return fileSummary{err: fmt.Errorf("result schema version %d is newer than supported version %d", summary.SchemaVersion, models.ResultSchemaVersion)}, size
+	}
+	return fileSummary{results: len(summary.Results), tags: summary.Tags, runID: summary.RunID}, size
+}
+
+// decodeResultFile decodes a result file, returning the report and the file's size in
+// bytes. Tools are renamed by the aliases recorded in the file and the given ones,
+// which win.
+func decodeResultFile(filename string, aliases models.ToolAliases) (*models.AgentReport, int64, error) {
+	var report models.AgentReport
+	size, err := decodeJSONFile(filename, &report)
+	if err != nil {
+		return nil, size, err
+	}
+
+	if report.SchemaVersion > models.ResultSchemaVersion {
+		return nil, size, fmt.Errorf("result schema version %d is newer than supported version %d", report.SchemaVersion, models.ResultSchemaVersion)
+	}
+
+	if aliases = report.ToolAliases.Merge(aliases); len(aliases) > 0 {
+		for i := range report.Results {
+			report.Results[i] = services.CanonicalizeToolNames(report.Results[i], aliases)
+		}
+	}
+
+	return &report, size, nil
+}
+
+// decodeJSONFile decodes the single JSON value of a file into v, returning the file's
+// size in bytes. The decoder buffers the whole value, so the file is held in memory
+// while it is decoded.
+func decodeJSONFile(filename string, v interface{}) (int64, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var size int64
+	if info, err := f.Stat(); err == nil {
+		size = info.Size()
+	}
+
+	decoder := json.NewDecoder(f)
+	if err := decoder.Decode(v); err != nil {
+		return size, err
+	}
+	// Like json.Unmarshal, reject anything after the value
+	if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+		return size, fmt.Errorf("invalid data after top-level value")
+	}
+	return size, nil
+}
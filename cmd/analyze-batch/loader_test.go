@@ -0,0 +1,295 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"model-test/models"
+)
+
+// The synthetic batch is benchmarkBatchMB megabytes unless ANALYZE_BATCH_BENCH_MB says
+// otherwise; multi-GB batches are measured with, for example,
+//
+//	ANALYZE_BATCH_BENCH_MB=4096 go test -run XXX -bench . -benchtime 1x ./cmd/analyze-batch
+const (
+	benchmarkBatchMB      = 128
+	benchmarkModels       = 8
+	benchmarkFileResults  = 200
+	benchmarkHeapInterval = 10 * time.Millisecond
+)
+
+// writeSyntheticBatch writes result files for benchmarkModels models into a temporary
+// batch directory until they add up to the benchmark size, returning the directory,
+// its result files and their total size in bytes
+func writeSyntheticBatch(b *testing.B) (string, []string, int64) {
+	b.Helper()
+
+	megabytes := benchmarkBatchMB
+	if value := os.Getenv("ANALYZE_BATCH_BENCH_MB"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			b.Fatalf("Invalid ANALYZE_BATCH_BENCH_MB: %q", value)
+		}
+		megabytes = parsed
+	}
+
+	reports := make([][]byte, benchmarkModels)
+	for i := range reports {
+		data, err := json.Marshal(syntheticReport(fmt.Sprintf("model-%d", i)))
+		if err != nil {
+			b.Fatalf("failed to marshal synthetic report: %v", err)
+		}
+		reports[i] = data
+	}
+
+	dir := b.TempDir()
+	var files []string
+	var size int64
+	for run := 0; size < int64(megabytes)<<20; run++ {
+		model := run % benchmarkModels
+		file := filepath.Join(dir, fmt.Sprintf("model-%d_agent_test_results_model-%d_%06d.json", model, model, run))
+		if err := os.WriteFile(file, reports[model], 0644); err != nil {
+			b.Fatalf("failed to write synthetic result file: %v", err)
+		}
+		files = append(files, file)
+		size += int64(len(reports[model]))
+	}
+	return dir, files, size
+}
+
+// syntheticReport builds a result file's report with benchmarkFileResults results
+// shaped like a real run: expected variants, tool calls with results, assistant turns
+// and a transcript
+func syntheticReport(model string) models.AgentReport {
+	report := models.AgentReport{
+		SchemaVersion: models.ResultSchemaVersion,
+		Timestamp:     time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		TestSuite:     "synthetic",
+	}
+
+	filler := strings.Repeat("The product matches the request and is in stock. ", 20)
+	for i := 0; i < benchmarkFileResults; i++ {
+		testCase := models.TestCase{
+			Name:   fmt.Sprintf("test_%03d", i%50),
+			Prompt: "Add two bottles of olive oil to my cart and check out",
+			ExpectedToolVariants: []models.ExpectedToolPath{{
+				Name: "primary",
+				Tools: []models.ExpectedToolCall{
+					{Name: "search_products", Arguments: map[string]interface{}{"query": "olive oil"}},
+					{Name: "add_to_cart", Arguments: map[string]interface{}{"product_id": "p-1", "quantity": 2}},
+				},
+			}},
+		}
+
+		response := &models.ChatResponse{
+			Message:     filler,
+			LLMRequests: 3,
+			ToolCalls: []models.ToolCallResult{
+				{CallID: "call_1", ToolName: "search_products", Success: true, Arguments: `{"query":"olive oil"}`, Result: map[string]interface{}{"products": filler}},
+				{CallID: "call_2", ToolName: "add_to_cart", Success: true, Arguments: `{"product_id":"p-1","quantity":2}`, Result: map[string]interface{}{"cart": filler}},
+			},
+			AssistantMessages: []models.AssistantMessage{
+				{Iteration: 1, ToolCalls: []string{"search_products"}},
+				{Iteration: 2, ToolCalls: []string{"add_to_cart"}},
+				{Iteration: 3, Content: filler, Final: true},
+			},
+		}
+
+		report.Results = append(report.Results, models.AgentTestResult{
+			TestCase:     testCase,
+			ModelName:    model,
+			Response:     response,
+			Success:      i%4 != 0,
+			MatchedPath:  "primary",
+			Timestamp:    report.Timestamp,
+			ResponseTime: time.Duration(i%7+1) * time.Second,
+			Transcript: []models.TranscriptMessage{
+				{Role: models.RoleUser, Content: testCase.Prompt},
+				{Role: models.RoleTool, Iteration: 1, ToolCallID: "call_1", ToolName: "search_products", Content: filler},
+				{Role: models.RoleTool, Iteration: 2, ToolCallID: "call_2", ToolName: "add_to_cart", Content: filler},
+				{Role: models.RoleAssistant, Iteration: 3, Content: filler},
+			},
+		})
+	}
+	report.TotalTests = len(report.Results)
+	return report
+}
+
+// sampleHeap records the largest heap in use until stop is called
+func sampleHeap() (stop func() uint64) {
+	var peak atomic.Uint64
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(benchmarkHeapInterval)
+		defer ticker.Stop()
+		for {
+			var stats runtime.MemStats
+			runtime.ReadMemStats(&stats)
+			if stats.HeapInuse > peak.Load() {
+				peak.Store(stats.HeapInuse)
+			}
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return func() uint64 {
+		close(done)
+		<-finished
+		return peak.Load()
+	}
+}
+
+// writeSyntheticFile writes a single synthetic result file, returning its path and size
+func writeSyntheticFile(b *testing.B) (string, int64) {
+	b.Helper()
+	file := filepath.Join(b.TempDir(), "model_agent_test_results_model_1.json")
+	data, err := json.Marshal(syntheticReport("model"))
+	if err != nil {
+		b.Fatalf("failed to marshal synthetic report: %v", err)
+	}
+	if err := os.WriteFile(file, data, 0644); err != nil {
+		b.Fatalf("failed to write synthetic result file: %v", err)
+	}
+	return file, int64(len(data))
+}
+
+// unmarshalResultFile is how the analyzer read a result file before the summary pass:
+// the whole file read into memory and unmarshaled into a report
+func unmarshalResultFile(filename string) (*models.AgentReport, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var report models.AgentReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// BenchmarkUnmarshalResultFile is the baseline for the decodes below: reading and
+// unmarshaling a whole result file
+func BenchmarkUnmarshalResultFile(b *testing.B) {
+	file, size := writeSyntheticFile(b)
+
+	b.SetBytes(size)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := unmarshalResultFile(file); err != nil {
+			b.Fatalf("failed to unmarshal result file: %v", err)
+		}
+	}
+}
+
+// BenchmarkDecodeResultFile measures decoding a whole result file, as the per-model
+// passes do
+func BenchmarkDecodeResultFile(b *testing.B) {
+	file, size := writeSyntheticFile(b)
+
+	b.SetBytes(size)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := decodeResultFile(file, nil); err != nil {
+			b.Fatalf("failed to decode result file: %v", err)
+		}
+	}
+}
+
+// BenchmarkSummarizeResultFile measures reading a result file's summary, as the
+// loading pass does
+func BenchmarkSummarizeResultFile(b *testing.B) {
+	file, size := writeSyntheticFile(b)
+
+	b.SetBytes(size)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if summary, _ := summarizeResultFile(file); summary.err != nil || summary.results != benchmarkFileResults {
+			b.Fatalf("failed to summarize result file: %v (%d results)", summary.err, summary.results)
+		}
+	}
+}
+
+// BenchmarkUnmarshalBatch is the baseline for BenchmarkLoadResultStore: unmarshaling
+// every file of the batch in full, in parallel, and keeping the results, as the
+// analyzer did before it summarized files
+func BenchmarkUnmarshalBatch(b *testing.B) {
+	_, files, size := writeSyntheticBatch(b)
+	runtime.GC()
+
+	b.SetBytes(size)
+	b.ResetTimer()
+	stop := sampleHeap()
+	for i := 0; i < b.N; i++ {
+		store := &resultStore{jobs: runtime.GOMAXPROCS(0)}
+		results := make(fileResults, len(files))
+		var mutex sync.Mutex
+		store.forEach(files, func(file string) {
+			report, err := unmarshalResultFile(file)
+			if err != nil {
+				b.Errorf("failed to unmarshal %s: %v", file, err)
+				return
+			}
+			mutex.Lock()
+			results[file] = report.Results
+			mutex.Unlock()
+		})
+	}
+	b.ReportMetric(float64(stop())/(1<<20), "peak-heap-MB")
+}
+
+// BenchmarkLoadResultStore measures the parallel summary pass over a whole batch
+func BenchmarkLoadResultStore(b *testing.B) {
+	_, files, size := writeSyntheticBatch(b)
+	runtime.GC()
+
+	b.SetBytes(size)
+	b.ResetTimer()
+	stop := sampleHeap()
+	for i := 0; i < b.N; i++ {
+		store := loadResultStore(files, runtime.GOMAXPROCS(0), nil)
+		for _, file := range files {
+			if _, err := store.resultCount(file); err != nil {
+				b.Fatalf("failed to load %s: %v", file, err)
+			}
+		}
+	}
+	b.ReportMetric(float64(stop())/(1<<20), "peak-heap-MB")
+}
+
+// BenchmarkAnalyzeBatches measures a full analysis of a batch, whose peak heap should
+// stay near one model's results however large the batch grows
+func BenchmarkAnalyzeBatches(b *testing.B) {
+	dir, _, size := writeSyntheticBatch(b)
+	runtime.GC()
+
+	b.SetBytes(size)
+	b.ResetTimer()
+	stop := sampleHeap()
+	for i := 0; i < b.N; i++ {
+		report, err := analyzeBatches([]string{dir}, AnalysisOptions{Jobs: runtime.GOMAXPROCS(0)})
+		if err != nil {
+			b.Fatalf("failed to analyze batch: %v", err)
+		}
+		if len(report.Models) != benchmarkModels {
+			b.Fatalf("analyzed %d models, want %d", len(report.Models), benchmarkModels)
+		}
+	}
+	b.ReportMetric(float64(stop())/(1<<20), "peak-heap-MB")
+}
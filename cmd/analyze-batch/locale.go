@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// defaultDateFormat is used for dates when the catalog entry does not set one
+const defaultDateFormat = "2006-01-02 15:04"
+
+// LocaleMessages is one locale's entry in a report message catalog. Messages are
+// keyed by their English text, so a template reads naturally and a missing
+// translation falls back to English.
+type LocaleMessages struct {
+	Locale      string            `json:"locale"`
+	Description string            `json:"description,omitempty"`
+	DateFormat  string            `json:"date_format,omitempty"`
+	Messages    map[string]string `json:"messages"`
+}
+
+// reportLocale formats numbers and dates and translates headers for -template reports
+type reportLocale struct {
+	printer    *message.Printer
+	dateFormat string
+	messages   map[string]string
+}
+
+// loadReportLocale parses the locale and looks it up in the message catalog, trying
+// the exact tag first and then its base language (de-CH falls back to de). Numbers
+// are formatted for the locale even when the catalog has no entry for it. An empty
+// locale is English and reads no catalog.
+func loadReportLocale(locale, catalogFile string) (*reportLocale, error) {
+	if locale == "" {
+		locale = "en"
+		catalogFile = ""
+	}
+
+	tag, err := language.Parse(locale)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse locale %q: %w", locale, err)
+	}
+
+	result := &reportLocale{
+		printer:    message.NewPrinter(tag),
+		dateFormat: defaultDateFormat,
+	}
+	if catalogFile == "" {
+		return result, nil
+	}
+
+	data, err := os.ReadFile(catalogFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read locale catalog: %w", err)
+	}
+	var catalog []LocaleMessages
+	if err := json.Unmarshal(data, &catalog); err != nil {
+		return nil, fmt.Errorf("failed to parse locale catalog %s: %w", catalogFile, err)
+	}
+
+	base, _ := tag.Base()
+	entry := findLocaleMessages(catalog, tag.String())
+	if entry == nil {
+		entry = findLocaleMessages(catalog, base.String())
+	}
+	if entry == nil {
+		if base.String() != "en" {
+			fmt.Fprintf(os.Stderr, "⚠️  No messages for locale %s in %s; headers stay in English\n", tag, catalogFile)
+		}
+		return result, nil
+	}
+
+	result.messages = entry.Messages
+	if entry.DateFormat != "" {
+		result.dateFormat = entry.DateFormat
+	}
+	return result, nil
+}
+
+// findLocaleMessages returns the catalog entry whose locale matches the tag, or nil
+func findLocaleMessages(catalog []LocaleMessages, tag string) *LocaleMessages {
+	for i := range catalog {
+		parsed, err := language.Parse(catalog[i].Locale)
+		if err == nil && parsed.String() == tag {
+			return &catalog[i]
+		}
+	}
+	return nil
+}
+
+// translate returns the catalog's translation of an English message, or the message itself
+func (l *reportLocale) translate(text string) string {
+	if translated, ok := l.messages[text]; ok && translated != "" {
+		return translated
+	}
+	return text
+}
+
+// funcs returns the locale-aware formatting helpers for -template reports
+func (l *reportLocale) funcs() map[string]interface{} {
+	return map[string]interface{}{
+		"t":       l.translate,
+		"percent": func(value float64) string { return l.printer.Sprintf("%.1f%%", value*100) },
+		"fixed":   func(digits int, value float64) string { return l.printer.Sprintf("%.*f", digits, value) },
+		"seconds": func(value float64) string { return l.printer.Sprintf("%.2fs", value) },
+		"number":  func(value int) string { return l.printer.Sprintf("%d", value) },
+		"date":    func(value time.Time) string { return value.Format(l.dateFormat) },
+	}
+}
@@ -9,11 +9,13 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"runtime"
 	"sort"
 	"strings"
 	"time"
 
 	"model-test/models"
+	"model-test/services"
 )
 
 // MetricSet represents precision, recall, and F1 metrics
@@ -27,31 +29,167 @@ type MetricSet struct {
 	FalseNegatives int     `json:"false_negatives"`
 }
 
+// IterationTokenStats represents the distribution of prompt sizes for one agent loop iteration
+type IterationTokenStats struct {
+	Iteration int     `json:"iteration"`
+	Samples   int     `json:"samples"`
+	Min       int64   `json:"min"`
+	Max       int64   `json:"max"`
+	Mean      float64 `json:"mean"`
+	P50       int64   `json:"p50"`
+	P95       int64   `json:"p95"`
+	Estimated int     `json:"estimated"` // Samples whose token count was estimated rather than reported
+}
+
+// EnumCompliance represents how often enum-constrained arguments used an allowed value
+type EnumCompliance struct {
+	Checked   int     `json:"checked"`
+	Compliant int     `json:"compliant"`
+	Rate      float64 `json:"rate"`
+}
+
+// DenialCompliance represents how often a model respected simulated permission denials
+type DenialCompliance struct {
+	TestsWithDenials int     `json:"tests_with_denials"`
+	Compliant        int     `json:"compliant"`
+	Rate             float64 `json:"rate"`
+}
+
+// LatencyBreakdown represents the average per-test time split into model latency
+// and harness time, in seconds
+type LatencyBreakdown struct {
+	LLMTime         float64 `json:"llm_time"`
+	ToolTime        float64 `json:"tool_time"`
+	HarnessOverhead float64 `json:"harness_overhead"`
+	Tests           int     `json:"tests"` // Tests with timing data; older result files have none
+}
+
 // ModelAnalysis represents the analysis results for a single model
 type ModelAnalysis struct {
-	ModelName           string    `json:"model_name"`
-	BatchSource         string    `json:"batch_source"`          // Which batch directory this model came from
-	ToolInvocation      MetricSet `json:"tool_invocation"`       // Binary: should call tool vs did call tool
-	ToolSelection       MetricSet `json:"tool_selection"`        // Specific: right tool vs wrong tool
-	AverageResponseTime float64   `json:"average_response_time"` // Average response time in seconds
-	TotalTests          int       `json:"total_tests"`
-	TotalRuns           int       `json:"total_runs"`
-	ResultFiles         []string  `json:"result_files"`
+	ModelName               string                        `json:"model_name"`
+	SamplingConfig          *models.TestConfig            `json:"sampling_config,omitempty"` // Set when the model's runs swept sampling parameters
+	Tags                    models.RunTags                `json:"tags,omitempty"`            // Values of the -group-by tags for this group
+	BatchSource             string                        `json:"batch_source"`              // Which batch directory this model came from
+	ToolInvocation          MetricSet                     `json:"tool_invocation"`           // Binary: should call tool vs did call tool
+	ToolSelection           MetricSet                     `json:"tool_selection"`            // Specific: right tool vs wrong tool
+	ToolSelectionF1StdErr   float64                       `json:"tool_selection_f1_std_err"` // Bootstrap estimate
+	Tier                    int                           `json:"tier"`                      // Leaderboard tier; models in the same tier are statistically tied
+	AverageResponseTime     float64                       `json:"average_response_time"`     // Average response time in seconds
+	Latency                 LatencyBreakdown              `json:"latency"`
+	LatencyOverRun          *LatencyOverRun               `json:"latency_over_run,omitempty"`
+	PromptTokensByIteration []IterationTokenStats         `json:"prompt_tokens_by_iteration,omitempty"`
+	TokenEfficiency         *TokenEfficiency              `json:"token_efficiency,omitempty"`
+	Scoring                 ScoringComparison             `json:"scoring"`
+	EnumCompliance          EnumCompliance                `json:"enum_compliance"`             // Separate from argument accuracy
+	SchemaCompliance        *models.SchemaCompliance      `json:"schema_compliance,omitempty"` // Tool calls whose arguments fit the tool schema; nil for results recorded without schema checks
+	DenialCompliance        *DenialCompliance             `json:"denial_compliance,omitempty"`
+	FailureReasons          map[string]int                `json:"failure_reasons,omitempty"`
+	LoopsDetected           int                           `json:"loops_detected"`            // Tests stopped for repeating identical tool calls in consecutive turns
+	CallEfficiency          *models.CallEfficiencySummary `json:"call_efficiency,omitempty"` // Tool calls made against the fewest needed
+	TotalTests              int                           `json:"total_tests"`
+	TotalRuns               int                           `json:"total_runs"`
+	ResultFiles             []string                      `json:"result_files"`
+
+	// Tests that failed against the server, excluded from all metrics above
+	InfrastructureFailures *InfrastructureFailures `json:"infrastructure_failures,omitempty"`
+
+	// Metrics from metric plugins, keyed plugin.metric
+	CustomMetrics    map[string]float64   `json:"custom_metrics,omitempty"`
+	CustomMetricSets map[string]MetricSet `json:"custom_metric_sets,omitempty"`
+}
+
+// BatchManifest describes what a batch run was expected to produce
+type BatchManifest struct {
+	BatchID           string          `json:"batch_id,omitempty"`
+	CreatedAt         string          `json:"created_at"`
+	ConfigFile        string          `json:"config_file"`
+	SuiteHash         string          `json:"suite_hash"`
+	Tags              models.RunTags  `json:"tags,omitempty"`
+	TestCaseFilter    string          `json:"test_case_filter,omitempty"`
+	RunsPerModel      int             `json:"runs_per_model"`
+	TestCasesPerRun   int             `json:"test_cases_per_run"`
+	TelemetryHook     string          `json:"telemetry_hook,omitempty"` // Each run's timeline is in its run manifest
+	TelemetryInterval string          `json:"telemetry_interval,omitempty"`
+	HooksFile         string          `json:"hooks_file,omitempty"` // Hook outputs are in the run manifests
+	Models            []ManifestModel `json:"models"`
+}
+
+// ManifestModel identifies a model in the batch manifest
+type ManifestModel struct {
+	Name       string `json:"name"`
+	FilePrefix string `json:"file_prefix"`
+}
+
+// BatchIntegrity reports missing, corrupted, or incomplete runs in a batch directory
+type BatchIntegrity struct {
+	BatchDirectory  string         `json:"batch_directory"`
+	ManifestFound   bool           `json:"manifest_found"`
+	BatchID         string         `json:"batch_id,omitempty"`
+	SuiteHash       string         `json:"suite_hash,omitempty"`
+	Complete        bool           `json:"complete"`
+	MissingRuns     map[string]int `json:"missing_runs,omitempty"`     // Model name -> runs expected but not found
+	CorruptedFiles  []string       `json:"corrupted_files,omitempty"`  // Result files that are not valid JSON
+	IncompleteFiles []string       `json:"incomplete_files,omitempty"` // Result files with fewer tests than expected
+
+	// Set when a verification key is given
+	SignaturesVerified bool     `json:"signatures_verified,omitempty"`
+	UnsignedFiles      []string `json:"unsigned_files,omitempty"`    // Files without a signature
+	ForeignKeyFiles    []string `json:"foreign_key_files,omitempty"` // Files signed with a different key
+	TamperedFiles      []string `json:"tampered_files,omitempty"`    // Files whose content no longer matches their signature
+}
+
+// VariantCoverage counts how often one expected variant of a test case was matched
+type VariantCoverage struct {
+	Name    string   `json:"name"`
+	Matches int      `json:"matches"`
+	Models  []string `json:"models,omitempty"` // Models that matched this variant at least once
+}
+
+// TestCaseCoverage reports which expected variants of a test case were exercised
+type TestCaseCoverage struct {
+	TestCase  string            `json:"test_case"`
+	Runs      int               `json:"runs"`
+	Passed    int               `json:"passed"`
+	Variants  []VariantCoverage `json:"variants"`
+	Unmatched []string          `json:"unmatched,omitempty"` // Variants no model ever matched
 }
 
 // BatchAnalysisReport represents the complete analysis report
 type BatchAnalysisReport struct {
-	BatchDirectories []string        `json:"batch_directories"`
-	AnalysisDate     time.Time       `json:"analysis_date"`
-	Models           []ModelAnalysis `json:"models"`
-	Summary          string          `json:"summary"`
+	BatchDirectories []string              `json:"batch_directories"`
+	AnalysisDate     time.Time             `json:"analysis_date"`
+	TagFilter        models.RunTags        `json:"tag_filter,omitempty"`
+	GroupBy          []string              `json:"group_by,omitempty"`
+	Integrity        []BatchIntegrity      `json:"integrity"`
+	Models           []ModelAnalysis       `json:"models"`
+	Tiers            []LeaderboardTier     `json:"tiers"`
+	VariantCoverage  []TestCaseCoverage    `json:"variant_coverage"`
+	Difficulty       DifficultyCalibration `json:"difficulty"`
+	Abilities        *AbilityScores        `json:"abilities,omitempty"` // Nil with fewer than two models or test cases
+	ToolConfusion    []ToolConfusion       `json:"tool_confusion"`
+	ToolCoverage     ToolCoverage          `json:"tool_coverage"`
+	Summary          string                `json:"summary"`
 }
 
 func main() {
 	var (
 		outputFile = flag.String("o", "", "Output file path (default: stdout)")
 		format     = flag.String("format", "text", "Output format: text or json")
+		strict     = flag.Bool("strict", false, "Exit with an error if any batch is missing runs or has corrupted result files")
+		groupBy    = flag.String("group-by", "", "Comma-separated run tag keys to split each model's results by (e.g. gpu,quant)")
+		template   = flag.String("template", "", "Go template file to render the report with instead of the built-in text report (*.html files are HTML-escaped; see config/report_templates)")
+		locale     = flag.String("locale", "", "Locale for numbers, dates and translated headers in -template reports, e.g. de or fr-CA (default: English)")
+		catalog    = flag.String("locale-catalog", "config/report_locales.json", "Message catalog with translated report headers per locale")
+		jobs       = flag.Int("jobs", runtime.GOMAXPROCS(0), "Number of result files to decode in parallel")
+		timing     = flag.Bool("timing", false, "Print how long loading the result files took, and at what throughput, to stderr")
+		parquet    = flag.String("parquet", "", "Also write every analyzed test result as a row of this Parquet file, for pandas or duckdb")
+		verifyKey  = flag.String("verify-key-file", "", "Shared secret the results were signed with (model-test/batch-run -sign-key-file); tampered files are reported and skipped")
+		plugins    = flag.String("metric-plugins", "", "JSON file of metric plugin commands run per result and per model, whose metrics are added to the report (see config/metric_plugins.example.json)")
+		aliasFile  = flag.String("tool-aliases", "", "Test config (e.g. config/test_cases.json) whose tool_aliases are matched as one tool, in addition to those recorded in each result file")
+		requireSig = flag.Bool("require-signatures", false, "With -verify-key-file, also skip result files that are unsigned or signed with another key")
 	)
+	tagFilter := models.RunTags{}
+	flag.Var(tagFilter, "tag", "Only analyze runs tagged key=value (repeatable; all must match)")
 	flag.Parse()
 
 	if len(flag.Args()) < 1 {
@@ -72,14 +210,65 @@ func main() {
 	}
 
 	// Analyze the batches
-	report, err := analyzeBatches(batchDirs)
+	options := AnalysisOptions{TagFilter: tagFilter, Jobs: *jobs, Timing: *timing, ParquetFile: *parquet, RequireSignatures: *requireSig}
+	if *requireSig && *verifyKey == "" {
+		log.Fatalf("Invalid -require-signatures: needs -verify-key-file")
+	}
+	if *verifyKey != "" {
+		key, err := services.LoadSigningKey(*verifyKey)
+		if err != nil {
+			log.Fatalf("Invalid -verify-key-file: %v", err)
+		}
+		options.VerifyKey = key
+	}
+	metricPlugins, err := loadMetricPlugins(*plugins)
+	if err != nil {
+		log.Fatalf("Invalid -metric-plugins: %v", err)
+	}
+	options.Plugins = metricPlugins
+	if *aliasFile != "" {
+		suite, err := services.LoadTestSuite(*aliasFile)
+		if err != nil {
+			log.Fatalf("Invalid -tool-aliases: %v", err)
+		}
+		options.ToolAliases = suite.ToolAliases
+	}
+	if *groupBy != "" {
+		for _, key := range strings.Split(*groupBy, ",") {
+			options.GroupBy = append(options.GroupBy, strings.TrimSpace(key))
+		}
+	}
+
+	report, err := analyzeBatches(batchDirs, options)
 	if err != nil {
 		log.Fatalf("Failed to analyze batches: %v", err)
 	}
 
+	if *strict {
+		for _, integrity := range report.Integrity {
+			if !integrity.Complete {
+				log.Fatalf("Batch %s is incomplete; rerun without -strict to see the integrity report", integrity.BatchDirectory)
+			}
+		}
+	}
+
 	// Generate output
 	var output string
-	if *format == "json" {
+	if *template != "" {
+		if *format == "json" {
+			log.Fatalf("-template cannot be combined with -format json")
+		}
+		localized, err := loadReportLocale(*locale, *catalog)
+		if err != nil {
+			log.Fatalf("Failed to load -locale: %v", err)
+		}
+		output, err = renderTemplate(*template, report, localized)
+		if err != nil {
+			log.Fatalf("Failed to render -template: %v", err)
+		}
+	} else if *locale != "" {
+		log.Fatalf("-locale requires -template; the built-in text report is English only")
+	} else if *format == "json" {
 		data, err := json.MarshalIndent(report, "", "  ")
 		if err != nil {
 			log.Fatalf("Failed to marshal JSON: %v", err)
@@ -105,57 +294,418 @@ func main() {
 type ModelFileInfo struct {
 	files       []string
 	batchSource string
+	tags        models.RunTags
+}
+
+// AnalysisOptions controls which runs are analyzed and how they are grouped
+type AnalysisOptions struct {
+	TagFilter models.RunTags // Only runs carrying all of these tags are analyzed
+	GroupBy   []string       // Tag keys that split a model's runs into separate groups
+
+	Jobs   int  // Result files decoded in parallel
+	Timing bool // Print load duration and throughput to stderr
+
+	ParquetFile string // Where to write the analyzed results as a table, if set
+
+	VerifyKey         []byte // Key that result files and manifests were signed with, if set
+	RequireSignatures bool   // Skip result files that are unsigned or signed with another key
+
+	Plugins []MetricPlugin // Compute custom metrics for every analyzed model
+
+	ToolAliases models.ToolAliases // Equivalent tool names, added to those recorded in each result file
 }
 
 // analyzeBatches analyzes all result files across multiple batch directories
-func analyzeBatches(batchDirs []string) (*BatchAnalysisReport, error) {
+func analyzeBatches(batchDirs []string, options AnalysisOptions) (*BatchAnalysisReport, error) {
 	var allResultFiles []string
 
+	var integrity []BatchIntegrity
+	corrupted := make(map[string]bool)
+
 	// Collect all result files from all batch directories
-	for _, batchDir := range batchDirs {
+	filesByBatch := make([][]string, len(batchDirs))
+	for i, batchDir := range batchDirs {
 		resultFiles, err := findResultFiles(batchDir)
 		if err != nil {
 			return nil, fmt.Errorf("failed to find result files in %s: %w", batchDir, err)
 		}
+		filesByBatch[i] = resultFiles
 		allResultFiles = append(allResultFiles, resultFiles...)
 	}
 
+	// The integrity check and tag filter read the summaries decoded here
+	store := loadResultStore(allResultFiles, options.Jobs, options.ToolAliases)
+	if options.Timing {
+		fmt.Fprintf(os.Stderr, "Loaded %d result files (%.1f MB) in %v: %.1f MB/s with %d jobs\n",
+			len(allResultFiles), float64(store.bytes)/(1<<20), store.duration.Round(time.Millisecond), store.throughput(), options.Jobs)
+	}
+
+	for i, batchDir := range batchDirs {
+		check := verifyBatchIntegrity(batchDir, filesByBatch[i], store, options)
+		for _, file := range check.CorruptedFiles {
+			corrupted[file] = true
+		}
+		for _, file := range check.TamperedFiles {
+			corrupted[file] = true
+		}
+		if options.RequireSignatures {
+			for _, file := range append(check.UnsignedFiles, check.ForeignKeyFiles...) {
+				corrupted[file] = true
+			}
+		}
+		integrity = append(integrity, check)
+	}
+
+	// Corrupted, tampered and (with -require-signatures) unverified files are reported, not analyzed
+	var validFiles []string
+	for _, file := range allResultFiles {
+		if !corrupted[file] {
+			validFiles = append(validFiles, file)
+		}
+	}
+	allResultFiles = validFiles
+
 	if len(allResultFiles) == 0 {
 		return nil, fmt.Errorf("no result files found in any of the directories: %v", batchDirs)
 	}
 
 	// Group files by model across all batches
 	modelFiles := groupFilesByModelWithSource(allResultFiles, batchDirs)
+	if len(options.TagFilter) > 0 || len(options.GroupBy) > 0 {
+		modelFiles = applyTagOptions(modelFiles, options, store)
+		if len(modelFiles) == 0 {
+			return nil, fmt.Errorf("no result files match tags %s", options.TagFilter)
+		}
+	}
+
+	// Analyze one model at a time, feeding its results to the cross-model passes, so
+	// only that model's results are held in memory
+	modelNames := make([]string, 0, len(modelFiles))
+	for modelName := range modelFiles {
+		modelNames = append(modelNames, modelName)
+	}
+	sort.Strings(modelNames)
+
+	variants := variantTally{}
+	outcomes := newOutcomeMatrix()
+	confusion := newConfusionTally()
+	coverage := newCoverageTally()
+	var table *services.ResultsTable
+	if options.ParquetFile != "" {
+		table = services.NewResultsTable()
+	}
 
-	// Analyze each model
 	var models []ModelAnalysis
-	for modelName, fileInfo := range modelFiles {
-		analysis, err := analyzeModelWithSource(modelName, fileInfo.files, fileInfo.batchSource)
+	for _, modelName := range modelNames {
+		fileInfo := modelFiles[modelName]
+		files := append([]string(nil), fileInfo.files...)
+		sort.Strings(files)
+		runs, err := loadRuns(files, store)
 		if err != nil {
 			log.Printf("Warning: failed to analyze model %s: %v", modelName, err)
 			continue
 		}
-		models = append(models, *analysis)
+
+		runsByFile := make(fileResults, len(files))
+		for i, file := range files {
+			runsByFile[file] = runs[i]
+			variants.add(modelName, runs[i])
+			outcomes.add(modelName, runs[i])
+			confusion.add(modelName, runs[i])
+			coverage.add(modelName, runs[i])
+			if table != nil {
+				tags, _ := store.tags(file)
+				table.Add(file, store.runID(file), tags, runs[i])
+			}
+		}
+
+		analyses, err := analyzeModelBySamplingConfig(modelName, runs, files, fileInfo.batchSource)
+		if err != nil {
+			log.Printf("Warning: failed to analyze model %s: %v", modelName, err)
+			continue
+		}
+		for _, analysis := range analyses {
+			analysis.Tags = fileInfo.tags
+			applyMetricPlugins(&analysis, options.Plugins, runsByFile)
+			models = append(models, analysis)
+		}
+	}
+
+	if table != nil {
+		if err := exportResultsTable(options.ParquetFile, table); err != nil {
+			return nil, err
+		}
 	}
 
 	// Sort models by F1 score (tool selection) descending
 	sort.Slice(models, func(i, j int) bool {
 		return models[i].ToolSelection.F1 > models[j].ToolSelection.F1
 	})
+	assignScoringRanks(models)
+	tiers := assignTiers(models)
 
 	report := &BatchAnalysisReport{
 		BatchDirectories: batchDirs,
 		AnalysisDate:     time.Now(),
+		TagFilter:        options.TagFilter,
+		GroupBy:          options.GroupBy,
+		Integrity:        integrity,
 		Models:           models,
-		Summary:          generateSummary(models),
+		Tiers:            tiers,
+		VariantCoverage:  calculateVariantCoverage(variants),
+		Difficulty:       calculateDifficultyCalibration(outcomes),
+		Abilities:        calculateAbilityScores(outcomes),
+		ToolConfusion:    calculateToolConfusion(confusion),
+		ToolCoverage:     calculateToolCoverage(coverage),
+		Summary:          generateSummary(models, tiers),
 	}
 
 	return report, nil
 }
 
+// variantState counts the passed runs that matched an expected variant and the models
+// that matched it
+type variantState struct {
+	matches int
+	models  map[string]bool
+}
+
+// testCaseState counts a test case's runs and tracks its variants in the order seen
+type testCaseState struct {
+	runs     int
+	passed   int
+	order    []string
+	variants map[string]*variantState
+}
+
+// variantTally counts, for every test case, how often each expected variant was the
+// one matched
+type variantTally map[string]*testCaseState
+
+// add counts the matched variants in one of a model's result files
+func (t variantTally) add(modelName string, results []models.AgentTestResult) {
+	for _, result := range results {
+		// Tests that expect no tools have no variants to cover, and tests that
+		// failed against the server never reached the model
+		if len(result.TestCase.ExpectedToolVariants) == 0 || isInfrastructureFailure(result) {
+			continue
+		}
+
+		state, exists := t[result.TestCase.Name]
+		if !exists {
+			state = &testCaseState{variants: make(map[string]*variantState)}
+			t[result.TestCase.Name] = state
+		}
+
+		// Suites may change between runs; cover the union of variant names
+		for _, variant := range result.TestCase.ExpectedToolVariants {
+			if _, exists := state.variants[variant.Name]; !exists {
+				state.variants[variant.Name] = &variantState{models: make(map[string]bool)}
+				state.order = append(state.order, variant.Name)
+			}
+		}
+
+		state.runs++
+		if !result.Success {
+			continue
+		}
+		state.passed++
+		if variant, exists := state.variants[result.MatchedPath]; exists {
+			variant.matches++
+			variant.models[modelName] = true
+		}
+	}
+}
+
+// calculateVariantCoverage lists, for every test case across all models and runs, how
+// often each expected variant was the one matched
+func calculateVariantCoverage(testCases variantTally) []TestCaseCoverage {
+	var coverage []TestCaseCoverage
+	for name, state := range testCases {
+		testCoverage := TestCaseCoverage{
+			TestCase: name,
+			Runs:     state.runs,
+			Passed:   state.passed,
+		}
+		for _, variantName := range state.order {
+			variant := state.variants[variantName]
+			variantCoverage := VariantCoverage{Name: variantName, Matches: variant.matches}
+			for model := range variant.models {
+				variantCoverage.Models = append(variantCoverage.Models, model)
+			}
+			sort.Strings(variantCoverage.Models)
+			testCoverage.Variants = append(testCoverage.Variants, variantCoverage)
+			if variant.matches == 0 {
+				testCoverage.Unmatched = append(testCoverage.Unmatched, variantName)
+			}
+		}
+		coverage = append(coverage, testCoverage)
+	}
+
+	sort.Slice(coverage, func(i, j int) bool {
+		return coverage[i].TestCase < coverage[j].TestCase
+	})
+
+	return coverage
+}
+
+// applyTagOptions drops runs that do not match the tag filter and splits each
+// model's runs into groups by the values of the group-by tags
+func applyTagOptions(modelFiles map[string]ModelFileInfo, options AnalysisOptions, store *resultStore) map[string]ModelFileInfo {
+	grouped := make(map[string]ModelFileInfo)
+
+	for modelName, info := range modelFiles {
+		for _, file := range info.files {
+			tags, err := store.tags(file)
+			if err != nil {
+				log.Printf("Warning: failed to read tags from %s: %v", file, err)
+				continue
+			}
+			if !tags.Matches(options.TagFilter) {
+				continue
+			}
+
+			groupTags := models.RunTags{}
+			for _, key := range options.GroupBy {
+				value, exists := tags[key]
+				if !exists {
+					value = "(none)"
+				}
+				groupTags[key] = value
+			}
+
+			groupName := modelName
+			if len(groupTags) > 0 {
+				groupName = fmt.Sprintf("%s [%s]", modelName, groupTags)
+			}
+
+			group := grouped[groupName]
+			group.files = append(group.files, file)
+			group.batchSource = info.batchSource
+			if len(groupTags) > 0 {
+				group.tags = groupTags
+			}
+			grouped[groupName] = group
+		}
+	}
+
+	return grouped
+}
+
+// verifyBatchIntegrity checks a batch directory against its manifest (if any) and
+// detects result files that are truncated or otherwise unreadable
+func verifyBatchIntegrity(batchDir string, resultFiles []string, store *resultStore, options AnalysisOptions) BatchIntegrity {
+	integrity := BatchIntegrity{
+		BatchDirectory: batchDir,
+		Complete:       true,
+	}
+
+	manifest, err := loadBatchManifest(batchDir)
+	if err != nil && !os.IsNotExist(err) {
+		log.Printf("Warning: failed to read batch manifest in %s: %v", batchDir, err)
+	}
+
+	// Count readable runs per file prefix and flag broken files
+	runsByPrefix := make(map[string]int)
+	for _, file := range resultFiles {
+		results, err := store.resultCount(file)
+		if err != nil {
+			integrity.CorruptedFiles = append(integrity.CorruptedFiles, file)
+			continue
+		}
+		if manifest != nil && manifest.TestCasesPerRun > 0 && results < manifest.TestCasesPerRun {
+			integrity.IncompleteFiles = append(integrity.IncompleteFiles, file)
+		}
+		for _, model := range manifestModels(manifest) {
+			if strings.HasPrefix(filepath.Base(file), model.FilePrefix+"_agent_test_results_") {
+				runsByPrefix[model.FilePrefix]++
+				break
+			}
+		}
+	}
+
+	if manifest != nil {
+		integrity.ManifestFound = true
+		integrity.BatchID = manifest.BatchID
+		integrity.SuiteHash = manifest.SuiteHash
+		for _, model := range manifest.Models {
+			if missing := manifest.RunsPerModel - runsByPrefix[model.FilePrefix]; missing > 0 {
+				if integrity.MissingRuns == nil {
+					integrity.MissingRuns = make(map[string]int)
+				}
+				integrity.MissingRuns[model.Name] = missing
+			}
+		}
+	}
+
+	if len(options.VerifyKey) > 0 {
+		verifySignatures(&integrity, batchDir, resultFiles, options.VerifyKey)
+	}
+
+	integrity.Complete = len(integrity.MissingRuns) == 0 &&
+		len(integrity.CorruptedFiles) == 0 &&
+		len(integrity.IncompleteFiles) == 0 &&
+		len(integrity.TamperedFiles) == 0 &&
+		(!options.RequireSignatures || len(integrity.UnsignedFiles)+len(integrity.ForeignKeyFiles) == 0)
+
+	return integrity
+}
+
+// verifySignatures checks the result files, the batch manifest and any run manifests
+// of a batch directory against their signatures
+func verifySignatures(integrity *BatchIntegrity, batchDir string, resultFiles []string, key []byte) {
+	integrity.SignaturesVerified = true
+
+	files := append([]string(nil), resultFiles...)
+	if _, err := os.Stat(filepath.Join(batchDir, "batch_manifest.json")); err == nil {
+		files = append(files, filepath.Join(batchDir, "batch_manifest.json"))
+	}
+	runManifests, _ := filepath.Glob(filepath.Join(batchDir, "*run_manifest_*.json"))
+	files = append(files, runManifests...)
+
+	for _, file := range files {
+		status, err := services.VerifyFile(file, key)
+		if err != nil {
+			log.Printf("Warning: %v", err)
+			status = models.SignatureMismatch
+		}
+		switch status {
+		case models.SignatureMissing:
+			integrity.UnsignedFiles = append(integrity.UnsignedFiles, file)
+		case models.SignatureForeignKey:
+			integrity.ForeignKeyFiles = append(integrity.ForeignKeyFiles, file)
+		case models.SignatureMismatch:
+			integrity.TamperedFiles = append(integrity.TamperedFiles, file)
+		}
+	}
+}
+
+// manifestModels returns the manifest's models, tolerating a missing manifest
+func manifestModels(manifest *BatchManifest) []ManifestModel {
+	if manifest == nil {
+		return nil
+	}
+	return manifest.Models
+}
+
+// loadBatchManifest loads batch_manifest.json from a batch directory
+func loadBatchManifest(batchDir string) (*BatchManifest, error) {
+	data, err := os.ReadFile(filepath.Join(batchDir, "batch_manifest.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest BatchManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, err
+	}
+
+	return &manifest, nil
+}
+
 // analyzeBatch analyzes all result files in a batch directory
 func analyzeBatch(batchDir string) (*BatchAnalysisReport, error) {
-	return analyzeBatches([]string{batchDir})
+	return analyzeBatches([]string{batchDir}, AnalysisOptions{})
 }
 
 // findResultFiles finds all agent test result files in the directory
@@ -181,7 +731,7 @@ func groupFilesByModel(files []string) map[string][]string {
 	modelFiles := make(map[string][]string)
 
 	// Pattern to extract model name from filename
-	// Expected format: {model}_agent_test_results_{model}_{timestamp}.json
+	// Expected format: {model}_agent_test_results_{model}_{run_id}.json
 	pattern := regexp.MustCompile(`^(.+?)_agent_test_results_`)
 
 	for _, file := range files {
@@ -258,62 +808,71 @@ func groupFilesByModelWithSource(files []string, batchDirs []string) map[string]
 	return modelFiles
 }
 
-// analyzeModel analyzes all result files for a single model
-func analyzeModel(modelName string, files []string) (*ModelAnalysis, error) {
-	return analyzeModelWithSource(modelName, files, "")
+// loadRuns loads the results of each result file
+func loadRuns(files []string, store *resultStore) ([][]models.AgentTestResult, error) {
+	return store.runs(files)
 }
 
-// analyzeModelWithSource analyzes all result files for a single model with batch source info
-func analyzeModelWithSource(modelName string, files []string, batchSource string) (*ModelAnalysis, error) {
-	var allResults []models.AgentTestResult
+// analyzeRuns computes the metrics of a model from the results of its runs
+func analyzeRuns(modelName string, runs [][]models.AgentTestResult, files []string, batchSource string) (*ModelAnalysis, error) {
+	runs, infrastructureFailures := excludeInfrastructureFailures(runs)
 
-	// Load and aggregate all results from all files
-	for _, file := range files {
-		results, err := loadResultFile(file)
-		if err != nil {
-			return nil, fmt.Errorf("failed to load file %s: %w", file, err)
-		}
+	var allResults []models.AgentTestResult
+	for _, results := range runs {
 		allResults = append(allResults, results...)
 	}
 
 	if len(allResults) == 0 {
+		if infrastructureFailures != nil {
+			return nil, fmt.Errorf("all %d tests of model %s failed against the server", infrastructureFailures.Tests, modelName)
+		}
 		return nil, fmt.Errorf("no test results found for model %s", modelName)
 	}
 
 	// Calculate metrics
 	toolInvocation := calculateToolInvocationMetrics(allResults)
 	toolSelection := calculateToolSelectionMetrics(allResults)
+	toolSelectionStdErr := bootstrapF1StdErr(allResults)
 	averageResponseTime := calculateAverageResponseTime(allResults)
+	latency := calculateLatencyBreakdown(allResults)
+	latencyOverRun := calculateLatencyOverRun(runs)
+	promptTokensByIteration := calculateIterationTokenStats(allResults)
+	tokenEfficiency := calculateTokenEfficiency(allResults)
+	scoring := calculateScoringComparison(allResults)
+	enumCompliance := calculateEnumCompliance(allResults)
+	schemaCompliance := services.SummarizeSchemaCompliance(allResults)
+	denialCompliance := calculateDenialCompliance(allResults)
+	failureReasons := countFailureReasons(allResults)
+	loopsDetected := countLoopsDetected(allResults)
+	callEfficiency := services.SummarizeCallEfficiency(allResults)
 
 	analysis := &ModelAnalysis{
-		ModelName:           modelName,
-		BatchSource:         batchSource,
-		ToolInvocation:      toolInvocation,
-		ToolSelection:       toolSelection,
-		AverageResponseTime: averageResponseTime,
-		TotalTests:          len(allResults),
-		TotalRuns:           len(files),
-		ResultFiles:         files,
+		ModelName:               modelName,
+		BatchSource:             batchSource,
+		ToolInvocation:          toolInvocation,
+		ToolSelection:           toolSelection,
+		ToolSelectionF1StdErr:   toolSelectionStdErr,
+		AverageResponseTime:     averageResponseTime,
+		Latency:                 latency,
+		LatencyOverRun:          latencyOverRun,
+		PromptTokensByIteration: promptTokensByIteration,
+		TokenEfficiency:         tokenEfficiency,
+		Scoring:                 scoring,
+		EnumCompliance:          enumCompliance,
+		SchemaCompliance:        schemaCompliance,
+		DenialCompliance:        denialCompliance,
+		FailureReasons:          failureReasons,
+		LoopsDetected:           loopsDetected,
+		CallEfficiency:          callEfficiency,
+		InfrastructureFailures:  infrastructureFailures,
+		TotalTests:              len(allResults),
+		TotalRuns:               len(files),
+		ResultFiles:             files,
 	}
 
 	return analysis, nil
 }
 
-// loadResultFile loads test results from a JSON file
-func loadResultFile(filename string) ([]models.AgentTestResult, error) {
-	data, err := os.ReadFile(filename)
-	if err != nil {
-		return nil, err
-	}
-
-	var report models.AgentReport
-	if err := json.Unmarshal(data, &report); err != nil {
-		return nil, err
-	}
-
-	return report.Results, nil
-}
-
 // calculateToolInvocationMetrics calculates binary tool invocation metrics
 func calculateToolInvocationMetrics(results []models.AgentTestResult) MetricSet {
 	var tp, fp, tn, fn int
@@ -345,36 +904,45 @@ func calculateToolInvocationMetrics(results []models.AgentTestResult) MetricSet
 
 // calculateToolSelectionMetrics calculates specific tool selection metrics
 func calculateToolSelectionMetrics(results []models.AgentTestResult) MetricSet {
-	var tp, fp, tn, fn int
-
+	var counts [4]int
 	for _, result := range results {
-		expectedTools := getExpectedTools(result.TestCase)
-		actualTools := getActualTools(result.Response)
+		counts[classifyToolSelection(result)]++
+	}
+	return calculateMetrics(counts[truePositive], counts[falsePositive], counts[trueNegative], counts[falseNegative])
+}
 
-		if len(expectedTools) == 0 && len(actualTools) == 0 {
-			tn++ // No tools expected, no tools called
-			continue
-		}
+// selectionOutcome is where a single result falls in the tool selection confusion matrix
+type selectionOutcome int
 
-		if len(expectedTools) == 0 && len(actualTools) > 0 {
-			fp++ // No tools expected, but tools called
-			continue
-		}
+const (
+	truePositive selectionOutcome = iota
+	falsePositive
+	trueNegative
+	falseNegative
+)
 
-		if len(expectedTools) > 0 && len(actualTools) == 0 {
-			fn++ // Tools expected, but no tools called
-			continue
-		}
+// classifyToolSelection places a result in the tool selection confusion matrix
+func classifyToolSelection(result models.AgentTestResult) selectionOutcome {
+	expectedTools := getExpectedTools(result.TestCase)
+	actualTools := getActualTools(result.Response)
 
-		// Check if actual tools match any expected variant
-		if matchesAnyVariant(result.TestCase, actualTools) {
-			tp++ // Correct tools called
-		} else {
-			fp++ // Wrong tools called
-		}
+	if len(expectedTools) == 0 && len(actualTools) == 0 {
+		return trueNegative // No tools expected, no tools called
 	}
 
-	return calculateMetrics(tp, fp, tn, fn)
+	if len(expectedTools) == 0 && len(actualTools) > 0 {
+		return falsePositive // No tools expected, but tools called
+	}
+
+	if len(expectedTools) > 0 && len(actualTools) == 0 {
+		return falseNegative // Tools expected, but no tools called
+	}
+
+	// Check if actual tools match any expected variant
+	if matchesAnyVariant(result.TestCase, actualTools) {
+		return truePositive // Correct tools called
+	}
+	return falsePositive // Wrong tools called
 }
 
 // shouldCallAnyTool determines if any tool should be called for a test case
@@ -452,6 +1020,164 @@ func calculateAverageResponseTime(results []models.AgentTestResult) float64 {
 	return averageNanoseconds / 1e9 // Convert nanoseconds to seconds
 }
 
+// calculateLatencyBreakdown averages the LLM, tool and harness time of results that
+// recorded a timing breakdown
+func calculateLatencyBreakdown(results []models.AgentTestResult) LatencyBreakdown {
+	var llmTime, toolTime, overhead time.Duration
+	var breakdown LatencyBreakdown
+
+	for _, result := range results {
+		if result.Timing == nil {
+			continue
+		}
+		llmTime += result.Timing.LLMTime
+		toolTime += result.Timing.ToolTime
+		overhead += result.Timing.HarnessOverhead
+		breakdown.Tests++
+	}
+
+	if breakdown.Tests > 0 {
+		count := float64(breakdown.Tests)
+		breakdown.LLMTime = llmTime.Seconds() / count
+		breakdown.ToolTime = toolTime.Seconds() / count
+		breakdown.HarnessOverhead = overhead.Seconds() / count
+	}
+
+	return breakdown
+}
+
+// calculateIterationTokenStats calculates the prompt token distribution for each agent loop iteration
+func calculateIterationTokenStats(results []models.AgentTestResult) []IterationTokenStats {
+	samples := make(map[int][]int64)
+	estimated := make(map[int]int)
+
+	for _, result := range results {
+		if result.Response == nil {
+			continue
+		}
+		for _, iteration := range result.Response.Iterations {
+			samples[iteration.Iteration] = append(samples[iteration.Iteration], iteration.PromptTokens)
+			if iteration.TokensEstimated {
+				estimated[iteration.Iteration]++
+			}
+		}
+	}
+
+	var stats []IterationTokenStats
+	for iteration, values := range samples {
+		sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+
+		var total int64
+		for _, v := range values {
+			total += v
+		}
+
+		stats = append(stats, IterationTokenStats{
+			Iteration: iteration,
+			Samples:   len(values),
+			Min:       values[0],
+			Max:       values[len(values)-1],
+			Mean:      float64(total) / float64(len(values)),
+			P50:       percentile(values, 0.50),
+			P95:       percentile(values, 0.95),
+			Estimated: estimated[iteration],
+		})
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Iteration < stats[j].Iteration
+	})
+
+	return stats
+}
+
+// calculateEnumCompliance calculates the share of enum-constrained arguments that used an allowed value
+func calculateEnumCompliance(results []models.AgentTestResult) EnumCompliance {
+	var compliance EnumCompliance
+
+	for _, result := range results {
+		if result.Response == nil {
+			continue
+		}
+		for _, toolCall := range result.Response.ToolCalls {
+			compliance.Checked += toolCall.EnumChecks
+			compliance.Compliant += toolCall.EnumChecks - len(toolCall.EnumViolations)
+		}
+	}
+
+	if compliance.Checked > 0 {
+		compliance.Rate = float64(compliance.Compliant) / float64(compliance.Checked)
+	}
+
+	return compliance
+}
+
+// calculateDenialCompliance calculates the share of tests with a denied tool call where the
+// model did not call the denied tool again. It returns nil when no call was denied.
+func calculateDenialCompliance(results []models.AgentTestResult) *DenialCompliance {
+	var compliance DenialCompliance
+
+	for _, result := range results {
+		if result.Approval == nil {
+			continue
+		}
+		compliance.TestsWithDenials++
+		if result.Approval.Compliant {
+			compliance.Compliant++
+		}
+	}
+
+	if compliance.TestsWithDenials == 0 {
+		return nil
+	}
+	compliance.Rate = float64(compliance.Compliant) / float64(compliance.TestsWithDenials)
+
+	return &compliance
+}
+
+// countFailureReasons counts failed tests by their structured failure reason
+func countFailureReasons(results []models.AgentTestResult) map[string]int {
+	counts := make(map[string]int)
+	for _, result := range results {
+		if result.Success {
+			continue
+		}
+		reason := string(result.FailureReason)
+		if reason == "" {
+			reason = "unclassified" // Result files written before failure reasons existed
+		}
+		counts[reason]++
+	}
+	return counts
+}
+
+// countLoopsDetected counts tests whose agent loop was stopped for repeating the
+// previous turn's tool calls, whether or not the test failed because of it
+func countLoopsDetected(results []models.AgentTestResult) int {
+	loops := 0
+	for _, result := range results {
+		if result.Response != nil && result.Response.LoopDetected {
+			loops++
+		}
+	}
+	return loops
+}
+
+// percentile returns the nearest-rank percentile of sorted values
+func percentile(sorted []int64, p float64) int64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(p*float64(len(sorted))+0.5) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return sorted[rank]
+}
+
 // calculateMetrics calculates precision, recall, and F1 from confusion matrix values
 func calculateMetrics(tp, fp, tn, fn int) MetricSet {
 	var precision, recall, f1 float64
@@ -486,8 +1212,16 @@ func generateTextReport(report *BatchAnalysisReport) string {
 	sb.WriteString("Batch Analysis Report\n")
 	sb.WriteString("=====================\n")
 	sb.WriteString(fmt.Sprintf("Batch Directories: %s\n", strings.Join(report.BatchDirectories, ", ")))
+	if len(report.TagFilter) > 0 {
+		sb.WriteString(fmt.Sprintf("Tag Filter: %s\n", report.TagFilter))
+	}
+	if len(report.GroupBy) > 0 {
+		sb.WriteString(fmt.Sprintf("Grouped By: %s\n", strings.Join(report.GroupBy, ", ")))
+	}
 	sb.WriteString(fmt.Sprintf("Analysis Date: %s\n\n", report.AnalysisDate.Format("2006-01-02 15:04:05")))
 
+	sb.WriteString(generateIntegritySection(report.Integrity))
+
 	sb.WriteString("Model Performance Summary:\n")
 	sb.WriteString("--------------------------\n")
 
@@ -497,7 +1231,21 @@ func generateTextReport(report *BatchAnalysisReport) string {
 			sb.WriteString(fmt.Sprintf("  Batch Source: %s\n", model.BatchSource))
 		}
 		sb.WriteString(fmt.Sprintf("  Runs: %d, Tests: %d\n", model.TotalRuns, model.TotalTests))
+		if model.InfrastructureFailures != nil {
+			sb.WriteString(formatInfrastructureFailures(model.InfrastructureFailures))
+		}
 		sb.WriteString(fmt.Sprintf("  Average Response Time: %.2fs\n", model.AverageResponseTime))
+		if model.Latency.Tests > 0 {
+			sb.WriteString(fmt.Sprintf("    LLM: %.2fs, Tools: %.3fs, Harness: %.3fs (%d tests)\n",
+				model.Latency.LLMTime, model.Latency.ToolTime, model.Latency.HarnessOverhead, model.Latency.Tests))
+		}
+		if model.LatencyOverRun != nil {
+			sb.WriteString(formatLatencyOverRun(model.LatencyOverRun))
+		}
+		if tokens := model.TokenEfficiency; tokens != nil {
+			sb.WriteString(fmt.Sprintf("  Tokens per Test: %.0f input, %.0f output, %.0f total (%d tests)\n",
+				tokens.AvgInputTokens, tokens.AvgOutputTokens, tokens.AvgTotalTokens, tokens.Tests))
+		}
 		sb.WriteString("  Tool Invocation (Binary):\n")
 		sb.WriteString(fmt.Sprintf("    Precision: %.3f (%d/%d)\n",
 			model.ToolInvocation.Precision,
@@ -518,25 +1266,199 @@ func generateTextReport(report *BatchAnalysisReport) string {
 			model.ToolSelection.Recall,
 			model.ToolSelection.TruePositives,
 			model.ToolSelection.TruePositives+model.ToolSelection.FalseNegatives))
-		sb.WriteString(fmt.Sprintf("    F1: %.3f\n\n", model.ToolSelection.F1))
-	}
+		sb.WriteString(fmt.Sprintf("    F1: %.3f\n", model.ToolSelection.F1))
 
-	if len(report.Models) > 1 {
-		sb.WriteString("Overall Rankings (by Tool Selection F1):\n")
-		sb.WriteString("-----------------------------------------\n")
-		for i, model := range report.Models {
-			sb.WriteString(fmt.Sprintf("%d. %s (F1: %.3f)\n", i+1, model.ModelName, model.ToolSelection.F1))
+		if model.EnumCompliance.Checked > 0 {
+			sb.WriteString(fmt.Sprintf("  Enum Compliance: %.3f (%d/%d)\n",
+				model.EnumCompliance.Rate,
+				model.EnumCompliance.Compliant,
+				model.EnumCompliance.Checked))
+		}
+
+		if compliance := model.SchemaCompliance; compliance != nil {
+			sb.WriteString(fmt.Sprintf("  Schema Compliance: %.3f (%d/%d calls)\n",
+				compliance.Rate, compliance.ValidCalls, compliance.CheckedCalls))
+			kinds := make([]string, 0, len(compliance.ByKind))
+			for kind := range compliance.ByKind {
+				kinds = append(kinds, string(kind))
+			}
+			sort.Strings(kinds)
+			for _, kind := range kinds {
+				sb.WriteString(fmt.Sprintf("    %s: %d\n", kind, compliance.ByKind[models.SchemaViolationKind(kind)]))
+			}
+		}
+
+		if model.DenialCompliance != nil {
+			sb.WriteString(fmt.Sprintf("  Denial Compliance: %.3f (%d/%d)\n",
+				model.DenialCompliance.Rate,
+				model.DenialCompliance.Compliant,
+				model.DenialCompliance.TestsWithDenials))
+		}
+
+		if len(model.FailureReasons) > 0 {
+			reasons := make([]string, 0, len(model.FailureReasons))
+			for reason := range model.FailureReasons {
+				reasons = append(reasons, reason)
+			}
+			sort.Slice(reasons, func(i, j int) bool {
+				return model.FailureReasons[reasons[i]] > model.FailureReasons[reasons[j]] ||
+					(model.FailureReasons[reasons[i]] == model.FailureReasons[reasons[j]] && reasons[i] < reasons[j])
+			})
+			sb.WriteString("  Failure Reasons:\n")
+			for _, reason := range reasons {
+				sb.WriteString(fmt.Sprintf("    %s: %d\n", reason, model.FailureReasons[reason]))
+			}
+		}
+
+		if model.LoopsDetected > 0 {
+			sb.WriteString(fmt.Sprintf("  Loops Detected: %d/%d tests (%.1f%%)\n",
+				model.LoopsDetected, model.TotalTests, float64(model.LoopsDetected)/float64(model.TotalTests)*100))
+		}
+
+		if efficiency := model.CallEfficiency; efficiency != nil {
+			sb.WriteString(fmt.Sprintf("  Call Efficiency: %.3f (%d redundant tool calls in %d/%d tests)\n",
+				efficiency.MeanRatio, efficiency.RedundantCalls, efficiency.InefficientTests, efficiency.Tests))
+		}
+
+		sb.WriteString(formatCustomMetrics(model))
+
+		if len(model.PromptTokensByIteration) > 0 {
+			sb.WriteString("  Prompt Tokens by Iteration:\n")
+			for _, it := range model.PromptTokensByIteration {
+				sb.WriteString(fmt.Sprintf("    #%d: n=%d min=%d p50=%d mean=%.0f p95=%d max=%d",
+					it.Iteration, it.Samples, it.Min, it.P50, it.Mean, it.P95, it.Max))
+				if it.Estimated > 0 {
+					sb.WriteString(fmt.Sprintf(" (%d estimated)", it.Estimated))
+				}
+				sb.WriteString("\n")
+			}
 		}
 		sb.WriteString("\n")
 	}
 
+	if len(report.Models) > 1 {
+		sb.WriteString(generateRankingSection(report.Models, report.Tiers))
+	}
+
+	if report.Abilities != nil {
+		sb.WriteString(generateAbilitySection(report.Abilities))
+	}
+
+	if len(report.Models) > 0 {
+		sb.WriteString(generateScoringSection(report.Models))
+	}
+
+	sb.WriteString(generateTokenEfficiencySection(report.Models))
+	sb.WriteString(generateCallEfficiencySection(report.Models))
+	sb.WriteString(generateSamplingSection(report.Models))
+
+	if len(report.VariantCoverage) > 0 {
+		sb.WriteString(generateVariantCoverageSection(report.VariantCoverage))
+	}
+
+	// Pass rates and discrimination only say something about a test relative to other models
+	if report.Difficulty.Models > 1 {
+		sb.WriteString(generateCalibrationSection(report.Difficulty))
+	}
+
+	if len(report.ToolConfusion) > 0 {
+		sb.WriteString(generateToolConfusionSection(report.ToolConfusion))
+	}
+
+	if len(report.Models) > 0 {
+		sb.WriteString(generateToolCoverageSection(report.ToolCoverage))
+	}
+
 	sb.WriteString(report.Summary)
 
 	return sb.String()
 }
 
+// generateIntegritySection describes missing, corrupted, and incomplete runs
+func generateIntegritySection(integrity []BatchIntegrity) string {
+	var sb strings.Builder
+
+	sb.WriteString("Batch Integrity:\n")
+	sb.WriteString("----------------\n")
+	for _, check := range integrity {
+		status := "complete"
+		if !check.Complete {
+			status = "INCOMPLETE"
+		}
+		if !check.ManifestFound {
+			status += " (no manifest, run counts not verified)"
+		}
+		sb.WriteString(fmt.Sprintf("%s: %s\n", check.BatchDirectory, status))
+		if check.BatchID != "" {
+			sb.WriteString(fmt.Sprintf("  Batch ID: %s\n", check.BatchID))
+		}
+		if check.SuiteHash != "" {
+			sb.WriteString(fmt.Sprintf("  Suite Hash: %s\n", check.SuiteHash))
+		}
+
+		models := make([]string, 0, len(check.MissingRuns))
+		for model := range check.MissingRuns {
+			models = append(models, model)
+		}
+		sort.Strings(models)
+		for _, model := range models {
+			sb.WriteString(fmt.Sprintf("  Missing runs: %s (%d)\n", model, check.MissingRuns[model]))
+		}
+		for _, file := range check.CorruptedFiles {
+			sb.WriteString(fmt.Sprintf("  Corrupted (skipped): %s\n", file))
+		}
+		for _, file := range check.IncompleteFiles {
+			sb.WriteString(fmt.Sprintf("  Incomplete: %s\n", file))
+		}
+		if check.SignaturesVerified && len(check.UnsignedFiles)+len(check.ForeignKeyFiles)+len(check.TamperedFiles) == 0 {
+			sb.WriteString("  Signatures: all valid\n")
+		}
+		for _, file := range check.TamperedFiles {
+			sb.WriteString(fmt.Sprintf("  Tampered (skipped): %s\n", file))
+		}
+		for _, file := range check.ForeignKeyFiles {
+			sb.WriteString(fmt.Sprintf("  Signed with another key: %s\n", file))
+		}
+		for _, file := range check.UnsignedFiles {
+			sb.WriteString(fmt.Sprintf("  Unsigned: %s\n", file))
+		}
+	}
+	sb.WriteString("\n")
+
+	return sb.String()
+}
+
+// generateVariantCoverageSection lists how often each expected variant was matched and
+// flags variants that no model ever matched
+func generateVariantCoverageSection(coverage []TestCaseCoverage) string {
+	var sb strings.Builder
+
+	sb.WriteString("Expected Variant Coverage:\n")
+	sb.WriteString("--------------------------\n")
+
+	unmatched := 0
+	for _, testCase := range coverage {
+		sb.WriteString(fmt.Sprintf("%s (%d/%d passed):\n", testCase.TestCase, testCase.Passed, testCase.Runs))
+		for _, variant := range testCase.Variants {
+			marker := ""
+			if variant.Matches == 0 {
+				marker = "  <- never matched"
+				unmatched++
+			}
+			sb.WriteString(fmt.Sprintf("  %s: %d%s\n", variant.Name, variant.Matches, marker))
+		}
+	}
+
+	if unmatched > 0 {
+		sb.WriteString(fmt.Sprintf("\n%d variants were never matched by any model; check them for authoring mistakes or prune dead paths.\n", unmatched))
+	}
+	sb.WriteString("\n")
+
+	return sb.String()
+}
+
 // generateSummary generates a summary of the analysis
-func generateSummary(models []ModelAnalysis) string {
+func generateSummary(models []ModelAnalysis, tiers []LeaderboardTier) string {
 	if len(models) == 0 {
 		return "No models analyzed."
 	}
@@ -560,8 +1482,23 @@ func generateSummary(models []ModelAnalysis) string {
 		best := models[0]
 		sb.WriteString(fmt.Sprintf("Analyzed %d models with %d total tests across %d runs.\n",
 			len(models), totalTests, totalRuns))
-		sb.WriteString(fmt.Sprintf("Best performing model: %s (Tool Selection F1: %.3f)\n",
-			best.ModelName, best.ToolSelection.F1))
+		if len(tiers) > 0 && len(tiers[0].Models) > 1 {
+			sb.WriteString(fmt.Sprintf("Top tier (statistically tied): %s (best Tool Selection F1: %.3f)\n",
+				strings.Join(tiers[0].Models, ", "), best.ToolSelection.F1))
+		} else {
+			sb.WriteString(fmt.Sprintf("Best performing model: %s (Tool Selection F1: %.3f)\n",
+				best.ModelName, best.ToolSelection.F1))
+		}
+	}
+
+	excluded := 0
+	for _, model := range models {
+		if model.InfrastructureFailures != nil {
+			excluded += model.InfrastructureFailures.Tests
+		}
+	}
+	if excluded > 0 {
+		sb.WriteString(fmt.Sprintf("Excluded %d tests that failed against the server (API errors, timeouts) from model metrics.\n", excluded))
 	}
 
 	return sb.String()
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"model-test/services"
+)
+
+// exportResultsTable writes the results table of the analyzed result files, filled in
+// model and file order after the tag filter has been applied, as a Parquet file
+func exportResultsTable(filename string, table *services.ResultsTable) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("failed to create parquet file: %w", err)
+	}
+	defer f.Close()
+	if err := table.WriteParquet(f); err != nil {
+		return fmt.Errorf("failed to write parquet file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write parquet file: %w", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "Results table written to: %s (%d rows)\n", filename, table.Len())
+	return nil
+}
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"model-test/models"
+	"model-test/services"
+)
+
+// defaultPluginTimeout bounds metric plugin commands that do not set their own timeout
+const defaultPluginTimeout = 60 * time.Second
+
+// MetricPlugin computes bespoke metrics, e.g. domain-specific correctness, alongside the
+// built-in ones. ResultMetrics is called for every analyzed test result and BatchMetrics
+// once with all of a model's results; either may return an empty output.
+type MetricPlugin interface {
+	Name() string
+	ResultMetrics(model string, result models.AgentTestResult) (PluginOutput, error)
+	BatchMetrics(model string, results []models.AgentTestResult) (PluginOutput, error)
+}
+
+// PluginOutput is what a metric plugin reports for one result or for a model's results
+type PluginOutput struct {
+	Values   map[string]float64       `json:"values,omitempty"`   // Averaged over a model's results at result scope
+	Outcomes map[string]PluginOutcome `json:"outcomes,omitempty"` // Tallied into a MetricSet; result scope only
+}
+
+// PluginOutcome classifies one result for a plugin-defined precision/recall metric
+type PluginOutcome struct {
+	Expected bool `json:"expected"` // The result should be positive
+	Actual   bool `json:"actual"`   // The model's response was positive
+}
+
+// MetricPluginConfig lists the metric plugins run as subprocesses
+type MetricPluginConfig struct {
+	Plugins []CommandPluginConfig `json:"plugins"`
+}
+
+// CommandPluginConfig defines a metric plugin run through sh -c. The command reads a
+// JSON request on stdin and writes a PluginOutput as JSON on stdout.
+type CommandPluginConfig struct {
+	Name    string   `json:"name"`
+	Command string   `json:"command"`
+	Scopes  []string `json:"scopes,omitempty"`  // result and/or batch; defaults to both
+	Timeout string   `json:"timeout,omitempty"` // Go duration per invocation; defaults to 60s
+}
+
+// compiledPlugins holds the plugins built into the analyzer
+var compiledPlugins []MetricPlugin
+
+// RegisterMetricPlugin builds a plugin into the analyzer; call it from an init function
+// in a file added to this package
+func RegisterMetricPlugin(plugin MetricPlugin) {
+	compiledPlugins = append(compiledPlugins, plugin)
+}
+
+// loadMetricPlugins loads and validates the subprocess plugins of a config file and
+// returns them after the compiled-in plugins
+func loadMetricPlugins(filename string) ([]MetricPlugin, error) {
+	plugins := append([]MetricPlugin(nil), compiledPlugins...)
+	if filename == "" {
+		return plugins, nil
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metric plugins file: %w", err)
+	}
+	var config MetricPluginConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse metric plugins: %w", err)
+	}
+
+	names := make(map[string]bool)
+	for _, plugin := range plugins {
+		names[plugin.Name()] = true
+	}
+	for i, definition := range config.Plugins {
+		if definition.Name == "" {
+			return nil, fmt.Errorf("metric plugin %d has no name", i+1)
+		}
+		if names[definition.Name] {
+			return nil, fmt.Errorf("metric plugin '%s' is defined twice", definition.Name)
+		}
+		names[definition.Name] = true
+		if definition.Command == "" {
+			return nil, fmt.Errorf("metric plugin '%s' has no command", definition.Name)
+		}
+
+		plugin := &commandPlugin{config: definition, timeout: defaultPluginTimeout}
+		if definition.Timeout != "" {
+			plugin.timeout, err = time.ParseDuration(definition.Timeout)
+			if err != nil || plugin.timeout <= 0 {
+				return nil, fmt.Errorf("metric plugin '%s' has an invalid timeout '%s'", definition.Name, definition.Timeout)
+			}
+		}
+		scopes := definition.Scopes
+		if len(scopes) == 0 {
+			scopes = []string{"result", "batch"}
+		}
+		for _, scope := range scopes {
+			switch scope {
+			case "result":
+				plugin.perResult = true
+			case "batch":
+				plugin.perBatch = true
+			default:
+				return nil, fmt.Errorf("metric plugin '%s' has an unknown scope '%s' (expected result or batch)", definition.Name, scope)
+			}
+		}
+		plugins = append(plugins, plugin)
+	}
+	return plugins, nil
+}
+
+// commandPlugin is a metric plugin run as a subprocess
+type commandPlugin struct {
+	config    CommandPluginConfig
+	timeout   time.Duration
+	perResult bool
+	perBatch  bool
+}
+
+// pluginRequest is the JSON a command plugin receives on stdin
+type pluginRequest struct {
+	Scope   string                   `json:"scope"` // result or batch
+	Model   string                   `json:"model"`
+	Result  *models.AgentTestResult  `json:"result,omitempty"`
+	Results []models.AgentTestResult `json:"results,omitempty"`
+}
+
+// Name returns the plugin's configured name
+func (p *commandPlugin) Name() string {
+	return p.config.Name
+}
+
+// ResultMetrics runs the command for one result, unless the plugin is batch-only
+func (p *commandPlugin) ResultMetrics(model string, result models.AgentTestResult) (PluginOutput, error) {
+	if !p.perResult {
+		return PluginOutput{}, nil
+	}
+	return p.run(pluginRequest{Scope: "result", Model: model, Result: &result})
+}
+
+// BatchMetrics runs the command for all of a model's results, unless the plugin is
+// result-only
+func (p *commandPlugin) BatchMetrics(model string, results []models.AgentTestResult) (PluginOutput, error) {
+	if !p.perBatch {
+		return PluginOutput{}, nil
+	}
+	return p.run(pluginRequest{Scope: "batch", Model: model, Results: results})
+}
+
+// run sends a request to the command and decodes its output
+func (p *commandPlugin) run(request pluginRequest) (PluginOutput, error) {
+	input, err := json.Marshal(request)
+	if err != nil {
+		return PluginOutput{}, fmt.Errorf("failed to marshal plugin request: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := services.ShellCommand(ctx, p.config.Command)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.Env = append(os.Environ(), "MODEL_TEST_METRIC_SCOPE="+request.Scope, "MODEL_TEST_MODEL="+request.Model)
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return PluginOutput{}, fmt.Errorf("timed out after %v", p.timeout)
+		}
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return PluginOutput{}, fmt.Errorf("%w: %s", err, message)
+		}
+		return PluginOutput{}, err
+	}
+
+	var output PluginOutput
+	if err := json.Unmarshal(stdout.Bytes(), &output); err != nil {
+		return PluginOutput{}, fmt.Errorf("failed to parse plugin output: %w", err)
+	}
+	return output, nil
+}
+
+// applyMetricPlugins runs the plugins over the results an analysis was computed from
+// and merges their metrics into it, keyed plugin.metric. A plugin that fails is
+// reported and left out of that model's metrics.
+func applyMetricPlugins(analysis *ModelAnalysis, plugins []MetricPlugin, runsByFile fileResults) {
+	results := analysisResults(analysis, runsByFile)
+	if len(results) == 0 {
+		return
+	}
+
+	for _, plugin := range plugins {
+		values, sets, err := pluginMetrics(plugin, analysis.ModelName, results)
+		if err != nil {
+			log.Printf("Warning: metric plugin %s failed for model %s: %v", plugin.Name(), analysis.ModelName, err)
+			continue
+		}
+		for name, value := range values {
+			if analysis.CustomMetrics == nil {
+				analysis.CustomMetrics = make(map[string]float64)
+			}
+			analysis.CustomMetrics[plugin.Name()+"."+name] = value
+		}
+		for name, set := range sets {
+			if analysis.CustomMetricSets == nil {
+				analysis.CustomMetricSets = make(map[string]MetricSet)
+			}
+			analysis.CustomMetricSets[plugin.Name()+"."+name] = set
+		}
+	}
+}
+
+// pluginMetrics averages a plugin's per-result values over the results that report
+// them, tallies its per-result outcomes, and adds its batch values, which win over
+// per-result values of the same name
+func pluginMetrics(plugin MetricPlugin, model string, results []models.AgentTestResult) (map[string]float64, map[string]MetricSet, error) {
+	sums := make(map[string]float64)
+	counts := make(map[string]int)
+	tallies := make(map[string]*[4]int) // tp, fp, tn, fn
+	for _, result := range results {
+		output, err := plugin.ResultMetrics(model, result)
+		if err != nil {
+			return nil, nil, fmt.Errorf("test %s: %w", result.TestCase.Name, err)
+		}
+		for name, value := range output.Values {
+			sums[name] += value
+			counts[name]++
+		}
+		for name, outcome := range output.Outcomes {
+			tally := tallies[name]
+			if tally == nil {
+				tally = &[4]int{}
+				tallies[name] = tally
+			}
+			switch {
+			case outcome.Expected && outcome.Actual:
+				tally[0]++
+			case !outcome.Expected && outcome.Actual:
+				tally[1]++
+			case !outcome.Expected && !outcome.Actual:
+				tally[2]++
+			default:
+				tally[3]++
+			}
+		}
+	}
+
+	values := make(map[string]float64, len(sums))
+	for name, sum := range sums {
+		values[name] = sum / float64(counts[name])
+	}
+	sets := make(map[string]MetricSet, len(tallies))
+	for name, tally := range tallies {
+		sets[name] = calculateMetrics(tally[0], tally[1], tally[2], tally[3])
+	}
+
+	output, err := plugin.BatchMetrics(model, results)
+	if err != nil {
+		return nil, nil, fmt.Errorf("batch: %w", err)
+	}
+	for name, value := range output.Values {
+		values[name] = value
+	}
+	return values, sets, nil
+}
+
+// analysisResults returns the results an analysis was computed from: those of its
+// result files in its sampling config, without infrastructure failures
+func analysisResults(analysis *ModelAnalysis, runsByFile fileResults) []models.AgentTestResult {
+	var runs [][]models.AgentTestResult
+	for _, file := range analysis.ResultFiles {
+		runs = append(runs, runsByFile[file])
+	}
+	runs, _ = excludeInfrastructureFailures(runs)
+
+	var results []models.AgentTestResult
+	for _, run := range runs {
+		for _, result := range run {
+			if analysis.SamplingConfig == nil || result.Config.Label == analysis.SamplingConfig.Label {
+				results = append(results, result)
+			}
+		}
+	}
+	return results
+}
+
+// formatCustomMetrics lists a model's plugin metrics in name order
+func formatCustomMetrics(model ModelAnalysis) string {
+	if len(model.CustomMetrics)+len(model.CustomMetricSets) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("  Custom Metrics:\n")
+	names := make([]string, 0, len(model.CustomMetrics))
+	for name := range model.CustomMetrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		sb.WriteString(fmt.Sprintf("    %s: %.3f\n", name, model.CustomMetrics[name]))
+	}
+
+	names = names[:0]
+	for name := range model.CustomMetricSets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		set := model.CustomMetricSets[name]
+		sb.WriteString(fmt.Sprintf("    %s: precision %.3f, recall %.3f, F1 %.3f (tp=%d fp=%d tn=%d fn=%d)\n",
+			name, set.Precision, set.Recall, set.F1, set.TruePositives, set.FalsePositives, set.TrueNegatives, set.FalseNegatives))
+	}
+	return sb.String()
+}
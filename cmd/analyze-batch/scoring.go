@@ -0,0 +1,190 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"model-test/models"
+)
+
+// ScoringComparison scores a model's results twice: strictly, as the evaluator did,
+// and leniently, ignoring call order, arguments and extra calls
+type ScoringComparison struct {
+	StrictPassed  int     `json:"strict_passed"`
+	StrictRate    float64 `json:"strict_rate"`
+	LenientPassed int     `json:"lenient_passed"` // Tests whose calls include every tool of some expected variant
+	LenientRate   float64 `json:"lenient_rate"`
+	PartialCredit float64 `json:"partial_credit"` // Mean share of the closest variant's tools that were called
+	StrictRank    int     `json:"strict_rank"`
+	LenientRank   int     `json:"lenient_rank"`
+
+	// Partial-credit scores recorded by model-test -scoring partial
+	ScoredTests int     `json:"scored_tests,omitempty"`
+	MeanScore   float64 `json:"mean_score,omitempty"`
+}
+
+// calculateScoringComparison computes strict and lenient scores over one model's results
+func calculateScoringComparison(results []models.AgentTestResult) ScoringComparison {
+	var scoring ScoringComparison
+	if len(results) == 0 {
+		return scoring
+	}
+
+	totalCredit, totalScore := 0.0, 0.0
+	for _, result := range results {
+		if result.PartialScore != nil {
+			totalScore += result.PartialScore.Score
+			scoring.ScoredTests++
+		}
+
+		credit := 1.0
+		if !result.Success {
+			credit = lenientCredit(result.TestCase, getActualTools(result.Response))
+		} else {
+			scoring.StrictPassed++
+		}
+		if credit == 1.0 {
+			scoring.LenientPassed++
+		}
+		totalCredit += credit
+	}
+
+	scoring.StrictRate = float64(scoring.StrictPassed) / float64(len(results))
+	scoring.LenientRate = float64(scoring.LenientPassed) / float64(len(results))
+	scoring.PartialCredit = totalCredit / float64(len(results))
+	if scoring.ScoredTests > 0 {
+		scoring.MeanScore = totalScore / float64(scoring.ScoredTests)
+	}
+
+	return scoring
+}
+
+// lenientCredit returns the best share of any variant's expected tools found among
+// the actual calls, counting repeated tools separately. A test that expects no tools
+// earns full credit only when none were called.
+func lenientCredit(testCase models.TestCase, actualTools []string) float64 {
+	if !shouldCallAnyTool(testCase) {
+		if len(actualTools) == 0 {
+			return 1.0
+		}
+		return 0.0
+	}
+
+	best := 0.0
+	for _, variant := range testCase.ExpectedToolVariants {
+		if len(variant.Tools) == 0 {
+			if len(actualTools) == 0 {
+				return 1.0
+			}
+			continue
+		}
+
+		available := make(map[string]int)
+		for _, tool := range actualTools {
+			available[tool]++
+		}
+		found := 0
+		for _, expected := range variant.Tools {
+			if available[expected.Name] > 0 {
+				available[expected.Name]--
+				found++
+			}
+		}
+
+		if credit := float64(found) / float64(len(variant.Tools)); credit > best {
+			best = credit
+		}
+	}
+	return best
+}
+
+// assignScoringRanks ranks the models under both scoring modes
+func assignScoringRanks(analyses []ModelAnalysis) {
+	strict := rankByRate(analyses, func(m ModelAnalysis) float64 { return m.Scoring.StrictRate })
+	lenient := rankByRate(analyses, func(m ModelAnalysis) float64 { return m.Scoring.LenientRate })
+	for i := range analyses {
+		analyses[i].Scoring.StrictRank = strict[i]
+		analyses[i].Scoring.LenientRank = lenient[i]
+	}
+}
+
+// rankByRate returns each model's rank by descending rate. Models with equal rates
+// share a rank (1, 2, 2, 4).
+func rankByRate(analyses []ModelAnalysis, rate func(ModelAnalysis) float64) []int {
+	order := make([]int, len(analyses))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return rate(analyses[order[i]]) > rate(analyses[order[j]])
+	})
+
+	ranks := make([]int, len(analyses))
+	for position, index := range order {
+		if position > 0 && rate(analyses[index]) == rate(analyses[order[position-1]]) {
+			ranks[index] = ranks[order[position-1]]
+			continue
+		}
+		ranks[index] = position + 1
+	}
+	return ranks
+}
+
+// generateScoringSection prints strict and lenient scores side by side, flagging
+// models whose rank depends on the matching strictness
+func generateScoringSection(analyses []ModelAnalysis) string {
+	var sb strings.Builder
+
+	sb.WriteString("Strict vs Lenient Scoring:\n")
+	sb.WriteString("---------------------------\n")
+	sb.WriteString(fmt.Sprintf("%-30s %8s %8s %8s %6s %6s\n", "Model", "Strict", "Lenient", "Partial", "S.Rank", "L.Rank"))
+
+	rankChanges := 0
+	for _, model := range analyses {
+		scoring := model.Scoring
+		marker := ""
+		if scoring.StrictRank != scoring.LenientRank {
+			marker = " *"
+			rankChanges++
+		}
+		sb.WriteString(fmt.Sprintf("%-30s %8.3f %8.3f %8.3f %6d %6d%s\n", model.ModelName,
+			scoring.StrictRate, scoring.LenientRate, scoring.PartialCredit,
+			scoring.StrictRank, scoring.LenientRank, marker))
+	}
+
+	if len(analyses) > 1 {
+		if rankChanges == 0 {
+			sb.WriteString("Rankings are the same under both scoring modes.\n")
+		} else {
+			sb.WriteString(fmt.Sprintf("* %d of %d models change rank when scoring leniently.\n", rankChanges, len(analyses)))
+		}
+	}
+	sb.WriteString("\n")
+
+	sb.WriteString(generateRecordedScoresSection(analyses))
+
+	return sb.String()
+}
+
+// generateRecordedScoresSection prints the mean partial-credit score recorded in the
+// result files of each model run with -scoring partial; empty when no model was
+func generateRecordedScoresSection(analyses []ModelAnalysis) string {
+	var sb strings.Builder
+	for _, model := range analyses {
+		scoring := model.Scoring
+		if scoring.ScoredTests == 0 {
+			continue
+		}
+		if sb.Len() == 0 {
+			sb.WriteString("Recorded Partial Scores:\n")
+			sb.WriteString("------------------------\n")
+			sb.WriteString(fmt.Sprintf("%-30s %8s %8s\n", "Model", "Score", "Tests"))
+		}
+		sb.WriteString(fmt.Sprintf("%-30s %8.3f %8d\n", model.ModelName, scoring.MeanScore, scoring.ScoredTests))
+	}
+	if sb.Len() > 0 {
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"model-test/models"
+)
+
+// analyzeModelBySamplingConfig analyzes the runs of a model's result files, splitting
+// results from a sampling sweep into one analysis per sampling config so configurations
+// are ranked alongside models. Runs without a sweep produce a single analysis.
+func analyzeModelBySamplingConfig(modelName string, runs [][]models.AgentTestResult, files []string, batchSource string) ([]ModelAnalysis, error) {
+	configs := make(map[string]models.TestConfig)
+	for _, results := range runs {
+		for _, result := range results {
+			configs[result.Config.Label] = result.Config
+		}
+	}
+
+	if len(configs) < 2 {
+		analysis, err := analyzeRuns(modelName, runs, files, batchSource)
+		if err != nil {
+			return nil, err
+		}
+		for label, config := range configs {
+			if label != "" {
+				analysis.SamplingConfig = &config
+			}
+		}
+		return []ModelAnalysis{*analysis}, nil
+	}
+
+	labels := make([]string, 0, len(configs))
+	for label := range configs {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+
+	var analyses []ModelAnalysis
+	for _, label := range labels {
+		var configRuns [][]models.AgentTestResult
+		var configFiles []string
+		for i, results := range runs {
+			var matching []models.AgentTestResult
+			for _, result := range results {
+				if result.Config.Label == label {
+					matching = append(matching, result)
+				}
+			}
+			if len(matching) > 0 {
+				configRuns = append(configRuns, matching)
+				configFiles = append(configFiles, files[i])
+			}
+		}
+
+		name := modelName
+		if label != "" {
+			name = fmt.Sprintf("%s [%s]", modelName, label)
+		}
+		analysis, err := analyzeRuns(name, configRuns, configFiles, batchSource)
+		if err != nil {
+			return nil, err
+		}
+		if label != "" {
+			config := configs[label]
+			analysis.SamplingConfig = &config
+		}
+		analyses = append(analyses, *analysis)
+	}
+
+	return analyses, nil
+}
+
+// generateSamplingSection compares the sampling configs of each model that ran a
+// sweep, best tool selection F1 first
+func generateSamplingSection(analyses []ModelAnalysis) string {
+	byModel := make(map[string][]ModelAnalysis)
+	var modelNames []string
+	for _, analysis := range analyses {
+		if analysis.SamplingConfig == nil {
+			continue
+		}
+		baseName := strings.TrimSuffix(analysis.ModelName, " ["+analysis.SamplingConfig.Label+"]")
+		if _, exists := byModel[baseName]; !exists {
+			modelNames = append(modelNames, baseName)
+		}
+		byModel[baseName] = append(byModel[baseName], analysis)
+	}
+
+	var sb strings.Builder
+	for _, modelName := range modelNames {
+		configs := byModel[modelName]
+		if len(configs) < 2 {
+			continue
+		}
+		if sb.Len() == 0 {
+			sb.WriteString("Sampling Configurations:\n")
+			sb.WriteString("------------------------\n")
+		}
+
+		sb.WriteString(fmt.Sprintf("%s:\n", modelName))
+		for _, config := range configs {
+			sb.WriteString(fmt.Sprintf("  %-45s F1: %.3f  Strict: %.1f%%  Tier %d  Avg Response: %.2fs\n",
+				config.SamplingConfig.Label, config.ToolSelection.F1, config.Scoring.StrictRate*100, config.Tier, config.AverageResponseTime))
+		}
+	}
+	if sb.Len() > 0 {
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
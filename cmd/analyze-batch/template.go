@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"os"
+	"path/filepath"
+	"strings"
+	texttemplate "text/template"
+)
+
+// sectionFuncs expose the sections of the built-in text report to -template
+// reports, so a template can reuse any of them
+var sectionFuncs = map[string]interface{}{
+	"join": strings.Join,
+
+	"textReport":             generateTextReport,
+	"integritySection":       generateIntegritySection,
+	"rankingSection":         generateRankingSection,
+	"scoringSection":         generateScoringSection,
+	"samplingSection":        generateSamplingSection,
+	"tokenEfficiencySection": generateTokenEfficiencySection,
+	"callEfficiencySection":  generateCallEfficiencySection,
+	"variantCoverageSection": generateVariantCoverageSection,
+	"toolConfusionSection":   generateToolConfusionSection,
+	"toolCoverageSection":    generateToolCoverageSection,
+	"latencyOverRun":         formatLatencyOverRun,
+}
+
+// templateFuncs combines the report sections with the locale's formatting helpers
+func templateFuncs(locale *reportLocale) map[string]interface{} {
+	funcs := locale.funcs()
+	for name, fn := range sectionFuncs {
+		funcs[name] = fn
+	}
+	return funcs
+}
+
+// renderTemplate renders the report with a user-provided Go template. Templates
+// named *.html or *.htm are parsed with html/template so report values are escaped;
+// any other file (text, Markdown) uses text/template.
+func renderTemplate(filename string, report *BatchAnalysisReport, locale *reportLocale) (string, error) {
+	source, err := os.ReadFile(filename)
+	if err != nil {
+		return "", fmt.Errorf("failed to read template: %w", err)
+	}
+
+	funcs := templateFuncs(locale)
+	var out bytes.Buffer
+	name := filepath.Base(filename)
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".html", ".htm":
+		tmpl, err := htmltemplate.New(name).Funcs(funcs).Parse(string(source))
+		if err != nil {
+			return "", fmt.Errorf("failed to parse template: %w", err)
+		}
+		err = tmpl.Execute(&out, report)
+		if err != nil {
+			return "", fmt.Errorf("failed to render template: %w", err)
+		}
+	default:
+		tmpl, err := texttemplate.New(name).Funcs(funcs).Parse(string(source))
+		if err != nil {
+			return "", fmt.Errorf("failed to parse template: %w", err)
+		}
+		err = tmpl.Execute(&out, report)
+		if err != nil {
+			return "", fmt.Errorf("failed to render template: %w", err)
+		}
+	}
+
+	return out.String(), nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+
+	"model-test/models"
+)
+
+const (
+	// bootstrapSamples is how many resamples estimate the standard error of a model's F1
+	bootstrapSamples = 1000
+
+	// tierZScore is the two-sided 95% critical value used to decide whether two models differ
+	tierZScore = 1.96
+)
+
+// LeaderboardTier groups models whose tool selection F1 scores cannot be told apart
+// at 95% confidence
+type LeaderboardTier struct {
+	Tier   int      `json:"tier"`
+	Models []string `json:"models"`
+	TopF1  float64  `json:"top_f1"`
+}
+
+// bootstrapF1StdErr estimates the standard error of the tool selection F1 by
+// resampling the results with replacement. The generator is seeded so repeated
+// analyses of the same files agree.
+func bootstrapF1StdErr(results []models.AgentTestResult) float64 {
+	if len(results) < 2 {
+		return 0
+	}
+
+	outcomes := make([]selectionOutcome, len(results))
+	for i, result := range results {
+		outcomes[i] = classifyToolSelection(result)
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	var sum, sumSquares float64
+	for sample := 0; sample < bootstrapSamples; sample++ {
+		var counts [4]int
+		for range outcomes {
+			counts[outcomes[rng.Intn(len(outcomes))]]++
+		}
+		f1 := calculateMetrics(counts[truePositive], counts[falsePositive], counts[trueNegative], counts[falseNegative]).F1
+		sum += f1
+		sumSquares += f1 * f1
+	}
+
+	mean := sum / bootstrapSamples
+	variance := (sumSquares - bootstrapSamples*mean*mean) / (bootstrapSamples - 1)
+	if variance < 0 {
+		return 0
+	}
+	return math.Sqrt(variance)
+}
+
+// assignTiers groups models, already sorted by F1 descending, into tiers. Each tier
+// is led by its best model; the following models join it while their F1 is within
+// tierZScore standard errors of the leader's, so adjacent tiers are separated by a
+// significant gap rather than by noise.
+func assignTiers(analyses []ModelAnalysis) []LeaderboardTier {
+	var tiers []LeaderboardTier
+	leader := -1
+
+	for i := range analyses {
+		if leader >= 0 && !significantlyBetter(analyses[leader], analyses[i]) {
+			analyses[i].Tier = len(tiers)
+			tiers[len(tiers)-1].Models = append(tiers[len(tiers)-1].Models, analyses[i].ModelName)
+			continue
+		}
+
+		leader = i
+		tiers = append(tiers, LeaderboardTier{
+			Tier:   len(tiers) + 1,
+			Models: []string{analyses[i].ModelName},
+			TopF1:  analyses[i].ToolSelection.F1,
+		})
+		analyses[i].Tier = len(tiers)
+	}
+
+	return tiers
+}
+
+// significantlyBetter reports whether the first model's F1 exceeds the second's by
+// more than the combined uncertainty of both
+func significantlyBetter(first, second ModelAnalysis) bool {
+	margin := tierZScore * math.Sqrt(first.ToolSelectionF1StdErr*first.ToolSelectionF1StdErr+
+		second.ToolSelectionF1StdErr*second.ToolSelectionF1StdErr)
+	return first.ToolSelection.F1-second.ToolSelection.F1 > margin
+}
+
+// generateRankingSection prints the leaderboard grouped into significance tiers
+func generateRankingSection(analyses []ModelAnalysis, tiers []LeaderboardTier) string {
+	var sb strings.Builder
+
+	sb.WriteString("Overall Rankings (by Tool Selection F1, tiered at 95% confidence):\n")
+	sb.WriteString("------------------------------------------------------------------\n")
+
+	index := 0
+	for _, tier := range tiers {
+		label := fmt.Sprintf("Tier %d", tier.Tier)
+		if len(tier.Models) > 1 {
+			label += fmt.Sprintf(" (%d models, statistically tied)", len(tier.Models))
+		}
+		sb.WriteString(label + ":\n")
+		for range tier.Models {
+			model := analyses[index]
+			sb.WriteString(fmt.Sprintf("  %s (F1: %.3f ± %.3f, %d tests)\n",
+				model.ModelName, model.ToolSelection.F1, tierZScore*model.ToolSelectionF1StdErr, model.TotalTests))
+			index++
+		}
+	}
+	sb.WriteString("\n")
+
+	return sb.String()
+}
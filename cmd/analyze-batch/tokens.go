@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"model-test/models"
+)
+
+// TokenEfficiency summarizes how many tokens a model consumed per test and per
+// passed test, from the usage recorded in each response
+type TokenEfficiency struct {
+	Tests               int     `json:"tests"` // Tests with token usage; tests that got no response have none
+	AvgInputTokens      float64 `json:"avg_input_tokens"`
+	AvgOutputTokens     float64 `json:"avg_output_tokens"`
+	AvgTotalTokens      float64 `json:"avg_total_tokens"`
+	TokensPerPassedTest float64 `json:"tokens_per_passed_test,omitempty"` // All tokens spent divided by the tests that passed
+	EstimatedTests      int     `json:"estimated_tests,omitempty"`        // Tests with estimated input tokens
+}
+
+// calculateTokenEfficiency averages the token usage of one model's results, or
+// returns nil when none recorded usage
+func calculateTokenEfficiency(results []models.AgentTestResult) *TokenEfficiency {
+	var total models.TokenUsage
+	efficiency := &TokenEfficiency{}
+	passed := 0
+
+	for _, result := range results {
+		if result.Response == nil || len(result.Response.Iterations) == 0 {
+			continue
+		}
+		usage := models.IterationUsage(result.Response.Iterations)
+		if result.Response.Usage != nil {
+			usage = *result.Response.Usage
+		}
+
+		efficiency.Tests++
+		total.Add(usage)
+		if usage.Estimated {
+			efficiency.EstimatedTests++
+		}
+		if result.Success {
+			passed++
+		}
+	}
+	if efficiency.Tests == 0 {
+		return nil
+	}
+
+	count := float64(efficiency.Tests)
+	efficiency.AvgInputTokens = float64(total.InputTokens) / count
+	efficiency.AvgOutputTokens = float64(total.OutputTokens) / count
+	efficiency.AvgTotalTokens = float64(total.TotalTokens) / count
+	if passed > 0 {
+		efficiency.TokensPerPassedTest = float64(total.TotalTokens) / float64(passed)
+	}
+	return efficiency
+}
+
+// generateTokenEfficiencySection compares the models' token consumption, most
+// efficient first by tokens per passed test
+func generateTokenEfficiencySection(analyses []ModelAnalysis) string {
+	var withTokens []ModelAnalysis
+	for _, model := range analyses {
+		if model.TokenEfficiency != nil {
+			withTokens = append(withTokens, model)
+		}
+	}
+	if len(withTokens) == 0 {
+		return ""
+	}
+	sort.SliceStable(withTokens, func(i, j int) bool {
+		a, b := withTokens[i].TokenEfficiency.TokensPerPassedTest, withTokens[j].TokenEfficiency.TokensPerPassedTest
+		if (a == 0) != (b == 0) {
+			return b == 0 // Models that passed nothing go last
+		}
+		return a < b
+	})
+
+	var sb strings.Builder
+	sb.WriteString("Token Efficiency (per test):\n")
+	sb.WriteString("----------------------------\n")
+	sb.WriteString(fmt.Sprintf("%-30s %10s %10s %10s %12s\n", "Model", "Input", "Output", "Total", "Per Passed"))
+
+	estimated := 0
+	for _, model := range withTokens {
+		efficiency := model.TokenEfficiency
+		perPassed := "-"
+		if efficiency.TokensPerPassedTest > 0 {
+			perPassed = fmt.Sprintf("%.0f", efficiency.TokensPerPassedTest)
+		}
+		marker := ""
+		if efficiency.EstimatedTests > 0 {
+			marker = " ~"
+			estimated++
+		}
+		sb.WriteString(fmt.Sprintf("%-30s %10.0f %10.0f %10.0f %12s%s\n", model.ModelName,
+			efficiency.AvgInputTokens, efficiency.AvgOutputTokens, efficiency.AvgTotalTokens, perPassed, marker))
+	}
+	if estimated > 0 {
+		sb.WriteString("~ Input tokens partly estimated because the backend did not report usage.\n")
+	}
+	sb.WriteString("\n")
+
+	return sb.String()
+}
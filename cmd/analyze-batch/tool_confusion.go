@@ -0,0 +1,318 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"model-test/models"
+	"model-test/tools"
+)
+
+// maxConfusionPairs caps how many confusable pairs the text report lists
+const maxConfusionPairs = 10
+
+// ToolConfusion counts how often two tools were substituted for each other across
+// all models, with suggestions for making them easier to tell apart
+type ToolConfusion struct {
+	ToolA       string   `json:"tool_a"`
+	ToolB       string   `json:"tool_b"`
+	AInsteadOfB int      `json:"a_instead_of_b"` // Model called A where B was expected
+	BInsteadOfA int      `json:"b_instead_of_a"` // Model called B where A was expected
+	Total       int      `json:"total"`
+	Models      []string `json:"models"`
+	Suggestions []string `json:"suggestions,omitempty"`
+}
+
+// toolSpec is the part of a tool definition used for naming and description feedback
+type toolSpec struct {
+	name        string
+	description string
+}
+
+// descriptionStopWords are ignored when comparing tool descriptions
+var descriptionStopWords = map[string]bool{
+	"a": true, "an": true, "the": true, "for": true, "to": true, "of": true, "and": true,
+	"or": true, "by": true, "in": true, "on": true, "from": true, "with": true, "current": true,
+}
+
+// confusionTally counts the tools substituted for one another in failed tests, by
+// pair of tools, with the models that substituted them
+type confusionTally struct {
+	pairs      map[[2]string]*ToolConfusion
+	pairModels map[[2]string]map[string]bool
+}
+
+// newConfusionTally returns an empty confusion tally
+func newConfusionTally() *confusionTally {
+	return &confusionTally{pairs: make(map[[2]string]*ToolConfusion), pairModels: make(map[[2]string]map[string]bool)}
+}
+
+// add aligns each failed test's tool calls in one of a model's result files with its
+// closest expected variant and counts the substituted tools
+func (t *confusionTally) add(modelName string, results []models.AgentTestResult) {
+	for _, result := range results {
+		if result.Success || result.Response == nil || len(result.TestCase.ExpectedToolVariants) == 0 {
+			continue
+		}
+
+		actual := getActualTools(result.Response)
+		expected := closestVariantTools(result.TestCase, actual)
+		for _, substitution := range alignSubstitutions(expected, actual) {
+			wanted, called := substitution[0], substitution[1]
+			if wanted == called {
+				continue
+			}
+
+			key := [2]string{wanted, called}
+			if key[0] > key[1] {
+				key = [2]string{called, wanted}
+			}
+			pair, exists := t.pairs[key]
+			if !exists {
+				pair = &ToolConfusion{ToolA: key[0], ToolB: key[1]}
+				t.pairs[key] = pair
+				t.pairModels[key] = make(map[string]bool)
+			}
+
+			if called == pair.ToolA {
+				pair.AInsteadOfB++
+			} else {
+				pair.BInsteadOfA++
+			}
+			pair.Total++
+			t.pairModels[key][modelName] = true
+		}
+	}
+}
+
+// calculateToolConfusion lists the tallied pairs of confused tools, most confused
+// first, with suggestions for telling them apart
+func calculateToolConfusion(tally *confusionTally) []ToolConfusion {
+	pairs, pairModels := tally.pairs, tally.pairModels
+
+	specs := make(map[string]toolSpec)
+	for _, definition := range tools.NewShoppingTools().GetToolDefinitions() {
+		specs[definition.Function.Name] = toolSpec{
+			name:        definition.Function.Name,
+			description: definition.Function.Description.Value,
+		}
+	}
+
+	var confusions []ToolConfusion
+	for key, pair := range pairs {
+		for model := range pairModels[key] {
+			pair.Models = append(pair.Models, model)
+		}
+		sort.Strings(pair.Models)
+		pair.Suggestions = suggestToolDisambiguation(*pair, specs)
+		confusions = append(confusions, *pair)
+	}
+
+	sort.Slice(confusions, func(i, j int) bool {
+		if confusions[i].Total != confusions[j].Total {
+			return confusions[i].Total > confusions[j].Total
+		}
+		return confusions[i].ToolA+confusions[i].ToolB < confusions[j].ToolA+confusions[j].ToolB
+	})
+
+	return confusions
+}
+
+// closestVariantTools returns the tool names of the expected variant that shares the
+// longest common subsequence with the actual calls
+func closestVariantTools(testCase models.TestCase, actual []string) []string {
+	var best []string
+	bestScore := -1
+
+	for _, variant := range testCase.ExpectedToolVariants {
+		var expected []string
+		for _, tool := range variant.Tools {
+			expected = append(expected, tool.Name)
+		}
+
+		score := len(longestCommonSubsequence(expected, actual))*100 - absInt(len(expected)-len(actual))
+		if score > bestScore {
+			best, bestScore = expected, score
+		}
+	}
+
+	return best
+}
+
+// alignSubstitutions aligns expected and actual tool sequences on their longest common
+// subsequence and pairs up the expected and actual calls left between the anchors.
+// Each result is {expected tool, actual tool}.
+func alignSubstitutions(expected, actual []string) [][2]string {
+	var substitutions [][2]string
+	anchors := longestCommonSubsequence(expected, actual)
+
+	e, a := 0, 0
+	for _, anchor := range append(anchors, [2]int{len(expected), len(actual)}) {
+		for e < anchor[0] && a < anchor[1] {
+			substitutions = append(substitutions, [2]string{expected[e], actual[a]})
+			e++
+			a++
+		}
+		e, a = anchor[0]+1, anchor[1]+1
+	}
+
+	return substitutions
+}
+
+// longestCommonSubsequence returns the index pairs {expected, actual} of a longest
+// common subsequence of the two sequences
+func longestCommonSubsequence(expected, actual []string) [][2]int {
+	lengths := make([][]int, len(expected)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(actual)+1)
+	}
+	for i := len(expected) - 1; i >= 0; i-- {
+		for j := len(actual) - 1; j >= 0; j-- {
+			if expected[i] == actual[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else {
+				lengths[i][j] = max(lengths[i+1][j], lengths[i][j+1])
+			}
+		}
+	}
+
+	var anchors [][2]int
+	for i, j := 0, 0; i < len(expected) && j < len(actual); {
+		switch {
+		case expected[i] == actual[j]:
+			anchors = append(anchors, [2]int{i, j})
+			i++
+			j++
+		case lengths[i+1][j] >= lengths[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+
+	return anchors
+}
+
+// suggestToolDisambiguation proposes naming and description changes for a confused pair
+func suggestToolDisambiguation(pair ToolConfusion, specs map[string]toolSpec) []string {
+	var suggestions []string
+
+	a, knownA := specs[pair.ToolA]
+	b, knownB := specs[pair.ToolB]
+	if !knownA || !knownB {
+		return []string{"One of these tools is not in the current catalog; check the suite for renamed or removed tools"}
+	}
+
+	if shared := sharedWords(strings.Split(a.name, "_"), strings.Split(b.name, "_")); len(shared) > 0 {
+		suggestions = append(suggestions, fmt.Sprintf(
+			"Names %s and %s share %q; lead with the part that differs so the names are not read as variants of one tool",
+			a.name, b.name, strings.Join(shared, "_")))
+	}
+
+	wordsA, wordsB := descriptionWords(a.description), descriptionWords(b.description)
+	if overlap := jaccard(wordsA, wordsB); overlap >= 0.3 {
+		suggestions = append(suggestions, fmt.Sprintf(
+			"Descriptions share %.0f%% of their words (%s); state what each does that the other does not",
+			overlap*100, strings.Join(sharedWords(wordsA, wordsB), ", ")))
+	}
+
+	// A strongly one-sided confusion points at the tool whose description under-sells it
+	if pair.AInsteadOfB >= 2*pair.BInsteadOfA && pair.AInsteadOfB > 0 {
+		suggestions = append(suggestions, fmt.Sprintf(
+			"Models reach for %s when %s is intended; make %s's description say when it must be used instead of %s",
+			pair.ToolA, pair.ToolB, pair.ToolB, pair.ToolA))
+	} else if pair.BInsteadOfA >= 2*pair.AInsteadOfB && pair.BInsteadOfA > 0 {
+		suggestions = append(suggestions, fmt.Sprintf(
+			"Models reach for %s when %s is intended; make %s's description say when it must be used instead of %s",
+			pair.ToolB, pair.ToolA, pair.ToolA, pair.ToolB))
+	}
+
+	if len(suggestions) == 0 {
+		suggestions = append(suggestions, fmt.Sprintf(
+			"Add an explicit \"use %s instead of %s when ...\" sentence to both descriptions", pair.ToolA, pair.ToolB))
+	}
+
+	return suggestions
+}
+
+// descriptionWords returns the distinct lowercase content words of a description
+func descriptionWords(description string) []string {
+	seen := make(map[string]bool)
+	var words []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(description), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9')
+	}) {
+		if descriptionStopWords[word] || seen[word] {
+			continue
+		}
+		seen[word] = true
+		words = append(words, word)
+	}
+	return words
+}
+
+// sharedWords returns the words present in both lists, in the order of the first
+func sharedWords(first, second []string) []string {
+	present := make(map[string]bool)
+	for _, word := range second {
+		present[word] = true
+	}
+
+	var shared []string
+	for _, word := range first {
+		if present[word] && !descriptionStopWords[word] {
+			shared = append(shared, word)
+		}
+	}
+	return shared
+}
+
+// jaccard returns the Jaccard similarity of two word sets
+func jaccard(first, second []string) float64 {
+	union := make(map[string]bool)
+	for _, word := range first {
+		union[word] = true
+	}
+	for _, word := range second {
+		union[word] = true
+	}
+	if len(union) == 0 {
+		return 0
+	}
+	return float64(len(sharedWords(first, second))) / float64(len(union))
+}
+
+// absInt returns the absolute value of an integer
+func absInt(value int) int {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
+
+// generateToolConfusionSection lists the most confused tool pairs with design suggestions
+func generateToolConfusionSection(confusions []ToolConfusion) string {
+	var sb strings.Builder
+
+	sb.WriteString("Confusable Tool Pairs:\n")
+	sb.WriteString("----------------------\n")
+
+	for i, pair := range confusions {
+		if i == maxConfusionPairs {
+			sb.WriteString(fmt.Sprintf("... %d more pairs in the JSON report\n", len(confusions)-maxConfusionPairs))
+			break
+		}
+		sb.WriteString(fmt.Sprintf("%s <-> %s: %d substitutions (%s for %s: %d, %s for %s: %d) across %d models\n",
+			pair.ToolA, pair.ToolB, pair.Total,
+			pair.ToolA, pair.ToolB, pair.AInsteadOfB,
+			pair.ToolB, pair.ToolA, pair.BInsteadOfA,
+			len(pair.Models)))
+		for _, suggestion := range pair.Suggestions {
+			sb.WriteString(fmt.Sprintf("  - %s\n", suggestion))
+		}
+	}
+	sb.WriteString("\n")
+
+	return sb.String()
+}
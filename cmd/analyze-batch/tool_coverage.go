@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"model-test/models"
+	"model-test/tools"
+)
+
+// ToolCoverage shows which tools of the catalog the suite's expected variants and the
+// models' calls reach, to find gaps in the benchmark design
+type ToolCoverage struct {
+	Tools         []ToolUsage `json:"tools"`             // Catalog tools, then tools outside the catalog
+	NeverExpected []string    `json:"never_expected"`    // Catalog tools no test case expects
+	NeverCalled   []string    `json:"never_called"`      // Catalog tools no model called
+	Unknown       []string    `json:"unknown,omitempty"` // Expected or called tools missing from the catalog
+}
+
+// ToolUsage counts the test cases expecting a tool and the calls models made to it
+type ToolUsage struct {
+	Name       string   `json:"name"`
+	InCatalog  bool     `json:"in_catalog"`
+	ExpectedBy int      `json:"expected_by"` // Test cases with at least one expected variant calling the tool
+	Calls      int      `json:"calls"`       // Calls across all models and runs
+	CalledBy   []string `json:"called_by"`   // Models that called the tool at least once
+}
+
+// coverageTally counts the test cases expecting each tool and the calls models made
+// to it
+type coverageTally struct {
+	usage      map[string]*ToolUsage
+	catalog    []string
+	expectedBy map[string]map[string]bool // Tool -> test cases expecting it
+	calledBy   map[string]map[string]bool // Tool -> models calling it
+}
+
+// newCoverageTally returns a coverage tally over the tool catalog
+func newCoverageTally() *coverageTally {
+	tally := &coverageTally{
+		usage:      make(map[string]*ToolUsage),
+		expectedBy: make(map[string]map[string]bool),
+		calledBy:   make(map[string]map[string]bool),
+	}
+	for _, definition := range tools.NewShoppingTools().GetToolDefinitions() {
+		name := definition.Function.Name
+		tally.catalog = append(tally.catalog, name)
+		tally.use(name).InCatalog = true
+	}
+	return tally
+}
+
+// use returns the usage of a tool, adding it when it is new
+func (t *coverageTally) use(name string) *ToolUsage {
+	tool, exists := t.usage[name]
+	if !exists {
+		tool = &ToolUsage{Name: name}
+		t.usage[name] = tool
+	}
+	return tool
+}
+
+// add counts the expected and called tools in one of a model's result files
+func (t *coverageTally) add(modelName string, results []models.AgentTestResult) {
+	for _, result := range results {
+		for _, variant := range result.TestCase.ExpectedToolVariants {
+			for _, expected := range variant.Tools {
+				if t.expectedBy[expected.Name] == nil {
+					t.expectedBy[expected.Name] = make(map[string]bool)
+				}
+				t.expectedBy[expected.Name][result.TestCase.Name] = true
+			}
+		}
+
+		if result.Response == nil {
+			continue
+		}
+		for _, toolCall := range result.Response.ToolCalls {
+			t.use(toolCall.ToolName).Calls++
+			if t.calledBy[toolCall.ToolName] == nil {
+				t.calledBy[toolCall.ToolName] = make(map[string]bool)
+			}
+			t.calledBy[toolCall.ToolName][modelName] = true
+		}
+	}
+}
+
+// calculateToolCoverage counts, for every tool in the catalog and every other tool the
+// analyzed test cases expect or the models called, the test cases expecting it and the
+// calls made to it
+func calculateToolCoverage(tally *coverageTally) ToolCoverage {
+	usage, use, catalog := tally.usage, tally.use, tally.catalog
+	expectedBy, calledBy := tally.expectedBy, tally.calledBy
+
+	for name, testCases := range expectedBy {
+		use(name).ExpectedBy = len(testCases)
+	}
+	for name, modelNames := range calledBy {
+		tool := use(name)
+		for model := range modelNames {
+			tool.CalledBy = append(tool.CalledBy, model)
+		}
+		sort.Strings(tool.CalledBy)
+	}
+
+	var coverage ToolCoverage
+	for _, name := range catalog {
+		tool := usage[name]
+		if tool.ExpectedBy == 0 {
+			coverage.NeverExpected = append(coverage.NeverExpected, name)
+		}
+		if tool.Calls == 0 {
+			coverage.NeverCalled = append(coverage.NeverCalled, name)
+		}
+		coverage.Tools = append(coverage.Tools, *tool)
+	}
+
+	for name, tool := range usage {
+		if !tool.InCatalog {
+			coverage.Unknown = append(coverage.Unknown, name)
+		}
+	}
+	sort.Strings(coverage.Unknown)
+	for _, name := range coverage.Unknown {
+		coverage.Tools = append(coverage.Tools, *usage[name])
+	}
+
+	return coverage
+}
+
+// generateToolCoverageSection lists how many test cases expect each catalog tool and
+// how often models called it, flagging tools the suite or the models never reach
+func generateToolCoverageSection(coverage ToolCoverage) string {
+	var sb strings.Builder
+
+	sb.WriteString("Tool Catalog Coverage:\n")
+	sb.WriteString("----------------------\n")
+	sb.WriteString(fmt.Sprintf("%-28s %12s %8s %8s\n", "Tool", "Expected By", "Calls", "Models"))
+	for _, tool := range coverage.Tools {
+		var markers []string
+		if !tool.InCatalog {
+			markers = append(markers, "not in catalog")
+		} else {
+			if tool.ExpectedBy == 0 {
+				markers = append(markers, "never expected")
+			}
+			if tool.Calls == 0 {
+				markers = append(markers, "never called")
+			}
+		}
+		marker := ""
+		if len(markers) > 0 {
+			marker = "  <- " + strings.Join(markers, ", ")
+		}
+		sb.WriteString(fmt.Sprintf("%-28s %12d %8d %8d%s\n", tool.Name, tool.ExpectedBy, tool.Calls, len(tool.CalledBy), marker))
+	}
+
+	if len(coverage.NeverExpected) > 0 {
+		sb.WriteString(fmt.Sprintf("\n%d catalog tools are never expected by any test case (%s); add test cases for them or drop them from the catalog.\n",
+			len(coverage.NeverExpected), strings.Join(coverage.NeverExpected, ", ")))
+	}
+	if len(coverage.NeverCalled) > 0 {
+		sb.WriteString(fmt.Sprintf("%d catalog tools were never called by any model (%s).\n",
+			len(coverage.NeverCalled), strings.Join(coverage.NeverCalled, ", ")))
+	}
+	if len(coverage.Unknown) > 0 {
+		sb.WriteString(fmt.Sprintf("%d tools are expected or called but missing from the current catalog (%s); check the suite for renamed or removed tools.\n",
+			len(coverage.Unknown), strings.Join(coverage.Unknown, ", ")))
+	}
+	sb.WriteString("\n")
+
+	return sb.String()
+}
@@ -0,0 +1,601 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"model-test/models"
+	"model-test/services"
+)
+
+// ModelOutcome records what the orchestrator did for one requested model
+type ModelOutcome struct {
+	Requested      string                         `json:"requested"`
+	Cluster        string                         `json:"cluster,omitempty"`      // Cluster from -clusters the model ran on
+	CatalogName    string                         `json:"catalog_name,omitempty"` // m_name used for deployment lookup
+	FilePrefix     string                         `json:"file_prefix"`
+	DeployConfig   *services.KamiwazaDeployConfig `json:"deploy_config,omitempty"` // Deployment options requested for this row
+	Engine         string                         `json:"engine,omitempty"`        // Engine the deployment reported
+	Tags           models.RunTags                 `json:"tags,omitempty"`          // Passed to model-test for every run
+	Pulled         bool                           `json:"pulled"`
+	Deployed       bool                           `json:"deployed"` // Deployed by this batch (and undeployed unless -keep-deployed)
+	DeploymentID   string                         `json:"deployment_id,omitempty"`
+	Undeployed     bool                           `json:"undeployed"`
+	Runs           int                            `json:"runs"`
+	SuccessfulRuns int                            `json:"successful_runs"`
+	Error          string                         `json:"error,omitempty"`
+}
+
+// BatchManifest is the batch description read by analyze-batch, extended with the
+// orchestration steps taken for each model
+type BatchManifest struct {
+	BatchID         string                `json:"batch_id"`
+	CreatedAt       string                `json:"created_at"`
+	ConfigFile      string                `json:"config_file"`
+	SuiteHash       string                `json:"suite_hash"`
+	RunsPerModel    int                   `json:"runs_per_model"`
+	TestCasesPerRun int                   `json:"test_cases_per_run"`
+	Models          []ManifestModel       `json:"models"`
+	KamiwazaURL     string                `json:"kamiwaza_url"`
+	Clusters        []*models.ClusterInfo `json:"clusters,omitempty"` // Clusters from -clusters, with their metadata
+	TestCaseFilter  string                `json:"test_case_filter,omitempty"`
+	Orchestration   []ModelOutcome        `json:"orchestration"`
+}
+
+// ManifestModel is a model expected in the batch and the prefix of its result files
+type ManifestModel struct {
+	Name       string `json:"name"`
+	FilePrefix string `json:"file_prefix"`
+}
+
+// runOptions configures how each model is pulled, deployed and tested
+type runOptions struct {
+	hub             string
+	downloadTimeout time.Duration
+	deployTimeout   time.Duration
+	keepDeployed    bool
+	redeployStuck   bool
+	modelTest       string
+	kamiwazaURL     string
+	clustersFile    string
+	proxy           string
+	signKeyFile     string
+	signingKey      []byte
+	configFile      string
+	runs            int
+	extraArgs       []string
+	batchDir        string
+}
+
+// clusterTarget is a Kamiwaza cluster the batch runs every model on
+type clusterTarget struct {
+	kamiwaza *services.KamiwazaService
+	cluster  *models.KamiwazaCluster // Nil when targeting -kamiwaza-url
+}
+
+// unsafeFileChars matches the characters test-all-models.sh replaces in file prefixes
+var unsafeFileChars = regexp.MustCompile(`[^a-zA-Z0-9._-]`)
+
+func main() {
+	var (
+		kamiwazaURL     = flag.String("kamiwaza-url", "https://localhost", "Kamiwaza base URL")
+		clustersFile    = flag.String("kamiwaza-clusters", "config/kamiwaza_clusters.json", "Path to the named Kamiwaza clusters -clusters chooses from")
+		clusterList     = flag.String("clusters", "", "Comma-separated clusters from -kamiwaza-clusters to run every model on instead of -kamiwaza-url; each is a separate leaderboard row")
+		modelList       = flag.String("models", "", "Comma-separated catalog names or hub repository IDs to test (e.g. Qwen/Qwen3-8B-GGUF)")
+		hub             = flag.String("hub", "hf", "Model hub to search and pull from")
+		search          = flag.String("search", "", "Search the hub catalog for this query, print the matches and exit")
+		downloadTimeout = flag.Duration("download-timeout", 2*time.Hour, "How long to wait for a model download")
+		deployTimeout   = flag.Duration("deploy-timeout", 20*time.Minute, "How long to wait for a deployment to become active")
+		keepDeployed    = flag.Bool("keep-deployed", false, "Leave models deployed by this batch running afterwards")
+		redeployStuck   = flag.Bool("redeploy-stuck", false, "Have model-test redeploy a deployment that stops responding mid-run (within -deploy-timeout) and rerun the affected tests")
+		modelTest       = flag.String("model-test", "./model-test", "Path to the model-test binary")
+		configFile      = flag.String("config", "config/test_cases.json", "Test cases configuration file passed to model-test")
+		runs            = flag.Int("runs", 1, "Number of model-test runs per model")
+		pollInterval    = flag.Duration("poll-interval", 10*time.Second, "How often to check download and deployment progress")
+		engines         = flag.String("engine", "", "Comma-separated serving engines to deploy with (e.g. llamacpp,vllm); each is a separate leaderboard row")
+		quantizations   = flag.String("quantization", "", "Comma-separated quantizations to deploy (e.g. Q4_K_M,Q8_0), matched against model file names; each is a separate leaderboard row")
+		contextLengths  = flag.String("context-length", "", "Comma-separated context lengths to deploy with (e.g. 8192,32768); each is a separate leaderboard row")
+		gpus            = flag.String("gpus", "", "Comma-separated GPU indices to deploy on (e.g. 0,1)")
+		signKeyFile     = flag.String("sign-key-file", "", "Path to a shared secret that signs the batch manifest and, forwarded to model-test, every results file and run manifest (verify with analyze-batch -verify-key-file)")
+		proxy           = flag.String("proxy", "", "Proxy for the Kamiwaza API and model-test's requests: an http, https, socks5 or socks5h URL, or direct to bypass the proxy environment variables (clusters may set their own)")
+	)
+	flag.Parse()
+
+	if err := services.ValidateProxy(*proxy); err != nil {
+		log.Fatalf("Invalid -proxy: %v", err)
+	}
+
+	var signingKey []byte
+	if *signKeyFile != "" {
+		key, err := services.LoadSigningKey(*signKeyFile)
+		if err != nil {
+			log.Fatalf("Invalid -sign-key-file: %v", err)
+		}
+		signingKey = key
+	}
+
+	targets := []clusterTarget{{kamiwaza: services.NewKamiwazaService(*kamiwazaURL)}}
+	if names := splitList(*clusterList); len(names) > 0 {
+		clusterConfig, err := services.LoadKamiwazaClusters(*clustersFile)
+		if err != nil {
+			log.Fatalf("Failed to load Kamiwaza clusters: %v", err)
+		}
+		targets = nil
+		for _, name := range names {
+			cluster, err := services.FindKamiwazaCluster(clusterConfig, name)
+			if err != nil {
+				log.Fatalf("Invalid -clusters: %v", err)
+			}
+			kamiwaza, err := services.NewKamiwazaServiceForCluster(*cluster)
+			if err != nil {
+				log.Fatalf("Failed to configure Kamiwaza cluster: %v", err)
+			}
+			targets = append(targets, clusterTarget{kamiwaza: kamiwaza, cluster: cluster})
+		}
+	}
+	for _, target := range targets {
+		target.kamiwaza.SetPollInterval(*pollInterval)
+		if target.cluster == nil || target.cluster.Proxy == "" {
+			target.kamiwaza.SetProxy(*proxy)
+		}
+	}
+	kamiwaza := targets[0].kamiwaza
+
+	if *search != "" {
+		results, err := kamiwaza.SearchCatalog(*search, *hub)
+		if err != nil {
+			log.Fatalf("Failed to search catalog: %v", err)
+		}
+		fmt.Printf("🔎 %d catalog matches for %q:\n", len(results), *search)
+		for _, result := range results {
+			fmt.Printf("   %s (%s, %d files)\n", result.Model.RepoModelID, result.Model.Hub, len(result.Files))
+		}
+		return
+	}
+
+	var requested []string
+	for _, name := range strings.Split(*modelList, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			requested = append(requested, name)
+		}
+	}
+	deployConfigs, err := expandDeployConfigs(*engines, *quantizations, *contextLengths, *gpus)
+	if err != nil {
+		log.Fatalf("Invalid deployment options: %v", err)
+	}
+
+	if len(requested) == 0 || *runs < 1 {
+		fmt.Fprintf(os.Stderr, "Usage: %s -models <name,...> [options] [-- model-test flags]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "\nPull, deploy, test and undeploy each model on a Kamiwaza cluster.\n")
+		fmt.Fprintf(os.Stderr, "\nOptions:\n")
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+
+	batchID, err := services.NewRunID()
+	if err != nil {
+		log.Fatalf("Failed to generate batch ID: %v", err)
+	}
+	batchDir := filepath.Join("results", "batch_test_"+batchID)
+	if err := os.MkdirAll(batchDir, 0755); err != nil {
+		log.Fatalf("Failed to create batch directory: %v", err)
+	}
+
+	options := runOptions{
+		hub:             *hub,
+		downloadTimeout: *downloadTimeout,
+		deployTimeout:   *deployTimeout,
+		keepDeployed:    *keepDeployed,
+		redeployStuck:   *redeployStuck,
+		modelTest:       *modelTest,
+		kamiwazaURL:     *kamiwazaURL,
+		clustersFile:    *clustersFile,
+		proxy:           *proxy,
+		signKeyFile:     *signKeyFile,
+		signingKey:      signingKey,
+		configFile:      *configFile,
+		runs:            *runs,
+		extraArgs:       flag.Args(),
+		batchDir:        batchDir,
+	}
+
+	switch {
+	case len(targets) > 1:
+		fmt.Printf("🚀 Kamiwaza batch %s: %d clusters × %d models × %d deployment configs, %d runs each\n\n", batchID, len(targets), len(requested), len(deployConfigs), *runs)
+	case len(deployConfigs) > 1:
+		fmt.Printf("🚀 Kamiwaza batch %s: %d models × %d deployment configs, %d runs each\n\n", batchID, len(requested), len(deployConfigs), *runs)
+	default:
+		fmt.Printf("🚀 Kamiwaza batch %s: %d models, %d runs each\n\n", batchID, len(requested), *runs)
+	}
+
+	var outcomes []ModelOutcome
+	total := len(targets) * len(requested) * len(deployConfigs)
+	for _, target := range targets {
+		if target.cluster != nil {
+			fmt.Printf("🌐 Cluster %s (%s)\n\n", target.cluster.Name, target.cluster.URL)
+		}
+		for _, name := range requested {
+			for _, config := range deployConfigs {
+				if config.IsDefault() {
+					fmt.Printf("📦 Model %d/%d: %s\n", len(outcomes)+1, total, name)
+				} else {
+					fmt.Printf("📦 Model %d/%d: %s [%s]\n", len(outcomes)+1, total, name, config.Tags())
+				}
+				outcome := runModel(target, name, config, options)
+				if outcome.Error != "" {
+					fmt.Printf("   ❌ %s\n", outcome.Error)
+				}
+				outcomes = append(outcomes, outcome)
+				fmt.Println()
+			}
+		}
+	}
+
+	manifestFile := filepath.Join(batchDir, "batch_manifest.json")
+	if err := saveBatchManifest(manifestFile, batchID, options, targets, outcomes); err != nil {
+		log.Fatalf("Failed to save batch manifest: %v", err)
+	}
+
+	failed := 0
+	fmt.Println("📊 Batch Summary:")
+	for _, outcome := range outcomes {
+		status := "✅"
+		if outcome.Error != "" || outcome.SuccessfulRuns < outcome.Runs {
+			status = "❌"
+			failed++
+		}
+		fmt.Printf("   %s %s: %d/%d runs", status, outcomeName(outcome), outcome.SuccessfulRuns, options.runs)
+		if outcome.Pulled {
+			fmt.Printf(", pulled")
+		}
+		if outcome.Deployed {
+			fmt.Printf(", deployed")
+		}
+		if outcome.Undeployed {
+			fmt.Printf(", undeployed")
+		}
+		fmt.Println()
+	}
+	fmt.Printf("\n💾 Results saved to: %s\n", batchDir)
+
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// runModel makes a model available, deploys it with the given options if needed, runs
+// the suite against it and undeploys it again. Deployments that already existed are
+// reused and left running, unless deployment options were requested: the options of
+// an existing deployment are unknown, so it could not be recorded faithfully.
+func runModel(target clusterTarget, name string, config services.KamiwazaDeployConfig, options runOptions) ModelOutcome {
+	kamiwaza := target.kamiwaza
+	outcome := ModelOutcome{Requested: name, FilePrefix: filePrefix(name, config, target.cluster), Tags: config.Tags()}
+	if !config.IsDefault() {
+		outcome.DeployConfig = &config
+	}
+	if target.cluster != nil {
+		outcome.Cluster = target.cluster.Name
+		outcome.Tags["cluster"] = target.cluster.Name
+	}
+
+	existing, err := kamiwaza.FindModel(name)
+	if err != nil {
+		outcome.Error = err.Error()
+		return outcome
+	}
+	if existing == nil || !existing.DownloadedFor(config.Quantization) {
+		fmt.Printf("   ⬇️  Pulling from %s (timeout %v)\n", options.hub, options.downloadTimeout)
+		outcome.Pulled = true
+	}
+
+	model, err := kamiwaza.EnsureModelDownloaded(name, options.hub, config.Quantization, options.downloadTimeout)
+	if err != nil {
+		outcome.Error = err.Error()
+		return outcome
+	}
+	outcome.CatalogName = model.Name
+
+	deployment, err := kamiwaza.GetDeploymentByModelName(model.Name)
+	if err == nil {
+		if !config.IsDefault() {
+			outcome.Error = fmt.Sprintf("%s is already deployed (%s) with unknown options; undeploy it to test deployment options", model.Name, deployment.ID)
+			return outcome
+		}
+		fmt.Printf("   ♻️  Using existing deployment %s\n", deployment.ID)
+	} else {
+		fmt.Printf("   🚢 Deploying %s (timeout %v)\n", model.Name, options.deployTimeout)
+		outcome.DeploymentID, err = kamiwaza.DeployModel(model, config)
+		if err != nil {
+			outcome.Error = err.Error()
+			return outcome
+		}
+		outcome.Deployed = true
+
+		deployment, err = kamiwaza.WaitForDeployment(model.Name, options.deployTimeout)
+		if err != nil {
+			outcome.Error = err.Error()
+			undeploy(kamiwaza, &outcome, options)
+			return outcome
+		}
+		outcome.DeploymentID = deployment.ID
+	}
+
+	outcome.Engine = deployment.Engine
+	if _, exists := outcome.Tags["engine"]; !exists && deployment.Engine != "" {
+		outcome.Tags["engine"] = deployment.Engine
+	}
+
+	for run := 1; run <= options.runs; run++ {
+		outcome.Runs++
+		fmt.Printf("   🔄 Run %d/%d\n", run, options.runs)
+		if err := runModelTest(model.Name, outcome.FilePrefix, outcome.Tags, target.cluster, run, options); err != nil {
+			fmt.Printf("   ❌ Run %d failed: %v\n", run, err)
+			continue
+		}
+		outcome.SuccessfulRuns++
+	}
+
+	undeploy(kamiwaza, &outcome, options)
+	return outcome
+}
+
+// undeploy removes a deployment this batch created, unless asked to keep it. A
+// deployment model-test replaced with -redeploy is looked up by model name.
+func undeploy(kamiwaza *services.KamiwazaService, outcome *ModelOutcome, options runOptions) {
+	if !outcome.Deployed || options.keepDeployed || outcome.DeploymentID == "" {
+		return
+	}
+	if current, err := kamiwaza.GetDeploymentByModelName(outcome.CatalogName); err == nil && current.ID != outcome.DeploymentID {
+		fmt.Printf("   🔁 Deployment was replaced during the runs: %s -> %s\n", outcome.DeploymentID, current.ID)
+		outcome.DeploymentID = current.ID
+	}
+	if err := kamiwaza.UndeployModel(outcome.DeploymentID); err != nil {
+		fmt.Printf("   ⚠️  %v\n", err)
+		if outcome.Error == "" {
+			outcome.Error = err.Error()
+		}
+		return
+	}
+	outcome.Undeployed = true
+	fmt.Printf("   🧹 Undeployed %s\n", outcome.DeploymentID)
+}
+
+// runModelTest runs model-test once against the deployed model on the given cluster,
+// tagged with its deployment options, and moves its result files and repro bundles into the batch
+// directory under the model's file prefix
+func runModelTest(catalogName, filePrefix string, tags models.RunTags, cluster *models.KamiwazaCluster, run int, options runOptions) error {
+	runID, err := services.NewRunID()
+	if err != nil {
+		return fmt.Errorf("failed to generate run ID: %w", err)
+	}
+
+	logFile, err := os.Create(filepath.Join(options.batchDir, fmt.Sprintf("%s_run%d_test.log", filePrefix, run)))
+	if err != nil {
+		return fmt.Errorf("failed to create run log: %w", err)
+	}
+	defer logFile.Close()
+
+	args := []string{"-provider", "kamiwaza"}
+	if cluster != nil {
+		args = append(args, "-kamiwaza-clusters", options.clustersFile, "-kamiwaza-cluster", cluster.Name)
+	} else {
+		args = append(args, "-kamiwaza-url", options.kamiwazaURL)
+	}
+	args = append(args,
+		"-kamiwaza-model", catalogName,
+		"-config", options.configFile,
+		"-run-id", runID,
+	)
+	if options.proxy != "" {
+		args = append(args, "-proxy", options.proxy)
+	}
+	if options.signKeyFile != "" {
+		args = append(args, "-sign-key-file", options.signKeyFile)
+	}
+	for key, value := range tags {
+		args = append(args, "-tag", key+"="+value)
+	}
+	if options.redeployStuck {
+		args = append(args, "-redeploy", "-watchdog-timeout", options.deployTimeout.String())
+	}
+	args = append(args, options.extraArgs...)
+
+	cmd := exec.Command(options.modelTest, args...)
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	runErr := cmd.Run()
+
+	files, err := filepath.Glob(filepath.Join("results", "*_"+runID+".json"))
+	if err != nil {
+		return fmt.Errorf("failed to find result files: %w", err)
+	}
+	signatures, _ := filepath.Glob(filepath.Join("results", "*_"+runID+".json.sig"))
+	files = append(files, signatures...)
+	reproDirs, _ := filepath.Glob(filepath.Join("results", "repro_*_"+runID))
+	files = append(files, reproDirs...)
+	for _, file := range files {
+		target := filepath.Join(options.batchDir, filePrefix+"_"+filepath.Base(file))
+		if err := os.Rename(file, target); err != nil {
+			return fmt.Errorf("failed to move %s: %w", file, err)
+		}
+	}
+
+	if runErr != nil {
+		return fmt.Errorf("model-test failed: %w (see %s)", runErr, logFile.Name())
+	}
+	return nil
+}
+
+// saveBatchManifest writes the manifest analyze-batch uses to check the batch is complete
+func saveBatchManifest(filename, batchID string, options runOptions, targets []clusterTarget, outcomes []ModelOutcome) error {
+	manifest := BatchManifest{
+		BatchID:       batchID,
+		CreatedAt:     time.Now().UTC().Format(time.RFC3339),
+		ConfigFile:    options.configFile,
+		RunsPerModel:  options.runs,
+		KamiwazaURL:   options.kamiwazaURL,
+		Orchestration: outcomes,
+	}
+
+	for _, target := range targets {
+		if target.cluster != nil {
+			manifest.Clusters = append(manifest.Clusters, target.cluster.Info())
+		}
+	}
+
+	if data, err := os.ReadFile(options.configFile); err == nil {
+		sum := sha256.Sum256(data)
+		manifest.SuiteHash = hex.EncodeToString(sum[:])
+	}
+
+	// A test case selection or tag filter passed through to model-test changes what a
+	// complete run holds
+	manifest.TestCaseFilter = passedFlag(options.extraArgs, "test-case")
+	includeTags := services.ParseTagList(passedFlag(options.extraArgs, "tags"))
+	excludeTags := services.ParseTagList(passedFlag(options.extraArgs, "exclude-tags"))
+	if suite, err := services.LoadTestSuite(options.configFile); err == nil {
+		testCases := suite.TestCases
+		if manifest.TestCaseFilter != "" {
+			testCases, err = services.SelectTestCases(testCases, manifest.TestCaseFilter)
+		}
+		if err == nil && (len(includeTags) > 0 || len(excludeTags) > 0) {
+			testCases, err = services.FilterTestCasesByTags(testCases, includeTags, excludeTags)
+		}
+		if err == nil {
+			manifest.TestCasesPerRun = len(testCases)
+		}
+	}
+
+	for _, outcome := range outcomes {
+		manifest.Models = append(manifest.Models, ManifestModel{Name: outcomeName(outcome), FilePrefix: outcome.FilePrefix})
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal batch manifest: %w", err)
+	}
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		return err
+	}
+	return services.SignFile(filename, options.signingKey)
+}
+
+// passedFlag returns the value of a flag in the arguments passed through to
+// model-test, written as -name value, -name=value or with two dashes
+func passedFlag(args []string, name string) string {
+	var value string
+	for i, arg := range args {
+		flagName := strings.TrimLeft(arg, "-")
+		if flagValue, ok := strings.CutPrefix(flagName, name+"="); ok {
+			value = flagValue
+		} else if flagName == name && i+1 < len(args) {
+			value = args[i+1]
+		}
+	}
+	return value
+}
+
+// expandDeployConfigs combines the comma-separated engines, quantizations and context
+// lengths into one deployment config per combination, all on the same GPUs
+func expandDeployConfigs(engines, quantizations, contextLengths, gpus string) ([]services.KamiwazaDeployConfig, error) {
+	var lengths []int
+	for _, value := range splitList(contextLengths) {
+		length, err := strconv.Atoi(value)
+		if err != nil || length <= 0 {
+			return nil, fmt.Errorf("invalid context length %q", value)
+		}
+		lengths = append(lengths, length)
+	}
+	if len(lengths) == 0 {
+		lengths = []int{0}
+	}
+
+	for _, value := range splitList(gpus) {
+		if _, err := strconv.Atoi(value); err != nil {
+			return nil, fmt.Errorf("invalid GPU index %q", value)
+		}
+	}
+
+	var configs []services.KamiwazaDeployConfig
+	for _, engine := range orDefault(splitList(engines)) {
+		for _, quantization := range orDefault(splitList(quantizations)) {
+			for _, length := range lengths {
+				configs = append(configs, services.KamiwazaDeployConfig{
+					Engine:        engine,
+					Quantization:  quantization,
+					ContextLength: length,
+					GPUs:          strings.Join(splitList(gpus), ","),
+				})
+			}
+		}
+	}
+	return configs, nil
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries
+func splitList(value string) []string {
+	var values []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			values = append(values, item)
+		}
+	}
+	return values
+}
+
+// orDefault returns the values, or a single empty value standing for the cluster default
+func orDefault(values []string) []string {
+	if len(values) == 0 {
+		return []string{""}
+	}
+	return values
+}
+
+// filePrefix builds the result file prefix of a model deployed with the given options
+// on the given cluster, so each deployment config and cluster is analyzed as its own model
+func filePrefix(name string, config services.KamiwazaDeployConfig, cluster *models.KamiwazaCluster) string {
+	parts := []string{name}
+	if config.Engine != "" {
+		parts = append(parts, config.Engine)
+	}
+	if config.Quantization != "" {
+		parts = append(parts, config.Quantization)
+	}
+	if config.ContextLength > 0 {
+		parts = append(parts, fmt.Sprintf("ctx%d", config.ContextLength))
+	}
+	if config.GPUs != "" {
+		parts = append(parts, "gpu"+strings.ReplaceAll(config.GPUs, ",", "-"))
+	}
+	if cluster != nil {
+		parts = append(parts, cluster.Name)
+	}
+	return unsafeFileChars.ReplaceAllString(strings.Join(parts, "_"), "_")
+}
+
+// outcomeName names a model in the manifest and summary, including its deployment
+// options and cluster
+func outcomeName(outcome ModelOutcome) string {
+	var labels []string
+	if outcome.DeployConfig != nil {
+		labels = append(labels, outcome.DeployConfig.Tags().String())
+	}
+	if outcome.Cluster != "" {
+		labels = append(labels, "cluster="+outcome.Cluster)
+	}
+	if len(labels) == 0 {
+		return outcome.Requested
+	}
+	return fmt.Sprintf("%s [%s]", outcome.Requested, strings.Join(labels, ","))
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+
+	"model-test/models"
+	"model-test/services"
+)
+
+// selection picks the results to export
+type selection struct {
+	testCase string // Glob pattern; empty selects every test case
+	failed   bool   // Only results that failed
+}
+
+// unsafeFileChars are replaced in the names of exported files
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+func main() {
+	var (
+		testCase = flag.String("test-case", "", "Only export results of test cases matching this name or glob pattern (e.g. \"complex_*\")")
+		failed   = flag.Bool("failed", false, "Only export results that failed")
+		format   = flag.String("format", "markdown", "Export format: markdown or html")
+		output   = flag.String("output", "transcripts", "Directory to write one file per result into, or - to write every result as one document to stdout")
+	)
+	flag.Parse()
+
+	if len(flag.Args()) < 1 {
+		fmt.Fprintf(os.Stderr, "Usage: %s [options] <result_file_or_directory> ...\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "\nExport test transcripts (conversation, tool calls, tool results and timings) as Markdown\n")
+		fmt.Fprintf(os.Stderr, "or HTML for bug reports.\n")
+		fmt.Fprintf(os.Stderr, "\nOptions:\n")
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+	if *format != "markdown" && *format != "html" {
+		log.Fatalf("Invalid -format %q: expected markdown or html", *format)
+	}
+	if _, err := path.Match(*testCase, ""); err != nil {
+		log.Fatalf("Invalid -test-case: %v", err)
+	}
+	filter := selection{testCase: *testCase, failed: *failed}
+
+	files, err := services.FindResultFiles(flag.Args())
+	if err != nil {
+		log.Fatalf("Failed to find result files: %v", err)
+	}
+	if len(files) == 0 {
+		log.Fatalf("No result files found in: %v", flag.Args())
+	}
+
+	var transcripts []transcriptView
+	for _, file := range files {
+		report, err := services.LoadAgentReport(file)
+		if err != nil {
+			log.Printf("Warning: skipping %s: %v", file, err)
+			continue
+		}
+		for _, result := range report.Results {
+			if filter.selects(result) {
+				transcripts = append(transcripts, buildTranscriptView(file, report, result))
+			}
+		}
+	}
+	if len(transcripts) == 0 {
+		log.Fatalf("No results matched")
+	}
+
+	if *output == "-" {
+		if err := render(os.Stdout, *format, transcripts); err != nil {
+			log.Fatalf("Failed to render transcripts: %v", err)
+		}
+		return
+	}
+
+	written, err := writeTranscripts(*output, *format, transcripts)
+	if err != nil {
+		log.Fatalf("Failed to export transcripts: %v", err)
+	}
+	fmt.Printf("📝 Exported %d transcripts to %s\n", written, *output)
+}
+
+// selects reports whether the selection picks the result
+func (s selection) selects(result models.AgentTestResult) bool {
+	if s.failed && result.Success {
+		return false
+	}
+	if s.testCase != "" {
+		if matched, _ := path.Match(s.testCase, result.TestCase.Name); !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// writeTranscripts writes each transcript to its own file in dir, returning how many
+// were written
+func writeTranscripts(dir, format string, transcripts []transcriptView) (int, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return 0, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	extension := ".md"
+	if format == "html" {
+		extension = ".html"
+	}
+	used := make(map[string]int)
+	for _, transcript := range transcripts {
+		name := transcriptFileName(transcript)
+		used[name]++
+		if used[name] > 1 {
+			name = fmt.Sprintf("%s_%d", name, used[name])
+		}
+
+		var out bytes.Buffer
+		if err := render(&out, format, []transcriptView{transcript}); err != nil {
+			return 0, fmt.Errorf("failed to render %s: %w", transcript.Title, err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name+extension), out.Bytes(), 0644); err != nil {
+			return 0, fmt.Errorf("failed to write transcript: %w", err)
+		}
+	}
+	return len(transcripts), nil
+}
+
+// transcriptFileName names a transcript's file after its model, test case, run and
+// sampling label
+func transcriptFileName(transcript transcriptView) string {
+	name := transcript.Model + "_" + transcript.TestCase
+	if transcript.Run > 0 {
+		name += fmt.Sprintf("_run%d", transcript.Run)
+	}
+	if transcript.Label != "" {
+		name += "_" + transcript.Label
+	}
+	return unsafeFileChars.ReplaceAllString(name, "_")
+}
+
+// prettyJSON indents text that is JSON and returns any other text unchanged
+func prettyJSON(text string) string {
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, []byte(text), "", "  "); err != nil {
+		return text
+	}
+	return indented.String()
+}
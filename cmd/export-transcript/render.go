@@ -0,0 +1,139 @@
+package main
+
+import (
+	htmltemplate "html/template"
+	"io"
+	"regexp"
+	"strings"
+	texttemplate "text/template"
+)
+
+// backtickRuns finds runs of backticks, which a Markdown code fence must outdo
+var backtickRuns = regexp.MustCompile("`+")
+
+// render writes the transcripts as one Markdown or HTML document
+func render(w io.Writer, format string, transcripts []transcriptView) error {
+	if format == "html" {
+		return htmlTemplate.Execute(w, transcripts)
+	}
+	return markdownTemplate.Execute(w, transcripts)
+}
+
+// fence wraps text in a Markdown code block whose fence is longer than any run of
+// backticks in the text
+func fence(language, text string) string {
+	marker := "```"
+	for _, run := range backtickRuns.FindAllString(text, -1) {
+		if len(run) >= len(marker) {
+			marker = strings.Repeat("`", len(run)+1)
+		}
+	}
+	return marker + language + "\n" + strings.TrimRight(text, "\n") + "\n" + marker
+}
+
+// markdownTemplate renders transcripts as Markdown that pastes into an issue tracker
+var markdownTemplate = texttemplate.Must(texttemplate.New("markdown").Funcs(texttemplate.FuncMap{"fence": fence}).Parse(
+	`{{range $i, $t := .}}{{if $i}}
+---
+
+{{end}}# {{$t.Title}}
+
+**Outcome:** {{$t.Outcome}}
+{{if $t.FailureDetails}}
+**Details:** {{$t.FailureDetails}}
+{{end}}
+| | |
+|---|---|
+{{range $t.Facts}}| {{.Name}} | {{.Value}} |
+{{end}}
+## Test Case
+
+{{fence "" $t.Prompt}}
+{{if $t.Expected}}
+Expected tool calls:
+{{range $t.Expected}}
+- {{.}}
+{{- end}}
+{{end}}
+## Conversation
+{{if $t.Reconstructed}}
+_This result was recorded without a transcript; the conversation is rebuilt from its tool calls and final reply._
+{{end}}{{range $t.Messages}}
+### {{.Heading}}
+{{if .Content}}
+{{if .JSON}}{{fence "json" .Content}}{{else}}{{fence "" .Content}}{{end}}
+{{end}}{{if .Refusal}}
+**Refusal:** {{.Refusal}}
+{{end}}{{range .Calls}}
+Calls ` + "`{{.Name}}`" + `{{if .ID}} ({{.ID}}){{end}}:
+
+{{fence "json" .Arguments}}
+{{end}}{{end}}{{if $t.Iterations}}
+## Timings
+
+| Iteration | LLM | Tools | Prompt Tokens | Completion Tokens | Finish | Tool Calls |
+|---|---|---|---|---|---|---|
+{{range $t.Iterations}}| {{.Iteration}} | {{.Duration}} | {{.ToolTime}} | {{.PromptTokens}}{{if .Estimated}}~{{end}} | {{.CompletionTokens}} | {{.FinishReason}} | {{.Tools}} |
+{{end}}{{end}}{{end}}`))
+
+// htmlTemplate renders transcripts as a self-contained HTML page
+var htmlTemplate = htmltemplate.Must(htmltemplate.New("html").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{if eq (len .) 1}}{{(index . 0).Title}}{{else}}Transcripts{{end}}</title>
+<style>
+body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", sans-serif; max-width: 960px; margin: 2em auto; padding: 0 1em; color: #222; }
+h1 { font-size: 1.5em; border-bottom: 1px solid #ddd; padding-bottom: 0.3em; }
+h2 { font-size: 1.2em; margin-top: 1.5em; }
+table { border-collapse: collapse; margin: 0.5em 0; }
+td, th { border: 1px solid #ddd; padding: 0.3em 0.6em; text-align: left; font-size: 0.9em; }
+th { background: #f5f5f5; }
+pre { background: #f6f8fa; padding: 0.8em; overflow-x: auto; white-space: pre-wrap; word-break: break-word; border-radius: 4px; }
+.outcome { font-weight: bold; }
+.passed { color: #1a7f37; }
+.failed { color: #cf222e; }
+.message { border-left: 4px solid #ddd; padding: 0.2em 1em; margin: 1em 0; }
+.message h3 { font-size: 1em; margin: 0.4em 0; }
+.system { border-color: #8c959f; }
+.user { border-color: #0969da; }
+.assistant { border-color: #8250df; }
+.tool { border-color: #bf8700; }
+.note { color: #666; font-style: italic; }
+hr { margin: 3em 0; }
+</style>
+</head>
+<body>
+{{range $i, $t := .}}{{if $i}}<hr>
+{{end}}<h1>{{$t.Title}}</h1>
+<p class="outcome {{if $t.Passed}}passed{{else}}failed{{end}}">{{$t.Outcome}}</p>
+{{if $t.FailureDetails}}<p>{{$t.FailureDetails}}</p>
+{{end}}<table>
+{{range $t.Facts}}<tr><th>{{.Name}}</th><td>{{.Value}}</td></tr>
+{{end}}</table>
+
+<h2>Test Case</h2>
+<pre>{{$t.Prompt}}</pre>
+{{if $t.Expected}}<p>Expected tool calls:</p>
+<ul>
+{{range $t.Expected}}<li>{{.}}</li>
+{{end}}</ul>
+{{end}}
+<h2>Conversation</h2>
+{{if $t.Reconstructed}}<p class="note">This result was recorded without a transcript; the conversation is rebuilt from its tool calls and final reply.</p>
+{{end}}{{range $t.Messages}}<div class="message {{.Role}}">
+<h3>{{.Heading}}</h3>
+{{if .Content}}<pre>{{.Content}}</pre>
+{{end}}{{if .Refusal}}<p><strong>Refusal:</strong> {{.Refusal}}</p>
+{{end}}{{range .Calls}}<p>Calls <code>{{.Name}}</code>{{if .ID}} ({{.ID}}){{end}}:</p>
+<pre>{{.Arguments}}</pre>
+{{end}}</div>
+{{end}}{{if $t.Iterations}}
+<h2>Timings</h2>
+<table>
+<tr><th>Iteration</th><th>LLM</th><th>Tools</th><th>Prompt Tokens</th><th>Completion Tokens</th><th>Finish</th><th>Tool Calls</th></tr>
+{{range $t.Iterations}}<tr><td>{{.Iteration}}</td><td>{{.Duration}}</td><td>{{.ToolTime}}</td><td>{{.PromptTokens}}{{if .Estimated}}~{{end}}</td><td>{{.CompletionTokens}}</td><td>{{.FinishReason}}</td><td>{{.Tools}}</td></tr>
+{{end}}</table>
+{{end}}{{end}}</body>
+</html>
+`))
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"model-test/models"
+)
+
+// transcriptView is one result laid out for the export templates
+type transcriptView struct {
+	Title          string
+	TestCase       string
+	Model          string
+	Run            int
+	Label          string
+	ResultFile     string
+	Passed         bool
+	Outcome        string // Passed or failed, with the failure reason and any adjudication
+	FailureDetails string
+	Prompt         string
+	Expected       []string // One line per expected variant
+	Facts          []fact   // Run, sampling, timing and token details
+	Messages       []messageView
+	Reconstructed  bool // The result predates recorded transcripts; Messages were rebuilt from its tool calls
+	Iterations     []iterationView
+}
+
+// fact is a named detail of a result
+type fact struct {
+	Name  string
+	Value string
+}
+
+// messageView is one message of the conversation
+type messageView struct {
+	Role    string
+	Heading string
+	Content string
+	JSON    bool // Content is indented JSON
+	Refusal string
+	Calls   []callView
+}
+
+// callView is a tool call requested by an assistant message
+type callView struct {
+	ID        string
+	Name      string
+	Arguments string
+}
+
+// iterationView is the timing and token usage of one agent loop iteration
+type iterationView struct {
+	Iteration        int
+	Duration         string
+	ToolTime         string
+	PromptTokens     int64
+	CompletionTokens int64
+	Estimated        bool
+	FinishReason     string
+	Tools            string
+}
+
+// buildTranscriptView lays out a result of a report for export
+func buildTranscriptView(file string, report *models.AgentReport, result models.AgentTestResult) transcriptView {
+	view := transcriptView{
+		Title:          fmt.Sprintf("%s: %s", result.ModelName, result.TestCase.Name),
+		TestCase:       result.TestCase.Name,
+		Model:          result.ModelName,
+		Run:            result.Run,
+		Label:          result.Config.Label,
+		ResultFile:     file,
+		Passed:         result.Success,
+		Outcome:        describeOutcome(result),
+		FailureDetails: result.FailureDetails,
+		Prompt:         result.TestCase.Prompt,
+		Expected:       describeExpected(result.TestCase),
+		Facts:          collectFacts(report, result),
+	}
+
+	if len(result.Transcript) > 0 {
+		view.Messages = transcriptMessages(result)
+	} else if result.Response != nil {
+		view.Messages = reconstructMessages(result)
+		view.Reconstructed = true
+	}
+
+	if result.Response != nil {
+		for _, iteration := range result.Response.Iterations {
+			view.Iterations = append(view.Iterations, iterationView{
+				Iteration:        iteration.Iteration,
+				Duration:         formatDuration(iteration.Duration),
+				ToolTime:         formatDuration(iteration.ToolTime),
+				PromptTokens:     iteration.PromptTokens,
+				CompletionTokens: iteration.CompletionTokens,
+				Estimated:        iteration.TokensEstimated,
+				FinishReason:     iteration.FinishReason,
+				Tools:            strings.Join(iteration.ToolCalls, ", "),
+			})
+		}
+	}
+	return view
+}
+
+// describeOutcome summarizes whether the result passed and why it failed
+func describeOutcome(result models.AgentTestResult) string {
+	outcome := "Failed"
+	if result.Success {
+		outcome = "Passed"
+		if result.MatchedPath != "" {
+			outcome += fmt.Sprintf(" (variant %s)", result.MatchedPath)
+		}
+	} else if result.FailureReason != "" {
+		outcome += fmt.Sprintf(" (%s)", result.FailureReason)
+	}
+	if result.Adjudication != nil {
+		outcome += fmt.Sprintf(", adjudicated %s", result.Adjudication.Verdict)
+	}
+	return outcome
+}
+
+// describeExpected renders each expected variant as its sequence of tools
+func describeExpected(testCase models.TestCase) []string {
+	var lines []string
+	for _, variant := range testCase.ExpectedToolVariants {
+		names := make([]string, len(variant.Tools))
+		for i, tool := range variant.Tools {
+			names[i] = tool.Name
+		}
+		line := variant.Name + ": "
+		switch {
+		case len(names) == 0:
+			line += "no tool calls"
+		case variant.Order == models.ToolOrderAny:
+			line += strings.Join(names, ", ") + " (any order)"
+		default:
+			line += strings.Join(names, " → ")
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// collectFacts gathers the details of the run, sampling, timing and token usage that
+// a vendor needs to reproduce the result
+func collectFacts(report *models.AgentReport, result models.AgentTestResult) []fact {
+	facts := []fact{{"Model", result.ModelName}}
+	if report.RunID != "" {
+		facts = append(facts, fact{"Run ID", report.RunID})
+	}
+	if result.Run > 0 {
+		facts = append(facts, fact{"Repetition", strconv.Itoa(result.Run)})
+	}
+	if !result.Timestamp.IsZero() {
+		facts = append(facts, fact{"Finished", result.Timestamp.UTC().Format(time.RFC3339)})
+	}
+
+	config := result.Config
+	if config.Label != "" {
+		facts = append(facts, fact{"Sampling", config.Label})
+	}
+	if config.Temperature != nil {
+		facts = append(facts, fact{"Temperature", strconv.FormatFloat(*config.Temperature, 'g', -1, 64)})
+	}
+	if config.TopP != nil {
+		facts = append(facts, fact{"Top P", strconv.FormatFloat(*config.TopP, 'g', -1, 64)})
+	}
+	if config.TopK > 0 {
+		facts = append(facts, fact{"Top K", strconv.Itoa(config.TopK)})
+	}
+	if config.MaxTokens > 0 {
+		facts = append(facts, fact{"Max Tokens", strconv.Itoa(config.MaxTokens)})
+	}
+	if config.Seed != nil {
+		facts = append(facts, fact{"Seed", strconv.FormatInt(*config.Seed, 10)})
+	}
+	if config.ParallelToolCalls != nil {
+		facts = append(facts, fact{"Parallel Tool Calls", strconv.FormatBool(*config.ParallelToolCalls)})
+	}
+
+	facts = append(facts, fact{"Response Time", formatDuration(result.ResponseTime)})
+	if timing := result.Timing; timing != nil {
+		facts = append(facts,
+			fact{"LLM Time", formatDuration(timing.LLMTime)},
+			fact{"Tool Time", formatDuration(timing.ToolTime)},
+			fact{"Harness Overhead", formatDuration(timing.HarnessOverhead)})
+	}
+	if response := result.Response; response != nil {
+		facts = append(facts, fact{"LLM Requests", strconv.Itoa(response.LLMRequests)})
+		if usage := response.Usage; usage != nil {
+			tokens := fmt.Sprintf("%d input, %d output", usage.InputTokens, usage.OutputTokens)
+			if usage.Estimated {
+				tokens += " (input partly estimated)"
+			}
+			facts = append(facts, fact{"Tokens", tokens})
+		}
+	}
+	return facts
+}
+
+// transcriptMessages lays out the recorded conversation, marking tool results of
+// calls that failed or were denied
+func transcriptMessages(result models.AgentTestResult) []messageView {
+	outcomes := make(map[string]string)
+	if result.Response != nil {
+		for _, call := range result.Response.ToolCalls {
+			switch {
+			case call.Denied:
+				outcomes[call.CallID] = "denied"
+			case !call.Success:
+				outcomes[call.CallID] = "failed"
+			}
+		}
+	}
+
+	var messages []messageView
+	for _, message := range result.Transcript {
+		view := messageView{Role: string(message.Role), Content: message.Content, Refusal: message.Refusal}
+		switch message.Role {
+		case models.RoleSystem:
+			view.Heading = "System"
+		case models.RoleUser:
+			view.Heading = "User"
+		case models.RoleAssistant:
+			view.Heading = fmt.Sprintf("Assistant (iteration %d)", message.Iteration)
+			for _, call := range message.ToolCalls {
+				view.Calls = append(view.Calls, callView{ID: call.ID, Name: call.Name, Arguments: prettyJSON(call.Arguments)})
+			}
+		case models.RoleTool:
+			view.Heading = fmt.Sprintf("Tool result: %s", message.ToolName)
+			if outcome := outcomes[message.ToolCallID]; outcome != "" {
+				view.Heading += fmt.Sprintf(" (%s)", outcome)
+			}
+			view.Content = prettyJSON(message.Content)
+			view.JSON = json.Valid([]byte(message.Content))
+		default:
+			view.Heading = string(message.Role)
+		}
+		messages = append(messages, view)
+	}
+	return messages
+}
+
+// reconstructMessages rebuilds the conversation of a result recorded without a
+// transcript from its prompt, tool calls and final reply
+func reconstructMessages(result models.AgentTestResult) []messageView {
+	messages := []messageView{{Role: string(models.RoleUser), Heading: "User", Content: result.TestCase.Prompt}}
+	for _, call := range result.Response.ToolCalls {
+		messages = append(messages, messageView{
+			Role:    string(models.RoleAssistant),
+			Heading: "Assistant",
+			Calls:   []callView{{ID: call.CallID, Name: call.ToolName, Arguments: prettyJSON(call.Arguments)}},
+		})
+
+		content := call.Error
+		heading := fmt.Sprintf("Tool result: %s", call.ToolName)
+		switch {
+		case call.Denied:
+			heading += " (denied)"
+		case !call.Success:
+			heading += " (failed)"
+		default:
+			if data, err := json.MarshalIndent(call.Result, "", "  "); err == nil {
+				content = string(data)
+			}
+		}
+		messages = append(messages, messageView{Role: string(models.RoleTool), Heading: heading, Content: content, JSON: call.Success})
+	}
+	if result.Response.Message != "" {
+		messages = append(messages, messageView{Role: string(models.RoleAssistant), Heading: "Assistant", Content: result.Response.Message})
+	}
+	return messages
+}
+
+// formatDuration rounds a duration to milliseconds for display
+func formatDuration(d time.Duration) string {
+	return d.Round(time.Millisecond).String()
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"model-test/models"
+	"model-test/services"
+)
+
+func main() {
+	var (
+		outputFile  = flag.String("o", "", "File to write the merged report to (default results/merged/<name of the first file>)")
+		prefer      = flag.String("prefer", string(models.MergeReachedModel), "Which result to keep when files hold the same test: reached-model (a result that reached the model beats an infrastructure failure, then the latest wins), latest, earliest (the first file listed wins) or error (stop)")
+		signKeyFile = flag.String("sign-key-file", "", "Path to the shared secret to sign the merged results file with (see model-test -sign-key-file)")
+	)
+	flag.Parse()
+
+	if len(flag.Args()) < 1 {
+		fmt.Fprintf(os.Stderr, "Usage: %s [options] <result_file_or_directory> ...\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "\nMerge result files of one model and suite (e.g. a run and a rerun of its failed tests)\n")
+		fmt.Fprintf(os.Stderr, "into one report. The first file supplies the run's settings and run ID.\n")
+		fmt.Fprintf(os.Stderr, "\nOptions:\n")
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+
+	rule, err := services.ParseMergeRule(*prefer)
+	if err != nil {
+		log.Fatalf("Invalid -prefer: %v", err)
+	}
+
+	var signingKey []byte
+	if *signKeyFile != "" {
+		signingKey, err = services.LoadSigningKey(*signKeyFile)
+		if err != nil {
+			log.Fatalf("Invalid -sign-key-file: %v", err)
+		}
+	}
+
+	// Files named on the command line keep their order, which the merge rules depend on
+	files, err := services.FindResultFiles(flag.Args())
+	if err != nil {
+		log.Fatalf("Failed to find result files: %v", err)
+	}
+	if len(files) < 2 {
+		log.Fatalf("Need at least two result files to merge, found %d in: %v", len(files), flag.Args())
+	}
+
+	reports := make([]*models.AgentReport, len(files))
+	for i, file := range files {
+		reports[i], err = services.LoadAgentReport(file)
+		if err != nil {
+			log.Fatalf("Failed to load %s: %v", file, err)
+		}
+	}
+
+	merged, conflicts, err := services.MergeReports(reports, files, rule)
+	if err != nil {
+		log.Fatalf("Failed to merge results: %v", err)
+	}
+
+	output := *outputFile
+	if output == "" {
+		output = filepath.Join("results", "merged", filepath.Base(files[0]))
+	}
+	if err := os.MkdirAll(filepath.Dir(output), 0755); err != nil {
+		log.Fatalf("Failed to create output directory: %v", err)
+	}
+	if err := services.SaveAgentReport(output, merged); err != nil {
+		log.Fatalf("Failed to save %s: %v", output, err)
+	}
+	if err := services.SignFile(output, signingKey); err != nil {
+		log.Fatalf("Failed to sign %s: %v", output, err)
+	}
+
+	for _, source := range merged.MergedFrom {
+		fmt.Printf("%s: kept %d of %d results\n", source.File, source.Kept, source.Results)
+	}
+	if len(conflicts) > 0 {
+		fmt.Printf("\n⚖️  %d tests were in more than one file (-prefer %s):\n", len(conflicts), rule)
+		for _, conflict := range conflicts {
+			fmt.Printf("  %s: kept %s\n", conflict.Key, conflict.KeptFile)
+		}
+	}
+
+	fmt.Printf("\n📊 Merged %d results: %d passed, %d failed", merged.TotalTests, merged.PassedTests, merged.FailedTests)
+	if merged.InfrastructureFailures > 0 {
+		fmt.Printf(" (%d against the server)", merged.InfrastructureFailures)
+	}
+	fmt.Println()
+	if merged.Incomplete {
+		fmt.Printf("⚠️  Still incomplete: %d scheduled tests are in none of the files\n", merged.InterruptedTests)
+	}
+	fmt.Printf("💾 Merged results saved to: %s\n", output)
+}
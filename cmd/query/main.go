@@ -0,0 +1,370 @@
+package main
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"model-test/models"
+	"model-test/services"
+)
+
+// loadedReport is a result file loaded into the database
+type loadedReport struct {
+	file   string
+	report *models.AgentReport
+}
+
+// table is a table created in the database, with the columns it is described by
+type table struct {
+	name        string
+	description string
+	columns     []column
+}
+
+// column is one column of a table
+type column struct {
+	name    string
+	sqlType string
+}
+
+func main() {
+	var (
+		query  = flag.String("sql", "", "SQL to run against the loaded results (default: read from stdin)")
+		format = flag.String("format", "table", "Output format: table, csv or json")
+		dbFile = flag.String("db", "", "Save the loaded tables to this new SQLite file, for further queries with sqlite3 or other tools, instead of keeping them in memory")
+		schema = flag.Bool("schema", false, "Print the tables and their columns instead of running a query")
+	)
+	flag.Parse()
+
+	if len(flag.Args()) < 1 {
+		fmt.Fprintf(os.Stderr, "Usage: %s [options] <result_file_or_directory> ...\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "\nLoad result files into SQLite tables (results, tool_calls, runs) and run a SQL query against them.\n")
+		fmt.Fprintf(os.Stderr, "\nOptions:\n")
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+	if *format != "table" && *format != "csv" && *format != "json" {
+		log.Fatalf("Invalid -format %q: expected table, csv or json", *format)
+	}
+
+	files, err := services.FindResultFiles(flag.Args())
+	if err != nil {
+		log.Fatalf("Failed to find result files: %v", err)
+	}
+	if len(files) == 0 {
+		log.Fatalf("No result files found in: %v", flag.Args())
+	}
+
+	var reports []loadedReport
+	for _, file := range files {
+		report, err := loadReport(file)
+		if err != nil {
+			log.Printf("Warning: skipping %s: %v", file, err)
+			continue
+		}
+		reports = append(reports, loadedReport{file: file, report: report})
+	}
+
+	dataSource := ":memory:"
+	if *dbFile != "" {
+		if _, err := os.Stat(*dbFile); err == nil {
+			log.Fatalf("Database file %s already exists; query it with sqlite3 or choose a new -db path", *dbFile)
+		}
+		dataSource = *dbFile
+	}
+	db, err := sql.Open("sqlite3", dataSource)
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	// Every connection to :memory: is a separate database
+	db.SetMaxOpenConns(1)
+
+	tables, err := loadTables(db, reports)
+	if err != nil {
+		log.Fatalf("Failed to load results: %v", err)
+	}
+
+	if *schema {
+		printSchema(os.Stdout, tables)
+		return
+	}
+
+	if *query == "" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			log.Fatalf("Failed to read SQL from stdin: %v", err)
+		}
+		*query = string(data)
+	}
+	if strings.TrimSpace(*query) == "" {
+		log.Fatalf("No SQL given: pass -sql or pipe the query on stdin (see -schema for the tables)")
+	}
+
+	rows, err := db.Query(*query)
+	if err != nil {
+		log.Fatalf("Query failed: %v", err)
+	}
+	defer rows.Close()
+
+	if err := writeRows(os.Stdout, rows, *format); err != nil {
+		log.Fatalf("Query failed: %v", err)
+	}
+}
+
+// loadTables creates the results, tool_calls and runs tables and fills them from the
+// loaded reports
+func loadTables(db *sql.DB, reports []loadedReport) ([]table, error) {
+	resultsTable := services.NewResultsTable()
+	for _, loaded := range reports {
+		resultsTable.Add(loaded.file, loaded.report.RunID, loaded.report.Tags, loaded.report.Results)
+	}
+
+	results := table{name: "results", description: "One row per test result", columns: []column{{"id", "INTEGER"}}}
+	for _, resultColumn := range resultsTable.Columns() {
+		results.columns = append(results.columns, column{resultColumn.Name, sqlType(resultColumn.Type)})
+	}
+	toolCalls := table{name: "tool_calls", description: "One row per tool call; result_id is the results row it belongs to", columns: []column{
+		{"result_id", "INTEGER"}, {"position", "INTEGER"}, {"iteration", "INTEGER"}, {"tool_name", "TEXT"},
+		{"arguments", "TEXT"}, {"success", "INTEGER"}, {"error", "TEXT"}, {"error_code", "TEXT"}, {"denied", "INTEGER"},
+	}}
+	runs := table{name: "runs", description: "One row per result file", columns: []column{
+		{"source_file", "TEXT"}, {"run_id", "TEXT"}, {"timestamp", "TEXT"}, {"test_suite", "TEXT"},
+		{"total_tests", "INTEGER"}, {"passed_tests", "INTEGER"}, {"failed_tests", "INTEGER"},
+		{"infrastructure_failures", "INTEGER"}, {"incomplete", "INTEGER"}, {"tags", "TEXT"},
+	}}
+	tables := []table{results, toolCalls, runs}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	inserts := make(map[string]*sql.Stmt)
+	for _, t := range tables {
+		var definitions, placeholders []string
+		for _, c := range t.columns {
+			definitions = append(definitions, quoteIdentifier(c.name)+" "+c.sqlType)
+			placeholders = append(placeholders, "?")
+		}
+		if _, err := tx.Exec(fmt.Sprintf("CREATE TABLE %s (%s)", t.name, strings.Join(definitions, ", "))); err != nil {
+			return nil, fmt.Errorf("failed to create table %s: %w", t.name, err)
+		}
+		insert, err := tx.Prepare(fmt.Sprintf("INSERT INTO %s VALUES (%s)", t.name, strings.Join(placeholders, ", ")))
+		if err != nil {
+			return nil, fmt.Errorf("failed to prepare insert into %s: %w", t.name, err)
+		}
+		defer insert.Close()
+		inserts[t.name] = insert
+	}
+
+	// Results are numbered in the order they were added to the results table
+	id := 0
+	resultRows := resultsTable.Rows()
+	for _, loaded := range reports {
+		report := loaded.report
+		tags, err := json.Marshal(report.Tags)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := inserts["runs"].Exec(loaded.file, report.RunID, sqlValue(report.Timestamp), report.TestSuite,
+			report.TotalTests, report.PassedTests, report.FailedTests, report.InfrastructureFailures, report.Incomplete, string(tags)); err != nil {
+			return nil, fmt.Errorf("failed to insert run %s: %w", loaded.file, err)
+		}
+
+		for _, result := range report.Results {
+			id++
+			values := []interface{}{id}
+			for _, value := range resultRows[id-1] {
+				values = append(values, sqlValue(value))
+			}
+			if _, err := inserts["results"].Exec(values...); err != nil {
+				return nil, fmt.Errorf("failed to insert result of %s: %w", result.TestCase.Name, err)
+			}
+
+			if result.Response == nil {
+				continue
+			}
+			for i, toolCall := range result.Response.ToolCalls {
+				if _, err := inserts["tool_calls"].Exec(id, i+1, toolCall.Iteration, toolCall.ToolName, toolCall.Arguments,
+					toolCall.Success, sqlValue(toolCall.Error), sqlValue(string(toolCall.ErrorCode)), toolCall.Denied); err != nil {
+					return nil, fmt.Errorf("failed to insert tool call of %s: %w", result.TestCase.Name, err)
+				}
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit: %w", err)
+	}
+	return tables, nil
+}
+
+// sqlType returns the SQLite column type for a results table column
+func sqlType(t services.ParquetType) string {
+	switch t {
+	case services.ParquetInt64, services.ParquetBool:
+		return "INTEGER"
+	case services.ParquetDouble:
+		return "REAL"
+	default:
+		return "TEXT"
+	}
+}
+
+// sqlValue converts a value for storage: times become UTC text that SQLite's date
+// functions understand, and empty strings become NULL
+func sqlValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case time.Time:
+		return v.UTC().Format("2006-01-02 15:04:05.000")
+	case string:
+		if v == "" {
+			return nil
+		}
+	}
+	return value
+}
+
+// quoteIdentifier quotes a column name, since tag keys may contain any character
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// printSchema prints each table with its columns
+func printSchema(w io.Writer, tables []table) {
+	for _, t := range tables {
+		fmt.Fprintf(w, "%s: %s\n", t.name, t.description)
+		for _, c := range t.columns {
+			fmt.Fprintf(w, "  %-24s %s\n", c.name, c.sqlType)
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// writeRows writes the query's rows as an aligned table, CSV or a JSON array of objects
+func writeRows(w io.Writer, rows *sql.Rows, format string) error {
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	out := bufio.NewWriter(w)
+	defer out.Flush()
+
+	var table *tabwriter.Writer
+	var csvWriter *csv.Writer
+	var records []map[string]interface{}
+	switch format {
+	case "table":
+		table = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(table, strings.Join(columns, "\t"))
+	case "csv":
+		csvWriter = csv.NewWriter(out)
+		csvWriter.Write(columns)
+	}
+
+	values := make([]interface{}, len(columns))
+	pointers := make([]interface{}, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	count := 0
+	for rows.Next() {
+		if err := rows.Scan(pointers...); err != nil {
+			return err
+		}
+		count++
+
+		switch format {
+		case "table":
+			cells := make([]string, len(values))
+			for i, value := range values {
+				cells[i] = formatCell(value, "NULL")
+			}
+			fmt.Fprintln(table, strings.Join(cells, "\t"))
+		case "csv":
+			cells := make([]string, len(values))
+			for i, value := range values {
+				cells[i] = formatCell(value, "")
+			}
+			csvWriter.Write(cells)
+		case "json":
+			record := make(map[string]interface{}, len(columns))
+			for i, value := range values {
+				if data, ok := value.([]byte); ok {
+					value = string(data)
+				}
+				record[columns[i]] = value
+			}
+			records = append(records, record)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	switch format {
+	case "table":
+		table.Flush()
+		fmt.Fprintf(out, "(%d rows)\n", count)
+	case "csv":
+		csvWriter.Flush()
+		return csvWriter.Error()
+	case "json":
+		if records == nil {
+			records = []map[string]interface{}{}
+		}
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(records)
+	}
+	return nil
+}
+
+// formatCell renders a value for text output, with null as given
+func formatCell(value interface{}, null string) string {
+	switch v := value.(type) {
+	case nil:
+		return null
+	case []byte:
+		return string(v)
+	case float64:
+		return fmt.Sprintf("%g", v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// loadReport streams an agent report from a result file
+func loadReport(filename string) (*models.AgentReport, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var report models.AgentReport
+	if err := json.NewDecoder(bufio.NewReaderSize(f, 1<<20)).Decode(&report); err != nil {
+		return nil, err
+	}
+
+	if report.SchemaVersion > models.ResultSchemaVersion {
+		return nil, fmt.Errorf("result schema version %d is newer than supported version %d", report.SchemaVersion, models.ResultSchemaVersion)
+	}
+
+	return &report, nil
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"model-test/models"
+	"model-test/services"
+)
+
+// RescoreChange describes a test whose outcome changed after re-scoring
+type RescoreChange struct {
+	TestCase      string               `json:"test_case"`
+	WasSuccess    bool                 `json:"was_success"`
+	NowSuccess    bool                 `json:"now_success"`
+	FailureReason models.FailureReason `json:"failure_reason,omitempty"`
+}
+
+func main() {
+	var (
+		outputDir     = flag.String("o", "results/rescored", "Directory to write re-scored result files to")
+		configFile    = flag.String("config", "", "Optional test cases file whose definitions replace the ones stored in the results (matched by name); its tool_aliases add to those recorded in the results and its scorers decide the outcome")
+		referenceTime = flag.String("reference-time", "", "Reference time for relative dates (defaults to the time recorded in each result file)")
+		unitsFile     = flag.String("units", "", "Path to unit conversion tables used when matching quantity arguments, replacing the built-in mass, volume and length tables")
+		caseSensitive = flag.Bool("match-case-sensitive", false, "Compare string arguments case-sensitively")
+		matchTrim     = flag.Bool("match-trim", false, "Trim leading and trailing whitespace before comparing string arguments")
+		matchCollapse = flag.Bool("match-collapse-whitespace", false, "Collapse runs of whitespace (and trim) before comparing string arguments")
+		matchUnicode  = flag.String("match-unicode", "none", "Unicode normalization before comparing string arguments: none, nfc, nfkc")
+		adjudicate    = flag.String("adjudications", "review/adjudications.json", "Human decisions on borderline results (from the review tool), applied when scoring matching responses")
+		evaluation    = flag.String("evaluation", "calls", "What a test's tool calls must get right to pass: calls (the expected tools and arguments) or workflow (the calls must also have worked)")
+		scoring       = flag.String("scoring", "binary", "How tests are scored: binary (pass/fail) or partial (adds a 0-1 partial-credit score per test)")
+		signKeyFile   = flag.String("sign-key-file", "", "Path to the shared secret to sign the re-scored results files with (see model-test -sign-key-file)")
+		mutationCheck = flag.Bool("mutation-check", false, "Self-check the evaluator instead of re-scoring: break each passing transcript (swap a tool, drop or change an argument, drop a call) and report mutants it still passes; exits 1 if any survive")
+	)
+	flag.Parse()
+
+	if len(flag.Args()) < 1 {
+		fmt.Fprintf(os.Stderr, "Usage: %s [options] <result_file_or_directory> ...\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "\nRe-run the evaluator over existing result files without querying any model.\n")
+		fmt.Fprintf(os.Stderr, "\nOptions:\n")
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+
+	unitTable, err := services.LoadUnitTable(*unitsFile)
+	if err != nil {
+		log.Fatalf("Failed to load unit tables: %v", err)
+	}
+
+	matchPolicy, err := services.NewStringMatchPolicy(*caseSensitive, *matchTrim, *matchCollapse, *matchUnicode)
+	if err != nil {
+		log.Fatalf("Invalid match policy: %v", err)
+	}
+
+	adjudications, err := services.LoadAdjudications(*adjudicate)
+	if err != nil {
+		log.Fatalf("Failed to load adjudications: %v", err)
+	}
+
+	scoringMode, err := services.ParseScoringMode(*scoring)
+	if err != nil {
+		log.Fatalf("Invalid -scoring: %v", err)
+	}
+
+	evaluationMode, err := services.ParseEvaluationMode(*evaluation)
+	if err != nil {
+		log.Fatalf("Invalid -evaluation: %v", err)
+	}
+
+	var signingKey []byte
+	if *signKeyFile != "" {
+		signingKey, err = services.LoadSigningKey(*signKeyFile)
+		if err != nil {
+			log.Fatalf("Invalid -sign-key-file: %v", err)
+		}
+	}
+
+	var overrides map[string]models.TestCase
+	var toolAliases models.ToolAliases
+	var scorers []models.ScorerConfig
+	if *configFile != "" {
+		overrides, toolAliases, scorers, err = loadTestCaseOverrides(*configFile)
+		if err != nil {
+			log.Fatalf("Failed to load test cases: %v", err)
+		}
+	}
+
+	files, err := services.FindResultFiles(flag.Args())
+	if err != nil {
+		log.Fatalf("Failed to find result files: %v", err)
+	}
+	if len(files) == 0 {
+		log.Fatalf("No result files found in: %v", flag.Args())
+	}
+
+	if !*mutationCheck {
+		if err := os.MkdirAll(*outputDir, 0755); err != nil {
+			log.Fatalf("Failed to create output directory: %v", err)
+		}
+	}
+
+	survivors := 0
+	for _, file := range files {
+		report, err := services.LoadAgentReport(file)
+		if err != nil {
+			log.Printf("Warning: skipping %s: %v", file, err)
+			continue
+		}
+
+		evaluator := services.NewEvaluator()
+		evaluator.SetUnitTable(unitTable)
+		evaluator.SetMatchPolicy(matchPolicy)
+		evaluator.SetAdjudications(adjudications)
+		evaluator.SetScoringMode(scoringMode)
+		evaluator.SetEvaluationMode(evaluationMode)
+		evaluator.SetReferenceTime(resolveReferenceTime(*referenceTime, report))
+		evaluator.SetToolAliases(report.ToolAliases.Merge(toolAliases))
+		if err := evaluator.SetScorers(scorers); err != nil {
+			log.Fatalf("Invalid scorers in -config: %v", err)
+		}
+
+		if *mutationCheck {
+			survivors += checkMutations(file, report, evaluator, overrides)
+			continue
+		}
+
+		rescored, changes := rescoreReport(report, evaluator, overrides)
+
+		outputFile := filepath.Join(*outputDir, filepath.Base(file))
+		if err := services.SaveAgentReport(outputFile, rescored); err != nil {
+			log.Fatalf("Failed to save %s: %v", outputFile, err)
+		}
+		if err := services.SignFile(outputFile, signingKey); err != nil {
+			log.Fatalf("Failed to sign %s: %v", outputFile, err)
+		}
+
+		fmt.Printf("%s: passed %d -> %d (%d changed) -> %s\n",
+			file, report.PassedTests, rescored.PassedTests, len(changes), outputFile)
+		for _, change := range changes {
+			if change.NowSuccess {
+				fmt.Printf("  + %s now passes\n", change.TestCase)
+			} else {
+				fmt.Printf("  - %s now fails (%s)\n", change.TestCase, change.FailureReason)
+			}
+		}
+	}
+
+	if survivors > 0 {
+		fmt.Printf("\n❌ %d mutants survived: the evaluator passed transcripts no expected path allows\n", survivors)
+		os.Exit(1)
+	}
+}
+
+// checkMutations scores mutants of every passing result in the report, prints how
+// many the evaluator caught and lists the survivors, and returns how many survived
+func checkMutations(file string, report *models.AgentReport, evaluator *services.Evaluator, overrides map[string]models.TestCase) int {
+	var caught, equivalent int
+	var survived []models.MutationOutcome
+	for _, result := range report.Results {
+		if testCase, ok := overrides[result.TestCase.Name]; ok {
+			result.TestCase = testCase
+		}
+
+		for _, outcome := range evaluator.CheckMutations(result) {
+			switch {
+			case outcome.Caught:
+				caught++
+			case outcome.Equivalent:
+				equivalent++
+			default:
+				survived = append(survived, outcome)
+			}
+		}
+	}
+
+	fmt.Printf("%s: %d mutants, %d caught, %d equivalent, %d survived\n",
+		file, caught+equivalent+len(survived), caught, equivalent, len(survived))
+	for _, outcome := range survived {
+		fmt.Printf("  ! %s: %s (%s) still matches %s\n", outcome.TestCase, outcome.Description, outcome.Kind, outcome.MatchedPath)
+	}
+	return len(survived)
+}
+
+// rescoreReport re-evaluates every result in the report and rebuilds the aggregates,
+// keeping the rest of the report as recorded
+func rescoreReport(report *models.AgentReport, evaluator *services.Evaluator, overrides map[string]models.TestCase) (*models.AgentReport, []RescoreChange) {
+	var changes []RescoreChange
+	results := make([]models.AgentTestResult, len(report.Results))
+
+	for i, result := range report.Results {
+		if testCase, ok := overrides[result.TestCase.Name]; ok {
+			result.TestCase = testCase
+		}
+
+		rescored := evaluator.Evaluate(result)
+		if rescored.Success != report.Results[i].Success {
+			changes = append(changes, RescoreChange{
+				TestCase:      rescored.TestCase.Name,
+				WasSuccess:    report.Results[i].Success,
+				NowSuccess:    rescored.Success,
+				FailureReason: rescored.FailureReason,
+			})
+		}
+		results[i] = rescored
+	}
+
+	return services.RebuildAgentReport(report, results, evaluator), changes
+}
+
+// resolveReferenceTime picks the reference clock: the flag if given, otherwise the
+// one recorded in the report, otherwise the report's timestamp
+func resolveReferenceTime(flagValue string, report *models.AgentReport) time.Time {
+	if flagValue != "" {
+		t, err := services.ParseReferenceTime(flagValue)
+		if err != nil {
+			log.Fatalf("Invalid -reference-time: %v", err)
+		}
+		return t
+	}
+	if !report.ReferenceTime.IsZero() {
+		return report.ReferenceTime
+	}
+	return report.Timestamp
+}
+
+// loadTestCaseOverrides loads test case definitions keyed by name, and the config's
+// tool aliases and scorers
+func loadTestCaseOverrides(filename string) (map[string]models.TestCase, models.ToolAliases, []models.ScorerConfig, error) {
+	suite, err := services.LoadTestSuite(filename)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	overrides := make(map[string]models.TestCase)
+	for _, testCase := range suite.TestCases {
+		overrides[testCase.Name] = testCase
+	}
+	return overrides, suite.ToolAliases, suite.Scorers, nil
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"model-test/models"
+	"model-test/services"
+)
+
+// annotationColumns are the columns of an annotation export, in CSV order
+var annotationColumns = []string{"key", "test_case", "prompt", "failure_reason", "failure_details", "occurrences", "models", "transcript", "label", "note", "labeler"}
+
+// annotationRow is one failed behavior exported for external labeling. Labelers fill
+// in label (pass or fail), note and labeler.
+type annotationRow struct {
+	Key            string   `json:"key"`
+	TestCase       string   `json:"test_case"`
+	Prompt         string   `json:"prompt"`
+	FailureReason  string   `json:"failure_reason"`
+	FailureDetails string   `json:"failure_details,omitempty"`
+	Occurrences    int      `json:"occurrences"`
+	Models         []string `json:"models"`
+	Transcript     string   `json:"transcript"`
+	Label          string   `json:"label"`
+	Note           string   `json:"note"`
+	Labeler        string   `json:"labeler"`
+}
+
+// buildAnnotationRows collects the failed results that have not been decided yet,
+// merging results with identical behavior into one row
+func buildAnnotationRows(results map[string][]models.AgentTestResult, adjudications map[string]models.Adjudication) []annotationRow {
+	rows := make(map[string]*annotationRow)
+
+	files := make([]string, 0, len(results))
+	for file := range results {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		for _, result := range results[file] {
+			if result.Success || result.Response == nil {
+				continue
+			}
+			key := services.ReviewKey(result.TestCase, result.Response)
+			if _, decided := adjudications[key]; decided {
+				continue
+			}
+
+			row, exists := rows[key]
+			if !exists {
+				row = &annotationRow{
+					Key:            key,
+					TestCase:       result.TestCase.Name,
+					Prompt:         result.TestCase.Prompt,
+					FailureReason:  string(result.FailureReason),
+					FailureDetails: result.FailureDetails,
+					Transcript:     formatTranscript(result),
+				}
+				rows[key] = row
+			}
+			row.Occurrences++
+			if !containsString(row.Models, result.ModelName) {
+				row.Models = append(row.Models, result.ModelName)
+			}
+		}
+	}
+
+	list := make([]annotationRow, 0, len(rows))
+	for _, row := range rows {
+		list = append(list, *row)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].TestCase != list[j].TestCase {
+			return list[i].TestCase < list[j].TestCase
+		}
+		return list[i].Key < list[j].Key
+	})
+	return list
+}
+
+// formatTranscript renders the conversation of a result as plain text, interleaving
+// each assistant turn with the tool calls it made
+func formatTranscript(result models.AgentTestResult) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("user: %s\n", result.TestCase.Prompt))
+
+	toolCalls := result.Response.ToolCalls
+	next := 0
+	writeToolCall := func(toolCall models.ToolCallResult) {
+		if toolCall.Success {
+			output, _ := json.Marshal(toolCall.Result)
+			sb.WriteString(fmt.Sprintf("tool %s %s -> %s\n", toolCall.ToolName, toolCall.Arguments, output))
+		} else {
+			sb.WriteString(fmt.Sprintf("tool %s %s -> error: %s\n", toolCall.ToolName, toolCall.Arguments, toolCall.Error))
+		}
+	}
+
+	for _, message := range result.Response.AssistantMessages {
+		if message.Content != "" {
+			sb.WriteString(fmt.Sprintf("assistant: %s\n", message.Content))
+		}
+		for range message.ToolCalls {
+			if next < len(toolCalls) {
+				writeToolCall(toolCalls[next])
+				next++
+			}
+		}
+	}
+
+	// Results recorded before assistant turns were captured only have the tool calls
+	// and the final message
+	for ; next < len(toolCalls); next++ {
+		writeToolCall(toolCalls[next])
+	}
+	if len(result.Response.AssistantMessages) == 0 && result.Response.Message != "" {
+		sb.WriteString(fmt.Sprintf("assistant: %s\n", result.Response.Message))
+	}
+
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// containsString reports whether the slice contains the value
+func containsString(values []string, value string) bool {
+	for _, existing := range values {
+		if existing == value {
+			return true
+		}
+	}
+	return false
+}
+
+// exportAnnotations writes rows as CSV or JSONL depending on the file extension
+func exportAnnotations(path string, rows []annotationRow) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create export directory: %w", err)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create export file: %w", err)
+	}
+	defer file.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		writer := csv.NewWriter(file)
+		if err := writer.Write(annotationColumns); err != nil {
+			return fmt.Errorf("failed to write CSV header: %w", err)
+		}
+		for _, row := range rows {
+			record := []string{row.Key, row.TestCase, row.Prompt, row.FailureReason, row.FailureDetails,
+				strconv.Itoa(row.Occurrences), strings.Join(row.Models, "; "), row.Transcript, row.Label, row.Note, row.Labeler}
+			if err := writer.Write(record); err != nil {
+				return fmt.Errorf("failed to write CSV row: %w", err)
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	case ".jsonl":
+		encoder := json.NewEncoder(file)
+		encoder.SetEscapeHTML(false)
+		for _, row := range rows {
+			if err := encoder.Encode(row); err != nil {
+				return fmt.Errorf("failed to write JSONL row: %w", err)
+			}
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unsupported export format '%s' (use .csv or .jsonl)", filepath.Ext(path))
+}
+
+// importAnnotations reads labeled rows from a CSV or JSONL export and records each
+// label as an adjudication. Rows without a label are skipped. It returns the number
+// of labels imported.
+func importAnnotations(path string, adjudications map[string]models.Adjudication) (int, error) {
+	rows, err := readAnnotations(path)
+	if err != nil {
+		return 0, err
+	}
+
+	source := "import:" + filepath.Base(path)
+	imported := 0
+	for i, row := range rows {
+		label := strings.ToLower(strings.TrimSpace(row.Label))
+		if label == "" {
+			continue
+		}
+		verdict := models.ReviewVerdict(label)
+		if verdict != models.VerdictPass && verdict != models.VerdictFail {
+			return imported, fmt.Errorf("row %d (%s): label must be pass or fail, got '%s'", i+1, row.Key, row.Label)
+		}
+		if row.Key == "" {
+			return imported, fmt.Errorf("row %d: missing key", i+1)
+		}
+
+		adjudications[row.Key] = models.Adjudication{
+			Key:       row.Key,
+			TestCase:  row.TestCase,
+			Verdict:   verdict,
+			Note:      row.Note,
+			Reviewer:  row.Labeler,
+			Source:    source,
+			DecidedAt: time.Now(),
+		}
+		imported++
+	}
+
+	return imported, nil
+}
+
+// readAnnotations parses an annotation file by its extension. CSV files are matched
+// by header name, so labeling tools may reorder or drop columns other than key and label.
+func readAnnotations(path string) ([]annotationRow, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open annotations: %w", err)
+	}
+	defer file.Close()
+
+	var rows []annotationRow
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		reader := csv.NewReader(file)
+		header, err := reader.Read()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV header: %w", err)
+		}
+		columns := make(map[string]int)
+		for i, name := range header {
+			columns[strings.ToLower(strings.TrimSpace(name))] = i
+		}
+		if _, ok := columns["key"]; !ok {
+			return nil, fmt.Errorf("CSV has no key column")
+		}
+		if _, ok := columns["label"]; !ok {
+			return nil, fmt.Errorf("CSV has no label column")
+		}
+
+		for {
+			record, err := reader.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to read CSV row: %w", err)
+			}
+			field := func(name string) string {
+				if i, ok := columns[name]; ok && i < len(record) {
+					return record[i]
+				}
+				return ""
+			}
+			rows = append(rows, annotationRow{
+				Key:      field("key"),
+				TestCase: field("test_case"),
+				Label:    field("label"),
+				Note:     field("note"),
+				Labeler:  field("labeler"),
+			})
+		}
+	case ".jsonl":
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(make([]byte, 1024*1024), 16*1024*1024)
+		for line := 1; scanner.Scan(); line++ {
+			if strings.TrimSpace(scanner.Text()) == "" {
+				continue
+			}
+			var row annotationRow
+			if err := json.Unmarshal(scanner.Bytes(), &row); err != nil {
+				return nil, fmt.Errorf("failed to parse line %d: %w", line, err)
+			}
+			rows = append(rows, row)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read annotations: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported annotation format '%s' (use .csv or .jsonl)", filepath.Ext(path))
+	}
+
+	return rows, nil
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"model-test/models"
+)
+
+// Limits on how many results one /api/results request returns
+const (
+	defaultAPILimit = 100
+	maxAPILimit     = 1000
+)
+
+// resultQuery is a parsed /api/results filter; empty fields match everything
+type resultQuery struct {
+	model    string
+	runTags  models.RunTags // tag=key=value
+	testTags []string       // tag=name
+	outcome  string         // pass, fail or infra
+	tool     string
+	since    time.Time
+	until    time.Time
+	text     string // Lower-cased full-text search
+	limit    int
+	offset   int
+}
+
+// apiResult is one matching result with the run it came from
+type apiResult struct {
+	ResultFile string                 `json:"result_file"`
+	RunID      string                 `json:"run_id,omitempty"`
+	RunTags    models.RunTags         `json:"run_tags,omitempty"`
+	Result     models.AgentTestResult `json:"result"`
+}
+
+// apiResults is the /api/results response
+type apiResults struct {
+	Total   int         `json:"total"` // Matches before offset and limit were applied
+	Offset  int         `json:"offset"`
+	Limit   int         `json:"limit"`
+	Results []apiResult `json:"results"`
+}
+
+// parseResultQuery reads the filters of an /api/results request
+func parseResultQuery(values url.Values) (resultQuery, error) {
+	query := resultQuery{
+		model:   values.Get("model"),
+		runTags: models.RunTags{},
+		outcome: values.Get("outcome"),
+		tool:    values.Get("tool"),
+		text:    strings.ToLower(values.Get("q")),
+		limit:   defaultAPILimit,
+	}
+
+	for _, tag := range values["tag"] {
+		if strings.Contains(tag, "=") {
+			if err := query.runTags.Set(tag); err != nil {
+				return query, err
+			}
+		} else {
+			query.testTags = append(query.testTags, tag)
+		}
+	}
+
+	switch query.outcome {
+	case "", "pass", "fail", "infra":
+	default:
+		return query, fmt.Errorf("outcome must be pass, fail or infra, got %q", query.outcome)
+	}
+
+	var err error
+	if query.since, err = parseQueryTime(values.Get("since")); err != nil {
+		return query, fmt.Errorf("invalid since: %w", err)
+	}
+	if query.until, err = parseQueryTime(values.Get("until")); err != nil {
+		return query, fmt.Errorf("invalid until: %w", err)
+	}
+
+	if limit := values.Get("limit"); limit != "" {
+		if query.limit, err = strconv.Atoi(limit); err != nil || query.limit < 1 {
+			return query, fmt.Errorf("limit must be a positive number, got %q", limit)
+		}
+		query.limit = min(query.limit, maxAPILimit)
+	}
+	if offset := values.Get("offset"); offset != "" {
+		if query.offset, err = strconv.Atoi(offset); err != nil || query.offset < 0 {
+			return query, fmt.Errorf("offset must not be negative, got %q", offset)
+		}
+	}
+
+	return query, nil
+}
+
+// parseQueryTime parses an RFC3339 time or a YYYY-MM-DD date; empty is the zero time
+func parseQueryTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return parsed, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+// matches reports whether a result of a run passes every filter of the query
+func (q resultQuery) matches(report *models.AgentReport, result models.AgentTestResult) bool {
+	if q.model != "" && result.ModelName != q.model {
+		return false
+	}
+	if !report.Tags.Matches(q.runTags) {
+		return false
+	}
+	for _, tag := range q.testTags {
+		if !result.TestCase.HasTag(tag) {
+			return false
+		}
+	}
+
+	switch q.outcome {
+	case "pass":
+		if !result.Success {
+			return false
+		}
+	case "fail":
+		if result.Success {
+			return false
+		}
+	case "infra":
+		if !result.InfrastructureFailure && !result.FailureReason.IsInfrastructure() {
+			return false
+		}
+	}
+
+	if q.tool != "" && !calledTool(result, q.tool) {
+		return false
+	}
+	if !q.since.IsZero() && result.Timestamp.Before(q.since) {
+		return false
+	}
+	if !q.until.IsZero() && !result.Timestamp.Before(q.until) {
+		return false
+	}
+	if q.text != "" && !strings.Contains(strings.ToLower(resultText(result)), q.text) {
+		return false
+	}
+	return true
+}
+
+// calledTool reports whether the model called the tool during the test
+func calledTool(result models.AgentTestResult, tool string) bool {
+	if result.Response == nil {
+		return false
+	}
+	for _, toolCall := range result.Response.ToolCalls {
+		if toolCall.ToolName == tool {
+			return true
+		}
+	}
+	return false
+}
+
+// resultText joins the searchable text of a result: the prompts, the transcript of
+// assistant turns and tool calls, and the failure details
+func resultText(result models.AgentTestResult) string {
+	parts := []string{result.TestCase.Name, result.TestCase.Prompt, result.TestCase.SystemPrompt, result.FailureDetails}
+	if response := result.Response; response != nil {
+		parts = append(parts, response.Message)
+		for _, message := range response.AssistantMessages {
+			parts = append(parts, message.Content, message.Refusal)
+		}
+		for _, toolCall := range response.ToolCalls {
+			parts = append(parts, toolCall.ToolName, toolCall.Arguments, toolCall.Error)
+			if toolCall.Result != nil {
+				if data, err := json.Marshal(toolCall.Result); err == nil {
+					parts = append(parts, string(data))
+				}
+			}
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// handleResultsAPI serves the loaded results matching the query as JSON, oldest first
+func (rs *reviewServer) handleResultsAPI(w http.ResponseWriter, r *http.Request) {
+	query, err := parseResultQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	matches := rs.findResults(query)
+	response := apiResults{Total: len(matches), Offset: query.offset, Limit: query.limit, Results: []apiResult{}}
+	if query.offset < len(matches) {
+		response.Results = matches[query.offset:min(query.offset+query.limit, len(matches))]
+	}
+
+	data, err := json.MarshalIndent(response, "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// findResults returns every loaded result matching the query, oldest first
+func (rs *reviewServer) findResults(query resultQuery) []apiResult {
+	var matches []apiResult
+	for file, report := range rs.reports {
+		for _, result := range report.Results {
+			if query.matches(report, result) {
+				matches = append(matches, apiResult{ResultFile: file, RunID: report.RunID, RunTags: report.Tags, Result: result})
+			}
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if !matches[i].Result.Timestamp.Equal(matches[j].Result.Timestamp) {
+			return matches[i].Result.Timestamp.Before(matches[j].Result.Timestamp)
+		}
+		if matches[i].ResultFile != matches[j].ResultFile {
+			return matches[i].ResultFile < matches[j].ResultFile
+		}
+		return matches[i].Result.TestCase.Name < matches[j].Result.TestCase.Name
+	})
+	return matches
+}
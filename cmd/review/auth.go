@@ -0,0 +1,71 @@
+package main
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// authConfig is who may use the review server: tools with the bearer token and people
+// with a basic auth user name and password. No token and no users leaves it open.
+type authConfig struct {
+	token string
+	users map[string]string // User name -> password
+}
+
+// parseUsers reads basic auth users from a comma-separated list of name:password pairs
+func parseUsers(spec string) (map[string]string, error) {
+	users := make(map[string]string)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, password, ok := strings.Cut(entry, ":")
+		if !ok || name == "" || password == "" {
+			return nil, fmt.Errorf("user %q must be name:password", name)
+		}
+		users[name] = password
+	}
+	return users, nil
+}
+
+// enabled reports whether requests must authenticate
+func (a authConfig) enabled() bool {
+	return a.token != "" || len(a.users) > 0
+}
+
+// authenticate checks the request's credentials, returning the basic auth user name,
+// or "" for the bearer token, and whether the request may proceed
+func (a authConfig) authenticate(r *http.Request) (string, bool) {
+	if !a.enabled() {
+		return "", true
+	}
+
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return "", a.token != "" && secretsEqual(token, a.token)
+	}
+	if name, password, ok := r.BasicAuth(); ok {
+		expected, known := a.users[name]
+		return name, known && secretsEqual(password, expected)
+	}
+	return "", false
+}
+
+// sameOrigin reports whether a request's Origin, when the browser sent one, is the
+// server it was sent to
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	parsed, err := url.Parse(origin)
+	return err == nil && parsed.Host == r.Host
+}
+
+// secretsEqual compares secrets in constant time
+func secretsEqual(given, expected string) bool {
+	return subtle.ConstantTimeCompare([]byte(given), []byte(expected)) == 1
+}
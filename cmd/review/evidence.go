@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"model-test/models"
+)
+
+// evidenceLinks points from a review item to the raw results and request log entries
+// of one result file it was seen in
+type evidenceLinks struct {
+	ResultFile string
+	ResultURL  string
+	LogURL     string // Empty when the run's request log was not found
+}
+
+// findRequestLog returns the request log written by the run, which model-test names
+// agent_test_logs_<model>_<run ID>.log, or "" when it is not in the logs directory
+func findRequestLog(logsDir, runID string) string {
+	if runID == "" {
+		return ""
+	}
+	matches, err := filepath.Glob(filepath.Join(logsDir, "agent_test_logs_*_"+runID+".log"))
+	if err != nil || len(matches) == 0 {
+		return ""
+	}
+	return matches[0]
+}
+
+// evidenceFor builds the links for each result file of a review item, to the server's
+// endpoints or to the files of a static snapshot
+func (rs *reviewServer) evidenceFor(item models.ReviewItem, static bool) []evidenceLinks {
+	links := make([]evidenceLinks, 0, len(item.ResultFiles))
+	for i, file := range item.ResultFiles {
+		query := url.Values{"file": {file}, "test": {item.TestCase}}.Encode()
+		link := evidenceLinks{ResultFile: file, ResultURL: "/result?" + query}
+		if static {
+			link.ResultURL = staticEvidencePath(item, i, ".json")
+		}
+		if rs.requestLogs[file] != "" {
+			link.LogURL = "/log?" + query
+			if static {
+				link.LogURL = staticEvidencePath(item, i, ".ndjson")
+			}
+		}
+		links = append(links, link)
+	}
+	return links
+}
+
+// resultsForTest returns the results of one test case in a report
+func resultsForTest(report *models.AgentReport, testCase string) []models.AgentTestResult {
+	var results []models.AgentTestResult
+	for _, result := range report.Results {
+		if result.TestCase.Name == testCase {
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+// handleResult writes the raw JSON results of one test case in a loaded result file
+func (rs *reviewServer) handleResult(w http.ResponseWriter, r *http.Request) {
+	file, testCase := r.URL.Query().Get("file"), r.URL.Query().Get("test")
+	report, ok := rs.reports[file]
+	if !ok {
+		http.Error(w, "unknown result file", http.StatusNotFound)
+		return
+	}
+
+	data, err := json.MarshalIndent(resultsForTest(report, testCase), "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// handleLog writes the request log entries of one test case in the run that produced
+// a loaded result file, one JSON entry per line as in the log itself
+func (rs *reviewServer) handleLog(w http.ResponseWriter, r *http.Request) {
+	file, testCase := r.URL.Query().Get("file"), r.URL.Query().Get("test")
+	logFile, ok := rs.requestLogs[file]
+	if !ok || logFile == "" {
+		http.Error(w, "no request log for result file", http.StatusNotFound)
+		return
+	}
+
+	entries, err := readLogEntries(logFile, testCase)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	for _, entry := range entries {
+		w.Write(entry)
+		w.Write([]byte("\n"))
+	}
+}
+
+// readLogEntries returns the raw lines of a request log that belong to the test case
+func readLogEntries(logFile, testCase string) ([][]byte, error) {
+	f, err := os.Open(logFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open request log: %w", err)
+	}
+	defer f.Close()
+
+	var entries [][]byte
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		var entry struct {
+			TestCase string `json:"test_case"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			log.Printf("Warning: skipping malformed entry in %s: %v", logFile, err)
+			continue
+		}
+		if entry.TestCase == testCase {
+			entries = append(entries, append([]byte(nil), scanner.Bytes()...))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read request log: %w", err)
+	}
+	return entries, nil
+}
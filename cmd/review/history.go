@@ -0,0 +1,233 @@
+package main
+
+import (
+	"encoding/json"
+	"html/template"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"model-test/models"
+)
+
+// batchManifest holds the fields of a batch directory's batch_manifest.json that
+// identify the batch
+type batchManifest struct {
+	BatchID   string `json:"batch_id"`
+	CreatedAt string `json:"created_at"`
+}
+
+// batchInfo identifies the batch a result file belongs to: the batch directory's
+// manifest when it has one, otherwise the directory itself
+type batchInfo struct {
+	ID        string    `json:"batch_id"`
+	Dir       string    `json:"dir"`
+	CreatedAt time.Time `json:"created_at"` // From the manifest, else the earliest run in the directory
+}
+
+// batchPoint is one model's results in one batch
+type batchPoint struct {
+	batchInfo
+	Runs                   int     `json:"runs"`
+	Tests                  int     `json:"tests"`
+	Passed                 int     `json:"passed"`
+	PassRate               float64 `json:"pass_rate"`
+	InfrastructureFailures int     `json:"infrastructure_failures,omitempty"`
+}
+
+// modelHistory is a model's results in every loaded batch, oldest batch first
+type modelHistory struct {
+	Model   string       `json:"model"`
+	Batches []batchPoint `json:"batches"`
+}
+
+// historyView is what the history page renders: every batch as a column and every
+// model as a row, with the model's point in each batch or nil when it did not run
+type historyView struct {
+	Batches []batchInfo
+	Rows    []historyRow
+	Links   siteLinks
+}
+
+// historyRow is one model's row of the history page
+type historyRow struct {
+	Model  string
+	Points []*batchPoint
+}
+
+// loadBatchInfo identifies the batch of a directory, reading its manifest when present
+func loadBatchInfo(dir string) batchInfo {
+	info := batchInfo{ID: filepath.Base(dir), Dir: dir}
+
+	data, err := os.ReadFile(filepath.Join(dir, "batch_manifest.json"))
+	if err != nil {
+		return info
+	}
+	var manifest batchManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		log.Printf("Warning: ignoring malformed batch manifest in %s: %v", dir, err)
+		return info
+	}
+	if manifest.BatchID != "" {
+		info.ID = manifest.BatchID
+	}
+	if createdAt, err := time.Parse(time.RFC3339, manifest.CreatedAt); err == nil {
+		info.CreatedAt = createdAt
+	}
+	return info
+}
+
+// buildModelHistories groups the loaded result files by batch directory and
+// summarizes each model's results per batch, so a model can be followed across batches
+func buildModelHistories(reports map[string]*models.AgentReport) ([]batchInfo, []modelHistory) {
+	batches := make(map[string]*batchInfo)
+	points := make(map[string]map[string]*batchPoint) // Model -> batch directory -> point
+	earliest := make(map[string]time.Time)            // Batch directory -> earliest run
+
+	for file, report := range reports {
+		dir := filepath.Dir(file)
+		if _, ok := batches[dir]; !ok {
+			info := loadBatchInfo(dir)
+			batches[dir] = &info
+		}
+		if first, ok := earliest[dir]; !ok || report.Timestamp.Before(first) {
+			earliest[dir] = report.Timestamp
+		}
+
+		inReport := make(map[string]bool)
+		for _, result := range report.Results {
+			if points[result.ModelName] == nil {
+				points[result.ModelName] = make(map[string]*batchPoint)
+			}
+			point, ok := points[result.ModelName][dir]
+			if !ok {
+				point = &batchPoint{}
+				points[result.ModelName][dir] = point
+			}
+			if !inReport[result.ModelName] {
+				inReport[result.ModelName] = true
+				point.Runs++
+			}
+			point.Tests++
+			if result.Success {
+				point.Passed++
+			}
+			if result.InfrastructureFailure || result.FailureReason.IsInfrastructure() {
+				point.InfrastructureFailures++
+			}
+		}
+	}
+
+	// Batches without a manifest are dated by their earliest run
+	for dir, batch := range batches {
+		if batch.CreatedAt.IsZero() {
+			batch.CreatedAt = earliest[dir]
+		}
+	}
+
+	ordered := make([]batchInfo, 0, len(batches))
+	for _, batch := range batches {
+		ordered = append(ordered, *batch)
+	}
+	sort.Slice(ordered, func(i, j int) bool {
+		if !ordered[i].CreatedAt.Equal(ordered[j].CreatedAt) {
+			return ordered[i].CreatedAt.Before(ordered[j].CreatedAt)
+		}
+		return ordered[i].Dir < ordered[j].Dir
+	})
+
+	histories := make([]modelHistory, 0, len(points))
+	for model, byDir := range points {
+		history := modelHistory{Model: model}
+		for _, batch := range ordered {
+			point, ok := byDir[batch.Dir]
+			if !ok {
+				continue
+			}
+			point.batchInfo = batch
+			if point.Tests > 0 {
+				point.PassRate = float64(point.Passed) / float64(point.Tests) * 100
+			}
+			history.Batches = append(history.Batches, *point)
+		}
+		histories = append(histories, history)
+	}
+	sort.Slice(histories, func(i, j int) bool {
+		return histories[i].Model < histories[j].Model
+	})
+
+	return ordered, histories
+}
+
+// historyJSON encodes each model's results per batch
+func (rs *reviewServer) historyJSON() ([]byte, error) {
+	return json.MarshalIndent(map[string]interface{}{
+		"batches": rs.batches,
+		"models":  rs.histories,
+	}, "", "  ")
+}
+
+// handleHistoryAPI serves each model's results per batch as JSON
+func (rs *reviewServer) handleHistoryAPI(w http.ResponseWriter) {
+	data, err := rs.historyJSON()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// handleHistory renders a table of each model's pass rate in every loaded batch
+func (rs *reviewServer) handleHistory(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := rs.renderHistory(w, serverLinks); err != nil {
+		log.Printf("Failed to render history page: %v", err)
+	}
+}
+
+// renderHistory writes the history page with the given links to the other pages
+func (rs *reviewServer) renderHistory(w io.Writer, links siteLinks) error {
+	view := historyView{Batches: rs.batches, Links: links}
+	for _, history := range rs.histories {
+		row := historyRow{Model: history.Model, Points: make([]*batchPoint, len(rs.batches))}
+		for _, point := range history.Batches {
+			for i, batch := range rs.batches {
+				if batch.Dir == point.Dir {
+					row.Points[i] = &point
+				}
+			}
+		}
+		view.Rows = append(view.Rows, row)
+	}
+	return historyTemplate.Execute(w, view)
+}
+
+// historyTemplate renders models as rows and batches as columns, oldest first
+var historyTemplate = template.Must(template.New("history").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Model History</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 0.4em 0.8em; text-align: right; }
+th:first-child, td:first-child { text-align: left; }
+small { color: #666; }
+</style>
+</head>
+<body>
+<h1>Model History ({{len .Batches}} batches)</h1>
+<p><a href="{{.Links.Queue}}">Review queue</a>{{if .Links.Results}} · <a href="{{.Links.Results}}">All results</a>{{end}} · <a href="{{.Links.HistoryJSON}}">JSON</a></p>
+<table>
+<tr><th>Model</th>{{range .Batches}}<th>{{.ID}}<br><small>{{.CreatedAt.Format "2006-01-02 15:04"}}</small></th>{{end}}</tr>
+{{range .Rows}}<tr><td>{{.Model}}</td>{{range .Points}}<td>{{if .}}{{printf "%.1f" .PassRate}}%<br><small>{{.Passed}}/{{.Tests}} in {{.Runs}} runs{{if .InfrastructureFailures}}, {{.InfrastructureFailures}} infra{{end}}</small>{{else}}-{{end}}</td>{{end}}</tr>
+{{end}}</table>
+</body>
+</html>
+`))
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"model-test/models"
+	"model-test/services"
+)
+
+func main() {
+	var (
+		queueFile         = flag.String("queue", "review/queue.json", "Where to write the queue of borderline results awaiting review")
+		adjudicationsFile = flag.String("adjudications", "review/adjudications.json", "Where human decisions are stored")
+		serveAddr         = flag.String("serve", "", "Serve a web UI for adjudicating the queue on this address (e.g. :8090)")
+		logsDir           = flag.String("logs", "logs", "Directory holding the runs' request logs, linked from each item in the web UI")
+		reviewer          = flag.String("reviewer", os.Getenv("USER"), "Name recorded with each decision made in the web UI, unless the reviewer signed in")
+		readOnly          = flag.Bool("read-only", false, "Serve the queue, results and history without accepting decisions")
+		exportSite        = flag.String("export-site", "", "Render the review queue, model history and results into this directory as a static site that needs no server")
+		exportFile        = flag.String("export", "", "Export every undecided failed result with its transcript for external labeling (.csv or .jsonl)")
+		importFile        = flag.String("import", "", "Import labels (pass or fail) from a labeled export as adjudications")
+		judgeGrayZone     = flag.String("judge-gray-zone", "0.4-0.6", "Queue failed results whose judge score falls in this band, as low-high on the judge's 0-1 scale (empty to ignore judge scores)")
+	)
+	flag.Parse()
+
+	grayZone, err := parseJudgeGrayZone(*judgeGrayZone)
+	if err != nil {
+		log.Fatalf("Invalid -judge-gray-zone: %v", err)
+	}
+
+	if len(flag.Args()) < 1 && *importFile == "" {
+		fmt.Fprintf(os.Stderr, "Usage: %s [options] <result_file_or_directory> ...\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s -import <labels.csv|labels.jsonl>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "\nQueue borderline results (near-miss matches) for human adjudication, or export failed\n")
+		fmt.Fprintf(os.Stderr, "results for external labeling and import the labels back.\n")
+		fmt.Fprintf(os.Stderr, "\nOptions:\n")
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+
+	adjudications, err := services.LoadAdjudications(*adjudicationsFile)
+	if err != nil {
+		log.Fatalf("Failed to load adjudications: %v", err)
+	}
+
+	if *importFile != "" {
+		imported, err := importAnnotations(*importFile, adjudications)
+		if err != nil {
+			log.Fatalf("Failed to import labels: %v", err)
+		}
+		if err := services.SaveAdjudications(*adjudicationsFile, adjudications); err != nil {
+			log.Fatalf("Failed to save adjudications: %v", err)
+		}
+		fmt.Printf("📥 Imported %d labels from %s into %s\n", imported, *importFile, *adjudicationsFile)
+		if len(flag.Args()) == 0 {
+			return
+		}
+	}
+
+	files, err := services.FindResultFiles(flag.Args())
+	if err != nil {
+		log.Fatalf("Failed to find result files: %v", err)
+	}
+	if len(files) == 0 {
+		log.Fatalf("No result files found in: %v", flag.Args())
+	}
+
+	reports := make(map[string]*models.AgentReport)
+	results := make(map[string][]models.AgentTestResult)
+	for _, file := range files {
+		report, err := loadReport(file)
+		if err != nil {
+			log.Printf("Warning: skipping %s: %v", file, err)
+			continue
+		}
+		reports[file] = report
+		results[file] = report.Results
+	}
+
+	if *exportFile != "" {
+		rows := buildAnnotationRows(results, adjudications)
+		if err := exportAnnotations(*exportFile, rows); err != nil {
+			log.Fatalf("Failed to export failed results: %v", err)
+		}
+		fmt.Printf("📤 Exported %d failed behaviors for labeling to %s\n", len(rows), *exportFile)
+	}
+
+	queue := services.BuildReviewQueue(results, adjudications, grayZone)
+	if err := saveQueue(*queueFile, queue); err != nil {
+		log.Fatalf("Failed to save review queue: %v", err)
+	}
+
+	fmt.Printf("🔎 %d borderline results awaiting review (%d already adjudicated)\n", len(queue), len(adjudications))
+	for _, item := range queue {
+		fmt.Printf("  %s  %-40s %s (%d occurrences)\n", item.Key, item.TestCase, item.Reason, item.Occurrences)
+	}
+	fmt.Printf("💾 Review queue saved to: %s\n", *queueFile)
+
+	if *serveAddr == "" && *exportSite == "" {
+		return
+	}
+
+	users, err := parseUsers(os.Getenv("REVIEW_USERS"))
+	if err != nil {
+		log.Fatalf("Invalid REVIEW_USERS: %v", err)
+	}
+
+	server := newReviewServer(queue, adjudications, *queueFile, *adjudicationsFile, *reviewer, reports, *logsDir)
+	server.auth = authConfig{token: os.Getenv("REVIEW_TOKEN"), users: users}
+	server.readOnly = *readOnly
+
+	if *exportSite != "" {
+		if err := server.exportSite(*exportSite); err != nil {
+			log.Fatalf("Failed to export static site: %v", err)
+		}
+		fmt.Printf("🗂️  Static snapshot written to: %s (open %s)\n", *exportSite, filepath.Join(*exportSite, "index.html"))
+		if *serveAddr == "" {
+			return
+		}
+	}
+
+	if !server.auth.enabled() {
+		fmt.Printf("🔓 No authentication: set REVIEW_USERS or REVIEW_TOKEN before exposing the review UI\n")
+	}
+	if server.readOnly {
+		fmt.Printf("🔒 Read-only: decisions are not accepted\n")
+	}
+	fmt.Printf("🌐 Review UI at http://localhost%s (decisions saved to %s)\n", *serveAddr, *adjudicationsFile)
+	if err := http.ListenAndServe(*serveAddr, server); err != nil {
+		log.Fatalf("Review server stopped: %v", err)
+	}
+}
+
+// parseJudgeGrayZone reads a judge score band written as low-high, e.g. 0.4-0.6. An
+// empty band yields nil, leaving judge scores out of the queue.
+func parseJudgeGrayZone(spec string) (*services.JudgeGrayZone, error) {
+	if spec == "" {
+		return nil, nil
+	}
+	lowText, highText, ok := strings.Cut(spec, "-")
+	if !ok {
+		return nil, fmt.Errorf("%q must be low-high, e.g. 0.4-0.6", spec)
+	}
+	low, err := strconv.ParseFloat(strings.TrimSpace(lowText), 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid low score %q", lowText)
+	}
+	high, err := strconv.ParseFloat(strings.TrimSpace(highText), 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid high score %q", highText)
+	}
+	if low < 0 || high > 1 || low > high {
+		return nil, fmt.Errorf("%q must satisfy 0 <= low <= high <= 1", spec)
+	}
+	return &services.JudgeGrayZone{Low: low, High: high}, nil
+}
+
+// saveQueue writes the review queue as JSON
+func saveQueue(path string, queue []models.ReviewItem) error {
+	data, err := json.MarshalIndent(queue, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal review queue: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create review directory: %w", err)
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// loadReport loads an agent report from a result file
+func loadReport(filename string) (*models.AgentReport, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var report models.AgentReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, err
+	}
+
+	if report.SchemaVersion > models.ResultSchemaVersion {
+		return nil, fmt.Errorf("result schema version %d is newer than supported version %d", report.SchemaVersion, models.ResultSchemaVersion)
+	}
+
+	return &report, nil
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"html/template"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"model-test/models"
+	"model-test/services"
+)
+
+// reviewServer serves a minimal web UI for adjudicating the review queue
+type reviewServer struct {
+	mutex             sync.Mutex
+	queue             []models.ReviewItem
+	adjudications     map[string]models.Adjudication
+	queueFile         string
+	adjudicationsFile string
+	reviewer          string
+
+	reports     map[string]*models.AgentReport // Loaded result files, served as evidence
+	requestLogs map[string]string              // Request log of each result file's run, when found
+
+	batches   []batchInfo    // Batch directories of the loaded result files, oldest first
+	histories []modelHistory // Each model's results per batch
+
+	auth      authConfig // Credentials required for every request, when configured
+	readOnly  bool       // Serve the queue and results but refuse decisions
+	csrfToken string     // Embedded in the decision forms; decisions without it are refused
+}
+
+// indexPage is what the review page renders
+type indexPage struct {
+	Items     []indexItem
+	ReadOnly  bool
+	Links     siteLinks
+	CSRFToken string
+}
+
+// siteLinks are where the pages link to each other, which differs between the
+// server and a static snapshot
+type siteLinks struct {
+	Queue       string
+	History     string
+	HistoryJSON string
+	Results     string // Searchable list of all results; only in a snapshot
+}
+
+// serverLinks are the links between the server's pages
+var serverLinks = siteLinks{Queue: "/", History: "/history", HistoryJSON: "/api/history"}
+
+// indexItem is a pending review item with links to its evidence
+type indexItem struct {
+	models.ReviewItem
+	Evidence []evidenceLinks
+}
+
+// newReviewServer creates a review server over a queue and the result files it was
+// built from, finding each run's request log in logsDir
+func newReviewServer(queue []models.ReviewItem, adjudications map[string]models.Adjudication, queueFile, adjudicationsFile, reviewer string, reports map[string]*models.AgentReport, logsDir string) *reviewServer {
+	requestLogs := make(map[string]string, len(reports))
+	for file, report := range reports {
+		requestLogs[file] = findRequestLog(logsDir, report.RunID)
+	}
+	batches, histories := buildModelHistories(reports)
+
+	return &reviewServer{
+		queue:             queue,
+		adjudications:     adjudications,
+		queueFile:         queueFile,
+		adjudicationsFile: adjudicationsFile,
+		reviewer:          reviewer,
+		reports:           reports,
+		requestLogs:       requestLogs,
+		batches:           batches,
+		histories:         histories,
+		csrfToken:         newCSRFToken(),
+	}
+}
+
+// newCSRFToken generates the secret the server's decision forms must send back, so a
+// page on another site cannot submit decisions with the browser's saved credentials
+func newCSRFToken() string {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		log.Fatalf("Failed to generate CSRF token: %v", err)
+	}
+	return hex.EncodeToString(token)
+}
+
+// ServeHTTP checks the request's credentials, then lists pending items on GET /, records a decision on POST /decide, serves
+// an item's raw results on GET /result and its request log entries on GET /log, shows
+// each model's results across the loaded batches on GET /history (JSON on
+// GET /api/history), and answers queries over all loaded results on GET /api/results
+func (rs *reviewServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	user, ok := rs.auth.authenticate(r)
+	if !ok {
+		w.Header().Set("WWW-Authenticate", `Basic realm="review"`)
+		http.Error(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	switch {
+	case r.URL.Path == "/" && r.Method == http.MethodGet:
+		rs.handleIndex(w)
+	case r.URL.Path == "/decide" && r.Method == http.MethodPost:
+		rs.handleDecide(w, r, user)
+	case r.URL.Path == "/result" && r.Method == http.MethodGet:
+		rs.handleResult(w, r)
+	case r.URL.Path == "/log" && r.Method == http.MethodGet:
+		rs.handleLog(w, r)
+	case r.URL.Path == "/history" && r.Method == http.MethodGet:
+		rs.handleHistory(w)
+	case r.URL.Path == "/api/history" && r.Method == http.MethodGet:
+		rs.handleHistoryAPI(w)
+	case r.URL.Path == "/api/results" && r.Method == http.MethodGet:
+		rs.handleResultsAPI(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// handleIndex renders the pending review items
+func (rs *reviewServer) handleIndex(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := rs.renderIndex(w, false); err != nil {
+		log.Printf("Failed to render review page: %v", err)
+	}
+}
+
+// renderIndex writes the review page, for a static snapshot without decision forms
+// and with evidence links to the snapshot's files
+func (rs *reviewServer) renderIndex(w io.Writer, static bool) error {
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+
+	page := indexPage{ReadOnly: rs.readOnly || static, Links: serverLinks, CSRFToken: rs.csrfToken}
+	if static {
+		page.Links = staticLinks
+		page.CSRFToken = ""
+	}
+	for _, item := range rs.queue {
+		page.Items = append(page.Items, indexItem{ReviewItem: item, Evidence: rs.evidenceFor(item, static)})
+	}
+	return indexTemplate.Execute(w, page)
+}
+
+// handleDecide stores a decision, removes the item from the queue and returns to the list.
+// The decision is recorded under the signed-in user's name when there is one. Decisions
+// must come from the server's own form: with its CSRF token and, when the browser sends
+// an Origin, from the server's origin.
+func (rs *reviewServer) handleDecide(w http.ResponseWriter, r *http.Request, user string) {
+	if rs.readOnly {
+		http.Error(w, "the review server is read-only", http.StatusForbidden)
+		return
+	}
+	if !sameOrigin(r) || !secretsEqual(r.FormValue("csrf"), rs.csrfToken) {
+		http.Error(w, "decisions must be submitted from the review page", http.StatusForbidden)
+		return
+	}
+
+	key := r.FormValue("key")
+	verdict := models.ReviewVerdict(r.FormValue("verdict"))
+	if verdict != models.VerdictPass && verdict != models.VerdictFail {
+		http.Error(w, "verdict must be pass or fail", http.StatusBadRequest)
+		return
+	}
+
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+
+	index := -1
+	for i, item := range rs.queue {
+		if item.Key == key {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		http.Error(w, "unknown review item", http.StatusNotFound)
+		return
+	}
+
+	reviewer := rs.reviewer
+	if user != "" {
+		reviewer = user
+	}
+	rs.adjudications[key] = models.Adjudication{
+		Key:       key,
+		TestCase:  rs.queue[index].TestCase,
+		Verdict:   verdict,
+		Note:      r.FormValue("note"),
+		Reviewer:  reviewer,
+		Source:    "review_ui",
+		DecidedAt: time.Now(),
+	}
+	if err := services.SaveAdjudications(rs.adjudicationsFile, rs.adjudications); err != nil {
+		delete(rs.adjudications, key)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rs.queue = append(rs.queue[:index], rs.queue[index+1:]...)
+	if err := saveQueue(rs.queueFile, rs.queue); err != nil {
+		log.Printf("Failed to update review queue: %v", err)
+	}
+
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// indexTemplate renders the review queue with a pass/fail form per item
+var indexTemplate = template.Must(template.New("index").Funcs(template.FuncMap{
+	"json": func(v interface{}) string {
+		data, _ := json.Marshal(v)
+		return string(data)
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Review Queue</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+.item { border: 1px solid #ccc; border-radius: 4px; padding: 1em; margin-bottom: 1em; }
+pre { background: #f6f6f6; padding: 0.5em; white-space: pre-wrap; }
+</style>
+</head>
+<body>
+<h1>Review Queue ({{len .Items}} pending){{if .ReadOnly}} <small>read-only</small>{{end}}</h1>
+<p><a href="{{.Links.History}}">Model history across batches</a>{{if .Links.Results}} · <a href="{{.Links.Results}}">All results</a>{{end}}</p>
+{{range .Items}}
+<div class="item">
+<h3>{{.TestCase}} <small>{{.Key}}</small></h3>
+<p><b>Prompt:</b> {{.Prompt}}</p>
+<p><b>Why queued:</b> {{.Reason}} ({{.FailureReason}}: {{.FailureDetails}})</p>
+{{with .Judge}}{{if not .Error}}<p><b>Judge ({{.Model}}):</b> {{printf "%.2f" .Score}}{{if .Rationale}}: {{.Rationale}}{{end}}</p>{{end}}{{end}}
+<p><b>Seen:</b> {{.Occurrences}} times, models {{range $i, $m := .Models}}{{if $i}}, {{end}}{{$m}}{{end}}</p>
+<p><b>Actual tool calls:</b></p>
+<pre>{{range .ToolCalls}}{{.Name}} {{json .Arguments}}
+{{end}}</pre>
+<p><b>Expected variants:</b></p>
+<pre>{{range .ExpectedVariants}}{{.Name}}:{{range .Tools}} {{.Name}} {{json .Arguments}}{{end}}
+{{end}}</pre>
+<p><b>Evidence:</b></p>
+<ul>
+{{range .Evidence}}<li>{{.ResultFile}}: <a href="{{.ResultURL}}">raw result JSON</a>{{if .LogURL}} · <a href="{{.LogURL}}">request log entries</a>{{end}}</li>
+{{end}}</ul>
+{{if not $.ReadOnly}}<form method="post" action="/decide">
+<input type="hidden" name="key" value="{{.Key}}">
+<input type="hidden" name="csrf" value="{{$.CSRFToken}}">
+<input type="text" name="note" placeholder="Note (optional)" size="60">
+<button name="verdict" value="pass">Pass</button>
+<button name="verdict" value="fail">Fail</button>
+</form>{{end}}
+</div>
+{{else}}
+<p>Nothing left to review.</p>
+{{end}}
+</body>
+</html>
+`))
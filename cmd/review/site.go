@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"model-test/models"
+)
+
+// staticLinks are the links between the pages of a static snapshot, relative so the
+// snapshot can be published under any path
+var staticLinks = siteLinks{Queue: "index.html", History: "history.html", HistoryJSON: "api/history.json", Results: "results.html"}
+
+// resultRow is one result in the search table of a static snapshot
+type resultRow struct {
+	Model      string    `json:"model"`
+	TestCase   string    `json:"test_case"`
+	Outcome    string    `json:"outcome"` // pass, fail or infra
+	Reason     string    `json:"reason,omitempty"`
+	Tools      []string  `json:"tools,omitempty"`
+	Timestamp  time.Time `json:"timestamp"`
+	ResultFile string    `json:"result_file"`
+}
+
+// staticEvidencePath is where a static snapshot stores the evidence of a review item
+// from its i-th result file
+func staticEvidencePath(item models.ReviewItem, i int, extension string) string {
+	return fmt.Sprintf("evidence/%s-%d%s", item.Key, i, extension)
+}
+
+// exportSite renders the review queue, the model history and the loaded results into
+// a directory of static files that can be published without running the server
+func (rs *reviewServer) exportSite(dir string) error {
+	for _, sub := range []string{"api", "evidence"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+			return fmt.Errorf("failed to create site directory: %w", err)
+		}
+	}
+
+	var index, history, results bytes.Buffer
+	if err := rs.renderIndex(&index, true); err != nil {
+		return fmt.Errorf("failed to render review page: %w", err)
+	}
+	if err := rs.renderHistory(&history, staticLinks); err != nil {
+		return fmt.Errorf("failed to render history page: %w", err)
+	}
+	matches := rs.findResults(resultQuery{})
+	if err := renderResultsPage(&results, matches); err != nil {
+		return fmt.Errorf("failed to render results page: %w", err)
+	}
+
+	historyData, err := rs.historyJSON()
+	if err != nil {
+		return fmt.Errorf("failed to marshal history: %w", err)
+	}
+	resultsData, err := json.MarshalIndent(apiResults{Total: len(matches), Limit: len(matches), Results: matches}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal results: %w", err)
+	}
+
+	files := map[string][]byte{
+		"index.html":       index.Bytes(),
+		"history.html":     history.Bytes(),
+		"results.html":     results.Bytes(),
+		"api/history.json": historyData,
+		"api/results.json": resultsData,
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+	}
+
+	return rs.exportEvidence(dir)
+}
+
+// exportEvidence writes the raw results and request log entries every review item
+// links to
+func (rs *reviewServer) exportEvidence(dir string) error {
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+
+	for _, item := range rs.queue {
+		for i, file := range item.ResultFiles {
+			report, ok := rs.reports[file]
+			if !ok {
+				continue
+			}
+			data, err := json.MarshalIndent(resultsForTest(report, item.TestCase), "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to marshal results of %s: %w", item.Key, err)
+			}
+			if err := os.WriteFile(filepath.Join(dir, staticEvidencePath(item, i, ".json")), data, 0644); err != nil {
+				return fmt.Errorf("failed to write evidence: %w", err)
+			}
+
+			logFile := rs.requestLogs[file]
+			if logFile == "" {
+				continue
+			}
+			entries, err := readLogEntries(logFile, item.TestCase)
+			if err != nil {
+				return err
+			}
+			var buf bytes.Buffer
+			for _, entry := range entries {
+				buf.Write(entry)
+				buf.WriteByte('\n')
+			}
+			if err := os.WriteFile(filepath.Join(dir, staticEvidencePath(item, i, ".ndjson")), buf.Bytes(), 0644); err != nil {
+				return fmt.Errorf("failed to write evidence: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// renderResultsPage writes a page listing every result, embedding them as JSON and
+// filtering them in the browser
+func renderResultsPage(w io.Writer, matches []apiResult) error {
+	rows := make([]resultRow, 0, len(matches))
+	for _, match := range matches {
+		result := match.Result
+		row := resultRow{
+			Model:      result.ModelName,
+			TestCase:   result.TestCase.Name,
+			Outcome:    "fail",
+			Reason:     string(result.FailureReason),
+			Timestamp:  result.Timestamp,
+			ResultFile: match.ResultFile,
+		}
+		switch {
+		case result.Success:
+			row.Outcome = "pass"
+		case result.InfrastructureFailure || result.FailureReason.IsInfrastructure():
+			row.Outcome = "infra"
+		}
+		if result.Response != nil {
+			for _, toolCall := range result.Response.ToolCalls {
+				row.Tools = append(row.Tools, toolCall.ToolName)
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	data, err := json.Marshal(rows)
+	if err != nil {
+		return err
+	}
+	return resultsTemplate.Execute(w, map[string]interface{}{
+		"Links": staticLinks,
+		"Count": len(rows),
+		"Rows":  template.JS(data),
+	})
+}
+
+// resultsTemplate renders the embedded results as a table filtered by outcome and by
+// text in the model, test case, tools and result file
+var resultsTemplate = template.Must(template.New("results").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Results</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.6em; text-align: left; }
+.pass { color: #080; } .fail { color: #a00; } .infra { color: #a60; }
+</style>
+</head>
+<body>
+<h1>Results ({{.Count}})</h1>
+<p><a href="{{.Links.Queue}}">Review queue</a> · <a href="{{.Links.History}}">Model history</a> · <a href="api/results.json">JSON</a></p>
+<p>
+<input id="q" type="search" placeholder="Model, test case, tool or file" size="50">
+<select id="outcome"><option value="">Any outcome</option><option>pass</option><option>fail</option><option>infra</option></select>
+<span id="shown"></span>
+</p>
+<table>
+<thead><tr><th>Time</th><th>Model</th><th>Test Case</th><th>Outcome</th><th>Tools</th><th>Result File</th></tr></thead>
+<tbody id="rows"></tbody>
+</table>
+<script>
+const rows = {{.Rows}};
+function render() {
+  const q = document.getElementById("q").value.toLowerCase();
+  const outcome = document.getElementById("outcome").value;
+  const body = document.getElementById("rows");
+  body.replaceChildren();
+  let shown = 0;
+  for (const row of rows) {
+    const text = [row.model, row.test_case, (row.tools || []).join(" "), row.result_file].join(" ").toLowerCase();
+    if ((outcome && row.outcome !== outcome) || (q && !text.includes(q))) continue;
+    const tr = body.insertRow();
+    for (const value of [row.timestamp, row.model, row.test_case, row.outcome + (row.reason ? " (" + row.reason + ")" : ""), (row.tools || []).join(", "), row.result_file]) {
+      tr.insertCell().textContent = value;
+    }
+    tr.cells[3].className = row.outcome;
+    shown++;
+  }
+  document.getElementById("shown").textContent = shown + " shown";
+}
+document.getElementById("q").addEventListener("input", render);
+document.getElementById("outcome").addEventListener("change", render);
+render();
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"model-test/models"
+	"model-test/services"
+)
+
+// selection picks the log entries to convert
+type selection struct {
+	testCase  string // Glob pattern; empty selects every test case
+	iteration int    // 0 selects every iteration
+	line      int    // 1-based line of the log file; 0 selects every line
+	errors    bool   // Only entries whose request failed
+}
+
+// unsafeVariableChars are replaced in the environment variable named after a header
+var unsafeVariableChars = regexp.MustCompile(`[^A-Z0-9]+`)
+
+// logEntry is a log entry with the line of its file it was read from
+type logEntry struct {
+	line  int
+	entry models.LogEntry
+}
+
+func main() {
+	var (
+		testCase  = flag.String("test-case", "", "Only convert entries of test cases matching this name or glob pattern (e.g. \"complex_*\")")
+		iteration = flag.Int("iteration", 0, "Only convert entries of this agent loop iteration (0 = all)")
+		line      = flag.Int("line", 0, "Only convert the entry on this line of the log file (0 = all)")
+		errors    = flag.Bool("errors", false, "Only convert entries whose request failed or got an error status")
+		format    = flag.String("format", "curl", "Command to generate: curl or httpie")
+		baseURL   = flag.String("base-url", "", "Send the requests to this base URL (e.g. http://localhost:8000/v1) instead of the one they were logged with")
+		compact   = flag.Bool("compact", false, "Put each request body on one line instead of indenting it")
+	)
+	flag.Parse()
+
+	if len(flag.Args()) < 1 {
+		fmt.Fprintf(os.Stderr, "Usage: %s [options] <request_log> ...\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "\nConvert request log entries (logs/agent_test_logs_*.log) into curl or HTTPie commands that replay them.\n")
+		fmt.Fprintf(os.Stderr, "The API key is left as ${OPENAI_API_KEY} and redacted header values as variables named after\n")
+		fmt.Fprintf(os.Stderr, "the header (X-Api-Key as ${X_API_KEY}); set them before running the commands.\n")
+		fmt.Fprintf(os.Stderr, "\nOptions:\n")
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+	if *format != "curl" && *format != "httpie" {
+		log.Fatalf("Invalid -format %q: expected curl or httpie", *format)
+	}
+	if _, err := path.Match(*testCase, ""); err != nil {
+		log.Fatalf("Invalid -test-case: %v", err)
+	}
+	if *iteration < 0 || *line < 0 {
+		log.Fatalf("Invalid -iteration or -line: must not be negative")
+	}
+	filter := selection{testCase: *testCase, iteration: *iteration, line: *line, errors: *errors}
+
+	converted := 0
+	for _, file := range flag.Args() {
+		entries, err := readLogEntries(file, filter)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		for _, entry := range entries {
+			command, err := renderCommand(entry, *format, *baseURL, *compact)
+			if err != nil {
+				log.Printf("Warning: skipping %s line %d: %v", file, entry.line, err)
+				continue
+			}
+			if converted > 0 {
+				fmt.Println()
+			}
+			fmt.Print(describeEntry(file, entry))
+			fmt.Print(command)
+			converted++
+		}
+	}
+	if converted == 0 {
+		log.Fatalf("No log entries matched")
+	}
+}
+
+// readLogEntries reads the entries of a request log that the selection picks
+func readLogEntries(file string, filter selection) ([]logEntry, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open request log: %w", err)
+	}
+	defer f.Close()
+
+	var entries []logEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for number := 1; scanner.Scan(); number++ {
+		if filter.line > 0 && number != filter.line {
+			continue
+		}
+		var entry models.LogEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			log.Printf("Warning: skipping malformed entry in %s line %d: %v", file, number, err)
+			continue
+		}
+		if filter.testCase != "" {
+			if matched, _ := path.Match(filter.testCase, entry.TestCase); !matched {
+				continue
+			}
+		}
+		if filter.iteration > 0 && entry.Iteration != filter.iteration {
+			continue
+		}
+		if filter.errors && entry.Error == "" && entry.Response.StatusCode < 400 {
+			continue
+		}
+		entries = append(entries, logEntry{line: number, entry: entry})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read request log: %w", err)
+	}
+	return entries, nil
+}
+
+// describeEntry renders the comment naming where an entry came from and how it ended
+func describeEntry(file string, entry logEntry) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# %s line %d: %s iteration %d", file, entry.line, entry.entry.TestCase, entry.entry.Iteration))
+	if entry.entry.RunID != "" {
+		sb.WriteString(fmt.Sprintf(", run %s", entry.entry.RunID))
+	}
+	sb.WriteString(fmt.Sprintf(" at %s\n", entry.entry.Timestamp))
+	switch {
+	case entry.entry.Error != "":
+		sb.WriteString(fmt.Sprintf("# Failed: %s\n", strings.Join(strings.Fields(entry.entry.Error), " ")))
+	case entry.entry.Response.StatusCode != 0:
+		sb.WriteString(fmt.Sprintf("# Logged response: HTTP %d\n", entry.entry.Response.StatusCode))
+	}
+	return sb.String()
+}
+
+// renderCommand renders a curl or HTTPie command that sends the entry's request again
+// with the endpoint headers it was logged with. Credentials never reach the log, so the
+// API key and redacted header values are environment variable references.
+func renderCommand(entry logEntry, format, baseURL string, compact bool) (string, error) {
+	request := entry.entry.Request
+	if request.Body == nil {
+		return "", fmt.Errorf("entry has no request body")
+	}
+	url := request.URL
+	if baseURL != "" {
+		url = strings.TrimRight(baseURL, "/") + "/chat/completions"
+	}
+	method := request.Method
+	if method == "" {
+		method = "POST"
+	}
+
+	var body []byte
+	var err error
+	if compact {
+		body, err = json.Marshal(request.Body)
+	} else {
+		body, err = json.MarshalIndent(request.Body, "", "  ")
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request body: %w", err)
+	}
+
+	// A configured Authorization header replaced the API key's when the request was sent
+	headers := []string{"Content-Type", "Authorization"}
+	values := map[string]string{"Content-Type": "application/json", "Authorization": "Bearer ${OPENAI_API_KEY}"}
+	secret := map[string]bool{"Authorization": true}
+	var names []string
+	for name := range request.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var sb strings.Builder
+	for _, logged := range names {
+		name, value := http.CanonicalHeaderKey(logged), request.Headers[logged]
+		redacted := services.IsRedacted(value)
+		if redacted {
+			variable := headerVariable(name)
+			sb.WriteString(fmt.Sprintf("# %s was redacted in the log; set %s to its value\n", name, variable))
+			value = "${" + variable + "}"
+		}
+		if _, exists := values[name]; !exists {
+			headers = append(headers, name)
+		}
+		values[name] = value
+		secret[name] = redacted
+	}
+
+	separator := ": "
+	if format == "httpie" {
+		separator = ":"
+		sb.WriteString(fmt.Sprintf("http %s %s \\\n", method, services.ShellQuote(url)))
+	} else {
+		sb.WriteString(fmt.Sprintf("curl -sS -X %s %s \\\n", method, services.ShellQuote(url)))
+	}
+	for _, name := range headers {
+		// Variable references are double-quoted so the shell expands them
+		header := services.ShellQuote(name + separator + values[name])
+		if secret[name] {
+			header = fmt.Sprintf("\"%s%s%s\"", name, separator, values[name])
+		}
+		if format == "httpie" {
+			sb.WriteString(fmt.Sprintf("  %s \\\n", header))
+		} else {
+			sb.WriteString(fmt.Sprintf("  -H %s \\\n", header))
+		}
+	}
+	if format == "httpie" {
+		sb.WriteString(fmt.Sprintf("  --raw %s\n", services.ShellQuote(string(body))))
+	} else {
+		sb.WriteString(fmt.Sprintf("  --data-binary %s\n", services.ShellQuote(string(body))))
+	}
+	return sb.String(), nil
+}
+
+// headerVariable names the environment variable standing in for a redacted header,
+// e.g. X_API_KEY for X-Api-Key
+func headerVariable(name string) string {
+	return strings.Trim(unsafeVariableChars.ReplaceAllString(strings.ToUpper(name), "_"), "_")
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"model-test/models"
+	"model-test/services"
+)
+
+// runConformance runs the built-in conformance suite against the scripted mock
+// provider, saves the comparison with the golden metrics and prints it. It returns the
+// exit code: 1 when any metric differs or the suite could not run.
+func runConformance(unitTable *services.UnitTable, matchPolicy models.StringMatchPolicy, outputFile string) int {
+	ctx, cancel := interruptibleContext()
+	defer cancel()
+
+	fmt.Println("🧪 Running the conformance suite against the mock provider...")
+	report, err := services.RunConformance(ctx, unitTable, matchPolicy)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return 1
+	}
+
+	if err := os.MkdirAll("results", 0755); err != nil {
+		fmt.Printf("❌ Failed to create results directory: %v\n", err)
+		return 1
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		fmt.Printf("❌ Failed to marshal conformance report: %v\n", err)
+		return 1
+	}
+	if err := os.WriteFile(outputFile, data, 0644); err != nil {
+		fmt.Printf("❌ Failed to save conformance report: %v\n", err)
+		return 1
+	}
+
+	fmt.Println("\n🧪 Conformance Results")
+	fmt.Println(strings.Repeat("=", 50))
+	fmt.Printf("Passed: %d/%d (expected %d/%d)\n", report.Actual.PassedTests, report.Actual.TotalTests, report.Expected.PassedTests, report.Expected.TotalTests)
+	fmt.Printf("LLM Requests: %d, Tool Calls: %d, Tokens: %d\n", report.Actual.LLMRequests, report.Actual.ToolCalls, report.Actual.TokenUsage.TotalTokens)
+	if report.Passed {
+		fmt.Println("✅ Every metric matches the golden report")
+	} else {
+		fmt.Printf("❌ %d metrics differ from the golden report:\n", len(report.Mismatches))
+		for _, mismatch := range report.Mismatches {
+			fmt.Printf("  - %s\n", mismatch)
+		}
+	}
+	fmt.Printf("💾 Conformance report saved to: %s\n", outputFile)
+
+	if !report.Passed {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"model-test/models"
+	"model-test/services"
+)
+
+// defaultBaseURL is the endpoint tested when no -base-url is given (Docker Model Runner)
+const defaultBaseURL = "http://localhost:12434/engines/v1"
+
+// multiEndpointUnsupported are the flags a run against several endpoints rejects,
+// since they act on a single endpoint or a single results file
+var multiEndpointUnsupported = []string{
+	"audit-concurrency", "dry-run", "events", "hooks", "hooks-stage", "kamiwaza-cluster", "mlflow-uri", "pin-baseline",
+	"redeploy", "rerun-infra-failures", "resume", "telemetry-hook", "tool-specs", "wandb-project", "watchdog",
+}
+
+// stringList backs a repeatable string flag
+type stringList []string
+
+// String renders the values comma-separated
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+// Set adds a value
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// checkMultiEndpointFlags returns an error naming the flags set that a run against
+// several endpoints does not support
+func checkMultiEndpointFlags(provider string) error {
+	if provider == "kamiwaza" {
+		return fmt.Errorf("-provider=kamiwaza cannot be combined with -endpoints or repeated -base-url/-model; use batch-run to test several Kamiwaza models")
+	}
+	var unsupported []string
+	flag.Visit(func(f *flag.Flag) {
+		for _, name := range multiEndpointUnsupported {
+			if f.Name == name {
+				unsupported = append(unsupported, "-"+name)
+			}
+		}
+	})
+	if len(unsupported) > 0 {
+		return fmt.Errorf("%s cannot be combined with -endpoints or repeated -base-url/-model", strings.Join(unsupported, ", "))
+	}
+	return nil
+}
+
+// endpointRun is what a run against several endpoints does on each of them
+type endpointRun struct {
+	runID         string
+	tags          models.RunTags
+	newRunner     func(apiKey, baseURL, model string, logger *services.RequestLogger, tags models.RunTags) (*services.TestRunner, error)
+	testCases     []models.TestCase
+	warmup        int
+	warmupTimeout time.Duration
+	healthCheck   bool
+	healthTimeout time.Duration
+	baselineDir   string
+	tolerance     models.BaselineTolerance
+	proxy         string            // -proxy, for endpoints without their own
+	headers       map[string]string // -header, which endpoint headers add to
+	reproBundles  bool              // Write repro bundles of failed tests
+}
+
+// endpointOutcome is one endpoint-model pair's row of the endpoint comparison
+type endpointOutcome struct {
+	endpoint    models.Endpoint
+	report      *models.AgentReport
+	resultsFile string
+	err         error
+}
+
+// runEndpoints runs the suite against each endpoint-model pair in turn, saving each
+// pair's results to its own file tagged endpoint=<name>, and prints how they compare.
+// Pairs run one after another so endpoints sharing a host do not skew each other's
+// latencies. It returns the exit code: 1 when a pair failed to run, stopped early or
+// regressed versus its baseline, 130 when interrupted.
+func runEndpoints(endpoints []models.Endpoint, plan endpointRun) int {
+	ctx, cancel := interruptibleContext()
+	defer cancel()
+
+	fmt.Printf("🌐 Running %d test cases against %d endpoint-model pairs (run ID %s)\n", len(plan.testCases), len(endpoints), plan.runID)
+	for _, endpoint := range endpoints {
+		fmt.Printf("   %s: %s (model %s)\n", endpoint.Name, endpoint.BaseURL, sanitizeModelName(endpoint.Model))
+	}
+
+	status := 0
+	var outcomes []endpointOutcome
+	for i, endpoint := range endpoints {
+		if ctx.Err() != nil {
+			break
+		}
+		suffix := fmt.Sprintf("%s_%s_%s", sanitizeModelName(endpoint.Model), endpoint.Name, plan.runID)
+		fmt.Printf("\n🌐 [%d/%d] %s: %s (model %s)\n", i+1, len(endpoints), endpoint.Name, endpoint.BaseURL, sanitizeModelName(endpoint.Model))
+
+		if plan.healthCheck {
+			connection := services.Connection{Proxy: endpoint.Proxy, Headers: services.MergeHeaders(plan.headers, endpoint.Headers)}
+			if connection.Proxy == "" {
+				connection.Proxy = plan.proxy
+			}
+			health := services.CheckEndpointHealth(ctx, endpoint.APIKey, endpoint.BaseURL, connection, endpoint.Model, plan.healthTimeout)
+			if code := reportEndpointHealth(health, fmt.Sprintf("results/endpoint_health_%s.json", suffix)); code != 0 {
+				status = code
+			}
+			continue
+		}
+
+		outcome := runEndpoint(ctx, endpoint, plan, suffix)
+		outcomes = append(outcomes, outcome)
+		switch {
+		case outcome.err != nil:
+			fmt.Printf("❌ %s: %v\n", endpoint.Name, outcome.err)
+			status = 1
+		case outcome.report.Incomplete && ctx.Err() != nil:
+			status = 130
+		case outcome.report.Incomplete:
+			status = 1
+		case outcome.report.BaselineComparison != nil && outcome.report.BaselineComparison.Regressed:
+			fmt.Printf("❌ Regression beyond tolerance versus baseline %s\n", outcome.report.BaselineComparison.BaselineFile)
+			status = 1
+		}
+	}
+
+	if len(outcomes) > 0 {
+		printEndpointComparison(outcomes)
+	}
+	return status
+}
+
+// runEndpoint runs the suite against one endpoint-model pair, compares it with the
+// model's baseline and saves its results
+func runEndpoint(ctx context.Context, endpoint models.Endpoint, plan endpointRun, suffix string) endpointOutcome {
+	outcome := endpointOutcome{endpoint: endpoint, resultsFile: fmt.Sprintf("results/agent_test_results_%s.json", suffix)}
+
+	logger, err := services.NewRequestLogger(fmt.Sprintf("logs/agent_test_logs_%s.log", suffix))
+	if err != nil {
+		outcome.err = fmt.Errorf("failed to create request logger: %w", err)
+		return outcome
+	}
+	defer logger.Close()
+	logger.SetRunID(plan.runID)
+
+	tags := models.RunTags{}
+	for key, value := range plan.tags {
+		tags[key] = value
+	}
+	for key, value := range endpoint.Tags {
+		tags[key] = value
+	}
+	if _, exists := tags["endpoint"]; !exists {
+		tags["endpoint"] = endpoint.Name
+	}
+
+	runner, err := plan.newRunner(endpoint.APIKey, endpoint.BaseURL, endpoint.Model, logger, tags)
+	if err != nil {
+		outcome.err = err
+		return outcome
+	}
+	if endpoint.ContextWindow > 0 {
+		runner.SetContextWindow(endpoint.ContextWindow)
+	}
+	if plan.reproBundles {
+		runner.SetReproDir("results/repro_" + suffix)
+	}
+	if endpoint.Proxy != "" {
+		if err := runner.SetProxy(endpoint.Proxy); err != nil {
+			outcome.err = err
+			return outcome
+		}
+	}
+	if len(endpoint.Headers) > 0 {
+		if err := runner.SetHeaders(services.MergeHeaders(plan.headers, endpoint.Headers)); err != nil {
+			outcome.err = err
+			return outcome
+		}
+	}
+
+	var warmup *models.Warmup
+	if plan.warmup > 0 {
+		warmup = runWarmup(ctx, runner, plan.warmup, plan.warmupTimeout)
+	}
+
+	report, err := runner.RunAgentTestSuite(ctx, plan.testCases)
+	if err != nil {
+		outcome.err = fmt.Errorf("failed to run agent test suite: %w", err)
+		return outcome
+	}
+	report.Warmup = warmup
+	outcome.report = report
+
+	// A partial run would skew the baseline comparison
+	if plan.baselineDir != "" && !report.Incomplete {
+		baseline, baselinePath, err := services.LoadBaseline(plan.baselineDir, sanitizeModelName(endpoint.Model))
+		if err != nil {
+			outcome.err = fmt.Errorf("failed to load baseline: %w", err)
+			return outcome
+		}
+		if baseline != nil {
+			report.BaselineComparison = services.CompareToBaseline(baseline, report, plan.tolerance)
+			report.BaselineComparison.BaselineFile = baselinePath
+		}
+	}
+
+	if err := runner.SaveResults(outcome.resultsFile, report); err != nil {
+		outcome.err = fmt.Errorf("failed to save results: %w", err)
+		return outcome
+	}
+
+	fmt.Println()
+	printAgentSummary(report)
+	if report.BaselineComparison != nil {
+		printBaselineComparison(report.BaselineComparison)
+	}
+	if report.Incomplete {
+		fmt.Printf("\n💾 Partial results saved to: %s\n", outcome.resultsFile)
+	} else {
+		fmt.Printf("\n💾 Results saved to: %s\n", outcome.resultsFile)
+	}
+	return outcome
+}
+
+// printEndpointComparison prints each endpoint-model pair's pass rate and latency
+func printEndpointComparison(outcomes []endpointOutcome) {
+	fmt.Println("\n🌐 Endpoint Comparison")
+	fmt.Println(strings.Repeat("=", 50))
+	fmt.Printf("%-24s %-32s %10s %12s %12s\n", "Endpoint", "Model", "Passed", "Avg Time", "Avg LLM Req")
+	for _, outcome := range outcomes {
+		model := sanitizeModelName(outcome.endpoint.Model)
+		if outcome.report == nil {
+			fmt.Printf("%-24s %-32s %10s\n", outcome.endpoint.Name, model, "error")
+			continue
+		}
+		report := outcome.report
+		passed := fmt.Sprintf("%d/%d", report.PassedTests, report.TotalTests)
+		if report.Incomplete {
+			passed += "*"
+		}
+		fmt.Printf("%-24s %-32s %10s %12v %12v\n", outcome.endpoint.Name, model, passed,
+			report.AverageTime.Round(time.Millisecond), report.AvgTimePerReq.Round(time.Millisecond))
+	}
+	for _, outcome := range outcomes {
+		if outcome.report != nil && outcome.report.Incomplete {
+			fmt.Println("* incomplete run")
+			break
+		}
+	}
+}
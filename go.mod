@@ -3,7 +3,12 @@ module model-test
 go 1.24.2
 
 require (
-	github.com/openai/openai-go v1.2.0 // indirect
+	github.com/mattn/go-sqlite3 v1.14.33
+	github.com/openai/openai-go v1.2.0
+	golang.org/x/text v0.21.0
+)
+
+require (
 	github.com/tidwall/gjson v1.14.4 // indirect
 	github.com/tidwall/match v1.1.1 // indirect
 	github.com/tidwall/pretty v1.2.1 // indirect
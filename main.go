@@ -6,8 +6,13 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"sort"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"model-test/models"
@@ -17,170 +22,1521 @@ import (
 func main() {
 	// Command line flags
 	var (
-		apiKey       = flag.String("api-key", "DMR", "OpenAI API key (or set OPENAI_API_KEY env var)")
-		baseURL      = flag.String("base-url", "http://localhost:12434/engines/v1", "OpenAI API base URL (or set OPENAI_BASE_URL env var)")
-		model        = flag.String("model", "", "Model to use (or set OPENAI_MODEL env var, defaults to gpt-4o-mini)")
-		configFile   = flag.String("config", "config/test_cases.json", "Path to test cases configuration file")
-		testCase     = flag.String("test-case", "", "Run only the specified test case by name")
-		provider     = flag.String("provider", "default", "Provider type: default, kamiwaza")
-		kamiwazaURL  = flag.String("kamiwaza-url", "https://localhost", "Kamiwaza base URL for deployment discovery")
+		apiKey        = flag.String("api-key", "DMR", "OpenAI API key (or set OPENAI_API_KEY env var)")
+		apiKeyFile    = flag.String("api-key-file", "", "Read the API key from this file instead of -api-key, keeping it off the command line")
+		apiKeyCommand = flag.String("api-key-command", "", "Credential helper run through the shell that prints the API key, e.g. an OS keychain lookup such as 'security find-generic-password -s model-test -w'")
+		credsFile     = flag.String("credentials", "", "Path to a JSON list mapping model names or glob patterns (e.g. claude-*, openai/*) to the environment variable or file holding their API key, so one run or batch can span several providers; the first match wins, -api-key covers the rest")
+		endpointsFile = flag.String("endpoints", "", "Path to a JSON list of endpoints (base URL, model, API key, tags) to run the suite against one after another, each endpoint-model pair saved to its own results file")
+		configFile    = flag.String("config", "config/test_cases.json", "Path to test cases configuration file")
+		testCase      = flag.String("test-case", "", "Run only these test cases: comma-separated names or glob patterns (e.g. \"simple_view_cart,complex_*\")")
+		listCases     = flag.Bool("list-test-cases", false, "Print the names of the test cases selected by -test-case, -tags and -exclude-tags and exit")
+		includeTags   = flag.String("tags", "", "Run only test cases with at least one of these comma-separated tags (e.g. cart-mutation,no-tool-expected)")
+		excludeTags   = flag.String("exclude-tags", "", "Skip test cases with any of these comma-separated tags")
+		provider      = flag.String("provider", "default", "Provider type: default, kamiwaza")
+		kamiwazaURL   = flag.String("kamiwaza-url", "https://localhost", "Kamiwaza base URL for deployment discovery")
 		kamiwazaModel = flag.String("kamiwaza-model", "", "Kamiwaza model name to look up (uses m_name from deployments)")
+		clustersFile  = flag.String("kamiwaza-clusters", "config/kamiwaza_clusters.json", "Path to the named Kamiwaza clusters (URL, credentials, metadata) -kamiwaza-cluster chooses from")
+		clusterName   = flag.String("kamiwaza-cluster", "", "Run against this cluster from -kamiwaza-clusters instead of -kamiwaza-url; its name and metadata are recorded in the results")
+		referenceTime = flag.String("reference-time", "", "Current time given to the model and used to resolve relative dates (RFC3339 or YYYY-MM-DD, defaults to now)")
+		unitsFile     = flag.String("units", "", "Path to unit conversion tables used when matching quantity arguments, replacing the built-in mass, volume and length tables")
+		caseSensitive = flag.Bool("match-case-sensitive", false, "Compare string arguments case-sensitively")
+		matchTrim     = flag.Bool("match-trim", false, "Trim leading and trailing whitespace before comparing string arguments")
+		matchCollapse = flag.Bool("match-collapse-whitespace", false, "Collapse runs of whitespace (and trim) before comparing string arguments")
+		matchUnicode  = flag.String("match-unicode", "none", "Unicode normalization before comparing string arguments: none, nfc, nfkc")
+		runID         = flag.String("run-id", "", "Identifier used in result, log and audit file names (defaults to a new ULID)")
+		mlflowURI     = flag.String("mlflow-uri", "", "MLflow tracking server to log run parameters and metrics to (token from MLFLOW_TRACKING_TOKEN)")
+		mlflowExp     = flag.String("mlflow-experiment", "model-test", "MLflow experiment name")
+		wandbProject  = flag.String("wandb-project", "", "Weights & Biases project to log run parameters and metrics to (key from WANDB_API_KEY)")
+		wandbEntity   = flag.String("wandb-entity", "", "Weights & Biases entity (defaults to the API key's default entity)")
+		eventsAddr    = flag.String("events", "", "Address to serve live run events on as Server-Sent Events at /events (e.g. :8080)")
+		baselineDir   = flag.String("baseline-dir", "baselines", "Directory holding pinned per-model baselines; runs are compared automatically when one exists (empty disables)")
+		pinBaseline   = flag.Bool("pin-baseline", false, "Pin this run's results as the model's baseline instead of comparing against it")
+		successTol    = flag.Float64("baseline-success-tolerance", 0.05, "Allowed absolute drop in pass rate versus the baseline (0.05 = 5 points)")
+		latencyTol    = flag.Float64("baseline-latency-tolerance", 0.5, "Allowed relative increase in LLM time per request versus the baseline (0.5 = +50%)")
+		toolSpecsFile = flag.String("tool-specs", "", "Path to alternative tool description versions; the suite is rerun once per version and pass rate deltas versus the canonical wording are reported")
+		toolErrors    = flag.String("tool-error-verbosity", "standard", "How much of a failed tool call is shown to the model: minimal (code), standard (code and message), detailed (adds tool, arguments and a recovery hint)")
+		approvalTools = flag.String("require-approval", "", "Comma-separated tools that need simulated user approval: their first call in each test is denied and the model is checked for retries")
+		adjudicate    = flag.String("adjudications", "review/adjudications.json", "Human decisions on borderline results (from the review tool), applied when scoring matching responses")
+		evaluation    = flag.String("evaluation", "calls", "What a test's tool calls must get right to pass: calls (the expected tools and arguments) or workflow (the calls must also have worked: failed calls count only when the model retried them successfully or the test allows the error)")
+		scoring       = flag.String("scoring", "binary", "How tests are scored: binary (pass/fail) or partial (pass/fail plus a 0-1 partial-credit score from the overlap with the closest expected tool sequence and the argument match ratio)")
+		judgeModel    = flag.String("judge-model", "", "Model that scores each test's final reply against the test case's rubric (helpfulness, correct cart summary, ...) from 1 to 5 per criterion; the score and rationale are recorded in the results without changing pass/fail")
+		judgeBaseURL  = flag.String("judge-base-url", "", "OpenAI-compatible base URL of the -judge-model (defaults to the first -base-url); its API key comes from -credentials or -api-key")
+		dryRun        = flag.Bool("dry-run", false, "Validate the test cases, resolve the endpoint and print the planned requests, tool definitions and estimated prompt sizes (and cost with -input-cost-per-mtok) without contacting the model; exits 1 on configuration problems")
+		inputCost     = flag.Float64("input-cost-per-mtok", 0, "Price per million input tokens used for the -dry-run cost preview")
+		hooksFile     = flag.String("hooks", "", "Path to shell or HTTP hooks run before and after the run (e.g. restart the model server, clear the KV cache); outputs are recorded in the run manifest")
+		hooksStage    = flag.String("hooks-stage", "", "Only run the hooks of this stage (pre_model or post_model; used by test-all-models.sh around each model), record them in a run manifest and exit")
+		telemetryHook = flag.String("telemetry-hook", "", "Shell command run periodically during the run whose JSON output (e.g. GPU temperatures, clocks) is recorded on the run manifest timeline")
+		telemetryTick = flag.Duration("telemetry-interval", 10*time.Second, "How often to run -telemetry-hook")
+		runs          = flag.Int("runs", 1, "Run each test case this many times and report per-run pass rates, pass-rate variance and flaky tests")
+		sweepFile     = flag.String("sweep", "", "Path to a test config with a grid of temperature, top_p and max_tokens values; the suite runs once per combination and results are tagged with their config")
+		seed          = flag.Int64("seed", 0, "Sampling seed sent with every request, for backends that support deterministic sampling; recorded in each result's config so runs can be reproduced (overrides the -sweep config's seed)")
+		watchdog      = flag.Bool("watchdog", false, "When tests fail against the server, check whether the endpoint stopped responding; if so pause the affected tests until it answers again and rerun them")
+		watchdogWait  = flag.Duration("watchdog-timeout", 10*time.Minute, "How long the -watchdog waits for an unresponsive endpoint (or its redeploy) before stopping the run")
+		redeploy      = flag.Bool("redeploy", false, "With -provider=kamiwaza, redeploy the model when the -watchdog finds it unresponsive (deploy options come from the engine, quant, context_length and gpus tags); implies -watchdog")
+		rerunInfra    = flag.String("rerun-infra-failures", "", "Results file of a finished run: rerun only its tests that failed against the server (API errors, timeouts) rather than on the model's answer and merge the new results into that file")
+		resumeFile    = flag.String("resume", "", "Checkpoint (results/checkpoint_*.jsonl) or results file of an interrupted run; its completed tests are reused instead of rerun")
+		healthCheck   = flag.Bool("health-check", false, "Check the endpoint is reachable, accepts the API key, serves the model and answers a one-token request, save the result to results/endpoint_health_*.json and exit (non-zero when unhealthy)")
+		healthTimeout = flag.Duration("health-timeout", 30*time.Second, "How long -health-check waits for the endpoint")
+		warmupCount   = flag.Int("warmup", 0, "Send this many throwaway completions before the suite starts so the cold-start model load on local deployments is not timed as part of the first test cases")
+		warmupTimeout = flag.Duration("warmup-timeout", 5*time.Minute, "How long each -warmup request may take (the first one may load the model)")
+		requestsLimit = flag.Int("requests-per-minute", 0, "Pace chat completion requests to this many per minute across all concurrent tests, for hosted APIs (OpenAI, Azure) with rate limits (0 = unlimited)")
+		tokensLimit   = flag.Int("tokens-per-minute", 0, "Pace chat completion requests to this many prompt plus completion tokens per minute across all concurrent tests (0 = unlimited)")
+		stream        = flag.Bool("stream", false, "Stream chat completions and record time to first token and tokens per second alongside total latency (some deployments behave differently when streaming)")
+		parallelCalls = flag.Bool("parallel-tool-calls", false, "Send parallel_tool_calls with every request to allow (true) or forbid (false) several tool calls in one turn; unset leaves the backend default (overrides the -sweep config)")
+		pruneResults  = flag.Bool("prune-tool-results", false, "Leave tool call result payloads out of the saved results file (the request log keeps them)")
+		reproBundles  = flag.Bool("repro-bundles", true, "Write a self-contained repro bundle (request bodies, tool definitions, system prompt, curl script) for every failed test to results/repro_<model>_<run_id>/")
+		maxMessageLen = flag.Int("max-message-length", 0, "Cut assistant messages in the saved results file to this many characters (0 keeps them whole; the request log keeps them)")
+		failFast      = flag.Bool("fail-fast", false, "Stop the run at the first failed test and save a partial report (same as -max-failures 1)")
+		maxFailures   = flag.Int("max-failures", 0, "Stop the run once this many tests have failed and save a partial report, so a clearly broken model does not take the full run time (0 = run every test)")
+		auditRuns     = flag.Int("audit-concurrency", 0, "Parallelism-safety audit: run each test case this many times concurrently and check session isolation instead of scoring")
+		contextWindow = flag.Int("context-window", 0, "Model's context window in tokens: a test whose next request (prompt plus max_tokens) would exceed it fails as context_exceeded instead of being sent (0 = the run's context_length tag, as set by batch-run deployments, or no check)")
+		conformance   = flag.Bool("conformance", false, "Verify the installation: run the built-in conformance suite against a scripted mock provider and compare its metrics with the golden report, scoring with -units and the match flags; exits 1 on any difference")
+		gateway       = flag.Bool("gateway", false, "The base URL is an API gateway (LiteLLM, Portkey) routing provider-prefixed model names such as openai/gpt-4o or bedrock/claude-3-5: tag the run with the provider and record each result's served model and gateway routing headers")
+		proxyURL      = flag.String("proxy", "", "Proxy for requests to the model server and the Kamiwaza API: an http, https, socks5 or socks5h URL, or direct to bypass the HTTP_PROXY/HTTPS_PROXY/NO_PROXY environment variables, which apply when unset (endpoints and clusters may set their own)")
+		signKeyFile   = flag.String("sign-key-file", "", "Path to a shared secret (at least 32 bytes) used to sign every results file and run manifest with HMAC-SHA256, written next to it as <file>.sig; analyze-batch -verify-key-file checks them")
+		chaosFile     = flag.String("chaos", "", "Path to a network chaos config (latency, jitter, dropped connections, slow bodies) injected into every request to the model server, to see how the provider integration and the client's retries cope with a degraded network; each test records its faults")
+		serviceState  = flag.String("service-state", "isolated", "Product and cart services the tests' tool calls run against: isolated (fresh instances per test) or shared (one set for the whole run, carts kept apart only by session; e.g. to audit session keying with -audit-concurrency)")
 	)
+	var baseURLs, modelNames, headerFlags stringList
+	flag.Var(&baseURLs, "base-url", "OpenAI API base URL (or set OPENAI_BASE_URL env var, defaults to "+defaultBaseURL+"); repeat to run the suite against several endpoints")
+	flag.Var(&modelNames, "model", "Model to use (or set OPENAI_MODEL env var, defaults to gpt-4o-mini); repeat to run several models, each on every -base-url")
+	flag.Var(&headerFlags, "header", "Static header sent with every request to the model server as 'Name: value', e.g. for API gateways such as LiteLLM or Portkey (repeatable; endpoints may add their own)")
+	tags := models.RunTags{}
+	flag.Var(tags, "tag", "Metadata to attach to the run as key=value (repeatable, e.g. -tag gpu=a100 -tag quant=q4_k_m)")
 	flag.Parse()
 
+	baseURL, model := defaultBaseURL, ""
+	if len(baseURLs) > 0 {
+		baseURL = baseURLs[0]
+	}
+	if len(modelNames) > 0 {
+		model = modelNames[0]
+	}
+	multiEndpoint := *endpointsFile != "" || len(baseURLs) > 1 || len(modelNames) > 1
+	if multiEndpoint {
+		if err := checkMultiEndpointFlags(*provider); err != nil {
+			log.Fatalf("%v", err)
+		}
+	}
+
+	// Resolve the reference clock
+	refTime, err := services.ParseReferenceTime(*referenceTime)
+	if err != nil {
+		log.Fatalf("Invalid -reference-time: %v", err)
+	}
+
+	// Resolve the run identifier
+	if *runID == "" {
+		*runID, err = services.NewRunID()
+		if err != nil {
+			log.Fatalf("Failed to generate run ID: %v", err)
+		}
+	} else if err := services.ValidateRunID(*runID); err != nil {
+		log.Fatalf("Invalid -run-id: %v", err)
+	}
+
+	if *runs < 1 {
+		log.Fatalf("Invalid -runs: must be at least 1, got %d", *runs)
+	}
+
+	if *maxFailures < 0 {
+		log.Fatalf("Invalid -max-failures: must not be negative, got %d", *maxFailures)
+	}
+	if *failFast {
+		if *maxFailures > 1 {
+			log.Fatalf("-fail-fast cannot be combined with -max-failures %d", *maxFailures)
+		}
+		*maxFailures = 1
+	}
+
+	// Resolve how tool failures are reported to the model
+	toolErrorVerbosity, err := services.ParseToolErrorVerbosity(*toolErrors)
+	if err != nil {
+		log.Fatalf("Invalid -tool-error-verbosity: %v", err)
+	}
+
+	// Resolve whether tool calls must also have worked
+	evaluationMode, err := services.ParseEvaluationMode(*evaluation)
+	if err != nil {
+		log.Fatalf("Invalid -evaluation: %v", err)
+	}
+
+	// Resolve whether results get a partial-credit score
+	scoringMode, err := services.ParseScoringMode(*scoring)
+	if err != nil {
+		log.Fatalf("Invalid -scoring: %v", err)
+	}
+
+	// Resolve whether tests share the product and cart services
+	state, err := services.ParseServiceState(*serviceState)
+	if err != nil {
+		log.Fatalf("Invalid -service-state: %v", err)
+	}
+
+	// Load unit conversion tables
+	unitTable, err := services.LoadUnitTable(*unitsFile)
+	if err != nil {
+		log.Fatalf("Failed to load unit tables: %v", err)
+	}
+
+	// Build the global string comparison policy
+	matchPolicy, err := services.NewStringMatchPolicy(*caseSensitive, *matchTrim, *matchCollapse, *matchUnicode)
+	if err != nil {
+		log.Fatalf("Invalid match policy: %v", err)
+	}
+
+	// Check the installation against the built-in conformance suite instead
+	if *conformance {
+		os.Exit(runConformance(unitTable, matchPolicy, fmt.Sprintf("results/conformance_%s.json", *runID)))
+	}
+
+	// Load human decisions on borderline results
+	adjudications, err := services.LoadAdjudications(*adjudicate)
+	if err != nil {
+		log.Fatalf("Failed to load adjudications: %v", err)
+	}
+
 	// Load test cases
-	testCases, err := loadTestCases(*configFile, *testCase)
+	suite, err := loadTestSuite(*configFile, *testCase)
 	if err != nil {
 		log.Fatalf("Failed to load test cases: %v", err)
 	}
+	testCases := suite.TestCases
+	if *includeTags != "" || *excludeTags != "" {
+		total := len(testCases)
+		testCases, err = services.FilterTestCasesByTags(testCases, services.ParseTagList(*includeTags), services.ParseTagList(*excludeTags))
+		if err != nil {
+			log.Fatalf("Invalid test case tags: %v", err)
+		}
+		if !*listCases {
+			fmt.Printf("🏷️  Selected %d of %d test cases by tag\n", len(testCases), total)
+		}
+	}
+	if *listCases {
+		for _, tc := range testCases {
+			fmt.Println(tc.Name)
+		}
+		return
+	}
+
+	// Load the sampling configuration
+	var testConfig models.TestConfig
+	if *sweepFile != "" {
+		config, err := services.LoadTestConfig(*sweepFile)
+		if err != nil {
+			log.Fatalf("Failed to load sweep config: %v", err)
+		}
+		testConfig = *config
+	}
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "seed":
+			testConfig.Seed = seed
+		case "parallel-tool-calls":
+			testConfig.ParallelToolCalls = parallelCalls
+		}
+	})
+	samplingConfigs := services.ExpandSweep(testConfig)
+
+	// Load alternative tool description wordings
+	var toolSpecVersions []models.ToolSpecVersion
+	if *toolSpecsFile != "" {
+		toolSpecVersions, err = services.LoadToolSpecVersions(*toolSpecsFile)
+		if err != nil {
+			log.Fatalf("Failed to load tool spec versions: %v", err)
+		}
+	}
+
+	// Generate output filenames with model name
+	modelNameForFile := model
+	if *provider == "kamiwaza" {
+		modelNameForFile = *kamiwazaModel
+	}
+	sanitizedModel := sanitizeModelName(modelNameForFile)
+	outputFile := fmt.Sprintf("results/agent_test_results_%s_%s.json", sanitizedModel, *runID)
+	logFile := fmt.Sprintf("logs/agent_test_logs_%s_%s.log", sanitizedModel, *runID)
+
+	// Ensure directories exist
+	if err := os.MkdirAll("results", 0755); err != nil {
+		log.Fatalf("Failed to create results directory: %v", err)
+	}
+	if err := os.MkdirAll("logs", 0755); err != nil {
+		log.Fatalf("Failed to create logs directory: %v", err)
+	}
+
+	// Sign results files and run manifests so published numbers can be traced to them
+	var signingKey []byte
+	if *signKeyFile != "" {
+		signingKey, err = services.LoadSigningKey(*signKeyFile)
+		if err != nil {
+			log.Fatalf("Invalid -sign-key-file: %v", err)
+		}
+	}
+
+	// Run pre/post hooks, recording their output in the run manifest
+	var hookConfig *models.HookConfig
+	if *hooksFile != "" {
+		hookConfig, err = services.LoadHookConfig(*hooksFile)
+		if err != nil {
+			log.Fatalf("Failed to load hooks: %v", err)
+		}
+	}
+	hookEnv := map[string]string{"MODEL_TEST_MODEL": modelNameForFile, "MODEL_TEST_RUN_ID": *runID}
+	manifestFile := fmt.Sprintf("results/run_manifest_%s_%s.json", sanitizedModel, *runID)
+	healthFile := fmt.Sprintf("results/endpoint_health_%s_%s.json", sanitizedModel, *runID)
+	manifest := &models.RunManifest{RunID: *runID, Model: sanitizedModel, StartedAt: time.Now()}
+
+	if *hooksStage != "" {
+		stage, err := services.ParseHookStage(*hooksStage)
+		if err != nil {
+			log.Fatalf("Invalid -hooks-stage: %v", err)
+		}
+		if hookConfig == nil {
+			log.Fatalf("-hooks-stage requires -hooks")
+		}
+		os.Exit(runHookStage(hookConfig, stage, hookEnv, manifest, manifestFile, signingKey))
+	}
+
+	if hookConfig != nil && !*healthCheck && !*dryRun {
+		if code := runHookStage(hookConfig, models.HookPreRun, hookEnv, manifest, manifestFile, signingKey); code != 0 {
+			os.Exit(code)
+		}
+		fmt.Println()
+	}
+
+	// Share one rate limit budget across all concurrent tests
+	rateLimiter, err := services.NewRateLimiter(*requestsLimit, *tokensLimit)
+	if err != nil {
+		log.Fatalf("Invalid rate limit: %v", err)
+	}
+	if *maxMessageLen < 0 {
+		log.Fatalf("Invalid -max-message-length: must not be negative, got %d", *maxMessageLen)
+	}
+	if *contextWindow < 0 {
+		log.Fatalf("Invalid -context-window: must not be negative, got %d", *contextWindow)
+	}
+	if err := services.ValidateProxy(*proxyURL); err != nil {
+		log.Fatalf("Invalid -proxy: %v", err)
+	}
+	headers := make(map[string]string)
+	for _, header := range headerFlags {
+		name, value, err := services.ParseHeader(header)
+		if err != nil {
+			log.Fatalf("Invalid -header: %v", err)
+		}
+		headers[name] = value
+	}
+	// Read the API key from a file or credential helper instead of the command line
+	if *apiKeyFile != "" && *apiKeyCommand != "" {
+		log.Fatalf("Invalid -api-key-file: cannot be combined with -api-key-command")
+	}
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "api-key" && (*apiKeyFile != "" || *apiKeyCommand != "") {
+			log.Fatalf("Invalid -api-key: cannot be combined with -api-key-file or -api-key-command")
+		}
+	})
+	if *apiKeyFile != "" {
+		*apiKey, err = services.ReadAPIKeyFile(*apiKeyFile)
+		if err != nil {
+			log.Fatalf("Invalid -api-key-file: %v", err)
+		}
+	}
+	if *apiKeyCommand != "" {
+		*apiKey, err = services.RunAPIKeyCommand(*apiKeyCommand)
+		if err != nil {
+			log.Fatalf("Invalid -api-key-command: %v", err)
+		}
+	}
+	var credentials []models.Credential
+	if *credsFile != "" {
+		credentials, err = services.LoadCredentials(*credsFile)
+		if err != nil {
+			log.Fatalf("Invalid -credentials: %v", err)
+		}
+	}
+	var networkChaos *models.NetworkChaos
+	if *chaosFile != "" {
+		networkChaos, err = services.LoadNetworkChaos(*chaosFile)
+		if err != nil {
+			log.Fatalf("Invalid -chaos: %v", err)
+		}
+	}
+
+	// Score final replies against the test cases' rubrics with a judge model
+	var judge *services.Judge
+	if *judgeModel != "" {
+		judgeURL, judgeConnection := *judgeBaseURL, services.Connection{Proxy: *proxyURL}
+		if judgeURL == "" {
+			judgeURL, judgeConnection.Headers = baseURL, headers
+		}
+		judgeAPIKey, err := services.ResolveAPIKey(credentials, *judgeModel, *apiKey)
+		if err != nil {
+			log.Fatalf("Invalid -judge-model: %v", err)
+		}
+		judge = services.NewJudge(judgeAPIKey, judgeURL, *judgeModel, judgeConnection)
+	} else if *judgeBaseURL != "" {
+		log.Fatalf("-judge-base-url requires -judge-model")
+	}
+
+	// newRunner creates a test runner for one endpoint with the run's settings
+	newRunner := func(apiKey, baseURL, model string, logger *services.RequestLogger, tags models.RunTags) (*services.TestRunner, error) {
+		runner := services.NewTestRunnerWithLogger(apiKey, baseURL, model, logger)
+		runner.SetRunID(*runID)
+		if provider, _ := services.ParseGatewayModel(model); *gateway && provider != "" {
+			if _, exists := tags["provider"]; !exists {
+				withProvider := models.RunTags{"provider": provider}
+				for key, value := range tags {
+					withProvider[key] = value
+				}
+				tags = withProvider
+			}
+		}
+		runner.SetTags(tags)
+		runner.SetGateway(*gateway)
+		runner.SetReferenceTime(refTime)
+		runner.SetUnitTable(unitTable)
+		runner.SetMatchPolicy(matchPolicy)
+		runner.SetToolAliases(suite.ToolAliases)
+		runner.SetAdjudications(adjudications)
+		runner.SetScoringMode(scoringMode)
+		runner.SetEvaluationMode(evaluationMode)
+		runner.SetToolErrorVerbosity(toolErrorVerbosity)
+		runner.SetServiceState(state)
+		runner.SetContextWindow(resolveContextWindow(*contextWindow, tags))
+		runner.SetRuns(*runs)
+		runner.SetTestConfig(testConfig)
+		runner.SetRateLimiter(rateLimiter)
+		runner.SetStreaming(*stream)
+		runner.SetPruningPolicy(models.PruningPolicy{DropToolResults: *pruneResults, MaxMessageLength: *maxMessageLen})
+		runner.SetMaxFailures(*maxFailures)
+		runner.SetSigningKey(signingKey)
+		runner.SetJudge(judge)
+		runner.SetSuiteHooks(suite.Setup, suite.Teardown)
+		if err := runner.SetScorers(suite.Scorers); err != nil {
+			return nil, fmt.Errorf("invalid scorers in %s: %w", *configFile, err)
+		}
+		if err := runner.SetProxy(*proxyURL); err != nil {
+			return nil, fmt.Errorf("invalid -proxy: %w", err)
+		}
+		if err := runner.SetHeaders(headers); err != nil {
+			return nil, fmt.Errorf("invalid -header: %w", err)
+		}
+		if err := runner.SetNetworkChaos(networkChaos); err != nil {
+			return nil, fmt.Errorf("invalid -chaos: %w", err)
+		}
+		if *approvalTools != "" {
+			if err := runner.SetApprovalRequired(strings.Split(*approvalTools, ",")); err != nil {
+				return nil, fmt.Errorf("invalid -require-approval: %w", err)
+			}
+		}
+		return runner, nil
+	}
+
+	// Run the suite against each endpoint in turn instead
+	if multiEndpoint {
+		endpoints := make([]models.Endpoint, 0, len(baseURLs))
+		if *endpointsFile != "" {
+			endpoints, err = services.LoadEndpoints(*endpointsFile)
+			if err != nil {
+				log.Fatalf("Failed to load endpoints: %v", err)
+			}
+		}
+		for _, url := range baseURLs {
+			endpoints = append(endpoints, models.Endpoint{BaseURL: url})
+		}
+		if len(endpoints) == 0 {
+			endpoints = append(endpoints, models.Endpoint{BaseURL: baseURL})
+		}
+		endpoints, err = services.ExpandEndpoints(endpoints, modelNames, *apiKey, credentials)
+		if err != nil {
+			log.Fatalf("Invalid endpoints: %v", err)
+		}
+
+		plan := endpointRun{
+			runID:         *runID,
+			tags:          tags,
+			newRunner:     newRunner,
+			testCases:     testCases,
+			warmup:        *warmupCount,
+			warmupTimeout: *warmupTimeout,
+			healthCheck:   *healthCheck,
+			healthTimeout: *healthTimeout,
+			baselineDir:   *baselineDir,
+			tolerance:     models.BaselineTolerance{SuccessRateDrop: *successTol, LatencyIncrease: *latencyTol},
+			proxy:         *proxyURL,
+			headers:       headers,
+			reproBundles:  *reproBundles,
+		}
+		os.Exit(runEndpoints(endpoints, plan))
+	}
+
+	// Resolve Kamiwaza configuration if needed
+	finalBaseURL := baseURL
+	finalModel := model
+	var cluster *models.KamiwazaCluster
+	var kamiwazaSvc *services.KamiwazaService
+	if *clusterName != "" && *provider != "kamiwaza" {
+		log.Fatalf("-kamiwaza-cluster requires -provider=kamiwaza")
+	}
+
+	if *provider == "kamiwaza" {
+		if *kamiwazaModel == "" {
+			log.Fatalf("Kamiwaza model name (-kamiwaza-model) is required when using -provider=kamiwaza")
+		}
+
+		kamiwazaSvc = services.NewKamiwazaService(*kamiwazaURL)
+		if err := kamiwazaSvc.SetProxy(*proxyURL); err != nil {
+			log.Fatalf("Invalid -proxy: %v", err)
+		}
+		if *clusterName != "" {
+			clusterConfig, err := services.LoadKamiwazaClusters(*clustersFile)
+			if err != nil {
+				log.Fatalf("Failed to load Kamiwaza clusters: %v", err)
+			}
+			cluster, err = services.FindKamiwazaCluster(clusterConfig, *clusterName)
+			if err != nil {
+				log.Fatalf("Invalid -kamiwaza-cluster: %v", err)
+			}
+			kamiwazaSvc, err = services.NewKamiwazaServiceForCluster(*cluster)
+			if err != nil {
+				log.Fatalf("Failed to configure Kamiwaza cluster: %v", err)
+			}
+			if cluster.Proxy == "" {
+				kamiwazaSvc.SetProxy(*proxyURL)
+			}
+			if _, exists := tags["cluster"]; !exists {
+				tags["cluster"] = cluster.Name
+			}
+		}
+
+		// Get the deployment endpoint for the specified model
+		endpoint, err := kamiwazaSvc.GetModelEndpoint(*kamiwazaModel)
+		if err != nil {
+			if *healthCheck {
+				discoveryURL := *kamiwazaURL
+				if cluster != nil {
+					discoveryURL = cluster.URL
+				}
+				health := models.EndpointHealth{Model: *kamiwazaModel, Endpoint: discoveryURL, Error: err.Error(), CheckedAt: time.Now()}
+				os.Exit(reportEndpointHealth(health, healthFile))
+			}
+			log.Fatalf("Failed to get Kamiwaza endpoint for model '%s': %v", *kamiwazaModel, err)
+		}
+
+		finalBaseURL = endpoint + "/v1"
+		finalModel = kamiwazaSvc.GetModelIdentifier()
+
+		fmt.Printf("🔍 Kamiwaza Discovery:\n")
+		if cluster != nil {
+			fmt.Printf("   Cluster: %s (%s)\n", cluster.Name, cluster.URL)
+		}
+		fmt.Printf("   Model Name: %s\n", *kamiwazaModel)
+		fmt.Printf("   Endpoint: %s\n", finalBaseURL)
+		fmt.Println()
+	}
+
+	// Pick the model's API key from the credentials, if one matches
+	modelAPIKey, err := services.ResolveAPIKey(credentials, finalModel, *apiKey)
+	if err != nil {
+		log.Fatalf("Invalid -credentials: %v", err)
+	}
+
+	// Validate the suite and preview its requests without contacting the model
+	if *dryRun {
+		runner := services.NewTestRunner(modelAPIKey, finalBaseURL, finalModel)
+		runner.SetReferenceTime(refTime)
+		runner.SetTestConfig(testConfig)
+		runner.SetToolAliases(suite.ToolAliases)
+		plan := dryRunPlan{
+			baseURL:       finalBaseURL,
+			model:         finalModel,
+			runs:          *runs,
+			configs:       len(samplingConfigs),
+			toolSpecs:     len(toolSpecVersions),
+			warmup:        *warmupCount,
+			approvalTools: *approvalTools,
+		}
+		os.Exit(runDryRun(runner, testCases, toolSpecVersions, plan, *inputCost))
+	}
+
+	if *healthCheck {
+		health := services.CheckEndpointHealth(context.Background(), modelAPIKey, finalBaseURL, services.Connection{Proxy: *proxyURL, Headers: headers}, finalModel, *healthTimeout)
+		if *provider == "kamiwaza" {
+			health.Model = *kamiwazaModel
+		}
+		os.Exit(reportEndpointHealth(health, healthFile))
+	}
+
+	// Create request logger
+	logger, err := services.NewRequestLogger(logFile)
+	if err != nil {
+		log.Fatalf("Failed to create request logger: %v", err)
+	}
+	defer logger.Close()
+	logger.SetRunID(*runID)
+
+	// Create test runner with logger
+	runner, err := newRunner(modelAPIKey, finalBaseURL, finalModel, logger, tags)
+	if err != nil {
+		log.Fatalf("Failed to configure test runner: %v", err)
+	}
+	if *reproBundles {
+		runner.SetReproDir(fmt.Sprintf("results/repro_%s_%s", sanitizedModel, *runID))
+	}
+	if cluster != nil {
+		runner.SetCluster(cluster.Info())
+	}
+
+	// Publish live progress events if requested
+	var events *services.EventBroadcaster
+	if *eventsAddr != "" {
+		events = services.NewEventBroadcaster(*runID)
+		runner.SetEventBroadcaster(events)
+
+		mux := http.NewServeMux()
+		mux.Handle("/events", events)
+		go func() {
+			if err := http.ListenAndServe(*eventsAddr, mux); err != nil {
+				log.Printf("Event stream server stopped: %v", err)
+			}
+		}()
+	}
+
+	// Reuse the completed tests of an interrupted run
+	if *resumeFile != "" {
+		completed, err := services.LoadCheckpoint(*resumeFile)
+		if err != nil {
+			log.Fatalf("Failed to load -resume file: %v", err)
+		}
+		if err := runner.SetCompletedResults(completed); err != nil {
+			log.Fatalf("Invalid -resume file: %v", err)
+		}
+	}
+
+	// Print test configuration
+	fmt.Printf("🚀 Starting Agent Loop Tool Efficiency Test\n")
+	fmt.Printf("📊 Configuration:\n")
+	fmt.Printf("   Run ID: %s\n", *runID)
+	if len(tags) > 0 {
+		fmt.Printf("   Tags: %s\n", tags)
+	}
+	fmt.Printf("   Provider: %s\n", *provider)
+	fmt.Printf("   Base URL: %s\n", finalBaseURL)
+	modelName := finalModel
+	if modelName == "" {
+		modelName = "gpt-4o-mini (default)"
+	}
+	if *provider == "kamiwaza" {
+		fmt.Printf("   Model: %s (API: %s)\n", *kamiwazaModel, modelName)
+	} else {
+		fmt.Printf("   Model: %s\n", modelName)
+	}
+	if *testCase != "" {
+		fmt.Printf("   Single Test Case: %s\n", *testCase)
+	}
+	fmt.Printf("   Reference Time: %s\n", refTime.Format(time.RFC3339))
+	fmt.Printf("   Test Cases: %d\n", len(testCases))
+	if *runs > 1 {
+		fmt.Printf("   Runs per Test Case: %d\n", *runs)
+	}
+	if testConfig.Seed != nil {
+		fmt.Printf("   Seed: %d\n", *testConfig.Seed)
+	}
+	if testConfig.ParallelToolCalls != nil {
+		fmt.Printf("   Parallel Tool Calls: %t\n", *testConfig.ParallelToolCalls)
+	}
+	if *stream {
+		fmt.Printf("   Streaming: enabled\n")
+	}
+	if *pruneResults || *maxMessageLen > 0 {
+		fmt.Printf("   Saved Results Pruning: tool results dropped=%t, max message length=%d\n", *pruneResults, *maxMessageLen)
+	}
+	if *sweepFile != "" {
+		fmt.Printf("   Sampling Sweep: %d configs\n", len(samplingConfigs))
+		for _, config := range samplingConfigs {
+			fmt.Printf("     %s\n", config.Label)
+		}
+	}
+	fmt.Printf("   Output: %s\n", outputFile)
+	fmt.Printf("   Log File: %s\n", logFile)
+	if *eventsAddr != "" {
+		host := *eventsAddr
+		if strings.HasPrefix(host, ":") {
+			host = "localhost" + host
+		}
+		fmt.Printf("   Event Stream: http://%s/events\n", host)
+	}
+	if *approvalTools != "" {
+		fmt.Printf("   Approval Required: %s\n", *approvalTools)
+	}
+	if *telemetryHook != "" {
+		fmt.Printf("   Telemetry Hook: %s (every %v)\n", *telemetryHook, *telemetryTick)
+	}
+	if len(toolSpecVersions) > 0 {
+		fmt.Printf("   Tool Spec Versions: %d (plus canonical)\n", len(toolSpecVersions))
+	}
+	if *auditRuns > 0 {
+		fmt.Printf("   Concurrency Audit: %d runs per test case\n", *auditRuns)
+	}
+	if state == models.ServiceStateShared {
+		fmt.Printf("   Service State: shared across tests\n")
+	}
+	if evaluationMode == models.EvaluationWorkflow {
+		fmt.Printf("   Evaluation: workflow (tool calls must have worked)\n")
+	}
+	if len(suite.Scorers) > 0 {
+		names := make([]string, len(suite.Scorers))
+		for i, scorer := range suite.Scorers {
+			names[i] = scorer.Name
+		}
+		fmt.Printf("   Scorers: %s (all must pass)\n", strings.Join(names, ", "))
+	}
+	if window := resolveContextWindow(*contextWindow, tags); window > 0 {
+		fmt.Printf("   Context Window: %d tokens\n", window)
+	}
+	if *gateway {
+		provider, _ := services.ParseGatewayModel(finalModel)
+		if provider == "" {
+			provider = "none (model name has no provider prefix)"
+		}
+		fmt.Printf("   Gateway Provider: %s\n", provider)
+	}
+	if credential, found := services.FindCredential(credentials, finalModel); found {
+		fmt.Printf("   API Key: %s\n", services.DescribeCredential(*credential))
+	}
+	if *proxyURL != "" {
+		fmt.Printf("   Proxy: %s\n", services.DescribeProxy(*proxyURL))
+	}
+	if len(headers) > 0 {
+		names := make([]string, 0, len(headers))
+		for name := range headers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fmt.Printf("   Extra Headers: %s\n", strings.Join(names, ", "))
+	}
+	if networkChaos != nil {
+		fmt.Printf("   Network Chaos: %s\n", formatNetworkChaos(networkChaos))
+	}
+	if judge != nil {
+		fmt.Printf("   Judge Model: %s\n", judge.Model())
+	}
+	if signingKey != nil {
+		fmt.Printf("   Signing: %s (key %s)\n", services.SignatureAlgorithm, services.SigningKeyID(signingKey))
+	}
+	if *warmupCount > 0 {
+		fmt.Printf("   Warm-up Requests: %d\n", *warmupCount)
+	}
+	if rateLimiter != nil {
+		fmt.Printf("   Rate Limit: %s\n", formatRateLimit(*requestsLimit, *tokensLimit))
+	}
+	if *maxFailures > 0 {
+		fmt.Printf("   Max Failures: %d\n", *maxFailures)
+	}
+	if *resumeFile != "" {
+		fmt.Printf("   Resume From: %s\n", *resumeFile)
+	}
+	if *rerunInfra != "" {
+		fmt.Printf("   Rerun Infrastructure Failures Of: %s\n", *rerunInfra)
+	}
+	fmt.Println()
+
+	// Run tests. The first SIGINT or SIGTERM stops the run and saves what finished;
+	// a second one exits immediately.
+	ctx, cancel := interruptibleContext()
+	defer cancel()
+
+	if *auditRuns > 0 {
+		auditFile := fmt.Sprintf("results/concurrency_audit_%s_%s.json", sanitizedModel, *runID)
+		violations, err := runConcurrencyAudit(ctx, runner, testCases, *auditRuns, auditFile)
+		if err != nil {
+			log.Fatalf("Failed to run concurrency audit: %v", err)
+		}
+		if violations > 0 {
+			logger.Close()
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Watch for the deployment dying mid-run
+	if *redeploy && kamiwazaSvc == nil {
+		log.Fatalf("-redeploy requires -provider=kamiwaza")
+	}
+	if *watchdog || *redeploy {
+		var redeployModel services.RedeployFunc
+		if *redeploy {
+			deployConfig := services.KamiwazaDeployConfigFromTags(tags)
+			redeployModel = func(ctx context.Context) (string, error) {
+				timeout := *watchdogWait
+				if deadline, ok := ctx.Deadline(); ok {
+					timeout = time.Until(deadline)
+				}
+				if _, err := kamiwazaSvc.RedeployModel(*kamiwazaModel, deployConfig, timeout); err != nil {
+					return "", err
+				}
+				endpoint, err := kamiwazaSvc.GetModelEndpoint(*kamiwazaModel)
+				if err != nil {
+					return "", err
+				}
+				return endpoint + "/v1", nil
+			}
+		}
+		runner.SetWatchdog(services.NewDeploymentWatchdog(*watchdogWait, redeployModel))
+	}
+
+	// Rerun the infrastructure failures of a finished run instead of the whole suite
+	if *rerunInfra != "" {
+		if *resumeFile != "" {
+			log.Fatalf("-rerun-infra-failures cannot be combined with -resume")
+		}
+		tolerance := models.BaselineTolerance{SuccessRateDrop: *successTol, LatencyIncrease: *latencyTol}
+		status := rerunInfrastructureFailures(ctx, runner, *rerunInfra, *baselineDir, sanitizedModel, tolerance)
+		events.Close(2 * time.Second)
+		logger.Close()
+		os.Exit(status)
+	}
+
+	// Sample hardware telemetry alongside the run
+	var telemetry *services.TelemetrySampler
+	if *telemetryHook != "" {
+		telemetry, err = services.NewTelemetrySampler(*telemetryHook, *telemetryTick)
+		if err != nil {
+			log.Fatalf("Invalid -telemetry-hook: %v", err)
+		}
+		telemetry.SetEventBroadcaster(events)
+		telemetry.Start(ctx)
+		manifest.TelemetryHook = *telemetryHook
+		manifest.TelemetryInterval = telemetryTick.String()
+	}
+
+	// Write each result as it completes so an interrupted run can be resumed
+	checkpointFile := fmt.Sprintf("results/checkpoint_%s_%s.jsonl", sanitizedModel, *runID)
+	checkpoint, err := services.NewResultCheckpoint(checkpointFile)
+	if err != nil {
+		log.Fatalf("Failed to create checkpoint: %v", err)
+	}
+	runner.SetCheckpoint(checkpoint)
+
+	// Load the model before any test is timed
+	var warmup *models.Warmup
+	if *warmupCount > 0 {
+		warmup = runWarmup(ctx, runner, *warmupCount, *warmupTimeout)
+	}
+
+	fmt.Println("🔄 Running agent tests...")
+	startTime := time.Now()
+
+	report, err := runner.RunAgentTestSuite(ctx, testCases)
+	if err != nil {
+		log.Fatalf("Failed to run agent test suite: %v (resume with -resume %s)", err, checkpointFile)
+	}
+	report.ResumedFrom = *resumeFile
+	report.Warmup = warmup
+	if report.Incomplete {
+		savePartialRun(runner, report, outputFile, checkpointFile, telemetry, manifest, manifestFile, signingKey)
+		events.Close(2 * time.Second)
+		logger.Close()
+		if ctx.Err() == nil {
+			os.Exit(1) // Stopped by -max-failures, or by the watchdog after the endpoint did not recover
+		}
+		os.Exit(130)
+	}
+
+	// Tool spec reruns are scored separately and must not reuse or overwrite the checkpoint
+	checkpoint.Close()
+	runner.SetCheckpoint(nil)
+	runner.SetCompletedResults(nil)
+
+	duration := time.Since(startTime)
+	fmt.Printf("✅ Tests completed in %v\n", duration)
+	if delayed, waited := rateLimiter.Delays(); delayed > 0 {
+		fmt.Printf("⏳ Rate limit delayed %d requests by %v in total\n", delayed, waited.Round(time.Millisecond))
+	}
+	fmt.Println()
+
+	// Let live subscribers receive the final events before exiting
+	events.Close(2 * time.Second)
+
+	// Compare with the pinned baseline before saving so the comparison is recorded
+	var baselinePath string
+	if *baselineDir != "" && !*pinBaseline {
+		var baseline *models.AgentReport
+		baseline, baselinePath, err = services.LoadBaseline(*baselineDir, sanitizedModel)
+		if err != nil {
+			log.Fatalf("Failed to load baseline: %v", err)
+		}
+		if baseline != nil {
+			tolerance := models.BaselineTolerance{SuccessRateDrop: *successTol, LatencyIncrease: *latencyTol}
+			report.BaselineComparison = services.CompareToBaseline(baseline, report, tolerance)
+			report.BaselineComparison.BaselineFile = baselinePath
+		}
+	}
+
+	// Save results; the checkpoint is only needed until they are on disk
+	if err := runner.SaveResults(outputFile, report); err != nil {
+		log.Fatalf("Failed to save results: %v (resume with -resume %s)", err, checkpointFile)
+	}
+	os.Remove(checkpointFile)
+
+	// Print summary
+	printAgentSummary(report)
+	if report.BaselineComparison != nil {
+		printBaselineComparison(report.BaselineComparison)
+	}
+
+	fmt.Printf("\n💾 Results saved to: %s\n", outputFile)
+	fmt.Printf("📝 Request logs saved to: %s\n", logFile)
+
+	// Rerun the suite once per tool description version
+	if len(toolSpecVersions) > 0 {
+		comparisonFile := fmt.Sprintf("results/tool_spec_comparison_%s_%s.json", sanitizedModel, *runID)
+		if err := runToolSpecVersions(ctx, runner, testCases, toolSpecVersions, report, outputFile, comparisonFile, sanitizedModel, *runID); err != nil {
+			log.Fatalf("Failed to run tool spec versions: %v", err)
+		}
+	}
+
+	// Record the telemetry timeline in the run manifest
+	manifest.ResultsFile = outputFile
+	if telemetry != nil {
+		manifest.Timeline = telemetry.Stop()
+		fmt.Printf("🌡️  Telemetry timeline: %d samples\n", len(manifest.Timeline))
+	}
+
+	// Post-run hook failures are recorded but do not invalidate the saved results
+	if hookConfig != nil {
+		fmt.Println()
+		runHookStage(hookConfig, models.HookPostRun, hookEnv, manifest, manifestFile, signingKey)
+	} else if telemetry != nil {
+		manifest.FinishedAt = time.Now()
+		if err := services.SaveRunManifest(manifestFile, manifest, signingKey); err != nil {
+			log.Fatalf("Failed to save run manifest: %v", err)
+		}
+		fmt.Printf("📜 Run manifest saved to: %s\n", manifestFile)
+	}
+
+	// Export to experiment trackers
+	var exporters []services.ExperimentExporter
+	if *mlflowURI != "" {
+		exporter := services.NewMLflowExporter(*mlflowURI, *mlflowExp)
+		exporter.SetToken(os.Getenv("MLFLOW_TRACKING_TOKEN"))
+		exporters = append(exporters, exporter)
+	}
+	if *wandbProject != "" {
+		exporters = append(exporters, services.NewWandbExporter(os.Getenv("WANDB_BASE_URL"), os.Getenv("WANDB_API_KEY"), *wandbEntity, *wandbProject))
+	}
+
+	exportModel := modelNameForFile
+	if exportModel == "" {
+		exportModel = "gpt-4o-mini"
+	}
+	params := map[string]string{
+		"model":       exportModel,
+		"provider":    *provider,
+		"base_url":    finalBaseURL,
+		"config_file": *configFile,
+		"test_case":   *testCase,
+		"units_file":  *unitsFile,
+		"match":       fmt.Sprintf("case_sensitive=%t,trim=%t,collapse_whitespace=%t,unicode=%s", *caseSensitive, *matchTrim, *matchCollapse, *matchUnicode),
+	}
+	if testConfig.Seed != nil {
+		params["seed"] = fmt.Sprintf("%d", *testConfig.Seed)
+	}
+	if testConfig.ParallelToolCalls != nil {
+		params["parallel_tool_calls"] = fmt.Sprintf("%t", *testConfig.ParallelToolCalls)
+	}
+	if *stream {
+		params["stream"] = "true"
+	}
+	for _, exporter := range exporters {
+		if err := exporter.Export(ctx, report, params); err != nil {
+			fmt.Printf("⚠️  Failed to export to %s: %v\n", exporter.Name(), err)
+			continue
+		}
+		fmt.Printf("📤 Exported run to %s\n", exporter.Name())
+	}
+
+	if *pinBaseline {
+		path, err := services.PinBaseline(*baselineDir, sanitizedModel, report)
+		if err != nil {
+			log.Fatalf("Failed to pin baseline: %v", err)
+		}
+		fmt.Printf("📌 Pinned as baseline: %s\n", path)
+	}
+
+	if report.BaselineComparison != nil && report.BaselineComparison.Regressed {
+		fmt.Printf("❌ Regression beyond tolerance versus baseline %s\n", baselinePath)
+		logger.Close()
+		os.Exit(1)
+	}
+}
+
+// interruptibleContext returns a context cancelled by the first SIGINT or SIGTERM;
+// the signal handler is then removed so a second one exits immediately
+func interruptibleContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		signal.Stop(signals)
+		fmt.Printf("\n⚠️  Received %v: waiting for in-flight tests, then saving a partial report (repeat to exit immediately)\n", sig)
+		cancel()
+	}()
+	return ctx, cancel
+}
+
+// savePartialRun saves the results of an interrupted run and keeps its checkpoint for
+// -resume. The baseline comparison, tool spec reruns, post-run hooks and exports are
+// skipped, since a partial run would skew them.
+func savePartialRun(runner *services.TestRunner, report *models.AgentReport, outputFile, checkpointFile string, telemetry *services.TelemetrySampler, manifest *models.RunManifest, manifestFile string, signingKey []byte) {
+	if err := runner.SaveResults(outputFile, report); err != nil {
+		log.Fatalf("Failed to save partial results: %v (resume with -resume %s)", err, checkpointFile)
+	}
+
+	printAgentSummary(report)
+	fmt.Printf("\n💾 Partial results saved to: %s\n", outputFile)
+	fmt.Printf("⏯️  Resume with: -resume %s\n", checkpointFile)
+
+	if telemetry != nil {
+		manifest.ResultsFile = outputFile
+		manifest.Timeline = telemetry.Stop()
+		manifest.FinishedAt = time.Now()
+		if err := services.SaveRunManifest(manifestFile, manifest, signingKey); err != nil {
+			log.Fatalf("Failed to save run manifest: %v", err)
+		}
+		fmt.Printf("📜 Run manifest saved to: %s\n", manifestFile)
+	}
+}
 
-	// Resolve Kamiwaza configuration if needed
-	finalBaseURL := *baseURL
-	finalModel := *model
+// printBaselineComparison prints how the run compares with the model's pinned baseline
+func printBaselineComparison(comparison *models.BaselineComparison) {
+	fmt.Println("\n📌 Baseline Comparison")
+	fmt.Println(strings.Repeat("=", 50))
+	fmt.Printf("Baseline: %s", comparison.BaselineFile)
+	if comparison.BaselineRunID != "" {
+		fmt.Printf(" (run %s)", comparison.BaselineRunID)
+	}
+	fmt.Printf("\nCommon Test Cases: %d\n", comparison.CommonTests)
 
-	if *provider == "kamiwaza" {
-		if *kamiwazaModel == "" {
-			log.Fatalf("Kamiwaza model name (-kamiwaza-model) is required when using -provider=kamiwaza")
+	for _, metric := range comparison.Metrics {
+		status := "✅"
+		if metric.Regressed {
+			status = "❌"
 		}
 
-		kamiwazaSvc := services.NewKamiwazaService(*kamiwazaURL)
+		switch metric.Name {
+		case "success_rate":
+			fmt.Printf("%s Success Rate: %.2f%% -> %.2f%% (%+.2f points, tolerance -%.2f)\n", status,
+				metric.Baseline*100, metric.Current*100, metric.Change*100, metric.Tolerance*100)
+		default:
+			fmt.Printf("%s %s: %.3f -> %.3f (%+.1f%%, tolerance +%.1f%%)\n", status, metric.Name,
+				metric.Baseline, metric.Current, metric.Change*100, metric.Tolerance*100)
+		}
+	}
 
-		// Get the deployment endpoint for the specified model
-		endpoint, err := kamiwazaSvc.GetModelEndpoint(*kamiwazaModel)
+	if len(comparison.NewlyFailing) > 0 {
+		fmt.Printf("Newly Failing: %s\n", strings.Join(comparison.NewlyFailing, ", "))
+	}
+	if len(comparison.NewlyPassing) > 0 {
+		fmt.Printf("Newly Passing: %s\n", strings.Join(comparison.NewlyPassing, ", "))
+	}
+}
+
+// runConcurrencyAudit runs the parallelism-safety audit, saves its report and prints
+// any isolation violations. It returns the number of violations found.
+func runConcurrencyAudit(ctx context.Context, runner *services.TestRunner, testCases []models.TestCase, concurrency int, outputFile string) (int, error) {
+	fmt.Println("🔀 Running concurrency audit...")
+	report := runner.RunConcurrencyAudit(ctx, testCases, concurrency)
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal audit report: %w", err)
+	}
+	if err := os.WriteFile(outputFile, services.RedactSecrets(data), 0644); err != nil {
+		return 0, fmt.Errorf("failed to save audit report: %w", err)
+	}
+
+	fmt.Println("\n🔀 Concurrency Audit Results")
+	fmt.Println(strings.Repeat("=", 50))
+	for _, audit := range report.Audits {
+		status := "✅ ISOLATED"
+		if len(audit.Violations) > 0 {
+			status = fmt.Sprintf("❌ %d VIOLATIONS", len(audit.Violations))
+		}
+		fmt.Printf("Test Case: %s (%d runs)\n", audit.TestCase, audit.Concurrency)
+		fmt.Printf("  Status: %s\n", status)
+		for _, violation := range audit.Violations {
+			fmt.Printf("  - %s [%s]: %s\n", violation.Invariant, violation.SessionID, violation.Details)
+		}
+	}
+
+	fmt.Printf("\n📊 Total Violations: %d\n", report.TotalViolations)
+	fmt.Printf("💾 Audit report saved to: %s\n", outputFile)
+
+	return report.TotalViolations, nil
+}
+
+// runToolSpecVersions reruns the suite once per tool description version, saving each
+// version's results next to the canonical ones, then saves and prints how each version's
+// accuracy differs from the canonical run
+func runToolSpecVersions(ctx context.Context, runner *services.TestRunner, testCases []models.TestCase, versions []models.ToolSpecVersion, canonical *models.AgentReport, canonicalFile, outputFile, modelName, runID string) error {
+	comparison := &models.ToolSpecComparison{
+		RunID:                runID,
+		Model:                modelName,
+		CanonicalResultsFile: canonicalFile,
+	}
+	if canonical.TotalTests > 0 {
+		comparison.CanonicalSuccessRate = float64(canonical.PassedTests) / float64(canonical.TotalTests)
+	}
+
+	reports := []*models.AgentReport{canonical}
+	for i := range versions {
+		version := &versions[i]
+		fmt.Printf("\n🔤 Running tool spec version: %s\n", version.Name)
+		if err := runner.SetToolSpecVersion(version); err != nil {
+			return err
+		}
+		if runner.ReproDir() != "" {
+			runner.SetReproDir(fmt.Sprintf("results/repro_%s_%s_%s", modelName, sanitizeModelName(version.Name), runID))
+		}
+
+		report, err := runner.RunAgentTestSuite(ctx, testCases)
 		if err != nil {
-			log.Fatalf("Failed to get Kamiwaza endpoint for model '%s': %v", *kamiwazaModel, err)
+			return fmt.Errorf("failed to run suite with tool spec version '%s': %w", version.Name, err)
 		}
 
-		finalBaseURL = endpoint + "/v1"
-		finalModel = kamiwazaSvc.GetModelIdentifier()
+		resultsFile := fmt.Sprintf("results/tool_spec_results_%s_%s_%s.json", modelName, sanitizeModelName(version.Name), runID)
+		if err := runner.SaveResults(resultsFile, report); err != nil {
+			return fmt.Errorf("failed to save results for tool spec version '%s': %w", version.Name, err)
+		}
 
-		fmt.Printf("🔍 Kamiwaza Discovery:\n")
-		fmt.Printf("   Model Name: %s\n", *kamiwazaModel)
-		fmt.Printf("   Endpoint: %s\n", finalBaseURL)
-		fmt.Println()
+		reports = append(reports, report)
+		result := services.CompareToolSpecVersion(canonical, report)
+		result.ResultsFile = resultsFile
+		comparison.Versions = append(comparison.Versions, result)
 	}
 
-	// Generate output filenames with model name
-	modelNameForFile := *model
-	if *provider == "kamiwaza" {
-		modelNameForFile = *kamiwazaModel
+	comparison.LocaleAccuracy = services.CalculateLocaleAccuracy(reports)
+
+	// Leave the runner with the canonical definitions
+	if err := runner.SetToolSpecVersion(nil); err != nil {
+		return err
 	}
-	sanitizedModel := sanitizeModelName(modelNameForFile)
-	timestamp := time.Now().Format("20060102_150405")
-	outputFile := fmt.Sprintf("results/agent_test_results_%s_%s.json", sanitizedModel, timestamp)
-	logFile := fmt.Sprintf("logs/agent_test_logs_%s_%s.log", sanitizedModel, timestamp)
 
-	// Ensure directories exist
-	if err := os.MkdirAll("results", 0755); err != nil {
-		log.Fatalf("Failed to create results directory: %v", err)
+	data, err := json.MarshalIndent(comparison, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal tool spec comparison: %w", err)
 	}
-	if err := os.MkdirAll("logs", 0755); err != nil {
-		log.Fatalf("Failed to create logs directory: %v", err)
+	if err := os.WriteFile(outputFile, services.RedactSecrets(data), 0644); err != nil {
+		return fmt.Errorf("failed to save tool spec comparison: %w", err)
 	}
 
-	// Create request logger
-	logger, err := services.NewRequestLogger(logFile)
-	if err != nil {
-		log.Fatalf("Failed to create request logger: %v", err)
+	fmt.Println("\n🔤 Tool Spec Comparison")
+	fmt.Println(strings.Repeat("=", 50))
+	fmt.Printf("canonical: %.2f%%\n", comparison.CanonicalSuccessRate*100)
+	for _, result := range comparison.Versions {
+		fmt.Printf("%s: %.2f%% (%+.2f points, %d/%d passed)\n", result.Version,
+			result.SuccessRate*100, result.SuccessRateDelta*100, result.PassedTests, result.TotalTests)
+
+		reasons := make([]string, 0, len(result.FailureDeltas))
+		for reason, delta := range result.FailureDeltas {
+			reasons = append(reasons, fmt.Sprintf("%s %+d", reason, delta))
+		}
+		sort.Strings(reasons)
+		if len(reasons) > 0 {
+			fmt.Printf("  Failure Changes: %s\n", strings.Join(reasons, ", "))
+		}
+		if len(result.NewlyFailing) > 0 {
+			fmt.Printf("  Newly Failing: %s\n", strings.Join(result.NewlyFailing, ", "))
+		}
+		if len(result.NewlyPassing) > 0 {
+			fmt.Printf("  Newly Passing: %s\n", strings.Join(result.NewlyPassing, ", "))
+		}
 	}
-	defer logger.Close()
+	if len(comparison.LocaleAccuracy) > 1 {
+		fmt.Println("\n🌐 Tool Selection Accuracy by Locale (prompt / tools)")
+		for _, entry := range comparison.LocaleAccuracy {
+			fmt.Printf("  %s / %s: %.2f%% selection, %.2f%% passed (%d tests)\n", entry.PromptLocale, entry.ToolLocale,
+				entry.SelectionAccuracy*100, entry.SuccessRate*100, entry.TotalTests)
+		}
+	}
+	fmt.Printf("💾 Tool spec comparison saved to: %s\n", outputFile)
 
-	// Create test runner with logger
-	runner := services.NewTestRunnerWithLogger(*apiKey, finalBaseURL, finalModel, logger)
+	return nil
+}
 
-	// Print test configuration
-	fmt.Printf("🚀 Starting Agent Loop Tool Efficiency Test\n")
-	fmt.Printf("📊 Configuration:\n")
-	fmt.Printf("   Provider: %s\n", *provider)
-	fmt.Printf("   Base URL: %s\n", finalBaseURL)
-	modelName := finalModel
-	if modelName == "" {
-		modelName = "gpt-4o-mini (default)"
+// runHookStage runs the hooks of one stage, appends their results to the run manifest
+// and saves it. It returns the process exit code: non-zero when a hook that does not
+// continue on error failed.
+func runHookStage(config *models.HookConfig, stage models.HookStage, env map[string]string, manifest *models.RunManifest, manifestFile string, signingKey []byte) int {
+	hooks := services.HooksForStage(config, stage)
+	if len(hooks) > 0 {
+		fmt.Printf("🪝 Running %d %s hooks\n", len(hooks), stage)
 	}
-	if *provider == "kamiwaza" {
-		fmt.Printf("   Model: %s (API: %s)\n", *kamiwazaModel, modelName)
-	} else {
-		fmt.Printf("   Model: %s\n", modelName)
+
+	results, err := services.RunHooks(context.Background(), config, stage, env)
+	for _, result := range results {
+		status := "✅"
+		if !result.Success {
+			status = "❌"
+		}
+		fmt.Printf("   %s %s (%v)", status, result.Name, result.Duration.Round(time.Millisecond))
+		if result.Error != "" {
+			fmt.Printf(": %s", result.Error)
+		}
+		fmt.Println()
 	}
-	if *testCase != "" {
-		fmt.Printf("   Single Test Case: %s\n", *testCase)
+
+	manifest.Hooks = append(manifest.Hooks, results...)
+	manifest.FinishedAt = time.Now()
+	if saveErr := services.SaveRunManifest(manifestFile, manifest, signingKey); saveErr != nil {
+		log.Fatalf("Failed to save run manifest: %v", saveErr)
+	}
+	fmt.Printf("📜 Run manifest saved to: %s\n", manifestFile)
+
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// reportEndpointHealth prints and saves the result of -health-check. It returns the
+// process exit code: non-zero when the endpoint is unhealthy.
+func reportEndpointHealth(health models.EndpointHealth, filename string) int {
+	check := func(ok bool) string {
+		if ok {
+			return "✅"
+		}
+		return "❌"
+	}
+	fmt.Printf("🩺 Endpoint health: %s (%s)\n", health.Model, health.Endpoint)
+	fmt.Printf("   %s Reachable  %s Auth  %s Model loaded", check(health.Reachable), check(health.AuthOK), check(health.ModelLoaded))
+	if health.SampleLatency > 0 {
+		fmt.Printf("  ⏱️  %v", health.SampleLatency.Round(time.Millisecond))
 	}
-	fmt.Printf("   Test Cases: %d\n", len(testCases))
-	fmt.Printf("   Output: %s\n", outputFile)
-	fmt.Printf("   Log File: %s\n", logFile)
 	fmt.Println()
+	if health.Error != "" {
+		fmt.Printf("   ⚠️  %s\n", services.RedactString(health.Error))
+	}
 
-	// Run tests
-	ctx := context.Background()
+	data, err := json.MarshalIndent(health, "", "  ")
+	if err != nil {
+		log.Fatalf("Failed to marshal endpoint health: %v", err)
+	}
+	if err := os.WriteFile(filename, services.RedactSecrets(data), 0644); err != nil {
+		log.Fatalf("Failed to save endpoint health: %v", err)
+	}
+	fmt.Printf("💾 Endpoint health saved to: %s\n", filename)
 
-	fmt.Println("🔄 Running agent tests...")
-	startTime := time.Now()
+	if !health.Healthy() {
+		return 1
+	}
+	return 0
+}
 
-	report, err := runner.RunAgentTestSuite(ctx, testCases)
+// rerunInfrastructureFailures reruns the tests of a results file that failed against
+// the server, merges the new results into the file and prints the updated summary.
+// It returns the exit status: non-zero when tests still fail against the server.
+func rerunInfrastructureFailures(ctx context.Context, runner *services.TestRunner, resultsFile, baselineDir, modelName string, tolerance models.BaselineTolerance) int {
+	original, err := services.LoadAgentReport(resultsFile)
 	if err != nil {
-		log.Fatalf("Failed to run agent test suite: %v", err)
+		log.Fatalf("Failed to load -rerun-infra-failures file: %v", err)
 	}
 
-	duration := time.Since(startTime)
-	fmt.Printf("✅ Tests completed in %v\n\n", duration)
+	report, rerun, err := runner.RerunInfrastructureFailures(ctx, original)
+	if err != nil {
+		log.Fatalf("Failed to rerun infrastructure failures: %v", err)
+	}
+	if rerun == nil {
+		fmt.Printf("✅ No tests in %s failed against the server; nothing to rerun\n", resultsFile)
+		return 0
+	}
 
-	// Save results
-	if err := runner.SaveResults(outputFile, report); err != nil {
+	// Refresh the baseline comparison with the merged results
+	if report.BaselineComparison != nil && baselineDir != "" {
+		baseline, baselinePath, err := services.LoadBaseline(baselineDir, modelName)
+		if err != nil {
+			log.Fatalf("Failed to load baseline: %v", err)
+		}
+		if baseline != nil {
+			report.BaselineComparison = services.CompareToBaseline(baseline, report, tolerance)
+			report.BaselineComparison.BaselineFile = baselinePath
+		}
+	}
+
+	if err := runner.SaveResults(resultsFile, report); err != nil {
 		log.Fatalf("Failed to save results: %v", err)
 	}
 
-	// Print summary
 	printAgentSummary(report)
+	if report.BaselineComparison != nil {
+		printBaselineComparison(report.BaselineComparison)
+	}
+	fmt.Printf("\n🔁 Reran %d tests in %v: %d passed, %d failed on the model's answer, %d failed against the server again",
+		rerun.RerunTests, rerun.Duration.Round(time.Millisecond), rerun.Passed, rerun.ModelFailed, rerun.StillFailing)
+	if rerun.Interrupted > 0 {
+		fmt.Printf(", %d interrupted", rerun.Interrupted)
+	}
+	fmt.Printf("\n💾 Merged results saved to: %s\n", resultsFile)
 
-	fmt.Printf("\n💾 Results saved to: %s\n", outputFile)
-	fmt.Printf("📝 Request logs saved to: %s\n", logFile)
+	if rerun.StillFailing > 0 || rerun.Interrupted > 0 {
+		return 1
+	}
+	return 0
 }
 
-// loadTestCases loads test cases from a JSON file, optionally filtering by test case name
-func loadTestCases(filename string, testCaseName string) ([]models.TestCase, error) {
-	data, err := os.ReadFile(filename)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read test cases file: %w", err)
+// runWarmup sends the warm-up requests and prints their latencies. A failed warm-up
+// is reported but does not stop the run.
+func runWarmup(ctx context.Context, runner *services.TestRunner, requests int, timeout time.Duration) *models.Warmup {
+	fmt.Printf("🔥 Warming up the endpoint with %d requests...\n", requests)
+	warmup := runner.WarmUp(ctx, requests, timeout)
+
+	for i, latency := range warmup.Latencies {
+		fmt.Printf("   #%d: %v\n", i+1, latency.Round(time.Millisecond))
+	}
+	if warmup.Error != "" {
+		fmt.Printf("⚠️  Warm-up stopped: %s\n", warmup.Error)
 	}
+	fmt.Println()
+
+	return warmup
+}
+
+// resolveContextWindow returns the context window set by -context-window, falling back
+// to the run's context_length tag, which batch-run sets for the deployments it creates
+func resolveContextWindow(flagValue int, tags models.RunTags) int {
+	if flagValue > 0 {
+		return flagValue
+	}
+	if length, err := strconv.Atoi(tags["context_length"]); err == nil && length > 0 {
+		return length
+	}
+	return 0
+}
+
+// formatRateLimit describes the per-minute limits that are set
+func formatRateLimit(requestsPerMinute, tokensPerMinute int) string {
+	var limits []string
+	if requestsPerMinute > 0 {
+		limits = append(limits, fmt.Sprintf("%d requests/min", requestsPerMinute))
+	}
+	if tokensPerMinute > 0 {
+		limits = append(limits, fmt.Sprintf("%d tokens/min", tokensPerMinute))
+	}
+	return strings.Join(limits, ", ")
+}
+
+// formatNetworkChaos describes the faults a chaos config injects
+func formatNetworkChaos(chaos *models.NetworkChaos) string {
+	var faults []string
+	if chaos.Latency != "" || chaos.Jitter != "" {
+		latency := chaos.Latency
+		if latency == "" {
+			latency = "0s"
+		}
+		if chaos.Jitter != "" {
+			latency += " ± " + chaos.Jitter
+		}
+		faults = append(faults, "latency "+latency)
+	}
+	if chaos.DropRate > 0 {
+		faults = append(faults, fmt.Sprintf("%.0f%% dropped connections", chaos.DropRate*100))
+	}
+	if chaos.SlowBodyRate > 0 {
+		faults = append(faults, fmt.Sprintf("%.0f%% slow bodies", chaos.SlowBodyRate*100))
+	}
+	if len(faults) == 0 {
+		return "no faults"
+	}
+	return strings.Join(faults, ", ")
+}
+
+// formatNetworkFaults counts injected faults by type, e.g. "3 latency, 1 dropped_connection"
+func formatNetworkFaults(faults []models.NetworkFault) string {
+	counts := make(map[models.NetworkFaultType]int)
+	for _, fault := range faults {
+		counts[fault.Type]++
+	}
+	var parts []string
+	for _, faultType := range []models.NetworkFaultType{models.NetworkFaultLatency, models.NetworkFaultDrop, models.NetworkFaultSlowBody} {
+		if counts[faultType] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[faultType], faultType))
+		}
+	}
+	if len(parts) == 0 {
+		return "no faults"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// dryRunPlan is what a run would do, described by -dry-run
+type dryRunPlan struct {
+	baseURL       string
+	model         string
+	runs          int
+	configs       int // Sampling configs of the sweep
+	toolSpecs     int // Tool spec versions run in addition to the canonical definitions
+	warmup        int
+	approvalTools string
+}
+
+// runDryRun validates the test cases and prints the endpoint, the planned requests,
+// the tool definitions and the prompt size preview without contacting the model. It
+// returns the exit code: 1 when the configuration has problems.
+func runDryRun(runner *services.TestRunner, testCases []models.TestCase, versions []models.ToolSpecVersion, plan dryRunPlan, costPerMTok float64) int {
+	fmt.Println("🧪 Dry Run: nothing is sent to the model")
+	fmt.Printf("   Endpoint: %s\n", plan.baseURL)
+	fmt.Printf("   Model: %s\n", plan.model)
+	fmt.Println()
+
+	problems := runner.ValidateTestCases(testCases)
+	if plan.approvalTools != "" {
+		if err := runner.SetApprovalRequired(strings.Split(plan.approvalTools, ",")); err != nil {
+			problems = append(problems, fmt.Sprintf("-require-approval: %v", err))
+		}
+	}
+	if len(problems) > 0 {
+		fmt.Printf("❌ %d problems in the configuration:\n", len(problems))
+		for _, problem := range problems {
+			fmt.Printf("   %s\n", problem)
+		}
+	} else {
+		fmt.Printf("✅ %d test cases are valid\n", len(testCases))
+	}
+
+	tests := len(testCases) * plan.runs * plan.configs * (1 + plan.toolSpecs)
+	fmt.Printf("📨 Planned: %d tests (%d test cases × %d runs × %d sampling configs × %d tool spec versions)\n",
+		tests, len(testCases), plan.runs, plan.configs, 1+plan.toolSpecs)
+	fmt.Printf("   %d to %d LLM requests (up to %d per test)", tests, tests*services.MaxIterations, services.MaxIterations)
+	if plan.warmup > 0 {
+		fmt.Printf(" plus %d warm-up requests", plan.warmup)
+	}
+	fmt.Println()
+
+	definitions := runner.ToolDefinitions()
+	fmt.Printf("\n🔧 Tool Definitions (%d):\n", len(definitions))
+	for _, definition := range definitions {
+		var parameters []string
+		if properties, ok := definition.Function.Parameters["properties"].(map[string]interface{}); ok {
+			for name := range properties {
+				parameters = append(parameters, name)
+			}
+		}
+		sort.Strings(parameters)
+		fmt.Printf("   %s(%s): %s\n", definition.Function.Name, strings.Join(parameters, ", "), definition.Function.Description.Value)
+	}
+	fmt.Println()
+
+	printPromptPreview(runner, testCases, versions, costPerMTok)
+
+	if len(problems) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// printPromptPreview prints the estimated first request size of each test case and
+// the suite totals, including one suite run per tool spec version
+func printPromptPreview(runner *services.TestRunner, testCases []models.TestCase, versions []models.ToolSpecVersion, costPerMTok float64) {
+	estimates := runner.EstimatePrompts(testCases)
 
-	var allTestCases []models.TestCase
-	if err := json.Unmarshal(data, &allTestCases); err != nil {
-		return nil, fmt.Errorf("failed to parse test cases: %w", err)
+	fmt.Println("🧮 Prompt Size Preview (dry run, ~4 characters per token)")
+	fmt.Println(strings.Repeat("=", 50))
+	fmt.Printf("%-40s %8s %8s %8s %8s\n", "Test Case", "System", "Tools", "Messages", "Total")
+
+	var suiteTokens int64
+	for _, estimate := range estimates {
+		fmt.Printf("%-40s %8d %8d %8d %8d\n", estimate.TestCase, estimate.SystemTokens, estimate.ToolTokens,
+			estimate.MessageTokens, estimate.TotalTokens)
+		suiteTokens += estimate.TotalTokens
 	}
 
-	// If no specific test case is requested, return all test cases
-	if testCaseName == "" {
-		return allTestCases, nil
+	fmt.Printf("\n📊 Suite: %d test cases, ~%d prompt tokens in first requests\n", len(estimates), suiteTokens)
+	if costPerMTok > 0 {
+		fmt.Printf("💰 Estimated input cost: $%.4f per suite run (first requests only)\n", float64(suiteTokens)/1e6*costPerMTok)
 	}
 
-	// Filter for the specific test case
-	var filteredTestCases []models.TestCase
-	for _, testCase := range allTestCases {
-		if testCase.Name == testCaseName {
-			filteredTestCases = append(filteredTestCases, testCase)
-			break
+	for i := range versions {
+		if err := runner.SetToolSpecVersion(&versions[i]); err != nil {
+			fmt.Printf("⚠️  Skipping tool spec version %s: %v\n", versions[i].Name, err)
+			continue
+		}
+		var versionTokens int64
+		for _, estimate := range runner.EstimatePrompts(testCases) {
+			versionTokens += estimate.TotalTokens
+		}
+		fmt.Printf("🔤 Tool spec version %s: ~%d prompt tokens", versions[i].Name, versionTokens)
+		if costPerMTok > 0 {
+			fmt.Printf(" ($%.4f)", float64(versionTokens)/1e6*costPerMTok)
 		}
+		fmt.Println()
+	}
+
+	fmt.Println("\nEach further agent loop iteration resends the prompt plus the tool calls and results so far.")
+}
+
+// loadTestSuite loads the test config from a JSON file, optionally keeping only the
+// test cases selected by -test-case names and patterns
+func loadTestSuite(filename string, selection string) (*models.TestSuite, error) {
+	suite, err := services.LoadTestSuite(filename)
+	if err != nil {
+		return nil, err
 	}
 
-	// Validate that the test case was found
-	if len(filteredTestCases) == 0 {
-		return nil, fmt.Errorf("test case '%s' not found in configuration file", testCaseName)
+	// If no specific test case is requested, keep all test cases
+	if selection == "" {
+		return suite, nil
 	}
 
-	return filteredTestCases, nil
+	suite.TestCases, err = services.SelectTestCases(suite.TestCases, selection)
+	return suite, err
 }
 
 // printAgentSummary prints a summary of the agent test results
 func printAgentSummary(report *models.AgentReport) {
 	fmt.Println("📈 Agent Test Results")
 	fmt.Println(strings.Repeat("=", 50))
+	if report.StoppedAfterFailures > 0 {
+		fmt.Printf("⚠️  INCOMPLETE: the run stopped after %d failed tests and %d scheduled tests did not finish\n", report.StoppedAfterFailures, report.InterruptedTests)
+	} else if report.Incomplete {
+		fmt.Printf("⚠️  INCOMPLETE: the run was interrupted and %d scheduled tests did not finish\n", report.InterruptedTests)
+	}
+	for _, recovery := range report.DeploymentRecoveries {
+		if recovery.Recovered {
+			fmt.Printf("🚨 Endpoint stopped responding at %s; %s, back after %v, %d tests rerun\n",
+				recovery.DetectedAt.Format("15:04:05"), recovery.Action, recovery.Duration.Round(time.Second), recovery.RequeuedTests)
+		} else {
+			fmt.Printf("🚨 Endpoint stopped responding at %s and did not recover: %s\n", recovery.DetectedAt.Format("15:04:05"), recovery.Error)
+		}
+	}
 
 	// Print overall statistics
 	fmt.Printf("Total Tests: %d\n", report.TotalTests)
 	fmt.Printf("✅ Passed: %d\n", report.PassedTests)
 	fmt.Printf("❌ Failed: %d\n", report.FailedTests)
+	if report.InfrastructureFailures > 0 {
+		fmt.Printf("🔌 Infrastructure Failures: %d of the failed tests failed against the server, not on the model's answer (rerun them with -rerun-infra-failures)\n", report.InfrastructureFailures)
+	}
+	if report.NetworkChaos != nil {
+		var faults []models.NetworkFault
+		for _, result := range report.Results {
+			faults = append(faults, result.NetworkFaults...)
+		}
+		fmt.Printf("🌩️  Network Chaos: %s injected (%s)\n", formatNetworkFaults(faults), formatNetworkChaos(report.NetworkChaos))
+	}
 	fmt.Printf("⏱️  Total LLM Time: %v\n", report.TotalLLMTime)
 	fmt.Printf("⏱️  Average Time per Request: %v\n", report.AvgTimePerReq)
+	fmt.Printf("🛠️  Total Tool Time: %v\n", report.TotalToolTime)
+	fmt.Printf("⚙️  Total Harness Overhead: %v\n", report.TotalOverhead)
+	if usage := report.TokenUsage; usage != nil {
+		estimated := ""
+		if usage.Estimated {
+			estimated = " (some input tokens estimated)"
+		}
+		fmt.Printf("🔢 Tokens: %d input, %d output, %d total%s\n", usage.InputTokens, usage.OutputTokens, usage.TotalTokens, estimated)
+	}
 	fmt.Println()
 
 	// Print results by test case
@@ -193,12 +1549,26 @@ func printAgentSummary(report *models.AgentReport) {
 			status = "✅ PASSED"
 		}
 
-		fmt.Printf("Test Case: %s\n", result.TestCase.Name)
+		name := result.TestCase.Name
+		if result.Config.Label != "" {
+			name = fmt.Sprintf("%s [%s]", name, result.Config.Label)
+		}
+		if result.Run > 0 && report.RunStats != nil {
+			fmt.Printf("Test Case: %s (run %d)\n", name, result.Run)
+		} else {
+			fmt.Printf("Test Case: %s\n", name)
+		}
 		fmt.Printf("  Status: %s\n", status)
 		if result.MatchedPath != "" {
 			fmt.Printf("  Matched Path: %s\n", result.MatchedPath)
 		}
 		fmt.Printf("  Response Time: %v\n", result.ResponseTime)
+		if result.Timing != nil {
+			fmt.Printf("    LLM: %v, Tools: %v, Harness: %v\n", result.Timing.LLMTime, result.Timing.ToolTime, result.Timing.HarnessOverhead)
+		}
+		if len(result.NetworkFaults) > 0 {
+			fmt.Printf("  Network Faults: %s\n", formatNetworkFaults(result.NetworkFaults))
+		}
 
 		if result.Response != nil {
 			fmt.Printf("  Tool Calls: %d\n", len(result.Response.ToolCalls))
@@ -214,8 +1584,40 @@ func printAgentSummary(report *models.AgentReport) {
 			}
 		}
 
-		if result.ErrorMessage != "" {
-			fmt.Printf("  Error: %s\n", result.ErrorMessage)
+		if result.FailureReason != "" && !result.Success {
+			fmt.Printf("  Failure: %s (%s)\n", result.FailureReason, result.FailureDetails)
+		}
+		if result.Adjudication != nil {
+			fmt.Printf("  Adjudicated: %s (automatic result: %s)\n", result.Adjudication.Verdict, result.FailureReason)
+		}
+		if violations := formatSchemaViolations(result); violations != "" {
+			fmt.Printf("  Schema Violations: %s\n", violations)
+		}
+		if score := result.PartialScore; score != nil {
+			fmt.Printf("  Partial Score: %.2f (sequence %.2f, arguments %.2f)\n", score.Score, score.SequenceOverlap, score.ArgumentMatch)
+		}
+		if result.RecoveredToolErrors > 0 {
+			fmt.Printf("  Recovered Tool Errors: %d\n", result.RecoveredToolErrors)
+		}
+		for _, verdict := range result.Scorers {
+			fmt.Printf("  Scorer %s: %s\n", verdict.Scorer, formatScorerVerdict(verdict))
+		}
+		if efficiency := result.CallEfficiency; efficiency != nil && efficiency.Ratio < 1 {
+			fmt.Printf("  Call Efficiency: %.2f (%d tool calls, %d needed)\n", efficiency.Ratio, efficiency.ActualCalls, efficiency.MinimalCalls)
+		}
+		if judged := result.Judge; judged != nil {
+			if judged.Error != "" {
+				fmt.Printf("  Judge Error: %s\n", judged.Error)
+			} else {
+				fmt.Printf("  Judge Score: %.2f (%s)\n", judged.Score, formatCriterionScores(judged.Criteria))
+			}
+		}
+		if result.Approval != nil {
+			if result.Approval.Compliant {
+				fmt.Printf("  Denial Respected: %s\n", strings.Join(result.Approval.DeniedTools, ", "))
+			} else {
+				fmt.Printf("  Denial Ignored: retried %s\n", strings.Join(result.Approval.RetriedTools, ", "))
+			}
 		}
 
 		fmt.Println(strings.Repeat("-", 30))
@@ -240,8 +1642,9 @@ func printAgentSummary(report *models.AgentReport) {
 					}
 				}
 
-				if result.ErrorMessage != "" {
-					fmt.Printf("Error: %s\n", result.ErrorMessage)
+				if result.FailureReason != "" {
+					fmt.Printf("Failure Reason: %s\n", result.FailureReason)
+					fmt.Printf("Details: %s\n", result.FailureDetails)
 				}
 				fmt.Printf("Response Time: %v\n", result.ResponseTime)
 				fmt.Println(strings.Repeat("-", 30))
@@ -252,6 +1655,105 @@ func printAgentSummary(report *models.AgentReport) {
 	// Print overall success rate
 	successRate := float64(report.PassedTests) / float64(report.TotalTests) * 100
 	fmt.Printf("\n📊 Overall Success Rate: %.2f%%\n", successRate)
+	if report.MeanScore != nil {
+		fmt.Printf("🎯 Mean Partial Score: %.3f\n", *report.MeanScore)
+	}
+	if summary := report.Judge; summary != nil {
+		if summary.Judged > 0 {
+			fmt.Printf("⚖️  Mean Judge Score: %.3f over %d replies (judge %s)", summary.MeanScore, summary.Judged, summary.Model)
+		} else {
+			fmt.Printf("⚖️  Judge %s scored no replies", summary.Model)
+		}
+		if summary.Errors > 0 {
+			fmt.Printf(", %d not scored", summary.Errors)
+		}
+		fmt.Println()
+	}
+	if efficiency := report.CallEfficiency; efficiency != nil {
+		fmt.Printf("🔁 Call Efficiency: %.3f mean", efficiency.MeanRatio)
+		if efficiency.PassedTests > 0 {
+			fmt.Printf(", %.3f on passed tests", efficiency.PassedMeanRatio)
+		}
+		fmt.Printf(" (%d redundant tool calls in %d tests)\n", efficiency.RedundantCalls, efficiency.InefficientTests)
+	}
+	if compliance := report.SchemaCompliance; compliance != nil {
+		fmt.Printf("📐 Schema Compliance: %.2f%% (%d/%d tool calls with valid arguments)\n",
+			compliance.Rate*100, compliance.ValidCalls, compliance.CheckedCalls)
+	}
+	if compliance := report.ApprovalCompliance; compliance != nil {
+		fmt.Printf("🔐 Denial Compliance: %.2f%% (%d/%d tests with denied calls did not retry)\n",
+			compliance.ComplianceRate*100, compliance.CompliantTests, compliance.TestsWithDenials)
+	}
+	if stats := report.RunStats; stats != nil {
+		printRunStatistics(stats)
+	}
+	if len(report.SweepResults) > 0 {
+		printSweepResults(report.SweepResults)
+	}
+	if parallel := countParallelToolCallTests(report.Results); parallel > 0 {
+		fmt.Printf("🔀 Parallel Tool Calls: %d of %d tests requested several tools in one turn\n", parallel, report.TotalTests)
+	}
+	if streaming := report.Streaming; streaming != nil {
+		estimated := ""
+		if streaming.TokensEstimated {
+			estimated = " (tokens estimated from chunks)"
+		}
+		fmt.Printf("⚡ Streaming: %v to first token (%v across all %d requests), %.1f tokens/s%s\n",
+			streaming.TimeToFirstToken.Round(time.Millisecond), streaming.AvgTimeToFirstToken.Round(time.Millisecond),
+			streaming.Requests, streaming.TokensPerSecond, estimated)
+	}
+	if len(report.SystemPromptVariants) > 0 {
+		fmt.Printf("📝 System Prompt Variants (%d):\n", len(report.SystemPromptVariants))
+		for _, variant := range report.SystemPromptVariants {
+			fmt.Printf("   %s: %.2f%% selection, %.2f%% passed (%d tests of %d cases)\n", variant.Variant,
+				variant.SelectionAccuracy*100, variant.SuccessRate*100, variant.TotalTests, variant.TestCases)
+		}
+	}
+}
+
+// printSweepResults prints the pass rate of each sampling combination, marking the best
+func printSweepResults(sweep []models.SamplingConfigResult) {
+	best := 0
+	for i, result := range sweep {
+		if result.PassRate > sweep[best].PassRate {
+			best = i
+		}
+	}
+
+	fmt.Printf("🎛️  Sampling Sweep (%d configs):\n", len(sweep))
+	for i, result := range sweep {
+		marker := ""
+		if i == best {
+			marker = " <- best"
+		}
+		fmt.Printf("   %s: %.2f%% (%d/%d), avg %v%s\n",
+			result.Config.Label, result.PassRate*100, result.PassedTests, result.TotalTests, result.AverageTime, marker)
+	}
+}
+
+// printRunStatistics prints the per-run pass rates and the test cases whose outcome
+// changed between runs
+func printRunStatistics(stats *models.RunStatistics) {
+	fmt.Printf("🔁 Pass Rate over %d runs: %.2f%% ± %.2f (variance %.4f)\n",
+		stats.Runs, stats.PassRateMean*100, stats.PassRateStdDev*100, stats.PassRateVariance)
+	for i, rate := range stats.RunPassRates {
+		fmt.Printf("   Run %d: %.2f%%\n", i+1, rate*100)
+	}
+	if stats.FlakyTests == 0 {
+		fmt.Println("   No flaky tests")
+		return
+	}
+	fmt.Printf("⚠️  Flaky Tests: %d\n", stats.FlakyTests)
+	for _, stability := range stats.TestCases {
+		if !stability.Flaky {
+			continue
+		}
+		if stability.Config != "" {
+			fmt.Printf("   %s [%s]: passed %d/%d runs\n", stability.TestCase, stability.Config, stability.Passed, stability.Runs)
+		} else {
+			fmt.Printf("   %s: passed %d/%d runs\n", stability.TestCase, stability.Passed, stability.Runs)
+		}
+	}
 }
 
 // sanitizeModelName sanitizes the model name for use in filenames
@@ -267,3 +1769,55 @@ func sanitizeModelName(modelName string) string {
 
 	return sanitized
 }
+
+// countParallelToolCallTests counts the tests in which the model requested more than
+// one tool call in a single turn
+func countParallelToolCallTests(results []models.AgentTestResult) int {
+	count := 0
+	for _, result := range results {
+		if result.Response != nil && result.Response.ParallelToolCallTurns > 0 {
+			count++
+		}
+	}
+	return count
+}
+
+// formatSchemaViolations lists a result's schema violations as "tool: path: message"
+func formatSchemaViolations(result models.AgentTestResult) string {
+	if result.Response == nil {
+		return ""
+	}
+	var violations []string
+	for _, toolCall := range result.Response.ToolCalls {
+		for _, violation := range toolCall.SchemaViolations {
+			if violation.Path == "" {
+				violations = append(violations, fmt.Sprintf("%s: %s", toolCall.ToolName, violation.Message))
+				continue
+			}
+			violations = append(violations, fmt.Sprintf("%s: %s: %s", toolCall.ToolName, violation.Path, violation.Message))
+		}
+	}
+	return strings.Join(violations, "; ")
+}
+
+// formatCriterionScores lists a judge's criterion scores as "name n/5"
+func formatCriterionScores(criteria []models.CriterionScore) string {
+	scores := make([]string, 0, len(criteria))
+	for _, criterion := range criteria {
+		scores = append(scores, fmt.Sprintf("%s %d/%d", criterion.Name, criterion.Score, models.JudgeScaleMax))
+	}
+	return strings.Join(scores, ", ")
+}
+
+// formatScorerVerdict describes one scorer's verdict as "passed (1.00): details"
+func formatScorerVerdict(verdict models.ScorerVerdict) string {
+	outcome := "failed"
+	if verdict.Passed {
+		outcome = "passed"
+	}
+	text := fmt.Sprintf("%s (%.2f)", outcome, verdict.Score)
+	if verdict.Details != "" {
+		text += ": " + verdict.Details
+	}
+	return text
+}
@@ -0,0 +1,17 @@
+package models
+
+// ApprovalOutcome records how the model reacted when the simulated user denied
+// permission to run a tool
+type ApprovalOutcome struct {
+	DeniedTools  []string `json:"denied_tools"`
+	RetriedTools []string `json:"retried_tools,omitempty"` // Denied tools the model called again anyway
+	Compliant    bool     `json:"compliant"`
+}
+
+// ApprovalCompliance summarizes how often the model respected permission denials
+type ApprovalCompliance struct {
+	DeniedTools      []string `json:"denied_tools"` // Every tool denied at least once
+	TestsWithDenials int      `json:"tests_with_denials"`
+	CompliantTests   int      `json:"compliant_tests"`
+	ComplianceRate   float64  `json:"compliance_rate"`
+}
@@ -0,0 +1,30 @@
+package models
+
+// BaselineTolerance defines how far a run may fall behind its pinned baseline
+// before it counts as a regression
+type BaselineTolerance struct {
+	SuccessRateDrop float64 `json:"success_rate_drop"` // Absolute drop in pass rate, e.g. 0.05 = 5 points
+	LatencyIncrease float64 `json:"latency_increase"`  // Relative increase in LLM time per request, e.g. 0.5 = +50%
+}
+
+// BaselineMetric compares one metric between the baseline and the current run
+type BaselineMetric struct {
+	Name      string  `json:"name"`
+	Baseline  float64 `json:"baseline"`
+	Current   float64 `json:"current"`
+	Change    float64 `json:"change"`    // Absolute for rates, relative for latency
+	Tolerance float64 `json:"tolerance"` // Allowed change in the regressing direction
+	Regressed bool    `json:"regressed"`
+}
+
+// BaselineComparison is the outcome of comparing a run with the model's pinned baseline
+type BaselineComparison struct {
+	BaselineFile  string            `json:"baseline_file"`
+	BaselineRunID string            `json:"baseline_run_id,omitempty"`
+	CommonTests   int               `json:"common_tests"` // Test cases present in both runs; metrics cover only these
+	Tolerance     BaselineTolerance `json:"tolerance"`
+	Metrics       []BaselineMetric  `json:"metrics"`
+	NewlyFailing  []string          `json:"newly_failing,omitempty"`
+	NewlyPassing  []string          `json:"newly_passing,omitempty"`
+	Regressed     bool              `json:"regressed"`
+}
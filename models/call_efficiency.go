@@ -0,0 +1,28 @@
+package models
+
+// CallEfficiency compares the tool calls a test made with the fewest any of its expected
+// paths needs, so a model that gets there by repeating calls (e.g. view_cart three times
+// before checkout) is told apart from one that goes straight there
+type CallEfficiency struct {
+	MinimalCalls int     `json:"minimal_calls"` // Calls in the shortest expected path
+	ActualCalls  int     `json:"actual_calls"`
+	Ratio        float64 `json:"ratio"` // MinimalCalls over ActualCalls, capped at 1; 1 when no tool was called
+}
+
+// RedundantCalls returns the calls made beyond the shortest expected path
+func (e CallEfficiency) RedundantCalls() int {
+	if e.ActualCalls > e.MinimalCalls {
+		return e.ActualCalls - e.MinimalCalls
+	}
+	return 0
+}
+
+// CallEfficiencySummary averages the call efficiency of a run's tests
+type CallEfficiencySummary struct {
+	Tests            int     `json:"tests"` // Tests that got a response to measure
+	MeanRatio        float64 `json:"mean_ratio"`
+	PassedTests      int     `json:"passed_tests"`
+	PassedMeanRatio  float64 `json:"passed_mean_ratio,omitempty"` // Mean over passed tests only, exposing passes bought with extra calls
+	RedundantCalls   int     `json:"redundant_calls"`             // Calls beyond the shortest expected path, summed over the tests
+	InefficientTests int     `json:"inefficient_tests"`           // Tests with a ratio below 1
+}
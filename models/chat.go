@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 )
 
@@ -11,6 +12,7 @@ const (
 	RoleUser      ChatRole = "user"
 	RoleAssistant ChatRole = "assistant"
 	RoleSystem    ChatRole = "system"
+	RoleTool      ChatRole = "tool"
 )
 
 // ChatMessage represents a single message in a chat conversation
@@ -26,6 +28,16 @@ type ChatSession struct {
 	Messages  []ChatMessage `json:"messages"`
 	CreatedAt time.Time     `json:"created_at"`
 	UpdatedAt time.Time     `json:"updated_at"`
+
+	// Every message exchanged with the model, appended by the agent loop as it runs so
+	// it survives a loop that fails partway
+	Transcript []TranscriptMessage `json:"transcript,omitempty"`
+
+	// Bodies of the requests sent to the model, in order; recorded only for repro bundles
+	Requests []json.RawMessage `json:"-"`
+
+	// How the API gateway routed the requests so far; nil unless the run targets a gateway
+	Gateway *GatewayRoute `json:"gateway,omitempty"`
 }
 
 // ChatResponse represents the response from processing a chat message
@@ -37,16 +49,84 @@ type ChatResponse struct {
 	ToolCalls    []ToolCallResult `json:"tool_calls,omitempty"`
 	LLMRequests  int              `json:"llm_requests"`
 	LLMTotalTime time.Duration    `json:"llm_total_time"`
+	ToolTime     time.Duration    `json:"tool_time"` // Time spent executing tools locally
+	Iterations   []IterationStats `json:"iterations,omitempty"`
+
+	// Every assistant turn of the agent loop, including content stated between tool calls;
+	// Message holds only the final one
+	AssistantMessages []AssistantMessage `json:"assistant_messages,omitempty"`
+
+	MaxIterationsReached bool `json:"max_iterations_reached,omitempty"`
+
+	// The loop stopped because the model repeated the previous turn's tool calls with
+	// identical arguments
+	LoopDetected bool `json:"loop_detected,omitempty"`
+
+	// Set when the loop stopped because its next request would not fit the context window
+	ContextExceeded *ContextOverflow `json:"context_exceeded,omitempty"`
+
+	// Present when the completions were streamed (-stream)
+	Streaming *StreamingMetrics `json:"streaming,omitempty"`
+
+	// Tokens consumed by all iterations of the agent loop
+	Usage *TokenUsage `json:"usage,omitempty"`
+
+	// Assistant turns that requested more than one tool call at once
+	ParallelToolCallTurns int `json:"parallel_tool_call_turns,omitempty"`
+}
+
+// IterationStats captures the details of a single agent loop iteration
+type IterationStats struct {
+	Iteration        int           `json:"iteration"`
+	MessageCount     int           `json:"message_count"`
+	PromptTokens     int64         `json:"prompt_tokens"`
+	CompletionTokens int64         `json:"completion_tokens"`
+	TotalTokens      int64         `json:"total_tokens,omitempty"`
+	TokensEstimated  bool          `json:"tokens_estimated,omitempty"` // True when the backend did not report usage
+	StartedAt        time.Time     `json:"started_at,omitempty"`       // When the request was sent; orders requests within a run
+	Duration         time.Duration `json:"duration"`                   // LLM latency of the request
+	FinishReason     string        `json:"finish_reason,omitempty"`
+	ToolCalls        []string      `json:"tool_calls,omitempty"` // Names of the tools requested in this iteration
+
+	// Time spent executing the iteration's tool calls locally; ChatResponse.ToolTime sums it
+	ToolTime time.Duration `json:"tool_time,omitempty"`
+
+	// Until the first content or tool call delta arrived; only set for streamed completions
+	TimeToFirstToken time.Duration `json:"time_to_first_token,omitempty"`
+}
+
+// AssistantMessage is a single assistant turn of the agent loop
+type AssistantMessage struct {
+	Iteration int      `json:"iteration"`
+	Content   string   `json:"content,omitempty"`
+	Refusal   string   `json:"refusal,omitempty"`
+	ToolCalls []string `json:"tool_calls,omitempty"` // Names of the tools requested alongside the content
+	Final     bool     `json:"final,omitempty"`      // True for the turn that ended the loop without tool calls
 }
 
 // ToolCallResult represents the result of executing a tool call
 type ToolCallResult struct {
-	CallID    string      `json:"call_id"`
-	ToolName  string      `json:"tool_name"`
-	Success   bool        `json:"success"`
-	Result    interface{} `json:"result,omitempty"`
-	Error     string      `json:"error,omitempty"`
-	Arguments string      `json:"arguments"`
+	CallID    string        `json:"call_id"`
+	ToolName  string        `json:"tool_name"`
+	Success   bool          `json:"success"`
+	Result    interface{}   `json:"result,omitempty"`
+	Error     string        `json:"error,omitempty"`
+	ErrorCode ToolErrorCode `json:"error_code,omitempty"`
+	Arguments string        `json:"arguments"`
+	Denied    bool          `json:"denied,omitempty"` // Not executed because the simulated user denied approval
+
+	// Enum-constrained arguments checked against the tool schema and any out-of-range values
+	EnumChecks     int      `json:"enum_checks,omitempty"`
+	EnumViolations []string `json:"enum_violations,omitempty"`
+
+	// Arguments checked against the tool's JSON schema (types, undeclared and missing
+	// properties) and how they break it; unknown tools are not checked
+	SchemaChecked    bool              `json:"schema_checked,omitempty"`
+	SchemaViolations []SchemaViolation `json:"schema_violations,omitempty"`
+
+	// Agent loop iteration whose assistant turn requested the call; calls sharing one were
+	// requested in parallel
+	Iteration int `json:"iteration,omitempty"`
 }
 
 // CartSummary represents the current state of a shopping cart
@@ -70,6 +150,7 @@ type CartItem struct {
 type ProductFilter struct {
 	Query    string `json:"query,omitempty"`
 	Category string `json:"category,omitempty"`
+	SortBy   string `json:"sort_by,omitempty"`
 	Limit    int    `json:"limit,omitempty"`
 }
 
@@ -79,6 +160,7 @@ type Product struct {
 	Category    string  `json:"category"`
 	Price       float64 `json:"price"`
 	Description string  `json:"description,omitempty"`
+	Rating      float64 `json:"rating"`
 	InStock     bool    `json:"in_stock"`
 }
 
@@ -91,29 +173,230 @@ type CheckoutResult struct {
 	Timestamp time.Time `json:"timestamp"`
 }
 
+// OrderItem represents a single line item in a direct order
+type OrderItem struct {
+	ProductName string `json:"product_name"`
+	Quantity    int    `json:"quantity"`
+}
+
+// ShippingAddress represents the delivery address for an order
+type ShippingAddress struct {
+	Street     string `json:"street"`
+	City       string `json:"city"`
+	PostalCode string `json:"postal_code,omitempty"`
+	Country    string `json:"country,omitempty"`
+}
+
+// OrderResult represents the result of placing a direct order
+type OrderResult struct {
+	OrderID         string           `json:"order_id"`
+	Items           []CartItem       `json:"items"`
+	ShippingAddress *ShippingAddress `json:"shipping_address,omitempty"`
+	Total           float64          `json:"total"`
+	Timestamp       time.Time        `json:"timestamp"`
+}
+
+// DeliverySchedule represents a scheduled delivery slot
+type DeliverySchedule struct {
+	SessionID    string `json:"session_id"`
+	DeliveryDate string `json:"delivery_date"`
+	TimeWindow   string `json:"time_window,omitempty"`
+	Weekday      string `json:"weekday"`
+}
+
 // AgentTestResult represents the result of testing the agent loop
 type AgentTestResult struct {
 	TestCase     TestCase      `json:"test_case"`
+	Run          int           `json:"run,omitempty"` // 1-based repetition of the test case within the suite (-runs)
 	ModelName    string        `json:"model_name"`
 	Config       TestConfig    `json:"config"`
 	Response     *ChatResponse `json:"response"`
 	Success      bool          `json:"success"`
 	MatchedPath  string        `json:"matched_path,omitempty"`
-	ErrorMessage string        `json:"error_message,omitempty"`
 	Timestamp    time.Time     `json:"timestamp"`
 	ResponseTime time.Duration `json:"response_time"`
+
+	FailureReason  FailureReason `json:"failure_reason,omitempty"`
+	FailureDetails string        `json:"failure_details,omitempty"`
+
+	// The test failed because a request to the model server errored or timed out, not
+	// because of the model's answer; such tests can be rerun with -rerun-infra-failures
+	InfrastructureFailure bool `json:"infrastructure_failure,omitempty"`
+
+	// Breakdown of ResponseTime; absent when the agent loop failed before completing
+	Timing *ResponseTiming `json:"timing,omitempty"`
+
+	// How the model reacted to permission denials; absent when no tool call was denied
+	Approval *ApprovalOutcome `json:"approval,omitempty"`
+
+	// Human decision that set Success for a borderline result; FailureReason keeps
+	// the automatic outcome
+	Adjudication *Adjudication `json:"adjudication,omitempty"`
+
+	// Times the test was rerun after the deployment stopped responding and recovered
+	// (see AgentReport.DeploymentRecoveries)
+	Requeued int `json:"requeued,omitempty"`
+
+	// Times the test was rerun by -rerun-infra-failures after an infrastructure failure
+	// (see AgentReport.InfrastructureReruns)
+	Reruns int `json:"reruns,omitempty"`
+
+	// Outcomes of the test case's setup and teardown hooks for this run
+	Hooks []HookResult `json:"hooks,omitempty"`
+
+	// The full conversation with the model: system prompt, user prompt, and each
+	// iteration's assistant message and tool results, up to a failed request if any
+	Transcript []TranscriptMessage `json:"transcript,omitempty"`
+
+	// Network faults injected into the test's requests by a chaos config, in order
+	NetworkFaults []NetworkFault `json:"network_faults,omitempty"`
+
+	// How the API gateway routed the test's requests (-gateway), up to a failed request if any
+	Gateway *GatewayRoute `json:"gateway,omitempty"`
+
+	// Bodies of the requests sent to the model, for the failed test's repro bundle; never saved
+	Requests []json.RawMessage `json:"-"`
+
+	// Partial credit against the closest expected path (-scoring partial); nil when the
+	// test got no response to score
+	PartialScore *PartialScore `json:"partial_score,omitempty"`
+
+	// A judge model's scores of the final reply against the test case's rubric
+	// (-judge-model); nil when the test has no rubric or got no reply
+	Judge *JudgeScore `json:"judge,omitempty"`
+
+	// Tool calls made against the fewest the test needed; nil when the test got no response
+	CallEfficiency *CallEfficiency `json:"call_efficiency,omitempty"`
+
+	// Failed tool calls the model recovered from by calling the tool again successfully
+	// (-evaluation workflow)
+	RecoveredToolErrors int `json:"recovered_tool_errors,omitempty"`
+
+	// Verdict of each scorer in the test config's chain; nil when the config lists no scorers
+	Scorers []ScorerVerdict `json:"scorers,omitempty"`
+}
+
+// ResponseTiming splits a test's wall time into model latency and time spent in
+// the harness, so latency comparisons are not polluted by harness behavior
+type ResponseTiming struct {
+	LLMTime         time.Duration `json:"llm_time"`         // Wall time waiting on the model
+	ToolTime        time.Duration `json:"tool_time"`        // Local tool execution
+	HarnessOverhead time.Duration `json:"harness_overhead"` // Setup, logging, prompt building and evaluation
+}
+
+// FailureReason classifies why an agent test failed
+type FailureReason string
+
+const (
+	FailureAPIError        FailureReason = "api_error" // The server was unreachable or overloaded
+	FailureTimeout         FailureReason = "timeout"
+	FailureSetupError      FailureReason = "setup_error"
+	FailureWrongTool       FailureReason = "wrong_tool"
+	FailureMissingTool     FailureReason = "missing_tool"
+	FailureExtraTool       FailureReason = "extra_tool"
+	FailureBadArguments    FailureReason = "bad_arguments"
+	FailureMaxIterations   FailureReason = "max_iterations"
+	FailureSchemaViolation FailureReason = "schema_violation"
+	FailureForbiddenTool   FailureReason = "forbidden_tool"
+	FailureContextExceeded FailureReason = "context_exceeded"
+	FailureLoopDetected    FailureReason = "loop_detected"
+
+	// The expected tools were called but the final reply failed an expected_response check
+	FailureResponseMismatch FailureReason = "response_mismatch"
+
+	// Workflow evaluation mode: the expected tools were called but one failed and was
+	// never retried successfully, or a failed call was repeated unchanged
+	FailureToolFailed        FailureReason = "tool_failed"
+	FailureToolErrorRepeated FailureReason = "tool_error_repeated"
+
+	// A custom scorer in the test config's chain failed the response
+	FailureScorerRejected FailureReason = "scorer_rejected"
+
+	// The server answered the model's request with an error that is not a sign of an
+	// unreachable or overloaded server, e.g. a 400 for a malformed tool call or a 500
+	// for tools the backend does not support
+	FailureModelError FailureReason = "model_error"
+)
+
+// IsInfrastructure reports whether the reason is a failure of the model server (an
+// API error or timeout) rather than of the model's answer
+func (r FailureReason) IsInfrastructure() bool {
+	return r == FailureAPIError || r == FailureTimeout
 }
 
+// ResultSchemaVersion is the version of the result file format written by the runner.
+// Files without a schema_version predate versioning and are treated as version 1.
+const ResultSchemaVersion = 2
+
 // AgentReport contains the results of an agent test suite
 type AgentReport struct {
-	Timestamp        time.Time         `json:"timestamp"`
-	TestSuite        string            `json:"test_suite"`
-	Results          []AgentTestResult `json:"results"`
-	TotalTests       int               `json:"total_tests"`
-	PassedTests      int               `json:"passed_tests"`
-	FailedTests      int               `json:"failed_tests"`
-	AverageTime      time.Duration     `json:"average_time"`
-	TotalLLMRequests int               `json:"total_llm_requests"`
-	TotalLLMTime     time.Duration     `json:"total_llm_time"`
-	AvgTimePerReq    time.Duration     `json:"avg_time_per_request"`
+	SchemaVersion    int          `json:"schema_version"`
+	RunID            string       `json:"run_id,omitempty"`            // ULID shared by the run's result, log and audit files
+	ResumedFrom      string       `json:"resumed_from,omitempty"`      // Checkpoint or results file of the interrupted run this one resumed
+	ResumedTests     int          `json:"resumed_tests,omitempty"`     // Results carried over from that run instead of rerun
+	Incomplete       bool         `json:"incomplete,omitempty"`        // The run was interrupted; Results holds only the tests that finished
+	InterruptedTests int          `json:"interrupted_tests,omitempty"` // Scheduled tests that did not finish
+	Tags             RunTags      `json:"tags,omitempty"`              // Metadata attached with -tag key=value
+	Cluster          *ClusterInfo `json:"cluster,omitempty"`           // Kamiwaza cluster the run targeted with -kamiwaza-cluster
+
+	Warmup               *Warmup              `json:"warmup,omitempty"`                // Throwaway completions sent before the suite started
+	DeploymentRecoveries []DeploymentRecovery `json:"deployment_recoveries,omitempty"` // Times the -watchdog found the endpoint unresponsive
+	Timestamp            time.Time            `json:"timestamp"`
+	TestSuite            string               `json:"test_suite"`
+	Results              []AgentTestResult    `json:"results"`
+	TotalTests           int                  `json:"total_tests"`
+	PassedTests          int                  `json:"passed_tests"`
+	FailedTests          int                  `json:"failed_tests"`
+	AverageTime          time.Duration        `json:"average_time"`
+	TotalLLMRequests     int                  `json:"total_llm_requests"`
+	TotalLLMTime         time.Duration        `json:"total_llm_time"`
+	AvgTimePerReq        time.Duration        `json:"avg_time_per_request"`
+	TotalToolTime        time.Duration        `json:"total_tool_time"`
+	TotalOverhead        time.Duration        `json:"total_harness_overhead"`
+	MatchPolicy          StringMatchPolicy    `json:"match_policy"`                // Global string comparison policy used for evaluation
+	ToolAliases          ToolAliases          `json:"tool_aliases,omitempty"`      // Equivalent tool names matched as one tool
+	ReferenceTime        time.Time            `json:"reference_time,omitempty"`    // Clock used for relative date arguments
+	ToolSpecVersion      string               `json:"tool_spec_version,omitempty"` // Tool description wording; empty for the canonical definitions
+	ToolLocale           string               `json:"tool_locale,omitempty"`       // Language of the tool descriptions; empty for DefaultLocale
+
+	ToolErrorVerbosity ToolErrorVerbosity `json:"tool_error_verbosity,omitempty"` // How much of a failed tool call the model was shown
+	ServiceState       ServiceState       `json:"service_state,omitempty"`        // Whether tests had their own product and cart services
+	ContextWindow      int                `json:"context_window,omitempty"`       // Context window requests were checked against; 0 when unchecked
+
+	NetworkChaos *NetworkChaos `json:"network_chaos,omitempty"` // Network faults injected into requests; nil when none
+	Gateway      bool          `json:"gateway,omitempty"`       // The base URL was an API gateway and results record its routing
+
+	Scoring    ScoringMode    `json:"scoring,omitempty"`    // Partial when results carry a partial-credit score
+	Evaluation EvaluationMode `json:"evaluation,omitempty"` // Workflow when tool calls also had to succeed
+	Scorers    []string       `json:"scorers,omitempty"`    // Names of the test config's scorer chain; empty for exact-path alone
+	MeanScore  *float64       `json:"mean_score,omitempty"` // Mean partial-credit score of the scored results
+
+	Judge *JudgeSummary `json:"judge,omitempty"` // Present when a judge model scored replies
+
+	CallEfficiency *CallEfficiencySummary `json:"call_efficiency,omitempty"` // Tool calls made against the fewest needed
+
+	StoppedAfterFailures int `json:"stopped_after_failures,omitempty"` // -max-failures limit that stopped the run early; Incomplete is set too
+
+	SuiteHooks []HookResult `json:"suite_hooks,omitempty"` // Outcomes of the test config's setup and teardown hooks
+
+	InfrastructureFailures int                   `json:"infrastructure_failures,omitempty"` // Failed tests (included in FailedTests) that failed against the server rather than the model
+	InfrastructureReruns   []InfrastructureRerun `json:"infrastructure_reruns,omitempty"`   // -rerun-infra-failures passes merged into Results
+
+	MergedFrom []MergeSource `json:"merged_from,omitempty"` // Result files merge-results combined into this report
+	MergeRule  MergeRule     `json:"merge_rule,omitempty"`  // How it chose between results of the same test
+
+	BaselineComparison *BaselineComparison    `json:"baseline_comparison,omitempty"` // Comparison with the model's pinned baseline
+	ApprovalCompliance *ApprovalCompliance    `json:"approval_compliance,omitempty"` // Present when tools required approval
+	SchemaCompliance   *SchemaCompliance      `json:"schema_compliance,omitempty"`   // Present when any tool call was checked against its schema
+	ManualOverrides    []ManualOverride       `json:"manual_overrides,omitempty"`    // Results whose verdict came from a human decision
+	RunStats           *RunStatistics         `json:"run_stats,omitempty"`           // Present when each test case ran more than once
+	SweepResults       []SamplingConfigResult `json:"sweep_results,omitempty"`       // Present when the suite ran a sampling sweep
+
+	SystemPromptVariants []SystemPromptVariantResult `json:"system_prompt_variants,omitempty"` // Present when test cases used more than one system prompt
+
+	Streaming *StreamingMetrics `json:"streaming,omitempty"` // Present when completions were streamed; time to first token is averaged over the tests
+
+	TokenUsage *TokenUsage `json:"token_usage,omitempty"` // Summed over the tests that got a response
+
+	Pruning *PruningPolicy `json:"pruning,omitempty"` // What was left out of the saved results; the request log has it all
 }
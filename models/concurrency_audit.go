@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+)
+
+// IsolationViolation describes a broken session isolation invariant observed
+// while running the same test concurrently
+type IsolationViolation struct {
+	Invariant string `json:"invariant"`
+	SessionID string `json:"session_id,omitempty"`
+	Details   string `json:"details"`
+}
+
+// ConcurrencyAuditRun captures one of the concurrent executions of a test case
+type ConcurrencyAuditRun struct {
+	SessionID     string        `json:"session_id"`
+	Success       bool          `json:"success"`
+	FailureReason FailureReason `json:"failure_reason,omitempty"`
+	ToolCalls     int           `json:"tool_calls"`
+	FinalCart     *CartSummary  `json:"final_cart,omitempty"`
+	OrderIDs      []string      `json:"order_ids,omitempty"`
+}
+
+// ConcurrencyAudit contains the outcome of auditing a single test case
+type ConcurrencyAudit struct {
+	TestCase    string                `json:"test_case"`
+	Concurrency int                   `json:"concurrency"`
+	Runs        []ConcurrencyAuditRun `json:"runs"`
+	Violations  []IsolationViolation  `json:"violations,omitempty"`
+}
+
+// ConcurrencyAuditReport contains the results of a parallelism-safety audit
+type ConcurrencyAuditReport struct {
+	RunID           string             `json:"run_id,omitempty"`
+	Tags            RunTags            `json:"tags,omitempty"`
+	Timestamp       time.Time          `json:"timestamp"`
+	ModelName       string             `json:"model_name"`
+	Audits          []ConcurrencyAudit `json:"audits"`
+	TotalViolations int                `json:"total_violations"`
+
+	ServiceState ServiceState `json:"service_state,omitempty"` // Whether the runs had their own product and cart services
+}
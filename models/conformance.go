@@ -0,0 +1,34 @@
+package models
+
+// ConformanceMetrics are the deterministic outcomes of a run of the conformance suite:
+// everything in a report except timings
+type ConformanceMetrics struct {
+	TotalTests             int                   `json:"total_tests"`
+	PassedTests            int                   `json:"passed_tests"`
+	FailedTests            int                   `json:"failed_tests"`
+	InfrastructureFailures int                   `json:"infrastructure_failures"`
+	LLMRequests            int                   `json:"llm_requests"`
+	ToolCalls              int                   `json:"tool_calls"`
+	TokenUsage             TokenUsage            `json:"token_usage"`
+	FailureReasons         map[FailureReason]int `json:"failure_reasons"`
+	Tests                  []ConformanceTest     `json:"tests"` // By test case name
+}
+
+// ConformanceTest is how one conformance test case was scored
+type ConformanceTest struct {
+	Name          string        `json:"name"`
+	Success       bool          `json:"success"`
+	MatchedPath   string        `json:"matched_path,omitempty"`
+	FailureReason FailureReason `json:"failure_reason,omitempty"`
+	LLMRequests   int           `json:"llm_requests"`
+	ToolCalls     []string      `json:"tool_calls,omitempty"` // Names of the tools called, in order
+}
+
+// ConformanceReport compares a run of the conformance suite with the golden metrics
+type ConformanceReport struct {
+	Passed      bool               `json:"passed"` // The run reproduced the golden metrics exactly
+	MatchPolicy StringMatchPolicy  `json:"match_policy"`
+	Expected    ConformanceMetrics `json:"expected"`
+	Actual      ConformanceMetrics `json:"actual"`
+	Mismatches  []string           `json:"mismatches,omitempty"` // Each metric that differs, e.g. "passed_tests: expected 9, got 8"
+}
@@ -0,0 +1,10 @@
+package models
+
+// ContextOverflow records the request the agent loop did not send because it would not
+// have fit the model's context window
+type ContextOverflow struct {
+	Iteration      int   `json:"iteration"`       // Iteration whose request was not sent
+	PromptTokens   int64 `json:"prompt_tokens"`   // Estimated prompt of that request, tool definitions included
+	ReservedTokens int64 `json:"reserved_tokens"` // Completion budget (max_tokens) the request would have reserved
+	ContextWindow  int   `json:"context_window"`
+}
@@ -0,0 +1,17 @@
+package models
+
+// CredentialsConfig maps models to the API keys they are tested with, so one batch can
+// span several providers
+type CredentialsConfig struct {
+	Credentials []Credential `json:"credentials"`
+}
+
+// Credential is where the API key for the models matching a pattern is read from. The
+// first credential whose pattern matches a model applies.
+type Credential struct {
+	Model      string `json:"model"`                  // Model name or glob pattern, e.g. gpt-4o, claude-* or openai/* for gateway-prefixed models
+	APIKeyEnv  string `json:"api_key_env,omitempty"`  // Environment variable holding the API key
+	APIKeyFile string `json:"api_key_file,omitempty"` // File holding the API key, used instead of api_key_env
+
+	APIKeyCommand string `json:"api_key_command,omitempty"` // Credential helper printing the API key, e.g. an OS keychain lookup
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// Deployment recovery actions
+const (
+	RecoveryWaited     = "waited"     // The endpoint came back on its own
+	RecoveryRedeployed = "redeployed" // The model was undeployed and deployed again
+)
+
+// DeploymentRecovery records one time the endpoint stopped responding mid-run and
+// what the watchdog did about it
+type DeploymentRecovery struct {
+	DetectedAt    time.Time     `json:"detected_at"`
+	Endpoint      string        `json:"endpoint"`
+	Health        string        `json:"health"`                 // Why the health probe failed
+	Action        string        `json:"action"`                 // waited or redeployed
+	NewEndpoint   string        `json:"new_endpoint,omitempty"` // Set when a redeploy moved the endpoint
+	Recovered     bool          `json:"recovered"`
+	Error         string        `json:"error,omitempty"` // Why recovery failed
+	Duration      time.Duration `json:"duration"`        // From detection until the endpoint answered again or recovery gave up
+	RequeuedTests int           `json:"requeued_tests"`  // Tests rerun after this recovery
+}
@@ -0,0 +1,22 @@
+package models
+
+// EndpointConfig lists the OpenAI-compatible endpoints one invocation runs the suite against
+type EndpointConfig struct {
+	Endpoints []Endpoint `json:"endpoints"`
+}
+
+// Endpoint is a server and the model tested on it. Every endpoint-model pair gets its
+// own results file, tagged endpoint=<name>.
+type Endpoint struct {
+	Name      string  `json:"name,omitempty"` // Defaults to the base URL's host and port
+	BaseURL   string  `json:"base_url"`
+	Model     string  `json:"model,omitempty"`       // Unset runs each -model (or the default) on this endpoint
+	APIKey    string  `json:"api_key,omitempty"`     // Defaults to the matching -credentials entry, then -api-key
+	APIKeyEnv string  `json:"api_key_env,omitempty"` // Environment variable holding the API key, used instead of api_key
+	Tags      RunTags `json:"tags,omitempty"`        // Added to the run's tags, e.g. engine=vllm
+
+	ContextWindow int `json:"context_window,omitempty"` // Model's context window in tokens, overriding -context-window
+
+	Proxy   string            `json:"proxy,omitempty"`   // Proxy URL (http, https, socks5) or "direct", overriding -proxy
+	Headers map[string]string `json:"headers,omitempty"` // Sent with every request, e.g. for LiteLLM or Portkey; added to -header
+}
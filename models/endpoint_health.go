@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// EndpointHealth is the result of probing a model endpoint before a batch starts
+type EndpointHealth struct {
+	Model         string        `json:"model"`
+	Endpoint      string        `json:"endpoint"`
+	Reachable     bool          `json:"reachable"`
+	AuthOK        bool          `json:"auth_ok"`
+	ModelLoaded   bool          `json:"model_loaded"`             // Listed by /models or answered the sample request
+	SampleLatency time.Duration `json:"sample_latency,omitempty"` // Round trip of a one-token chat completion
+	Error         string        `json:"error,omitempty"`          // Why the first failing check failed
+	CheckedAt     time.Time     `json:"checked_at"`
+}
+
+// Healthy reports whether every check passed
+func (h EndpointHealth) Healthy() bool {
+	return h.Reachable && h.AuthOK && h.ModelLoaded && h.Error == ""
+}
@@ -0,0 +1,11 @@
+package models
+
+// GatewayRoute is how an API gateway such as LiteLLM or Portkey routed a test's
+// requests, for runs whose provider-prefixed model names (openai/gpt-4o,
+// bedrock/claude-3-5) go through one gateway base URL
+type GatewayRoute struct {
+	Provider      string            `json:"provider,omitempty"`       // Prefix of the requested model name, e.g. bedrock
+	ProviderModel string            `json:"provider_model,omitempty"` // Requested model name without the prefix
+	ServedModels  []string          `json:"served_models,omitempty"`  // Distinct model names the responses reported, in order
+	Metadata      map[string]string `json:"metadata,omitempty"`       // Gateway response headers (x-litellm-*, x-portkey-*, llm_provider-*); later responses overwrite earlier values
+}
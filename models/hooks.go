@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+// HookStage is the point in a batch at which a hook runs
+type HookStage string
+
+const (
+	HookPreModel  HookStage = "pre_model"  // Before the first run of a model in a batch
+	HookPreRun    HookStage = "pre_run"    // Before each run, ahead of deployment discovery
+	HookPostRun   HookStage = "post_run"   // After each run, once results are saved
+	HookPostModel HookStage = "post_model" // After the last run of a model in a batch
+
+	HookSuiteSetup    HookStage = "suite_setup"    // Test config setup, before the suite's first test
+	HookSuiteTeardown HookStage = "suite_teardown" // Test config teardown, after the suite's last test
+	HookTestSetup     HookStage = "test_setup"     // A test case's setup, before each of its runs
+	HookTestTeardown  HookStage = "test_teardown"  // A test case's teardown, after each of its runs
+)
+
+// HookConfig lists the hooks to run at each stage, in order
+type HookConfig struct {
+	PreModel  []Hook `json:"pre_model,omitempty"`
+	PreRun    []Hook `json:"pre_run,omitempty"`
+	PostRun   []Hook `json:"post_run,omitempty"`
+	PostModel []Hook `json:"post_model,omitempty"`
+}
+
+// Hook is a shell command or HTTP request run around a run, e.g. to restart a model
+// server, clear its KV cache or snapshot a disk. Exactly one of Command and URL is set.
+type Hook struct {
+	Name            string            `json:"name"`
+	Command         string            `json:"command,omitempty"` // Run through sh -c
+	URL             string            `json:"url,omitempty"`
+	Method          string            `json:"method,omitempty"` // HTTP method; defaults to POST
+	Headers         map[string]string `json:"headers,omitempty"`
+	Body            string            `json:"body,omitempty"`
+	Timeout         string            `json:"timeout,omitempty"`           // Go duration; defaults to 60s
+	ContinueOnError bool              `json:"continue_on_error,omitempty"` // A failing pre hook does not abort the run
+}
+
+// HookResult is the captured outcome of one hook invocation
+type HookResult struct {
+	Stage      HookStage     `json:"stage"`
+	Name       string        `json:"name"`
+	Kind       string        `json:"kind"` // command or http
+	StartedAt  time.Time     `json:"started_at"`
+	Duration   time.Duration `json:"duration"`
+	Success    bool          `json:"success"`
+	ExitCode   int           `json:"exit_code,omitempty"`
+	StatusCode int           `json:"status_code,omitempty"`
+	Output     string        `json:"output,omitempty"` // Combined stdout and stderr, or the response body
+	Truncated  bool          `json:"truncated,omitempty"`
+	Error      string        `json:"error,omitempty"`
+}
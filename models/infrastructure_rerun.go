@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// InfrastructureRerun records one -rerun-infra-failures pass over a results file:
+// the tests that had failed against the server were run again and their new results
+// replaced the old ones
+type InfrastructureRerun struct {
+	RunID        string        `json:"run_id"` // Identifies the rerun's request log
+	StartedAt    time.Time     `json:"started_at"`
+	Duration     time.Duration `json:"duration"`
+	RerunTests   int           `json:"rerun_tests"`
+	Passed       int           `json:"passed"`                // Reruns that passed
+	ModelFailed  int           `json:"model_failed"`          // Reruns that reached the model and failed on its answer
+	StillFailing int           `json:"still_failing"`         // Reruns that failed against the server again
+	Interrupted  int           `json:"interrupted,omitempty"` // Reruns cut short; their previous results were kept
+}
@@ -0,0 +1,44 @@
+package models
+
+// JudgeScaleMax is the top of the 1-to-N scale a judge model scores each criterion on
+const JudgeScaleMax = 5
+
+// JudgeRubric is what a judge model scores a test's final reply against, e.g. whether
+// it is helpful and recites the cart correctly
+type JudgeRubric struct {
+	Criteria  []RubricCriterion `json:"criteria"`
+	Reference string            `json:"reference,omitempty"` // What a good reply contains, e.g. the expected cart summary
+}
+
+// RubricCriterion is one aspect of the reply the judge scores
+type RubricCriterion struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description"`      // What the judge should look for
+	Weight      float64 `json:"weight,omitempty"` // Relative weight in the overall score; defaults to 1
+}
+
+// JudgeScore is a judge model's assessment of a test's final reply. It is recorded
+// alongside the automatic outcome and does not change Success.
+type JudgeScore struct {
+	Model     string           `json:"model"`
+	Score     float64          `json:"score"` // Weighted mean of the criteria, scaled to 0-1
+	Criteria  []CriterionScore `json:"criteria,omitempty"`
+	Rationale string           `json:"rationale,omitempty"`
+	Error     string           `json:"error,omitempty"` // The judge request failed or its answer could not be parsed; Score is unset
+}
+
+// CriterionScore is the judge's score of one rubric criterion
+type CriterionScore struct {
+	Name      string `json:"name"`
+	Score     int    `json:"score"` // 1 (poor) to JudgeScaleMax (excellent)
+	Rationale string `json:"rationale,omitempty"`
+}
+
+// JudgeSummary averages the judge scores of a run
+type JudgeSummary struct {
+	Model     string             `json:"model"`
+	Judged    int                `json:"judged"`           // Results the judge scored
+	Errors    int                `json:"errors,omitempty"` // Results the judge failed to score
+	MeanScore float64            `json:"mean_score"`
+	Criteria  map[string]float64 `json:"criteria,omitempty"` // Mean 1-5 score of each criterion
+}
@@ -0,0 +1,29 @@
+package models
+
+// KamiwazaClusterConfig lists the Kamiwaza clusters tests can target
+type KamiwazaClusterConfig struct {
+	Clusters []KamiwazaCluster `json:"clusters"`
+}
+
+// KamiwazaCluster is a named Kamiwaza cluster and how to log in to it
+type KamiwazaCluster struct {
+	Name        string            `json:"name"`
+	URL         string            `json:"url"`
+	Username    string            `json:"username,omitempty"`     // Defaults to admin
+	Password    string            `json:"password,omitempty"`     // Defaults to kamiwaza
+	PasswordEnv string            `json:"password_env,omitempty"` // Environment variable holding the password, used instead of password
+	Proxy       string            `json:"proxy,omitempty"`        // Proxy URL (http, https, socks5) for the Kamiwaza API, or "direct"; unset follows HTTPS_PROXY/NO_PROXY
+	Metadata    map[string]string `json:"metadata,omitempty"`     // Recorded with every run, e.g. hardware or purpose
+}
+
+// ClusterInfo identifies the cluster a run targeted, without its credentials
+type ClusterInfo struct {
+	Name     string            `json:"name"`
+	URL      string            `json:"url"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// Info returns the cluster's identity and metadata for recording in results
+func (c KamiwazaCluster) Info() *ClusterInfo {
+	return &ClusterInfo{Name: c.Name, URL: c.URL, Metadata: c.Metadata}
+}
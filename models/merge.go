@@ -0,0 +1,26 @@
+package models
+
+// MergeRule decides which result is kept when merged result files hold the same test
+type MergeRule string
+
+const (
+	MergeReachedModel MergeRule = "reached-model" // A result that reached the model beats an infrastructure failure; otherwise the latest wins
+	MergeLatest       MergeRule = "latest"        // The most recently finished result wins
+	MergeEarliest     MergeRule = "earliest"      // The result of the earliest file listed wins
+	MergeError        MergeRule = "error"         // Any test present in more than one file stops the merge
+)
+
+// MergeSource is a result file merged into a report by merge-results
+type MergeSource struct {
+	File    string `json:"file"`
+	RunID   string `json:"run_id,omitempty"`
+	Results int    `json:"results"`
+	Kept    int    `json:"kept"` // Results that were the only one of their test or won its conflict
+}
+
+// MergeConflict is a test present in more than one merged file and the result kept
+type MergeConflict struct {
+	Key      string   `json:"key"`       // Test case, sampling label and repetition
+	KeptFile string   `json:"kept_file"` // File of the kept result
+	Dropped  []string `json:"dropped"`   // Files whose results were discarded
+}
@@ -0,0 +1,33 @@
+package models
+
+import "encoding/json"
+
+// MockScript is what the mock provider answers: the assistant turns of each
+// conversation, keyed by its user prompt
+type MockScript struct {
+	Conversations []MockConversation `json:"conversations"`
+}
+
+// MockConversation is the scripted answer to one prompt. Turns are played in order,
+// one per request; past the end the last turn repeats.
+type MockConversation struct {
+	Prompt string     `json:"prompt"`
+	Turns  []MockTurn `json:"turns"`
+}
+
+// MockTurn is one assistant message, or a request error when Error is set
+type MockTurn struct {
+	Content   string         `json:"content,omitempty"`
+	ToolCalls []MockToolCall `json:"tool_calls,omitempty"`
+	Error     string         `json:"error,omitempty"`  // Answered with an error response with this message
+	Status    int            `json:"status,omitempty"` // HTTP status of the error response; defaults to 400
+}
+
+// MockToolCall is a tool call requested by a scripted turn
+type MockToolCall struct {
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+
+	// Sent verbatim instead of Arguments, e.g. to script malformed JSON
+	RawArguments string `json:"raw_arguments,omitempty"`
+}
@@ -0,0 +1,30 @@
+package models
+
+// MutationKind is a way of breaking a passing transcript for the evaluator self-check
+type MutationKind string
+
+const (
+	MutationSwapTool       MutationKind = "swap_tool"       // A call's tool replaced by one no expected path uses
+	MutationDropArgument   MutationKind = "drop_argument"   // An argument the matched path checks removed from a call
+	MutationChangeArgument MutationKind = "change_argument" // An argument the matched path checks given a different value
+	MutationDropCall       MutationKind = "drop_call"       // A call removed from the transcript
+)
+
+// MutationOutcome is how the evaluator scored one mutant of a passing result
+type MutationOutcome struct {
+	TestCase    string        `json:"test_case"`
+	Kind        MutationKind  `json:"kind"`
+	Description string        `json:"description"`            // What was changed, e.g. "call 2: add_to_cart -> checkout"
+	Caught      bool          `json:"caught"`                 // The evaluator failed the mutant
+	Reason      FailureReason `json:"reason,omitempty"`       // Why a caught mutant failed
+	MatchedPath string        `json:"matched_path,omitempty"` // Path a surviving mutant matched
+
+	// The mutant survived by matching a different expected path than the original
+	// transcript, so it is still a valid answer rather than a matcher bug
+	Equivalent bool `json:"equivalent,omitempty"`
+}
+
+// Survived reports whether the evaluator passed a mutant that no expected path allows
+func (o MutationOutcome) Survived() bool {
+	return !o.Caught && !o.Equivalent
+}
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// NetworkChaos configures faults injected into the requests sent to the model server,
+// to characterize how a provider integration and the client's retries behave on a
+// degraded network
+type NetworkChaos struct {
+	Latency                string  `json:"latency,omitempty"`                    // Go duration added before every request attempt
+	Jitter                 string  `json:"jitter,omitempty"`                     // Go duration the added latency varies by, either way
+	DropRate               float64 `json:"drop_rate,omitempty"`                  // Fraction of attempts whose connection drops before a response
+	SlowBodyRate           float64 `json:"slow_body_rate,omitempty"`             // Fraction of responses whose body is throttled
+	SlowBodyBytesPerSecond int     `json:"slow_body_bytes_per_second,omitempty"` // Throttled body rate; defaults to 1024
+	Seed                   *int64  `json:"seed,omitempty"`                       // Makes runs see the same faults; random when unset
+}
+
+// NetworkFaultType is the kind of an injected network fault
+type NetworkFaultType string
+
+const (
+	NetworkFaultLatency  NetworkFaultType = "latency"            // The attempt was delayed before it was sent
+	NetworkFaultDrop     NetworkFaultType = "dropped_connection" // The attempt failed with a reset connection
+	NetworkFaultSlowBody NetworkFaultType = "slow_body"          // The response body was throttled
+)
+
+// NetworkFault is one fault injected into a request attempt of a test
+type NetworkFault struct {
+	Type           NetworkFaultType `json:"type"`
+	At             time.Time        `json:"at"`
+	Attempt        int              `json:"attempt"`                    // The client's retry attempt, 0 for the first
+	Delay          time.Duration    `json:"delay,omitempty"`            // Latency added
+	BytesPerSecond int              `json:"bytes_per_second,omitempty"` // Rate a slow body was read at
+}
@@ -0,0 +1,19 @@
+package models
+
+// ScoringMode is how a test's outcome is scored besides pass/fail
+type ScoringMode string
+
+const (
+	ScoringBinary  ScoringMode = "binary"  // Pass or fail only
+	ScoringPartial ScoringMode = "partial" // Each test also gets a partial-credit score
+)
+
+// PartialScore credits how close a test's tool calls came to its closest expected
+// path, so a model that gets most of a sequence right is not scored like one that gets
+// none of it. Every value is between 0 and 1.
+type PartialScore struct {
+	Score           float64 `json:"score"`            // Mean of the sequence overlap and argument match
+	SequenceOverlap float64 `json:"sequence_overlap"` // Longest common subsequence of tool names over the mean sequence length
+	ArgumentMatch   float64 `json:"argument_match"`   // Argument checks (required, optional, absent) passed by the calls paired with the path
+	Variant         string  `json:"variant,omitempty"`
+}
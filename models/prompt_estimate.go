@@ -0,0 +1,12 @@
+package models
+
+// PromptEstimate is the estimated size of the first request sent for a test case,
+// computed without contacting the model. Later iterations of the agent loop resend
+// this prompt plus the tool calls and results so far.
+type PromptEstimate struct {
+	TestCase      string `json:"test_case"`
+	SystemTokens  int64  `json:"system_tokens"`
+	ToolTokens    int64  `json:"tool_tokens"`
+	MessageTokens int64  `json:"message_tokens"`
+	TotalTokens   int64  `json:"total_tokens"`
+}
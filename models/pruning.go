@@ -0,0 +1,13 @@
+package models
+
+// PruningPolicy lists what was left out of a saved results file to keep it small.
+// The request log still holds the full requests and responses.
+type PruningPolicy struct {
+	DropToolResults  bool `json:"drop_tool_results,omitempty"`  // Tool call result payloads were removed
+	MaxMessageLength int  `json:"max_message_length,omitempty"` // Assistant message text was cut to this many characters
+}
+
+// Enabled reports whether the policy prunes anything
+func (p PruningPolicy) Enabled() bool {
+	return p.DropToolResults || p.MaxMessageLength > 0
+}
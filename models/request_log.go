@@ -0,0 +1,30 @@
+package models
+
+// LogSchemaVersion is the version of the request log entry format
+const LogSchemaVersion = 2
+
+// LogEntry represents a single request/response log entry
+type LogEntry struct {
+	SchemaVersion int         `json:"schema_version"`
+	Timestamp     string      `json:"timestamp"`
+	RunID         string      `json:"run_id,omitempty"`
+	TestCase      string      `json:"test_case"`
+	Iteration     int         `json:"iteration"`
+	Request       LogRequest  `json:"request"`
+	Response      LogResponse `json:"response"`
+	Error         string      `json:"error,omitempty"`
+}
+
+// LogRequest represents the request part of a log entry
+type LogRequest struct {
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"` // Static headers configured for the endpoint, credential values redacted
+	Body    interface{}       `json:"body"`
+}
+
+// LogResponse represents the response part of a log entry
+type LogResponse struct {
+	StatusCode int         `json:"status_code"`
+	Body       interface{} `json:"body"`
+}
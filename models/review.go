@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+// ReviewVerdict is a human decision on a borderline result
+type ReviewVerdict string
+
+const (
+	VerdictPass ReviewVerdict = "pass"
+	VerdictFail ReviewVerdict = "fail"
+)
+
+// Adjudication is a human decision on a borderline result. It is keyed by the test
+// case and the exact tool calls made, so it applies to every result with the same
+// behavior, including reruns and re-scored results.
+type Adjudication struct {
+	Key       string        `json:"key"`
+	TestCase  string        `json:"test_case"`
+	Verdict   ReviewVerdict `json:"verdict"`
+	Note      string        `json:"note,omitempty"`
+	Reviewer  string        `json:"reviewer,omitempty"`
+	Source    string        `json:"source,omitempty"` // Where the decision came from, e.g. review_ui or import:labels.csv
+	DecidedAt time.Time     `json:"decided_at"`
+}
+
+// ManualOverride is the audit record of a result whose automatic verdict was replaced
+// by a human decision
+type ManualOverride struct {
+	TestCase        string        `json:"test_case"`
+	Key             string        `json:"key"`
+	AutomaticReason FailureReason `json:"automatic_reason"`
+	Verdict         ReviewVerdict `json:"verdict"`
+	Reviewer        string        `json:"reviewer,omitempty"`
+	Source          string        `json:"source,omitempty"`
+	Note            string        `json:"note,omitempty"`
+	DecidedAt       time.Time     `json:"decided_at"`
+}
+
+// ReviewItem is a borderline result waiting for human adjudication. Results with the
+// same key are merged into one item.
+type ReviewItem struct {
+	Key              string             `json:"key"`
+	TestCase         string             `json:"test_case"`
+	Prompt           string             `json:"prompt"`
+	Reason           string             `json:"reason"` // Why the result is borderline
+	FailureReason    FailureReason      `json:"failure_reason"`
+	FailureDetails   string             `json:"failure_details,omitempty"`
+	ToolCalls        []ActualToolCall   `json:"tool_calls"`
+	ExpectedVariants []ExpectedToolPath `json:"expected_variants"`
+	Occurrences      int                `json:"occurrences"`
+	Models           []string           `json:"models"`
+	ResultFiles      []string           `json:"result_files"`
+	Judge            *JudgeScore        `json:"judge,omitempty"` // The judge's score of the first occurrence, if a judge ran
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+)
+
+// RunEventType identifies a kind of run progress event
+type RunEventType string
+
+const (
+	EventRunStarted         RunEventType = "run_started"
+	EventTestStarted        RunEventType = "test_started"
+	EventIterationCompleted RunEventType = "iteration_completed"
+	EventToolCalled         RunEventType = "tool_called"
+	EventTestFinished       RunEventType = "test_finished"
+	EventRunFinished        RunEventType = "run_finished"
+	EventTelemetrySampled   RunEventType = "telemetry_sampled"
+)
+
+// RunEvent describes a single step of run progress published to live subscribers
+type RunEvent struct {
+	Type      RunEventType `json:"type"`
+	Timestamp time.Time    `json:"timestamp"`
+	RunID     string       `json:"run_id,omitempty"`
+	TestCase  string       `json:"test_case,omitempty"`
+	Iteration int          `json:"iteration,omitempty"`
+	ToolName  string       `json:"tool_name,omitempty"`
+	Success   *bool        `json:"success,omitempty"`
+	Data      interface{}  `json:"data,omitempty"` // Event-specific payload (iteration stats, tool arguments, failure reason, ...)
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// RunManifest records how a single run was executed. It is saved next to the result
+// file when the run has something to record beyond its results, such as telemetry or
+// hook output.
+type RunManifest struct {
+	RunID       string    `json:"run_id"`
+	Model       string    `json:"model"`
+	ResultsFile string    `json:"results_file,omitempty"` // Empty for pre_model and post_model hook runs
+	StartedAt   time.Time `json:"started_at"`
+	FinishedAt  time.Time `json:"finished_at"`
+
+	TelemetryHook     string            `json:"telemetry_hook,omitempty"`
+	TelemetryInterval string            `json:"telemetry_interval,omitempty"`
+	Timeline          []TelemetrySample `json:"timeline,omitempty"` // Telemetry hook outputs in the order they were taken
+
+	Hooks []HookResult `json:"hooks,omitempty"` // Pre and post hooks in the order they ran
+}
+
+// TelemetrySample is one invocation of the telemetry hook, placed on the run timeline
+type TelemetrySample struct {
+	Timestamp time.Time       `json:"timestamp"`
+	Offset    time.Duration   `json:"offset"`          // Since the run started
+	Data      json.RawMessage `json:"data,omitempty"`  // The hook's JSON output, e.g. GPU temperatures and clocks
+	Error     string          `json:"error,omitempty"` // Why the hook produced no data
+}
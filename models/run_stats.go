@@ -0,0 +1,23 @@
+package models
+
+// RunStatistics summarizes a suite executed several times (-runs), since tool-calling
+// behavior can vary between runs even at temperature 0
+type RunStatistics struct {
+	Runs             int                 `json:"runs"`
+	RunPassRates     []float64           `json:"run_pass_rates"` // Pass rate of each run, in run order
+	PassRateMean     float64             `json:"pass_rate_mean"`
+	PassRateVariance float64             `json:"pass_rate_variance"` // Sample variance across runs
+	PassRateStdDev   float64             `json:"pass_rate_std_dev"`
+	TestCases        []TestCaseStability `json:"test_cases"`
+	FlakyTests       int                 `json:"flaky_tests"`
+}
+
+// TestCaseStability is how consistently a single test case passed across runs
+type TestCaseStability struct {
+	TestCase string  `json:"test_case"`
+	Config   string  `json:"config,omitempty"` // Sampling combination label when the suite ran a sweep
+	Runs     int     `json:"runs"`
+	Passed   int     `json:"passed"`
+	PassRate float64 `json:"pass_rate"`
+	Flaky    bool    `json:"flaky,omitempty"` // Passed in some runs and failed in others
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// RunTags holds arbitrary key=value metadata attached to a run (e.g. gpu=a100,
+// quant=q4_k_m). It implements flag.Value so it can back a repeatable -tag flag.
+type RunTags map[string]string
+
+// String renders the tags as comma-separated key=value pairs sorted by key
+func (t RunTags) String() string {
+	keys := make([]string, 0, len(t))
+	for key := range t {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, key+"="+t[key])
+	}
+	return strings.Join(pairs, ",")
+}
+
+// Set parses a single key=value pair and adds it to the tags
+func (t RunTags) Set(value string) error {
+	key, tagValue, found := strings.Cut(value, "=")
+	key = strings.TrimSpace(key)
+	if !found || key == "" {
+		return fmt.Errorf("invalid tag %q, expected key=value", value)
+	}
+
+	t[key] = strings.TrimSpace(tagValue)
+	return nil
+}
+
+// Matches reports whether every tag in the filter is present with the same value
+func (t RunTags) Matches(filter RunTags) bool {
+	for key, value := range filter {
+		if actual, exists := t[key]; !exists || actual != value {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,27 @@
+package models
+
+// SchemaViolationKind classifies how a tool call's arguments break the tool's schema
+type SchemaViolationKind string
+
+const (
+	SchemaInvalidJSON     SchemaViolationKind = "invalid_json"     // Arguments are not a JSON object
+	SchemaWrongType       SchemaViolationKind = "wrong_type"       // A value has a different JSON type than declared
+	SchemaUnknownProperty SchemaViolationKind = "unknown_property" // A property the schema does not declare
+	SchemaMissingRequired SchemaViolationKind = "missing_required" // A required property is absent
+)
+
+// SchemaViolation is one way a tool call's arguments break the tool's declared schema
+type SchemaViolation struct {
+	Kind    SchemaViolationKind `json:"kind"`
+	Path    string              `json:"path,omitempty"` // e.g. items[0].quantity; empty for the arguments object itself
+	Message string              `json:"message"`
+}
+
+// SchemaCompliance summarizes how many tool calls had arguments valid under their
+// tool's schema, regardless of whether the right tool was called
+type SchemaCompliance struct {
+	CheckedCalls int                         `json:"checked_calls"`
+	ValidCalls   int                         `json:"valid_calls"`
+	Rate         float64                     `json:"rate"`
+	ByKind       map[SchemaViolationKind]int `json:"by_kind,omitempty"` // Violations of each kind across all calls
+}
@@ -0,0 +1,21 @@
+package models
+
+// ExactPathScorer names the built-in scorer that matches the tool calls against the
+// test case's expected variants; it is the whole chain when a test config lists none
+const ExactPathScorer = "exact-path"
+
+// ScorerConfig selects one scorer of a test config's chain. Without a command it names
+// the built-in or a compiled-in scorer; with one it runs the command for every test.
+type ScorerConfig struct {
+	Name    string `json:"name"`
+	Command string `json:"command,omitempty"` // Run through sh -c; reads the test on stdin and writes a verdict on stdout
+	Timeout string `json:"timeout,omitempty"` // Go duration per test; defaults to 60s
+}
+
+// ScorerVerdict is one scorer's decision on a test's response
+type ScorerVerdict struct {
+	Scorer  string  `json:"scorer"`
+	Passed  bool    `json:"passed"`
+	Score   float64 `json:"score"` // 0 to 1; 1 or 0 when the scorer only passes or fails
+	Details string  `json:"details,omitempty"`
+}
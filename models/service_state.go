@@ -0,0 +1,9 @@
+package models
+
+// ServiceState is how tests share the product and cart services their tool calls run against
+type ServiceState string
+
+const (
+	ServiceStateIsolated ServiceState = "isolated" // Each test gets fresh service instances (default)
+	ServiceStateShared   ServiceState = "shared"   // All tests use one set of instances, with carts kept apart only by session ID
+)
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// FileSignature is the detached signature of a result file or manifest, stored next to
+// it with a .sig suffix
+type FileSignature struct {
+	Algorithm string    `json:"algorithm"` // hmac-sha256
+	KeyID     string    `json:"key_id"`    // Fingerprint of the signing key, to tell a wrong key from tampering
+	Signature string    `json:"signature"` // Hex-encoded MAC of the file's exact bytes
+	SignedAt  time.Time `json:"signed_at"`
+}
+
+// SignatureStatus is the outcome of verifying a file against its signature
+type SignatureStatus string
+
+const (
+	SignatureValid      SignatureStatus = "valid"
+	SignatureMissing    SignatureStatus = "unsigned"
+	SignatureForeignKey SignatureStatus = "foreign_key" // Signed, but with a different key
+	SignatureMismatch   SignatureStatus = "tampered"    // Signed with this key, but the content changed since
+)
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// StreamingMetrics measures how streamed completions arrived, alongside the total LLM
+// latency already recorded for a test. On an AgentReport the same fields summarize
+// every streamed test.
+type StreamingMetrics struct {
+	Requests            int           `json:"requests"`
+	TimeToFirstToken    time.Duration `json:"time_to_first_token"`     // First request of the agent loop, which a user waits on
+	AvgTimeToFirstToken time.Duration `json:"avg_time_to_first_token"` // Across every request
+	CompletionTokens    int64         `json:"completion_tokens"`
+	GenerationTime      time.Duration `json:"generation_time"`            // Time from each first token to the end of its stream
+	TokensPerSecond     float64       `json:"tokens_per_second"`          // Completion tokens over generation time
+	TokensEstimated     bool          `json:"tokens_estimated,omitempty"` // True when the backend did not report usage and streamed chunks were counted instead
+}
@@ -0,0 +1,17 @@
+package models
+
+// DefaultSystemPromptVariant names the run's system prompt in the per-variant summary:
+// the built-in shopping assistant prompt, or the config's when it replaces it
+const DefaultSystemPromptVariant = "default"
+
+// SystemPromptVariantResult is the tool-selection accuracy of the tests that ran with
+// one system prompt variant
+type SystemPromptVariantResult struct {
+	Variant           string  `json:"variant"`
+	TestCases         int     `json:"test_cases"`
+	TotalTests        int     `json:"total_tests"`
+	CorrectSelection  int     `json:"correct_selection"` // Tests that called the expected tools, whether or not the arguments matched
+	PassedTests       int     `json:"passed_tests"`
+	SelectionAccuracy float64 `json:"selection_accuracy"`
+	SuccessRate       float64 `json:"success_rate"`
+}
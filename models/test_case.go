@@ -1,13 +1,67 @@
 package models
 
-import "github.com/openai/openai-go"
+import (
+	"time"
+
+	"github.com/openai/openai-go"
+)
 
 // TestCase represents a single test scenario
 type TestCase struct {
 	Name                 string             `json:"name"`
+	Tags                 []string           `json:"tags,omitempty"` // Labels for selecting subsets with -tags and -exclude-tags, e.g. "cart-mutation"
 	Prompt               string             `json:"prompt"`
+	Locale               string             `json:"locale,omitempty"` // Language of the prompt, e.g. "de"; defaults to DefaultLocale
 	InitialCartState     *InitialCartState  `json:"initial_cart_state,omitempty"`
-	ExpectedToolVariants []ExpectedToolPath `json:"expected_tools_variants"` // Multi-path format
+	ExpectedToolVariants []ExpectedToolPath `json:"expected_tools_variants"`   // Multi-path format
+	ForbiddenTools       []string           `json:"forbidden_tools,omitempty"` // Tools that must never be called
+
+	// Checks on the assistant's final text reply, e.g. that it declined politely
+	ExpectedResponse *ExpectedResponse `json:"expected_response,omitempty"`
+
+	// Criteria a judge model (-judge-model) scores the final reply against
+	Rubric *JudgeRubric `json:"rubric,omitempty"`
+
+	// Replaces the run's system prompt for this test, so prompt variants can be compared in one batch
+	SystemPrompt        string `json:"system_prompt,omitempty"`
+	SystemPromptVariant string `json:"system_prompt_variant,omitempty"` // Names the prompt in the per-variant summary, e.g. "terse"
+
+	// Hooks run before and after each run of this test case, e.g. to seed or reset state
+	Setup    []Hook `json:"setup,omitempty"`
+	Teardown []Hook `json:"teardown,omitempty"`
+}
+
+// TestSuite is a test config file: its test cases and the hooks run once before and
+// after the whole suite. A config file may also be a bare array of test cases.
+type TestSuite struct {
+	Setup     []Hook     `json:"setup,omitempty"`
+	Teardown  []Hook     `json:"teardown,omitempty"`
+	TestCases []TestCase `json:"test_cases"`
+
+	// Equivalent tool names matched as one tool, e.g. when providers name a tool differently
+	ToolAliases ToolAliases `json:"tool_aliases,omitempty"`
+
+	// Scorers that decide whether a test passes, all of which must pass; defaults to exact-path
+	Scorers []ScorerConfig `json:"scorers,omitempty"`
+}
+
+// HasTag reports whether the test case is labeled with the tag
+func (tc TestCase) HasTag(tag string) bool {
+	for _, own := range tc.Tags {
+		if own == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// SystemPromptFor returns the system prompt the test runs with: its own override,
+// then the config's, or empty for the built-in shopping assistant prompt
+func (tc TestCase) SystemPromptFor(config TestConfig) string {
+	if tc.SystemPrompt != "" {
+		return tc.SystemPrompt
+	}
+	return config.SystemPrompt
 }
 
 // InitialCartState represents the initial state of the cart for a test
@@ -26,20 +80,98 @@ type ExpectedToolPath struct {
 	Name        string             `json:"name"`
 	Description string             `json:"description,omitempty"`
 	Tools       []ExpectedToolCall `json:"tools"`
+	Order       ToolOrder          `json:"order,omitempty"` // Defaults to strict
 }
 
+// ToolOrder is how the calls of an expected path must be ordered
+type ToolOrder string
+
+const (
+	ToolOrderStrict ToolOrder = "strict" // In the listed order; calls of one parallel turn may still swap
+	ToolOrderAny    ToolOrder = "any"    // In any order, e.g. adding two independent items to the cart
+)
+
 // ExpectedToolCall represents the expected function call
 type ExpectedToolCall struct {
-	Name      string                 `json:"name"`
-	Arguments map[string]interface{} `json:"arguments"`
+	Name        string                       `json:"name"`
+	Arguments   map[string]interface{}       `json:"arguments"`
+	MatchPolicy map[string]StringMatchPolicy `json:"match_policy,omitempty"` // Per-argument overrides of the global policy
+
+	OptionalArguments map[string]interface{} `json:"optional_arguments,omitempty"` // Checked only when the call passes them
+	AbsentArguments   []string               `json:"absent_arguments,omitempty"`   // The call must not pass these
+
+	// Errors the call may end in under the workflow evaluation mode, e.g. execution_failed
+	// for a checkout the test expects to be refused
+	AllowedErrors []ToolErrorCode `json:"allowed_errors,omitempty"`
+}
+
+// ExpectedResponse lists checks the assistant's final text reply must all pass
+type ExpectedResponse struct {
+	Contains       []string `json:"contains,omitempty"`         // Each must appear in the reply
+	Regex          []string `json:"regex,omitempty"`            // Each must match somewhere in the reply
+	MustNotContain []string `json:"must_not_contain,omitempty"` // None may appear in the reply
+	CaseSensitive  bool     `json:"case_sensitive,omitempty"`   // Contains checks ignore case unless set; regexes use (?i)
+}
+
+// StringMatchPolicy controls how expected and actual string values are compared.
+// Unset fields inherit from the policy they are merged onto.
+type StringMatchPolicy struct {
+	CaseSensitive        *bool  `json:"case_sensitive,omitempty"`
+	Trim                 *bool  `json:"trim,omitempty"`
+	CollapseWhitespace   *bool  `json:"collapse_whitespace,omitempty"`
+	UnicodeNormalization string `json:"unicode_normalization,omitempty"` // none, nfc or nfkc
+}
+
+// Merge returns a copy of the policy with the fields set in override applied on top
+func (p StringMatchPolicy) Merge(override StringMatchPolicy) StringMatchPolicy {
+	merged := p
+	if override.CaseSensitive != nil {
+		merged.CaseSensitive = override.CaseSensitive
+	}
+	if override.Trim != nil {
+		merged.Trim = override.Trim
+	}
+	if override.CollapseWhitespace != nil {
+		merged.CollapseWhitespace = override.CollapseWhitespace
+	}
+	if override.UnicodeNormalization != "" {
+		merged.UnicodeNormalization = override.UnicodeNormalization
+	}
+	return merged
 }
 
 // TestConfig holds configuration parameters for the test
 type TestConfig struct {
-	SystemPrompt string  `json:"system_prompt,omitempty"`
-	Temperature  float32 `json:"temperature,omitempty"`
-	TopK         int     `json:"top_k,omitempty"`
-	MaxTokens    int     `json:"max_tokens,omitempty"`
+	Label        string   `json:"label,omitempty"` // Identifies one combination of a sampling sweep
+	SystemPrompt string   `json:"system_prompt,omitempty"`
+	Temperature  *float64 `json:"temperature,omitempty"` // Unset keeps the harness default of 0
+	TopP         *float64 `json:"top_p,omitempty"`
+	TopK         int      `json:"top_k,omitempty"`
+	MaxTokens    int      `json:"max_tokens,omitempty"`
+	Seed         *int64   `json:"seed,omitempty"` // Sent with every request for backends that support deterministic sampling
+
+	// Whether the model may request several tool calls in one turn; unset leaves the backend default
+	ParallelToolCalls *bool `json:"parallel_tool_calls,omitempty"`
+
+	// Grid of sampling values; the suite runs once for every combination
+	Sweep *SamplingSweep `json:"sweep,omitempty"`
+}
+
+// SamplingSweep lists the values of each sampling parameter to combine. A parameter
+// without values keeps the single value set on the TestConfig.
+type SamplingSweep struct {
+	Temperature []float64 `json:"temperature,omitempty"`
+	TopP        []float64 `json:"top_p,omitempty"`
+	MaxTokens   []int     `json:"max_tokens,omitempty"`
+}
+
+// SamplingConfigResult summarizes the results of one sampling combination of a sweep
+type SamplingConfigResult struct {
+	Config      TestConfig    `json:"config"`
+	TotalTests  int           `json:"total_tests"`
+	PassedTests int           `json:"passed_tests"`
+	PassRate    float64       `json:"pass_rate"`
+	AverageTime time.Duration `json:"average_time"`
 }
 
 // TestExecution represents a single test execution
@@ -0,0 +1,37 @@
+package models
+
+// TokenUsage counts the tokens consumed by a test or a whole run, from the usage
+// each completion reported
+type TokenUsage struct {
+	InputTokens  int64 `json:"input_tokens"`
+	OutputTokens int64 `json:"output_tokens"`
+	TotalTokens  int64 `json:"total_tokens"`
+	Estimated    bool  `json:"estimated,omitempty"` // Some input tokens were estimated because the backend did not report usage
+}
+
+// Add accumulates another usage into this one
+func (u *TokenUsage) Add(other TokenUsage) {
+	u.InputTokens += other.InputTokens
+	u.OutputTokens += other.OutputTokens
+	u.TotalTokens += other.TotalTokens
+	u.Estimated = u.Estimated || other.Estimated
+}
+
+// IterationUsage sums the token usage of the agent loop iterations. Iterations
+// recorded before total tokens were kept count their input and output tokens.
+func IterationUsage(iterations []IterationStats) TokenUsage {
+	var usage TokenUsage
+	for _, iteration := range iterations {
+		total := iteration.TotalTokens
+		if total == 0 {
+			total = iteration.PromptTokens + iteration.CompletionTokens
+		}
+		usage.Add(TokenUsage{
+			InputTokens:  iteration.PromptTokens,
+			OutputTokens: iteration.CompletionTokens,
+			TotalTokens:  total,
+			Estimated:    iteration.TokensEstimated,
+		})
+	}
+	return usage
+}
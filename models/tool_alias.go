@@ -0,0 +1,43 @@
+package models
+
+// ToolAliases maps a canonical tool name to the names of equivalent tools other
+// toolsets expose, e.g. "view_cart": ["get_cart"]. Matching compares tools by their
+// canonical names, so a suite written for one toolset scores calls to another.
+type ToolAliases map[string][]string
+
+// Canonical returns the canonical name of a tool; names that are no alias are their
+// own canonical name
+func (a ToolAliases) Canonical(name string) string {
+	for canonical, aliases := range a {
+		for _, alias := range aliases {
+			if alias == name {
+				return canonical
+			}
+		}
+	}
+	return name
+}
+
+// Merge returns the aliases with those of other added; other wins for an alias both
+// map to different canonical names
+func (a ToolAliases) Merge(other ToolAliases) ToolAliases {
+	if len(other) == 0 {
+		return a
+	}
+	merged := make(ToolAliases, len(a)+len(other))
+	claimed := make(map[string]bool)
+	for canonical, aliases := range other {
+		merged[canonical] = append([]string(nil), aliases...)
+		for _, alias := range aliases {
+			claimed[alias] = true
+		}
+	}
+	for canonical, aliases := range a {
+		for _, alias := range aliases {
+			if !claimed[alias] {
+				merged[canonical] = append(merged[canonical], alias)
+			}
+		}
+	}
+	return merged
+}
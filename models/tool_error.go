@@ -0,0 +1,34 @@
+package models
+
+// ToolErrorCode classifies why a tool call failed
+type ToolErrorCode string
+
+const (
+	ToolErrorInvalidArguments ToolErrorCode = "invalid_arguments" // Arguments did not parse
+	ToolErrorUnknownTool      ToolErrorCode = "unknown_tool"      // No tool with that name exists
+	ToolErrorExecutionFailed  ToolErrorCode = "execution_failed"  // The tool ran but could not complete the request
+	ToolErrorPermissionDenied ToolErrorCode = "permission_denied" // The simulated user denied approval to run the tool
+)
+
+// ToolErrorVerbosity controls how much of a tool failure is shown to the model
+type ToolErrorVerbosity string
+
+const (
+	ToolErrorMinimal  ToolErrorVerbosity = "minimal"  // Error code only
+	ToolErrorStandard ToolErrorVerbosity = "standard" // Error code and message
+	ToolErrorDetailed ToolErrorVerbosity = "detailed" // Error code, message, tool, arguments and a recovery hint
+)
+
+// ToolErrorPayload is the tool message content sent to the model when a tool call fails
+type ToolErrorPayload struct {
+	Error ToolErrorDetails `json:"error"`
+}
+
+// ToolErrorDetails describes a tool failure; fields beyond the code depend on the verbosity
+type ToolErrorDetails struct {
+	Code      ToolErrorCode `json:"code"`
+	Message   string        `json:"message,omitempty"`
+	Tool      string        `json:"tool,omitempty"`
+	Arguments string        `json:"arguments,omitempty"`
+	Hint      string        `json:"hint,omitempty"`
+}
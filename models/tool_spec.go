@@ -0,0 +1,57 @@
+package models
+
+// DefaultLocale is the locale of test prompts and tool descriptions that do not declare one
+const DefaultLocale = "en"
+
+// ToolSpecVersion is an alternative wording of the tool definitions. Tools that are
+// not listed keep their canonical name and descriptions.
+type ToolSpecVersion struct {
+	Name        string                      `json:"name"`
+	Description string                      `json:"description,omitempty"`
+	Locale      string                      `json:"locale,omitempty"` // Language of the tool descriptions; defaults to DefaultLocale
+	Tools       map[string]ToolSpecOverride `json:"tools"`            // Keyed by canonical tool name
+}
+
+// ToolSpecOverride replaces the wording of a single tool. Empty fields keep the
+// canonical value.
+type ToolSpecOverride struct {
+	Name        string            `json:"name,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Parameters  map[string]string `json:"parameters,omitempty"` // Parameter name -> description
+}
+
+// ToolSpecVersionResult compares one tool spec version with the canonical definitions
+type ToolSpecVersionResult struct {
+	Version          string                `json:"version"`
+	Locale           string                `json:"locale"`
+	ResultsFile      string                `json:"results_file"`
+	TotalTests       int                   `json:"total_tests"`
+	PassedTests      int                   `json:"passed_tests"`
+	SuccessRate      float64               `json:"success_rate"`
+	SuccessRateDelta float64               `json:"success_rate_delta"` // Versus the canonical run, in absolute points
+	FailureDeltas    map[FailureReason]int `json:"failure_deltas,omitempty"`
+	NewlyFailing     []string              `json:"newly_failing,omitempty"`
+	NewlyPassing     []string              `json:"newly_passing,omitempty"`
+}
+
+// ToolSpecComparison is the outcome of running the suite once per tool spec version
+type ToolSpecComparison struct {
+	RunID                string                  `json:"run_id"`
+	Model                string                  `json:"model"`
+	CanonicalResultsFile string                  `json:"canonical_results_file"`
+	CanonicalSuccessRate float64                 `json:"canonical_success_rate"`
+	Versions             []ToolSpecVersionResult `json:"versions"`
+	LocaleAccuracy       []LocaleAccuracy        `json:"locale_accuracy,omitempty"`
+}
+
+// LocaleAccuracy is the tool-selection accuracy for one combination of prompt locale
+// and tool description locale
+type LocaleAccuracy struct {
+	PromptLocale      string  `json:"prompt_locale"`
+	ToolLocale        string  `json:"tool_locale"`
+	TotalTests        int     `json:"total_tests"`
+	CorrectSelection  int     `json:"correct_selection"` // Tests that called the expected tools, whether or not the arguments matched
+	PassedTests       int     `json:"passed_tests"`
+	SelectionAccuracy float64 `json:"selection_accuracy"`
+	SuccessRate       float64 `json:"success_rate"`
+}
@@ -0,0 +1,20 @@
+package models
+
+// TranscriptMessage is one message of the conversation with the model, in the order
+// it was sent or received
+type TranscriptMessage struct {
+	Role       ChatRole             `json:"role"`
+	Iteration  int                  `json:"iteration,omitempty"` // Agent loop iteration that added the message; 0 for the prompt
+	Content    string               `json:"content,omitempty"`
+	Refusal    string               `json:"refusal,omitempty"`
+	ToolCalls  []TranscriptToolCall `json:"tool_calls,omitempty"`   // Calls requested by an assistant message, as the model sent them
+	ToolCallID string               `json:"tool_call_id,omitempty"` // Call a tool message answers
+	ToolName   string               `json:"tool_name,omitempty"`    // Tool that produced a tool message
+}
+
+// TranscriptToolCall is a tool call requested by an assistant message
+type TranscriptToolCall struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
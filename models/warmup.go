@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// Warmup records the throwaway completions sent before the suite started, so the
+// model's cold-start load time is not charged to the first test cases
+type Warmup struct {
+	Requests  int             `json:"requests"`            // Warm-up requests attempted
+	Latencies []time.Duration `json:"latencies,omitempty"` // One per completed request; the first usually includes the model load
+	Error     string          `json:"error,omitempty"`     // Why warm-up stopped early
+}
@@ -0,0 +1,9 @@
+package models
+
+// EvaluationMode is what a test's tool calls must get right for it to pass
+type EvaluationMode string
+
+const (
+	EvaluationCalls    EvaluationMode = "calls"    // The expected tools with the expected arguments
+	EvaluationWorkflow EvaluationMode = "workflow" // The calls also worked: tool errors were recovered from or allowed
+)
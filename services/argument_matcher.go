@@ -0,0 +1,152 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"model-test/models"
+)
+
+// Argument matcher operators. An expected argument value that is an object with one of
+// these keys is a matcher rather than a literal object:
+//
+//	{"$regex": "iphone.*"}             the whole value matches the pattern
+//	{"$approx": 2, "tolerance": 0.5}   a number within tolerance (default 0)
+//	{"$oneOf": ["red", "crimson"]}     any of the values (which may be matchers)
+const (
+	matcherRegex  = "$regex"
+	matcherApprox = "$approx"
+	matcherOneOf  = "$oneOf"
+)
+
+// asMatcher reports whether an expected value is a matcher object, and its operator
+func asMatcher(expected interface{}) (map[string]interface{}, string, bool) {
+	object, ok := expected.(map[string]interface{})
+	if !ok {
+		return nil, "", false
+	}
+	for key := range object {
+		if strings.HasPrefix(key, "$") {
+			return object, key, true
+		}
+	}
+	return nil, "", false
+}
+
+// matcherMatches evaluates a matcher object against the actual value; malformed
+// matchers never match (ValidateTestCases reports them)
+func (ev *Evaluator) matcherMatches(matcher map[string]interface{}, operator string, actual interface{}, policy models.StringMatchPolicy) bool {
+	if validateMatcher(matcher, operator) != nil {
+		return false
+	}
+
+	switch operator {
+	case matcherRegex:
+		pattern := `^(?:` + matcher[matcherRegex].(string) + `)$`
+		if policy.CaseSensitive == nil || !*policy.CaseSensitive {
+			pattern = `(?i)` + pattern
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return false
+		}
+		return re.MatchString(normalizeString(fmt.Sprintf("%v", actual), policy))
+	case matcherApprox:
+		value, ok := numericValue(actual)
+		if !ok {
+			return false
+		}
+		tolerance, _ := matcher["tolerance"].(float64)
+		return math.Abs(value-matcher[matcherApprox].(float64)) <= tolerance
+	case matcherOneOf:
+		for _, option := range matcher[matcherOneOf].([]interface{}) {
+			if ev.valuesMatch(option, actual, policy) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// numericValue reads a number from a JSON number or a numeric string
+func numericValue(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case string:
+		number, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return number, err == nil
+	}
+	return 0, false
+}
+
+// validateMatcher checks a matcher object's operator and operands
+func validateMatcher(matcher map[string]interface{}, operator string) error {
+	allowed := map[string]bool{operator: true}
+	switch operator {
+	case matcherRegex:
+		pattern, ok := matcher[matcherRegex].(string)
+		if !ok {
+			return fmt.Errorf("%s needs a string pattern", matcherRegex)
+		}
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("%s pattern %q does not compile: %w", matcherRegex, pattern, err)
+		}
+	case matcherApprox:
+		if _, ok := matcher[matcherApprox].(float64); !ok {
+			return fmt.Errorf("%s needs a number", matcherApprox)
+		}
+		if tolerance, exists := matcher["tolerance"]; exists {
+			if value, ok := tolerance.(float64); !ok || value < 0 {
+				return fmt.Errorf("%s tolerance must be a non-negative number", matcherApprox)
+			}
+		}
+		allowed["tolerance"] = true
+	case matcherOneOf:
+		options, ok := matcher[matcherOneOf].([]interface{})
+		if !ok || len(options) == 0 {
+			return fmt.Errorf("%s needs a non-empty list of values", matcherOneOf)
+		}
+	default:
+		return fmt.Errorf("unknown matcher %s (expected %s, %s or %s)", operator, matcherRegex, matcherApprox, matcherOneOf)
+	}
+
+	for key := range matcher {
+		if !allowed[key] {
+			return fmt.Errorf("%s matcher has unexpected key %s", operator, key)
+		}
+	}
+	return nil
+}
+
+// validateMatchers checks every matcher object within an expected argument value
+func validateMatchers(expected interface{}) error {
+	if matcher, operator, ok := asMatcher(expected); ok {
+		if err := validateMatcher(matcher, operator); err != nil {
+			return err
+		}
+		if operator == matcherOneOf {
+			return validateMatchers(matcher[matcherOneOf])
+		}
+		return nil
+	}
+
+	switch value := expected.(type) {
+	case map[string]interface{}:
+		for _, nested := range value {
+			if err := validateMatchers(nested); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, nested := range value {
+			if err := validateMatchers(nested); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
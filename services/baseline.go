@@ -0,0 +1,154 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"model-test/models"
+)
+
+// BaselinePath returns where the pinned baseline for a model is stored
+func BaselinePath(baselineDir, modelName string) string {
+	return filepath.Join(baselineDir, modelName+".json")
+}
+
+// PinBaseline stores a report as the model's baseline, replacing any previous one
+func PinBaseline(baselineDir, modelName string, report *models.AgentReport) (string, error) {
+	if err := os.MkdirAll(baselineDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create baseline directory: %w", err)
+	}
+
+	path := BaselinePath(baselineDir, modelName)
+	if err := SaveAgentReport(path, report); err != nil {
+		return "", fmt.Errorf("failed to save baseline: %w", err)
+	}
+	return path, nil
+}
+
+// LoadBaseline loads the model's pinned baseline. It returns nil without an error
+// when no baseline has been pinned.
+func LoadBaseline(baselineDir, modelName string) (*models.AgentReport, string, error) {
+	path := BaselinePath(baselineDir, modelName)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, path, nil
+	}
+	if err != nil {
+		return nil, path, fmt.Errorf("failed to read baseline: %w", err)
+	}
+
+	var report models.AgentReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, path, fmt.Errorf("failed to parse baseline: %w", err)
+	}
+	return &report, path, nil
+}
+
+// CompareToBaseline compares the test cases present in both reports and flags
+// metrics that regressed beyond the tolerance
+func CompareToBaseline(baseline, current *models.AgentReport, tolerance models.BaselineTolerance) *models.BaselineComparison {
+	comparison := &models.BaselineComparison{
+		BaselineRunID: baseline.RunID,
+		Tolerance:     tolerance,
+	}
+
+	baselineResults := make(map[string]models.AgentTestResult)
+	for _, result := range baseline.Results {
+		baselineResults[ResultKey(result)] = result
+	}
+
+	var before, after baselineStats
+	for _, result := range current.Results {
+		previous, exists := baselineResults[ResultKey(result)]
+		if !exists {
+			continue
+		}
+		comparison.CommonTests++
+		before.add(previous)
+		after.add(result)
+
+		if previous.Success && !result.Success {
+			comparison.NewlyFailing = append(comparison.NewlyFailing, ResultKey(result))
+		} else if !previous.Success && result.Success {
+			comparison.NewlyPassing = append(comparison.NewlyPassing, ResultKey(result))
+		}
+	}
+	sort.Strings(comparison.NewlyFailing)
+	sort.Strings(comparison.NewlyPassing)
+
+	if comparison.CommonTests == 0 {
+		return comparison
+	}
+
+	// Pass rate: absolute drop
+	successRate := models.BaselineMetric{
+		Name:      "success_rate",
+		Baseline:  before.successRate(),
+		Current:   after.successRate(),
+		Tolerance: tolerance.SuccessRateDrop,
+	}
+	successRate.Change = successRate.Current - successRate.Baseline
+	successRate.Regressed = -successRate.Change > tolerance.SuccessRateDrop
+	comparison.Metrics = append(comparison.Metrics, successRate)
+
+	// LLM latency: relative increase, skipped when either run made no requests
+	if baselineLatency, currentLatency := before.timePerRequest(), after.timePerRequest(); baselineLatency > 0 && currentLatency > 0 {
+		latency := models.BaselineMetric{
+			Name:      "llm_time_per_request_s",
+			Baseline:  baselineLatency.Seconds(),
+			Current:   currentLatency.Seconds(),
+			Tolerance: tolerance.LatencyIncrease,
+		}
+		latency.Change = latency.Current/latency.Baseline - 1
+		latency.Regressed = latency.Change > tolerance.LatencyIncrease
+		comparison.Metrics = append(comparison.Metrics, latency)
+	}
+
+	for _, metric := range comparison.Metrics {
+		if metric.Regressed {
+			comparison.Regressed = true
+		}
+	}
+
+	return comparison
+}
+
+// baselineStats accumulates the metrics compared against a baseline
+type baselineStats struct {
+	tests       int
+	passed      int
+	llmRequests int
+	llmTime     time.Duration
+}
+
+// add accumulates a single test result
+func (bs *baselineStats) add(result models.AgentTestResult) {
+	bs.tests++
+	if result.Success {
+		bs.passed++
+	}
+	if result.Response != nil {
+		bs.llmRequests += result.Response.LLMRequests
+		bs.llmTime += result.Response.LLMTotalTime
+	}
+}
+
+// successRate returns the fraction of passed tests
+func (bs *baselineStats) successRate() float64 {
+	if bs.tests == 0 {
+		return 0
+	}
+	return float64(bs.passed) / float64(bs.tests)
+}
+
+// timePerRequest returns the average LLM time per request
+func (bs *baselineStats) timePerRequest() time.Duration {
+	if bs.llmRequests == 0 {
+		return 0
+	}
+	return bs.llmTime / time.Duration(bs.llmRequests)
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"model-test/models"
+)
+
+// MeasureCallEfficiency compares the response's tool calls with the shortest expected
+// path of the test case. A test that expects no tool needs zero calls, so every call
+// it makes is redundant. Making fewer calls than needed is not penalized here; the
+// test fails for it instead. Returns nil when there is no response.
+func MeasureCallEfficiency(testCase models.TestCase, response *models.ChatResponse) *models.CallEfficiency {
+	if response == nil {
+		return nil
+	}
+
+	minimal := -1
+	for _, variant := range testCase.ExpectedToolVariants {
+		if minimal < 0 || len(variant.Tools) < minimal {
+			minimal = len(variant.Tools)
+		}
+	}
+	if minimal < 0 {
+		minimal = 0
+	}
+
+	efficiency := &models.CallEfficiency{MinimalCalls: minimal, ActualCalls: len(response.ToolCalls), Ratio: 1}
+	if efficiency.ActualCalls > minimal {
+		efficiency.Ratio = float64(minimal) / float64(efficiency.ActualCalls)
+	}
+	return efficiency
+}
+
+// SummarizeCallEfficiency averages the call efficiency of the results, measuring it
+// for results recorded before it was; nil when no result got a response
+func SummarizeCallEfficiency(results []models.AgentTestResult) *models.CallEfficiencySummary {
+	summary := &models.CallEfficiencySummary{}
+	var total, passedTotal float64
+
+	for _, result := range results {
+		efficiency := result.CallEfficiency
+		if efficiency == nil {
+			efficiency = MeasureCallEfficiency(result.TestCase, result.Response)
+		}
+		if efficiency == nil {
+			continue
+		}
+
+		summary.Tests++
+		total += efficiency.Ratio
+		summary.RedundantCalls += efficiency.RedundantCalls()
+		if efficiency.Ratio < 1 {
+			summary.InefficientTests++
+		}
+		if result.Success {
+			summary.PassedTests++
+			passedTotal += efficiency.Ratio
+		}
+	}
+	if summary.Tests == 0 {
+		return nil
+	}
+
+	summary.MeanRatio = total / float64(summary.Tests)
+	if summary.PassedTests > 0 {
+		summary.PassedMeanRatio = passedTotal / float64(summary.PassedTests)
+	}
+	return summary
+}
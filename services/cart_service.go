@@ -94,7 +94,7 @@ func (cs *CartService) CheckoutCart(sessionID string) (*models.CheckoutResult, e
 
 	cart := cs.getOrCreateCart(sessionID)
 	total := cart.Total
-	orderID := fmt.Sprintf("ORD-%d", time.Now().Unix())
+	orderID := fmt.Sprintf("ORD-%s-%d", sessionID, time.Now().UnixNano())
 
 	// Clear the cart after checkout
 	cart.Items = []models.CartItem{}
@@ -111,6 +111,63 @@ func (cs *CartService) CheckoutCart(sessionID string) (*models.CheckoutResult, e
 	}, nil
 }
 
+// CreateOrder places an order for the given items directly, without touching the session cart
+func (cs *CartService) CreateOrder(sessionID string, items []models.OrderItem, address *models.ShippingAddress) (*models.OrderResult, error) {
+	if len(items) == 0 {
+		return nil, fmt.Errorf("order must contain at least one item")
+	}
+
+	order := &models.OrderResult{
+		OrderID:         fmt.Sprintf("ORD-%s-%d", sessionID, time.Now().UnixNano()),
+		Items:           []models.CartItem{},
+		ShippingAddress: address,
+		Timestamp:       time.Now(),
+	}
+
+	for _, item := range items {
+		if item.ProductName == "" {
+			return nil, fmt.Errorf("order item is missing product_name")
+		}
+		if item.Quantity <= 0 {
+			return nil, fmt.Errorf("invalid quantity %d for %s", item.Quantity, item.ProductName)
+		}
+
+		price := cs.getProductPrice(item.ProductName)
+		lineItem := models.CartItem{
+			ProductName: item.ProductName,
+			Quantity:    item.Quantity,
+			Price:       price,
+			Subtotal:    float64(item.Quantity) * price,
+		}
+		order.Items = append(order.Items, lineItem)
+		order.Total += lineItem.Subtotal
+	}
+
+	return order, nil
+}
+
+// ScheduleDelivery books a delivery slot for the session's order; the time window is
+// optional and left empty when the model does not pick one
+func (cs *CartService) ScheduleDelivery(sessionID, deliveryDate, timeWindow string) (*models.DeliverySchedule, error) {
+	date, err := time.Parse("2006-01-02", deliveryDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid delivery_date %q, expected YYYY-MM-DD", deliveryDate)
+	}
+
+	switch timeWindow {
+	case "", "morning", "afternoon", "evening":
+	default:
+		return nil, fmt.Errorf("invalid time_window %q, expected morning, afternoon or evening", timeWindow)
+	}
+
+	return &models.DeliverySchedule{
+		SessionID:    sessionID,
+		DeliveryDate: date.Format("2006-01-02"),
+		TimeWindow:   timeWindow,
+		Weekday:      date.Weekday().String(),
+	}, nil
+}
+
 // getOrCreateCart gets an existing cart or creates a new one for the session
 func (cs *CartService) getOrCreateCart(sessionID string) *models.CartSummary {
 	cart, exists := cs.carts[sessionID]
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"model-test/models"
+)
+
+// ResultCheckpoint appends each finished test result to a JSON Lines file as soon as
+// it completes, so an interrupted run can be resumed without repeating finished tests
+type ResultCheckpoint struct {
+	file  *os.File
+	mutex sync.Mutex
+}
+
+// NewResultCheckpoint creates (or truncates) a checkpoint file
+func NewResultCheckpoint(filename string) (*ResultCheckpoint, error) {
+	file, err := os.Create(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create checkpoint file: %w", err)
+	}
+	return &ResultCheckpoint{file: file}, nil
+}
+
+// Write appends a result and flushes it to disk so it survives a crash
+func (c *ResultCheckpoint) Write(result models.AgentTestResult) error {
+	if c == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkpoint result: %w", err)
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if _, err := c.file.Write(append(RedactSecrets(data), '\n')); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	return c.file.Sync()
+}
+
+// Name returns the checkpoint file path
+func (c *ResultCheckpoint) Name() string {
+	return c.file.Name()
+}
+
+// Close closes the checkpoint file
+func (c *ResultCheckpoint) Close() error {
+	if c == nil {
+		return nil
+	}
+	return c.file.Close()
+}
+
+// LoadCheckpoint loads the results of an interrupted run, either from a checkpoint
+// file or from a saved (possibly partial) results file. A checkpoint line cut short
+// by a crash is ignored.
+func LoadCheckpoint(filename string) ([]models.AgentTestResult, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read resume file: %w", err)
+	}
+
+	var report models.AgentReport
+	if err := json.Unmarshal(data, &report); err == nil && report.Results != nil {
+		return report.Results, nil
+	}
+
+	var results []models.AgentTestResult
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var result models.AgentTestResult
+		if err := json.Unmarshal(scanner.Bytes(), &result); err != nil {
+			fmt.Printf("⚠️  Ignoring unreadable checkpoint line %d: %v\n", line, err)
+			continue
+		}
+		results = append(results, result)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read resume file: %w", err)
+	}
+
+	return results, nil
+}
+
+// resumable reports whether a previous result can stand in for rerunning its test.
+// Requests that failed against the server are rerun, since the interruption is often
+// what caused them.
+func resumable(result models.AgentTestResult) bool {
+	return !failedAgainstServer(result)
+}
+
+// failedAgainstServer reports whether a test failed because a request to the model
+// server errored or timed out, rather than because of the model's answer
+func failedAgainstServer(result models.AgentTestResult) bool {
+	return result.FailureReason.IsInfrastructure()
+}
@@ -0,0 +1,214 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"model-test/models"
+
+	"github.com/openai/openai-go"
+)
+
+// Isolation invariants checked by the concurrency audit
+const (
+	InvariantUniqueSession  = "unique_session"   // Every run gets its own session
+	InvariantCartOwnership  = "cart_ownership"   // The final cart belongs to the run's session
+	InvariantCartReplay     = "cart_replay"      // The final cart matches replaying the run's own tool calls in isolation
+	InvariantUniqueOrderIDs = "unique_order_ids" // Orders placed by different runs never share an ID
+)
+
+// RunConcurrencyAudit runs each test case concurrently K times, each run in its own
+// sandbox or all in the shared one, and checks that the runs did not observe or
+// modify each other's state
+func (tr *TestRunner) RunConcurrencyAudit(ctx context.Context, testCases []models.TestCase, concurrency int) *models.ConcurrencyAuditReport {
+	report := &models.ConcurrencyAuditReport{
+		RunID:     tr.runID,
+		Tags:      tr.tags,
+		Timestamp: time.Now(),
+		ModelName: tr.getModelName(),
+
+		ServiceState: tr.serviceState,
+	}
+
+	for _, testCase := range testCases {
+		fmt.Printf("Auditing %s with %d concurrent runs\n", testCase.Name, concurrency)
+
+		audit := tr.auditTestCase(ctx, testCase, concurrency)
+		report.TotalViolations += len(audit.Violations)
+		report.Audits = append(report.Audits, audit)
+	}
+
+	return report
+}
+
+// auditTestCase executes the concurrent runs of a single test case and checks them.
+// The audit checks isolation rather than sampling, so only the first sampling config runs.
+func (tr *TestRunner) auditTestCase(ctx context.Context, testCase models.TestCase, concurrency int) models.ConcurrencyAudit {
+	var wg sync.WaitGroup
+	results := make([]models.AgentTestResult, concurrency)
+
+	// Release all runs at once to maximize contention on the services
+	start := make(chan struct{})
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			<-start
+			results[index] = tr.runAgentTest(ctx, testCase, tr.configs[0])
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	audit := models.ConcurrencyAudit{
+		TestCase:    testCase.Name,
+		Concurrency: concurrency,
+	}
+
+	sessions := make(map[string]int)
+	orders := make(map[string]string)
+
+	for _, result := range results {
+		run := models.ConcurrencyAuditRun{
+			Success:       result.Success,
+			FailureReason: result.FailureReason,
+		}
+
+		if result.Response != nil {
+			response := result.Response
+			run.SessionID = response.SessionID
+			run.ToolCalls = len(response.ToolCalls)
+			run.FinalCart = response.CartSummary
+			run.OrderIDs = extractOrderIDs(response.ToolCalls)
+
+			sessions[run.SessionID]++
+			audit.Violations = append(audit.Violations, checkRunIsolation(testCase, response)...)
+
+			for _, orderID := range run.OrderIDs {
+				if owner, exists := orders[orderID]; exists && owner != run.SessionID {
+					audit.Violations = append(audit.Violations, models.IsolationViolation{
+						Invariant: InvariantUniqueOrderIDs,
+						SessionID: run.SessionID,
+						Details:   fmt.Sprintf("order %s was also issued to session %s", orderID, owner),
+					})
+				}
+				orders[orderID] = run.SessionID
+			}
+		}
+
+		audit.Runs = append(audit.Runs, run)
+	}
+
+	for sessionID, count := range sessions {
+		if count > 1 {
+			audit.Violations = append(audit.Violations, models.IsolationViolation{
+				Invariant: InvariantUniqueSession,
+				SessionID: sessionID,
+				Details:   fmt.Sprintf("%d runs shared the session", count),
+			})
+		}
+	}
+
+	return audit
+}
+
+// checkRunIsolation verifies that a run's final cart is its own and is exactly
+// what its tool calls produce when replayed against fresh services
+func checkRunIsolation(testCase models.TestCase, response *models.ChatResponse) []models.IsolationViolation {
+	var violations []models.IsolationViolation
+
+	if response.CartSummary != nil && response.CartSummary.SessionID != response.SessionID {
+		violations = append(violations, models.IsolationViolation{
+			Invariant: InvariantCartOwnership,
+			SessionID: response.SessionID,
+			Details:   fmt.Sprintf("final cart belongs to session %s", response.CartSummary.SessionID),
+		})
+	}
+
+	expected, err := replayCart(testCase, response)
+	if err != nil {
+		return append(violations, models.IsolationViolation{
+			Invariant: InvariantCartReplay,
+			SessionID: response.SessionID,
+			Details:   fmt.Sprintf("failed to replay tool calls: %v", err),
+		})
+	}
+
+	if actual := describeCart(response.CartSummary); actual != describeCart(expected) {
+		violations = append(violations, models.IsolationViolation{
+			Invariant: InvariantCartReplay,
+			SessionID: response.SessionID,
+			Details:   fmt.Sprintf("final cart %s, replay in isolation gives %s", actual, describeCart(expected)),
+		})
+	}
+
+	return violations
+}
+
+// replayCart re-executes a run's tool calls against fresh, unshared services and
+// returns the resulting cart
+func replayCart(testCase models.TestCase, response *models.ChatResponse) (*models.CartSummary, error) {
+	cartService := NewCartService()
+	executor := NewToolExecutor(NewProductService(), cartService)
+
+	if err := cartService.InitializeCartState(response.SessionID, testCase.InitialCartState); err != nil {
+		return nil, fmt.Errorf("failed to initialize cart state: %w", err)
+	}
+
+	for _, toolCall := range response.ToolCalls {
+		executor.executeToolCall(context.Background(), openai.ChatCompletionMessageToolCall{
+			ID: toolCall.CallID,
+			Function: openai.ChatCompletionMessageToolCallFunction{
+				Name:      toolCall.ToolName,
+				Arguments: toolCall.Arguments,
+			},
+		}, response.SessionID)
+	}
+
+	return cartService.GetCartSummary(response.SessionID), nil
+}
+
+// describeCart renders a cart's contents in a stable, comparable form
+func describeCart(cart *models.CartSummary) string {
+	if cart == nil || len(cart.Items) == 0 {
+		return "[]"
+	}
+
+	items := make([]string, 0, len(cart.Items))
+	for _, item := range cart.Items {
+		items = append(items, fmt.Sprintf("%s x%d", item.ProductName, item.Quantity))
+	}
+	sort.Strings(items)
+
+	return "[" + strings.Join(items, ", ") + "]"
+}
+
+// extractOrderIDs collects the order IDs returned by checkout and create_order calls
+func extractOrderIDs(toolCalls []models.ToolCallResult) []string {
+	var orderIDs []string
+
+	for _, toolCall := range toolCalls {
+		if !toolCall.Success || (toolCall.ToolName != "checkout" && toolCall.ToolName != "create_order") {
+			continue
+		}
+
+		data, err := json.Marshal(toolCall.Result)
+		if err != nil {
+			continue
+		}
+
+		var order struct {
+			OrderID string `json:"order_id"`
+		}
+		if err := json.Unmarshal(data, &order); err == nil && order.OrderID != "" {
+			orderIDs = append(orderIDs, order.OrderID)
+		}
+	}
+
+	return orderIDs
+}
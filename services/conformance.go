@@ -0,0 +1,201 @@
+package services
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"model-test/models"
+)
+
+// conformanceFiles holds the conformance suite: its test cases, the mock provider's
+// script answering them, and the golden metrics a correct installation reproduces.
+// After changing the cases or the script, check each test's outcome in the saved
+// conformance report by hand and copy its actual metrics into expected.json.
+//
+//go:embed conformance/*.json
+var conformanceFiles embed.FS
+
+// ConformanceReferenceTime is the clock the conformance suite runs against, so its
+// relative dates resolve the same everywhere (a Wednesday)
+var ConformanceReferenceTime = time.Date(2025, time.January, 15, 12, 0, 0, 0, time.UTC)
+
+// ConformanceModel is the model name the conformance suite requests from the mock provider
+const ConformanceModel = "conformance-mock"
+
+// RunConformance runs the built-in conformance suite against the scripted mock
+// provider, scoring it with the given unit table and match policy, and compares the
+// metrics with the golden ones. Any difference means the installation, or a custom
+// evaluator setting, scores the same transcripts differently than expected.
+func RunConformance(ctx context.Context, unitTable *UnitTable, matchPolicy models.StringMatchPolicy) (*models.ConformanceReport, error) {
+	var testCases []models.TestCase
+	if err := readConformanceFile("test_cases.json", &testCases); err != nil {
+		return nil, err
+	}
+	var script models.MockScript
+	if err := readConformanceFile("script.json", &script); err != nil {
+		return nil, err
+	}
+	var expected models.ConformanceMetrics
+	if err := readConformanceFile("expected.json", &expected); err != nil {
+		return nil, err
+	}
+
+	mock, err := StartMockProvider(script)
+	if err != nil {
+		return nil, err
+	}
+	defer mock.Close()
+
+	runner := NewTestRunner("conformance", mock.BaseURL(), ConformanceModel)
+	runner.SetReferenceTime(ConformanceReferenceTime)
+	runner.SetUnitTable(unitTable)
+	runner.SetMatchPolicy(matchPolicy)
+
+	agentReport, err := runner.RunAgentTestSuite(ctx, testCases)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run conformance suite: %w", err)
+	}
+
+	report := &models.ConformanceReport{
+		MatchPolicy: matchPolicy,
+		Expected:    expected,
+		Actual:      ConformanceMetricsFor(agentReport),
+	}
+	report.Mismatches = compareConformanceMetrics(report.Expected, report.Actual)
+	report.Passed = len(report.Mismatches) == 0 && !agentReport.Incomplete
+
+	return report, nil
+}
+
+// readConformanceFile parses one of the embedded conformance files
+func readConformanceFile(name string, v interface{}) error {
+	data, err := conformanceFiles.ReadFile("conformance/" + name)
+	if err != nil {
+		return fmt.Errorf("failed to read conformance %s: %w", name, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse conformance %s: %w", name, err)
+	}
+	return nil
+}
+
+// ConformanceMetricsFor extracts the deterministic metrics of a report, with its
+// tests ordered by name
+func ConformanceMetricsFor(report *models.AgentReport) models.ConformanceMetrics {
+	metrics := models.ConformanceMetrics{
+		TotalTests:             report.TotalTests,
+		PassedTests:            report.PassedTests,
+		FailedTests:            report.FailedTests,
+		InfrastructureFailures: report.InfrastructureFailures,
+		LLMRequests:            report.TotalLLMRequests,
+		FailureReasons:         make(map[models.FailureReason]int),
+	}
+	if report.TokenUsage != nil {
+		metrics.TokenUsage = *report.TokenUsage
+	}
+
+	for _, result := range report.Results {
+		test := models.ConformanceTest{
+			Name:          result.TestCase.Name,
+			Success:       result.Success,
+			MatchedPath:   result.MatchedPath,
+			FailureReason: result.FailureReason,
+		}
+		if result.Response != nil {
+			test.LLMRequests = result.Response.LLMRequests
+			for _, toolCall := range result.Response.ToolCalls {
+				test.ToolCalls = append(test.ToolCalls, toolCall.ToolName)
+			}
+		}
+		metrics.ToolCalls += len(test.ToolCalls)
+		if !result.Success {
+			metrics.FailureReasons[result.FailureReason]++
+		}
+		metrics.Tests = append(metrics.Tests, test)
+	}
+	sort.Slice(metrics.Tests, func(i, j int) bool {
+		return metrics.Tests[i].Name < metrics.Tests[j].Name
+	})
+
+	return metrics
+}
+
+// compareConformanceMetrics lists every metric that differs between the golden and
+// the actual metrics
+func compareConformanceMetrics(expected, actual models.ConformanceMetrics) []string {
+	var mismatches []string
+	check := func(name string, want, got interface{}) {
+		wantText, gotText := fmt.Sprint(want), fmt.Sprint(got)
+		if wantText == gotText {
+			return
+		}
+		if wantText == "" {
+			wantText = "none"
+		}
+		if gotText == "" {
+			gotText = "none"
+		}
+		mismatches = append(mismatches, fmt.Sprintf("%s: expected %s, got %s", name, wantText, gotText))
+	}
+
+	check("total_tests", expected.TotalTests, actual.TotalTests)
+	check("passed_tests", expected.PassedTests, actual.PassedTests)
+	check("failed_tests", expected.FailedTests, actual.FailedTests)
+	check("infrastructure_failures", expected.InfrastructureFailures, actual.InfrastructureFailures)
+	check("llm_requests", expected.LLMRequests, actual.LLMRequests)
+	check("tool_calls", expected.ToolCalls, actual.ToolCalls)
+	check("token_usage.input_tokens", expected.TokenUsage.InputTokens, actual.TokenUsage.InputTokens)
+	check("token_usage.output_tokens", expected.TokenUsage.OutputTokens, actual.TokenUsage.OutputTokens)
+	check("token_usage.total_tokens", expected.TokenUsage.TotalTokens, actual.TokenUsage.TotalTokens)
+
+	reasons := make(map[models.FailureReason]bool)
+	for reason := range expected.FailureReasons {
+		reasons[reason] = true
+	}
+	for reason := range actual.FailureReasons {
+		reasons[reason] = true
+	}
+	for _, reason := range sortedFailureReasons(reasons) {
+		check("failure_reasons."+string(reason), expected.FailureReasons[reason], actual.FailureReasons[reason])
+	}
+
+	actualTests := make(map[string]models.ConformanceTest)
+	for _, test := range actual.Tests {
+		actualTests[test.Name] = test
+	}
+	for _, want := range expected.Tests {
+		got, exists := actualTests[want.Name]
+		if !exists {
+			mismatches = append(mismatches, fmt.Sprintf("%s: missing from the run", want.Name))
+			continue
+		}
+		delete(actualTests, want.Name)
+		check(want.Name+".success", want.Success, got.Success)
+		check(want.Name+".matched_path", want.MatchedPath, got.MatchedPath)
+		check(want.Name+".failure_reason", want.FailureReason, got.FailureReason)
+		check(want.Name+".llm_requests", want.LLMRequests, got.LLMRequests)
+		check(want.Name+".tool_calls", strings.Join(want.ToolCalls, ","), strings.Join(got.ToolCalls, ","))
+	}
+	for _, test := range actual.Tests {
+		if _, unexpected := actualTests[test.Name]; unexpected {
+			mismatches = append(mismatches, fmt.Sprintf("%s: not in the golden metrics", test.Name))
+		}
+	}
+
+	return mismatches
+}
+
+// sortedFailureReasons returns the reasons of a set in order
+func sortedFailureReasons(reasons map[models.FailureReason]bool) []models.FailureReason {
+	sorted := make([]models.FailureReason, 0, len(reasons))
+	for reason := range reasons {
+		sorted = append(sorted, reason)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted
+}
@@ -0,0 +1,57 @@
+package services
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Connection is how requests reach a model server, besides its URL and API key
+type Connection struct {
+	Proxy   string            // Proxy setting (see ValidateProxy); empty follows the environment
+	Headers map[string]string // Static headers sent with every request, e.g. gateway routing or org IDs
+}
+
+// ValidateHeaders checks that header names are HTTP tokens and values fit on one line
+func ValidateHeaders(headers map[string]string) error {
+	for name, value := range headers {
+		if name == "" || strings.IndexFunc(name, func(r rune) bool {
+			return r <= ' ' || r >= 0x7f || strings.ContainsRune(`"(),/:;<=>?@[\]{}`, r)
+		}) >= 0 {
+			return fmt.Errorf("invalid header name '%s'", name)
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("header '%s' has a line break in its value", name)
+		}
+	}
+	return nil
+}
+
+// ParseHeader parses a "Name: value" header as given on the command line
+func ParseHeader(header string) (string, string, error) {
+	name, value, found := strings.Cut(header, ":")
+	if !found {
+		return "", "", fmt.Errorf("header '%s' is not in the form 'Name: value'", header)
+	}
+	name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+	if err := ValidateHeaders(map[string]string{name: value}); err != nil {
+		return "", "", err
+	}
+	return name, value, nil
+}
+
+// MergeHeaders returns the base headers with the overrides applied; names match
+// case-insensitively, as in HTTP
+func MergeHeaders(base, overrides map[string]string) map[string]string {
+	if len(overrides) == 0 {
+		return base
+	}
+	merged := make(map[string]string, len(base)+len(overrides))
+	for name, value := range base {
+		merged[http.CanonicalHeaderKey(name)] = value
+	}
+	for name, value := range overrides {
+		merged[http.CanonicalHeaderKey(name)] = value
+	}
+	return merged
+}
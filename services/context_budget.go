@@ -0,0 +1,61 @@
+package services
+
+import (
+	"encoding/json"
+
+	"github.com/openai/openai-go"
+
+	"model-test/models"
+)
+
+// SetContextWindow sets the model's context window in tokens. The agent loop stops
+// before a request whose prompt plus max_tokens would exceed it, instead of sending a
+// request the backend rejects; 0 disables the check.
+func (ai *OpenAIService) SetContextWindow(tokens int) {
+	ai.contextWindow = tokens
+}
+
+// checkContextBudget returns the overflow when the next request, with the messages so
+// far and maxTokens reserved for the completion, would not fit the context window
+func (ai *OpenAIService) checkContextBudget(messages []openai.ChatCompletionMessageParamUnion, iterations []models.IterationStats, maxTokens int) *models.ContextOverflow {
+	if ai.contextWindow <= 0 {
+		return nil
+	}
+
+	promptTokens := ai.nextPromptTokens(messages, iterations)
+	if promptTokens+int64(maxTokens) <= int64(ai.contextWindow) {
+		return nil
+	}
+	return &models.ContextOverflow{
+		Iteration:      len(iterations) + 1,
+		PromptTokens:   promptTokens,
+		ReservedTokens: int64(maxTokens),
+		ContextWindow:  ai.contextWindow,
+	}
+}
+
+// nextPromptTokens estimates the prompt of the next request. When the backend reported
+// the previous request's usage, the estimate grows that prompt by its completion and
+// the tool results added since, so only the new messages rely on the character
+// heuristic; otherwise the whole conversation and the tool definitions are estimated.
+func (ai *OpenAIService) nextPromptTokens(messages []openai.ChatCompletionMessageParamUnion, iterations []models.IterationStats) int64 {
+	if len(iterations) > 0 {
+		last := iterations[len(iterations)-1]
+		// The message after the last request's is the assistant turn it returned,
+		// already counted by its completion tokens
+		if !last.TokensEstimated && last.MessageCount+1 <= len(messages) {
+			return last.PromptTokens + last.CompletionTokens + estimatePromptTokens(messages[last.MessageCount+1:])
+		}
+	}
+	return estimatePromptTokens(messages) + ai.estimateToolTokens()
+}
+
+// estimateToolTokens approximates the token count of the tool definitions sent with
+// every request
+func (ai *OpenAIService) estimateToolTokens() int64 {
+	data, err := json.Marshal(ai.getToolDefinitions())
+	if err != nil {
+		return 0
+	}
+	return int64(len(data) / 4)
+}
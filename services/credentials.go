@@ -0,0 +1,96 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+
+	"model-test/models"
+)
+
+// LoadCredentials loads and validates a credentials file
+func LoadCredentials(filename string) ([]models.Credential, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credentials file: %w", err)
+	}
+
+	var config models.CredentialsConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse credentials: %w", err)
+	}
+
+	for i, credential := range config.Credentials {
+		if credential.Model == "" {
+			return nil, fmt.Errorf("credential %d has no model", i+1)
+		}
+		if _, err := path.Match(credential.Model, ""); err != nil {
+			return nil, fmt.Errorf("credential %d has an invalid model pattern '%s': %w", i+1, credential.Model, err)
+		}
+		sources := 0
+		for _, source := range []string{credential.APIKeyEnv, credential.APIKeyFile, credential.APIKeyCommand} {
+			if source != "" {
+				sources++
+			}
+		}
+		if sources != 1 {
+			return nil, fmt.Errorf("credential '%s' must set exactly one of api_key_env, api_key_file and api_key_command", credential.Model)
+		}
+	}
+
+	return config.Credentials, nil
+}
+
+// FindCredential returns the first credential whose model pattern matches the model
+func FindCredential(credentials []models.Credential, model string) (*models.Credential, bool) {
+	for i, credential := range credentials {
+		if matched, _ := path.Match(credential.Model, model); matched {
+			return &credentials[i], true
+		}
+	}
+	return nil, false
+}
+
+// ResolveCredential reads the API key a credential points to
+func ResolveCredential(credential models.Credential) (string, error) {
+	var apiKey string
+	var err error
+	switch {
+	case credential.APIKeyEnv != "":
+		apiKey = os.Getenv(credential.APIKeyEnv)
+		if apiKey == "" {
+			return "", fmt.Errorf("credential '%s' reads its API key from %s, which is not set", credential.Model, credential.APIKeyEnv)
+		}
+		RegisterSecret(apiKey)
+	case credential.APIKeyFile != "":
+		apiKey, err = ReadAPIKeyFile(credential.APIKeyFile)
+	default:
+		apiKey, err = RunAPIKeyCommand(credential.APIKeyCommand)
+	}
+	if err != nil {
+		return "", fmt.Errorf("credential '%s': %w", credential.Model, err)
+	}
+	return apiKey, nil
+}
+
+// ResolveAPIKey returns the API key for a model from the first matching credential,
+// falling back to apiKey when none matches
+func ResolveAPIKey(credentials []models.Credential, model, apiKey string) (string, error) {
+	credential, found := FindCredential(credentials, model)
+	if !found {
+		return apiKey, nil
+	}
+	return ResolveCredential(*credential)
+}
+
+// DescribeCredential names where a credential's API key comes from, for output
+func DescribeCredential(credential models.Credential) string {
+	switch {
+	case credential.APIKeyEnv != "":
+		return fmt.Sprintf("$%s (credential %s)", credential.APIKeyEnv, credential.Model)
+	case credential.APIKeyFile != "":
+		return fmt.Sprintf("%s (credential %s)", credential.APIKeyFile, credential.Model)
+	}
+	return fmt.Sprintf("command (credential %s)", credential.Model)
+}
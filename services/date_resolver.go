@@ -0,0 +1,91 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// relativeDatePrefix marks an expected argument value as a relative date expression
+// that is resolved against the reference clock, e.g. "@date:next friday"
+const relativeDatePrefix = "@date:"
+
+// ParseReferenceTime parses a reference clock value given as RFC3339 or YYYY-MM-DD.
+// An empty value returns the current time.
+func ParseReferenceTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Now(), nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+
+	return time.Time{}, fmt.Errorf("invalid reference time %q, expected RFC3339 or YYYY-MM-DD", value)
+}
+
+// ResolveRelativeDate resolves a relative date expression against the reference time.
+// Supported forms: today, tomorrow, yesterday, next week, this <weekday>,
+// next <weekday>, in N days, +Nd and -Nd.
+func ResolveRelativeDate(expression string, reference time.Time) (time.Time, error) {
+	expr := strings.ToLower(strings.TrimSpace(expression))
+	day := time.Date(reference.Year(), reference.Month(), reference.Day(), 0, 0, 0, 0, reference.Location())
+
+	switch expr {
+	case "today":
+		return day, nil
+	case "tomorrow":
+		return day.AddDate(0, 0, 1), nil
+	case "yesterday":
+		return day.AddDate(0, 0, -1), nil
+	case "next week":
+		return day.AddDate(0, 0, 7), nil
+	}
+
+	// Offsets such as +3d or -1d
+	if len(expr) > 2 && (expr[0] == '+' || expr[0] == '-') && strings.HasSuffix(expr, "d") {
+		days, err := strconv.Atoi(expr[:len(expr)-1])
+		if err == nil {
+			return day.AddDate(0, 0, days), nil
+		}
+	}
+
+	fields := strings.Fields(expr)
+
+	// "in N days"
+	if len(fields) == 3 && fields[0] == "in" && (fields[2] == "days" || fields[2] == "day") {
+		days, err := strconv.Atoi(fields[1])
+		if err == nil {
+			return day.AddDate(0, 0, days), nil
+		}
+	}
+
+	// "this <weekday>" is the next occurrence including today,
+	// "next <weekday>" is the next occurrence strictly after today
+	if len(fields) == 2 && (fields[0] == "this" || fields[0] == "next") {
+		if weekday, ok := parseWeekday(fields[1]); ok {
+			offset := (int(weekday) - int(day.Weekday()) + 7) % 7
+			if fields[0] == "next" && offset == 0 {
+				offset = 7
+			}
+			return day.AddDate(0, 0, offset), nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("unsupported relative date expression: %q", expression)
+}
+
+// parseWeekday converts an English weekday name to a time.Weekday
+func parseWeekday(name string) (time.Weekday, bool) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(d.String(), name) {
+			return d, true
+		}
+	}
+	return time.Sunday, false
+}
@@ -0,0 +1,182 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"model-test/models"
+)
+
+const (
+	watchdogProbeTimeout = 30 * time.Second
+	watchdogPollInterval = 10 * time.Second
+	watchdogMaxRequeues  = 3 // Reruns of one test before its failure is kept
+)
+
+// RedeployFunc redeploys the model under test and returns the base URL it is served on
+type RedeployFunc func(ctx context.Context) (string, error)
+
+// DeploymentWatchdog notices when the endpoint stops responding mid-run. A test that
+// fails against the server asks it whether to run again: the first one probes the
+// endpoint and, if it is down, waits for it to answer again or redeploys it while
+// the other affected tests wait. The tests are then rerun instead of being reported
+// as connection errors.
+type DeploymentWatchdog struct {
+	timeout  time.Duration // How long a recovery may take before the run stops
+	poll     time.Duration
+	redeploy RedeployFunc
+	service  *OpenAIService // Set by TestRunner.SetWatchdog
+
+	mutex      sync.Mutex
+	generation int           // Completed recoveries; a test started before the latest one is rerun without probing
+	recovering chan struct{} // Closed when the recovery in progress finishes
+	failed     bool          // A recovery gave up; no test is rerun or started any more
+	recoveries []models.DeploymentRecovery
+}
+
+// NewDeploymentWatchdog creates a watchdog that gives an unresponsive endpoint up to
+// timeout to answer again, redeploying it first when redeploy is set
+func NewDeploymentWatchdog(timeout time.Duration, redeploy RedeployFunc) *DeploymentWatchdog {
+	return &DeploymentWatchdog{timeout: timeout, poll: watchdogPollInterval, redeploy: redeploy}
+}
+
+// Generation returns the number of completed recoveries; tests record it when they start
+func (w *DeploymentWatchdog) Generation() int {
+	if w == nil {
+		return 0
+	}
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.generation
+}
+
+// Failed reports whether the endpoint did not recover, so no further tests should run
+func (w *DeploymentWatchdog) Failed() bool {
+	if w == nil {
+		return false
+	}
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.failed
+}
+
+// Recoveries returns the times the endpoint was found unresponsive
+func (w *DeploymentWatchdog) Recoveries() []models.DeploymentRecovery {
+	if w == nil {
+		return nil
+	}
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return append([]models.DeploymentRecovery(nil), w.recoveries...)
+}
+
+// Requeue reports whether a test that started at the given generation and failed
+// against the server should run again. It is false when the endpoint is healthy,
+// since the failure was then not the deployment's, and when it did not recover.
+func (w *DeploymentWatchdog) Requeue(ctx context.Context, started int) bool {
+	if w == nil {
+		return false
+	}
+
+	for {
+		w.mutex.Lock()
+		switch {
+		case w.failed:
+			w.mutex.Unlock()
+			return false
+		case w.generation > started:
+			w.recoveries[started].RequeuedTests++
+			w.mutex.Unlock()
+			return true
+		case w.recovering != nil:
+			done := w.recovering
+			w.mutex.Unlock()
+			select {
+			case <-done:
+				continue
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		done := make(chan struct{})
+		w.recovering = done
+		w.mutex.Unlock()
+
+		recovery := w.recover(ctx)
+
+		w.mutex.Lock()
+		w.recovering = nil
+		close(done)
+		if recovery == nil {
+			w.mutex.Unlock()
+			return false
+		}
+		w.recoveries = append(w.recoveries, *recovery)
+		if !recovery.Recovered {
+			w.failed = true
+		} else {
+			w.generation++
+		}
+		w.mutex.Unlock()
+	}
+}
+
+// recover probes the endpoint and, when it is down, redeploys it if configured and
+// waits for it to answer again. It returns nil when the endpoint is healthy.
+func (w *DeploymentWatchdog) recover(ctx context.Context) *models.DeploymentRecovery {
+	health := w.service.CheckHealth(ctx, watchdogProbeTimeout)
+	if health.Healthy() {
+		return nil
+	}
+
+	recovery := &models.DeploymentRecovery{
+		DetectedAt: time.Now(),
+		Endpoint:   health.Endpoint,
+		Health:     health.Error,
+		Action:     models.RecoveryWaited,
+	}
+	fmt.Printf("🚨 Endpoint %s stopped responding: %s\n", health.Endpoint, health.Error)
+
+	ctx, cancel := context.WithTimeout(ctx, w.timeout)
+	defer cancel()
+
+	if w.redeploy != nil {
+		recovery.Action = models.RecoveryRedeployed
+		fmt.Printf("🔁 Redeploying the model (up to %v); affected tests will be rerun\n", w.timeout)
+		baseURL, err := w.redeploy(ctx)
+		if err != nil {
+			recovery.Error = fmt.Sprintf("failed to redeploy: %v", err)
+			recovery.Duration = time.Since(recovery.DetectedAt)
+			fmt.Printf("❌ %s\n", recovery.Error)
+			return recovery
+		}
+		if baseURL != health.Endpoint {
+			w.service.SetBaseURL(baseURL)
+			recovery.NewEndpoint = baseURL
+		}
+	} else {
+		fmt.Printf("⏸️  Pausing affected tests until it answers again (up to %v)\n", w.timeout)
+	}
+
+	for {
+		health = w.service.CheckHealth(ctx, watchdogProbeTimeout)
+		if health.Healthy() {
+			recovery.Recovered = true
+			recovery.Duration = time.Since(recovery.DetectedAt)
+			fmt.Printf("✅ Endpoint %s answering again after %v\n", health.Endpoint, recovery.Duration.Round(time.Second))
+			return recovery
+		}
+
+		select {
+		case <-ctx.Done():
+			recovery.Error = fmt.Sprintf("endpoint still unhealthy after %v: %s", w.timeout, health.Error)
+			recovery.Duration = time.Since(recovery.DetectedAt)
+			fmt.Printf("❌ %s\n", recovery.Error)
+			return recovery
+		case <-time.After(w.poll):
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/openai/openai-go"
+
+	"model-test/models"
+)
+
+// CheckEndpointHealth probes an OpenAI-compatible endpoint: whether it answers at
+// all, accepts the API key, serves the model and how long a one-token completion
+// takes. The sample request is skipped when the endpoint is unreachable or rejects
+// the key. Requests go through the connection's proxy and carry its headers.
+func CheckEndpointHealth(ctx context.Context, apiKey, baseURL string, connection Connection, model string, timeout time.Duration) models.EndpointHealth {
+	health := models.EndpointHealth{Model: model, Endpoint: baseURL, CheckedAt: time.Now()}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	status, listed, err := listEndpointModels(ctx, apiKey, baseURL, connection)
+	if err != nil {
+		health.Error = err.Error()
+		return health
+	}
+	health.Reachable = true
+	if status == http.StatusUnauthorized || status == http.StatusForbidden {
+		health.Error = fmt.Sprintf("%s/models rejected the API key: %d %s", strings.TrimSuffix(baseURL, "/"), status, http.StatusText(status))
+		return health
+	}
+	health.AuthOK = true
+	for _, id := range listed {
+		if id == model {
+			health.ModelLoaded = true
+		}
+	}
+
+	client := newOpenAIClient(apiKey, baseURL, connection, nil)
+	start := time.Now()
+	_, err = client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
+		Model:     model,
+		Messages:  []openai.ChatCompletionMessageParamUnion{openai.UserMessage("ping")},
+		MaxTokens: openai.Int(1),
+	})
+	if err != nil {
+		var apiErr *openai.Error
+		if errors.As(err, &apiErr) && (apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden) {
+			health.AuthOK = false
+		}
+		health.Error = fmt.Sprintf("sample request failed: %v", err)
+		return health
+	}
+	health.SampleLatency = time.Since(start)
+	health.ModelLoaded = true
+
+	return health
+}
+
+// listEndpointModels requests the endpoint's /models list and returns the response
+// status with the listed model IDs. Not every server implements the list, so any
+// response counts as reachable and an unreadable body yields no IDs.
+func listEndpointModels(ctx context.Context, apiKey, baseURL string, connection Connection) (int, []string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(baseURL, "/")+"/models", nil)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to create models request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+	for name, value := range connection.Headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := endpointHTTPClient(baseURL, connection.Proxy).Do(req)
+	if err != nil {
+		return 0, nil, fmt.Errorf("endpoint unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var page struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if resp.StatusCode != http.StatusOK || json.NewDecoder(resp.Body).Decode(&page) != nil {
+		return resp.StatusCode, nil, nil
+	}
+
+	ids := make([]string, 0, len(page.Data))
+	for _, listed := range page.Data {
+		ids = append(ids, listed.ID)
+	}
+	return resp.StatusCode, ids, nil
+}
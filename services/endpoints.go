@@ -0,0 +1,124 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+
+	"model-test/models"
+)
+
+// endpointNamePattern is what endpoint names may contain, since they are used in file names
+var endpointNamePattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// LoadEndpoints loads and validates an endpoints file
+func LoadEndpoints(filename string) ([]models.Endpoint, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read endpoints file: %w", err)
+	}
+
+	var config models.EndpointConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse endpoints: %w", err)
+	}
+	if len(config.Endpoints) == 0 {
+		return nil, fmt.Errorf("no endpoints in %s", filename)
+	}
+
+	for i, endpoint := range config.Endpoints {
+		if endpoint.BaseURL == "" {
+			return nil, fmt.Errorf("endpoint %d has no base_url", i+1)
+		}
+		if endpoint.APIKey != "" && endpoint.APIKeyEnv != "" {
+			return nil, fmt.Errorf("endpoint %d must set at most one of api_key and api_key_env", i+1)
+		}
+		if err := ValidateProxy(endpoint.Proxy); err != nil {
+			return nil, fmt.Errorf("endpoint %d: %w", i+1, err)
+		}
+		if err := ValidateHeaders(endpoint.Headers); err != nil {
+			return nil, fmt.Errorf("endpoint %d: %w", i+1, err)
+		}
+	}
+
+	return config.Endpoints, nil
+}
+
+// ExpandEndpoints pairs every endpoint without a model with each of the model names
+// (or the default model when there are none), names unnamed endpoints after their
+// host and port, and resolves their API keys: the endpoint's own, then the first
+// credential matching the model, then apiKey. Each endpoint-model pair may appear only
+// once.
+func ExpandEndpoints(endpoints []models.Endpoint, modelNames []string, apiKey string, credentials []models.Credential) ([]models.Endpoint, error) {
+	if len(modelNames) == 0 {
+		modelNames = []string{""}
+	}
+
+	var expanded []models.Endpoint
+	names := make(map[string]string) // Base URL -> default name
+	taken := make(map[string]bool)   // Default names already used by another base URL
+	pairs := make(map[string]bool)
+	for _, endpoint := range endpoints {
+		if endpoint.Name == "" {
+			name, ok := names[endpoint.BaseURL]
+			if !ok {
+				name = defaultEndpointName(endpoint.BaseURL)
+				for i := 2; taken[name]; i++ {
+					name = fmt.Sprintf("%s-%d", defaultEndpointName(endpoint.BaseURL), i)
+				}
+				names[endpoint.BaseURL] = name
+				taken[name] = true
+			}
+			endpoint.Name = name
+		}
+		if !endpointNamePattern.MatchString(endpoint.Name) {
+			return nil, fmt.Errorf("invalid endpoint name '%s': use letters, digits, '.', '_' and '-'", endpoint.Name)
+		}
+
+		if endpoint.APIKeyEnv != "" {
+			endpoint.APIKey = os.Getenv(endpoint.APIKeyEnv)
+			if endpoint.APIKey == "" {
+				return nil, fmt.Errorf("endpoint '%s' reads its API key from %s, which is not set", endpoint.Name, endpoint.APIKeyEnv)
+			}
+			RegisterSecret(endpoint.APIKey)
+		}
+		endpointModels := modelNames
+		if endpoint.Model != "" {
+			endpointModels = []string{endpoint.Model}
+		}
+		for _, model := range endpointModels {
+			pair := endpoint
+			pair.Model = model
+			key := pair.Name + "\x00" + model
+			if pairs[key] {
+				return nil, fmt.Errorf("endpoint '%s' is listed more than once for model '%s'", pair.Name, model)
+			}
+			pairs[key] = true
+			if pair.APIKey == "" {
+				resolved, err := ResolveAPIKey(credentials, model, apiKey)
+				if err != nil {
+					return nil, fmt.Errorf("endpoint '%s': %w", pair.Name, err)
+				}
+				pair.APIKey = resolved
+			}
+			expanded = append(expanded, pair)
+		}
+	}
+
+	return expanded, nil
+}
+
+// defaultEndpointName names an endpoint after its host and port, e.g. localhost-8000
+func defaultEndpointName(baseURL string) string {
+	name := baseURL
+	if parsed, err := url.Parse(baseURL); err == nil && parsed.Host != "" {
+		name = parsed.Host
+	}
+	name = regexp.MustCompile(`[^A-Za-z0-9._-]+`).ReplaceAllString(name, "-")
+	if name == "" || name == "-" {
+		return "endpoint"
+	}
+	return name
+}
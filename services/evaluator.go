@@ -0,0 +1,547 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"model-test/models"
+)
+
+// Evaluator decides whether an agent response matches a test case's expected tool calls
+type Evaluator struct {
+	referenceTime time.Time
+	unitTable     *UnitTable
+	matchPolicy   models.StringMatchPolicy
+	adjudications map[string]models.Adjudication // Human decisions on borderline results, by review key
+	scoring       models.ScoringMode             // Partial also gives each result a partial-credit score
+	toolAliases   models.ToolAliases             // Equivalent tool names, compared by their canonical name
+	mode          models.EvaluationMode          // Workflow also requires the tool calls to have worked
+	scorers       []Scorer                       // Chain deciding the outcome; nil for the matcher alone
+}
+
+// NewEvaluator creates an evaluator with the default matching rules
+func NewEvaluator() *Evaluator {
+	return &Evaluator{
+		referenceTime: time.Now(),
+		unitTable:     DefaultUnitTable(),
+		matchPolicy:   DefaultStringMatchPolicy(),
+		scoring:       models.ScoringBinary,
+		mode:          models.EvaluationCalls,
+	}
+}
+
+// SetReferenceTime sets the clock used to resolve relative date expressions
+func (ev *Evaluator) SetReferenceTime(referenceTime time.Time) {
+	ev.referenceTime = referenceTime
+}
+
+// SetUnitTable sets the conversion tables used to normalize quantities with units
+func (ev *Evaluator) SetUnitTable(unitTable *UnitTable) {
+	ev.unitTable = unitTable
+}
+
+// SetMatchPolicy sets the global string comparison policy; test cases may override
+// it per argument
+func (ev *Evaluator) SetMatchPolicy(policy models.StringMatchPolicy) {
+	ev.matchPolicy = policy
+}
+
+// SetAdjudications sets human decisions that override the outcome of borderline
+// results with the same test case and tool calls
+func (ev *Evaluator) SetAdjudications(adjudications map[string]models.Adjudication) {
+	ev.adjudications = adjudications
+}
+
+// SetScoringMode sets whether results also get a partial-credit score
+func (ev *Evaluator) SetScoringMode(mode models.ScoringMode) {
+	ev.scoring = mode
+}
+
+// SetEvaluationMode sets whether the tool calls must also have worked for a test to pass
+func (ev *Evaluator) SetEvaluationMode(mode models.EvaluationMode) {
+	ev.mode = mode
+}
+
+// EvaluationMode returns whether the tool calls must also have worked for a test to pass
+func (ev *Evaluator) EvaluationMode() models.EvaluationMode {
+	return ev.mode
+}
+
+// SetToolAliases sets the equivalent tool names that match each other
+func (ev *Evaluator) SetToolAliases(aliases models.ToolAliases) {
+	ev.toolAliases = aliases
+}
+
+// ToolAliases returns the equivalent tool names that match each other
+func (ev *Evaluator) ToolAliases() models.ToolAliases {
+	return ev.toolAliases
+}
+
+// ScoringMode returns whether results also get a partial-credit score
+func (ev *Evaluator) ScoringMode() models.ScoringMode {
+	return ev.scoring
+}
+
+// MatchPolicy returns the global string comparison policy
+func (ev *Evaluator) MatchPolicy() models.StringMatchPolicy {
+	return ev.matchPolicy
+}
+
+// ReferenceTime returns the clock used to resolve relative date expressions
+func (ev *Evaluator) ReferenceTime() time.Time {
+	return ev.referenceTime
+}
+
+// Evaluate re-scores a stored result against its test case, keeping the recorded
+// response. Results without a response (API or setup failures) are returned unchanged.
+func (ev *Evaluator) Evaluate(result models.AgentTestResult) models.AgentTestResult {
+	if result.Response == nil {
+		return result
+	}
+
+	evaluation := ev.evaluateAgentResponse(result.TestCase, result.Response)
+	result.Success = evaluation.success
+	result.MatchedPath = evaluation.matchedPath
+	result.FailureReason = evaluation.failureReason
+	result.FailureDetails = evaluation.failureDetails
+	result.Adjudication = evaluation.adjudication
+	result.PartialScore = evaluation.partialScore
+	result.CallEfficiency = evaluation.callEfficiency
+	result.RecoveredToolErrors = evaluation.recoveredToolErrors
+	result.Scorers = evaluation.scorerVerdicts
+	return result
+}
+
+// evaluation holds the outcome of evaluating an agent response
+type evaluation struct {
+	success        bool
+	matchedPath    string
+	failureReason  models.FailureReason
+	failureDetails string
+	adjudication   *models.Adjudication
+	partialScore   *models.PartialScore
+	callEfficiency *models.CallEfficiency
+
+	recoveredToolErrors int                    // Workflow mode only
+	scorerVerdicts      []models.ScorerVerdict // Only when a scorer chain is set
+}
+
+// evaluateAgentResponse decides a response with the exact-path matcher or the scorer
+// chain, letting a human adjudication of a failed response decide its outcome, scores
+// partial credit in the partial scoring mode, and measures call efficiency
+func (ev *Evaluator) evaluateAgentResponse(testCase models.TestCase, response *models.ChatResponse) evaluation {
+	result := ev.matchExpected(testCase, response)
+	if len(ev.scorers) > 0 {
+		result = ev.runScorers(testCase, response, result)
+	}
+	if !result.success && len(ev.adjudications) > 0 {
+		if adjudication, decided := ev.adjudications[ReviewKey(testCase, response)]; decided {
+			result.adjudication = &adjudication
+			result.success = adjudication.Verdict == models.VerdictPass
+		}
+	}
+
+	if ev.scoring == models.ScoringPartial {
+		actualTools, _ := ev.actualToolCalls(response)
+		result.partialScore = ev.scorePartialCredit(canonicalTestCase(testCase, ev.toolAliases), actualTools, result)
+	}
+	result.callEfficiency = MeasureCallEfficiency(testCase, response)
+	return result
+}
+
+// matchExpected checks if the agent response matches expected tool calls, in the
+// workflow mode that they worked, and that the final reply passes its expected_response
+// check
+func (ev *Evaluator) matchExpected(testCase models.TestCase, response *models.ChatResponse) evaluation {
+	var result evaluation
+	if ev.mode == models.EvaluationWorkflow {
+		check := ev.checkWorkflow(response)
+		result = ev.matchAgentResponse(testCase, check.response)
+		ev.applyWorkflow(&result, testCase, check)
+		result.recoveredToolErrors = check.recovered
+	} else {
+		result = ev.matchAgentResponse(testCase, response)
+	}
+	if result.success && testCase.ExpectedResponse != nil {
+		if details, ok := checkExpectedResponse(*testCase.ExpectedResponse, response.Message); !ok {
+			result.success = false
+			result.failureReason = models.FailureResponseMismatch
+			result.failureDetails = details
+		}
+	}
+	return result
+}
+
+// matchAgentResponse checks if the agent response matches expected tool calls,
+// comparing tools by their canonical names
+func (ev *Evaluator) matchAgentResponse(testCase models.TestCase, response *models.ChatResponse) evaluation {
+	testCase = canonicalTestCase(testCase, ev.toolAliases)
+	actualTools, turns := ev.actualToolCalls(response)
+
+	// Calling a forbidden tool fails the test regardless of the path taken
+	if name, forbidden := findForbiddenTool(testCase, response, ev.toolAliases); forbidden {
+		return evaluation{
+			failureReason:  models.FailureForbiddenTool,
+			failureDetails: fmt.Sprintf("called forbidden tool %s", name),
+		}
+	}
+
+	// A conversation cut short by the context window fails even if its calls so far
+	// match, since the model never got to finish
+	if overflow := response.ContextExceeded; overflow != nil {
+		return evaluation{
+			failureReason: models.FailureContextExceeded,
+			failureDetails: fmt.Sprintf("request %d would need ~%d prompt tokens plus %d for the completion, over the %d-token context window",
+				overflow.Iteration, overflow.PromptTokens, overflow.ReservedTokens, overflow.ContextWindow),
+		}
+	}
+
+	if len(testCase.ExpectedToolVariants) == 0 {
+		// No expected tools - success if no tools were called
+		if len(response.ToolCalls) == 0 {
+			return evaluation{success: true, matchedPath: "no_tools_expected"}
+		}
+		return evaluation{
+			matchedPath:    "no_tools_expected",
+			failureReason:  models.FailureExtraTool,
+			failureDetails: fmt.Sprintf("expected no tool calls, got %s", formatToolNames(actualToolNames(actualTools))),
+		}
+	}
+
+	// Check all variants to find a match
+	for _, variant := range testCase.ExpectedToolVariants {
+		if ev.isPathSuccessful(variant, actualTools, turns) {
+			return evaluation{success: true, matchedPath: variant.Name}
+		}
+	}
+
+	reason, details := ev.classifyMismatch(testCase, response, actualTools)
+	return evaluation{failureReason: reason, failureDetails: details}
+}
+
+// actualToolCalls extracts the response's tool calls under their canonical names with
+// parsed arguments, and the turn that requested each
+func (ev *Evaluator) actualToolCalls(response *models.ChatResponse) ([]models.ActualToolCall, []int) {
+	actualTools := make([]models.ActualToolCall, len(response.ToolCalls))
+	turns := make([]int, len(response.ToolCalls))
+	for i, toolResult := range response.ToolCalls {
+		actualTools[i] = models.ActualToolCall{
+			Name:      ev.toolAliases.Canonical(toolResult.ToolName),
+			Arguments: ev.parseArguments(toolResult.Arguments),
+		}
+		turns[i] = toolResult.Iteration
+	}
+	return actualTools, turns
+}
+
+// findForbiddenTool reports the first call to a tool the test forbids or the executor does not know
+func findForbiddenTool(testCase models.TestCase, response *models.ChatResponse, aliases models.ToolAliases) (string, bool) {
+	for _, toolCall := range response.ToolCalls {
+		if strings.HasPrefix(toolCall.Error, "Unknown tool") {
+			return toolCall.ToolName, true
+		}
+		for _, forbidden := range testCase.ForbiddenTools {
+			if aliases.Canonical(toolCall.ToolName) == forbidden {
+				return toolCall.ToolName, true
+			}
+		}
+	}
+	return "", false
+}
+
+// classifyMismatch explains why no expected variant matched, comparing against the closest variant
+func (ev *Evaluator) classifyMismatch(testCase models.TestCase, response *models.ChatResponse, actualTools []models.ActualToolCall) (models.FailureReason, string) {
+	if response.LoopDetected {
+		return models.FailureLoopDetected, fmt.Sprintf("repeated %s with identical arguments in consecutive turns, stopped after %d LLM requests",
+			formatToolNames(lastTurnToolNames(response)), response.LLMRequests)
+	}
+	if response.MaxIterationsReached {
+		return models.FailureMaxIterations, fmt.Sprintf("stopped after %d LLM requests with %d tool calls", response.LLMRequests, len(actualTools))
+	}
+
+	for i, toolCall := range response.ToolCalls {
+		if len(toolCall.EnumViolations) > 0 {
+			return models.FailureSchemaViolation, fmt.Sprintf("%s: value outside enum: %s", toolCall.ToolName, strings.Join(toolCall.EnumViolations, ", "))
+		}
+		if _, invalid := actualTools[i].Arguments["_parse_error"]; invalid {
+			return models.FailureSchemaViolation, fmt.Sprintf("%s: arguments are not valid JSON", toolCall.ToolName)
+		}
+	}
+
+	actualNames := actualToolNames(actualTools)
+	sortedActualNames := append([]string(nil), actualNames...)
+	sort.Strings(sortedActualNames)
+
+	// Rank the possible explanations from most to least specific
+	rank := map[models.FailureReason]int{
+		models.FailureBadArguments: 0,
+		models.FailureMissingTool:  1,
+		models.FailureExtraTool:    1,
+		models.FailureWrongTool:    2,
+	}
+
+	bestReason := models.FailureWrongTool
+	bestVariant := testCase.ExpectedToolVariants[0]
+	for _, variant := range testCase.ExpectedToolVariants {
+		expectedNames := make([]string, len(variant.Tools))
+		for i, tool := range variant.Tools {
+			expectedNames[i] = tool.Name
+		}
+
+		// Without an order, the calls compare as sorted multisets
+		names := actualNames
+		if variant.Order == models.ToolOrderAny {
+			sort.Strings(expectedNames)
+			names = sortedActualNames
+		}
+
+		reason := models.FailureWrongTool
+		switch {
+		case equalNames(expectedNames, names):
+			reason = models.FailureBadArguments
+		case len(names) < len(expectedNames) && isSubsequence(names, expectedNames):
+			reason = models.FailureMissingTool
+		case len(names) > len(expectedNames) && isSubsequence(expectedNames, names):
+			reason = models.FailureExtraTool
+		}
+
+		if rank[reason] < rank[bestReason] {
+			bestReason = reason
+			bestVariant = variant
+		}
+	}
+
+	expectedNames := make([]string, len(bestVariant.Tools))
+	for i, tool := range bestVariant.Tools {
+		expectedNames[i] = tool.Name
+	}
+
+	return bestReason, fmt.Sprintf("closest variant %s expected %s, got %s",
+		bestVariant.Name, formatToolNames(expectedNames), formatToolNames(actualNames))
+}
+
+// actualToolNames returns the names of the actual tool calls in order
+func actualToolNames(actualTools []models.ActualToolCall) []string {
+	names := make([]string, len(actualTools))
+	for i, tool := range actualTools {
+		names[i] = tool.Name
+	}
+	return names
+}
+
+// lastTurnToolNames returns the names of the tools called in the response's last turn
+func lastTurnToolNames(response *models.ChatResponse) []string {
+	var names []string
+	last := 0
+	for _, toolCall := range response.ToolCalls {
+		if toolCall.Iteration > last {
+			last, names = toolCall.Iteration, nil
+		}
+		if toolCall.Iteration == last {
+			names = append(names, toolCall.ToolName)
+		}
+	}
+	return names
+}
+
+// formatToolNames renders a tool name sequence for failure details
+func formatToolNames(names []string) string {
+	return "[" + strings.Join(names, ", ") + "]"
+}
+
+// equalNames reports whether two tool name sequences are identical
+func equalNames(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// isSubsequence reports whether sub appears in seq in order, not necessarily contiguously
+func isSubsequence(sub, seq []string) bool {
+	j := 0
+	for i := 0; i < len(seq) && j < len(sub); i++ {
+		if seq[i] == sub[j] {
+			j++
+		}
+	}
+	return j == len(sub)
+}
+
+// parseArguments parses the arguments string into a map
+func (ev *Evaluator) parseArguments(arguments string) map[string]interface{} {
+	var args map[string]interface{}
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+		// If parsing fails, return the raw string
+		return map[string]interface{}{
+			"_raw_arguments": arguments,
+			"_parse_error":   err.Error(),
+		}
+	}
+	return args
+}
+
+// isPathSuccessful checks if actual tool calls match a specific expected path. Calls
+// requested together in one turn (the same non-zero turn) may match the expected calls
+// at their positions in any order, since the model issued them in parallel; a path
+// with order any matches its calls in any order at all.
+func (ev *Evaluator) isPathSuccessful(path models.ExpectedToolPath, actual []models.ActualToolCall, turns []int) bool {
+	expected := path.Tools
+
+	// First check: exact count match
+	if len(actual) != len(expected) {
+		return false
+	}
+
+	if path.Order == models.ToolOrderAny {
+		return ev.matchUnordered(expected, actual, make([]bool, len(actual)))
+	}
+
+	// Second check: all expected tools must be called correctly in order, turn by turn
+	for start := 0; start < len(actual); {
+		end := start + 1
+		for end < len(actual) && turns[start] != 0 && turns[end] == turns[start] {
+			end++
+		}
+		if !ev.matchUnordered(expected[start:end], actual[start:end], make([]bool, end-start)) {
+			return false
+		}
+		start = end
+	}
+
+	return true
+}
+
+// matchUnordered reports whether every expected call matches a distinct actual call
+// not yet used, trying each assignment in turn
+func (ev *Evaluator) matchUnordered(expected []models.ExpectedToolCall, actual []models.ActualToolCall, used []bool) bool {
+	if len(expected) == 0 {
+		return true
+	}
+	for i := range actual {
+		if used[i] || !ev.isToolCallCorrect(expected[0], actual[i]) {
+			continue
+		}
+		used[i] = true
+		if ev.matchUnordered(expected[1:], actual, used) {
+			return true
+		}
+		used[i] = false
+	}
+	return false
+}
+
+// isToolCallCorrect checks if an actual tool call matches an expected one
+func (ev *Evaluator) isToolCallCorrect(expected models.ExpectedToolCall, actual models.ActualToolCall) bool {
+	if expected.Name != actual.Name {
+		return false
+	}
+
+	passed, total := ev.argumentChecks(expected, actual)
+	return passed == total
+}
+
+// argumentChecks counts the expected call's argument checks against an actual call,
+// and how many passed: every required argument must be present and match, optional
+// arguments must match when present, and absent arguments must not be passed
+func (ev *Evaluator) argumentChecks(expected models.ExpectedToolCall, actual models.ActualToolCall) (int, int) {
+	passed, total := 0, 0
+	for key, expectedValue := range expected.Arguments {
+		total++
+		actualValue, exists := actual.Arguments[key]
+		if exists && ev.valuesMatch(expectedValue, actualValue, ev.matchPolicy.Merge(expected.MatchPolicy[key])) {
+			passed++
+		}
+	}
+	for key, expectedValue := range expected.OptionalArguments {
+		actualValue, exists := actual.Arguments[key]
+		if !exists {
+			continue
+		}
+		total++
+		if ev.valuesMatch(expectedValue, actualValue, ev.matchPolicy.Merge(expected.MatchPolicy[key])) {
+			passed++
+		}
+	}
+	for _, key := range expected.AbsentArguments {
+		total++
+		if _, exists := actual.Arguments[key]; !exists {
+			passed++
+		}
+	}
+	return passed, total
+}
+
+// valuesMatch compares an expected argument value against the actual one, recursing
+// into nested objects and arrays. Objects match when every expected key matches
+// (extra actual keys are ignored); arrays must match element by element.
+// Quantities with units ("2 kg" vs "2000 g") are normalized before comparison, and
+// matcher objects ($regex, $approx, $oneOf) are evaluated instead of compared.
+func (ev *Evaluator) valuesMatch(expected, actual interface{}, policy models.StringMatchPolicy) bool {
+	if matcher, operator, ok := asMatcher(expected); ok {
+		return ev.matcherMatches(matcher, operator, actual, policy)
+	}
+	if ev.unitTable != nil {
+		if equal, isQuantity := ev.unitTable.Equal(expected, actual); isQuantity {
+			return equal
+		}
+	}
+
+	switch exp := expected.(type) {
+	case map[string]interface{}:
+		act, ok := actual.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for key, expectedValue := range exp {
+			actualValue, exists := act[key]
+			if !exists || !ev.valuesMatch(expectedValue, actualValue, policy) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		act, ok := actual.([]interface{})
+		if !ok || len(act) != len(exp) {
+			return false
+		}
+		for i := range exp {
+			if !ev.valuesMatch(exp[i], act[i], policy) {
+				return false
+			}
+		}
+		return true
+	case string:
+		if strings.HasPrefix(exp, relativeDatePrefix) {
+			return ev.dateMatches(strings.TrimPrefix(exp, relativeDatePrefix), actual)
+		}
+		return stringsMatch(exp, fmt.Sprintf("%v", actual), policy)
+	default:
+		// Compare scalars by their string form under the match policy
+		return stringsMatch(fmt.Sprintf("%v", expected), fmt.Sprintf("%v", actual), policy)
+	}
+}
+
+// dateMatches resolves a relative date expression against the reference clock and
+// compares it with the actual argument, which may be a date or an RFC3339 timestamp
+func (ev *Evaluator) dateMatches(expression string, actual interface{}) bool {
+	expectedDate, err := ResolveRelativeDate(expression, ev.referenceTime)
+	if err != nil {
+		return false
+	}
+
+	actualString, ok := actual.(string)
+	if !ok || len(actualString) < len("2006-01-02") {
+		return false
+	}
+
+	return actualString[:len("2006-01-02")] == expectedDate.Format("2006-01-02")
+}
@@ -0,0 +1,190 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"model-test/models"
+	"model-test/tools"
+)
+
+// mutationFallbackTool replaces a call's tool when every catalog tool is expected somewhere
+const mutationFallbackTool = "mutated_tool"
+
+// mutant is a broken copy of a passing result's transcript
+type mutant struct {
+	kind        models.MutationKind
+	description string
+	response    *models.ChatResponse
+}
+
+// CheckMutations guards against matcher bugs that silently pass tests: when the
+// matcher passes the result, it scores mutants of the transcript that break the
+// matched path (a call's tool swapped, a checked argument dropped or changed, a call
+// dropped) and reports whether each was caught. Results that fail, or pass only
+// through a human adjudication, have no mutants.
+func (ev *Evaluator) CheckMutations(result models.AgentTestResult) []models.MutationOutcome {
+	// Mutants are generated from canonical names so a swapped tool is never an alias
+	result = CanonicalizeToolNames(result, ev.toolAliases)
+	original := ev.Evaluate(result)
+	if !original.Success || original.Adjudication != nil {
+		return nil
+	}
+
+	var outcomes []models.MutationOutcome
+	for _, m := range generateMutants(original.TestCase, original.MatchedPath, original.Response, ev.toolAliases) {
+		mutated := result
+		mutated.Response = m.response
+		scored := ev.Evaluate(mutated)
+
+		outcome := models.MutationOutcome{
+			TestCase:    result.TestCase.Name,
+			Kind:        m.kind,
+			Description: m.description,
+			Caught:      !scored.Success,
+		}
+		if scored.Success {
+			outcome.MatchedPath = scored.MatchedPath
+			outcome.Equivalent = scored.MatchedPath != original.MatchedPath
+		} else {
+			outcome.Reason = scored.FailureReason
+		}
+		outcomes = append(outcomes, outcome)
+	}
+	return outcomes
+}
+
+// generateMutants breaks each call of a transcript that matched the named path
+func generateMutants(testCase models.TestCase, matchedPath string, response *models.ChatResponse, aliases models.ToolAliases) []mutant {
+	var path *models.ExpectedToolPath
+	for i := range testCase.ExpectedToolVariants {
+		if testCase.ExpectedToolVariants[i].Name == matchedPath {
+			path = &testCase.ExpectedToolVariants[i]
+		}
+	}
+	replacement := unexpectedTool(testCase, aliases)
+
+	var mutants []mutant
+	for i, call := range response.ToolCalls {
+		position := fmt.Sprintf("call %d", i+1)
+
+		swapped := cloneResponse(response)
+		swapped.ToolCalls[i].ToolName = replacement
+		mutants = append(mutants, mutant{
+			kind:        models.MutationSwapTool,
+			description: fmt.Sprintf("%s: %s -> %s", position, call.ToolName, replacement),
+			response:    swapped,
+		})
+
+		dropped := cloneResponse(response)
+		dropped.ToolCalls = append(dropped.ToolCalls[:i], dropped.ToolCalls[i+1:]...)
+		mutants = append(mutants, mutant{
+			kind:        models.MutationDropCall,
+			description: fmt.Sprintf("%s: %s removed", position, call.ToolName),
+			response:    dropped,
+		})
+
+		// Calls are matched in order, so the path's call at the same position is the one
+		// whose arguments were checked; parallel calls matched out of order are skipped
+		if path == nil || i >= len(path.Tools) || path.Tools[i].Name != call.ToolName {
+			continue
+		}
+		var arguments map[string]interface{}
+		if err := json.Unmarshal([]byte(call.Arguments), &arguments); err != nil {
+			continue
+		}
+		keys := make([]string, 0, len(path.Tools[i].Arguments))
+		for key := range path.Tools[i].Arguments {
+			if _, exists := arguments[key]; exists {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			if withoutKey, ok := mutateArguments(arguments, key, nil); ok {
+				mutated := cloneResponse(response)
+				mutated.ToolCalls[i].Arguments = withoutKey
+				mutants = append(mutants, mutant{
+					kind:        models.MutationDropArgument,
+					description: fmt.Sprintf("%s: %s without %s", position, call.ToolName, key),
+					response:    mutated,
+				})
+			}
+
+			changed := changeValue(arguments[key])
+			if withValue, ok := mutateArguments(arguments, key, changed); ok {
+				mutated := cloneResponse(response)
+				mutated.ToolCalls[i].Arguments = withValue
+				mutants = append(mutants, mutant{
+					kind:        models.MutationChangeArgument,
+					description: fmt.Sprintf("%s: %s with %s=%v", position, call.ToolName, key, changed),
+					response:    mutated,
+				})
+			}
+		}
+	}
+
+	return mutants
+}
+
+// unexpectedTool returns a catalog tool no expected path of the test case calls, so a
+// transcript calling it can never match
+func unexpectedTool(testCase models.TestCase, aliases models.ToolAliases) string {
+	expected := make(map[string]bool)
+	for _, variant := range testCase.ExpectedToolVariants {
+		for _, tool := range variant.Tools {
+			expected[tool.Name] = true
+		}
+	}
+	for _, definition := range tools.NewShoppingTools().GetToolDefinitions() {
+		if name := definition.Function.Name; !expected[aliases.Canonical(name)] {
+			return name
+		}
+	}
+	return mutationFallbackTool
+}
+
+// cloneResponse copies a response deeply enough that its tool calls can be mutated
+func cloneResponse(response *models.ChatResponse) *models.ChatResponse {
+	clone := *response
+	clone.ToolCalls = append([]models.ToolCallResult(nil), response.ToolCalls...)
+	return &clone
+}
+
+// mutateArguments returns the arguments as JSON with key removed (value nil) or set
+// to value
+func mutateArguments(arguments map[string]interface{}, key string, value interface{}) (string, bool) {
+	mutated := make(map[string]interface{}, len(arguments))
+	for k, v := range arguments {
+		mutated[k] = v
+	}
+	if value == nil {
+		delete(mutated, key)
+	} else {
+		mutated[key] = value
+	}
+
+	data, err := json.Marshal(mutated)
+	if err != nil {
+		return "", false
+	}
+	return string(data), true
+}
+
+// changeValue returns a value that no sensible expectation of the original matches:
+// strings get a prefix (a suffix would survive date matching on the leading date),
+// numbers are incremented, booleans negated, and anything else becomes a string
+func changeValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return "mutated " + v
+	case float64:
+		return v + 1
+	case bool:
+		return !v
+	default:
+		return "mutated"
+	}
+}
@@ -0,0 +1,176 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"model-test/models"
+)
+
+// eventHistoryLimit caps how many past events are replayed to late subscribers; once
+// it is reached the oldest events make way for new ones
+const eventHistoryLimit = 1000
+
+// eventSubscriberBuffer is how many events a slow subscriber may fall behind before
+// events are dropped for it; publishing never blocks the run
+const eventSubscriberBuffer = 256
+
+// EventBroadcaster fans run progress events out to Server-Sent Events subscribers.
+// A nil broadcaster is valid and discards events.
+type EventBroadcaster struct {
+	runID       string
+	subscribers map[chan models.RunEvent]struct{}
+	history     []models.RunEvent
+	oldest      int
+	mutex       sync.Mutex
+	done        chan struct{}
+	streams     sync.WaitGroup
+}
+
+// NewEventBroadcaster creates a broadcaster that stamps events with the run ID
+func NewEventBroadcaster(runID string) *EventBroadcaster {
+	return &EventBroadcaster{
+		runID:       runID,
+		subscribers: make(map[chan models.RunEvent]struct{}),
+		done:        make(chan struct{}),
+	}
+}
+
+// Close ends all streams once they have delivered the events already published,
+// waiting at most the given timeout
+func (eb *EventBroadcaster) Close(timeout time.Duration) {
+	if eb == nil {
+		return
+	}
+	close(eb.done)
+
+	finished := make(chan struct{})
+	go func() {
+		eb.streams.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+	case <-time.After(timeout):
+	}
+}
+
+// Publish sends an event to every subscriber and records it for late subscribers,
+// keeping the most recent eventHistoryLimit events
+func (eb *EventBroadcaster) Publish(event models.RunEvent) {
+	if eb == nil {
+		return
+	}
+
+	event.RunID = eb.runID
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
+	eb.mutex.Lock()
+	defer eb.mutex.Unlock()
+
+	if len(eb.history) < eventHistoryLimit {
+		eb.history = append(eb.history, event)
+	} else {
+		eb.history[eb.oldest] = event
+		eb.oldest = (eb.oldest + 1) % eventHistoryLimit
+	}
+
+	for subscriber := range eb.subscribers {
+		select {
+		case subscriber <- event:
+		default:
+			// Subscriber is too slow; drop the event rather than stall the run
+		}
+	}
+}
+
+// subscribe registers a subscriber and returns the recorded events, oldest first
+func (eb *EventBroadcaster) subscribe() (chan models.RunEvent, []models.RunEvent) {
+	eb.mutex.Lock()
+	defer eb.mutex.Unlock()
+
+	subscriber := make(chan models.RunEvent, eventSubscriberBuffer)
+	eb.subscribers[subscriber] = struct{}{}
+
+	history := make([]models.RunEvent, 0, len(eb.history))
+	history = append(history, eb.history[eb.oldest:]...)
+	history = append(history, eb.history[:eb.oldest]...)
+	return subscriber, history
+}
+
+// unsubscribe removes a subscriber
+func (eb *EventBroadcaster) unsubscribe(subscriber chan models.RunEvent) {
+	eb.mutex.Lock()
+	defer eb.mutex.Unlock()
+
+	delete(eb.subscribers, subscriber)
+}
+
+// ServeHTTP streams events as Server-Sent Events, replaying the run so far first
+func (eb *EventBroadcaster) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	eb.streams.Add(1)
+	defer eb.streams.Done()
+
+	subscriber, history := eb.subscribe()
+	defer eb.unsubscribe(subscriber)
+
+	for _, event := range history {
+		if err := writeServerSentEvent(w, event); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-eb.done:
+			// Deliver whatever is still buffered, then end the stream
+			for {
+				select {
+				case event := <-subscriber:
+					if err := writeServerSentEvent(w, event); err != nil {
+						return
+					}
+				default:
+					flusher.Flush()
+					return
+				}
+			}
+		case event := <-subscriber:
+			if err := writeServerSentEvent(w, event); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// writeServerSentEvent writes a single event in SSE wire format
+func writeServerSentEvent(w http.ResponseWriter, event models.RunEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, RedactSecrets(data))
+	return err
+}
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"model-test/models"
+)
+
+// ExperimentExporter logs a finished run's parameters and metrics to an external
+// experiment tracker
+type ExperimentExporter interface {
+	Name() string
+	Export(ctx context.Context, report *models.AgentReport, params map[string]string) error
+}
+
+// ReportMetrics flattens a report into the numeric metrics sent to experiment trackers
+func ReportMetrics(report *models.AgentReport) map[string]float64 {
+	metrics := map[string]float64{
+		"total_tests":            float64(report.TotalTests),
+		"passed_tests":           float64(report.PassedTests),
+		"failed_tests":           float64(report.FailedTests),
+		"total_llm_requests":     float64(report.TotalLLMRequests),
+		"average_time_s":         report.AverageTime.Seconds(),
+		"avg_time_per_request_s": report.AvgTimePerReq.Seconds(),
+		"total_llm_time_s":       report.TotalLLMTime.Seconds(),
+		"total_tool_time_s":      report.TotalToolTime.Seconds(),
+		"total_harness_time_s":   report.TotalOverhead.Seconds(),
+	}
+
+	if report.TotalTests > 0 {
+		metrics["success_rate"] = float64(report.PassedTests) / float64(report.TotalTests)
+	}
+
+	for _, result := range report.Results {
+		if result.FailureReason != "" {
+			metrics["failures_"+string(result.FailureReason)]++
+		}
+	}
+
+	if stats := report.RunStats; stats != nil {
+		metrics["runs"] = float64(stats.Runs)
+		metrics["pass_rate_mean"] = stats.PassRateMean
+		metrics["pass_rate_std_dev"] = stats.PassRateStdDev
+		metrics["flaky_tests"] = float64(stats.FlakyTests)
+	}
+	if len(report.SweepResults) > 0 {
+		metrics["sampling_configs"] = float64(len(report.SweepResults))
+	}
+	if usage := report.TokenUsage; usage != nil {
+		metrics["input_tokens"] = float64(usage.InputTokens)
+		metrics["output_tokens"] = float64(usage.OutputTokens)
+		metrics["total_tokens"] = float64(usage.TotalTokens)
+	}
+	if streaming := report.Streaming; streaming != nil {
+		metrics["time_to_first_token_s"] = streaming.TimeToFirstToken.Seconds()
+		metrics["avg_time_to_first_token_s"] = streaming.AvgTimeToFirstToken.Seconds()
+		metrics["tokens_per_second"] = streaming.TokensPerSecond
+	}
+
+	return metrics
+}
+
+// ReportParams collects the run parameters sent to experiment trackers, combining
+// the caller's parameters with what the report recorded about the run. Run tags are
+// left to each exporter, since trackers have their own notion of tags.
+func ReportParams(report *models.AgentReport, params map[string]string) map[string]string {
+	merged := make(map[string]string)
+	for key, value := range params {
+		merged[key] = value
+	}
+
+	merged["run_id"] = report.RunID
+	merged["schema_version"] = fmt.Sprintf("%d", report.SchemaVersion)
+	if !report.ReferenceTime.IsZero() {
+		merged["reference_time"] = report.ReferenceTime.Format(time.RFC3339)
+	}
+
+	return merged
+}
+
+// sendJSON sends a request with an optional JSON body and decodes the JSON
+// response into out (if not nil)
+func sendJSON(ctx context.Context, client *http.Client, method, url string, headers map[string]string, body interface{}, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return fmt.Errorf("failed to decode response: %w", err)
+		}
+	}
+
+	return nil
+}
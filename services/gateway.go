@@ -0,0 +1,61 @@
+package services
+
+import (
+	"net/http"
+	"strings"
+
+	"model-test/models"
+)
+
+// gatewayHeaderPrefixes are the response headers in which API gateways report how they
+// routed a request: LiteLLM's own and the provider headers it forwards, and Portkey's
+var gatewayHeaderPrefixes = []string{"x-litellm-", "llm_provider-", "x-portkey-"}
+
+// ParseGatewayModel splits a provider-prefixed model name such as bedrock/claude-3-5
+// into the provider and the provider's model name; a name without a prefix has no
+// provider
+func ParseGatewayModel(model string) (provider, providerModel string) {
+	provider, providerModel, found := strings.Cut(model, "/")
+	if !found || provider == "" {
+		return "", model
+	}
+	return provider, providerModel
+}
+
+// recordGatewayRoute adds the model a response reported and its gateway headers to the
+// session's route, creating the route for the requested model on the first response.
+// The response is nil when the request never got one.
+func recordGatewayRoute(session *models.ChatSession, requestedModel, servedModel string, response *http.Response) {
+	route := session.Gateway
+	if route == nil {
+		route = &models.GatewayRoute{}
+		route.Provider, route.ProviderModel = ParseGatewayModel(requestedModel)
+		session.Gateway = route
+	}
+
+	if servedModel != "" {
+		seen := false
+		for _, model := range route.ServedModels {
+			seen = seen || model == servedModel
+		}
+		if !seen {
+			route.ServedModels = append(route.ServedModels, servedModel)
+		}
+	}
+
+	if response == nil {
+		return
+	}
+	for name, values := range response.Header {
+		name = strings.ToLower(name)
+		for _, prefix := range gatewayHeaderPrefixes {
+			if strings.HasPrefix(name, prefix) && len(values) > 0 {
+				if route.Metadata == nil {
+					route.Metadata = make(map[string]string)
+				}
+				route.Metadata[name] = strings.Join(values, ", ")
+				break
+			}
+		}
+	}
+}
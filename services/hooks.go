@@ -0,0 +1,238 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"model-test/models"
+)
+
+const (
+	// defaultHookTimeout bounds hooks that do not set their own timeout
+	defaultHookTimeout = 60 * time.Second
+
+	// hookOutputLimit caps how much hook output is kept in the run manifest
+	hookOutputLimit = 64 * 1024
+)
+
+// LoadHookConfig loads and validates the pre/post hook definitions
+func LoadHookConfig(filename string) (*models.HookConfig, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read hooks file: %w", err)
+	}
+
+	var config models.HookConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse hooks: %w", err)
+	}
+
+	for _, stage := range []models.HookStage{models.HookPreModel, models.HookPreRun, models.HookPostRun, models.HookPostModel} {
+		if err := validateHooks(stage, HooksForStage(&config, stage)); err != nil {
+			return nil, err
+		}
+	}
+
+	return &config, nil
+}
+
+// validateHooks checks each hook of a stage is named, runs exactly one of a command
+// and a request, and has a valid timeout
+func validateHooks(stage models.HookStage, hooks []models.Hook) error {
+	for i, hook := range hooks {
+		if hook.Name == "" {
+			return fmt.Errorf("%s hook %d has no name", stage, i+1)
+		}
+		if (hook.Command == "") == (hook.URL == "") {
+			return fmt.Errorf("%s hook '%s' must set exactly one of command and url", stage, hook.Name)
+		}
+		if _, err := hookTimeout(hook); err != nil {
+			return fmt.Errorf("%s hook '%s': %w", stage, hook.Name, err)
+		}
+	}
+	return nil
+}
+
+// ParseHookStage validates a hook stage name
+func ParseHookStage(value string) (models.HookStage, error) {
+	switch stage := models.HookStage(value); stage {
+	case models.HookPreModel, models.HookPreRun, models.HookPostRun, models.HookPostModel:
+		return stage, nil
+	default:
+		return "", fmt.Errorf("unknown hook stage '%s' (expected pre_model, pre_run, post_run or post_model)", value)
+	}
+}
+
+// HooksForStage returns the hooks configured for a stage
+func HooksForStage(config *models.HookConfig, stage models.HookStage) []models.Hook {
+	if config == nil {
+		return nil
+	}
+	switch stage {
+	case models.HookPreModel:
+		return config.PreModel
+	case models.HookPreRun:
+		return config.PreRun
+	case models.HookPostRun:
+		return config.PostRun
+	case models.HookPostModel:
+		return config.PostModel
+	}
+	return nil
+}
+
+// RunHooks runs the hooks of a stage in order. Commands see the variables in env, plus
+// MODEL_TEST_STAGE, in their environment, and ${NAME} references to them are expanded
+// in HTTP URLs, headers and bodies. It stops at the first failing hook that does not
+// continue on error and returns the results so far along with an error naming it.
+func RunHooks(ctx context.Context, config *models.HookConfig, stage models.HookStage, env map[string]string) ([]models.HookResult, error) {
+	return RunHookList(ctx, HooksForStage(config, stage), stage, env)
+}
+
+// RunHookList runs the given hooks as a stage, like RunHooks
+func RunHookList(ctx context.Context, hooks []models.Hook, stage models.HookStage, env map[string]string) ([]models.HookResult, error) {
+	stageEnv := map[string]string{"MODEL_TEST_STAGE": string(stage)}
+	for key, value := range env {
+		stageEnv[key] = value
+	}
+	env = stageEnv
+
+	var results []models.HookResult
+	for _, hook := range hooks {
+		result := runHook(ctx, hook, stage, env)
+		results = append(results, result)
+		if !result.Success && !hook.ContinueOnError {
+			return results, fmt.Errorf("%s hook '%s' failed: %s", stage, hook.Name, result.Error)
+		}
+	}
+	return results, nil
+}
+
+// runHook runs a single hook and captures its outcome
+func runHook(ctx context.Context, hook models.Hook, stage models.HookStage, env map[string]string) models.HookResult {
+	result := models.HookResult{Stage: stage, Name: hook.Name, StartedAt: time.Now()}
+
+	timeout, _ := hookTimeout(hook)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if hook.Command != "" {
+		result.Kind = "command"
+		runCommandHook(ctx, hook, env, &result)
+	} else {
+		result.Kind = "http"
+		runHTTPHook(ctx, hook, env, &result)
+	}
+
+	result.Duration = time.Since(result.StartedAt)
+	return result
+}
+
+// runCommandHook runs a hook command through the shell
+func runCommandHook(ctx context.Context, hook models.Hook, env map[string]string, result *models.HookResult) {
+	var output bytes.Buffer
+	cmd := ShellCommand(ctx, hook.Command)
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	cmd.Env = os.Environ()
+	for key, value := range env {
+		cmd.Env = append(cmd.Env, key+"="+value)
+	}
+
+	err := cmd.Run()
+	result.Output, result.Truncated = truncateHookOutput(output.Bytes())
+
+	var exitErr *exec.ExitError
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		result.Error = "timed out"
+	case errors.As(err, &exitErr):
+		result.ExitCode = exitErr.ExitCode()
+		result.Error = fmt.Sprintf("exit status %d", result.ExitCode)
+	case err != nil:
+		result.Error = err.Error()
+	default:
+		result.Success = true
+	}
+}
+
+// runHTTPHook sends a hook's HTTP request; any 2xx response counts as success
+func runHTTPHook(ctx context.Context, hook models.Hook, env map[string]string, result *models.HookResult) {
+	method := hook.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, expandHookVariables(hook.URL, env), strings.NewReader(expandHookVariables(hook.Body, env)))
+	if err != nil {
+		result.Error = fmt.Sprintf("failed to create request: %v", err)
+		return
+	}
+	for key, value := range hook.Headers {
+		req.Header.Set(key, expandHookVariables(value, env))
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		result.Error = fmt.Sprintf("request failed: %v", err)
+		return
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, hookOutputLimit+1))
+	if err != nil {
+		result.Error = fmt.Sprintf("failed to read response: %v", err)
+		return
+	}
+	result.Output, result.Truncated = truncateHookOutput(body)
+	result.StatusCode = resp.StatusCode
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		result.Error = fmt.Sprintf("status %d", resp.StatusCode)
+		return
+	}
+	result.Success = true
+}
+
+// hookTimeout returns the hook's timeout, or the default when it sets none
+func hookTimeout(hook models.Hook) (time.Duration, error) {
+	if hook.Timeout == "" {
+		return defaultHookTimeout, nil
+	}
+	timeout, err := time.ParseDuration(hook.Timeout)
+	if err != nil {
+		return 0, fmt.Errorf("invalid timeout: %w", err)
+	}
+	if timeout <= 0 {
+		return 0, fmt.Errorf("timeout must be positive, got %v", timeout)
+	}
+	return timeout, nil
+}
+
+// expandHookVariables replaces ${NAME} references to hook variables, leaving any
+// other reference untouched
+func expandHookVariables(value string, env map[string]string) string {
+	return os.Expand(value, func(name string) string {
+		if replacement, ok := env[name]; ok {
+			return replacement
+		}
+		return "${" + name + "}"
+	})
+}
+
+// truncateHookOutput trims hook output to hookOutputLimit
+func truncateHookOutput(output []byte) (string, bool) {
+	if len(output) > hookOutputLimit {
+		return string(output[:hookOutputLimit]), true
+	}
+	return string(output), false
+}
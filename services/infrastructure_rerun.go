@@ -0,0 +1,135 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"model-test/models"
+)
+
+// RerunInfrastructureFailures reruns the tests of a finished run that failed against
+// the server (API errors and timeouts) rather than on the model's answer, and returns
+// the report with their new results merged in. The reruns use the run's own reference
+// time, match policy, scoring and evaluation modes and tool error verbosity so they are
+// scored like the originals; a scorer chain other than the run's is refused. Reruns
+// that are interrupted keep their previous result. The returned pass is nil when there
+// was nothing to rerun.
+func (tr *TestRunner) RerunInfrastructureFailures(ctx context.Context, original *models.AgentReport) (*models.AgentReport, *models.InfrastructureRerun, error) {
+	if original.Incomplete {
+		return nil, nil, fmt.Errorf("the run is incomplete (%d tests did not finish); finish it with -resume first", original.InterruptedTests)
+	}
+	if original.ToolSpecVersion != "" {
+		return nil, nil, fmt.Errorf("results of tool spec version %s cannot be rerun", original.ToolSpecVersion)
+	}
+
+	var failed []int
+	for i, result := range original.Results {
+		if result.ModelName != tr.getModelName() {
+			return nil, nil, fmt.Errorf("result for %s was produced by model %s, not %s", result.TestCase.Name, result.ModelName, tr.getModelName())
+		}
+		if !result.Success && failedAgainstServer(result) {
+			failed = append(failed, i)
+		}
+	}
+
+	if len(failed) == 0 {
+		return original, nil, nil
+	}
+
+	// Only the scorer names are recorded, so the chain cannot be restored from the report
+	if scorers := tr.evaluator.ScorerNames(); strings.Join(scorers, ",") != strings.Join(original.Scorers, ",") {
+		return nil, nil, fmt.Errorf("the run was scored by scorer chain [%s], not [%s]; rerun with the run's test config", strings.Join(original.Scorers, ", "), strings.Join(scorers, ", "))
+	}
+
+	if !original.ReferenceTime.IsZero() {
+		tr.SetReferenceTime(original.ReferenceTime)
+	}
+	tr.SetMatchPolicy(original.MatchPolicy)
+	// Files from before the modes were recorded were scored with the defaults
+	scoring, evaluation := original.Scoring, original.Evaluation
+	if scoring == "" {
+		scoring = models.ScoringBinary
+	}
+	if evaluation == "" {
+		evaluation = models.EvaluationCalls
+	}
+	tr.SetScoringMode(scoring)
+	tr.SetEvaluationMode(evaluation)
+	if original.ToolErrorVerbosity != "" {
+		tr.SetToolErrorVerbosity(original.ToolErrorVerbosity)
+	}
+
+	rerun := &models.InfrastructureRerun{
+		RunID:      tr.runID,
+		StartedAt:  time.Now(),
+		RerunTests: len(failed),
+	}
+	fmt.Printf("Rerunning %d of %d tests that failed against the server\n", len(failed), len(original.Results))
+
+	suiteHooks, err := tr.runSuiteHooks(ctx, models.HookSuiteSetup, tr.suiteSetup)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Each goroutine owns one slot, so results can be replaced without locking
+	results := append([]models.AgentTestResult(nil), original.Results...)
+	finished := make([]bool, len(results))
+
+	var wg sync.WaitGroup
+	for _, i := range failed {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if ctx.Err() != nil || tr.watchdog.Failed() {
+				return
+			}
+
+			previous := original.Results[i]
+			name := previous.TestCase.Name
+			if previous.Config.Label != "" {
+				name = fmt.Sprintf("%s [%s]", name, previous.Config.Label)
+			}
+			fmt.Printf("Rerunning agent test: %s\n", name)
+
+			result, done := tr.runWatchedTest(ctx, previous.TestCase, previous.Config, name)
+			if !done {
+				return
+			}
+			result.Run = previous.Run
+			result.Reruns = previous.Reruns + 1
+			results[i] = result
+			finished[i] = true
+		}(i)
+	}
+	wg.Wait()
+
+	teardown, err := tr.runSuiteHooks(context.WithoutCancel(ctx), models.HookSuiteTeardown, tr.suiteTeardown)
+	if err != nil {
+		fmt.Printf("⚠️  %v\n", err)
+	}
+	suiteHooks = append(suiteHooks, teardown...)
+
+	for _, i := range failed {
+		switch {
+		case !finished[i]:
+			rerun.Interrupted++
+		case results[i].Success:
+			rerun.Passed++
+		case failedAgainstServer(results[i]):
+			rerun.StillFailing++
+		default:
+			rerun.ModelFailed++
+		}
+	}
+	rerun.Duration = time.Since(rerun.StartedAt)
+
+	report := RebuildAgentReport(original, results, tr.evaluator)
+	report.DeploymentRecoveries = append(original.DeploymentRecoveries, tr.watchdog.Recoveries()...)
+	report.InfrastructureReruns = append(original.InfrastructureReruns, *rerun)
+	report.SuiteHooks = append(original.SuiteHooks, suiteHooks...)
+
+	return report, rerun, nil
+}
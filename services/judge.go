@@ -0,0 +1,225 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/openai/openai-go"
+
+	"model-test/models"
+)
+
+const (
+	// judgeTimeout bounds one judge request
+	judgeTimeout = 2 * time.Minute
+
+	// judgeToolResultLength caps how much of each tool result the judge is shown
+	judgeToolResultLength = 2000
+)
+
+// judgeSystemPrompt instructs the judge model how to score and answer
+var judgeSystemPrompt = fmt.Sprintf(`You are an impartial judge grading the final reply of a shopping assistant that uses tools to search products and manage a cart.
+Score the final reply on each criterion from 1 (poor) to %d (excellent), using the tool results in the conversation as the ground truth for products, prices and cart contents.
+Answer with JSON only, in this form:
+{"criteria": [{"name": "<criterion name>", "score": <1-%d>, "rationale": "<one or two sentences>"}], "rationale": "<overall assessment>"}`,
+	models.JudgeScaleMax, models.JudgeScaleMax)
+
+// Judge scores the final replies of tests against their rubrics with a judge model
+type Judge struct {
+	client openai.Client
+	model  string
+}
+
+// NewJudge creates a judge that sends its requests to the model at baseURL
+func NewJudge(apiKey, baseURL, model string, connection Connection) *Judge {
+	return &Judge{
+		client: newOpenAIClient(apiKey, baseURL, connection, nil),
+		model:  model,
+	}
+}
+
+// Model returns the name of the judge model
+func (j *Judge) Model() string {
+	return j.model
+}
+
+// Score asks the judge to grade a test's final reply against the test case's rubric,
+// given the conversation that led to it. A failed request or unparsable answer is
+// recorded in the score's Error rather than returned.
+func (j *Judge) Score(ctx context.Context, testCase models.TestCase, transcript []models.TranscriptMessage, reply string) *models.JudgeScore {
+	score := &models.JudgeScore{Model: j.model}
+
+	ctx, cancel := context.WithTimeout(ctx, judgeTimeout)
+	defer cancel()
+	completion, err := j.client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
+		Model: j.model,
+		Messages: []openai.ChatCompletionMessageParamUnion{
+			openai.SystemMessage(judgeSystemPrompt),
+			openai.UserMessage(judgePrompt(testCase, transcript, reply)),
+		},
+		Temperature: openai.Float(0),
+	})
+	if err != nil {
+		score.Error = fmt.Sprintf("judge request failed: %v", err)
+		return score
+	}
+	if len(completion.Choices) == 0 {
+		score.Error = "judge returned no choices"
+		return score
+	}
+
+	if err := parseJudgeAnswer(completion.Choices[0].Message.Content, *testCase.Rubric, score); err != nil {
+		score.Error = err.Error()
+	}
+	return score
+}
+
+// judgePrompt lays out the user's request, the conversation, the final reply and the
+// rubric for the judge
+func judgePrompt(testCase models.TestCase, transcript []models.TranscriptMessage, reply string) string {
+	var sb strings.Builder
+	sb.WriteString("User request:\n" + testCase.Prompt + "\n\n")
+
+	sb.WriteString("Conversation before the final reply:\n")
+	for _, message := range transcript {
+		switch message.Role {
+		case models.RoleAssistant:
+			for _, call := range message.ToolCalls {
+				sb.WriteString(fmt.Sprintf("- assistant called %s(%s)\n", call.Name, call.Arguments))
+			}
+		case models.RoleTool:
+			content := message.Content
+			if runes := []rune(content); len(runes) > judgeToolResultLength {
+				content = string(runes[:judgeToolResultLength]) + "..."
+			}
+			sb.WriteString(fmt.Sprintf("- %s returned: %s\n", message.ToolName, content))
+		}
+	}
+
+	sb.WriteString("\nFinal reply:\n" + reply + "\n\n")
+	if testCase.Rubric.Reference != "" {
+		sb.WriteString("A good reply: " + testCase.Rubric.Reference + "\n\n")
+	}
+	sb.WriteString("Criteria:\n")
+	for _, criterion := range testCase.Rubric.Criteria {
+		sb.WriteString(fmt.Sprintf("- %s: %s\n", criterion.Name, criterion.Description))
+	}
+	return sb.String()
+}
+
+// parseJudgeAnswer reads the judge's JSON answer into the score, checking it scored
+// every criterion of the rubric on the scale, and computes the weighted overall score
+func parseJudgeAnswer(content string, rubric models.JudgeRubric, score *models.JudgeScore) error {
+	// Judges often wrap the JSON in a code fence or a sentence
+	start, end := strings.Index(content, "{"), strings.LastIndex(content, "}")
+	if start < 0 || end < start {
+		return fmt.Errorf("judge answer is not JSON: %s", replyExcerpt(content))
+	}
+	var answer struct {
+		Criteria  []models.CriterionScore `json:"criteria"`
+		Rationale string                  `json:"rationale"`
+	}
+	if err := json.Unmarshal([]byte(content[start:end+1]), &answer); err != nil {
+		return fmt.Errorf("failed to parse judge answer: %w", err)
+	}
+
+	byName := make(map[string]models.CriterionScore, len(answer.Criteria))
+	for _, criterion := range answer.Criteria {
+		byName[strings.ToLower(strings.TrimSpace(criterion.Name))] = criterion
+	}
+
+	var weighted, totalWeight float64
+	for _, criterion := range rubric.Criteria {
+		scored, found := byName[strings.ToLower(criterion.Name)]
+		if !found {
+			return fmt.Errorf("judge did not score criterion '%s'", criterion.Name)
+		}
+		if scored.Score < 1 || scored.Score > models.JudgeScaleMax {
+			return fmt.Errorf("judge scored criterion '%s' %d, outside 1-%d", criterion.Name, scored.Score, models.JudgeScaleMax)
+		}
+		scored.Name = criterion.Name
+		score.Criteria = append(score.Criteria, scored)
+
+		weight := criterion.Weight
+		if weight == 0 {
+			weight = 1
+		}
+		weighted += weight * float64(scored.Score-1) / float64(models.JudgeScaleMax-1)
+		totalWeight += weight
+	}
+
+	score.Score = weighted / totalWeight
+	score.Rationale = answer.Rationale
+	return nil
+}
+
+// validateRubric reports rubrics without criteria and criteria that are unnamed,
+// undescribed, duplicated or negatively weighted
+func validateRubric(label string, rubric *models.JudgeRubric) []string {
+	if rubric == nil {
+		return nil
+	}
+
+	var problems []string
+	if len(rubric.Criteria) == 0 {
+		problems = append(problems, fmt.Sprintf("%s: rubric has no criteria", label))
+	}
+	names := make(map[string]bool)
+	for i, criterion := range rubric.Criteria {
+		name := strings.ToLower(strings.TrimSpace(criterion.Name))
+		switch {
+		case name == "":
+			problems = append(problems, fmt.Sprintf("%s: rubric criterion %d has no name", label, i+1))
+		case names[name]:
+			problems = append(problems, fmt.Sprintf("%s: rubric criterion '%s' is listed twice", label, criterion.Name))
+		}
+		names[name] = true
+		if strings.TrimSpace(criterion.Description) == "" {
+			problems = append(problems, fmt.Sprintf("%s: rubric criterion %d has no description", label, i+1))
+		}
+		if criterion.Weight < 0 {
+			problems = append(problems, fmt.Sprintf("%s: rubric criterion '%s' has a negative weight", label, criterion.Name))
+		}
+	}
+	return problems
+}
+
+// summarizeJudge averages the judge scores of the results; nil when no result was judged
+func summarizeJudge(results []models.AgentTestResult) *models.JudgeSummary {
+	var summary *models.JudgeSummary
+	var total float64
+	criteriaTotals := make(map[string]float64)
+	criteriaCounts := make(map[string]int)
+
+	for _, result := range results {
+		if result.Judge == nil {
+			continue
+		}
+		if summary == nil {
+			summary = &models.JudgeSummary{Model: result.Judge.Model}
+		}
+		if result.Judge.Error != "" {
+			summary.Errors++
+			continue
+		}
+		summary.Judged++
+		total += result.Judge.Score
+		for _, criterion := range result.Judge.Criteria {
+			criteriaTotals[criterion.Name] += float64(criterion.Score)
+			criteriaCounts[criterion.Name]++
+		}
+	}
+	if summary == nil || summary.Judged == 0 {
+		return summary
+	}
+
+	summary.MeanScore = total / float64(summary.Judged)
+	summary.Criteria = make(map[string]float64, len(criteriaTotals))
+	for name, sum := range criteriaTotals {
+		summary.Criteria[name] = sum / float64(criteriaCounts[name])
+	}
+	return summary
+}
@@ -0,0 +1,456 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"model-test/models"
+)
+
+// kamiwazaPollInterval is how often download and deployment progress is checked
+const kamiwazaPollInterval = 10 * time.Second
+
+// KamiwazaModel is a model registered in the Kamiwaza catalog
+type KamiwazaModel struct {
+	ID          string              `json:"id"`
+	Name        string              `json:"name"`         // Matches m_name on deployments
+	RepoModelID string              `json:"repo_modelId"` // Hub repository, e.g. Qwen/Qwen3-8B-GGUF
+	Hub         string              `json:"hub"`
+	Files       []KamiwazaModelFile `json:"m_files,omitempty"`
+}
+
+// KamiwazaModelFile is a single file of a catalog model
+type KamiwazaModelFile struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Size     int64  `json:"size"`
+	Download bool   `json:"download"` // Whether the file is present on the cluster
+}
+
+// Downloaded reports whether every file of the model is present on the cluster
+func (m KamiwazaModel) Downloaded() bool {
+	if len(m.Files) == 0 {
+		return false
+	}
+	for _, file := range m.Files {
+		if !file.Download {
+			return false
+		}
+	}
+	return true
+}
+
+// DownloadedFor reports whether the model file matching the quantization is present
+// on the cluster; without a quantization the whole model must be
+func (m KamiwazaModel) DownloadedFor(quantization string) bool {
+	if quantization == "" {
+		return m.Downloaded()
+	}
+	file := m.FileForQuantization(quantization)
+	return file != nil && file.Download
+}
+
+// FileForQuantization returns the first model file whose name contains the
+// quantization, ignoring case, or nil when none does
+func (m KamiwazaModel) FileForQuantization(quantization string) *KamiwazaModelFile {
+	for i, file := range m.Files {
+		if strings.Contains(strings.ToLower(file.Name), strings.ToLower(quantization)) {
+			return &m.Files[i]
+		}
+	}
+	return nil
+}
+
+// KamiwazaDeployConfig selects how a model is served. Empty fields keep the
+// cluster's defaults.
+type KamiwazaDeployConfig struct {
+	Engine        string `json:"engine,omitempty"`       // Serving engine, e.g. llamacpp, vllm or mlx
+	Quantization  string `json:"quantization,omitempty"` // Picks the model file whose name contains it, e.g. Q4_K_M
+	ContextLength int    `json:"context_length,omitempty"`
+	GPUs          string `json:"gpus,omitempty"` // Comma-separated GPU indices, e.g. "0,1"
+}
+
+// Tags returns the deployment options that are set as run tags, so results from
+// different deployments of the same model can be told apart
+func (c KamiwazaDeployConfig) Tags() models.RunTags {
+	tags := models.RunTags{}
+	if c.Engine != "" {
+		tags["engine"] = c.Engine
+	}
+	if c.Quantization != "" {
+		tags["quant"] = c.Quantization
+	}
+	if c.ContextLength > 0 {
+		tags["context_length"] = strconv.Itoa(c.ContextLength)
+	}
+	if c.GPUs != "" {
+		tags["gpus"] = strings.ReplaceAll(c.GPUs, ",", "+") // Commas separate tags in group names
+	}
+	return tags
+}
+
+// KamiwazaDeployConfigFromTags reads deployment options back from the run tags set
+// from Tags, e.g. to redeploy a model the way batch-run deployed it
+func KamiwazaDeployConfigFromTags(tags models.RunTags) KamiwazaDeployConfig {
+	config := KamiwazaDeployConfig{
+		Engine:       tags["engine"],
+		Quantization: tags["quant"],
+		GPUs:         strings.ReplaceAll(tags["gpus"], "+", ","),
+	}
+	if length, err := strconv.Atoi(tags["context_length"]); err == nil {
+		config.ContextLength = length
+	}
+	return config
+}
+
+// IsDefault reports whether no deployment option is set
+func (c KamiwazaDeployConfig) IsDefault() bool {
+	return c == KamiwazaDeployConfig{}
+}
+
+// KamiwazaSearchResult is a hub model returned by a catalog search
+type KamiwazaSearchResult struct {
+	Model KamiwazaModel       `json:"model"`
+	Files []KamiwazaModelFile `json:"files,omitempty"`
+}
+
+// KamiwazaDownloadStatus is the download progress of one model file
+type KamiwazaDownloadStatus struct {
+	FileName      string  `json:"name"`
+	Percentage    float64 `json:"download_percentage"`
+	IsDownloading bool    `json:"is_downloading"`
+	Error         string  `json:"download_error,omitempty"`
+}
+
+// ListModels returns the models registered in the catalog, downloaded or not
+func (k *KamiwazaService) ListModels() ([]KamiwazaModel, error) {
+	var catalog []KamiwazaModel
+	if err := k.doJSON("GET", "/api/models/", nil, &catalog); err != nil {
+		return nil, fmt.Errorf("failed to list models: %w", err)
+	}
+	return catalog, nil
+}
+
+// FindModel looks up a catalog model by name or hub repository ID. It returns nil
+// without error when the catalog does not know the model.
+func (k *KamiwazaService) FindModel(name string) (*KamiwazaModel, error) {
+	catalog, err := k.ListModels()
+	if err != nil {
+		return nil, err
+	}
+	for _, model := range catalog {
+		if model.Name == name || strings.EqualFold(model.RepoModelID, name) {
+			return &model, nil
+		}
+	}
+	return nil, nil
+}
+
+// SearchCatalog searches the model hub for models matching the query
+func (k *KamiwazaService) SearchCatalog(query, hub string) ([]KamiwazaSearchResult, error) {
+	params := url.Values{}
+	params.Set("query", query)
+	if hub != "" {
+		params.Set("hubs_to_search", hub)
+	}
+
+	var response struct {
+		Results []KamiwazaSearchResult `json:"results"`
+	}
+	if err := k.doJSON("GET", "/api/models/search/?"+params.Encode(), nil, &response); err != nil {
+		return nil, fmt.Errorf("failed to search catalog: %w", err)
+	}
+	return response.Results, nil
+}
+
+// PullModel starts downloading a hub model to the cluster and returns its catalog
+// entry. When files are given only those files are downloaded.
+func (k *KamiwazaService) PullModel(repoModelID, hub string, files []string) (*KamiwazaModel, error) {
+	request := map[string]interface{}{"model": repoModelID, "hub": hub}
+	if len(files) > 0 {
+		request["files_to_download"] = files
+	}
+
+	var response struct {
+		Model KamiwazaModel `json:"model"`
+	}
+	if err := k.doJSON("POST", "/api/models/download/", request, &response); err != nil {
+		return nil, fmt.Errorf("failed to pull model %s: %w", repoModelID, err)
+	}
+	return &response.Model, nil
+}
+
+// GetDownloadStatus returns the per-file download progress of a model
+func (k *KamiwazaService) GetDownloadStatus(modelID string) ([]KamiwazaDownloadStatus, error) {
+	var status []KamiwazaDownloadStatus
+	if err := k.doJSON("GET", "/api/models/download/status/?model_id="+url.QueryEscape(modelID), nil, &status); err != nil {
+		return nil, fmt.Errorf("failed to get download status: %w", err)
+	}
+	return status, nil
+}
+
+// WaitForDownload polls until every file of the model has downloaded, a file reports
+// an error, or the timeout passes
+func (k *KamiwazaService) WaitForDownload(modelID string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		status, err := k.GetDownloadStatus(modelID)
+		if err != nil {
+			return err
+		}
+
+		complete := len(status) > 0
+		for _, file := range status {
+			if file.Error != "" {
+				return fmt.Errorf("download of %s failed: %s", file.FileName, file.Error)
+			}
+			if file.IsDownloading || file.Percentage < 100 {
+				complete = false
+			}
+		}
+		if complete {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("download did not finish within %v", timeout)
+		}
+		time.Sleep(k.pollInterval())
+	}
+}
+
+// EnsureModelDownloaded makes a model available on the cluster. A model the catalog
+// already holds is downloaded if needed; otherwise the hub is searched for a model
+// whose repository ID or name matches exactly, and that model is pulled. With a
+// quantization only the matching file is required and downloaded.
+func (k *KamiwazaService) EnsureModelDownloaded(name, hub, quantization string, timeout time.Duration) (*KamiwazaModel, error) {
+	model, err := k.FindModel(name)
+	if err != nil {
+		return nil, err
+	}
+	if model != nil && model.DownloadedFor(quantization) {
+		return model, nil
+	}
+
+	repoModelID := name
+	candidate := model
+	if model != nil {
+		repoModelID = model.RepoModelID
+	} else {
+		results, err := k.SearchCatalog(name, hub)
+		if err != nil {
+			return nil, err
+		}
+		match := matchSearchResult(results, name)
+		if match == nil {
+			return nil, fmt.Errorf("no catalog model matches %s exactly (%d search results)", name, len(results))
+		}
+		repoModelID = match.Model.RepoModelID
+		if match.Model.Hub != "" {
+			hub = match.Model.Hub
+		}
+		candidate = &match.Model
+		if len(candidate.Files) == 0 {
+			candidate.Files = match.Files
+		}
+	}
+
+	var files []string
+	if quantization != "" {
+		file := candidate.FileForQuantization(quantization)
+		if file == nil {
+			return nil, fmt.Errorf("no file of %s matches quantization %s", repoModelID, quantization)
+		}
+		files = []string{file.Name}
+	}
+
+	pulled, err := k.PullModel(repoModelID, hub, files)
+	if err != nil {
+		return nil, err
+	}
+	if err := k.WaitForDownload(pulled.ID, timeout); err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", repoModelID, err)
+	}
+
+	model, err = k.FindModel(repoModelID)
+	if err != nil {
+		return nil, err
+	}
+	if model == nil {
+		return nil, fmt.Errorf("model %s is missing from the catalog after download", repoModelID)
+	}
+	return model, nil
+}
+
+// DeployModel deploys a downloaded model with the given options and returns the
+// deployment ID
+func (k *KamiwazaService) DeployModel(model *KamiwazaModel, config KamiwazaDeployConfig) (string, error) {
+	request := map[string]interface{}{"m_id": model.ID}
+	if config.Quantization != "" {
+		file := model.FileForQuantization(config.Quantization)
+		if file == nil {
+			return "", fmt.Errorf("no file of %s matches quantization %s", model.Name, config.Quantization)
+		}
+		request["m_file_id"] = file.ID
+	}
+	if config.Engine != "" {
+		request["engine_name"] = config.Engine
+	}
+	if config.ContextLength > 0 {
+		request["context_length"] = config.ContextLength
+	}
+	if config.GPUs != "" {
+		var gpuIDs []int
+		for _, value := range strings.Split(config.GPUs, ",") {
+			id, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				return "", fmt.Errorf("invalid GPU index %q", value)
+			}
+			gpuIDs = append(gpuIDs, id)
+		}
+		request["gpu_ids"] = gpuIDs
+	}
+
+	var deploymentID string
+	if err := k.doJSON("POST", "/api/serving/deploy_model", request, &deploymentID); err != nil {
+		return "", fmt.Errorf("failed to deploy model: %w", err)
+	}
+	return deploymentID, nil
+}
+
+// WaitForDeployment polls until the model has an active deployment or the timeout passes
+func (k *KamiwazaService) WaitForDeployment(modelName string, timeout time.Duration) (*KamiwazaDeployment, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		deployment, err := k.GetDeploymentByModelName(modelName)
+		if err == nil {
+			return deployment, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("model %s was not deployed within %v: %w", modelName, timeout, err)
+		}
+		time.Sleep(k.pollInterval())
+	}
+}
+
+// RedeployModel replaces the model's deployments with a fresh one using the given
+// options, e.g. when the current one stopped responding, and waits until it is active
+func (k *KamiwazaService) RedeployModel(name string, config KamiwazaDeployConfig, timeout time.Duration) (*KamiwazaDeployment, error) {
+	deployments, err := k.ListDeployments()
+	if err != nil {
+		return nil, err
+	}
+	for _, deployment := range deployments {
+		if deployment.ModelName == name {
+			if err := k.UndeployModel(deployment.ID); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	model, err := k.FindModel(name)
+	if err != nil {
+		return nil, err
+	}
+	if model == nil {
+		return nil, fmt.Errorf("model %s is not in the catalog", name)
+	}
+	if _, err := k.DeployModel(model, config); err != nil {
+		return nil, err
+	}
+	return k.WaitForDeployment(name, timeout)
+}
+
+// UndeployModel stops and removes a deployment
+func (k *KamiwazaService) UndeployModel(deploymentID string) error {
+	if err := k.doJSON("DELETE", "/api/serving/deployment/"+url.PathEscape(deploymentID), nil, nil); err != nil {
+		return fmt.Errorf("failed to undeploy %s: %w", deploymentID, err)
+	}
+	return nil
+}
+
+// SetCredentials sets the username and password used to log in, dropping any token
+// obtained with the previous credentials
+func (k *KamiwazaService) SetCredentials(username, password string) {
+	k.username = username
+	k.password = password
+	k.token = ""
+	RegisterSecret(password)
+}
+
+// SetPollInterval changes how often download and deployment progress is checked
+func (k *KamiwazaService) SetPollInterval(interval time.Duration) {
+	k.poll = interval
+}
+
+// pollInterval returns the configured poll interval or the default
+func (k *KamiwazaService) pollInterval() time.Duration {
+	if k.poll > 0 {
+		return k.poll
+	}
+	return kamiwazaPollInterval
+}
+
+// matchSearchResult picks the search result whose repository ID or name equals the
+// requested model, ignoring case
+func matchSearchResult(results []KamiwazaSearchResult, name string) *KamiwazaSearchResult {
+	for i, result := range results {
+		if strings.EqualFold(result.Model.RepoModelID, name) || strings.EqualFold(result.Model.Name, name) {
+			return &results[i]
+		}
+	}
+	return nil
+}
+
+// doJSON sends an authenticated request with an optional JSON body and decodes the
+// JSON response into out when out is not nil
+func (k *KamiwazaService) doJSON(method, path string, body interface{}, out interface{}) error {
+	if err := k.ensureAuthenticated(); err != nil {
+		return fmt.Errorf("authentication failed: %w", err)
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, k.baseURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", k.token))
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(data))
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
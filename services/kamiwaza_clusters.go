@@ -0,0 +1,83 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"model-test/models"
+)
+
+// LoadKamiwazaClusters loads and validates the configured Kamiwaza clusters
+func LoadKamiwazaClusters(filename string) (*models.KamiwazaClusterConfig, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read clusters file: %w", err)
+	}
+
+	var config models.KamiwazaClusterConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse clusters: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	for i, cluster := range config.Clusters {
+		if cluster.Name == "" {
+			return nil, fmt.Errorf("cluster %d has no name", i+1)
+		}
+		if seen[cluster.Name] {
+			return nil, fmt.Errorf("cluster '%s' is defined more than once", cluster.Name)
+		}
+		seen[cluster.Name] = true
+		if cluster.URL == "" {
+			return nil, fmt.Errorf("cluster '%s' has no url", cluster.Name)
+		}
+		if cluster.Password != "" && cluster.PasswordEnv != "" {
+			return nil, fmt.Errorf("cluster '%s' must set at most one of password and password_env", cluster.Name)
+		}
+		if err := ValidateProxy(cluster.Proxy); err != nil {
+			return nil, fmt.Errorf("cluster '%s': %w", cluster.Name, err)
+		}
+	}
+
+	return &config, nil
+}
+
+// FindKamiwazaCluster looks up a configured cluster by name
+func FindKamiwazaCluster(config *models.KamiwazaClusterConfig, name string) (*models.KamiwazaCluster, error) {
+	var names []string
+	for i, cluster := range config.Clusters {
+		if cluster.Name == name {
+			return &config.Clusters[i], nil
+		}
+		names = append(names, cluster.Name)
+	}
+	return nil, fmt.Errorf("unknown cluster '%s' (configured: %v)", name, names)
+}
+
+// NewKamiwazaServiceForCluster creates a Kamiwaza service logged in with the
+// cluster's credentials
+func NewKamiwazaServiceForCluster(cluster models.KamiwazaCluster) (*KamiwazaService, error) {
+	service := NewKamiwazaService(cluster.URL)
+
+	username := cluster.Username
+	if username == "" {
+		username = service.username
+	}
+	password := cluster.Password
+	if cluster.PasswordEnv != "" {
+		password = os.Getenv(cluster.PasswordEnv)
+		if password == "" {
+			return nil, fmt.Errorf("cluster '%s' reads its password from %s, which is not set", cluster.Name, cluster.PasswordEnv)
+		}
+	}
+	if password == "" {
+		password = service.password
+	}
+
+	service.SetCredentials(username, password)
+	if err := service.SetProxy(cluster.Proxy); err != nil {
+		return nil, fmt.Errorf("cluster '%s': %w", cluster.Name, err)
+	}
+	return service, nil
+}
@@ -1,7 +1,6 @@
 package services
 
 import (
-	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -36,6 +35,7 @@ type KamiwazaService struct {
 	username string
 	password string
 	token    string
+	poll     time.Duration // Download and deployment poll interval; defaults to kamiwazaPollInterval
 }
 
 // NewKamiwazaService creates a new Kamiwaza service instance with authentication
@@ -46,9 +46,7 @@ func NewKamiwazaService(baseURL string) *KamiwazaService {
 	}
 
 	// Create HTTP client with TLS verification disabled for self-signed certs
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-	}
+	tr := newHTTPTransport("", true)
 
 	return &KamiwazaService{
 		baseURL:  baseURL,
@@ -61,6 +59,16 @@ func NewKamiwazaService(baseURL string) *KamiwazaService {
 	}
 }
 
+// SetProxy routes requests to the Kamiwaza API through a proxy setting (see
+// ValidateProxy); empty follows the proxy environment variables
+func (k *KamiwazaService) SetProxy(proxy string) error {
+	if err := ValidateProxy(proxy); err != nil {
+		return err
+	}
+	k.client.Transport = newHTTPTransport(proxy, true)
+	return nil
+}
+
 // authenticate obtains an access token from Kamiwaza
 func (k *KamiwazaService) authenticate() error {
 	authURL := fmt.Sprintf("%s/api/auth/token", k.baseURL)
@@ -98,6 +106,7 @@ func (k *KamiwazaService) authenticate() error {
 	}
 
 	k.token = authResp.AccessToken
+	RegisterSecret(k.token)
 	return nil
 }
 
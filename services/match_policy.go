@@ -0,0 +1,66 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"model-test/models"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// NewStringMatchPolicy builds a fully specified string comparison policy
+func NewStringMatchPolicy(caseSensitive, trim, collapseWhitespace bool, unicodeNormalization string) (models.StringMatchPolicy, error) {
+	switch unicodeNormalization {
+	case "", "none", "nfc", "nfkc":
+	default:
+		return models.StringMatchPolicy{}, fmt.Errorf("unknown unicode normalization %q (expected none, nfc or nfkc)", unicodeNormalization)
+	}
+
+	if unicodeNormalization == "" {
+		unicodeNormalization = "none"
+	}
+
+	return models.StringMatchPolicy{
+		CaseSensitive:        &caseSensitive,
+		Trim:                 &trim,
+		CollapseWhitespace:   &collapseWhitespace,
+		UnicodeNormalization: unicodeNormalization,
+	}, nil
+}
+
+// DefaultStringMatchPolicy returns the historical behavior: case-insensitive and
+// whitespace-exact
+func DefaultStringMatchPolicy() models.StringMatchPolicy {
+	policy, _ := NewStringMatchPolicy(false, false, false, "none")
+	return policy
+}
+
+// stringsMatch compares two strings under the given policy
+func stringsMatch(expected, actual string, policy models.StringMatchPolicy) bool {
+	expected = normalizeString(expected, policy)
+	actual = normalizeString(actual, policy)
+
+	if policy.CaseSensitive != nil && *policy.CaseSensitive {
+		return expected == actual
+	}
+	return strings.EqualFold(expected, actual)
+}
+
+// normalizeString applies the policy's unicode, trim and whitespace rules
+func normalizeString(value string, policy models.StringMatchPolicy) string {
+	switch policy.UnicodeNormalization {
+	case "nfc":
+		value = norm.NFC.String(value)
+	case "nfkc":
+		value = norm.NFKC.String(value)
+	}
+
+	if policy.CollapseWhitespace != nil && *policy.CollapseWhitespace {
+		value = strings.Join(strings.Fields(value), " ")
+	} else if policy.Trim != nil && *policy.Trim {
+		value = strings.TrimSpace(value)
+	}
+
+	return value
+}
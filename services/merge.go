@@ -0,0 +1,217 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"model-test/models"
+)
+
+// mergeCandidate is one result of a test and the index of the file it came from
+type mergeCandidate struct {
+	result models.AgentTestResult
+	source int
+}
+
+// ParseMergeRule validates a merge conflict rule name
+func ParseMergeRule(value string) (models.MergeRule, error) {
+	switch rule := models.MergeRule(value); rule {
+	case models.MergeReachedModel, models.MergeLatest, models.MergeEarliest, models.MergeError:
+		return rule, nil
+	default:
+		return "", fmt.Errorf("unknown merge rule '%s' (expected reached-model, latest, earliest or error)", value)
+	}
+}
+
+// MergeReports combines result files of one model and suite, such as a run and a
+// rerun of its infrastructure failures, into one report with one result per test.
+// Tests present in several files are settled by the rule. Outcomes are kept as
+// recorded; the aggregates are rebuilt from the merged results. The first report
+// supplies the run's settings and identity. Reports of other models, tool spec
+// versions or locales, or whose test case definitions differ, are rejected.
+func MergeReports(reports []*models.AgentReport, files []string, rule models.MergeRule) (*models.AgentReport, []models.MergeConflict, error) {
+	if len(reports) == 0 {
+		return nil, nil, fmt.Errorf("no reports to merge")
+	}
+	base := reports[0]
+
+	if err := checkMergeable(reports, files); err != nil {
+		return nil, nil, err
+	}
+
+	var keys []string
+	candidates := make(map[string][]mergeCandidate)
+	for i, report := range reports {
+		for _, result := range report.Results {
+			key := ResultKey(result)
+			if _, seen := candidates[key]; !seen {
+				keys = append(keys, key)
+			}
+			candidates[key] = append(candidates[key], mergeCandidate{result: result, source: i})
+		}
+	}
+
+	sources := make([]models.MergeSource, len(reports))
+	for i, report := range reports {
+		sources[i] = models.MergeSource{File: files[i], RunID: report.RunID, Results: len(report.Results)}
+	}
+
+	var conflicts []models.MergeConflict
+	results := make([]models.AgentTestResult, 0, len(keys))
+	for _, key := range keys {
+		options := candidates[key]
+		kept := options[0]
+		if len(options) > 1 {
+			if rule == models.MergeError {
+				return nil, nil, fmt.Errorf("%s is in both %s and %s", key, files[options[0].source], files[options[1].source])
+			}
+			kept = chooseMergeCandidate(options, rule)
+
+			conflict := models.MergeConflict{Key: key, KeptFile: files[kept.source]}
+			for _, option := range options {
+				if option.source != kept.source {
+					conflict.Dropped = append(conflict.Dropped, files[option.source])
+				}
+			}
+			conflicts = append(conflicts, conflict)
+		}
+		sources[kept.source].Kept++
+		results = append(results, kept.result)
+	}
+
+	evaluator := NewEvaluator()
+	evaluator.SetMatchPolicy(base.MatchPolicy)
+	evaluator.SetReferenceTime(base.ReferenceTime)
+	if base.Scoring != "" {
+		evaluator.SetScoringMode(base.Scoring)
+	}
+	if base.Evaluation != "" {
+		evaluator.SetEvaluationMode(base.Evaluation)
+	}
+	var aliases models.ToolAliases
+	for _, report := range reports {
+		aliases = aliases.Merge(report.ToolAliases)
+	}
+	evaluator.SetToolAliases(aliases)
+
+	merged := BuildAgentReport(results, evaluator)
+	merged.TestSuite = base.TestSuite
+	merged.RunID = base.RunID
+	merged.Timestamp = base.Timestamp
+	merged.Cluster = base.Cluster
+	merged.Warmup = base.Warmup
+	merged.ToolSpecVersion = base.ToolSpecVersion
+	merged.ToolLocale = base.ToolLocale
+	merged.ToolErrorVerbosity = base.ToolErrorVerbosity
+	merged.ServiceState = base.ServiceState
+	merged.ContextWindow = base.ContextWindow
+	merged.NetworkChaos = base.NetworkChaos
+	merged.Gateway = base.Gateway
+	merged.Pruning = base.Pruning
+	merged.BaselineComparison = base.BaselineComparison
+	merged.MergedFrom = sources
+	merged.MergeRule = rule
+	merged.Scorers = base.Scorers
+
+	merged.Tags = models.RunTags{}
+	for i := len(reports) - 1; i >= 0; i-- {
+		for key, value := range reports[i].Tags {
+			merged.Tags[key] = value
+		}
+	}
+	if len(merged.Tags) == 0 {
+		merged.Tags = nil
+	}
+
+	// The merge is still incomplete if no file had every scheduled test
+	scheduled := 0
+	for _, report := range reports {
+		if total := len(report.Results) + report.InterruptedTests; total > scheduled {
+			scheduled = total
+		}
+		merged.DeploymentRecoveries = append(merged.DeploymentRecoveries, report.DeploymentRecoveries...)
+		merged.InfrastructureReruns = append(merged.InfrastructureReruns, report.InfrastructureReruns...)
+		merged.SuiteHooks = append(merged.SuiteHooks, report.SuiteHooks...)
+	}
+	if len(results) < scheduled {
+		merged.Incomplete = true
+		merged.InterruptedTests = scheduled - len(results)
+		merged.StoppedAfterFailures = base.StoppedAfterFailures
+	}
+
+	return merged, conflicts, nil
+}
+
+// checkMergeable rejects reports that were not produced by the same model against the
+// same tool definitions and test cases as the first
+func checkMergeable(reports []*models.AgentReport, files []string) error {
+	base := reports[0]
+	model := ""
+	definitions := make(map[string]models.TestCase)
+	definedIn := make(map[string]string)
+
+	for i, report := range reports {
+		if report.ToolSpecVersion != base.ToolSpecVersion {
+			return fmt.Errorf("%s uses tool spec version '%s', %s uses '%s'", files[i], report.ToolSpecVersion, files[0], base.ToolSpecVersion)
+		}
+		if report.ToolLocale != base.ToolLocale {
+			return fmt.Errorf("%s uses tool locale '%s', %s uses '%s'", files[i], report.ToolLocale, files[0], base.ToolLocale)
+		}
+
+		for _, result := range report.Results {
+			if model == "" {
+				model = result.ModelName
+			} else if result.ModelName != model {
+				return fmt.Errorf("%s has results of model %s, expected %s", files[i], result.ModelName, model)
+			}
+
+			name := result.TestCase.Name
+			previous, seen := definitions[name]
+			if !seen {
+				definitions[name] = result.TestCase
+				definedIn[name] = files[i]
+				continue
+			}
+			if !sameTestCase(previous, result.TestCase) {
+				return fmt.Errorf("test case %s differs between %s and %s; rescore both with the same -config first", name, definedIn[name], files[i])
+			}
+		}
+	}
+	return nil
+}
+
+// sameTestCase compares two test case definitions as they are stored
+func sameTestCase(a, b models.TestCase) bool {
+	aJSON, errA := json.Marshal(a)
+	bJSON, errB := json.Marshal(b)
+	if errA != nil || errB != nil {
+		return reflect.DeepEqual(a, b)
+	}
+	return string(aJSON) == string(bJSON)
+}
+
+// chooseMergeCandidate picks the result of a test to keep by the rule. Candidates are
+// in file order, so ties go to the later file except under MergeEarliest.
+func chooseMergeCandidate(options []mergeCandidate, rule models.MergeRule) mergeCandidate {
+	if rule == models.MergeEarliest {
+		return options[0]
+	}
+
+	kept := options[0]
+	for _, option := range options[1:] {
+		if rule == models.MergeReachedModel {
+			keptFailed, optionFailed := failedAgainstServer(kept.result), failedAgainstServer(option.result)
+			if keptFailed != optionFailed {
+				if keptFailed {
+					kept = option
+				}
+				continue
+			}
+		}
+		if !option.result.Timestamp.Before(kept.result.Timestamp) {
+			kept = option
+		}
+	}
+	return kept
+}
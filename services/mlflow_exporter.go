@@ -0,0 +1,169 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"model-test/models"
+)
+
+// mlflowMaxParamLength is the longest parameter value the MLflow API accepts
+const mlflowMaxParamLength = 6000
+
+// MLflowExporter logs runs to an MLflow tracking server through its REST API
+type MLflowExporter struct {
+	trackingURI string
+	experiment  string
+	token       string
+	client      *http.Client
+}
+
+// NewMLflowExporter creates an exporter for the given tracking server and experiment
+func NewMLflowExporter(trackingURI, experiment string) *MLflowExporter {
+	return &MLflowExporter{
+		trackingURI: strings.TrimSuffix(trackingURI, "/"),
+		experiment:  experiment,
+		client:      &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// SetToken sets the bearer token for tracking servers that require authentication
+func (m *MLflowExporter) SetToken(token string) {
+	m.token = token
+}
+
+// Name returns the exporter's display name
+func (m *MLflowExporter) Name() string {
+	return "MLflow"
+}
+
+// Export creates an MLflow run in the experiment and logs the report's parameters,
+// tags and metrics to it
+func (m *MLflowExporter) Export(ctx context.Context, report *models.AgentReport, params map[string]string) error {
+	experimentID, err := m.getOrCreateExperiment(ctx)
+	if err != nil {
+		return err
+	}
+
+	startTime := report.Timestamp.UnixMilli()
+	runName := params["model"]
+	if report.RunID != "" {
+		runName = fmt.Sprintf("%s-%s", runName, report.RunID)
+	}
+
+	var created struct {
+		Run struct {
+			Info struct {
+				RunID string `json:"run_id"`
+			} `json:"info"`
+		} `json:"run"`
+	}
+	err = m.call(ctx, http.MethodPost, "runs/create", map[string]interface{}{
+		"experiment_id": experimentID,
+		"run_name":      runName,
+		"start_time":    startTime,
+	}, &created)
+	if err != nil {
+		return fmt.Errorf("failed to create run: %w", err)
+	}
+	runID := created.Run.Info.RunID
+
+	type keyValue struct {
+		Key   string `json:"key"`
+		Value string `json:"value"`
+	}
+	type metric struct {
+		Key       string  `json:"key"`
+		Value     float64 `json:"value"`
+		Timestamp int64   `json:"timestamp"`
+		Step      int     `json:"step"`
+	}
+
+	var logParams, logTags []keyValue
+	runParams := ReportParams(report, params)
+	for _, key := range sortedKeys(runParams) {
+		value := runParams[key]
+		if len(value) > mlflowMaxParamLength {
+			value = value[:mlflowMaxParamLength]
+		}
+		logParams = append(logParams, keyValue{Key: key, Value: value})
+	}
+	for _, key := range sortedKeys(report.Tags) {
+		logTags = append(logTags, keyValue{Key: key, Value: report.Tags[key]})
+	}
+
+	var logMetrics []metric
+	metrics := ReportMetrics(report)
+	for _, key := range sortedKeys(metrics) {
+		logMetrics = append(logMetrics, metric{Key: key, Value: metrics[key], Timestamp: startTime})
+	}
+
+	err = m.call(ctx, http.MethodPost, "runs/log-batch", map[string]interface{}{
+		"run_id":  runID,
+		"params":  logParams,
+		"tags":    logTags,
+		"metrics": logMetrics,
+	}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to log run data: %w", err)
+	}
+
+	err = m.call(ctx, http.MethodPost, "runs/update", map[string]interface{}{
+		"run_id":   runID,
+		"status":   "FINISHED",
+		"end_time": time.Now().UnixMilli(),
+	}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to finish run: %w", err)
+	}
+
+	return nil
+}
+
+// getOrCreateExperiment looks up the experiment by name, creating it if needed
+func (m *MLflowExporter) getOrCreateExperiment(ctx context.Context) (string, error) {
+	var found struct {
+		Experiment struct {
+			ExperimentID string `json:"experiment_id"`
+		} `json:"experiment"`
+	}
+	err := m.call(ctx, http.MethodGet, "experiments/get-by-name?experiment_name="+url.QueryEscape(m.experiment), nil, &found)
+	if err == nil && found.Experiment.ExperimentID != "" {
+		return found.Experiment.ExperimentID, nil
+	}
+
+	var created struct {
+		ExperimentID string `json:"experiment_id"`
+	}
+	if err := m.call(ctx, http.MethodPost, "experiments/create", map[string]string{"name": m.experiment}, &created); err != nil {
+		return "", fmt.Errorf("failed to create experiment %q: %w", m.experiment, err)
+	}
+
+	return created.ExperimentID, nil
+}
+
+// call invokes an MLflow REST endpoint
+func (m *MLflowExporter) call(ctx context.Context, method, endpoint string, body interface{}, out interface{}) error {
+	headers := map[string]string{}
+	if m.token != "" {
+		headers["Authorization"] = "Bearer " + m.token
+	}
+
+	endpointURL := fmt.Sprintf("%s/api/2.0/mlflow/%s", m.trackingURI, endpoint)
+	return sendJSON(ctx, m.client, method, endpointURL, headers, body, out)
+}
+
+// sortedKeys returns a map's keys in sorted order
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
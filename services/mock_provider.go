@@ -0,0 +1,173 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"model-test/models"
+)
+
+// Token counts the mock provider reports, fixed so usage metrics are deterministic
+const (
+	mockPromptTokensPerMessage = 100
+	mockCompletionTokens       = 10
+)
+
+// MockProvider is an OpenAI-compatible chat completions server that answers from a
+// script instead of a model, so a run's outcome depends only on the harness
+type MockProvider struct {
+	script   map[string][]models.MockTurn // Prompt -> turns
+	server   *http.Server
+	listener net.Listener
+}
+
+// mockRequest is the part of a chat completion request the mock provider reads
+type mockRequest struct {
+	Model    string `json:"model"`
+	Stream   bool   `json:"stream"`
+	Messages []struct {
+		Role    string          `json:"role"`
+		Content json.RawMessage `json:"content"`
+	} `json:"messages"`
+}
+
+// StartMockProvider serves the script on a free local port until Close
+func StartMockProvider(script models.MockScript) (*MockProvider, error) {
+	mock := &MockProvider{script: make(map[string][]models.MockTurn)}
+	for _, conversation := range script.Conversations {
+		if len(conversation.Turns) == 0 {
+			return nil, fmt.Errorf("mock conversation for prompt '%s' has no turns", conversation.Prompt)
+		}
+		mock.script[conversation.Prompt] = conversation.Turns
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to start mock provider: %w", err)
+	}
+	mock.listener = listener
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/chat/completions", mock.handleChatCompletion)
+	mock.server = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go mock.server.Serve(listener)
+
+	return mock, nil
+}
+
+// BaseURL returns the OpenAI API base URL of the mock provider
+func (m *MockProvider) BaseURL() string {
+	return fmt.Sprintf("http://%s/v1", m.listener.Addr())
+}
+
+// Close stops the mock provider
+func (m *MockProvider) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return m.server.Shutdown(ctx)
+}
+
+// handleChatCompletion answers with the turn of the prompt's conversation that follows
+// the assistant messages already in the request
+func (m *MockProvider) handleChatCompletion(w http.ResponseWriter, r *http.Request) {
+	var request mockRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeMockError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+	if request.Stream {
+		writeMockError(w, http.StatusBadRequest, "the mock provider does not support streaming")
+		return
+	}
+
+	var prompt string
+	turn := 0
+	for _, message := range request.Messages {
+		switch message.Role {
+		case "user":
+			if prompt == "" {
+				json.Unmarshal(message.Content, &prompt)
+			}
+		case "assistant":
+			turn++
+		}
+	}
+	turns, exists := m.script[prompt]
+	if !exists {
+		writeMockError(w, http.StatusBadRequest, fmt.Sprintf("no scripted conversation for prompt '%s'", prompt))
+		return
+	}
+	if turn >= len(turns) {
+		turn = len(turns) - 1
+	}
+	scripted := turns[turn]
+	if scripted.Error != "" {
+		status := scripted.Status
+		if status == 0 {
+			status = http.StatusBadRequest
+		}
+		writeMockError(w, status, scripted.Error)
+		return
+	}
+
+	message := map[string]interface{}{"role": "assistant", "content": scripted.Content}
+	finishReason := "stop"
+	if len(scripted.ToolCalls) > 0 {
+		var toolCalls []map[string]interface{}
+		for i, call := range scripted.ToolCalls {
+			arguments := call.RawArguments
+			if arguments == "" {
+				arguments = "{}"
+				if len(call.Arguments) > 0 {
+					arguments = string(call.Arguments)
+				}
+			}
+			toolCalls = append(toolCalls, map[string]interface{}{
+				"id":       fmt.Sprintf("call_%d_%d", turn+1, i+1),
+				"type":     "function",
+				"function": map[string]string{"name": call.Name, "arguments": arguments},
+			})
+		}
+		message["tool_calls"] = toolCalls
+		finishReason = "tool_calls"
+	}
+
+	promptTokens := mockPromptTokensPerMessage * len(request.Messages)
+	writeMockJSON(w, http.StatusOK, map[string]interface{}{
+		"id":      fmt.Sprintf("chatcmpl-mock-%d", turn+1),
+		"object":  "chat.completion",
+		"created": 0,
+		"model":   request.Model,
+		"choices": []map[string]interface{}{{"index": 0, "message": message, "finish_reason": finishReason}},
+		"usage": map[string]int{
+			"prompt_tokens":     promptTokens,
+			"completion_tokens": mockCompletionTokens,
+			"total_tokens":      promptTokens + mockCompletionTokens,
+		},
+	})
+}
+
+// writeMockError answers with an OpenAI-style error: an invalid request error for a
+// 4xx status and a server error otherwise
+func writeMockError(w http.ResponseWriter, status int, message string) {
+	errorType := "invalid_request_error"
+	if status >= http.StatusInternalServerError {
+		errorType = "server_error"
+	}
+	writeMockJSON(w, status, map[string]interface{}{
+		"error": map[string]string{"message": message, "type": errorType},
+	})
+}
+
+// writeMockJSON writes a JSON response
+func writeMockJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		fmt.Printf("Failed to write mock response: %v\n", err)
+	}
+}
@@ -0,0 +1,249 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"model-test/models"
+)
+
+// defaultSlowBodyBytesPerSecond is the throttled body rate when the chaos config sets none
+const defaultSlowBodyBytesPerSecond = 1024
+
+// LoadNetworkChaos loads and validates a network chaos config
+func LoadNetworkChaos(filename string) (*models.NetworkChaos, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chaos file: %w", err)
+	}
+
+	var config models.NetworkChaos
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse chaos config: %w", err)
+	}
+	if _, err := NewNetworkChaosInjector(config); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
+
+// NetworkChaosInjector decides the faults of a chaos config for each request attempt.
+// One injector is shared by every test, so its random source is guarded.
+type NetworkChaosInjector struct {
+	latency                time.Duration
+	jitter                 time.Duration
+	dropRate               float64
+	slowBodyRate           float64
+	slowBodyBytesPerSecond int
+
+	rngMutex sync.Mutex
+	rng      *rand.Rand
+}
+
+// NewNetworkChaosInjector validates a chaos config and returns its injector
+func NewNetworkChaosInjector(config models.NetworkChaos) (*NetworkChaosInjector, error) {
+	chaos := &NetworkChaosInjector{
+		dropRate:               config.DropRate,
+		slowBodyRate:           config.SlowBodyRate,
+		slowBodyBytesPerSecond: config.SlowBodyBytesPerSecond,
+	}
+
+	var err error
+	if chaos.latency, err = parseChaosDuration("latency", config.Latency); err != nil {
+		return nil, err
+	}
+	if chaos.jitter, err = parseChaosDuration("jitter", config.Jitter); err != nil {
+		return nil, err
+	}
+	if config.DropRate < 0 || config.DropRate > 1 {
+		return nil, fmt.Errorf("chaos drop_rate must be between 0 and 1, got %v", config.DropRate)
+	}
+	if config.SlowBodyRate < 0 || config.SlowBodyRate > 1 {
+		return nil, fmt.Errorf("chaos slow_body_rate must be between 0 and 1, got %v", config.SlowBodyRate)
+	}
+	if config.SlowBodyBytesPerSecond < 0 {
+		return nil, fmt.Errorf("chaos slow_body_bytes_per_second must not be negative, got %d", config.SlowBodyBytesPerSecond)
+	}
+	if chaos.slowBodyBytesPerSecond == 0 {
+		chaos.slowBodyBytesPerSecond = defaultSlowBodyBytesPerSecond
+	}
+
+	seed := time.Now().UnixNano()
+	if config.Seed != nil {
+		seed = *config.Seed
+	}
+	chaos.rng = rand.New(rand.NewSource(seed))
+
+	return chaos, nil
+}
+
+// parseChaosDuration parses an optional, non-negative duration of the chaos config
+func parseChaosDuration(name, value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid chaos %s '%s': %w", name, value, err)
+	}
+	if duration < 0 {
+		return 0, fmt.Errorf("chaos %s must not be negative, got %s", name, value)
+	}
+	return duration, nil
+}
+
+// Transport returns a transport injecting faults into the requests it forwards to base
+// (http.DefaultTransport when nil)
+func (c *NetworkChaosInjector) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &chaosTransport{chaos: c, base: base}
+}
+
+// chaosTransport is an http.RoundTripper applying an injector's faults
+type chaosTransport struct {
+	chaos *NetworkChaosInjector
+	base  http.RoundTripper
+}
+
+// RoundTrip delays the attempt, then either drops its connection or forwards it,
+// possibly throttling the response body, and records each fault with the test the
+// request belongs to
+func (t *chaosTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	recorder := networkFaultRecorderFrom(req.Context())
+	attempt, _ := strconv.Atoi(req.Header.Get("X-Stainless-Retry-Count"))
+
+	delay, drop, slowBody := t.chaos.roll()
+	if delay > 0 {
+		recorder.record(models.NetworkFault{Type: models.NetworkFaultLatency, Attempt: attempt, Delay: delay})
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			timer.Stop()
+			closeRequestBody(req)
+			return nil, req.Context().Err()
+		}
+	}
+
+	if drop {
+		recorder.record(models.NetworkFault{Type: models.NetworkFaultDrop, Attempt: attempt})
+		closeRequestBody(req)
+		return nil, &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || !slowBody {
+		return resp, err
+	}
+	recorder.record(models.NetworkFault{Type: models.NetworkFaultSlowBody, Attempt: attempt, BytesPerSecond: t.chaos.slowBodyBytesPerSecond})
+	resp.Body = &slowBodyReader{body: resp.Body, bytesPerSecond: t.chaos.slowBodyBytesPerSecond}
+	return resp, nil
+}
+
+// roll decides the faults of one attempt
+func (c *NetworkChaosInjector) roll() (delay time.Duration, drop, slowBody bool) {
+	c.rngMutex.Lock()
+	defer c.rngMutex.Unlock()
+
+	delay = c.latency
+	if c.jitter > 0 {
+		delay += time.Duration(c.rng.Int63n(int64(2*c.jitter)+1)) - c.jitter
+	}
+	if delay < 0 {
+		delay = 0
+	}
+	drop = c.rng.Float64() < c.dropRate
+	slowBody = c.rng.Float64() < c.slowBodyRate
+	return delay, drop, slowBody
+}
+
+// closeRequestBody closes the body of a request that is not forwarded, as a
+// RoundTripper must
+func closeRequestBody(req *http.Request) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+}
+
+// slowBodyReader throttles a response body to a byte rate
+type slowBodyReader struct {
+	body           io.ReadCloser
+	bytesPerSecond int
+}
+
+// Read reads at most a tenth of a second's worth of bytes and waits out that time
+func (r *slowBodyReader) Read(p []byte) (int, error) {
+	chunk := r.bytesPerSecond / 10
+	if chunk < 1 {
+		chunk = 1
+	}
+	if len(p) > chunk {
+		p = p[:chunk]
+	}
+	n, err := r.body.Read(p)
+	if n > 0 {
+		time.Sleep(time.Duration(n) * time.Second / time.Duration(r.bytesPerSecond))
+	}
+	return n, err
+}
+
+// Close closes the underlying body
+func (r *slowBodyReader) Close() error {
+	return r.body.Close()
+}
+
+// networkFaultsKey is the context key of a test's fault recorder
+type networkFaultsKey struct{}
+
+// NetworkFaultRecorder collects the faults injected into the requests of one test
+type NetworkFaultRecorder struct {
+	mutex  sync.Mutex
+	faults []models.NetworkFault
+}
+
+// WithNetworkFaultRecorder returns a context whose requests record their injected
+// faults in the returned recorder
+func WithNetworkFaultRecorder(ctx context.Context) (context.Context, *NetworkFaultRecorder) {
+	recorder := &NetworkFaultRecorder{}
+	return context.WithValue(ctx, networkFaultsKey{}, recorder), recorder
+}
+
+// networkFaultRecorderFrom returns the recorder of a request's context, or nil
+func networkFaultRecorderFrom(ctx context.Context) *NetworkFaultRecorder {
+	recorder, _ := ctx.Value(networkFaultsKey{}).(*NetworkFaultRecorder)
+	return recorder
+}
+
+// record adds a fault; requests outside a test, such as warmup, have no recorder
+func (r *NetworkFaultRecorder) record(fault models.NetworkFault) {
+	if r == nil {
+		return
+	}
+	fault.At = time.Now()
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.faults = append(r.faults, fault)
+}
+
+// Faults returns the faults recorded so far
+func (r *NetworkFaultRecorder) Faults() []models.NetworkFault {
+	if r == nil {
+		return nil
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]models.NetworkFault(nil), r.faults...)
+}
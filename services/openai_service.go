@@ -3,12 +3,12 @@ package services
 import (
 	"context"
 	"crypto/rand"
-	"crypto/tls"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"model-test/models"
@@ -19,40 +19,72 @@ import (
 	"github.com/openai/openai-go/packages/param"
 )
 
+// MaxIterations is how many LLM requests the agent loop sends for one test at most
+const MaxIterations = 5
+
 // OpenAIService handles interactions with the OpenAI API using an agent loop
 type OpenAIService struct {
 	client        openai.Client
 	shoppingTools *tools.ShoppingTools
-	toolExecutor  *ToolExecutor
-	cartService   *CartService
 	defaultModel  string
+	apiKey        string
 	baseURL       string
+	endpointMutex sync.RWMutex // Guards client, baseURL and connection; baseURL changes when the model is redeployed
+	connection    Connection   // Proxy and headers of requests to the endpoint
 	logger        *RequestLogger
+	referenceTime time.Time
+	events        *EventBroadcaster
+	limiter       *RateLimiter // Shared by all test goroutines; nil when requests are not paced
+	toolSpec      *models.ToolSpecVersion
+	toolAliases   map[string]string // Renamed tool name -> canonical name
+
+	toolErrorVerbosity models.ToolErrorVerbosity
+	approvalRequired   map[string]bool // Tools whose first call per conversation is denied
+
+	streaming bool // Request completions with the streaming API to measure time to first token
+
+	contextWindow int // Tokens a request's prompt and max_tokens may use together; 0 disables the check
+
+	chaos *NetworkChaosInjector // Faults injected into every request; nil for none; guarded by endpointMutex
+
+	gateway bool // Record each session's gateway routing from the responses
+
+	recordRequests bool // Keep each session's request bodies for repro bundles
+
+	serviceState models.ServiceState
+	sandboxes    map[string]*ToolSandbox // Per-session services, by session ID
+	shared       *ToolSandbox            // The one sandbox every session uses with ServiceStateShared
+	sandboxMutex sync.Mutex
 }
 
-// NewOpenAIServiceWithLogger creates a new OpenAI service instance with logging
-func NewOpenAIServiceWithLogger(apiKey, baseURL, defaultModel string, logger *RequestLogger) *OpenAIService {
+// newOpenAIClient creates a client for an OpenAI-compatible endpoint that connects
+// through the connection's proxy and sends its headers, injecting the faults of chaos
+// into its requests unless chaos is nil. The headers are set after the API key, so they
+// may replace its Authorization header.
+func newOpenAIClient(apiKey, baseURL string, connection Connection, chaos *NetworkChaosInjector) openai.Client {
+	RegisterSecret(apiKey)
+	registerSensitiveHeaders(connection.Headers)
+
+	httpClient := endpointHTTPClient(baseURL, connection.Proxy)
+	if chaos != nil {
+		httpClient.Transport = chaos.Transport(httpClient.Transport)
+	}
+
 	options := []option.RequestOption{
 		option.WithBaseURL(baseURL),
 		option.WithAPIKey(apiKey),
+		option.WithHTTPClient(httpClient),
 	}
-
-	// Disable SSL verification for localhost HTTPS connections (Kamiwaza, etc.)
-	if strings.HasPrefix(baseURL, "https://localhost") || strings.Contains(baseURL, "https://127.0.0.1") {
-		httpClient := &http.Client{
-			Transport: &http.Transport{
-				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-			},
-		}
-		options = append(options, option.WithHTTPClient(httpClient))
+	for name, value := range connection.Headers {
+		options = append(options, option.WithHeader(name, value))
 	}
 
-	client := openai.NewClient(options...)
+	return openai.NewClient(options...)
+}
 
-	// Initialize services
-	productService := NewProductService()
-	cartService := NewCartService()
-	toolExecutor := NewToolExecutor(productService, cartService)
+// NewOpenAIServiceWithLogger creates a new OpenAI service instance with logging
+func NewOpenAIServiceWithLogger(apiKey, baseURL, defaultModel string, logger *RequestLogger) *OpenAIService {
+	client := newOpenAIClient(apiKey, baseURL, Connection{}, nil)
 
 	// Set default model if not provided
 	if defaultModel == "" {
@@ -62,16 +94,157 @@ func NewOpenAIServiceWithLogger(apiKey, baseURL, defaultModel string, logger *Re
 	return &OpenAIService{
 		client:        client,
 		shoppingTools: tools.NewShoppingTools(),
-		toolExecutor:  toolExecutor,
-		cartService:   cartService,
 		defaultModel:  defaultModel,
+		apiKey:        apiKey,
 		baseURL:       baseURL,
 		logger:        logger,
+		referenceTime: time.Now(),
+
+		toolErrorVerbosity: models.ToolErrorStandard,
+
+		serviceState: models.ServiceStateIsolated,
+		sandboxes:    make(map[string]*ToolSandbox),
+	}
+}
+
+// SetBaseURL points the service at a new endpoint, e.g. after the model was redeployed
+func (ai *OpenAIService) SetBaseURL(baseURL string) {
+	ai.endpointMutex.Lock()
+	defer ai.endpointMutex.Unlock()
+	ai.client = newOpenAIClient(ai.apiKey, baseURL, ai.connection, ai.chaos)
+	ai.baseURL = baseURL
+}
+
+// SetProxy routes requests to the model server through a proxy setting (see
+// ValidateProxy); empty follows the proxy environment variables
+func (ai *OpenAIService) SetProxy(proxy string) error {
+	if err := ValidateProxy(proxy); err != nil {
+		return err
+	}
+
+	ai.endpointMutex.Lock()
+	defer ai.endpointMutex.Unlock()
+	ai.connection.Proxy = proxy
+	ai.client = newOpenAIClient(ai.apiKey, ai.baseURL, ai.connection, ai.chaos)
+	return nil
+}
+
+// SetHeaders sends static headers with every request to the model server, e.g. the
+// routing or organization headers an API gateway expects
+func (ai *OpenAIService) SetHeaders(headers map[string]string) error {
+	if err := ValidateHeaders(headers); err != nil {
+		return err
+	}
+
+	ai.endpointMutex.Lock()
+	defer ai.endpointMutex.Unlock()
+	ai.connection.Headers = headers
+	ai.client = newOpenAIClient(ai.apiKey, ai.baseURL, ai.connection, ai.chaos)
+	return nil
+}
+
+// SetGateway records the gateway routing of each session's requests
+func (ai *OpenAIService) SetGateway(gateway bool) {
+	ai.gateway = gateway
+}
+
+// SetNetworkChaos injects the faults of a chaos config into every request sent to the
+// model server; nil sends requests unchanged
+func (ai *OpenAIService) SetNetworkChaos(config *models.NetworkChaos) error {
+	var chaos *NetworkChaosInjector
+	if config != nil {
+		var err error
+		if chaos, err = NewNetworkChaosInjector(*config); err != nil {
+			return err
+		}
+	}
+
+	ai.endpointMutex.Lock()
+	defer ai.endpointMutex.Unlock()
+	ai.chaos = chaos
+	ai.client = newOpenAIClient(ai.apiKey, ai.baseURL, ai.connection, chaos)
+	return nil
+}
+
+// endpoint returns the client, base URL and static headers requests currently go to
+func (ai *OpenAIService) endpoint() (openai.Client, string, map[string]string) {
+	ai.endpointMutex.RLock()
+	defer ai.endpointMutex.RUnlock()
+	return ai.client, ai.baseURL, ai.connection.Headers
+}
+
+// CheckHealth probes the endpoint requests currently go to with the service's model
+func (ai *OpenAIService) CheckHealth(ctx context.Context, timeout time.Duration) models.EndpointHealth {
+	ai.endpointMutex.RLock()
+	baseURL, connection := ai.baseURL, ai.connection
+	ai.endpointMutex.RUnlock()
+	return CheckEndpointHealth(ctx, ai.apiKey, baseURL, connection, ai.defaultModel, timeout)
+}
+
+// SetReferenceTime sets the "current time" the model is told about in the system prompt
+func (ai *OpenAIService) SetReferenceTime(referenceTime time.Time) {
+	ai.referenceTime = referenceTime
+}
+
+// SetEventBroadcaster sets where iteration and tool call progress events are published
+func (ai *OpenAIService) SetEventBroadcaster(events *EventBroadcaster) {
+	ai.events = events
+}
+
+// SetRateLimiter sets the limiter every chat completion request waits on; nil disables pacing
+func (ai *OpenAIService) SetRateLimiter(limiter *RateLimiter) {
+	ai.limiter = limiter
+}
+
+// SetStreaming sets whether completions are streamed, recording time to first token
+// and tokens per second alongside the total latency
+func (ai *OpenAIService) SetStreaming(streaming bool) {
+	ai.streaming = streaming
+}
+
+// SetToolErrorVerbosity sets how much of a failed tool call is shown to the model
+func (ai *OpenAIService) SetToolErrorVerbosity(verbosity models.ToolErrorVerbosity) {
+	ai.toolErrorVerbosity = verbosity
+}
+
+// SetApprovalRequired sets the tools that need simulated user approval. The first
+// call to each of them in a conversation is denied instead of executed.
+func (ai *OpenAIService) SetApprovalRequired(toolNames []string) error {
+	known := make(map[string]bool)
+	for _, tool := range ai.shoppingTools.GetToolDefinitions() {
+		known[tool.Function.Name] = true
+	}
+
+	required := make(map[string]bool)
+	for _, name := range toolNames {
+		name = strings.TrimSpace(name)
+		if !known[name] {
+			return fmt.Errorf("unknown tool '%s'", name)
+		}
+		required[name] = true
 	}
+	ai.approvalRequired = required
+	return nil
 }
 
-// ProcessChatMessage processes a chat message with test case context for logging
-func (ai *OpenAIService) ProcessChatMessage(ctx context.Context, userMessage string, session *models.ChatSession, testCase string) (*models.ChatResponse, error) {
+// SetToolSpecVersion rewords the tool definitions and system prompt tool list with a
+// tool spec version. Calls to renamed tools are executed and recorded under their
+// canonical names so results stay comparable. A nil version restores the canonical
+// definitions.
+func (ai *OpenAIService) SetToolSpecVersion(version *models.ToolSpecVersion) error {
+	_, aliases, err := ai.shoppingTools.GetToolDefinitionsForVersion(version)
+	if err != nil {
+		return fmt.Errorf("failed to apply tool spec version: %w", err)
+	}
+	ai.toolSpec = version
+	ai.toolAliases = aliases
+	return nil
+}
+
+// ProcessChatMessage processes a chat message with test case context for logging,
+// sampling with the config's temperature, top_p, max_tokens and seed where set and
+// replacing the built-in system prompt with the config's when it has one
+func (ai *OpenAIService) ProcessChatMessage(ctx context.Context, userMessage string, session *models.ChatSession, testCase string, config models.TestConfig) (*models.ChatResponse, error) {
 	// Generate session ID if not provided
 	sessionID := session.SessionID
 	if sessionID == "" {
@@ -82,21 +255,42 @@ func (ai *OpenAIService) ProcessChatMessage(ctx context.Context, userMessage str
 	t := ai.getToolDefinitions()
 
 	// Build messages including conversation history
-	messages := ai.buildMessagesFromSession(session, userMessage)
+	messages := ai.buildMessagesFromSession(session, userMessage, config.SystemPrompt)
+	ai.recordPrompt(session, userMessage, config.SystemPrompt)
 
 	var cartSummary *models.CartSummary
 	var toolResults []models.ToolCallResult
 	var responseMessage string
+	var iterations []models.IterationStats
+	var assistantMessages []models.AssistantMessage
+	deniedTools := make(map[string]bool)
 
 	// Track LLM request metrics
 	var llmRequests int
 	var totalLLMTime time.Duration
+	var totalToolTime time.Duration
+	var streamTimings []streamTiming
+	var parallelTurns int
+	var contextExceeded *models.ContextOverflow
+	var previousToolCalls []openai.ChatCompletionMessageToolCall
+	loopDetected := false
 
-	// Maximum number of tool call iterations
-	maxIterations := 5
 	currentIteration := 0
 
-	for currentIteration < maxIterations {
+	for currentIteration < MaxIterations {
+		// Stop before a request the backend would reject for its size
+		if contextExceeded = ai.checkContextBudget(messages, iterations, config.MaxTokens); contextExceeded != nil {
+			break
+		}
+
+		// Wait for the rate limiter before starting the LLM request clock. Hosted APIs
+		// count the prompt, tool definitions included, and max_tokens against the
+		// tokens-per-minute limit up front.
+		estimatedTokens := ai.nextPromptTokens(messages, iterations) + int64(config.MaxTokens)
+		if err := ai.limiter.Wait(ctx, estimatedTokens); err != nil {
+			return nil, err
+		}
+
 		// Track LLM request time
 		llmStart := time.Now()
 
@@ -107,9 +301,51 @@ func (ai *OpenAIService) ProcessChatMessage(ctx context.Context, userMessage str
 			Tools:       t,
 			Temperature: param.Opt[float64]{Value: 0},
 		}
+		if config.Temperature != nil {
+			requestParams.Temperature = openai.Float(*config.Temperature)
+		}
+		if config.TopP != nil {
+			requestParams.TopP = openai.Float(*config.TopP)
+		}
+		if config.MaxTokens > 0 {
+			requestParams.MaxTokens = openai.Int(int64(config.MaxTokens))
+		}
+		if config.Seed != nil {
+			requestParams.Seed = openai.Int(*config.Seed)
+		}
+		if config.ParallelToolCalls != nil {
+			requestParams.ParallelToolCalls = openai.Bool(*config.ParallelToolCalls)
+		}
+		if ai.streaming {
+			requestParams.StreamOptions.IncludeUsage = openai.Bool(true)
+		}
+
+		if ai.recordRequests {
+			ai.recordRequest(session, requestParams)
+		}
 
 		// Create the chat completion request
-		completion, err := ai.client.Chat.Completions.New(ctx, requestParams)
+		client, baseURL, headers := ai.endpoint()
+		var completion *openai.ChatCompletion
+		var timing streamTiming
+		var err error
+		var httpResponse *http.Response
+		var requestOptions []option.RequestOption
+		if ai.gateway {
+			requestOptions = append(requestOptions, option.WithResponseInto(&httpResponse))
+		}
+		if ai.streaming {
+			completion, timing, err = ai.streamCompletion(ctx, client, requestParams, llmStart, requestOptions...)
+		} else {
+			completion, err = client.Chat.Completions.New(ctx, requestParams, requestOptions...)
+		}
+		if ai.gateway {
+			servedModel := ""
+			if completion != nil {
+				servedModel = completion.Model
+			}
+			recordGatewayRoute(session, ai.defaultModel, servedModel, httpResponse)
+		}
 
 		// Record LLM request metrics
 		llmDuration := time.Since(llmStart)
@@ -119,11 +355,11 @@ func (ai *OpenAIService) ProcessChatMessage(ctx context.Context, userMessage str
 		// Log the request/response or error
 		if ai.logger != nil {
 			if err != nil {
-				if logErr := ai.logger.LogError(testCase, currentIteration+1, requestParams, err, ai.baseURL); logErr != nil {
+				if logErr := ai.logger.LogError(testCase, currentIteration+1, requestParams, err, baseURL, headers); logErr != nil {
 					fmt.Printf("Failed to log error: %v\n", logErr)
 				}
 			} else {
-				if logErr := ai.logger.LogRequest(testCase, currentIteration+1, requestParams, completion, ai.baseURL); logErr != nil {
+				if logErr := ai.logger.LogRequest(testCase, currentIteration+1, requestParams, completion, baseURL, headers); logErr != nil {
 					fmt.Printf("Failed to log request: %v\n", logErr)
 				}
 			}
@@ -132,52 +368,118 @@ func (ai *OpenAIService) ProcessChatMessage(ctx context.Context, userMessage str
 		if err != nil {
 			return nil, fmt.Errorf("failed to get AI response: %w", err)
 		}
+		ai.limiter.Record(estimatedTokens, completion.Usage.TotalTokens)
+
+		// Record the prompt size for this iteration
+		iterationStats := ai.buildIterationStats(currentIteration+1, messages, completion, llmStart, llmDuration)
+		if ai.streaming {
+			iterationStats.TimeToFirstToken = timing.firstToken
+			streamTimings = append(streamTimings, timing)
+		}
+		iterations = append(iterations, iterationStats)
+		ai.events.Publish(models.RunEvent{
+			Type:      models.EventIterationCompleted,
+			TestCase:  testCase,
+			Iteration: iterationStats.Iteration,
+			Data:      iterationStats,
+		})
 
 		// Process the response
 		choice := completion.Choices[0]
 		responseMessage = choice.Message.Content
+		session.Transcript = append(session.Transcript, assistantTranscriptMessage(iterationStats.Iteration, choice.Message))
+		assistantMessages = append(assistantMessages, models.AssistantMessage{
+			Iteration: iterationStats.Iteration,
+			Content:   choice.Message.Content,
+			Refusal:   choice.Message.Refusal,
+			ToolCalls: iterationStats.ToolCalls,
+			Final:     len(choice.Message.ToolCalls) == 0,
+		})
 
 		// If no tool calls, we're done
 		if len(choice.Message.ToolCalls) == 0 {
 			break
 		}
 
+		if len(choice.Message.ToolCalls) > 1 {
+			parallelTurns++
+		}
+
 		// Add the model's function call message to the conversation
 		messages = append(messages, choice.Message.ToParam())
 
 		// Execute tool calls
-		iterationResults, err := ai.toolExecutor.ExecuteToolCalls(ctx, choice.Message.ToolCalls, sessionID)
+		toolStart := time.Now()
+		toolCalls := ai.canonicalToolCalls(choice.Message.ToolCalls)
+		iterationResults, err := ai.executeToolCallsWithApproval(ctx, toolCalls, sessionID, deniedTools)
+		toolTime := time.Since(toolStart)
+		iterations[len(iterations)-1].ToolTime = toolTime
+		totalToolTime += toolTime
 		if err != nil {
 			// Log error but don't stop the loop
 			fmt.Printf("Error executing tool calls: %v\n", err)
 		}
 
+		// Check the arguments' types, properties and enum values against the tool schema
+		for i := range iterationResults {
+			iterationResults[i].Iteration = currentIteration + 1
+			iterationResults[i].EnumChecks, iterationResults[i].EnumViolations = ai.shoppingTools.ValidateEnumArguments(iterationResults[i].ToolName, iterationResults[i].Arguments)
+			iterationResults[i].SchemaChecked, iterationResults[i].SchemaViolations = ai.shoppingTools.ValidateArgumentSchema(iterationResults[i].ToolName, iterationResults[i].Arguments)
+		}
+
+		for _, result := range iterationResults {
+			success := result.Success
+			ai.events.Publish(models.RunEvent{
+				Type:      models.EventToolCalled,
+				TestCase:  testCase,
+				Iteration: currentIteration + 1,
+				ToolName:  result.ToolName,
+				Success:   &success,
+				Data:      map[string]string{"arguments": result.Arguments, "error": result.Error},
+			})
+		}
+
 		// Add results to our collection
 		toolResults = append(toolResults, iterationResults...)
 
 		// Add tool results to the conversation as function call outputs
 		for _, result := range iterationResults {
-			// Convert the result to JSON string
-			resultJSON, err := json.Marshal(result.Result)
+			// Convert the result, or a structured error, to a JSON string
+			content, err := BuildToolMessage(result, ai.toolErrorVerbosity)
 			if err != nil {
 				fmt.Printf("Error marshaling tool result: %v\n", err)
 				continue
 			}
 
 			// Add the function call output message
-			messages = append(messages, openai.ToolMessage(string(resultJSON), result.CallID))
+			messages = append(messages, openai.ToolMessage(content, result.CallID))
+			session.Transcript = append(session.Transcript, models.TranscriptMessage{
+				Role:       models.RoleTool,
+				Iteration:  result.Iteration,
+				Content:    content,
+				ToolCallID: result.CallID,
+				ToolName:   result.ToolName,
+			})
 		}
 
+		// The repeated calls are kept so the evaluator sees them
+		if repeatsToolCalls(previousToolCalls, toolCalls) {
+			loopDetected = true
+			break
+		}
+		previousToolCalls = toolCalls
+
 		currentIteration++
 	}
 
 	// If we hit the maximum iterations, add a warning message
-	if currentIteration >= maxIterations {
+	maxIterationsReached := currentIteration >= MaxIterations
+	if maxIterationsReached {
 		responseMessage = "I've reached the maximum number of operations I can perform. Let me know if you need anything else!"
 	}
 
 	// Get the final cart summary after all tool executions
-	cartSummary = ai.cartService.GetCartSummary(sessionID)
+	cartSummary = ai.sandbox(sessionID).carts.GetCartSummary(sessionID)
 
 	return &models.ChatResponse{
 		Message:      responseMessage,
@@ -187,13 +489,91 @@ func (ai *OpenAIService) ProcessChatMessage(ctx context.Context, userMessage str
 		ToolCalls:    toolResults,
 		LLMRequests:  llmRequests,
 		LLMTotalTime: totalLLMTime,
+		ToolTime:     totalToolTime,
+		Iterations:   iterations,
+
+		AssistantMessages: assistantMessages,
+
+		MaxIterationsReached: maxIterationsReached,
+		ContextExceeded:      contextExceeded,
+		LoopDetected:         loopDetected,
+
+		Streaming: summarizeStreaming(streamTimings),
+		Usage:     responseUsage(iterations),
+
+		ParallelToolCallTurns: parallelTurns,
 	}, nil
 }
 
-// buildMessagesFromSession converts chat session messages to OpenAI format
-func (ai *OpenAIService) buildMessagesFromSession(session *models.ChatSession, userMessage string) []openai.ChatCompletionMessageParamUnion {
+// buildIterationStats records the details of a single iteration, falling back to a
+// character-based prompt size estimate when the backend does not report token usage
+func (ai *OpenAIService) buildIterationStats(iteration int, messages []openai.ChatCompletionMessageParamUnion, completion *openai.ChatCompletion, startedAt time.Time, duration time.Duration) models.IterationStats {
+	stats := models.IterationStats{
+		Iteration:        iteration,
+		MessageCount:     len(messages),
+		PromptTokens:     completion.Usage.PromptTokens,
+		CompletionTokens: completion.Usage.CompletionTokens,
+		TotalTokens:      completion.Usage.TotalTokens,
+		StartedAt:        startedAt,
+		Duration:         duration,
+	}
+
+	if len(completion.Choices) > 0 {
+		choice := completion.Choices[0]
+		stats.FinishReason = choice.FinishReason
+		for _, toolCall := range ai.canonicalToolCalls(choice.Message.ToolCalls) {
+			stats.ToolCalls = append(stats.ToolCalls, toolCall.Function.Name)
+		}
+	}
+
+	if stats.PromptTokens == 0 {
+		stats.PromptTokens = estimatePromptTokens(messages)
+		stats.TokensEstimated = true
+	}
+
+	return stats
+}
+
+// responseUsage sums the token usage of a response's iterations, or returns nil when
+// no completion was received
+func responseUsage(iterations []models.IterationStats) *models.TokenUsage {
+	if len(iterations) == 0 {
+		return nil
+	}
+	usage := models.IterationUsage(iterations)
+	return &usage
+}
+
+// estimatePromptTokens approximates the token count of the messages using the
+// common heuristic of four characters per token
+func estimatePromptTokens(messages []openai.ChatCompletionMessageParamUnion) int64 {
+	data, err := json.Marshal(messages)
+	if err != nil {
+		return 0
+	}
+	return int64(len(data) / 4)
+}
+
+// EstimatePrompt estimates the size of the first request for a prompt, split into the
+// system prompt, the tool definitions and the conversation messages
+func (ai *OpenAIService) EstimatePrompt(userMessage, systemPrompt string) models.PromptEstimate {
+	messages := ai.buildMessagesFromSession(nil, userMessage, systemPrompt)
+	estimate := models.PromptEstimate{
+		SystemTokens:  estimatePromptTokens(messages[:1]),
+		MessageTokens: estimatePromptTokens(messages[1:]),
+	}
+
+	estimate.ToolTokens = ai.estimateToolTokens()
+	estimate.TotalTokens = estimate.SystemTokens + estimate.ToolTokens + estimate.MessageTokens
+
+	return estimate
+}
+
+// buildMessagesFromSession converts chat session messages to OpenAI format, starting
+// with the system prompt (the built-in one when systemPrompt is empty)
+func (ai *OpenAIService) buildMessagesFromSession(session *models.ChatSession, userMessage, systemPrompt string) []openai.ChatCompletionMessageParamUnion {
 	messages := []openai.ChatCompletionMessageParamUnion{
-		openai.SystemMessage(ai.getSystemPrompt()),
+		openai.SystemMessage(ai.getSystemPrompt(systemPrompt)),
 	}
 
 	// Add previous messages from the session (if any)
@@ -217,30 +597,137 @@ func (ai *OpenAIService) buildMessagesFromSession(session *models.ChatSession, u
 	return messages
 }
 
-// getSystemPrompt returns the system prompt for the shopping assistant
-func (ai *OpenAIService) getSystemPrompt() string {
+// recordPrompt adds the messages that open a request to the session's transcript: the
+// system prompt and earlier history on the session's first request, then the user message
+func (ai *OpenAIService) recordPrompt(session *models.ChatSession, userMessage, systemPrompt string) {
+	if len(session.Transcript) == 0 {
+		session.Transcript = append(session.Transcript, models.TranscriptMessage{
+			Role:    models.RoleSystem,
+			Content: ai.getSystemPrompt(systemPrompt),
+		})
+		for _, msg := range session.Messages {
+			role := models.ChatRole(msg.Role)
+			if role == models.RoleUser || role == models.RoleAssistant {
+				session.Transcript = append(session.Transcript, models.TranscriptMessage{Role: role, Content: msg.Content})
+			}
+		}
+	}
+	session.Transcript = append(session.Transcript, models.TranscriptMessage{Role: models.RoleUser, Content: userMessage})
+}
+
+// assistantTranscriptMessage records an assistant message with the tool calls it
+// requested, as the model sent them
+func assistantTranscriptMessage(iteration int, message openai.ChatCompletionMessage) models.TranscriptMessage {
+	transcript := models.TranscriptMessage{
+		Role:      models.RoleAssistant,
+		Iteration: iteration,
+		Content:   message.Content,
+		Refusal:   message.Refusal,
+	}
+	for _, toolCall := range message.ToolCalls {
+		transcript.ToolCalls = append(transcript.ToolCalls, models.TranscriptToolCall{
+			ID:        toolCall.ID,
+			Name:      toolCall.Function.Name,
+			Arguments: toolCall.Function.Arguments,
+		})
+	}
+	return transcript
+}
+
+// getSystemPrompt returns the system prompt for the shopping assistant, or the custom
+// prompt that replaces it. The current date is appended to either.
+func (ai *OpenAIService) getSystemPrompt(custom string) string {
+	if custom != "" {
+		return strings.TrimRight(custom, "\n") + "\n" + ai.getReferenceTimePrompt()
+	}
+	return ai.getBaseSystemPrompt() + ai.getReferenceTimePrompt()
+}
+
+// getReferenceTimePrompt tells the model the current date so relative dates can be grounded
+func (ai *OpenAIService) getReferenceTimePrompt() string {
+	return fmt.Sprintf("\nThe current date and time is %s (%s).\n",
+		ai.referenceTime.Format("Monday, 2006-01-02 15:04 MST"),
+		ai.referenceTime.Format(time.RFC3339))
+}
+
+// getBaseSystemPrompt returns the static part of the shopping assistant system prompt
+func (ai *OpenAIService) getBaseSystemPrompt() string {
 	return `You are a helpful shopping assistant. You can help users search for products, manage their shopping cart, and complete purchases.
 
 Available tools:
-- search_products: Search for products by query, category, or both
-- add_to_cart: Add products to the shopping cart
-- remove_from_cart: Remove products from the shopping cart  
-- view_cart: View current cart contents and totals
-- checkout: Process checkout for the current cart
-
+` + ai.getPromptToolList() + `
 Always be helpful and provide clear information about products and cart operations.
 If the user asks anything else, politely decline and say you are a shopping assistant.
 `
 }
 
+// promptToolSummaries lists the tools described in the system prompt, in order
+var promptToolSummaries = []struct{ name, summary string }{
+	{"search_products", "Search for products by query, category, or both, optionally sorted by price, rating, or relevance"},
+	{"add_to_cart", "Add products to the shopping cart"},
+	{"remove_from_cart", "Remove products from the shopping cart  "},
+	{"view_cart", "View current cart contents and totals"},
+	{"checkout", "Process checkout for the current cart"},
+	{"create_order", "Place an order directly for a list of products with an optional shipping address"},
+	{"schedule_delivery", "Schedule a delivery date (YYYY-MM-DD) and time window"},
+}
+
+// getPromptToolList returns the system prompt's tool list, reworded by the active
+// tool spec version so the prompt never contradicts the tool definitions
+func (ai *OpenAIService) getPromptToolList() string {
+	var sb strings.Builder
+	for _, tool := range promptToolSummaries {
+		name, summary := tool.name, tool.summary
+		if ai.toolSpec != nil {
+			if override, ok := ai.toolSpec.Tools[name]; ok {
+				if override.Name != "" {
+					name = override.Name
+				}
+				if override.Description != "" {
+					summary = override.Description
+				}
+			}
+		}
+		sb.WriteString(fmt.Sprintf("- %s: %s\n", name, summary))
+	}
+	return sb.String()
+}
+
+// ToolDefinitions returns the tool definitions sent with every request, worded by the
+// current tool spec version
+func (ai *OpenAIService) ToolDefinitions() []openai.ChatCompletionToolParam {
+	return ai.getToolDefinitions()
+}
+
 // getToolDefinitions returns the tool definitions for OpenAI function calling
 func (ai *OpenAIService) getToolDefinitions() []openai.ChatCompletionToolParam {
-	return ai.shoppingTools.GetToolDefinitions()
+	definitions, _, err := ai.shoppingTools.GetToolDefinitionsForVersion(ai.toolSpec)
+	if err != nil {
+		// The version was validated by SetToolSpecVersion
+		return ai.shoppingTools.GetToolDefinitions()
+	}
+	return definitions
+}
+
+// canonicalToolCalls maps calls to renamed tools back to their canonical names
+func (ai *OpenAIService) canonicalToolCalls(toolCalls []openai.ChatCompletionMessageToolCall) []openai.ChatCompletionMessageToolCall {
+	if len(ai.toolAliases) == 0 {
+		return toolCalls
+	}
+
+	canonical := make([]openai.ChatCompletionMessageToolCall, len(toolCalls))
+	copy(canonical, toolCalls)
+	for i := range canonical {
+		if name, ok := ai.toolAliases[canonical[i].Function.Name]; ok {
+			canonical[i].Function.Name = name
+		}
+	}
+	return canonical
 }
 
 // InitializeCartForTest initializes the cart with predefined state for testing
 func (ai *OpenAIService) InitializeCartForTest(sessionID string, initialState *models.InitialCartState) error {
-	return ai.cartService.InitializeCartState(sessionID, initialState)
+	return ai.sandbox(sessionID).carts.InitializeCartState(sessionID, initialState)
 }
 
 // generateSessionID generates a random session ID
@@ -0,0 +1,480 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// ParquetType is the type of a Parquet column's values
+type ParquetType int
+
+const (
+	ParquetString    ParquetType = iota // string
+	ParquetInt64                        // int64
+	ParquetDouble                       // float64
+	ParquetBool                         // bool
+	ParquetTimestamp                    // time.Time, stored as UTC milliseconds
+)
+
+// ParquetColumn describes one column of a Parquet file
+type ParquetColumn struct {
+	Name     string
+	Type     ParquetType
+	Optional bool // Rows may hold nil for a null value
+}
+
+// parquetRowGroupRows is how many rows are buffered before they are written out as a
+// row group, so readers can skip through large files
+const parquetRowGroupRows = 100000
+
+// ParquetWriter writes rows to a flat Parquet file: one uncompressed, PLAIN-encoded
+// data page per column and row group, which every Parquet reader (pandas, pyarrow,
+// duckdb, Spark) can load
+type ParquetWriter struct {
+	w         io.Writer
+	offset    int64
+	columns   []ParquetColumn
+	rows      [][]interface{}
+	rowGroups []parquetRowGroup
+	numRows   int64
+}
+
+// parquetRowGroup records where a written row group's column chunks are
+type parquetRowGroup struct {
+	numRows int64
+	chunks  []parquetColumnChunk
+}
+
+// parquetColumnChunk records where a column chunk's single data page was written
+type parquetColumnChunk struct {
+	offset    int64
+	size      int64
+	numValues int64
+}
+
+// Parquet format constants, from parquet.thrift
+const (
+	parquetBooleanType   = 0
+	parquetInt64Type     = 2
+	parquetDoubleType    = 5
+	parquetByteArrayType = 6
+
+	parquetRequired = 0
+	parquetOptional = 1
+
+	parquetUTF8            = 0 // ConvertedType
+	parquetTimestampMillis = 9 // ConvertedType
+
+	parquetPlain = 0 // Encoding
+	parquetRLE   = 3 // Encoding
+
+	parquetUncompressed = 0 // CompressionCodec
+	parquetDataPage     = 0 // PageType
+)
+
+// parquetMagic opens and closes every Parquet file
+var parquetMagic = []byte("PAR1")
+
+// NewParquetWriter starts a Parquet file with the given columns
+func NewParquetWriter(w io.Writer, columns []ParquetColumn) (*ParquetWriter, error) {
+	pw := &ParquetWriter{w: w, columns: columns}
+	if err := pw.write(parquetMagic); err != nil {
+		return nil, err
+	}
+	return pw, nil
+}
+
+// Write adds a row holding one value per column, in column order
+func (pw *ParquetWriter) Write(row []interface{}) error {
+	if len(row) != len(pw.columns) {
+		return fmt.Errorf("row has %d values for %d columns", len(row), len(pw.columns))
+	}
+	for i, column := range pw.columns {
+		if err := checkParquetValue(column, row[i]); err != nil {
+			return err
+		}
+	}
+	pw.rows = append(pw.rows, row)
+	if len(pw.rows) >= parquetRowGroupRows {
+		return pw.flushRowGroup()
+	}
+	return nil
+}
+
+// Close writes the buffered rows and the file footer
+func (pw *ParquetWriter) Close() error {
+	if len(pw.rows) > 0 {
+		if err := pw.flushRowGroup(); err != nil {
+			return err
+		}
+	}
+
+	footer := pw.encodeFileMetaData()
+	length := binary.LittleEndian.AppendUint32(nil, uint32(len(footer)))
+	for _, data := range [][]byte{footer, length, parquetMagic} {
+		if err := pw.write(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// write writes data to the file, tracking the offset for the footer
+func (pw *ParquetWriter) write(data []byte) error {
+	n, err := pw.w.Write(data)
+	pw.offset += int64(n)
+	if err != nil {
+		return fmt.Errorf("failed to write parquet data: %w", err)
+	}
+	return nil
+}
+
+// checkParquetValue checks a value has the Go type its column stores
+func checkParquetValue(column ParquetColumn, value interface{}) error {
+	if value == nil {
+		if !column.Optional {
+			return fmt.Errorf("column %s is required but the value is nil", column.Name)
+		}
+		return nil
+	}
+
+	var ok bool
+	switch column.Type {
+	case ParquetString:
+		_, ok = value.(string)
+	case ParquetInt64:
+		_, ok = value.(int64)
+	case ParquetDouble:
+		_, ok = value.(float64)
+	case ParquetBool:
+		_, ok = value.(bool)
+	case ParquetTimestamp:
+		_, ok = value.(time.Time)
+	}
+	if !ok {
+		return fmt.Errorf("column %s has a value of unexpected type %T", column.Name, value)
+	}
+	return nil
+}
+
+// flushRowGroup writes the buffered rows as a row group, one data page per column
+func (pw *ParquetWriter) flushRowGroup() error {
+	group := parquetRowGroup{numRows: int64(len(pw.rows))}
+	for i, column := range pw.columns {
+		page := encodeParquetPage(column, pw.rows, i)
+
+		header := &thriftEncoder{}
+		header.i32(1, parquetDataPage)
+		header.i32(2, int32(len(page)))
+		header.i32(3, int32(len(page)))
+		header.structField(5) // DataPageHeader
+		header.i32(1, int32(len(pw.rows)))
+		header.i32(2, parquetPlain)
+		header.i32(3, parquetRLE)
+		header.i32(4, parquetRLE)
+		header.endStruct()
+		header.stop()
+
+		chunk := parquetColumnChunk{offset: pw.offset, numValues: int64(len(pw.rows))}
+		if err := pw.write(header.buf.Bytes()); err != nil {
+			return err
+		}
+		if err := pw.write(page); err != nil {
+			return err
+		}
+		chunk.size = pw.offset - chunk.offset
+		group.chunks = append(group.chunks, chunk)
+	}
+
+	pw.rowGroups = append(pw.rowGroups, group)
+	pw.numRows += group.numRows
+	pw.rows = pw.rows[:0]
+	return nil
+}
+
+// encodeParquetPage encodes column i of the rows as the body of a data page: the
+// definition levels of an optional column, then its non-null values
+func encodeParquetPage(column ParquetColumn, rows [][]interface{}, i int) []byte {
+	var page, values bytes.Buffer
+	var bits []bool
+	defined := make([]bool, len(rows))
+
+	for r, row := range rows {
+		value := row[i]
+		if value == nil {
+			continue
+		}
+		defined[r] = true
+
+		switch v := value.(type) {
+		case string:
+			values.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(v))))
+			values.WriteString(v)
+		case int64:
+			values.Write(binary.LittleEndian.AppendUint64(nil, uint64(v)))
+		case float64:
+			values.Write(binary.LittleEndian.AppendUint64(nil, math.Float64bits(v)))
+		case bool:
+			bits = append(bits, v)
+		case time.Time:
+			values.Write(binary.LittleEndian.AppendUint64(nil, uint64(v.UnixMilli())))
+		}
+	}
+
+	// Booleans are bit-packed, least significant bit first
+	if column.Type == ParquetBool {
+		packed := make([]byte, (len(bits)+7)/8)
+		for b, bit := range bits {
+			if bit {
+				packed[b/8] |= 1 << (b % 8)
+			}
+		}
+		values.Write(packed)
+	}
+
+	if column.Optional {
+		levels := encodeDefinitionLevels(defined)
+		page.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(levels))))
+		page.Write(levels)
+	}
+	page.Write(values.Bytes())
+	return page.Bytes()
+}
+
+// encodeDefinitionLevels encodes whether each value is defined as RLE runs of 1-bit
+// levels, the RLE/bit-packing hybrid's simplest form
+func encodeDefinitionLevels(defined []bool) []byte {
+	var levels []byte
+	for start := 0; start < len(defined); {
+		end := start
+		for end < len(defined) && defined[end] == defined[start] {
+			end++
+		}
+		levels = binary.AppendUvarint(levels, uint64(end-start)<<1)
+		if defined[start] {
+			levels = append(levels, 1)
+		} else {
+			levels = append(levels, 0)
+		}
+		start = end
+	}
+	return levels
+}
+
+// encodeFileMetaData encodes the footer: the schema and where each row group's
+// column chunks are
+func (pw *ParquetWriter) encodeFileMetaData() []byte {
+	meta := &thriftEncoder{}
+	meta.i32(1, 1) // Format version
+
+	meta.list(2, compactStruct, len(pw.columns)+1)
+	meta.beginStruct() // Root of the schema
+	meta.str(4, "schema")
+	meta.i32(5, int32(len(pw.columns)))
+	meta.endStruct()
+	for _, column := range pw.columns {
+		meta.beginStruct()
+		repetition := int32(parquetRequired)
+		if column.Optional {
+			repetition = parquetOptional
+		}
+		switch column.Type {
+		case ParquetString:
+			meta.i32(1, parquetByteArrayType)
+			meta.i32(3, repetition)
+			meta.str(4, column.Name)
+			meta.i32(6, parquetUTF8)
+			meta.structField(10) // LogicalType
+			meta.structField(1)  // STRING
+			meta.endStruct()
+			meta.endStruct()
+		case ParquetTimestamp:
+			meta.i32(1, parquetInt64Type)
+			meta.i32(3, repetition)
+			meta.str(4, column.Name)
+			meta.i32(6, parquetTimestampMillis)
+			meta.structField(10) // LogicalType
+			meta.structField(8)  // TIMESTAMP
+			meta.boolean(1, true)
+			meta.structField(2) // Unit
+			meta.structField(1) // MILLIS
+			meta.endStruct()
+			meta.endStruct()
+			meta.endStruct()
+			meta.endStruct()
+		default:
+			meta.i32(1, parquetPhysicalType(column.Type))
+			meta.i32(3, repetition)
+			meta.str(4, column.Name)
+		}
+		meta.endStruct()
+	}
+
+	meta.i64(3, pw.numRows)
+
+	meta.list(4, compactStruct, len(pw.rowGroups))
+	for _, group := range pw.rowGroups {
+		meta.beginStruct()
+		var size int64
+		meta.list(1, compactStruct, len(group.chunks))
+		for i, chunk := range group.chunks {
+			column := pw.columns[i]
+			size += chunk.size
+
+			meta.beginStruct()
+			meta.i64(2, chunk.offset)
+			meta.structField(3) // ColumnMetaData
+			meta.i32(1, parquetPhysicalType(column.Type))
+			meta.list(2, compactI32, 2)
+			meta.listI32(parquetPlain)
+			meta.listI32(parquetRLE)
+			meta.list(3, compactBinary, 1)
+			meta.listStr(column.Name)
+			meta.i32(4, parquetUncompressed)
+			meta.i64(5, chunk.numValues)
+			meta.i64(6, chunk.size)
+			meta.i64(7, chunk.size)
+			meta.i64(9, chunk.offset)
+			meta.endStruct()
+			meta.endStruct()
+		}
+		meta.i64(2, size)
+		meta.i64(3, group.numRows)
+		meta.endStruct()
+	}
+
+	meta.str(6, "model-test")
+	meta.stop()
+	return meta.buf.Bytes()
+}
+
+// parquetPhysicalType returns how a column's values are stored
+func parquetPhysicalType(t ParquetType) int32 {
+	switch t {
+	case ParquetInt64, ParquetTimestamp:
+		return parquetInt64Type
+	case ParquetDouble:
+		return parquetDoubleType
+	case ParquetBool:
+		return parquetBooleanType
+	default:
+		return parquetByteArrayType
+	}
+}
+
+// Thrift compact protocol type codes
+const (
+	compactTrue   = 1
+	compactFalse  = 2
+	compactI32    = 5
+	compactI64    = 6
+	compactBinary = 8
+	compactList   = 9
+	compactStruct = 12
+)
+
+// thriftEncoder writes the Thrift compact protocol Parquet uses for its page headers
+// and footer. Fields must be written in increasing id order within a struct.
+type thriftEncoder struct {
+	buf    bytes.Buffer
+	lastID int16
+	stack  []int16 // Last field id of each enclosing struct
+}
+
+// field writes a field header, as a delta from the previous field id when it fits
+func (e *thriftEncoder) field(id int16, kind byte) {
+	if delta := id - e.lastID; delta > 0 && delta <= 15 {
+		e.buf.WriteByte(byte(delta)<<4 | kind)
+	} else {
+		e.buf.WriteByte(kind)
+		e.varint(zigzag(int64(id)))
+	}
+	e.lastID = id
+}
+
+// varint writes an unsigned LEB128 integer
+func (e *thriftEncoder) varint(v uint64) {
+	e.buf.Write(binary.AppendUvarint(nil, v))
+}
+
+// zigzag maps signed integers to unsigned ones so small magnitudes stay short
+func zigzag(v int64) uint64 {
+	return uint64((v << 1) ^ (v >> 63))
+}
+
+// i32 writes a 32-bit integer (or enum) field
+func (e *thriftEncoder) i32(id int16, v int32) {
+	e.field(id, compactI32)
+	e.varint(zigzag(int64(v)))
+}
+
+// i64 writes a 64-bit integer field
+func (e *thriftEncoder) i64(id int16, v int64) {
+	e.field(id, compactI64)
+	e.varint(zigzag(v))
+}
+
+// str writes a string field
+func (e *thriftEncoder) str(id int16, v string) {
+	e.field(id, compactBinary)
+	e.listStr(v)
+}
+
+// boolean writes a bool field, whose value is carried by its type code
+func (e *thriftEncoder) boolean(id int16, v bool) {
+	if v {
+		e.field(id, compactTrue)
+	} else {
+		e.field(id, compactFalse)
+	}
+}
+
+// structField starts a struct-valued field; close it with endStruct
+func (e *thriftEncoder) structField(id int16) {
+	e.field(id, compactStruct)
+	e.beginStruct()
+}
+
+// beginStruct starts a struct, such as a list element; close it with endStruct
+func (e *thriftEncoder) beginStruct() {
+	e.stack = append(e.stack, e.lastID)
+	e.lastID = 0
+}
+
+// endStruct ends the innermost struct
+func (e *thriftEncoder) endStruct() {
+	e.stop()
+	e.lastID = e.stack[len(e.stack)-1]
+	e.stack = e.stack[:len(e.stack)-1]
+}
+
+// stop ends the top-level struct
+func (e *thriftEncoder) stop() {
+	e.buf.WriteByte(0)
+}
+
+// list writes a list field header; the elements follow
+func (e *thriftEncoder) list(id int16, kind byte, size int) {
+	e.field(id, compactList)
+	if size < 15 {
+		e.buf.WriteByte(byte(size)<<4 | kind)
+	} else {
+		e.buf.WriteByte(0xF0 | kind)
+		e.varint(uint64(size))
+	}
+}
+
+// listI32 writes a 32-bit integer list element
+func (e *thriftEncoder) listI32(v int32) {
+	e.varint(zigzag(int64(v)))
+}
+
+// listStr writes a string list element
+func (e *thriftEncoder) listStr(v string) {
+	e.varint(uint64(len(v)))
+	e.buf.WriteString(v)
+}
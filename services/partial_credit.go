@@ -0,0 +1,122 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+
+	"model-test/models"
+)
+
+// ParseScoringMode validates a scoring mode name
+func ParseScoringMode(value string) (models.ScoringMode, error) {
+	switch mode := models.ScoringMode(value); mode {
+	case models.ScoringBinary, models.ScoringPartial:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown scoring mode '%s' (expected binary or partial)", value)
+	}
+}
+
+// scorePartialCredit scores the calls against every expected path and keeps the best.
+// A passing test scores 1 against the path it matched. A test expecting no tools scores
+// 1 without calls and 0 with any.
+func (ev *Evaluator) scorePartialCredit(testCase models.TestCase, actual []models.ActualToolCall, outcome evaluation) *models.PartialScore {
+	if outcome.success {
+		return &models.PartialScore{Score: 1, SequenceOverlap: 1, ArgumentMatch: 1, Variant: outcome.matchedPath}
+	}
+	if len(testCase.ExpectedToolVariants) == 0 {
+		score := 0.0
+		if len(actual) == 0 {
+			score = 1
+		}
+		return &models.PartialScore{Score: score, SequenceOverlap: score, ArgumentMatch: score}
+	}
+
+	var best *models.PartialScore
+	for _, variant := range testCase.ExpectedToolVariants {
+		score := ev.scoreVariant(variant, actual)
+		if best == nil || score.Score > best.Score {
+			best = score
+		}
+	}
+	return best
+}
+
+// scoreVariant pairs the calls with a path's calls along their longest common
+// subsequence of tool names (in sorted order for a path with order any) and credits
+// the expected arguments the paired calls match. Without expected arguments, argument
+// match follows the sequence overlap.
+func (ev *Evaluator) scoreVariant(variant models.ExpectedToolPath, actual []models.ActualToolCall) *models.PartialScore {
+	expected := variant.Tools
+	if variant.Order == models.ToolOrderAny {
+		expected = append([]models.ExpectedToolCall(nil), expected...)
+		sort.SliceStable(expected, func(i, j int) bool { return expected[i].Name < expected[j].Name })
+		actual = append([]models.ActualToolCall(nil), actual...)
+		sort.SliceStable(actual, func(i, j int) bool { return actual[i].Name < actual[j].Name })
+	}
+
+	score := &models.PartialScore{Variant: variant.Name}
+	if len(expected)+len(actual) == 0 {
+		score.Score, score.SequenceOverlap, score.ArgumentMatch = 1, 1, 1
+		return score
+	}
+
+	pairs := longestCommonCalls(expected, actual)
+	score.SequenceOverlap = 2 * float64(len(pairs)) / float64(len(expected)+len(actual))
+
+	// Unpaired expected calls fail their required and absent-argument checks
+	totalArguments, matchedArguments := 0, 0
+	paired := make(map[int]bool, len(pairs))
+	for _, pair := range pairs {
+		passed, total := ev.argumentChecks(expected[pair[0]], actual[pair[1]])
+		matchedArguments += passed
+		totalArguments += total
+		paired[pair[0]] = true
+	}
+	for i, call := range expected {
+		if !paired[i] {
+			totalArguments += len(call.Arguments) + len(call.AbsentArguments)
+		}
+	}
+	if totalArguments > 0 {
+		score.ArgumentMatch = float64(matchedArguments) / float64(totalArguments)
+	} else {
+		score.ArgumentMatch = score.SequenceOverlap
+	}
+
+	score.Score = (score.SequenceOverlap + score.ArgumentMatch) / 2
+	return score
+}
+
+// longestCommonCalls returns the index pairs (expected, actual) of a longest common
+// subsequence of tool names
+func longestCommonCalls(expected []models.ExpectedToolCall, actual []models.ActualToolCall) [][2]int {
+	lengths := make([][]int, len(expected)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(actual)+1)
+	}
+	for i := len(expected) - 1; i >= 0; i-- {
+		for j := len(actual) - 1; j >= 0; j-- {
+			if expected[i].Name == actual[j].Name {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else {
+				lengths[i][j] = max(lengths[i+1][j], lengths[i][j+1])
+			}
+		}
+	}
+
+	var pairs [][2]int
+	for i, j := 0, 0; i < len(expected) && j < len(actual); {
+		switch {
+		case expected[i].Name == actual[j].Name:
+			pairs = append(pairs, [2]int{i, j})
+			i++
+			j++
+		case lengths[i+1][j] >= lengths[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return pairs
+}
@@ -2,6 +2,7 @@ package services
 
 import (
 	"model-test/models"
+	"sort"
 	"strings"
 )
 
@@ -27,7 +28,7 @@ func (ps *ProductService) SearchProducts(filter models.ProductFilter) ([]models.
 		limit = 10
 	}
 
-	for _, product := range ps.products {
+	for _, product := range ps.sortedProducts(filter.SortBy) {
 		// Filter by category if specified
 		if filter.Category != "" && !strings.EqualFold(product.Category, filter.Category) {
 			continue
@@ -55,6 +56,26 @@ func (ps *ProductService) SearchProducts(filter models.ProductFilter) ([]models.
 	return results, nil
 }
 
+// sortedProducts returns the catalog ordered by the requested sort key.
+// "relevance" (or no key) keeps the catalog order.
+func (ps *ProductService) sortedProducts(sortBy string) []models.Product {
+	products := make([]models.Product, len(ps.products))
+	copy(products, ps.products)
+
+	switch sortBy {
+	case "price":
+		sort.SliceStable(products, func(i, j int) bool {
+			return products[i].Price < products[j].Price
+		})
+	case "rating":
+		sort.SliceStable(products, func(i, j int) bool {
+			return products[i].Rating > products[j].Rating
+		})
+	}
+
+	return products
+}
+
 // getMockProducts returns a list of mock products for testing
 func getMockProducts() []models.Product {
 	return []models.Product{
@@ -63,6 +84,7 @@ func getMockProducts() []models.Product {
 			Category:    "electronics",
 			Price:       999.99,
 			Description: "Latest Apple smartphone with advanced features",
+			Rating:      4.7,
 			InStock:     true,
 		},
 		{
@@ -70,6 +92,7 @@ func getMockProducts() []models.Product {
 			Category:    "electronics",
 			Price:       899.99,
 			Description: "Premium Android smartphone with excellent camera",
+			Rating:      4.6,
 			InStock:     true,
 		},
 		{
@@ -77,6 +100,7 @@ func getMockProducts() []models.Product {
 			Category:    "electronics",
 			Price:       199.99,
 			Description: "High-quality wireless headphones with noise cancellation",
+			Rating:      4.4,
 			InStock:     true,
 		},
 		{
@@ -84,6 +108,7 @@ func getMockProducts() []models.Product {
 			Category:    "electronics",
 			Price:       1999.99,
 			Description: "Professional laptop for developers and creators",
+			Rating:      4.8,
 			InStock:     true,
 		},
 		{
@@ -91,6 +116,7 @@ func getMockProducts() []models.Product {
 			Category:    "clothing",
 			Price:       129.99,
 			Description: "Comfortable running shoes for daily exercise",
+			Rating:      4.3,
 			InStock:     true,
 		},
 		{
@@ -98,6 +124,7 @@ func getMockProducts() []models.Product {
 			Category:    "clothing",
 			Price:       89.99,
 			Description: "Warm winter jacket for cold weather",
+			Rating:      4.1,
 			InStock:     true,
 		},
 		{
@@ -105,6 +132,7 @@ func getMockProducts() []models.Product {
 			Category:    "home",
 			Price:       79.99,
 			Description: "Automatic coffee maker for perfect morning brew",
+			Rating:      4.2,
 			InStock:     true,
 		},
 		{
@@ -112,6 +140,7 @@ func getMockProducts() []models.Product {
 			Category:    "home",
 			Price:       149.99,
 			Description: "Powerful vacuum cleaner for home cleaning",
+			Rating:      3.9,
 			InStock:     true,
 		},
 		{
@@ -119,6 +148,7 @@ func getMockProducts() []models.Product {
 			Category:    "books",
 			Price:       49.99,
 			Description: "Learn programming with this comprehensive guide",
+			Rating:      4.6,
 			InStock:     true,
 		},
 		{
@@ -126,6 +156,7 @@ func getMockProducts() []models.Product {
 			Category:    "books",
 			Price:       29.99,
 			Description: "Delicious recipes for home cooking",
+			Rating:      4.5,
 			InStock:     true,
 		},
 		{
@@ -133,6 +164,7 @@ func getMockProducts() []models.Product {
 			Category:    "sports",
 			Price:       159.99,
 			Description: "Professional tennis racket for competitive play",
+			Rating:      4.0,
 			InStock:     true,
 		},
 		{
@@ -140,6 +172,7 @@ func getMockProducts() []models.Product {
 			Category:    "sports",
 			Price:       39.99,
 			Description: "Non-slip yoga mat for comfortable practice",
+			Rating:      4.4,
 			InStock:     true,
 		},
 		{
@@ -147,6 +180,7 @@ func getMockProducts() []models.Product {
 			Category:    "beauty",
 			Price:       24.99,
 			Description: "Moisturizing face cream for healthy skin",
+			Rating:      3.8,
 			InStock:     true,
 		},
 		{
@@ -154,6 +188,7 @@ func getMockProducts() []models.Product {
 			Category:    "beauty",
 			Price:       12.99,
 			Description: "Gentle shampoo for all hair types",
+			Rating:      4.1,
 			InStock:     true,
 		},
 		{
@@ -161,6 +196,7 @@ func getMockProducts() []models.Product {
 			Category:    "toys",
 			Price:       34.99,
 			Description: "Fun board game for family entertainment",
+			Rating:      4.7,
 			InStock:     true,
 		},
 		{
@@ -168,6 +204,7 @@ func getMockProducts() []models.Product {
 			Category:    "toys",
 			Price:       19.99,
 			Description: "Collectible action figure for kids and collectors",
+			Rating:      4.2,
 			InStock:     true,
 		},
 		{
@@ -175,6 +212,7 @@ func getMockProducts() []models.Product {
 			Category:    "food",
 			Price:       4.99,
 			Description: "Organic whole wheat pasta for healthy meals",
+			Rating:      4.3,
 			InStock:     true,
 		},
 		{
@@ -182,6 +220,7 @@ func getMockProducts() []models.Product {
 			Category:    "food",
 			Price:       8.99,
 			Description: "Premium green tea with antioxidants",
+			Rating:      4.5,
 			InStock:     true,
 		},
 	}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ProxyDirect as a proxy setting connects straight to the server, ignoring the proxy
+// environment variables
+const ProxyDirect = "direct"
+
+// ValidateProxy checks a proxy setting: empty (the HTTP_PROXY, HTTPS_PROXY and
+// NO_PROXY environment variables decide), ProxyDirect, or an http, https, socks5 or
+// socks5h URL
+func ValidateProxy(proxy string) error {
+	if proxy == "" || proxy == ProxyDirect {
+		return nil
+	}
+	_, err := parseProxyURL(proxy)
+	return err
+}
+
+// parseProxyURL parses a proxy URL with a supported scheme and a host
+func parseProxyURL(proxy string) (*url.URL, error) {
+	proxyURL, err := url.Parse(proxy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy '%s': %w", proxy, err)
+	}
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("invalid proxy '%s': scheme must be http, https, socks5 or socks5h", proxy)
+	}
+	if proxyURL.Host == "" {
+		return nil, fmt.Errorf("invalid proxy '%s': no host", proxy)
+	}
+	return proxyURL, nil
+}
+
+// newHTTPTransport returns a transport with the default dialing and pooling that sends
+// requests through the proxy setting (see ValidateProxy), skipping certificate
+// verification when insecureTLS is set. An invalid proxy falls back to the environment;
+// settings are validated when they are loaded.
+func newHTTPTransport(proxy string, insecureTLS bool) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	switch proxy {
+	case "":
+		transport.Proxy = http.ProxyFromEnvironment
+	case ProxyDirect:
+		transport.Proxy = nil
+	default:
+		if proxyURL, err := parseProxyURL(proxy); err == nil {
+			transport.Proxy = http.ProxyURL(proxyURL)
+		}
+	}
+	if insecureTLS {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	return transport
+}
+
+// endpointHTTPClient returns the HTTP client for a model server: it goes through the
+// proxy setting and skips certificate verification for localhost HTTPS endpoints
+// (Kamiwaza, etc.)
+func endpointHTTPClient(baseURL, proxy string) *http.Client {
+	insecureTLS := strings.HasPrefix(baseURL, "https://localhost") || strings.Contains(baseURL, "https://127.0.0.1")
+	return &http.Client{Transport: newHTTPTransport(proxy, insecureTLS)}
+}
+
+// DescribeProxy names a proxy setting for output, without its credentials
+func DescribeProxy(proxy string) string {
+	switch proxy {
+	case "":
+		return "from environment"
+	case ProxyDirect:
+		return "none (direct)"
+	}
+	if proxyURL, err := url.Parse(proxy); err == nil && proxyURL.User != nil {
+		proxyURL.User = url.User("***")
+		return proxyURL.String()
+	}
+	return proxy
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"fmt"
+
+	"model-test/models"
+)
+
+// PruneReport returns a copy of the report with the policy applied to every response
+// and transcript, recording the policy on it. The original report is not modified.
+func PruneReport(report *models.AgentReport, policy models.PruningPolicy) *models.AgentReport {
+	pruned := *report
+	pruned.Pruning = &policy
+	pruned.Results = make([]models.AgentTestResult, len(report.Results))
+	for i, result := range report.Results {
+		if result.Response != nil {
+			result.Response = pruneResponse(result.Response, policy)
+		}
+		if result.Transcript != nil {
+			result.Transcript = pruneTranscript(result.Transcript, policy)
+		}
+		pruned.Results[i] = result
+	}
+	return &pruned
+}
+
+// pruneResponse copies a response, dropping tool result payloads and cutting
+// assistant message text as the policy asks
+func pruneResponse(response *models.ChatResponse, policy models.PruningPolicy) *models.ChatResponse {
+	pruned := *response
+	pruned.Message = truncateMessage(response.Message, policy.MaxMessageLength)
+
+	pruned.ToolCalls = make([]models.ToolCallResult, len(response.ToolCalls))
+	for i, toolCall := range response.ToolCalls {
+		if policy.DropToolResults {
+			toolCall.Result = nil
+		}
+		pruned.ToolCalls[i] = toolCall
+	}
+
+	pruned.AssistantMessages = make([]models.AssistantMessage, len(response.AssistantMessages))
+	for i, message := range response.AssistantMessages {
+		message.Content = truncateMessage(message.Content, policy.MaxMessageLength)
+		message.Refusal = truncateMessage(message.Refusal, policy.MaxMessageLength)
+		pruned.AssistantMessages[i] = message
+	}
+	return &pruned
+}
+
+// pruneTranscript copies a transcript, dropping the content of tool messages and
+// cutting assistant message text as the policy asks. The prompts are kept whole.
+func pruneTranscript(transcript []models.TranscriptMessage, policy models.PruningPolicy) []models.TranscriptMessage {
+	pruned := make([]models.TranscriptMessage, len(transcript))
+	for i, message := range transcript {
+		switch message.Role {
+		case models.RoleTool:
+			if policy.DropToolResults {
+				message.Content = ""
+			}
+		case models.RoleAssistant:
+			message.Content = truncateMessage(message.Content, policy.MaxMessageLength)
+			message.Refusal = truncateMessage(message.Refusal, policy.MaxMessageLength)
+		}
+		pruned[i] = message
+	}
+	return pruned
+}
+
+// truncateMessage cuts text to at most limit characters, noting how many were
+// removed; a limit of 0 keeps the text whole
+func truncateMessage(text string, limit int) string {
+	runes := []rune(text)
+	if limit <= 0 || len(runes) <= limit {
+		return text
+	}
+	return fmt.Sprintf("%s… [truncated %d characters]", string(runes[:limit]), len(runes)-limit)
+}
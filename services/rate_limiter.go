@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// RateLimiter paces chat completion requests to a hosted API's requests-per-minute and
+// tokens-per-minute limits. It is shared by every test goroutine of a run, so
+// concurrent tests draw from the same budget. A nil limiter never waits.
+type RateLimiter struct {
+	mutex    sync.Mutex
+	requests *tokenBucket // nil when requests are not limited
+	tokens   *tokenBucket // nil when tokens are not limited
+	waited   time.Duration
+	delayed  int
+}
+
+// tokenBucket holds up to a minute's worth of capacity and refills continuously
+type tokenBucket struct {
+	capacity  float64
+	available float64
+	perSecond float64
+	updated   time.Time
+}
+
+// NewRateLimiter creates a limiter for the given per-minute limits; zero disables a
+// limit, and nil is returned when neither is set
+func NewRateLimiter(requestsPerMinute, tokensPerMinute int) (*RateLimiter, error) {
+	if requestsPerMinute < 0 || tokensPerMinute < 0 {
+		return nil, fmt.Errorf("rate limits must not be negative, got %d requests/min and %d tokens/min", requestsPerMinute, tokensPerMinute)
+	}
+	if requestsPerMinute == 0 && tokensPerMinute == 0 {
+		return nil, nil
+	}
+
+	now := time.Now()
+	return &RateLimiter{
+		requests: newTokenBucket(requestsPerMinute, now),
+		tokens:   newTokenBucket(tokensPerMinute, now),
+	}, nil
+}
+
+// newTokenBucket creates a full bucket for a per-minute limit, or nil when the limit is zero
+func newTokenBucket(perMinute int, now time.Time) *tokenBucket {
+	if perMinute == 0 {
+		return nil
+	}
+	return &tokenBucket{
+		capacity:  float64(perMinute),
+		available: float64(perMinute),
+		perSecond: float64(perMinute) / 60,
+		updated:   now,
+	}
+}
+
+// refill adds the capacity that accrued since the last update
+func (b *tokenBucket) refill(now time.Time) {
+	if b == nil {
+		return
+	}
+	b.available = math.Min(b.capacity, b.available+now.Sub(b.updated).Seconds()*b.perSecond)
+	b.updated = now
+}
+
+// delay returns how long until the bucket holds the amount, which is capped at the
+// bucket's capacity so a single oversized request can still go through
+func (b *tokenBucket) delay(amount float64) time.Duration {
+	if b == nil {
+		return 0
+	}
+	amount = math.Min(amount, b.capacity)
+	if b.available >= amount {
+		return 0
+	}
+	return time.Duration((amount - b.available) / b.perSecond * float64(time.Second))
+}
+
+// take removes the amount from the bucket; token usage may leave it in debt
+func (b *tokenBucket) take(amount float64) {
+	if b != nil {
+		b.available -= amount
+	}
+}
+
+// Wait blocks until a request estimated to use the given number of tokens fits within
+// both limits, then reserves it. It returns early with an error when ctx is cancelled.
+func (rl *RateLimiter) Wait(ctx context.Context, estimatedTokens int64) error {
+	if rl == nil {
+		return nil
+	}
+
+	start := time.Now()
+	delayed := false
+	for {
+		rl.mutex.Lock()
+		now := time.Now()
+		rl.requests.refill(now)
+		rl.tokens.refill(now)
+
+		delay := max(rl.requests.delay(1), rl.tokens.delay(float64(estimatedTokens)))
+		if delay == 0 {
+			rl.requests.take(1)
+			rl.tokens.take(float64(estimatedTokens))
+			if delayed {
+				rl.waited += now.Sub(start)
+				rl.delayed++
+			}
+			rl.mutex.Unlock()
+			return nil
+		}
+		rl.mutex.Unlock()
+		delayed = true
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("rate limiter wait cancelled: %w", ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// Record corrects a reservation made by Wait with the tokens the request actually
+// used, as reported by the backend
+func (rl *RateLimiter) Record(estimatedTokens, actualTokens int64) {
+	if rl == nil || rl.tokens == nil || actualTokens <= 0 {
+		return
+	}
+
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+	rl.tokens.refill(time.Now())
+	rl.tokens.available = math.Min(rl.tokens.capacity, rl.tokens.available+float64(estimatedTokens-actualTokens))
+}
+
+// Delays returns how many requests had to wait and for how long in total
+func (rl *RateLimiter) Delays() (int, time.Duration) {
+	if rl == nil {
+		return 0, 0
+	}
+
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+	return rl.delayed, rl.waited
+}
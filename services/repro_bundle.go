@@ -0,0 +1,172 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/openai/openai-go"
+
+	"model-test/models"
+)
+
+// unsafeBundleChars are replaced in repro bundle directory names
+var unsafeBundleChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// SetRecordRequests sets whether each session keeps the bodies of the requests sent
+// for it, which repro bundles replay
+func (ai *OpenAIService) SetRecordRequests(record bool) {
+	ai.recordRequests = record
+}
+
+// recordRequest appends a request body to the session as it goes over the wire; a
+// streamed request carries the stream flag the client adds
+func (ai *OpenAIService) recordRequest(session *models.ChatSession, params openai.ChatCompletionNewParams) {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return
+	}
+	if ai.streaming {
+		var fields map[string]json.RawMessage
+		if json.Unmarshal(body, &fields) == nil {
+			fields["stream"] = json.RawMessage("true")
+			if streamed, err := json.Marshal(fields); err == nil {
+				body = streamed
+			}
+		}
+	}
+	session.Requests = append(session.Requests, body)
+}
+
+// reproTarget returns the endpoint and headers the service's requests go to
+func (ai *OpenAIService) reproTarget() (string, map[string]string) {
+	ai.endpointMutex.RLock()
+	defer ai.endpointMutex.RUnlock()
+	return ai.baseURL, ai.connection.Headers
+}
+
+// writeReproBundle writes a failed test's repro bundle; a failed write only costs the
+// bundle, so it is reported rather than aborting the run
+func (tr *TestRunner) writeReproBundle(result models.AgentTestResult) {
+	if tr.reproDir == "" || len(result.Requests) == 0 {
+		return
+	}
+	baseURL, headers := tr.openaiService.reproTarget()
+	if err := WriteReproBundle(tr.reproDir, result, baseURL, headers); err != nil {
+		fmt.Printf("⚠️  %v\n", err)
+	}
+}
+
+// WriteReproBundle writes everything needed to replay a failed test against its
+// endpoint without the harness into a directory of its own under dir: every request
+// body as sent (request_1.json, ...), the tool definitions, the system prompt, the
+// result, and repro.sh, a curl command sending the last request. Secrets are redacted.
+func WriteReproBundle(dir string, result models.AgentTestResult, baseURL string, headers map[string]string) error {
+	bundle := filepath.Join(dir, reproBundleName(result))
+	if err := os.MkdirAll(bundle, 0755); err != nil {
+		return fmt.Errorf("failed to create repro bundle: %w", err)
+	}
+
+	files := make(map[string][]byte)
+	for i, request := range result.Requests {
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, request, "", "  "); err != nil {
+			return fmt.Errorf("failed to format request %d of %s: %w", i+1, result.TestCase.Name, err)
+		}
+		files[fmt.Sprintf("request_%d.json", i+1)] = indented.Bytes()
+	}
+
+	var first struct {
+		Messages []struct {
+			Role    string          `json:"role"`
+			Content json.RawMessage `json:"content"`
+		} `json:"messages"`
+		Tools json.RawMessage `json:"tools"`
+	}
+	if err := json.Unmarshal(result.Requests[0], &first); err != nil {
+		return fmt.Errorf("failed to parse request of %s: %w", result.TestCase.Name, err)
+	}
+	if len(first.Tools) > 0 {
+		var tools bytes.Buffer
+		if err := json.Indent(&tools, first.Tools, "", "  "); err == nil {
+			files["tools.json"] = tools.Bytes()
+		}
+	}
+	if len(first.Messages) > 0 && first.Messages[0].Role == string(models.RoleSystem) {
+		var prompt string
+		if json.Unmarshal(first.Messages[0].Content, &prompt) == nil {
+			files["system_prompt.txt"] = []byte(prompt + "\n")
+		}
+	}
+
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal result of %s: %w", result.TestCase.Name, err)
+	}
+	files["result.json"] = data
+	files["repro.sh"] = []byte(reproScript(result, baseURL, headers))
+
+	for name, content := range files {
+		mode := os.FileMode(0644)
+		if name == "repro.sh" {
+			mode = 0755
+		}
+		if err := os.WriteFile(filepath.Join(bundle, name), RedactSecrets(content), mode); err != nil {
+			return fmt.Errorf("failed to write repro bundle: %w", err)
+		}
+	}
+	return nil
+}
+
+// reproBundleName names a result's bundle after its test case, run and sampling config
+func reproBundleName(result models.AgentTestResult) string {
+	name := result.TestCase.Name
+	if result.Run > 0 {
+		name += fmt.Sprintf("_run%d", result.Run)
+	}
+	if result.Config.Label != "" {
+		name += "_" + result.Config.Label
+	}
+	return strings.Trim(unsafeBundleChars.ReplaceAllString(name, "_"), "_")
+}
+
+// reproScript renders a shell script that posts one of the bundle's requests with curl,
+// the last one unless another number is given
+func reproScript(result models.AgentTestResult, baseURL string, headers map[string]string) string {
+	var sb strings.Builder
+	sb.WriteString("#!/bin/sh\n")
+	sb.WriteString(fmt.Sprintf("# Reproduces %s with %s: %s\n", result.TestCase.Name, result.ModelName, oneLine(string(result.FailureReason)+": "+result.FailureDetails)))
+	sb.WriteString(fmt.Sprintf("# Usage: ./repro.sh [request number, 1-%d]\n", len(result.Requests)))
+	sb.WriteString("# Set OPENAI_API_KEY first, and OPENAI_BASE_URL to target another endpoint.\n")
+	sb.WriteString("set -e\n")
+	sb.WriteString("cd \"$(dirname \"$0\")\"\n")
+	sb.WriteString(fmt.Sprintf("curl -sS \"${OPENAI_BASE_URL:-%s}/chat/completions\" \\\n", strings.TrimRight(baseURL, "/")))
+	sb.WriteString("  -H 'Content-Type: application/json' \\\n")
+	sb.WriteString("  -H \"Authorization: Bearer ${OPENAI_API_KEY}\" \\\n")
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		sb.WriteString(fmt.Sprintf("  -H %s \\\n", ShellQuote(name+": "+headers[name])))
+	}
+	sb.WriteString(fmt.Sprintf("  --data-binary @\"request_${1:-%d}.json\"\n", len(result.Requests)))
+	return sb.String()
+}
+
+// ShellQuote quotes a value as one sh word
+func ShellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// oneLine collapses whitespace, including newlines, for a script comment
+func oneLine(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
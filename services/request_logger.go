@@ -6,35 +6,15 @@ import (
 	"os"
 	"time"
 
+	"model-test/models"
+
 	"github.com/openai/openai-go"
 )
 
 // RequestLogger handles logging of HTTP requests and responses
 type RequestLogger struct {
 	logFile *os.File
-}
-
-// LogEntry represents a single request/response log entry
-type LogEntry struct {
-	Timestamp string      `json:"timestamp"`
-	TestCase  string      `json:"test_case"`
-	Iteration int         `json:"iteration"`
-	Request   LogRequest  `json:"request"`
-	Response  LogResponse `json:"response"`
-	Error     string      `json:"error,omitempty"`
-}
-
-// LogRequest represents the request part of a log entry
-type LogRequest struct {
-	Method string      `json:"method"`
-	URL    string      `json:"url"`
-	Body   interface{} `json:"body"`
-}
-
-// LogResponse represents the response part of a log entry
-type LogResponse struct {
-	StatusCode int         `json:"status_code"`
-	Body       interface{} `json:"body"`
+	runID   string
 }
 
 // NewRequestLogger creates a new request logger with the specified log file
@@ -55,18 +35,26 @@ func NewRequestLogger(logFilePath string) (*RequestLogger, error) {
 	}, nil
 }
 
+// SetRunID sets the run identifier recorded in every log entry
+func (rl *RequestLogger) SetRunID(runID string) {
+	rl.runID = runID
+}
+
 // LogRequest logs a successful request/response pair
-func (rl *RequestLogger) LogRequest(testCase string, iteration int, requestParams openai.ChatCompletionNewParams, response *openai.ChatCompletion, baseURL string) error {
-	entry := LogEntry{
-		Timestamp: time.Now().UTC().Format(time.RFC3339),
-		TestCase:  testCase,
-		Iteration: iteration,
-		Request: LogRequest{
-			Method: "POST",
-			URL:    fmt.Sprintf("%s/chat/completions", baseURL),
-			Body:   requestParams,
+func (rl *RequestLogger) LogRequest(testCase string, iteration int, requestParams openai.ChatCompletionNewParams, response *openai.ChatCompletion, baseURL string, headers map[string]string) error {
+	entry := models.LogEntry{
+		SchemaVersion: models.LogSchemaVersion,
+		Timestamp:     time.Now().UTC().Format(time.RFC3339),
+		RunID:         rl.runID,
+		TestCase:      testCase,
+		Iteration:     iteration,
+		Request: models.LogRequest{
+			Method:  "POST",
+			URL:     fmt.Sprintf("%s/chat/completions", baseURL),
+			Headers: logHeaders(headers),
+			Body:    requestParams,
 		},
-		Response: LogResponse{
+		Response: models.LogResponse{
 			StatusCode: 200,
 			Body:       response,
 		},
@@ -76,17 +64,20 @@ func (rl *RequestLogger) LogRequest(testCase string, iteration int, requestParam
 }
 
 // LogError logs a failed request
-func (rl *RequestLogger) LogError(testCase string, iteration int, requestParams openai.ChatCompletionNewParams, err error, baseURL string) error {
-	entry := LogEntry{
-		Timestamp: time.Now().UTC().Format(time.RFC3339),
-		TestCase:  testCase,
-		Iteration: iteration,
-		Request: LogRequest{
-			Method: "POST",
-			URL:    fmt.Sprintf("%s/chat/completions", baseURL),
-			Body:   requestParams,
+func (rl *RequestLogger) LogError(testCase string, iteration int, requestParams openai.ChatCompletionNewParams, err error, baseURL string, headers map[string]string) error {
+	entry := models.LogEntry{
+		SchemaVersion: models.LogSchemaVersion,
+		Timestamp:     time.Now().UTC().Format(time.RFC3339),
+		RunID:         rl.runID,
+		TestCase:      testCase,
+		Iteration:     iteration,
+		Request: models.LogRequest{
+			Method:  "POST",
+			URL:     fmt.Sprintf("%s/chat/completions", baseURL),
+			Headers: logHeaders(headers),
+			Body:    requestParams,
 		},
-		Response: LogResponse{
+		Response: models.LogResponse{
 			StatusCode: 0, // Unknown status code for errors
 			Body:       nil,
 		},
@@ -96,15 +87,31 @@ func (rl *RequestLogger) LogError(testCase string, iteration int, requestParams
 	return rl.writeLogEntry(entry)
 }
 
+// logHeaders copies the endpoint's static headers for a log entry, redacting the
+// values of those that carry credentials
+func logHeaders(headers map[string]string) map[string]string {
+	if len(headers) == 0 {
+		return nil
+	}
+	logged := make(map[string]string, len(headers))
+	for name, value := range headers {
+		if sensitiveHeaderPattern.MatchString(name) {
+			value = redactedValue
+		}
+		logged[name] = value
+	}
+	return logged
+}
+
 // writeLogEntry writes a log entry to the file
-func (rl *RequestLogger) writeLogEntry(entry LogEntry) error {
+func (rl *RequestLogger) writeLogEntry(entry models.LogEntry) error {
 	jsonData, err := json.Marshal(entry)
 	if err != nil {
 		return fmt.Errorf("failed to marshal log entry: %w", err)
 	}
 
 	// Write JSON entry followed by newline
-	if _, err := rl.logFile.Write(jsonData); err != nil {
+	if _, err := rl.logFile.Write(RedactSecrets(jsonData)); err != nil {
 		return fmt.Errorf("failed to write log entry: %w", err)
 	}
 
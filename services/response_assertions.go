@@ -0,0 +1,85 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"model-test/models"
+)
+
+// checkExpectedResponse checks the assistant's final reply against a test's expected
+// response and describes the first check it fails
+func checkExpectedResponse(expected models.ExpectedResponse, message string) (string, bool) {
+	haystack := message
+	if !expected.CaseSensitive {
+		haystack = strings.ToLower(message)
+	}
+	contains := func(text string) bool {
+		if !expected.CaseSensitive {
+			text = strings.ToLower(text)
+		}
+		return strings.Contains(haystack, text)
+	}
+
+	for _, text := range expected.Contains {
+		if !contains(text) {
+			return fmt.Sprintf("reply does not contain %q: %s", text, replyExcerpt(message)), false
+		}
+	}
+	for _, pattern := range expected.Regex {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Sprintf("invalid expected_response regex %q: %v", pattern, err), false
+		}
+		if !re.MatchString(message) {
+			return fmt.Sprintf("reply does not match /%s/: %s", pattern, replyExcerpt(message)), false
+		}
+	}
+	for _, text := range expected.MustNotContain {
+		if contains(text) {
+			return fmt.Sprintf("reply contains %q: %s", text, replyExcerpt(message)), false
+		}
+	}
+	return "", true
+}
+
+// replyExcerptLength caps how much of the reply failure details quote
+const replyExcerptLength = 120
+
+// replyExcerpt quotes the start of a reply for failure details
+func replyExcerpt(message string) string {
+	message = strings.Join(strings.Fields(message), " ")
+	if message == "" {
+		return "empty reply"
+	}
+	if runes := []rune(message); len(runes) > replyExcerptLength {
+		message = string(runes[:replyExcerptLength]) + "..."
+	}
+	return fmt.Sprintf("%q", message)
+}
+
+// validateExpectedResponse reports empty checks and regexes that do not compile
+func validateExpectedResponse(label string, expected *models.ExpectedResponse) []string {
+	if expected == nil {
+		return nil
+	}
+
+	var problems []string
+	if len(expected.Contains)+len(expected.Regex)+len(expected.MustNotContain) == 0 {
+		problems = append(problems, fmt.Sprintf("%s: expected_response has no checks", label))
+	}
+	for _, texts := range [][]string{expected.Contains, expected.MustNotContain} {
+		for _, text := range texts {
+			if strings.TrimSpace(text) == "" {
+				problems = append(problems, fmt.Sprintf("%s: expected_response has an empty string", label))
+			}
+		}
+	}
+	for _, pattern := range expected.Regex {
+		if _, err := regexp.Compile(pattern); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: expected_response regex %q is invalid: %v", label, pattern, err))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,194 @@
+package services
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"model-test/models"
+)
+
+// ResultsTable flattens test results from any number of result files into one row per
+// test, for analysis in dataframe tools (pandas, duckdb) rather than by walking JSON.
+// Every run tag becomes a tag_<key> column.
+type ResultsTable struct {
+	rows    []resultsTableRow
+	tagKeys map[string]bool
+}
+
+// resultsTableRow is one test result with the run it came from
+type resultsTableRow struct {
+	sourceFile string
+	runID      string
+	tags       models.RunTags
+	result     models.AgentTestResult
+}
+
+// NewResultsTable creates an empty results table
+func NewResultsTable() *ResultsTable {
+	return &ResultsTable{tagKeys: make(map[string]bool)}
+}
+
+// Add adds the results of one result file, with the run ID and tags of its report
+func (t *ResultsTable) Add(sourceFile, runID string, tags models.RunTags, results []models.AgentTestResult) {
+	for key := range tags {
+		t.tagKeys[key] = true
+	}
+	for _, result := range results {
+		t.rows = append(t.rows, resultsTableRow{sourceFile: sourceFile, runID: runID, tags: tags, result: result})
+	}
+}
+
+// Len returns the number of rows
+func (t *ResultsTable) Len() int {
+	return len(t.rows)
+}
+
+// resultsTableColumns are the columns every results table has, before its tag columns
+var resultsTableColumns = []ParquetColumn{
+	{Name: "source_file", Type: ParquetString},
+	{Name: "run_id", Type: ParquetString, Optional: true},
+	{Name: "model", Type: ParquetString},
+	{Name: "test_case", Type: ParquetString},
+	{Name: "run", Type: ParquetInt64},
+	{Name: "config", Type: ParquetString, Optional: true},
+	{Name: "outcome", Type: ParquetString}, // pass, fail or infra
+	{Name: "success", Type: ParquetBool},
+	{Name: "failure_reason", Type: ParquetString, Optional: true},
+	{Name: "matched_path", Type: ParquetString, Optional: true},
+	{Name: "timestamp", Type: ParquetTimestamp},
+	{Name: "response_time_ms", Type: ParquetDouble},
+	{Name: "llm_time_ms", Type: ParquetDouble, Optional: true},
+	{Name: "tool_time_ms", Type: ParquetDouble, Optional: true},
+	{Name: "llm_requests", Type: ParquetInt64},
+	{Name: "tool_calls", Type: ParquetInt64},
+	{Name: "tools", Type: ParquetString, Optional: true}, // Called tools in order, comma-separated
+	{Name: "input_tokens", Type: ParquetInt64, Optional: true},
+	{Name: "output_tokens", Type: ParquetInt64, Optional: true},
+	{Name: "total_tokens", Type: ParquetInt64, Optional: true},
+	{Name: "tokens_estimated", Type: ParquetBool, Optional: true},
+}
+
+// Columns returns the table's columns: the fixed ones, then a tag_<key> column for
+// every run tag in sorted order
+func (t *ResultsTable) Columns() []ParquetColumn {
+	columns := append([]ParquetColumn{}, resultsTableColumns...)
+	for _, key := range t.sortedTagKeys() {
+		columns = append(columns, ParquetColumn{Name: "tag_" + key, Type: ParquetString, Optional: true})
+	}
+	return columns
+}
+
+// Rows returns the table's rows, one value per column with nil for nulls
+func (t *ResultsTable) Rows() [][]interface{} {
+	tagKeys := t.sortedTagKeys()
+	rows := make([][]interface{}, 0, len(t.rows))
+	for _, row := range t.rows {
+		values := row.values()
+		for _, key := range tagKeys {
+			values = append(values, optionalString(row.tags[key]))
+		}
+		rows = append(rows, values)
+	}
+	return rows
+}
+
+// sortedTagKeys returns the tag keys of every added run
+func (t *ResultsTable) sortedTagKeys() []string {
+	tagKeys := make([]string, 0, len(t.tagKeys))
+	for key := range t.tagKeys {
+		tagKeys = append(tagKeys, key)
+	}
+	sort.Strings(tagKeys)
+	return tagKeys
+}
+
+// WriteParquet writes the table as a Parquet file
+func (t *ResultsTable) WriteParquet(w io.Writer) error {
+	writer, err := NewParquetWriter(w, t.Columns())
+	if err != nil {
+		return err
+	}
+	for i, values := range t.Rows() {
+		if err := writer.Write(values); err != nil {
+			return fmt.Errorf("failed to write row for %s: %w", t.rows[i].result.TestCase.Name, err)
+		}
+	}
+	return writer.Close()
+}
+
+// values returns the row's values for resultsTableColumns
+func (r resultsTableRow) values() []interface{} {
+	result := r.result
+
+	outcome := "fail"
+	switch {
+	case result.Success:
+		outcome = "pass"
+	case result.InfrastructureFailure || result.FailureReason.IsInfrastructure():
+		outcome = "infra"
+	}
+
+	var llmTime, toolTime interface{}
+	if result.Timing != nil {
+		llmTime = milliseconds(result.Timing.LLMTime)
+		toolTime = milliseconds(result.Timing.ToolTime)
+	}
+
+	var llmRequests, toolCalls int64
+	var tools, inputTokens, outputTokens, totalTokens, estimated interface{}
+	if response := result.Response; response != nil {
+		llmRequests = int64(response.LLMRequests)
+		toolCalls = int64(len(response.ToolCalls))
+		names := make([]string, len(response.ToolCalls))
+		for i, toolCall := range response.ToolCalls {
+			names[i] = toolCall.ToolName
+		}
+		tools = optionalString(strings.Join(names, ","))
+		if usage := response.Usage; usage != nil {
+			inputTokens = usage.InputTokens
+			outputTokens = usage.OutputTokens
+			totalTokens = usage.TotalTokens
+			estimated = usage.Estimated
+		}
+	}
+
+	return []interface{}{
+		r.sourceFile,
+		optionalString(r.runID),
+		result.ModelName,
+		result.TestCase.Name,
+		int64(result.Run),
+		optionalString(result.Config.Label),
+		outcome,
+		result.Success,
+		optionalString(string(result.FailureReason)),
+		optionalString(result.MatchedPath),
+		result.Timestamp,
+		milliseconds(result.ResponseTime),
+		llmTime,
+		toolTime,
+		llmRequests,
+		toolCalls,
+		tools,
+		inputTokens,
+		outputTokens,
+		totalTokens,
+		estimated,
+	}
+}
+
+// optionalString returns nil for an empty string so it is stored as null
+func optionalString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// milliseconds converts a duration to fractional milliseconds
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
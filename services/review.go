@@ -0,0 +1,249 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"model-test/models"
+)
+
+// Reasons a failed result is considered borderline and queued for human review
+const (
+	ReviewNearMissArguments = "near_miss_arguments" // Right tools in the right order, arguments differed
+	ReviewNearMissOneTool   = "near_miss_one_tool"  // One tool call away from an expected variant
+	ReviewJudgeGrayZone     = "judge_gray_zone"     // The judge scored the reply inside the gray zone
+)
+
+// JudgeGrayZone is the band of judge scores, inclusive, in which a failed result is
+// borderline: the judge neither clearly approved nor clearly rejected the reply
+type JudgeGrayZone struct {
+	Low  float64
+	High float64
+}
+
+// Contains reports whether a judge score falls inside the gray zone
+func (z JudgeGrayZone) Contains(score float64) bool {
+	return score >= z.Low && score <= z.High
+}
+
+// ReviewKey identifies a result for adjudication by its test case, prompt and the
+// exact tool calls made
+func ReviewKey(testCase models.TestCase, response *models.ChatResponse) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\n%s\n", testCase.Name, testCase.Prompt)
+	for _, toolCall := range response.ToolCalls {
+		// Re-marshaling sorts object keys, so formatting differences do not change the key
+		arguments := toolCall.Arguments
+		var parsed interface{}
+		if err := json.Unmarshal([]byte(arguments), &parsed); err == nil {
+			if data, err := json.Marshal(parsed); err == nil {
+				arguments = string(data)
+			}
+		}
+		fmt.Fprintf(hash, "%s %s\n", toolCall.ToolName, arguments)
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:16]
+}
+
+// ReviewReason reports whether a result is borderline enough to need human review,
+// and why. Passing results and results without a response are never borderline. A
+// judge score inside the gray zone queues a failed result that is not a near miss;
+// a nil gray zone leaves judge scores out.
+func ReviewReason(result models.AgentTestResult, grayZone *JudgeGrayZone) (string, bool) {
+	if result.Success || result.Response == nil {
+		return "", false
+	}
+
+	switch result.FailureReason {
+	case models.FailureBadArguments:
+		return ReviewNearMissArguments, true
+	case models.FailureMissingTool, models.FailureExtraTool, models.FailureWrongTool:
+		actual := make([]string, len(result.Response.ToolCalls))
+		for i, toolCall := range result.Response.ToolCalls {
+			actual[i] = toolCall.ToolName
+		}
+		for _, variant := range result.TestCase.ExpectedToolVariants {
+			expected := make([]string, len(variant.Tools))
+			for i, tool := range variant.Tools {
+				expected[i] = tool.Name
+			}
+			if oneEditApart(expected, actual) {
+				return ReviewNearMissOneTool, true
+			}
+		}
+	}
+
+	if grayZone != nil && result.Judge != nil && result.Judge.Error == "" && grayZone.Contains(result.Judge.Score) {
+		return ReviewJudgeGrayZone, true
+	}
+
+	return "", false
+}
+
+// oneEditApart reports whether two tool sequences differ by exactly one inserted,
+// removed or substituted call
+func oneEditApart(a, b []string) bool {
+	switch len(a) - len(b) {
+	case 0:
+		differences := 0
+		for i := range a {
+			if a[i] != b[i] {
+				differences++
+			}
+		}
+		return differences == 1
+	case 1:
+		return isSubsequence(b, a)
+	case -1:
+		return isSubsequence(a, b)
+	}
+	return false
+}
+
+// BuildReviewQueue collects the borderline results that have not been adjudicated
+// yet, merging results with identical behavior into one item
+func BuildReviewQueue(results map[string][]models.AgentTestResult, adjudications map[string]models.Adjudication, grayZone *JudgeGrayZone) []models.ReviewItem {
+	items := make(map[string]*models.ReviewItem)
+
+	files := make([]string, 0, len(results))
+	for file := range results {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		for _, result := range results[file] {
+			reason, borderline := ReviewReason(result, grayZone)
+			if !borderline {
+				continue
+			}
+			key := ReviewKey(result.TestCase, result.Response)
+			if _, decided := adjudications[key]; decided {
+				continue
+			}
+
+			item, exists := items[key]
+			if !exists {
+				item = &models.ReviewItem{
+					Key:              key,
+					TestCase:         result.TestCase.Name,
+					Prompt:           result.TestCase.Prompt,
+					Reason:           reason,
+					FailureReason:    result.FailureReason,
+					FailureDetails:   result.FailureDetails,
+					ExpectedVariants: result.TestCase.ExpectedToolVariants,
+					Judge:            result.Judge,
+				}
+				for _, toolCall := range result.Response.ToolCalls {
+					var arguments map[string]interface{}
+					if err := json.Unmarshal([]byte(toolCall.Arguments), &arguments); err != nil {
+						arguments = map[string]interface{}{"_raw": toolCall.Arguments}
+					}
+					item.ToolCalls = append(item.ToolCalls, models.ActualToolCall{Name: toolCall.ToolName, Arguments: arguments})
+				}
+				items[key] = item
+			}
+
+			item.Occurrences++
+			item.Models = appendUnique(item.Models, result.ModelName)
+			item.ResultFiles = appendUnique(item.ResultFiles, file)
+		}
+	}
+
+	queue := make([]models.ReviewItem, 0, len(items))
+	for _, item := range items {
+		queue = append(queue, *item)
+	}
+	sort.Slice(queue, func(i, j int) bool {
+		if queue[i].TestCase != queue[j].TestCase {
+			return queue[i].TestCase < queue[j].TestCase
+		}
+		return queue[i].Key < queue[j].Key
+	})
+
+	return queue
+}
+
+// appendUnique appends a value unless the slice already contains it
+func appendUnique(values []string, value string) []string {
+	for _, existing := range values {
+		if existing == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
+
+// collectManualOverrides lists the results whose verdict came from a human decision,
+// as an audit trail for the report
+func collectManualOverrides(results []models.AgentTestResult) []models.ManualOverride {
+	var overrides []models.ManualOverride
+	for _, result := range results {
+		if result.Adjudication == nil {
+			continue
+		}
+		overrides = append(overrides, models.ManualOverride{
+			TestCase:        result.TestCase.Name,
+			Key:             result.Adjudication.Key,
+			AutomaticReason: result.FailureReason,
+			Verdict:         result.Adjudication.Verdict,
+			Reviewer:        result.Adjudication.Reviewer,
+			Source:          result.Adjudication.Source,
+			Note:            result.Adjudication.Note,
+			DecidedAt:       result.Adjudication.DecidedAt,
+		})
+	}
+	return overrides
+}
+
+// LoadAdjudications reads stored adjudications keyed by review key. A missing file
+// means nothing has been adjudicated yet.
+func LoadAdjudications(path string) (map[string]models.Adjudication, error) {
+	adjudications := make(map[string]models.Adjudication)
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return adjudications, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read adjudications: %w", err)
+	}
+
+	var list []models.Adjudication
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse adjudications: %w", err)
+	}
+	for _, adjudication := range list {
+		adjudications[adjudication.Key] = adjudication
+	}
+
+	return adjudications, nil
+}
+
+// SaveAdjudications writes the adjudications sorted by test case and key
+func SaveAdjudications(path string, adjudications map[string]models.Adjudication) error {
+	list := make([]models.Adjudication, 0, len(adjudications))
+	for _, adjudication := range adjudications {
+		list = append(list, adjudication)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].TestCase != list[j].TestCase {
+			return list[i].TestCase < list[j].TestCase
+		}
+		return list[i].Key < list[j].Key
+	})
+
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal adjudications: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create adjudications directory: %w", err)
+	}
+	return os.WriteFile(path, data, 0644)
+}
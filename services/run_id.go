@@ -0,0 +1,54 @@
+package services
+
+import (
+	"crypto/rand"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// crockfordAlphabet is the base32 alphabet used by ULIDs
+const crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// runIDPattern restricts run IDs to characters that are safe in file names
+var runIDPattern = regexp.MustCompile(`^[0-9A-Za-z_-]+$`)
+
+// NewRunID generates a ULID: a 48-bit millisecond timestamp followed by 80 random
+// bits, Crockford base32 encoded. IDs sort by creation time and stay unique across
+// machines that start runs in the same second.
+func NewRunID() (string, error) {
+	var data [16]byte
+
+	ms := uint64(time.Now().UnixMilli())
+	for i := 5; i >= 0; i-- {
+		data[i] = byte(ms)
+		ms >>= 8
+	}
+	if _, err := rand.Read(data[6:]); err != nil {
+		return "", fmt.Errorf("failed to generate run ID: %w", err)
+	}
+
+	// 128 bits encode to 26 characters; the first character carries only 3 bits
+	id := make([]byte, 26)
+	var value [2]uint64
+	value[0] = uint64(data[0])<<56 | uint64(data[1])<<48 | uint64(data[2])<<40 | uint64(data[3])<<32 |
+		uint64(data[4])<<24 | uint64(data[5])<<16 | uint64(data[6])<<8 | uint64(data[7])
+	value[1] = uint64(data[8])<<56 | uint64(data[9])<<48 | uint64(data[10])<<40 | uint64(data[11])<<32 |
+		uint64(data[12])<<24 | uint64(data[13])<<16 | uint64(data[14])<<8 | uint64(data[15])
+
+	for i := 25; i >= 0; i-- {
+		id[i] = crockfordAlphabet[value[1]&0x1f]
+		value[1] = value[1]>>5 | value[0]<<59
+		value[0] >>= 5
+	}
+
+	return string(id), nil
+}
+
+// ValidateRunID checks that an externally supplied run ID is safe to use in file names
+func ValidateRunID(runID string) error {
+	if !runIDPattern.MatchString(runID) {
+		return fmt.Errorf("run ID %q may only contain letters, digits, '-' and '_'", runID)
+	}
+	return nil
+}
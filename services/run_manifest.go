@@ -0,0 +1,22 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"model-test/models"
+)
+
+// SaveRunManifest saves a run manifest to a JSON file, signed if a signing key is given
+func SaveRunManifest(filename string, manifest *models.RunManifest, signingKey []byte) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal run manifest: %w", err)
+	}
+
+	if err := os.WriteFile(filename, RedactSecrets(data), 0644); err != nil {
+		return err
+	}
+	return SignFile(filename, signingKey)
+}
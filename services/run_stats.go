@@ -0,0 +1,95 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"model-test/models"
+)
+
+// calculateRunStatistics computes per-run pass rates and per-test stability for a
+// suite executed several times. It returns nil when every test case ran once.
+func calculateRunStatistics(results []models.AgentTestResult) *models.RunStatistics {
+	runs := 0
+	for _, result := range results {
+		if result.Run > runs {
+			runs = result.Run
+		}
+	}
+	if runs < 2 {
+		return nil
+	}
+
+	runTotals := make([]int, runs)
+	runPassed := make([]int, runs)
+	byTestCase := make(map[string]*models.TestCaseStability)
+	for _, result := range results {
+		if result.Run < 1 {
+			continue
+		}
+		runTotals[result.Run-1]++
+		if result.Success {
+			runPassed[result.Run-1]++
+		}
+
+		key := result.TestCase.Name + "\x00" + result.Config.Label
+		stability, exists := byTestCase[key]
+		if !exists {
+			stability = &models.TestCaseStability{TestCase: result.TestCase.Name, Config: result.Config.Label}
+			byTestCase[key] = stability
+		}
+		stability.Runs++
+		if result.Success {
+			stability.Passed++
+		}
+	}
+
+	stats := &models.RunStatistics{Runs: runs}
+	for i := range runTotals {
+		rate := 0.0
+		if runTotals[i] > 0 {
+			rate = float64(runPassed[i]) / float64(runTotals[i])
+		}
+		stats.RunPassRates = append(stats.RunPassRates, rate)
+		stats.PassRateMean += rate
+	}
+	stats.PassRateMean /= float64(runs)
+
+	for _, rate := range stats.RunPassRates {
+		stats.PassRateVariance += (rate - stats.PassRateMean) * (rate - stats.PassRateMean)
+	}
+	stats.PassRateVariance /= float64(runs - 1)
+	stats.PassRateStdDev = math.Sqrt(stats.PassRateVariance)
+
+	for _, stability := range byTestCase {
+		stability.PassRate = float64(stability.Passed) / float64(stability.Runs)
+		stability.Flaky = stability.Passed > 0 && stability.Passed < stability.Runs
+		if stability.Flaky {
+			stats.FlakyTests++
+		}
+		stats.TestCases = append(stats.TestCases, *stability)
+	}
+	sort.Slice(stats.TestCases, func(i, j int) bool {
+		if stats.TestCases[i].TestCase != stats.TestCases[j].TestCase {
+			return stats.TestCases[i].TestCase < stats.TestCases[j].TestCase
+		}
+		return stats.TestCases[i].Config < stats.TestCases[j].Config
+	})
+
+	return stats
+}
+
+// ResultKey identifies a result within a report. Sampling sweep combinations are told
+// apart by their config label and repeated runs by their run number; the first run
+// keeps the plain test case name so single-run reports still line up with multi-run ones.
+func ResultKey(result models.AgentTestResult) string {
+	key := result.TestCase.Name
+	if result.Config.Label != "" {
+		key += " [" + result.Config.Label + "]"
+	}
+	if result.Run > 1 {
+		key = fmt.Sprintf("%s#%d", key, result.Run)
+	}
+	return key
+}
@@ -0,0 +1,173 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"model-test/models"
+)
+
+// LoadTestConfig loads and validates a test configuration, including its sampling sweep
+func LoadTestConfig(filename string) (*models.TestConfig, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read test config: %w", err)
+	}
+
+	var config models.TestConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse test config: %w", err)
+	}
+
+	for _, sampling := range ExpandSweep(config) {
+		if err := validateSampling(sampling); err != nil {
+			return nil, fmt.Errorf("invalid sampling config %s: %w", sampling.Label, err)
+		}
+	}
+
+	return &config, nil
+}
+
+// ExpandSweep returns one config per combination of the sweep's temperature, top_p
+// and max_tokens values, each labeled with its sampling values. A config without a
+// sweep is returned as is, labeled if it sets any sampling value.
+func ExpandSweep(config models.TestConfig) []models.TestConfig {
+	base := config
+	base.Sweep = nil
+
+	temperatures := []*float64{base.Temperature}
+	topPs := []*float64{base.TopP}
+	maxTokens := []int{base.MaxTokens}
+	if config.Sweep != nil {
+		if len(config.Sweep.Temperature) > 0 {
+			temperatures = floatPointers(config.Sweep.Temperature)
+		}
+		if len(config.Sweep.TopP) > 0 {
+			topPs = floatPointers(config.Sweep.TopP)
+		}
+		if len(config.Sweep.MaxTokens) > 0 {
+			maxTokens = config.Sweep.MaxTokens
+		}
+	}
+
+	var configs []models.TestConfig
+	for _, temperature := range temperatures {
+		for _, topP := range topPs {
+			for _, tokens := range maxTokens {
+				combination := base
+				combination.Temperature = temperature
+				combination.TopP = topP
+				combination.MaxTokens = tokens
+				combination.Label = samplingLabel(combination)
+				configs = append(configs, combination)
+			}
+		}
+	}
+	return configs
+}
+
+// samplingLabel describes the sampling values a config sets, e.g.
+// "temperature=0.7,top_p=0.9,max_tokens=512"
+func samplingLabel(config models.TestConfig) string {
+	var parts []string
+	if config.Temperature != nil {
+		parts = append(parts, "temperature="+strconv.FormatFloat(*config.Temperature, 'g', -1, 64))
+	}
+	if config.TopP != nil {
+		parts = append(parts, "top_p="+strconv.FormatFloat(*config.TopP, 'g', -1, 64))
+	}
+	if config.MaxTokens > 0 {
+		parts = append(parts, "max_tokens="+strconv.Itoa(config.MaxTokens))
+	}
+	return strings.Join(parts, ",")
+}
+
+// validateSampling checks that sampling values are within the ranges the OpenAI API accepts
+func validateSampling(config models.TestConfig) error {
+	if config.Temperature != nil && (*config.Temperature < 0 || *config.Temperature > 2) {
+		return fmt.Errorf("temperature must be between 0 and 2, got %v", *config.Temperature)
+	}
+	if config.TopP != nil && (*config.TopP <= 0 || *config.TopP > 1) {
+		return fmt.Errorf("top_p must be greater than 0 and at most 1, got %v", *config.TopP)
+	}
+	if config.MaxTokens < 0 {
+		return fmt.Errorf("max_tokens must be positive, got %d", config.MaxTokens)
+	}
+	return nil
+}
+
+// floatPointers returns a pointer to each value
+func floatPointers(values []float64) []*float64 {
+	pointers := make([]*float64, len(values))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	return pointers
+}
+
+// summarizeSweep computes the pass rate and average response time of each sampling
+// combination, ordered by temperature, top_p and max_tokens. It returns nil when the
+// results carry no sampling labels.
+func summarizeSweep(results []models.AgentTestResult) []models.SamplingConfigResult {
+	byLabel := make(map[string]*models.SamplingConfigResult)
+	var order []string
+	totalTimes := make(map[string]time.Duration)
+
+	for _, result := range results {
+		label := result.Config.Label
+		if label == "" {
+			continue
+		}
+		summary, exists := byLabel[label]
+		if !exists {
+			summary = &models.SamplingConfigResult{Config: result.Config}
+			byLabel[label] = summary
+			order = append(order, label)
+		}
+		summary.TotalTests++
+		if result.Success {
+			summary.PassedTests++
+		}
+		totalTimes[label] += result.ResponseTime
+	}
+	if len(order) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(order, func(i, j int) bool {
+		return configOrder(byLabel[order[i]].Config, byLabel[order[j]].Config)
+	})
+
+	summaries := make([]models.SamplingConfigResult, 0, len(order))
+	for _, label := range order {
+		summary := byLabel[label]
+		summary.PassRate = float64(summary.PassedTests) / float64(summary.TotalTests)
+		summary.AverageTime = totalTimes[label] / time.Duration(summary.TotalTests)
+		summaries = append(summaries, *summary)
+	}
+	return summaries
+}
+
+// configOrder orders sampling combinations by temperature, then top_p, then max_tokens
+func configOrder(a, b models.TestConfig) bool {
+	if value, other := floatValue(a.Temperature), floatValue(b.Temperature); value != other {
+		return value < other
+	}
+	if value, other := floatValue(a.TopP), floatValue(b.TopP); value != other {
+		return value < other
+	}
+	return a.MaxTokens < b.MaxTokens
+}
+
+// floatValue returns the value of an optional float, or -1 when it is unset
+func floatValue(value *float64) float64 {
+	if value == nil {
+		return -1
+	}
+	return *value
+}
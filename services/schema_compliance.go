@@ -0,0 +1,37 @@
+package services
+
+import "model-test/models"
+
+// SummarizeSchemaCompliance aggregates the schema checks of every tool call in the
+// results. It returns nil when no call was checked.
+func SummarizeSchemaCompliance(results []models.AgentTestResult) *models.SchemaCompliance {
+	summary := &models.SchemaCompliance{ByKind: make(map[models.SchemaViolationKind]int)}
+
+	for _, result := range results {
+		if result.Response == nil {
+			continue
+		}
+		for _, toolCall := range result.Response.ToolCalls {
+			if !toolCall.SchemaChecked {
+				continue
+			}
+			summary.CheckedCalls++
+			if len(toolCall.SchemaViolations) == 0 {
+				summary.ValidCalls++
+			}
+			for _, violation := range toolCall.SchemaViolations {
+				summary.ByKind[violation.Kind]++
+			}
+		}
+	}
+
+	if summary.CheckedCalls == 0 {
+		return nil
+	}
+	if len(summary.ByKind) == 0 {
+		summary.ByKind = nil
+	}
+	summary.Rate = float64(summary.ValidCalls) / float64(summary.CheckedCalls)
+
+	return summary
+}
@@ -0,0 +1,241 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"model-test/models"
+)
+
+// defaultScorerTimeout bounds command scorers that do not set their own timeout
+const defaultScorerTimeout = 60 * time.Second
+
+// Scorer decides whether a response passes a test case, with a score from 0 to 1 and
+// details explaining the verdict. The evaluator's exact-path matcher is the default;
+// a test config's scorers chain others after or instead of it.
+type Scorer interface {
+	Name() string
+	Score(testCase models.TestCase, response *models.ChatResponse) (models.ScorerVerdict, error)
+}
+
+// compiledScorers holds the scorers built into the harness, by name
+var compiledScorers = make(map[string]Scorer)
+
+// RegisterScorer builds a scorer into the harness so test configs can list it by name;
+// call it from an init function in a file added to this package
+func RegisterScorer(scorer Scorer) {
+	compiledScorers[scorer.Name()] = scorer
+}
+
+// Name returns the name test configs select the evaluator's matcher by
+func (ev *Evaluator) Name() string {
+	return models.ExactPathScorer
+}
+
+// Score matches a response against the test case's expected variants, in the workflow
+// mode also requiring the calls to have worked, and checks its expected reply
+func (ev *Evaluator) Score(testCase models.TestCase, response *models.ChatResponse) (models.ScorerVerdict, error) {
+	return exactPathVerdict(ev.matchExpected(testCase, response)), nil
+}
+
+// SetScorers sets the chain of scorers that decide whether a test passes, replacing
+// the exact-path matcher alone; an empty chain restores it
+func (ev *Evaluator) SetScorers(configs []models.ScorerConfig) error {
+	scorers, err := buildScorers(configs, ev)
+	if err != nil {
+		return err
+	}
+	ev.scorers = scorers
+	return nil
+}
+
+// ScorerNames returns the names of the scorer chain, or nil for exact-path alone
+func (ev *Evaluator) ScorerNames() []string {
+	var names []string
+	for _, scorer := range ev.scorers {
+		names = append(names, scorer.Name())
+	}
+	return names
+}
+
+// ValidateScorers checks a test config's scorer chain: every scorer is named once, and
+// names the built-in or a compiled-in scorer unless it has a command
+func ValidateScorers(configs []models.ScorerConfig) error {
+	_, err := buildScorers(configs, NewEvaluator())
+	return err
+}
+
+// buildScorers resolves a scorer chain, using exactPath for the built-in matcher
+func buildScorers(configs []models.ScorerConfig, exactPath Scorer) ([]Scorer, error) {
+	var scorers []Scorer
+	names := make(map[string]bool)
+	for i, config := range configs {
+		if config.Name == "" {
+			return nil, fmt.Errorf("scorer %d has no name", i+1)
+		}
+		if names[config.Name] {
+			return nil, fmt.Errorf("scorer '%s' is listed twice", config.Name)
+		}
+		names[config.Name] = true
+
+		if config.Command == "" {
+			if config.Timeout != "" {
+				return nil, fmt.Errorf("scorer '%s' sets a timeout but has no command", config.Name)
+			}
+			if config.Name == models.ExactPathScorer {
+				scorers = append(scorers, exactPath)
+				continue
+			}
+			scorer, ok := compiledScorers[config.Name]
+			if !ok {
+				return nil, fmt.Errorf("unknown scorer '%s' (expected %s, a compiled-in scorer or a command)", config.Name, models.ExactPathScorer)
+			}
+			scorers = append(scorers, scorer)
+			continue
+		}
+
+		if config.Name == models.ExactPathScorer || compiledScorers[config.Name] != nil {
+			return nil, fmt.Errorf("scorer '%s' has a command but names a built-in scorer", config.Name)
+		}
+		scorer := &commandScorer{config: config, timeout: defaultScorerTimeout}
+		if config.Timeout != "" {
+			timeout, err := time.ParseDuration(config.Timeout)
+			if err != nil || timeout <= 0 {
+				return nil, fmt.Errorf("scorer '%s' has an invalid timeout '%s'", config.Name, config.Timeout)
+			}
+			scorer.timeout = timeout
+		}
+		scorers = append(scorers, scorer)
+	}
+	return scorers, nil
+}
+
+// runScorers decides a response with the scorer chain. The exact-path verdict is the
+// matcher's outcome, which keeps its failure reason; a chain without exact-path
+// ignores that outcome. Every scorer runs, and the test passes only when all pass.
+func (ev *Evaluator) runScorers(testCase models.TestCase, response *models.ChatResponse, matched evaluation) evaluation {
+	result := evaluation{success: true, recoveredToolErrors: matched.recoveredToolErrors}
+	for _, scorer := range ev.scorers {
+		if scorer.Name() == models.ExactPathScorer {
+			result.scorerVerdicts = append(result.scorerVerdicts, exactPathVerdict(matched))
+			result.matchedPath = matched.matchedPath
+			if result.success && !matched.success {
+				result.success = false
+				result.failureReason = matched.failureReason
+				result.failureDetails = matched.failureDetails
+			}
+			continue
+		}
+
+		verdict, err := scorer.Score(testCase, response)
+		if err != nil {
+			verdict = models.ScorerVerdict{Details: fmt.Sprintf("scorer failed: %v", err)}
+		}
+		verdict.Scorer = scorer.Name()
+		result.scorerVerdicts = append(result.scorerVerdicts, verdict)
+		if result.success && !verdict.Passed {
+			result.success = false
+			result.failureReason = models.FailureScorerRejected
+			result.failureDetails = verdict.Scorer
+			if verdict.Details != "" {
+				result.failureDetails += ": " + verdict.Details
+			}
+		}
+	}
+	return result
+}
+
+// exactPathVerdict renders the matcher's outcome as a scorer verdict
+func exactPathVerdict(matched evaluation) models.ScorerVerdict {
+	verdict := models.ScorerVerdict{Scorer: models.ExactPathScorer, Passed: matched.success}
+	switch {
+	case matched.success:
+		verdict.Score = 1
+		if matched.matchedPath != "" {
+			verdict.Details = fmt.Sprintf("matched variant %s", matched.matchedPath)
+		}
+	case matched.failureDetails != "":
+		verdict.Details = fmt.Sprintf("%s: %s", matched.failureReason, matched.failureDetails)
+	default:
+		verdict.Details = string(matched.failureReason)
+	}
+	return verdict
+}
+
+// commandScorer is a scorer run as a subprocess
+type commandScorer struct {
+	config  models.ScorerConfig
+	timeout time.Duration
+}
+
+// scorerRequest is the JSON a command scorer receives on stdin
+type scorerRequest struct {
+	TestCase models.TestCase      `json:"test_case"`
+	Response *models.ChatResponse `json:"response"`
+}
+
+// scorerOutput is the JSON a command scorer writes on stdout; passed is required
+type scorerOutput struct {
+	Passed  *bool    `json:"passed"`
+	Score   *float64 `json:"score"`
+	Details string   `json:"details"`
+}
+
+// Name returns the scorer's configured name
+func (s *commandScorer) Name() string {
+	return s.config.Name
+}
+
+// Score runs the command for one test and decodes its verdict. A missing score is 1
+// for a pass and 0 for a failure.
+func (s *commandScorer) Score(testCase models.TestCase, response *models.ChatResponse) (models.ScorerVerdict, error) {
+	input, err := json.Marshal(scorerRequest{TestCase: testCase, Response: response})
+	if err != nil {
+		return models.ScorerVerdict{}, fmt.Errorf("failed to marshal scorer request: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := ShellCommand(ctx, s.config.Command)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.Env = append(os.Environ(), "MODEL_TEST_SCORER="+s.config.Name, "MODEL_TEST_TEST_CASE="+testCase.Name)
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return models.ScorerVerdict{}, fmt.Errorf("timed out after %v", s.timeout)
+		}
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return models.ScorerVerdict{}, fmt.Errorf("%w: %s", err, message)
+		}
+		return models.ScorerVerdict{}, err
+	}
+
+	var output scorerOutput
+	if err := json.Unmarshal(stdout.Bytes(), &output); err != nil {
+		return models.ScorerVerdict{}, fmt.Errorf("failed to parse scorer output: %w", err)
+	}
+	if output.Passed == nil {
+		return models.ScorerVerdict{}, fmt.Errorf("scorer output has no passed verdict")
+	}
+
+	verdict := models.ScorerVerdict{Scorer: s.config.Name, Passed: *output.Passed, Details: output.Details}
+	switch {
+	case output.Score != nil:
+		if *output.Score < 0 || *output.Score > 1 {
+			return models.ScorerVerdict{}, fmt.Errorf("scorer output has score %g outside 0 to 1", *output.Score)
+		}
+		verdict.Score = *output.Score
+	case verdict.Passed:
+		verdict.Score = 1
+	}
+	return verdict, nil
+}
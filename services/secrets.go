@@ -0,0 +1,110 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// minSecretLength is the shortest value redacted; shorter ones are placeholders such as
+// the default "DMR" key, and redacting them would mangle unrelated text
+const minSecretLength = 8
+
+// redactedValue replaces secrets in everything written to disk or streamed
+const redactedValue = "[REDACTED]"
+
+// sensitiveHeaderPattern matches header names whose values are credentials
+var sensitiveHeaderPattern = regexp.MustCompile(`(?i)authorization|api[-_]?key|token|secret|password|cookie`)
+
+// secretRegistry holds every credential the run has used, so result files, manifests,
+// checkpoints and logs can be scrubbed of them
+var secretRegistry = struct {
+	sync.RWMutex
+	values map[string]bool
+}{values: make(map[string]bool)}
+
+// RegisterSecret adds a credential to redact from everything the run writes
+func RegisterSecret(value string) {
+	value = strings.TrimSpace(value)
+	if len(value) < minSecretLength {
+		return
+	}
+	secretRegistry.Lock()
+	defer secretRegistry.Unlock()
+	secretRegistry.values[value] = true
+}
+
+// registerSensitiveHeaders registers the values of headers that carry credentials
+func registerSensitiveHeaders(headers map[string]string) {
+	for name, value := range headers {
+		if sensitiveHeaderPattern.MatchString(name) {
+			RegisterSecret(strings.TrimPrefix(value, "Bearer "))
+		}
+	}
+}
+
+// RedactSecrets replaces every registered credential in data, in raw and JSON-escaped
+// form
+func RedactSecrets(data []byte) []byte {
+	secretRegistry.RLock()
+	defer secretRegistry.RUnlock()
+	for secret := range secretRegistry.values {
+		data = bytes.ReplaceAll(data, []byte(secret), []byte(redactedValue))
+		if escaped, err := json.Marshal(secret); err == nil {
+			escaped = escaped[1 : len(escaped)-1]
+			data = bytes.ReplaceAll(data, escaped, []byte(redactedValue))
+		}
+	}
+	return data
+}
+
+// IsRedacted reports whether a secret was redacted from text
+func IsRedacted(text string) bool {
+	return strings.Contains(text, redactedValue)
+}
+
+// RedactString replaces every registered credential in text
+func RedactString(text string) string {
+	return string(RedactSecrets([]byte(text)))
+}
+
+// ReadAPIKeyFile reads an API key from a file, ignoring surrounding whitespace
+func ReadAPIKeyFile(filename string) (string, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return "", fmt.Errorf("failed to read API key file: %w", err)
+	}
+	apiKey := strings.TrimSpace(string(data))
+	if apiKey == "" {
+		return "", fmt.Errorf("API key file %s is empty", filename)
+	}
+	RegisterSecret(apiKey)
+	return apiKey, nil
+}
+
+// RunAPIKeyCommand runs a credential helper through the shell and returns the API key
+// it prints, e.g. "security find-generic-password -s model-test -w" for the macOS
+// keychain or "secret-tool lookup service model-test" for the Secret Service
+func RunAPIKeyCommand(command string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			err = fmt.Errorf("%w: %s", err, message)
+		}
+		return "", fmt.Errorf("API key command '%s' failed: %w", command, err)
+	}
+	apiKey := strings.TrimSpace(string(output))
+	if apiKey == "" {
+		return "", fmt.Errorf("API key command '%s' printed no key", command)
+	}
+	RegisterSecret(apiKey)
+	return apiKey, nil
+}
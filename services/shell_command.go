@@ -0,0 +1,21 @@
+package services
+
+import (
+	"context"
+	"os/exec"
+	"time"
+)
+
+// shellWaitDelay bounds how long a killed shell command may hold its output pipes open,
+// e.g. through a background child that escaped the kill
+const shellWaitDelay = 2 * time.Second
+
+// ShellCommand prepares a command run through sh -c. When the context ends, the shell
+// and every process it started are killed, so a timeout is not held up by children
+// still writing to the command's output.
+func ShellCommand(ctx context.Context, command string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	killProcessGroup(cmd)
+	cmd.WaitDelay = shellWaitDelay
+	return cmd
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package services
+
+import "os/exec"
+
+// killProcessGroup leaves cancellation to kill the shell alone where process groups
+// are not available; the wait delay still bounds the wait for its children
+func killProcessGroup(cmd *exec.Cmd) {}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"model-test/models"
+)
+
+// SignatureAlgorithm is how result files and manifests are signed
+const SignatureAlgorithm = "hmac-sha256"
+
+// minSigningKeyLength is the shortest signing key accepted, in bytes
+const minSigningKeyLength = 32
+
+// LoadSigningKey reads a shared signing key from a file, ignoring surrounding whitespace
+func LoadSigningKey(filename string) ([]byte, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing key: %w", err)
+	}
+	key := []byte(strings.TrimSpace(string(data)))
+	if len(key) < minSigningKeyLength {
+		return nil, fmt.Errorf("signing key in %s is %d bytes; use at least %d (e.g. openssl rand -hex 32)", filename, len(key), minSigningKeyLength)
+	}
+	return key, nil
+}
+
+// SignatureFile returns where the signature of a file is stored
+func SignatureFile(filename string) string {
+	return filename + ".sig"
+}
+
+// SignFile writes a detached signature of the file's current content; it does nothing
+// without a key
+func SignFile(filename string, key []byte) error {
+	if len(key) == 0 {
+		return nil
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("failed to read %s for signing: %w", filename, err)
+	}
+	signature := models.FileSignature{
+		Algorithm: SignatureAlgorithm,
+		KeyID:     SigningKeyID(key),
+		Signature: hex.EncodeToString(fileMAC(data, key)),
+		SignedAt:  time.Now().UTC(),
+	}
+
+	encoded, err := json.MarshalIndent(signature, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal signature: %w", err)
+	}
+	if err := os.WriteFile(SignatureFile(filename), encoded, 0644); err != nil {
+		return fmt.Errorf("failed to save signature of %s: %w", filename, err)
+	}
+	return nil
+}
+
+// VerifyFile checks a file against its detached signature
+func VerifyFile(filename string, key []byte) (models.SignatureStatus, error) {
+	encoded, err := os.ReadFile(SignatureFile(filename))
+	if os.IsNotExist(err) {
+		return models.SignatureMissing, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read signature of %s: %w", filename, err)
+	}
+
+	var signature models.FileSignature
+	if err := json.Unmarshal(encoded, &signature); err != nil {
+		return models.SignatureMismatch, nil
+	}
+	if signature.Algorithm != SignatureAlgorithm || signature.KeyID != SigningKeyID(key) {
+		return models.SignatureForeignKey, nil
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s for verification: %w", filename, err)
+	}
+	expected, err := hex.DecodeString(signature.Signature)
+	if err != nil || !hmac.Equal(expected, fileMAC(data, key)) {
+		return models.SignatureMismatch, nil
+	}
+	return models.SignatureValid, nil
+}
+
+// fileMAC computes the HMAC-SHA256 of a file's bytes
+func fileMAC(data, key []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+// SigningKeyID fingerprints a signing key without revealing it
+func SigningKeyID(key []byte) string {
+	sum := sha256.Sum256(append([]byte("model-test signing key:"), key...))
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+
+	"model-test/models"
+)
+
+// streamTiming is what streaming measured for a single completion request
+type streamTiming struct {
+	firstToken       time.Duration // From sending the request to the first content or tool call delta
+	duration         time.Duration // From sending the request to the end of the stream
+	completionTokens int64
+	estimated        bool // completionTokens counts streamed chunks because usage was not reported
+}
+
+// streamCompletion sends the request with the SDK's streaming API and accumulates the
+// chunks into the same completion a non-streamed request returns, timing the first token
+func (ai *OpenAIService) streamCompletion(ctx context.Context, client openai.Client, params openai.ChatCompletionNewParams, startedAt time.Time, opts ...option.RequestOption) (*openai.ChatCompletion, streamTiming, error) {
+	var timing streamTiming
+	var chunks int64
+	var accumulator openai.ChatCompletionAccumulator
+
+	stream := client.Chat.Completions.NewStreaming(ctx, params, opts...)
+	defer stream.Close()
+
+	for stream.Next() {
+		chunk := stream.Current()
+		if chunkHasOutput(chunk) {
+			if chunks == 0 {
+				timing.firstToken = time.Since(startedAt)
+			}
+			chunks++
+		}
+		if !accumulator.AddChunk(chunk) {
+			return nil, timing, fmt.Errorf("failed to accumulate streamed chunk %q", chunk.ID)
+		}
+	}
+	timing.duration = time.Since(startedAt)
+	if err := stream.Err(); err != nil {
+		return nil, timing, err
+	}
+	if len(accumulator.Choices) == 0 {
+		return nil, timing, fmt.Errorf("stream ended without a choice")
+	}
+
+	timing.completionTokens = accumulator.Usage.CompletionTokens
+	if timing.completionTokens == 0 {
+		timing.completionTokens = chunks
+		timing.estimated = true
+	}
+	return &accumulator.ChatCompletion, timing, nil
+}
+
+// chunkHasOutput reports whether a streamed chunk carries generated content, a refusal
+// or part of a tool call, rather than only a role, finish reason or usage
+func chunkHasOutput(chunk openai.ChatCompletionChunk) bool {
+	for _, choice := range chunk.Choices {
+		if choice.Delta.Content != "" || choice.Delta.Refusal != "" || len(choice.Delta.ToolCalls) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// summarizeStreaming combines the timings of a test's streamed requests, or returns
+// nil when nothing was streamed
+func summarizeStreaming(timings []streamTiming) *models.StreamingMetrics {
+	if len(timings) == 0 {
+		return nil
+	}
+
+	metrics := &models.StreamingMetrics{
+		Requests:         len(timings),
+		TimeToFirstToken: timings[0].firstToken,
+	}
+	var totalFirstToken time.Duration
+	for _, timing := range timings {
+		totalFirstToken += timing.firstToken
+		metrics.CompletionTokens += timing.completionTokens
+		metrics.GenerationTime += timing.duration - timing.firstToken
+		metrics.TokensEstimated = metrics.TokensEstimated || timing.estimated
+	}
+	metrics.AvgTimeToFirstToken = totalFirstToken / time.Duration(len(timings))
+	if metrics.GenerationTime > 0 {
+		metrics.TokensPerSecond = float64(metrics.CompletionTokens) / metrics.GenerationTime.Seconds()
+	}
+	return metrics
+}
+
+// summarizeReportStreaming averages the streaming metrics of every streamed test,
+// weighting the average time to first token by each test's requests, or returns nil
+// when the run did not stream
+func summarizeReportStreaming(results []models.AgentTestResult) *models.StreamingMetrics {
+	var summary models.StreamingMetrics
+	var tests int
+	var totalFirstToken, totalAvgFirstToken time.Duration
+
+	for _, result := range results {
+		if result.Response == nil || result.Response.Streaming == nil {
+			continue
+		}
+		streaming := result.Response.Streaming
+		tests++
+		totalFirstToken += streaming.TimeToFirstToken
+		totalAvgFirstToken += streaming.AvgTimeToFirstToken * time.Duration(streaming.Requests)
+		summary.Requests += streaming.Requests
+		summary.CompletionTokens += streaming.CompletionTokens
+		summary.GenerationTime += streaming.GenerationTime
+		summary.TokensEstimated = summary.TokensEstimated || streaming.TokensEstimated
+	}
+	if tests == 0 {
+		return nil
+	}
+
+	summary.TimeToFirstToken = totalFirstToken / time.Duration(tests)
+	summary.AvgTimeToFirstToken = totalAvgFirstToken / time.Duration(summary.Requests)
+	if summary.GenerationTime > 0 {
+		summary.TokensPerSecond = float64(summary.CompletionTokens) / summary.GenerationTime.Seconds()
+	}
+	return &summary
+}
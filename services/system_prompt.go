@@ -0,0 +1,66 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+
+	"model-test/models"
+)
+
+// systemPromptVariant returns the name a test's system prompt is summarized under:
+// its declared variant, "custom-" and a short hash of an unnamed prompt, or
+// DefaultSystemPromptVariant when the test does not replace the run's prompt
+func systemPromptVariant(testCase models.TestCase) string {
+	switch {
+	case testCase.SystemPromptVariant != "":
+		return testCase.SystemPromptVariant
+	case testCase.SystemPrompt != "":
+		sum := sha256.Sum256([]byte(testCase.SystemPrompt))
+		return "custom-" + hex.EncodeToString(sum[:4])
+	default:
+		return models.DefaultSystemPromptVariant
+	}
+}
+
+// summarizeSystemPromptVariants groups the results by system prompt variant so the
+// effect of the prompt on tool selection can be compared within one run. It returns
+// nil when every test used the same prompt.
+func summarizeSystemPromptVariants(results []models.AgentTestResult) []models.SystemPromptVariantResult {
+	byVariant := make(map[string]*models.SystemPromptVariantResult)
+	testCases := make(map[string]map[string]bool)
+
+	for _, result := range results {
+		variant := systemPromptVariant(result.TestCase)
+		entry, exists := byVariant[variant]
+		if !exists {
+			entry = &models.SystemPromptVariantResult{Variant: variant}
+			byVariant[variant] = entry
+			testCases[variant] = make(map[string]bool)
+		}
+
+		entry.TotalTests++
+		testCases[variant][result.TestCase.Name] = true
+		if result.Success {
+			entry.PassedTests++
+		}
+		if selectedExpectedTools(result) {
+			entry.CorrectSelection++
+		}
+	}
+	if len(byVariant) < 2 {
+		return nil
+	}
+
+	summaries := make([]models.SystemPromptVariantResult, 0, len(byVariant))
+	for variant, entry := range byVariant {
+		entry.TestCases = len(testCases[variant])
+		entry.SelectionAccuracy = float64(entry.CorrectSelection) / float64(entry.TotalTests)
+		entry.SuccessRate = float64(entry.PassedTests) / float64(entry.TotalTests)
+		summaries = append(summaries, *entry)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].Variant < summaries[j].Variant
+	})
+	return summaries
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"model-test/models"
+)
+
+// TelemetrySampler runs an external hook command periodically during a run and
+// collects its JSON output (GPU temperatures, clocks, ...) into a timeline, so latency
+// anomalies can be correlated with hardware throttling
+type TelemetrySampler struct {
+	command  string
+	interval time.Duration
+	events   *EventBroadcaster
+
+	started time.Time
+	samples []models.TelemetrySample
+	mutex   sync.Mutex
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// NewTelemetrySampler creates a sampler that runs the command through the shell
+// every interval
+func NewTelemetrySampler(command string, interval time.Duration) (*TelemetrySampler, error) {
+	if strings.TrimSpace(command) == "" {
+		return nil, fmt.Errorf("telemetry hook command is empty")
+	}
+	if interval <= 0 {
+		return nil, fmt.Errorf("telemetry interval must be positive, got %v", interval)
+	}
+	return &TelemetrySampler{command: command, interval: interval}, nil
+}
+
+// SetEventBroadcaster publishes each sample as a live run event
+func (ts *TelemetrySampler) SetEventBroadcaster(events *EventBroadcaster) {
+	ts.events = events
+}
+
+// Start takes a first sample and keeps sampling every interval until Stop is called
+func (ts *TelemetrySampler) Start(ctx context.Context) {
+	ctx, ts.cancel = context.WithCancel(ctx)
+	ts.done = make(chan struct{})
+	ts.started = time.Now()
+
+	go func() {
+		defer close(ts.done)
+
+		ticker := time.NewTicker(ts.interval)
+		defer ticker.Stop()
+
+		ts.sample(ctx)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				ts.sample(ctx)
+			}
+		}
+	}()
+}
+
+// Stop ends periodic sampling, takes a final sample and returns the timeline
+func (ts *TelemetrySampler) Stop() []models.TelemetrySample {
+	ts.cancel()
+	<-ts.done
+	ts.sample(context.Background())
+
+	ts.mutex.Lock()
+	defer ts.mutex.Unlock()
+	return append([]models.TelemetrySample(nil), ts.samples...)
+}
+
+// sample runs the hook once and records its output. A hook that runs longer than
+// the interval is cut off so samples stay evenly spaced.
+func (ts *TelemetrySampler) sample(ctx context.Context) {
+	now := time.Now()
+	sample := models.TelemetrySample{Timestamp: now, Offset: now.Sub(ts.started)}
+
+	ctx, cancel := context.WithTimeout(ctx, ts.interval)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := ShellCommand(ctx, ts.command)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.Canceled {
+			return // Stopped mid-sample; the final sample follows
+		}
+		sample.Error = fmt.Sprintf("hook failed: %v", err)
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			sample.Error += ": " + message
+		}
+	} else if output := bytes.TrimSpace(stdout.Bytes()); !json.Valid(output) {
+		sample.Error = "hook output is not valid JSON"
+	} else {
+		sample.Data = json.RawMessage(output)
+	}
+
+	ts.mutex.Lock()
+	ts.samples = append(ts.samples, sample)
+	ts.mutex.Unlock()
+
+	ts.events.Publish(models.RunEvent{Type: models.EventTelemetrySampled, Data: sample})
+}
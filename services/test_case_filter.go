@@ -0,0 +1,114 @@
+package services
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"model-test/models"
+)
+
+// SelectTestCases keeps the test cases named by a comma-separated list of names and
+// glob patterns (e.g. "simple_view_cart,checkout_*"), in configuration order. Every
+// entry must match at least one test case.
+func SelectTestCases(testCases []models.TestCase, selection string) ([]models.TestCase, error) {
+	patterns := ParseTagList(selection)
+	matched := make([]bool, len(patterns))
+	var selected []models.TestCase
+	for _, testCase := range testCases {
+		keep := false
+		for i, pattern := range patterns {
+			ok, err := path.Match(pattern, testCase.Name)
+			if err != nil {
+				return nil, fmt.Errorf("invalid test case pattern '%s': %w", pattern, err)
+			}
+			if ok {
+				matched[i] = true
+				keep = true
+			}
+		}
+		if keep {
+			selected = append(selected, testCase)
+		}
+	}
+
+	var unmatched []string
+	for i, pattern := range patterns {
+		if !matched[i] {
+			unmatched = append(unmatched, pattern)
+		}
+	}
+	if len(unmatched) > 0 {
+		return nil, fmt.Errorf("no test case matches %s", strings.Join(unmatched, ", "))
+	}
+	return selected, nil
+}
+
+// FilterTestCasesByTags keeps the test cases labeled with at least one of the include
+// tags (all of them when include is empty) and none of the exclude tags. An include
+// tag no test case carries is an error, since it is most likely a typo.
+func FilterTestCasesByTags(testCases []models.TestCase, include, exclude []string) ([]models.TestCase, error) {
+	known := make(map[string]bool)
+	for _, testCase := range testCases {
+		for _, tag := range testCase.Tags {
+			known[tag] = true
+		}
+	}
+	for _, tag := range include {
+		if !known[tag] {
+			return nil, fmt.Errorf("no test case is tagged '%s' (known tags: %s)", tag, strings.Join(sortedTags(known), ", "))
+		}
+	}
+	for _, tag := range exclude {
+		if !known[tag] {
+			fmt.Printf("⚠️  No test case is tagged '%s'; nothing excluded for it\n", tag)
+		}
+	}
+
+	var filtered []models.TestCase
+	for _, testCase := range testCases {
+		if len(include) > 0 && !hasAnyTag(testCase, include) {
+			continue
+		}
+		if hasAnyTag(testCase, exclude) {
+			continue
+		}
+		filtered = append(filtered, testCase)
+	}
+	if len(filtered) == 0 {
+		return nil, fmt.Errorf("no test cases left after filtering by tags")
+	}
+	return filtered, nil
+}
+
+// ParseTagList splits a comma-separated tag (or test case) list, dropping empty entries
+func ParseTagList(value string) []string {
+	var tags []string
+	for _, tag := range strings.Split(value, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// hasAnyTag reports whether the test case carries any of the tags
+func hasAnyTag(testCase models.TestCase, tags []string) bool {
+	for _, tag := range tags {
+		if testCase.HasTag(tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedTags returns the tags of a set in alphabetical order
+func sortedTags(set map[string]bool) []string {
+	tags := make([]string, 0, len(set))
+	for tag := range set {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags
+}
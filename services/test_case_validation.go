@@ -0,0 +1,158 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/openai/openai-go"
+
+	"model-test/models"
+)
+
+// ValidateTestCases checks test cases against the tool definitions for mistakes that
+// would otherwise only surface as failed tests: missing names or prompts, duplicate
+// names, expected or forbidden calls to unknown tools, expected arguments the tool
+// does not take, and invalid match policies or expected_response checks. A tool may be
+// named by any of its aliases. It returns one message per problem.
+func ValidateTestCases(testCases []models.TestCase, definitions []openai.ChatCompletionToolParam, aliases models.ToolAliases) []string {
+	parameters := make(map[string]map[string]bool, len(definitions))
+	for _, definition := range definitions {
+		known := make(map[string]bool)
+		if properties, ok := definition.Function.Parameters["properties"].(map[string]interface{}); ok {
+			for name := range properties {
+				known[name] = true
+			}
+		}
+		parameters[definition.Function.Name] = known
+	}
+
+	// Every name of a defined tool takes its parameters
+	for canonical, names := range aliases {
+		group := append([]string{canonical}, names...)
+		for _, name := range group {
+			if known, defined := parameters[name]; defined {
+				for _, equivalent := range group {
+					if _, exists := parameters[equivalent]; !exists {
+						parameters[equivalent] = known
+					}
+				}
+				break
+			}
+		}
+	}
+
+	var problems []string
+	seen := make(map[string]bool)
+	for i, testCase := range testCases {
+		label := testCase.Name
+		if label == "" {
+			label = fmt.Sprintf("test case #%d", i+1)
+			problems = append(problems, fmt.Sprintf("%s: missing name", label))
+		} else if seen[label] {
+			problems = append(problems, fmt.Sprintf("%s: duplicate name", label))
+		}
+		seen[label] = true
+
+		if strings.TrimSpace(testCase.Prompt) == "" {
+			problems = append(problems, fmt.Sprintf("%s: missing prompt", label))
+		}
+		for _, tool := range testCase.ForbiddenTools {
+			if _, ok := parameters[tool]; !ok {
+				problems = append(problems, fmt.Sprintf("%s: forbids unknown tool %s", label, tool))
+			}
+		}
+		if testCase.InitialCartState != nil {
+			for _, item := range testCase.InitialCartState.Items {
+				if item.Quantity < 1 {
+					problems = append(problems, fmt.Sprintf("%s: initial cart item %s has quantity %d", label, item.ProductName, item.Quantity))
+				}
+			}
+		}
+
+		problems = append(problems, validateExpectedResponse(label, testCase.ExpectedResponse)...)
+		problems = append(problems, validateRubric(label, testCase.Rubric)...)
+
+		variants := make(map[string]bool)
+		for j, variant := range testCase.ExpectedToolVariants {
+			variantLabel := variant.Name
+			if variantLabel == "" {
+				variantLabel = fmt.Sprintf("#%d", j+1)
+				problems = append(problems, fmt.Sprintf("%s: variant %s has no name", label, variantLabel))
+			} else if variants[variantLabel] {
+				problems = append(problems, fmt.Sprintf("%s: duplicate variant %s", label, variantLabel))
+			}
+			variants[variantLabel] = true
+			switch variant.Order {
+			case "", models.ToolOrderStrict, models.ToolOrderAny:
+			default:
+				problems = append(problems, fmt.Sprintf("%s: variant %s has unknown order %q (expected strict or any)", label, variantLabel, variant.Order))
+			}
+
+			for _, tool := range variant.Tools {
+				problems = append(problems, validateExpectedCall(fmt.Sprintf("%s: variant %s", label, variantLabel), tool, parameters)...)
+			}
+		}
+	}
+	return problems
+}
+
+// validateExpectedCall checks one expected call's tool, required, optional and absent
+// arguments, matchers, match policies and allowed errors
+func validateExpectedCall(label string, tool models.ExpectedToolCall, parameters map[string]map[string]bool) []string {
+	known, ok := parameters[tool.Name]
+	if !ok {
+		return []string{fmt.Sprintf("%s: expects unknown tool %s", label, tool.Name)}
+	}
+
+	var problems []string
+	for _, argument := range sortedKeys(tool.Arguments) {
+		if !known[argument] {
+			problems = append(problems, fmt.Sprintf("%s: %s takes no argument %s", label, tool.Name, argument))
+		}
+		if err := validateMatchers(tool.Arguments[argument]); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %s.%s: %v", label, tool.Name, argument, err))
+		}
+	}
+	for _, argument := range sortedKeys(tool.OptionalArguments) {
+		if !known[argument] {
+			problems = append(problems, fmt.Sprintf("%s: %s takes no argument %s", label, tool.Name, argument))
+		}
+		if _, required := tool.Arguments[argument]; required {
+			problems = append(problems, fmt.Sprintf("%s: %s.%s is both required and optional", label, tool.Name, argument))
+		}
+		if err := validateMatchers(tool.OptionalArguments[argument]); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %s.%s: %v", label, tool.Name, argument, err))
+		}
+	}
+	for _, code := range tool.AllowedErrors {
+		switch code {
+		case models.ToolErrorInvalidArguments, models.ToolErrorExecutionFailed:
+		default:
+			problems = append(problems, fmt.Sprintf("%s: %s allows error %q (expected invalid_arguments or execution_failed)", label, tool.Name, code))
+		}
+	}
+	for _, argument := range tool.AbsentArguments {
+		if !known[argument] {
+			problems = append(problems, fmt.Sprintf("%s: %s takes no argument %s", label, tool.Name, argument))
+		}
+		_, required := tool.Arguments[argument]
+		_, optional := tool.OptionalArguments[argument]
+		if required || optional {
+			problems = append(problems, fmt.Sprintf("%s: %s.%s is both expected and absent", label, tool.Name, argument))
+		}
+	}
+	for _, argument := range sortedKeys(tool.MatchPolicy) {
+		policy := tool.MatchPolicy[argument]
+		_, required := tool.Arguments[argument]
+		_, optional := tool.OptionalArguments[argument]
+		if !required && !optional {
+			problems = append(problems, fmt.Sprintf("%s: %s has a match policy for unexpected argument %s", label, tool.Name, argument))
+		}
+		switch policy.UnicodeNormalization {
+		case "", "none", "nfc", "nfkc":
+		default:
+			problems = append(problems, fmt.Sprintf("%s: %s.%s has unknown unicode normalization %q", label, tool.Name, argument, policy.UnicodeNormalization))
+		}
+	}
+	return problems
+}
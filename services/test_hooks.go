@@ -0,0 +1,83 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"model-test/models"
+)
+
+// SetSuiteHooks sets the test config's hooks run once before and once after each suite
+func (tr *TestRunner) SetSuiteHooks(setup, teardown []models.Hook) {
+	tr.suiteSetup = setup
+	tr.suiteTeardown = teardown
+}
+
+// runSuiteHooks runs the test config's setup or teardown hooks, printing each outcome
+func (tr *TestRunner) runSuiteHooks(ctx context.Context, stage models.HookStage, hooks []models.Hook) ([]models.HookResult, error) {
+	if len(hooks) == 0 {
+		return nil, nil
+	}
+	fmt.Printf("🪝 Running %d %s hooks\n", len(hooks), stage)
+
+	results, err := RunHookList(ctx, hooks, stage, tr.hookEnv(nil, models.TestConfig{}))
+	for _, result := range results {
+		status := "✅"
+		if !result.Success {
+			status = "❌"
+		}
+		fmt.Printf("   %s %s (%v)", status, result.Name, result.Duration.Round(time.Millisecond))
+		if result.Error != "" {
+			fmt.Printf(": %s", result.Error)
+		}
+		fmt.Println()
+	}
+	return results, err
+}
+
+// runAgentTest runs a test case between its setup and teardown hooks. A failing setup
+// fails the test with setup_error without sending it to the model; teardown runs
+// regardless, even after an interruption, and its failures are only recorded.
+func (tr *TestRunner) runAgentTest(ctx context.Context, testCase models.TestCase, config models.TestConfig) models.AgentTestResult {
+	if len(testCase.Setup) == 0 && len(testCase.Teardown) == 0 {
+		return tr.executeAgentTest(ctx, testCase, config)
+	}
+	env := tr.hookEnv(&testCase, config)
+
+	var result models.AgentTestResult
+	setup, err := RunHookList(ctx, testCase.Setup, models.HookTestSetup, env)
+	if err != nil {
+		result = models.AgentTestResult{
+			TestCase:       testCase,
+			ModelName:      tr.getModelName(),
+			Config:         config,
+			Success:        false,
+			FailureReason:  models.FailureSetupError,
+			FailureDetails: err.Error(),
+			Timestamp:      time.Now(),
+		}
+	} else {
+		result = tr.executeAgentTest(ctx, testCase, config)
+	}
+
+	teardown, err := RunHookList(context.WithoutCancel(ctx), testCase.Teardown, models.HookTestTeardown, env)
+	if err != nil {
+		fmt.Printf("⚠️  %s: %v\n", testCase.Name, err)
+	}
+	result.Hooks = append(setup, teardown...)
+	return result
+}
+
+// hookEnv returns the variables hooks see: the model and run ID, plus the test case
+// and sampling config for test case hooks
+func (tr *TestRunner) hookEnv(testCase *models.TestCase, config models.TestConfig) map[string]string {
+	env := map[string]string{"MODEL_TEST_MODEL": tr.getModelName(), "MODEL_TEST_RUN_ID": tr.runID}
+	if testCase != nil {
+		env["MODEL_TEST_CASE"] = testCase.Name
+		if config.Label != "" {
+			env["MODEL_TEST_CONFIG"] = config.Label
+		}
+	}
+	return env
+}
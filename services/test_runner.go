@@ -3,12 +3,21 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
 	"os"
-	"strings"
+	"path/filepath"
+	"regexp"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/openai/openai-go"
+
 	"model-test/models"
 )
 
@@ -19,8 +28,45 @@ type TestRunner struct {
 	mutex         sync.Mutex
 	defaultModel  string
 	logger        *RequestLogger
+	evaluator     *Evaluator
+	runID         string
+	tags          models.RunTags
+	cluster       *models.ClusterInfo
+	events        *EventBroadcaster
+	toolSpec      *models.ToolSpecVersion
+	runs          int
+	configs       []models.TestConfig // Sampling combinations; each runs the whole suite
+	checkpoint    *ResultCheckpoint
+	watchdog      *DeploymentWatchdog
+	completed     map[string]models.AgentTestResult // Results of an interrupted run, by ResultKey
+
+	toolErrorVerbosity models.ToolErrorVerbosity
+
+	pruning    models.PruningPolicy // What SaveResults leaves out of results files
+	signingKey []byte               // SaveResults signs results files with it when set
+
+	maxFailures int // Failed tests after which the suite stops; 0 runs it to the end
+
+	suiteSetup    []models.Hook // Test config hooks run before each suite
+	suiteTeardown []models.Hook // Test config hooks run after each suite
+
+	serviceState models.ServiceState // Whether tests get their own product and cart services
+
+	contextWindow int // Model's context window in tokens; 0 when unknown
+
+	networkChaos *models.NetworkChaos // Faults injected into requests; nil for none
+
+	gateway bool // The base URL is an API gateway whose routing each result records
+
+	reproDir string // Where failed tests' repro bundles are written; empty for none
+
+	judge *Judge // Scores the final replies of tests with a rubric; nil for none
 }
 
+// sessionSequence keeps session IDs, and with them the tests' sandboxes, apart when
+// concurrent runs of a test case start within the same clock tick
+var sessionSequence atomic.Int64
+
 // NewTestRunner creates a new test runner instance
 func NewTestRunner(apiKey, baseURL, defaultModel string) *TestRunner {
 	return NewTestRunnerWithLogger(apiKey, baseURL, defaultModel, nil)
@@ -33,26 +79,344 @@ func NewTestRunnerWithLogger(apiKey, baseURL, defaultModel string, logger *Reque
 		results:       make([]models.AgentTestResult, 0),
 		defaultModel:  defaultModel,
 		logger:        logger,
+		evaluator:     NewEvaluator(),
+		runs:          1,
+		configs:       []models.TestConfig{{}},
+
+		toolErrorVerbosity: models.ToolErrorStandard,
+
+		serviceState: models.ServiceStateIsolated,
+	}
+}
+
+// SetReferenceTime sets the clock used for the system prompt and for resolving
+// relative date expressions in expected arguments
+func (tr *TestRunner) SetReferenceTime(referenceTime time.Time) {
+	tr.evaluator.SetReferenceTime(referenceTime)
+	tr.openaiService.SetReferenceTime(referenceTime)
+}
+
+// SetRunID sets the identifier recorded in the reports produced by this runner
+func (tr *TestRunner) SetRunID(runID string) {
+	tr.runID = runID
+}
+
+// SetRuns sets how many times each test case is executed per suite run
+func (tr *TestRunner) SetRuns(runs int) {
+	if runs < 1 {
+		runs = 1
+	}
+	tr.runs = runs
+}
+
+// SetTestConfig sets the sampling parameters for the suite. A config with a sweep
+// runs the suite once for every combination of its values.
+func (tr *TestRunner) SetTestConfig(config models.TestConfig) {
+	tr.configs = ExpandSweep(config)
+}
+
+// SetCheckpoint sets where each result is written as soon as its test finishes; nil
+// stops checkpointing
+func (tr *TestRunner) SetCheckpoint(checkpoint *ResultCheckpoint) {
+	tr.checkpoint = checkpoint
+}
+
+// SetCompletedResults sets results from an interrupted run of the same model. Tests
+// they cover are not rerun, except those that failed with an API error or timeout;
+// nil clears them.
+func (tr *TestRunner) SetCompletedResults(results []models.AgentTestResult) error {
+	tr.completed = nil
+	for _, result := range results {
+		if result.ModelName != tr.getModelName() {
+			return fmt.Errorf("result for %s was produced by model %s, not %s", result.TestCase.Name, result.ModelName, tr.getModelName())
+		}
+	}
+	for _, result := range results {
+		if !resumable(result) {
+			continue
+		}
+		if tr.completed == nil {
+			tr.completed = make(map[string]models.AgentTestResult)
+		}
+		tr.completed[ResultKey(result)] = result
+	}
+	return nil
+}
+
+// SetWatchdog reruns tests that failed because the endpoint stopped responding,
+// once the watchdog has brought it back
+func (tr *TestRunner) SetWatchdog(watchdog *DeploymentWatchdog) {
+	watchdog.service = tr.openaiService
+	tr.watchdog = watchdog
+}
+
+// SetRateLimiter sets the requests/tokens per minute budget shared by all concurrent
+// tests; nil runs them unpaced
+func (tr *TestRunner) SetRateLimiter(limiter *RateLimiter) {
+	tr.openaiService.SetRateLimiter(limiter)
+}
+
+// SetStreaming sets whether completions are streamed to measure time to first token
+func (tr *TestRunner) SetStreaming(streaming bool) {
+	tr.openaiService.SetStreaming(streaming)
+}
+
+// SetReproDir writes a self-contained reproduction of every failed test into a
+// directory of its own under dir; empty writes none
+func (tr *TestRunner) SetReproDir(dir string) {
+	tr.reproDir = dir
+	tr.openaiService.SetRecordRequests(dir != "")
+}
+
+// ReproDir returns where failed tests' repro bundles are written; empty for none
+func (tr *TestRunner) ReproDir() string {
+	return tr.reproDir
+}
+
+// SetJudge sets the judge model that scores the final replies of test cases with a
+// rubric; nil scores none
+func (tr *TestRunner) SetJudge(judge *Judge) {
+	tr.judge = judge
+}
+
+// SetPruningPolicy sets what SaveResults leaves out of results files; the request log
+// keeps the full data
+func (tr *TestRunner) SetPruningPolicy(policy models.PruningPolicy) {
+	tr.pruning = policy
+}
+
+// SetSigningKey makes SaveResults write a detached signature next to every results file
+func (tr *TestRunner) SetSigningKey(key []byte) {
+	tr.signingKey = key
+}
+
+// SetMaxFailures stops the suite once this many of its tests have failed, so a clearly
+// broken model does not take the full run time; 0 runs every test
+func (tr *TestRunner) SetMaxFailures(maxFailures int) {
+	tr.maxFailures = maxFailures
+}
+
+// SetServiceState sets whether each test runs its tool calls against fresh product
+// and cart services (the default) or all tests share one set
+func (tr *TestRunner) SetServiceState(state models.ServiceState) {
+	tr.serviceState = state
+	tr.openaiService.SetServiceState(state)
+}
+
+// SetContextWindow sets the model's context window in tokens, so a test whose
+// conversation outgrows it fails as context_exceeded instead of with a backend error;
+// 0 disables the check
+func (tr *TestRunner) SetContextWindow(tokens int) {
+	tr.contextWindow = tokens
+	tr.openaiService.SetContextWindow(tokens)
+}
+
+// SetProxy routes the run's requests to the model server through a proxy setting (see
+// ValidateProxy); empty follows the proxy environment variables
+func (tr *TestRunner) SetProxy(proxy string) error {
+	return tr.openaiService.SetProxy(proxy)
+}
+
+// SetHeaders sends static headers with every request of the run to the model server
+func (tr *TestRunner) SetHeaders(headers map[string]string) error {
+	return tr.openaiService.SetHeaders(headers)
+}
+
+// SetGateway treats the base URL as an API gateway such as LiteLLM: each result
+// records the provider prefix of the model name, the models the responses reported
+// and the gateway's routing headers
+func (tr *TestRunner) SetGateway(gateway bool) {
+	tr.gateway = gateway
+	tr.openaiService.SetGateway(gateway)
+}
+
+// SetNetworkChaos injects the faults of a chaos config into the requests of the run
+// and records them with each test; nil sends requests unchanged
+func (tr *TestRunner) SetNetworkChaos(config *models.NetworkChaos) error {
+	if err := tr.openaiService.SetNetworkChaos(config); err != nil {
+		return err
+	}
+	tr.networkChaos = config
+	return nil
+}
+
+// SetTags sets the metadata recorded in the reports produced by this runner
+func (tr *TestRunner) SetTags(tags models.RunTags) {
+	tr.tags = tags
+}
+
+// SetCluster sets the Kamiwaza cluster recorded in the reports produced by this runner
+func (tr *TestRunner) SetCluster(cluster *models.ClusterInfo) {
+	tr.cluster = cluster
+}
+
+// SetToolErrorVerbosity sets how much of a failed tool call is shown to the model
+func (tr *TestRunner) SetToolErrorVerbosity(verbosity models.ToolErrorVerbosity) {
+	tr.toolErrorVerbosity = verbosity
+	tr.openaiService.SetToolErrorVerbosity(verbosity)
+}
+
+// SetApprovalRequired sets the tools whose first call in each test is denied to
+// check whether the model respects the denial
+func (tr *TestRunner) SetApprovalRequired(toolNames []string) error {
+	return tr.openaiService.SetApprovalRequired(toolNames)
+}
+
+// SetToolSpecVersion sets the tool description wording used for subsequent suite
+// runs; nil restores the canonical definitions
+func (tr *TestRunner) SetToolSpecVersion(version *models.ToolSpecVersion) error {
+	if err := tr.openaiService.SetToolSpecVersion(version); err != nil {
+		return err
 	}
+	tr.toolSpec = version
+	return nil
+}
+
+// SetEventBroadcaster sets where live run progress events are published
+func (tr *TestRunner) SetEventBroadcaster(events *EventBroadcaster) {
+	tr.events = events
+	tr.openaiService.SetEventBroadcaster(events)
+}
+
+// SetUnitTable sets the conversion tables used to normalize quantities with units
+func (tr *TestRunner) SetUnitTable(unitTable *UnitTable) {
+	tr.evaluator.SetUnitTable(unitTable)
+}
+
+// SetAdjudications sets human decisions on borderline results, applied when
+// scoring matching responses
+func (tr *TestRunner) SetAdjudications(adjudications map[string]models.Adjudication) {
+	tr.evaluator.SetAdjudications(adjudications)
+}
+
+// SetScoringMode sets whether results also get a partial-credit score
+func (tr *TestRunner) SetScoringMode(mode models.ScoringMode) {
+	tr.evaluator.SetScoringMode(mode)
+}
+
+// SetEvaluationMode sets whether the tool calls must also have worked for a test to pass
+func (tr *TestRunner) SetEvaluationMode(mode models.EvaluationMode) {
+	tr.evaluator.SetEvaluationMode(mode)
+}
+
+// SetScorers sets the chain of scorers that decide whether a test passes
+func (tr *TestRunner) SetScorers(configs []models.ScorerConfig) error {
+	return tr.evaluator.SetScorers(configs)
+}
+
+// SetToolAliases sets the equivalent tool names the evaluator matches as one tool
+func (tr *TestRunner) SetToolAliases(aliases models.ToolAliases) {
+	tr.evaluator.SetToolAliases(aliases)
+}
+
+// SetMatchPolicy sets the global string comparison policy; test cases may override
+// it per argument
+func (tr *TestRunner) SetMatchPolicy(policy models.StringMatchPolicy) {
+	tr.evaluator.SetMatchPolicy(policy)
 }
 
 // RunAgentTestSuite executes a test suite using the agent loop approach
 func (tr *TestRunner) RunAgentTestSuite(ctx context.Context, testCases []models.TestCase) (*models.AgentReport, error) {
-	fmt.Printf("Starting agent test suite with %d test cases\n", len(testCases))
+	switch {
+	case len(tr.configs) > 1:
+		fmt.Printf("Starting agent test suite with %d test cases, %d runs each, across %d sampling configs\n", len(testCases), tr.runs, len(tr.configs))
+	case tr.runs > 1:
+		fmt.Printf("Starting agent test suite with %d test cases, %d runs each\n", len(testCases), tr.runs)
+	default:
+		fmt.Printf("Starting agent test suite with %d test cases\n", len(testCases))
+	}
+	tr.events.Publish(models.RunEvent{
+		Type: models.EventRunStarted,
+		Data: map[string]interface{}{"model": tr.getModelName(), "test_cases": len(testCases), "runs": tr.runs, "sampling_configs": len(tr.configs)},
+	})
+
+	// A failing setup hook aborts the suite before any test runs
+	suiteHooks, err := tr.runSuiteHooks(ctx, models.HookSuiteSetup, tr.suiteSetup)
+	if err != nil {
+		return nil, err
+	}
+
+	// Cancelled once -max-failures tests have failed, which stops the rest like an interruption
+	ctx, stop := context.WithCancel(ctx)
+	defer stop()
 
 	var wg sync.WaitGroup
-	resultsChan := make(chan models.AgentTestResult, len(testCases))
+	resultsChan := make(chan models.AgentTestResult, len(testCases)*tr.runs*len(tr.configs))
 
-	// Execute tests concurrently
-	for _, testCase := range testCases {
-		wg.Add(1)
-		go func(tc models.TestCase) {
-			defer wg.Done()
+	// Results carried over from an interrupted run are checkpointed again so the new
+	// checkpoint alone is enough to resume
+	var results []models.AgentTestResult
+	resumed := 0
+
+	// Repeats and sampling configs share the concurrency of one pass over the test
+	// cases, so -runs and sweeps do not multiply the load on the endpoint
+	slots := make(chan struct{}, len(testCases))
+
+	// Execute tests concurrently, each test case once per run of every sampling config
+	for _, config := range tr.configs {
+		for run := 1; run <= tr.runs; run++ {
+			for _, testCase := range testCases {
+				if previous, done := tr.completedResult(testCase, run, config); done {
+					results = append(results, previous)
+					tr.writeCheckpoint(previous)
+					resumed++
+					continue
+				}
+
+				wg.Add(1)
+				go func(tc models.TestCase, run int, config models.TestConfig) {
+					defer wg.Done()
+
+					select {
+					case slots <- struct{}{}:
+						defer func() { <-slots }()
+					case <-ctx.Done():
+						return
+					}
+
+					// Tests not yet started when the run is interrupted, or after the
+					// endpoint failed to recover, are left for -resume
+					if ctx.Err() != nil || tr.watchdog.Failed() {
+						return
+					}
+
+					name := tc.Name
+					if config.Label != "" {
+						name = fmt.Sprintf("%s [%s]", tc.Name, config.Label)
+					}
+					if tr.runs > 1 {
+						fmt.Printf("Running agent test: %s (run %d/%d)\n", name, run, tr.runs)
+					} else {
+						fmt.Printf("Running agent test: %s\n", name)
+					}
+					tr.events.Publish(models.RunEvent{Type: models.EventTestStarted, TestCase: tc.Name})
+
+					result, finished := tr.runWatchedTest(ctx, tc, config, name)
+					if !finished {
+						return
+					}
+					if tr.runs > 1 {
+						result.Run = run
+					}
+					tr.events.Publish(models.RunEvent{
+						Type:     models.EventTestFinished,
+						TestCase: tc.Name,
+						Success:  &result.Success,
+						Data: map[string]interface{}{
+							"failure_reason": result.FailureReason,
+							"response_time":  result.ResponseTime,
+							"run":            run,
+							"config":         config.Label,
+						},
+					})
+					resultsChan <- result
+				}(testCase, run, config)
+			}
+		}
+	}
 
-			fmt.Printf("Running agent test: %s\n", tc.Name)
-			result := tr.runAgentTest(ctx, tc)
-			resultsChan <- result
-		}(testCase)
+	if resumed > 0 {
+		fmt.Printf("Resumed %d completed tests from the interrupted run\n", resumed)
 	}
 
 	// Wait for all tests to complete
@@ -61,28 +425,161 @@ func (tr *TestRunner) RunAgentTestSuite(ctx context.Context, testCases []models.
 		close(resultsChan)
 	}()
 
-	// Collect results and aggregate LLM metrics
-	var results []models.AgentTestResult
+	// Collect results
+	failures := 0
+	for result := range resultsChan {
+		if !result.Success {
+			tr.writeReproBundle(result)
+		}
+		result.Requests = nil
+		results = append(results, result)
+		tr.writeCheckpoint(result)
+		if result.Success {
+			continue
+		}
+		failures++
+		if tr.maxFailures > 0 && failures == tr.maxFailures {
+			fmt.Printf("🛑 %d tests failed: stopping the run and saving a partial report\n", failures)
+			stop()
+		}
+	}
+
+	// Teardown runs even when the suite was interrupted; its failures are only recorded
+	teardown, err := tr.runSuiteHooks(context.WithoutCancel(ctx), models.HookSuiteTeardown, tr.suiteTeardown)
+	if err != nil {
+		fmt.Printf("⚠️  %v\n", err)
+	}
+	suiteHooks = append(suiteHooks, teardown...)
+
+	report := BuildAgentReport(results, tr.evaluator)
+	report.RunID = tr.runID
+	report.ResumedTests = resumed
+	report.SuiteHooks = suiteHooks
+	if scheduled := len(testCases) * tr.runs * len(tr.configs); len(results) < scheduled {
+		report.Incomplete = true
+		report.InterruptedTests = scheduled - len(results)
+		if tr.maxFailures > 0 && failures >= tr.maxFailures {
+			report.StoppedAfterFailures = tr.maxFailures
+		}
+	}
+	report.Tags = tr.tags
+	report.Cluster = tr.cluster
+	report.DeploymentRecoveries = tr.watchdog.Recoveries()
+	report.ToolErrorVerbosity = tr.toolErrorVerbosity
+	report.ServiceState = tr.serviceState
+	report.ContextWindow = tr.contextWindow
+	report.NetworkChaos = tr.networkChaos
+	report.Gateway = tr.gateway
+	if tr.toolSpec != nil {
+		report.ToolSpecVersion = tr.toolSpec.Name
+		report.ToolLocale = tr.toolSpec.Locale
+	}
+
+	tr.events.Publish(models.RunEvent{
+		Type: models.EventRunFinished,
+		Data: map[string]int{"total_tests": report.TotalTests, "passed_tests": report.PassedTests, "failed_tests": report.FailedTests},
+	})
+
+	return report, nil
+}
+
+// runWatchedTest runs a test, requeuing it while the watchdog brings an unresponsive
+// endpoint back. It reports false when the test did not finish: it was cut short by
+// an interruption, or the deployment died and the test is left for -resume rather
+// than failed.
+func (tr *TestRunner) runWatchedTest(ctx context.Context, testCase models.TestCase, config models.TestConfig, name string) (models.AgentTestResult, bool) {
+	started := tr.watchdog.Generation()
+	result := tr.runAgentTest(ctx, testCase, config)
+	for requeued := 1; requeued <= watchdogMaxRequeues && failedAgainstServer(result) && ctx.Err() == nil; requeued++ {
+		if !tr.watchdog.Requeue(ctx, started) {
+			break
+		}
+		fmt.Printf("Requeuing agent test: %s\n", name)
+		started = tr.watchdog.Generation()
+		result = tr.runAgentTest(ctx, testCase, config)
+		result.Requeued = requeued
+	}
+	if ctx.Err() != nil && !result.Success {
+		return result, false
+	}
+	if tr.watchdog.Failed() && failedAgainstServer(result) {
+		return result, false
+	}
+	return result, true
+}
+
+// WarmUp sends throwaway completions to the endpoint before the suite is timed
+func (tr *TestRunner) WarmUp(ctx context.Context, requests int, timeout time.Duration) *models.Warmup {
+	return tr.openaiService.WarmUp(ctx, requests, timeout)
+}
+
+// completedResult returns the result of an interrupted run for one scheduled test
+func (tr *TestRunner) completedResult(testCase models.TestCase, run int, config models.TestConfig) (models.AgentTestResult, bool) {
+	if tr.completed == nil {
+		return models.AgentTestResult{}, false
+	}
+	scheduled := models.AgentTestResult{TestCase: testCase, Config: config}
+	if tr.runs > 1 {
+		scheduled.Run = run
+	}
+	result, done := tr.completed[ResultKey(scheduled)]
+	return result, done
+}
+
+// writeCheckpoint records a finished result; a failed write only costs the ability
+// to resume, so it is reported rather than aborting the run
+func (tr *TestRunner) writeCheckpoint(result models.AgentTestResult) {
+	if err := tr.checkpoint.Write(result); err != nil {
+		fmt.Printf("⚠️  %v\n", err)
+	}
+}
+
+// BuildAgentReport aggregates test results and LLM metrics into a report, recording
+// the evaluator's matching rules
+func BuildAgentReport(results []models.AgentTestResult, evaluator *Evaluator) *models.AgentReport {
 	var totalTime time.Duration
 	var totalLLMRequests int
 	var totalLLMTime time.Duration
+	var totalToolTime time.Duration
+	var totalOverhead time.Duration
+	var tokenUsage *models.TokenUsage
 	passedTests := 0
 	failedTests := 0
+	infrastructureFailures := 0
+	var totalScore float64
+	scoredTests := 0
 
-	for result := range resultsChan {
-		results = append(results, result)
+	for _, result := range results {
 		totalTime += result.ResponseTime
 
 		// Aggregate LLM metrics from successful responses
 		if result.Response != nil {
 			totalLLMRequests += result.Response.LLMRequests
 			totalLLMTime += result.Response.LLMTotalTime
+			if result.Response.Usage != nil {
+				if tokenUsage == nil {
+					tokenUsage = &models.TokenUsage{}
+				}
+				tokenUsage.Add(*result.Response.Usage)
+			}
+		}
+		if result.Timing != nil {
+			totalToolTime += result.Timing.ToolTime
+			totalOverhead += result.Timing.HarnessOverhead
+		}
+
+		if result.PartialScore != nil {
+			totalScore += result.PartialScore.Score
+			scoredTests++
 		}
 
 		if result.Success {
 			passedTests++
 		} else {
 			failedTests++
+			if failedAgainstServer(result) {
+				infrastructureFailures++
+			}
 		}
 	}
 
@@ -96,7 +593,14 @@ func (tr *TestRunner) RunAgentTestSuite(ctx context.Context, testCases []models.
 		avgTimePerReq = totalLLMTime / time.Duration(totalLLMRequests)
 	}
 
-	report := &models.AgentReport{
+	var meanScore *float64
+	if scoredTests > 0 {
+		mean := totalScore / float64(scoredTests)
+		meanScore = &mean
+	}
+
+	return &models.AgentReport{
+		SchemaVersion:    models.ResultSchemaVersion,
 		Timestamp:        time.Now(),
 		TestSuite:        "Agent Loop Tool Efficiency Test",
 		Results:          results,
@@ -107,17 +611,106 @@ func (tr *TestRunner) RunAgentTestSuite(ctx context.Context, testCases []models.
 		TotalLLMRequests: totalLLMRequests,
 		TotalLLMTime:     totalLLMTime,
 		AvgTimePerReq:    avgTimePerReq,
+		TotalToolTime:    totalToolTime,
+		TotalOverhead:    totalOverhead,
+		MatchPolicy:      evaluator.MatchPolicy(),
+		ToolAliases:      evaluator.ToolAliases(),
+		ReferenceTime:    evaluator.ReferenceTime(),
+
+		InfrastructureFailures: infrastructureFailures,
+
+		ApprovalCompliance: summarizeApproval(results),
+		SchemaCompliance:   SummarizeSchemaCompliance(results),
+		ManualOverrides:    collectManualOverrides(results),
+		RunStats:           calculateRunStatistics(results),
+		SweepResults:       summarizeSweep(results),
+
+		SystemPromptVariants: summarizeSystemPromptVariants(results),
+		Streaming:            summarizeReportStreaming(results),
+
+		TokenUsage: tokenUsage,
+
+		Scoring:    evaluator.ScoringMode(),
+		Evaluation: evaluator.EvaluationMode(),
+		Scorers:    evaluator.ScorerNames(),
+		MeanScore:  meanScore,
+
+		Judge: summarizeJudge(results),
+
+		CallEfficiency: SummarizeCallEfficiency(results),
 	}
+}
 
-	return report, nil
+// RebuildAgentReport replaces the results of a stored report with re-evaluated ones
+// and recomputes the aggregates and evaluator settings derived from them. Everything
+// else, such as the run's identity, timestamp, settings and completeness, is kept.
+func RebuildAgentReport(report *models.AgentReport, results []models.AgentTestResult, evaluator *Evaluator) *models.AgentReport {
+	built := BuildAgentReport(results, evaluator)
+	rebuilt := *report
+
+	rebuilt.SchemaVersion = built.SchemaVersion
+	rebuilt.Results = built.Results
+	rebuilt.TotalTests = built.TotalTests
+	rebuilt.PassedTests = built.PassedTests
+	rebuilt.FailedTests = built.FailedTests
+	rebuilt.AverageTime = built.AverageTime
+	rebuilt.TotalLLMRequests = built.TotalLLMRequests
+	rebuilt.TotalLLMTime = built.TotalLLMTime
+	rebuilt.AvgTimePerReq = built.AvgTimePerReq
+	rebuilt.TotalToolTime = built.TotalToolTime
+	rebuilt.TotalOverhead = built.TotalOverhead
+	rebuilt.MatchPolicy = built.MatchPolicy
+	rebuilt.ToolAliases = built.ToolAliases
+	rebuilt.ReferenceTime = built.ReferenceTime
+	rebuilt.InfrastructureFailures = built.InfrastructureFailures
+	rebuilt.ApprovalCompliance = built.ApprovalCompliance
+	rebuilt.SchemaCompliance = built.SchemaCompliance
+	rebuilt.ManualOverrides = built.ManualOverrides
+	rebuilt.RunStats = built.RunStats
+	rebuilt.SweepResults = built.SweepResults
+	rebuilt.SystemPromptVariants = built.SystemPromptVariants
+	rebuilt.Streaming = built.Streaming
+	rebuilt.TokenUsage = built.TokenUsage
+	rebuilt.Scoring = built.Scoring
+	rebuilt.Evaluation = built.Evaluation
+	rebuilt.Scorers = built.Scorers
+	rebuilt.MeanScore = built.MeanScore
+	rebuilt.Judge = built.Judge
+	rebuilt.CallEfficiency = built.CallEfficiency
+	return &rebuilt
+}
+
+// ToolDefinitions returns the tool definitions the model is offered
+func (tr *TestRunner) ToolDefinitions() []openai.ChatCompletionToolParam {
+	return tr.openaiService.ToolDefinitions()
+}
+
+// ValidateTestCases checks test cases against the canonical tool definitions,
+// returning one message per problem
+func (tr *TestRunner) ValidateTestCases(testCases []models.TestCase) []string {
+	return ValidateTestCases(testCases, tr.openaiService.shoppingTools.GetToolDefinitions(), tr.evaluator.ToolAliases())
 }
 
-// runAgentTest executes a single test case using the agent loop
-func (tr *TestRunner) runAgentTest(ctx context.Context, testCase models.TestCase) models.AgentTestResult {
+// EstimatePrompts estimates the first request size of each test case without
+// contacting the model
+func (tr *TestRunner) EstimatePrompts(testCases []models.TestCase) []models.PromptEstimate {
+	estimates := make([]models.PromptEstimate, 0, len(testCases))
+	for _, testCase := range testCases {
+		estimate := tr.openaiService.EstimatePrompt(testCase.Prompt, testCase.SystemPromptFor(tr.configs[0]))
+		estimate.TestCase = testCase.Name
+		estimates = append(estimates, estimate)
+	}
+	return estimates
+}
+
+// executeAgentTest executes a single test case using the agent loop with the given
+// sampling config
+func (tr *TestRunner) executeAgentTest(ctx context.Context, testCase models.TestCase, config models.TestConfig) models.AgentTestResult {
 	startTime := time.Now()
 
 	// Generate a unique session ID for this test
-	sessionID := fmt.Sprintf("test_%s_%d", testCase.Name, time.Now().UnixNano())
+	sessionID := fmt.Sprintf("test_%s_%d_%d", testCase.Name, time.Now().UnixNano(), sessionSequence.Add(1))
+	defer tr.openaiService.ReleaseSandbox(sessionID)
 
 	// Create a session for the test
 	session := &models.ChatSession{
@@ -132,121 +725,124 @@ func (tr *TestRunner) runAgentTest(ctx context.Context, testCase models.TestCase
 		err := tr.openaiService.InitializeCartForTest(sessionID, testCase.InitialCartState)
 		if err != nil {
 			return models.AgentTestResult{
-				TestCase:     testCase,
-				ModelName:    tr.getModelName(),
-				Success:      false,
-				ErrorMessage: fmt.Sprintf("Failed to initialize cart state: %v", err),
-				Timestamp:    time.Now(),
-				ResponseTime: time.Since(startTime),
+				TestCase:       testCase,
+				ModelName:      tr.getModelName(),
+				Config:         config,
+				Success:        false,
+				FailureReason:  models.FailureSetupError,
+				FailureDetails: fmt.Sprintf("Failed to initialize cart state: %v", err),
+				Timestamp:      time.Now(),
+				ResponseTime:   time.Since(startTime),
 			}
 		}
 	}
 
-	// Execute the test using the agent loop
-	response, err := tr.openaiService.ProcessChatMessage(ctx, testCase.Prompt, session, testCase.Name)
-	responseTime := time.Since(startTime)
-
+	// Execute the test using the agent loop. The test case's own system prompt takes
+	// precedence over the config's; the result keeps the config as it was scheduled.
+	requestConfig := config
+	requestConfig.SystemPrompt = testCase.SystemPromptFor(config)
+	var faults *NetworkFaultRecorder
+	if tr.networkChaos != nil {
+		ctx, faults = WithNetworkFaultRecorder(ctx)
+	}
+	response, err := tr.openaiService.ProcessChatMessage(ctx, testCase.Prompt, session, testCase.Name, requestConfig)
 	if err != nil {
+		reason := classifyRequestError(err)
 		return models.AgentTestResult{
-			TestCase:     testCase,
-			ModelName:    tr.getModelName(),
-			Success:      false,
-			ErrorMessage: err.Error(),
-			Timestamp:    time.Now(),
-			ResponseTime: responseTime,
+			TestCase:       testCase,
+			ModelName:      tr.getModelName(),
+			Config:         config,
+			Success:        false,
+			FailureReason:  reason,
+			FailureDetails: err.Error(),
+			Timestamp:      time.Now(),
+			ResponseTime:   time.Since(startTime),
+
+			InfrastructureFailure: reason.IsInfrastructure(),
+
+			Transcript:    session.Transcript,
+			NetworkFaults: faults.Faults(),
+			Gateway:       session.Gateway,
+			Requests:      session.Requests,
 		}
 	}
 
 	// Evaluate if the test was successful by checking tool calls
-	success, matchedPath := tr.evaluateAgentResponse(testCase, response)
+	evaluation := tr.evaluator.evaluateAgentResponse(testCase, response)
 
-	return models.AgentTestResult{
-		TestCase:     testCase,
-		ModelName:    tr.getModelName(),
-		Response:     response,
-		Success:      success,
-		MatchedPath:  matchedPath,
-		Timestamp:    time.Now(),
-		ResponseTime: responseTime,
-	}
-}
-
-// evaluateAgentResponse checks if the agent response matches expected tool calls
-func (tr *TestRunner) evaluateAgentResponse(testCase models.TestCase, response *models.ChatResponse) (bool, string) {
-	if len(testCase.ExpectedToolVariants) == 0 {
-		// No expected tools - success if no tools were called
-		return len(response.ToolCalls) == 0, "no_tools_expected"
+	// Response time covers the whole test, including evaluation; the breakdown
+	// separates the model's latency from time spent in the harness
+	responseTime := time.Since(startTime)
+	timing := &models.ResponseTiming{
+		LLMTime:         response.LLMTotalTime,
+		ToolTime:        response.ToolTime,
+		HarnessOverhead: responseTime - response.LLMTotalTime - response.ToolTime,
 	}
 
-	// Extract actual tool calls from response
-	actualTools := make([]models.ActualToolCall, len(response.ToolCalls))
-	for i, toolResult := range response.ToolCalls {
-		actualTools[i] = models.ActualToolCall{
-			Name:      toolResult.ToolName,
-			Arguments: tr.parseArguments(toolResult.Arguments),
-		}
+	result := models.AgentTestResult{
+		TestCase:       testCase,
+		ModelName:      tr.getModelName(),
+		Config:         config,
+		Response:       response,
+		Success:        evaluation.success,
+		MatchedPath:    evaluation.matchedPath,
+		FailureReason:  evaluation.failureReason,
+		FailureDetails: evaluation.failureDetails,
+		Timestamp:      time.Now(),
+		ResponseTime:   responseTime,
+		Timing:         timing,
+		Approval:       evaluateApproval(response),
+		Adjudication:   evaluation.adjudication,
+		PartialScore:   evaluation.partialScore,
+		CallEfficiency: evaluation.callEfficiency,
+
+		RecoveredToolErrors: evaluation.recoveredToolErrors,
+		Scorers:             evaluation.scorerVerdicts,
+
+		Transcript:    session.Transcript,
+		NetworkFaults: faults.Faults(),
+		Gateway:       session.Gateway,
+		Requests:      session.Requests,
 	}
 
-	// Check all variants to find a match
-	for _, variant := range testCase.ExpectedToolVariants {
-		if tr.isPathSuccessful(variant.Tools, actualTools) {
-			return true, variant.Name
-		}
+	// The judge's request is not part of the test's response time
+	if tr.judge != nil && testCase.Rubric != nil {
+		result.Judge = tr.judge.Score(ctx, testCase, session.Transcript, response.Message)
 	}
-
-	return false, ""
+	return result
 }
 
-// parseArguments parses the arguments string into a map
-func (tr *TestRunner) parseArguments(arguments string) map[string]interface{} {
-	var args map[string]interface{}
-	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
-		// If parsing fails, return the raw string
-		return map[string]interface{}{
-			"_raw_arguments": arguments,
-			"_parse_error":   err.Error(),
-		}
+// classifyRequestError maps an agent loop error to a failure reason. Timeouts, failed
+// connections and the statuses of an overloaded or unreachable server (408, 429, 502,
+// 503 and 504) fail against the server. Any other error response is the model's, such
+// as a 400 for a malformed tool call or a 500 for tools the backend does not support.
+func classifyRequestError(err error) models.FailureReason {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return models.FailureTimeout
 	}
-	return args
-}
 
-// isPathSuccessful checks if actual tool calls match a specific expected path
-func (tr *TestRunner) isPathSuccessful(expected []models.ExpectedToolCall, actual []models.ActualToolCall) bool {
-	// First check: exact count match
-	if len(actual) != len(expected) {
-		return false
+	var timeoutErr interface{ Timeout() bool }
+	if errors.As(err, &timeoutErr) && timeoutErr.Timeout() {
+		return models.FailureTimeout
 	}
 
-	// Second check: all expected tools must be called correctly in order
-	for i, expectedTool := range expected {
-		if i >= len(actual) || !tr.isToolCallCorrect(expectedTool, actual[i]) {
-			return false
+	var apiErr *openai.Error
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusRequestTimeout, http.StatusTooManyRequests, http.StatusBadGateway,
+			http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return models.FailureAPIError
 		}
+		return models.FailureModelError
 	}
 
-	return true
-}
-
-// isToolCallCorrect checks if an actual tool call matches an expected one
-func (tr *TestRunner) isToolCallCorrect(expected models.ExpectedToolCall, actual models.ActualToolCall) bool {
-	if expected.Name != actual.Name {
-		return false
+	// The request never got an answer: the connection failed, was cut off, or the
+	// run was interrupted
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, context.Canceled) {
+		return models.FailureAPIError
 	}
-
-	// Check if all expected arguments are present and correct
-	for key, expectedValue := range expected.Arguments {
-		actualValue, exists := actual.Arguments[key]
-		if !exists {
-			return false
-		}
-
-		// Simple equality check using case-insensitive comparison
-		if !strings.EqualFold(fmt.Sprintf("%v", expectedValue), fmt.Sprintf("%v", actualValue)) {
-			return false
-		}
-	}
-
-	return true
+	return models.FailureModelError
 }
 
 // getModelName returns the model name to use for test results
@@ -257,12 +853,82 @@ func (tr *TestRunner) getModelName() string {
 	return tr.defaultModel
 }
 
-// SaveResults saves test results to a JSON file
+// SaveResults saves test results to a JSON file, pruned by the runner's policy or,
+// when it has none, by the policy the report was last saved with, and signs it if the
+// runner has a signing key
 func (tr *TestRunner) SaveResults(filename string, report *models.AgentReport) error {
+	policy := tr.pruning
+	if !policy.Enabled() && report.Pruning != nil {
+		policy = *report.Pruning
+	}
+	if policy.Enabled() {
+		report = PruneReport(report, policy)
+	}
+	if err := SaveAgentReport(filename, report); err != nil {
+		return err
+	}
+	return SignFile(filename, tr.signingKey)
+}
+
+// SaveAgentReport writes a report to a JSON file
+func SaveAgentReport(filename string, report *models.AgentReport) error {
 	data, err := json.MarshalIndent(report, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal results: %w", err)
 	}
 
-	return os.WriteFile(filename, data, 0644)
+	return os.WriteFile(filename, RedactSecrets(data), 0644)
+}
+
+// LoadAgentReport reads a report written by SaveAgentReport
+func LoadAgentReport(filename string) (*models.AgentReport, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read results: %w", err)
+	}
+
+	var report models.AgentReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse results: %w", err)
+	}
+	if report.SchemaVersion > models.ResultSchemaVersion {
+		return nil, fmt.Errorf("result schema version %d is newer than supported version %d", report.SchemaVersion, models.ResultSchemaVersion)
+	}
+	return &report, nil
+}
+
+// resultFilePattern matches the names SaveAgentReport's callers give result files
+var resultFilePattern = regexp.MustCompile(`agent_test_results_.*\.json$`)
+
+// FindResultFiles expands paths into result files. Files are kept as given, in order;
+// directories are walked for files named like results.
+func FindResultFiles(paths []string) ([]string, error) {
+	var files []string
+
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && resultFilePattern.MatchString(d.Name()) {
+				files = append(files, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return files, nil
 }
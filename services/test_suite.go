@@ -0,0 +1,57 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"model-test/models"
+)
+
+// LoadTestSuite loads a test config file: either a JSON array of test cases or an
+// object with test_cases and the setup and teardown hooks run around the suite.
+// Suite and test case hooks are validated like those of a hooks file, tool aliases
+// must each name one tool, and scorers must each resolve to one scorer.
+func LoadTestSuite(filename string) (*models.TestSuite, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read test cases file: %w", err)
+	}
+
+	var suite models.TestSuite
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(data, &suite.TestCases)
+	} else {
+		err = json.Unmarshal(data, &suite)
+		if err == nil && suite.TestCases == nil {
+			err = fmt.Errorf("no test_cases in the test config")
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse test cases: %w", err)
+	}
+
+	if err := ValidateToolAliases(suite.ToolAliases); err != nil {
+		return nil, err
+	}
+	if err := ValidateScorers(suite.Scorers); err != nil {
+		return nil, err
+	}
+	if err := validateHooks(models.HookSuiteSetup, suite.Setup); err != nil {
+		return nil, err
+	}
+	if err := validateHooks(models.HookSuiteTeardown, suite.Teardown); err != nil {
+		return nil, err
+	}
+	for _, testCase := range suite.TestCases {
+		if err := validateHooks(models.HookTestSetup, testCase.Setup); err != nil {
+			return nil, fmt.Errorf("test case %s: %w", testCase.Name, err)
+		}
+		if err := validateHooks(models.HookTestTeardown, testCase.Teardown); err != nil {
+			return nil, fmt.Errorf("test case %s: %w", testCase.Name, err)
+		}
+	}
+
+	return &suite, nil
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"model-test/models"
+)
+
+// ValidateToolAliases checks that every alias is a non-empty name other than its
+// canonical name, belongs to one canonical tool only, and is not itself canonical
+func ValidateToolAliases(aliases models.ToolAliases) error {
+	owners := make(map[string]string)
+	for _, canonical := range sortedAliasKeys(aliases) {
+		if strings.TrimSpace(canonical) == "" {
+			return fmt.Errorf("tool_aliases has an empty canonical name")
+		}
+		for _, alias := range aliases[canonical] {
+			switch {
+			case strings.TrimSpace(alias) == "":
+				return fmt.Errorf("tool_aliases of %s has an empty name", canonical)
+			case alias == canonical:
+				return fmt.Errorf("tool_aliases of %s lists the tool itself", canonical)
+			case owners[alias] != "":
+				return fmt.Errorf("tool alias %s is listed for both %s and %s", alias, owners[alias], canonical)
+			}
+			if _, isCanonical := aliases[alias]; isCanonical {
+				return fmt.Errorf("tool alias %s of %s is also a canonical name", alias, canonical)
+			}
+			owners[alias] = canonical
+		}
+	}
+	return nil
+}
+
+// CanonicalizeToolNames returns the result with the tool names of its test case's
+// expected and forbidden calls and of its response's calls replaced by their canonical
+// names. The result's slices are copied, not modified.
+func CanonicalizeToolNames(result models.AgentTestResult, aliases models.ToolAliases) models.AgentTestResult {
+	if len(aliases) == 0 {
+		return result
+	}
+	result.TestCase = canonicalTestCase(result.TestCase, aliases)
+	if result.Response != nil {
+		response := *result.Response
+		response.ToolCalls = append([]models.ToolCallResult(nil), response.ToolCalls...)
+		for i := range response.ToolCalls {
+			response.ToolCalls[i].ToolName = aliases.Canonical(response.ToolCalls[i].ToolName)
+		}
+		result.Response = &response
+	}
+	return result
+}
+
+// canonicalTestCase returns the test case with its expected and forbidden tools named
+// by their canonical names, copying the slices it changes
+func canonicalTestCase(testCase models.TestCase, aliases models.ToolAliases) models.TestCase {
+	if len(aliases) == 0 {
+		return testCase
+	}
+	if testCase.ExpectedToolVariants != nil {
+		variants := make([]models.ExpectedToolPath, len(testCase.ExpectedToolVariants))
+		for i, variant := range testCase.ExpectedToolVariants {
+			variant.Tools = append([]models.ExpectedToolCall(nil), variant.Tools...)
+			for j := range variant.Tools {
+				variant.Tools[j].Name = aliases.Canonical(variant.Tools[j].Name)
+			}
+			variants[i] = variant
+		}
+		testCase.ExpectedToolVariants = variants
+	}
+
+	if testCase.ForbiddenTools != nil {
+		forbidden := make([]string, len(testCase.ForbiddenTools))
+		for i, name := range testCase.ForbiddenTools {
+			forbidden[i] = aliases.Canonical(name)
+		}
+		testCase.ForbiddenTools = forbidden
+	}
+	return testCase
+}
+
+// sortedAliasKeys returns the canonical names of the aliases in order, so validation
+// reports the same problem first on every run
+func sortedAliasKeys(aliases models.ToolAliases) []string {
+	names := make([]string, 0, len(aliases))
+	for name := range aliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
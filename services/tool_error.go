@@ -0,0 +1,60 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"model-test/models"
+)
+
+// toolErrorHints tells the model how it might recover from each kind of failure
+var toolErrorHints = map[models.ToolErrorCode]string{
+	models.ToolErrorInvalidArguments: "Check the arguments against the tool's parameters and call it again",
+	models.ToolErrorUnknownTool:      "Call one of the available tools instead",
+	models.ToolErrorExecutionFailed:  "Adjust the arguments and retry, or tell the user why the request cannot be completed",
+	models.ToolErrorPermissionDenied: "Ask the user how they would like to proceed",
+}
+
+// ParseToolErrorVerbosity validates a tool error verbosity name
+func ParseToolErrorVerbosity(value string) (models.ToolErrorVerbosity, error) {
+	switch verbosity := models.ToolErrorVerbosity(value); verbosity {
+	case models.ToolErrorMinimal, models.ToolErrorStandard, models.ToolErrorDetailed:
+		return verbosity, nil
+	}
+	return "", fmt.Errorf("unknown tool error verbosity '%s' (expected minimal, standard or detailed)", value)
+}
+
+// BuildToolMessage returns the tool message content sent to the model for a tool call
+// result: the result itself on success, or a structured error payload on failure
+func BuildToolMessage(result models.ToolCallResult, verbosity models.ToolErrorVerbosity) (string, error) {
+	if result.Success {
+		data, err := json.Marshal(result.Result)
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal tool result: %w", err)
+		}
+		return string(data), nil
+	}
+
+	code := result.ErrorCode
+	if code == "" {
+		code = models.ToolErrorExecutionFailed
+	}
+
+	details := models.ToolErrorDetails{Code: code}
+	switch verbosity {
+	case models.ToolErrorMinimal:
+	case models.ToolErrorDetailed:
+		details.Message = result.Error
+		details.Tool = result.ToolName
+		details.Arguments = result.Arguments
+		details.Hint = toolErrorHints[code]
+	default:
+		details.Message = result.Error
+	}
+
+	data, err := json.Marshal(models.ToolErrorPayload{Error: details})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal tool error: %w", err)
+	}
+	return string(data), nil
+}
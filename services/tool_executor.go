@@ -52,12 +52,17 @@ func (te *ToolExecutor) executeToolCall(ctx context.Context, toolCall openai.Cha
 		return te.handleViewCart(sessionID, toolCallID)
 	case "checkout":
 		return te.handleCheckout(sessionID, toolCallID)
+	case "create_order":
+		return te.handleCreateOrder(arguments, sessionID, toolCallID)
+	case "schedule_delivery":
+		return te.handleScheduleDelivery(arguments, sessionID, toolCallID)
 	default:
 		return models.ToolCallResult{
 			CallID:    toolCallID,
 			ToolName:  functionName,
 			Success:   false,
 			Error:     fmt.Sprintf("Unknown tool: %s", functionName),
+			ErrorCode: models.ToolErrorUnknownTool,
 			Arguments: arguments,
 		}
 	}
@@ -68,6 +73,7 @@ func (te *ToolExecutor) handleSearchProducts(arguments string, toolCallID string
 	var args struct {
 		Query    string `json:"query"`
 		Category string `json:"category"`
+		SortBy   string `json:"sort_by"`
 		Limit    int    `json:"limit"`
 	}
 
@@ -77,6 +83,7 @@ func (te *ToolExecutor) handleSearchProducts(arguments string, toolCallID string
 			ToolName:  "search_products",
 			Success:   false,
 			Error:     "Invalid arguments",
+			ErrorCode: models.ToolErrorInvalidArguments,
 			Arguments: arguments,
 		}
 	}
@@ -88,6 +95,7 @@ func (te *ToolExecutor) handleSearchProducts(arguments string, toolCallID string
 	filter := models.ProductFilter{
 		Query:    args.Query,
 		Category: args.Category,
+		SortBy:   args.SortBy,
 		Limit:    args.Limit,
 	}
 
@@ -98,6 +106,7 @@ func (te *ToolExecutor) handleSearchProducts(arguments string, toolCallID string
 			ToolName:  "search_products",
 			Success:   false,
 			Error:     err.Error(),
+			ErrorCode: models.ToolErrorExecutionFailed,
 			Arguments: arguments,
 		}
 	}
@@ -124,6 +133,7 @@ func (te *ToolExecutor) handleAddToCart(arguments string, sessionID string, tool
 			ToolName:  "add_to_cart",
 			Success:   false,
 			Error:     "Invalid arguments",
+			ErrorCode: models.ToolErrorInvalidArguments,
 			Arguments: arguments,
 		}
 	}
@@ -139,6 +149,7 @@ func (te *ToolExecutor) handleAddToCart(arguments string, sessionID string, tool
 			ToolName:  "add_to_cart",
 			Success:   false,
 			Error:     err.Error(),
+			ErrorCode: models.ToolErrorExecutionFailed,
 			Arguments: arguments,
 		}
 	}
@@ -164,6 +175,7 @@ func (te *ToolExecutor) handleRemoveFromCart(arguments string, sessionID string,
 			ToolName:  "remove_from_cart",
 			Success:   false,
 			Error:     "Invalid arguments",
+			ErrorCode: models.ToolErrorInvalidArguments,
 			Arguments: arguments,
 		}
 	}
@@ -175,6 +187,7 @@ func (te *ToolExecutor) handleRemoveFromCart(arguments string, sessionID string,
 			ToolName:  "remove_from_cart",
 			Success:   false,
 			Error:     err.Error(),
+			ErrorCode: models.ToolErrorExecutionFailed,
 			Arguments: arguments,
 		}
 	}
@@ -209,6 +222,7 @@ func (te *ToolExecutor) handleCheckout(sessionID string, toolCallID string) mode
 			ToolName:  "checkout",
 			Success:   false,
 			Error:     err.Error(),
+			ErrorCode: models.ToolErrorExecutionFailed,
 			Arguments: "{}",
 		}
 	}
@@ -221,3 +235,81 @@ func (te *ToolExecutor) handleCheckout(sessionID string, toolCallID string) mode
 		Arguments: "{}",
 	}
 }
+
+// handleCreateOrder handles direct order tool calls with nested item and address arguments
+func (te *ToolExecutor) handleCreateOrder(arguments string, sessionID string, toolCallID string) models.ToolCallResult {
+	var args struct {
+		Items           []models.OrderItem      `json:"items"`
+		ShippingAddress *models.ShippingAddress `json:"shipping_address"`
+	}
+
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+		return models.ToolCallResult{
+			CallID:    toolCallID,
+			ToolName:  "create_order",
+			Success:   false,
+			Error:     "Invalid arguments",
+			ErrorCode: models.ToolErrorInvalidArguments,
+			Arguments: arguments,
+		}
+	}
+
+	orderResult, err := te.cartService.CreateOrder(sessionID, args.Items, args.ShippingAddress)
+	if err != nil {
+		return models.ToolCallResult{
+			CallID:    toolCallID,
+			ToolName:  "create_order",
+			Success:   false,
+			Error:     err.Error(),
+			ErrorCode: models.ToolErrorExecutionFailed,
+			Arguments: arguments,
+		}
+	}
+
+	return models.ToolCallResult{
+		CallID:    toolCallID,
+		ToolName:  "create_order",
+		Success:   true,
+		Result:    orderResult,
+		Arguments: arguments,
+	}
+}
+
+// handleScheduleDelivery handles delivery scheduling tool calls with date arguments
+func (te *ToolExecutor) handleScheduleDelivery(arguments string, sessionID string, toolCallID string) models.ToolCallResult {
+	var args struct {
+		DeliveryDate string `json:"delivery_date"`
+		TimeWindow   string `json:"time_window"`
+	}
+
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+		return models.ToolCallResult{
+			CallID:    toolCallID,
+			ToolName:  "schedule_delivery",
+			Success:   false,
+			Error:     "Invalid arguments",
+			ErrorCode: models.ToolErrorInvalidArguments,
+			Arguments: arguments,
+		}
+	}
+
+	schedule, err := te.cartService.ScheduleDelivery(sessionID, args.DeliveryDate, args.TimeWindow)
+	if err != nil {
+		return models.ToolCallResult{
+			CallID:    toolCallID,
+			ToolName:  "schedule_delivery",
+			Success:   false,
+			Error:     err.Error(),
+			ErrorCode: models.ToolErrorExecutionFailed,
+			Arguments: arguments,
+		}
+	}
+
+	return models.ToolCallResult{
+		CallID:    toolCallID,
+		ToolName:  "schedule_delivery",
+		Success:   true,
+		Result:    schedule,
+		Arguments: arguments,
+	}
+}
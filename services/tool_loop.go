@@ -0,0 +1,42 @@
+package services
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/openai/openai-go"
+)
+
+// repeatsToolCalls reports whether a turn requested exactly the tool calls of the turn
+// before it, ignoring their order and the formatting of their arguments. A model stuck
+// like this rarely recovers, so the loop stops instead of spending its remaining
+// iterations.
+func repeatsToolCalls(previous, current []openai.ChatCompletionMessageToolCall) bool {
+	if len(previous) == 0 || len(previous) != len(current) {
+		return false
+	}
+	previousKeys, currentKeys := toolCallKeys(previous), toolCallKeys(current)
+	for i := range previousKeys {
+		if previousKeys[i] != currentKeys[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// toolCallKeys identifies each call by its tool and canonical arguments, sorted
+func toolCallKeys(toolCalls []openai.ChatCompletionMessageToolCall) []string {
+	keys := make([]string, len(toolCalls))
+	for i, toolCall := range toolCalls {
+		arguments := toolCall.Function.Arguments
+		var parsed interface{}
+		if err := json.Unmarshal([]byte(arguments), &parsed); err == nil {
+			if canonical, err := json.Marshal(parsed); err == nil {
+				arguments = string(canonical)
+			}
+		}
+		keys[i] = toolCall.Function.Name + "\x00" + arguments
+	}
+	sort.Strings(keys)
+	return keys
+}
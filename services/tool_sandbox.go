@@ -0,0 +1,69 @@
+package services
+
+import (
+	"fmt"
+
+	"model-test/models"
+)
+
+// ToolSandbox is the set of services one test's tool calls run against
+type ToolSandbox struct {
+	carts    *CartService
+	executor *ToolExecutor
+}
+
+// NewToolSandbox creates a sandbox with fresh product and cart services
+func NewToolSandbox() *ToolSandbox {
+	carts := NewCartService()
+	return &ToolSandbox{
+		carts:    carts,
+		executor: NewToolExecutor(NewProductService(), carts),
+	}
+}
+
+// ParseServiceState validates a service state name
+func ParseServiceState(value string) (models.ServiceState, error) {
+	switch state := models.ServiceState(value); state {
+	case models.ServiceStateIsolated, models.ServiceStateShared:
+		return state, nil
+	}
+	return "", fmt.Errorf("unknown service state '%s' (expected isolated or shared)", value)
+}
+
+// SetServiceState sets whether each test session gets its own sandbox or all
+// sessions share one
+func (ai *OpenAIService) SetServiceState(state models.ServiceState) {
+	ai.sandboxMutex.Lock()
+	defer ai.sandboxMutex.Unlock()
+
+	ai.serviceState = state
+	ai.sandboxes = make(map[string]*ToolSandbox)
+	ai.shared = nil
+	if state == models.ServiceStateShared {
+		ai.shared = NewToolSandbox()
+	}
+}
+
+// sandbox returns the session's sandbox, creating it on first use
+func (ai *OpenAIService) sandbox(sessionID string) *ToolSandbox {
+	ai.sandboxMutex.Lock()
+	defer ai.sandboxMutex.Unlock()
+
+	if ai.shared != nil {
+		return ai.shared
+	}
+	sandbox, exists := ai.sandboxes[sessionID]
+	if !exists {
+		sandbox = NewToolSandbox()
+		ai.sandboxes[sessionID] = sandbox
+	}
+	return sandbox
+}
+
+// ReleaseSandbox drops a finished session's sandbox; the shared sandbox is kept
+func (ai *OpenAIService) ReleaseSandbox(sessionID string) {
+	ai.sandboxMutex.Lock()
+	defer ai.sandboxMutex.Unlock()
+
+	delete(ai.sandboxes, sessionID)
+}
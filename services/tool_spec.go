@@ -0,0 +1,163 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"model-test/models"
+	"model-test/tools"
+)
+
+// LoadToolSpecVersions reads alternative tool description wordings and checks each
+// one against the canonical tool definitions
+func LoadToolSpecVersions(path string) ([]models.ToolSpecVersion, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tool spec versions: %w", err)
+	}
+
+	var versions []models.ToolSpecVersion
+	if err := json.Unmarshal(data, &versions); err != nil {
+		return nil, fmt.Errorf("failed to parse tool spec versions: %w", err)
+	}
+
+	shoppingTools := tools.NewShoppingTools()
+	names := make(map[string]bool)
+	for i := range versions {
+		version := &versions[i]
+		if version.Name == "" {
+			return nil, fmt.Errorf("tool spec version %d has no name", i+1)
+		}
+		if names[version.Name] {
+			return nil, fmt.Errorf("tool spec version '%s' is defined more than once", version.Name)
+		}
+		names[version.Name] = true
+
+		if _, _, err := shoppingTools.GetToolDefinitionsForVersion(version); err != nil {
+			return nil, err
+		}
+	}
+
+	return versions, nil
+}
+
+// CompareToolSpecVersion compares a run using a tool spec version with the canonical
+// run over the test cases present in both, so accuracy changes can be attributed to
+// the tool wording
+func CompareToolSpecVersion(canonical, variant *models.AgentReport) models.ToolSpecVersionResult {
+	result := models.ToolSpecVersionResult{
+		Version:       variant.ToolSpecVersion,
+		Locale:        localeOrDefault(variant.ToolLocale),
+		FailureDeltas: make(map[models.FailureReason]int),
+	}
+
+	canonicalResults := make(map[string]models.AgentTestResult)
+	for _, testResult := range canonical.Results {
+		canonicalResults[ResultKey(testResult)] = testResult
+	}
+
+	canonicalPassed := 0
+	for _, testResult := range variant.Results {
+		previous, exists := canonicalResults[ResultKey(testResult)]
+		if !exists {
+			continue
+		}
+		result.TotalTests++
+		if testResult.Success {
+			result.PassedTests++
+		} else {
+			result.FailureDeltas[testResult.FailureReason]++
+		}
+		if previous.Success {
+			canonicalPassed++
+		} else {
+			result.FailureDeltas[previous.FailureReason]--
+		}
+
+		if previous.Success && !testResult.Success {
+			result.NewlyFailing = append(result.NewlyFailing, ResultKey(testResult))
+		} else if !previous.Success && testResult.Success {
+			result.NewlyPassing = append(result.NewlyPassing, ResultKey(testResult))
+		}
+	}
+	sort.Strings(result.NewlyFailing)
+	sort.Strings(result.NewlyPassing)
+
+	for reason, delta := range result.FailureDeltas {
+		if delta == 0 {
+			delete(result.FailureDeltas, reason)
+		}
+	}
+
+	if result.TotalTests > 0 {
+		result.SuccessRate = float64(result.PassedTests) / float64(result.TotalTests)
+		result.SuccessRateDelta = result.SuccessRate - float64(canonicalPassed)/float64(result.TotalTests)
+	}
+
+	return result
+}
+
+// CalculateLocaleAccuracy groups the results of the reports by prompt locale and tool
+// description locale, so tool-selection accuracy can be correlated with both
+func CalculateLocaleAccuracy(reports []*models.AgentReport) []models.LocaleAccuracy {
+	type localePair struct{ prompt, tools string }
+	accuracy := make(map[localePair]*models.LocaleAccuracy)
+
+	for _, report := range reports {
+		toolLocale := localeOrDefault(report.ToolLocale)
+		for _, result := range report.Results {
+			pair := localePair{localeOrDefault(result.TestCase.Locale), toolLocale}
+			entry, exists := accuracy[pair]
+			if !exists {
+				entry = &models.LocaleAccuracy{PromptLocale: pair.prompt, ToolLocale: pair.tools}
+				accuracy[pair] = entry
+			}
+
+			entry.TotalTests++
+			if result.Success {
+				entry.PassedTests++
+			}
+			if selectedExpectedTools(result) {
+				entry.CorrectSelection++
+			}
+		}
+	}
+
+	var results []models.LocaleAccuracy
+	for _, entry := range accuracy {
+		entry.SelectionAccuracy = float64(entry.CorrectSelection) / float64(entry.TotalTests)
+		entry.SuccessRate = float64(entry.PassedTests) / float64(entry.TotalTests)
+		results = append(results, *entry)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].PromptLocale != results[j].PromptLocale {
+			return results[i].PromptLocale < results[j].PromptLocale
+		}
+		return results[i].ToolLocale < results[j].ToolLocale
+	})
+
+	return results
+}
+
+// selectedExpectedTools reports whether a test called the expected tools, counting
+// tests that only failed on argument values or on a tool's execution as correct selections
+func selectedExpectedTools(result models.AgentTestResult) bool {
+	if result.Success {
+		return true
+	}
+	switch result.FailureReason {
+	case models.FailureBadArguments, models.FailureSchemaViolation, models.FailureResponseMismatch, models.FailureToolFailed:
+		return true
+	}
+	return false
+}
+
+// localeOrDefault returns the locale, or DefaultLocale when none was declared
+func localeOrDefault(locale string) string {
+	if locale == "" {
+		return models.DefaultLocale
+	}
+	return locale
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// UnitDimension groups units that measure the same quantity (mass, volume, ...).
+// Factors convert a value in the unit to the dimension's base unit.
+type UnitDimension struct {
+	Base    string             `json:"base"`
+	Factors map[string]float64 `json:"factors"`
+}
+
+// UnitTable holds the conversion tables used to normalize quantities with units
+type UnitTable struct {
+	Dimensions map[string]UnitDimension `json:"dimensions"`
+}
+
+// quantityPattern matches values such as "2 kg", "2kg", "1.5 L" or "-3 oz"
+var quantityPattern = regexp.MustCompile(`^\s*(-?[0-9]+(?:\.[0-9]+)?)\s*([A-Za-z]+)\s*$`)
+
+// defaultUnits holds the built-in conversion tables for mass, volume and length
+//
+//go:embed units.json
+var defaultUnits []byte
+
+// DefaultUnitTable returns the built-in conversion table for mass, volume and length
+func DefaultUnitTable() *UnitTable {
+	var table UnitTable
+	if err := json.Unmarshal(defaultUnits, &table); err != nil {
+		panic(fmt.Sprintf("invalid built-in unit table: %v", err))
+	}
+	return &table
+}
+
+// LoadUnitTable loads conversion tables from a JSON file in the format of the built-in
+// units.json, replacing the built-in tables. An empty path returns the built-in ones.
+func LoadUnitTable(path string) (*UnitTable, error) {
+	if path == "" {
+		return DefaultUnitTable(), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read unit table: %w", err)
+	}
+
+	var table UnitTable
+	if err := json.Unmarshal(data, &table); err != nil {
+		return nil, fmt.Errorf("failed to parse unit table: %w", err)
+	}
+
+	return &table, nil
+}
+
+// Equal compares two values as quantities with units. The second return value is
+// false when either side is not a recognizable quantity, in which case the caller
+// should fall back to its regular comparison.
+func (ut *UnitTable) Equal(expected, actual interface{}) (bool, bool) {
+	expectedValue, expectedDim, ok := ut.normalize(expected)
+	if !ok {
+		return false, false
+	}
+	actualValue, actualDim, ok := ut.normalize(actual)
+	if !ok {
+		return false, false
+	}
+
+	if expectedDim != actualDim {
+		return false, true
+	}
+
+	tolerance := 1e-6 * math.Max(math.Abs(expectedValue), 1)
+	return math.Abs(expectedValue-actualValue) <= tolerance, true
+}
+
+// normalize converts a quantity to its dimension's base unit. Quantities may be
+// strings ("2 kg") or objects with "value" and "unit" keys.
+func (ut *UnitTable) normalize(value interface{}) (float64, string, bool) {
+	var amount float64
+	var unit string
+
+	switch v := value.(type) {
+	case string:
+		matches := quantityPattern.FindStringSubmatch(v)
+		if matches == nil {
+			return 0, "", false
+		}
+		parsed, err := strconv.ParseFloat(matches[1], 64)
+		if err != nil {
+			return 0, "", false
+		}
+		amount, unit = parsed, matches[2]
+	case map[string]interface{}:
+		number, ok := v["value"].(float64)
+		if !ok {
+			return 0, "", false
+		}
+		unitName, ok := v["unit"].(string)
+		if !ok {
+			return 0, "", false
+		}
+		amount, unit = number, unitName
+	default:
+		return 0, "", false
+	}
+
+	unit = strings.ToLower(strings.TrimSpace(unit))
+	for name, dimension := range ut.Dimensions {
+		if factor, exists := dimension.Factors[unit]; exists {
+			return amount * factor, name, true
+		}
+	}
+
+	return 0, "", false
+}
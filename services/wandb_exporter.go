@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"model-test/models"
+)
+
+// wandbUpsertRunMutation creates (or updates) a run with its config and summary metrics
+const wandbUpsertRunMutation = `mutation UpsertBucket($name: String, $project: String, $entity: String, $displayName: String, $config: JSONString, $summaryMetrics: JSONString, $tags: [String!], $jobType: String) {
+  upsertBucket(input: {name: $name, modelName: $project, entityName: $entity, displayName: $displayName, config: $config, summaryMetrics: $summaryMetrics, tags: $tags, jobType: $jobType}) {
+    bucket { id name }
+  }
+}`
+
+// WandbExporter logs runs to Weights & Biases through its GraphQL API
+type WandbExporter struct {
+	baseURL string
+	apiKey  string
+	entity  string
+	project string
+	client  *http.Client
+}
+
+// NewWandbExporter creates an exporter for the given W&B project. An empty entity
+// uses the API key's default entity.
+func NewWandbExporter(baseURL, apiKey, entity, project string) *WandbExporter {
+	if baseURL == "" {
+		baseURL = "https://api.wandb.ai"
+	}
+
+	return &WandbExporter{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		apiKey:  apiKey,
+		entity:  entity,
+		project: project,
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Name returns the exporter's display name
+func (w *WandbExporter) Name() string {
+	return "Weights & Biases"
+}
+
+// Export creates a W&B run holding the report's parameters as config and its
+// metrics as the run summary
+func (w *WandbExporter) Export(ctx context.Context, report *models.AgentReport, params map[string]string) error {
+	if w.apiKey == "" {
+		return fmt.Errorf("no W&B API key (set WANDB_API_KEY)")
+	}
+
+	runName := strings.ToLower(report.RunID)
+	if runName == "" {
+		runID, err := NewRunID()
+		if err != nil {
+			return err
+		}
+		runName = strings.ToLower(runID)
+	}
+
+	// W&B stores config entries as {"value": ...} objects
+	config := make(map[string]interface{})
+	for key, value := range ReportParams(report, params) {
+		config[key] = map[string]interface{}{"value": value}
+	}
+	configJSON, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	summaryJSON, err := json.Marshal(ReportMetrics(report))
+	if err != nil {
+		return fmt.Errorf("failed to marshal metrics: %w", err)
+	}
+
+	tags := []string{}
+	for _, key := range sortedKeys(report.Tags) {
+		tags = append(tags, key+"="+report.Tags[key])
+	}
+
+	variables := map[string]interface{}{
+		"name":           runName,
+		"project":        w.project,
+		"displayName":    fmt.Sprintf("%s-%s", params["model"], report.RunID),
+		"config":         string(configJSON),
+		"summaryMetrics": string(summaryJSON),
+		"tags":           tags,
+		"jobType":        "benchmark",
+	}
+	if w.entity != "" {
+		variables["entity"] = w.entity
+	}
+
+	var response struct {
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	headers := map[string]string{
+		"Authorization": "Basic " + base64.StdEncoding.EncodeToString([]byte("api:"+w.apiKey)),
+	}
+	err = sendJSON(ctx, w.client, http.MethodPost, w.baseURL+"/graphql", headers, map[string]interface{}{
+		"query":     wandbUpsertRunMutation,
+		"variables": variables,
+	}, &response)
+	if err != nil {
+		return fmt.Errorf("failed to create run: %w", err)
+	}
+	if len(response.Errors) > 0 {
+		return fmt.Errorf("failed to create run: %s", response.Errors[0].Message)
+	}
+
+	return nil
+}
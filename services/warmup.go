@@ -0,0 +1,51 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/openai/openai-go"
+
+	"model-test/models"
+)
+
+// warmupMaxTokens bounds each warm-up completion; only the prompt needs processing
+const warmupMaxTokens = 16
+
+// WarmUp sends throwaway completions with the suite's system prompt and tools before
+// timing begins, so a local deployment loads the model and processes the shared
+// prompt prefix outside of any test's ResponseTime. Requests are not logged but do
+// count against the rate limiter. It stops at the first failure.
+func (ai *OpenAIService) WarmUp(ctx context.Context, requests int, timeout time.Duration) *models.Warmup {
+	warmup := &models.Warmup{Requests: requests}
+	messages := ai.buildMessagesFromSession(nil, "Hello", "")
+	params := openai.ChatCompletionNewParams{
+		Model:     ai.defaultModel,
+		Messages:  messages,
+		Tools:     ai.getToolDefinitions(),
+		MaxTokens: openai.Int(warmupMaxTokens),
+	}
+	estimatedTokens := estimatePromptTokens(messages) + warmupMaxTokens
+
+	for i := 0; i < requests; i++ {
+		if err := ai.limiter.Wait(ctx, estimatedTokens); err != nil {
+			warmup.Error = err.Error()
+			return warmup
+		}
+
+		requestCtx, cancel := context.WithTimeout(ctx, timeout)
+		client, _, _ := ai.endpoint()
+		start := time.Now()
+		completion, err := client.Chat.Completions.New(requestCtx, params)
+		cancel()
+		if err != nil {
+			warmup.Error = fmt.Sprintf("warm-up request %d failed: %v", i+1, err)
+			return warmup
+		}
+		ai.limiter.Record(estimatedTokens, completion.Usage.TotalTokens)
+		warmup.Latencies = append(warmup.Latencies, time.Since(start))
+	}
+
+	return warmup
+}
@@ -0,0 +1,136 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"model-test/models"
+)
+
+// workflowCheck is how a response's tool calls worked out under the workflow
+// evaluation mode
+type workflowCheck struct {
+	response  *models.ChatResponse // The response without the failed calls the model recovered from
+	recovered int                  // Failed calls followed by a successful call to the same tool
+	repeated  string               // Details of a failed call the model repeated unchanged; empty if none
+}
+
+// ParseEvaluationMode validates an evaluation mode name
+func ParseEvaluationMode(value string) (models.EvaluationMode, error) {
+	switch mode := models.EvaluationMode(value); mode {
+	case models.EvaluationCalls, models.EvaluationWorkflow:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown evaluation mode '%s' (expected calls or workflow)", value)
+	}
+}
+
+// checkWorkflow finds the failed tool calls the model recovered from by calling the
+// same tool again successfully, and failed calls it repeated with the same arguments
+// only to fail the same way. Denied calls are left to the approval check.
+func (ev *Evaluator) checkWorkflow(response *models.ChatResponse) workflowCheck {
+	calls := response.ToolCalls
+	check := workflowCheck{response: response}
+
+	recovered := make([]bool, len(calls))
+	for i, call := range calls {
+		if call.Success || call.Denied {
+			continue
+		}
+		name := ev.toolAliases.Canonical(call.ToolName)
+		for _, later := range calls[i+1:] {
+			if ev.toolAliases.Canonical(later.ToolName) != name {
+				continue
+			}
+			if later.Success {
+				recovered[i] = true
+				check.recovered++
+				break
+			}
+			if check.repeated == "" && !later.Denied && later.ErrorCode == call.ErrorCode && sameArguments(call.Arguments, later.Arguments) {
+				check.repeated = fmt.Sprintf("repeated %s with the arguments that failed (%s)", call.ToolName, describeToolError(call))
+			}
+		}
+	}
+
+	if check.recovered > 0 {
+		filtered := *response
+		filtered.ToolCalls = nil
+		for i, call := range calls {
+			if !recovered[i] {
+				filtered.ToolCalls = append(filtered.ToolCalls, call)
+			}
+		}
+		check.response = &filtered
+	}
+	return check
+}
+
+// applyWorkflow fails a response that matched an expected path when one of its calls
+// failed without being recovered from, unless the matched path allows that error,
+// and explains mismatches caused by repeating a failed call unchanged
+func (ev *Evaluator) applyWorkflow(result *evaluation, testCase models.TestCase, check workflowCheck) {
+	if !result.success {
+		switch result.failureReason {
+		case models.FailureWrongTool, models.FailureMissingTool, models.FailureExtraTool, models.FailureBadArguments,
+			models.FailureMaxIterations, models.FailureLoopDetected:
+			if check.repeated != "" {
+				result.failureReason = models.FailureToolErrorRepeated
+				result.failureDetails = check.repeated
+			}
+		}
+		return
+	}
+
+	// Each allowed error excuses one failed call to its tool
+	allowed := make(map[string]int)
+	for _, variant := range canonicalTestCase(testCase, ev.toolAliases).ExpectedToolVariants {
+		if variant.Name != result.matchedPath {
+			continue
+		}
+		for _, tool := range variant.Tools {
+			for _, code := range tool.AllowedErrors {
+				allowed[tool.Name+"/"+string(code)]++
+			}
+		}
+	}
+
+	for _, call := range check.response.ToolCalls {
+		if call.Success || call.Denied {
+			continue
+		}
+		key := ev.toolAliases.Canonical(call.ToolName) + "/" + string(call.ErrorCode)
+		if allowed[key] > 0 {
+			allowed[key]--
+			continue
+		}
+		result.success = false
+		result.failureReason = models.FailureToolFailed
+		result.failureDetails = fmt.Sprintf("%s failed (%s) and was not retried successfully", call.ToolName, describeToolError(call))
+		return
+	}
+}
+
+// describeToolError renders a failed call's error code and message
+func describeToolError(call models.ToolCallResult) string {
+	switch {
+	case call.ErrorCode == "":
+		return call.Error
+	case call.Error == "":
+		return string(call.ErrorCode)
+	default:
+		return fmt.Sprintf("%s: %s", call.ErrorCode, call.Error)
+	}
+}
+
+// sameArguments reports whether two calls passed the same arguments, ignoring JSON
+// formatting
+func sameArguments(a, b string) bool {
+	var aValue, bValue interface{}
+	if json.Unmarshal([]byte(a), &aValue) != nil || json.Unmarshal([]byte(b), &bValue) != nil {
+		return a == b
+	}
+	aJSON, _ := json.Marshal(aValue)
+	bJSON, _ := json.Marshal(bValue)
+	return string(aJSON) == string(bJSON)
+}
@@ -16,10 +16,10 @@ get_provider_config() {
             echo "base_url:http://localhost:13434/engines/v1,api_key:DMR,api_endpoint:http://localhost:13434/engines/v1/models"
             ;;
         "openai")
-            echo "base_url:https://api.openai.com/v1,api_key:${OPENAI_API_KEY:-},api_endpoint:"
+            echo "base_url:https://api.openai.com/v1,api_key:${OPENAI_API_KEY:-},rpm:${OPENAI_RPM:-},tpm:${OPENAI_TPM:-},api_endpoint:"
             ;;
         "anthropic")
-            echo "base_url:https://api.anthropic.com/v1,api_key:${ANTHROPIC_API_KEY:-},api_endpoint:"
+            echo "base_url:https://api.anthropic.com/v1,api_key:${ANTHROPIC_API_KEY:-},rpm:${ANTHROPIC_RPM:-},tpm:${ANTHROPIC_TPM:-},api_endpoint:"
             ;;
         "kamiwaza")
             echo "base_url:${KAMIWAZA_BASE_URL:-https://localhost},api_key:kamiwaza,api_endpoint:${KAMIWAZA_BASE_URL:-https://localhost}/api/serving/deployments"
@@ -50,6 +50,15 @@ MODELS_OVERRIDE=""
 PROVIDERS_OVERRIDE=""
 VERBOSE=false
 DRY_RUN=false
+SKIP_UNHEALTHY=false
+RERUN_INFRA=false
+WARMUP="${WARMUP:-0}"
+TAGS=()
+TELEMETRY_HOOK="${TELEMETRY_HOOK:-}"
+RATE_LIMIT_RPM="${RATE_LIMIT_RPM:-}"
+RATE_LIMIT_TPM="${RATE_LIMIT_TPM:-}"
+HOOKS_FILE="${HOOKS_FILE:-}"
+TELEMETRY_INTERVAL="${TELEMETRY_INTERVAL:-10s}"
 
 # Colors for output
 RED='\033[0;31m'
@@ -95,10 +104,17 @@ OPTIONS:
     -p, --providers PROVIDERS Comma-separated list of providers to test (e.g., "ollama,dmr")
     -m, --models MODELS     Comma-separated list of models to test (overrides auto-discovery)
     -r, --runs NUMBER       Number of test runs per model (default: 10)
-    -t, --test-case NAME    Run only specific test case
+    -t, --test-case NAMES   Run only these test cases (comma-separated names or glob patterns, e.g. "checkout_*")
     -c, --config FILE       Path to test cases config file (default: $DEFAULT_CONFIG)
     -u, --base-url URL      API base URL (default: $DEFAULT_BASE_URL)
     -k, --api-key KEY       API key (default: $DEFAULT_API_KEY)
+    -g, --tag KEY=VALUE     Attach metadata to every run and the manifest (repeatable)
+    -H, --telemetry-hook CMD Command run periodically during each run; its JSON output is saved on the run's timeline
+    -i, --telemetry-interval DURATION How often to run the telemetry hook (default: 10s)
+    -x, --hooks FILE        Pre/post hooks run around each model and each run (see config/hooks.example.json)
+    -s, --skip-unhealthy    Skip models whose endpoint fails the health check run before the batch
+    -R, --rerun-infra-failures Rerun each run's tests that failed against the server (API errors, timeouts) and merge them into its results
+    -w, --warmup NUMBER     Throwaway completions sent before each run's tests are timed (default: 0)
 
 ENVIRONMENT VARIABLES:
     BASE_URL               API base URL
@@ -109,6 +125,14 @@ ENVIRONMENT VARIABLES:
     KAMIWAZA_BASE_URL      Kamiwaza base URL (default: https://localhost)
     KAMIWAZA_USERNAME      Kamiwaza username (default: admin)
     KAMIWAZA_PASSWORD      Kamiwaza password (default: kamiwaza)
+    TELEMETRY_HOOK         Telemetry hook command
+    TELEMETRY_INTERVAL     Telemetry hook interval (default: 10s)
+    HOOKS_FILE             Pre/post hooks file
+    WARMUP                 Warm-up requests per run (default: 0)
+    RATE_LIMIT_RPM         Requests per minute allowed against --base-url (default: unlimited)
+    RATE_LIMIT_TPM         Tokens per minute allowed against --base-url (default: unlimited)
+    OPENAI_RPM, OPENAI_TPM Requests/tokens per minute for the openai provider (default: unlimited)
+    ANTHROPIC_RPM, ANTHROPIC_TPM Requests/tokens per minute for the anthropic provider (default: unlimited)
 
 EXAMPLES:
     $0                                          # Test all discovered models (10 runs each)
@@ -121,7 +145,12 @@ EXAMPLES:
     $0 -p "ollama" -t "simple_add_iphone"      # Test ollama models with specific test case
     $0 -v -n                                   # Dry run with verbose output
     $0 -u "http://localhost:8080/v1"           # Custom API endpoint
+    $0 -m "llama3" -g gpu=a100 -g quant=q4_k_m # Tag runs for grouping in analyze-batch
+    $0 -m "llama3" -H ./gpu_stats.sh -i 5s     # Record GPU telemetry every 5 seconds
+    $0 -m "llama3" -x config/hooks.json        # Restart the server before each model, clear its cache per run
+    $0 -m "llama3" -R                          # Rerun tests that hit a flapping endpoint once each run finishes
     TEST_RUNS=20 $0                            # Use environment variable for 20 runs
+    OPENAI_RPM=500 OPENAI_TPM=200000 $0 -p openai # Stay under the OpenAI account's rate limits
 
 AVAILABLE PROVIDERS:
     ollama      - Local Ollama instance (http://localhost:11434/v1)
@@ -131,10 +160,13 @@ AVAILABLE PROVIDERS:
     kamiwaza    - Kamiwaza local instance (https://localhost, set KAMIWAZA_BASE_URL to override)
 
 OUTPUT:
-    Results are saved to: results/batch_test_YYYYMMDD_HHMMSS/
-    - Individual model results: <model>_results.json
+    Results are saved to: results/batch_test_<ULID>/
+    - Individual model results: <model>_agent_test_results_<model>_<run ULID>.json
     - Execution log: test_execution.log  
     - Summary report: summary_report.json
+    - Batch manifest: batch_manifest.json (models, runs, suite hash, tags, endpoint health)
+    - Endpoint health: <model>_endpoint_health_<model>_<ULID>.json (reachable, auth, model loaded, sample latency)
+    - Run manifests (with -H or -x): <model>_run_manifest_<model>_<run ULID>.json (telemetry timeline, hook outputs)
 
 EOF
 }
@@ -182,6 +214,38 @@ while [[ $# -gt 0 ]]; do
             API_KEY="$2"
             shift 2
             ;;
+        -g|--tag)
+            if [[ "$2" != *=* ]]; then
+                print_error "Invalid tag: $2 (expected KEY=VALUE)"
+                exit 1
+            fi
+            TAGS+=("$2")
+            shift 2
+            ;;
+        -H|--telemetry-hook)
+            TELEMETRY_HOOK="$2"
+            shift 2
+            ;;
+        -i|--telemetry-interval)
+            TELEMETRY_INTERVAL="$2"
+            shift 2
+            ;;
+        -x|--hooks)
+            HOOKS_FILE="$2"
+            shift 2
+            ;;
+        -s|--skip-unhealthy)
+            SKIP_UNHEALTHY=true
+            shift
+            ;;
+        -R|--rerun-infra-failures)
+            RERUN_INFRA=true
+            shift
+            ;;
+        -w|--warmup)
+            WARMUP="$2"
+            shift 2
+            ;;
         *)
             print_error "Unknown option: $1"
             show_usage
@@ -214,8 +278,10 @@ parse_provider_config() {
     local base_url=$(echo "$config" | grep -o 'base_url:[^,]*' | cut -d: -f2-)
     local api_key=$(echo "$config" | grep -o 'api_key:[^,]*' | cut -d: -f2-)
     local api_endpoint=$(echo "$config" | grep -o 'api_endpoint:.*' | cut -d: -f2-)
+    local rpm=$(echo "$config" | grep -o 'rpm:[^,]*' | cut -d: -f2-)
+    local tpm=$(echo "$config" | grep -o 'tpm:[^,]*' | cut -d: -f2-)
     
-    echo "$base_url|$api_key|$api_endpoint"
+    echo "$base_url|$api_key|$api_endpoint|$rpm|$tpm"
 }
 
 # Function to discover models from a specific provider
@@ -376,6 +442,30 @@ sanitize_model_name() {
     echo "$1" | sed 's/[^a-zA-Z0-9._-]/_/g'
 }
 
+# Function to generate a ULID (millisecond timestamp + randomness, Crockford base32)
+# so run IDs sort by time and never collide across machines sharing storage
+generate_ulid() {
+    local alphabet="0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+    local ms=$(date +%s%3N 2>/dev/null)
+    if [[ ! "$ms" =~ ^[0-9]+$ ]]; then
+        ms="$(date +%s)000"
+    fi
+
+    local id=""
+    local i
+    for i in $(seq 1 10); do
+        id="${alphabet:$((ms % 32)):1}$id"
+        ms=$((ms / 32))
+    done
+
+    local byte
+    for byte in $(od -An -N16 -tu1 /dev/urandom); do
+        id="$id${alphabet:$((byte % 32)):1}"
+    done
+
+    echo "$id"
+}
+
 # Function to get provider for a model (when using provider-based discovery)
 get_model_provider() {
     local model="$1"
@@ -398,15 +488,139 @@ get_model_provider() {
     echo ""
 }
 
+# Function to print the model-test flags that reach a model's endpoint, using the
+# provider-specific configuration when testing providers
+model_endpoint_flags() {
+    local model="$1"
+    local test_base_url="$BASE_URL"
+    local test_api_key="$API_KEY"
+    local test_rpm="$RATE_LIMIT_RPM"
+    local test_tpm="$RATE_LIMIT_TPM"
+    local model_provider=""
+
+    if [[ -n "$PROVIDERS_OVERRIDE" ]]; then
+        model_provider=$(get_model_provider "$model")
+        if [[ -n "$model_provider" ]]; then
+            local config_result=$(parse_provider_config "$model_provider")
+            if [[ $? -eq 0 ]]; then
+                test_base_url=$(echo "$config_result" | cut -d'|' -f1)
+                test_api_key=$(echo "$config_result" | cut -d'|' -f2)
+                test_rpm=$(echo "$config_result" | cut -d'|' -f4)
+                test_tpm=$(echo "$config_result" | cut -d'|' -f5)
+                log_message "Using provider $model_provider config for model $model: base_url=$test_base_url" >&2
+            fi
+        fi
+    fi
+
+    local rate_limit_flags=""
+    if [[ -n "$test_rpm" ]]; then
+        rate_limit_flags+=" --requests-per-minute=$test_rpm"
+    fi
+    if [[ -n "$test_tpm" ]]; then
+        rate_limit_flags+=" --tokens-per-minute=$test_tpm"
+    fi
+
+    if [[ "$model_provider" == "kamiwaza" ]]; then
+        echo "--provider=kamiwaza --kamiwaza-model=\"$model\" --kamiwaza-url=\"$test_base_url\" --api-key=\"$test_api_key\"$rate_limit_flags"
+    else
+        echo "--model=\"$model\" --base-url=\"$test_base_url\" --api-key=\"$test_api_key\"$rate_limit_flags"
+    fi
+}
+
+# Function to check every model's endpoint before the batch starts: prints a health
+# table, keeps each result in the batch directory, collects them for the batch
+# manifest and lists unhealthy models in UNHEALTHY_MODELS
+check_endpoint_health() {
+    local models="$1"
+    ENDPOINT_HEALTH=""
+    UNHEALTHY_MODELS=""
+
+    if [[ "$DRY_RUN" == "true" ]]; then
+        print_warning "DRY RUN: Would check the endpoint health of every model"
+        return 0
+    fi
+
+    print_status "Checking endpoint health..."
+    if ! make build >> "$LOG_FILE" 2>&1; then
+        print_error "Failed to build application for endpoint health checks"
+        return 1
+    fi
+
+    printf "  %-45s %-10s %-5s %-13s %s\n" "MODEL" "REACHABLE" "AUTH" "MODEL LOADED" "LATENCY"
+    local model
+    for model in $(echo "$models" | tr ',' '\n'); do
+        local sanitized_model=$(sanitize_model_name "$model")
+        local run_id=$(generate_ulid)
+        local health_cmd="./model-test $(model_endpoint_flags "$model") --health-check --run-id=\"$run_id\""
+        log_message "Checking endpoint health: $health_cmd"
+
+        local healthy=true
+        eval "$health_cmd" >> "$LOG_FILE" 2>&1 || healthy=false
+
+        local health_file=""
+        local file
+        for file in results/endpoint_health_*_"${run_id}".json; do
+            if [[ -f "$file" ]]; then
+                health_file="$BATCH_DIR/${sanitized_model}_$(basename "$file")"
+                mv "$file" "$health_file"
+            fi
+        done
+
+        local entry="{\"model\": \"$(json_escape "$model")\", \"reachable\": false, \"auth_ok\": false, \"model_loaded\": false, \"error\": \"health check did not run (see test_execution.log)\"}"
+        local row="$(health_mark false) $(health_mark false) $(health_mark false) -"
+        if [[ -n "$health_file" ]]; then
+            entry=$(cat "$health_file")
+            if command -v jq >/dev/null 2>&1; then
+                entry=$(jq -c . "$health_file")
+                row=$(jq -r '[.reachable, .auth_ok, .model_loaded, (if .sample_latency then "\((.sample_latency / 1000000) | floor)ms" else "-" end)] | map(tostring) | join(" ")' "$health_file")
+            else
+                row="$(health_mark $healthy) $(health_mark $healthy) $(health_mark $healthy) -"
+            fi
+        fi
+
+        local reachable auth loaded latency
+        read -r reachable auth loaded latency <<< "$row"
+        printf "  %-45s %b%-9s %b%-4s %b%-12s %s\n" "$model" "$(health_mark "$reachable")" "" "$(health_mark "$auth")" "" "$(health_mark "$loaded")" "" "$latency"
+
+        if [[ -n "$ENDPOINT_HEALTH" ]]; then
+            ENDPOINT_HEALTH="$ENDPOINT_HEALTH,
+    $entry"
+        else
+            ENDPOINT_HEALTH="    $entry"
+        fi
+        if [[ "$healthy" != "true" ]]; then
+            UNHEALTHY_MODELS="${UNHEALTHY_MODELS:+$UNHEALTHY_MODELS,}$model"
+        fi
+    done
+
+    if [[ -n "$UNHEALTHY_MODELS" ]]; then
+        print_warning "Unhealthy endpoints: $UNHEALTHY_MODELS (details in $LOG_FILE)"
+    else
+        print_success "All endpoints healthy"
+    fi
+    echo ""
+    return 0
+}
+
+# Function to print a check mark for true and a cross otherwise
+health_mark() {
+    if [[ "$1" == "true" ]]; then
+        echo -n "${GREEN}✓${NC}"
+    else
+        echo -n "${RED}✗${NC}"
+    fi
+}
+
 # Function to run test for a single model (single run)
 run_model_test() {
     local model="$1"
     local run_number="$2"
     local sanitized_model=$(sanitize_model_name "$model")
     local model_log_file="$BATCH_DIR/${sanitized_model}_run${run_number}_test.log"
+    local run_id=$(generate_ulid)
     
     print_status "Model $model - Run $run_number/$TEST_RUNS"
-    log_message "Starting test run $run_number for model: $model"
+    log_message "Starting test run $run_number for model: $model (run ID $run_id)"
     
     if [[ "$DRY_RUN" == "true" ]]; then
         print_warning "DRY RUN: Would test model $model (run $run_number/$TEST_RUNS)"
@@ -414,22 +628,6 @@ run_model_test() {
         return 0
     fi
     
-    # Determine provider-specific configuration if using providers
-    local test_base_url="$BASE_URL"
-    local test_api_key="$API_KEY"
-    
-    if [[ -n "$PROVIDERS_OVERRIDE" ]]; then
-        local model_provider=$(get_model_provider "$model")
-        if [[ -n "$model_provider" ]]; then
-            local config_result=$(parse_provider_config "$model_provider")
-            if [[ $? -eq 0 ]]; then
-                test_base_url=$(echo "$config_result" | cut -d'|' -f1)
-                test_api_key=$(echo "$config_result" | cut -d'|' -f2)
-                log_message "Using provider $model_provider config for model $model: base_url=$test_base_url"
-            fi
-        fi
-    fi
-    
     # Build the application first
     print_status "Building application..."
     if ! make build >> "$model_log_file" 2>&1; then
@@ -439,26 +637,25 @@ run_model_test() {
     fi
     
     # Prepare test command
-    local test_cmd=""
-
-    # Check if this is a Kamiwaza model
-    if [[ -n "$PROVIDERS_OVERRIDE" ]] && [[ "$PROVIDERS_OVERRIDE" == *"kamiwaza"* ]]; then
-        local model_provider=$(get_model_provider "$model")
-        if [[ "$model_provider" == "kamiwaza" ]]; then
-            # Use Kamiwaza-specific flags
-            test_cmd="./model-test --provider=kamiwaza --kamiwaza-model=\"$model\" --kamiwaza-url=\"$test_base_url\" --config=\"$CONFIG_FILE\" --api-key=\"$test_api_key\""
-        else
-            # Standard command for non-Kamiwaza models
-            test_cmd="./model-test --model=\"$model\" --config=\"$CONFIG_FILE\" --base-url=\"$test_base_url\" --api-key=\"$test_api_key\""
-        fi
-    else
-        # Standard command
-        test_cmd="./model-test --model=\"$model\" --config=\"$CONFIG_FILE\" --base-url=\"$test_base_url\" --api-key=\"$test_api_key\""
-    fi
+    local test_cmd="./model-test $(model_endpoint_flags "$model") --config=\"$CONFIG_FILE\""
 
     if [[ -n "$TEST_CASE" ]]; then
         test_cmd="$test_cmd --test-case=\"$TEST_CASE\""
     fi
+    test_cmd="$test_cmd --run-id=\"$run_id\""
+    local tag
+    for tag in ${TAGS[@]+"${TAGS[@]}"}; do
+        test_cmd="$test_cmd --tag=\"$tag\""
+    done
+    if [[ -n "$HOOKS_FILE" ]]; then
+        test_cmd="$test_cmd --hooks=\"$HOOKS_FILE\""
+    fi
+    if [[ "$WARMUP" -gt 0 ]]; then
+        test_cmd="$test_cmd --warmup=$WARMUP"
+    fi
+    if [[ -n "$TELEMETRY_HOOK" ]]; then
+        test_cmd="$test_cmd --telemetry-hook=$(printf '%q' "$TELEMETRY_HOOK") --telemetry-interval=\"$TELEMETRY_INTERVAL\""
+    fi
     
     log_message "Executing test command: $test_cmd"
     print_status "Running tests for $model..."
@@ -471,9 +668,8 @@ run_model_test() {
         print_success "Model $model completed in ${duration}s"
         log_message "Test completed successfully for model $model in ${duration}s"
         
-        # Move result files to batch directory with model prefix
-        local timestamp_pattern=$(date '+%Y%m%d')
-        find results/ -name "*${timestamp_pattern}*" -newer "$BATCH_DIR" 2>/dev/null | while read -r file; do
+        # Move this run's result files to batch directory with model prefix
+        find results/ -maxdepth 1 -name "*_${run_id}.json" 2>/dev/null | while read -r file; do
             if [[ -f "$file" ]]; then
                 local basename=$(basename "$file")
                 local new_name="${sanitized_model}_${basename}"
@@ -481,6 +677,10 @@ run_model_test() {
                 log_message "Moved result file: $file -> $BATCH_DIR/$new_name"
             fi
         done
+
+        if [[ "$RERUN_INFRA" == "true" ]]; then
+            rerun_infrastructure_failures "$model" "$run_id" "$model_log_file"
+        fi
         
         return 0
     else
@@ -492,6 +692,157 @@ run_model_test() {
     fi
 }
 
+# Function to rerun the tests of a model's run that failed against the server rather
+# than on the model's answer, merging the new results into its results file
+rerun_infrastructure_failures() {
+    local model="$1"
+    local run_id="$2"
+    local model_log_file="$3"
+    local results_file=$(find "$BATCH_DIR" -maxdepth 1 -name "*_agent_test_results_*_${run_id}.json" 2>/dev/null | head -1)
+
+    if [[ -z "$results_file" ]] || ! command -v jq >/dev/null 2>&1; then
+        return 0
+    fi
+
+    local infra_failures=$(jq -r '.infrastructure_failures // 0' "$results_file")
+    if [[ "$infra_failures" -eq 0 ]]; then
+        return 0
+    fi
+
+    print_warning "Model $model: $infra_failures tests failed against the server, rerunning them..."
+    local rerun_cmd="./model-test $(model_endpoint_flags "$model") --rerun-infra-failures=\"$results_file\""
+    log_message "Executing rerun command: $rerun_cmd"
+
+    if eval "$rerun_cmd" >> "$model_log_file" 2>&1; then
+        print_success "Model $model: infrastructure failures rerun and merged into $(basename "$results_file")"
+    else
+        print_warning "Model $model: some tests still fail against the server (see $model_log_file)"
+        log_message "Infrastructure failure rerun incomplete for model $model"
+    fi
+}
+
+# Function to run the pre_model or post_model hooks for a model, keeping their run
+# manifest in the batch directory
+run_model_hooks() {
+    local model="$1"
+    local stage="$2"
+    local sanitized_model=$(sanitize_model_name "$model")
+    local model_log_file="$BATCH_DIR/${sanitized_model}_${stage}_hooks.log"
+    local run_id=$(generate_ulid)
+
+    if [[ -z "$HOOKS_FILE" ]]; then
+        return 0
+    fi
+
+    if [[ "$DRY_RUN" == "true" ]]; then
+        print_warning "DRY RUN: Would run $stage hooks for model $model"
+        return 0
+    fi
+
+    if ! make build >> "$model_log_file" 2>&1; then
+        print_error "Failed to build application for $stage hooks of model $model"
+        return 1
+    fi
+
+    print_status "Running $stage hooks for $model..."
+    log_message "Running $stage hooks for model $model (run ID $run_id)"
+
+    local status=0
+    ./model-test --model="$model" --hooks="$HOOKS_FILE" --hooks-stage="$stage" --run-id="$run_id" >> "$model_log_file" 2>&1 || status=$?
+
+    find results/ -maxdepth 1 -name "*_${run_id}.json" 2>/dev/null | while read -r file; do
+        mv "$file" "$BATCH_DIR/${sanitized_model}_$(basename "$file")"
+    done
+
+    if [[ $status -ne 0 ]]; then
+        print_error "$stage hooks failed for model $model (see $model_log_file)"
+        log_message "$stage hooks failed for model $model"
+        return 1
+    fi
+    return 0
+}
+
+# Function to compute the SHA-256 of the test suite file
+compute_suite_hash() {
+    if command -v sha256sum >/dev/null 2>&1; then
+        sha256sum "$CONFIG_FILE" | cut -d' ' -f1
+    elif command -v shasum >/dev/null 2>&1; then
+        shasum -a 256 "$CONFIG_FILE" | cut -d' ' -f1
+    else
+        echo ""
+    fi
+}
+
+# Function to escape a string for use inside a JSON string literal
+json_escape() {
+    local value="${1//\\/\\\\}"
+    echo -n "${value//\"/\\\"}"
+}
+
+# Function to write the batch manifest used by analyze-batch to verify completeness
+write_batch_manifest() {
+    local manifest_file="$BATCH_DIR/batch_manifest.json"
+    local suite_hash=$(compute_suite_hash)
+    local test_cases_per_run=0
+
+    if [[ -n "$TEST_CASE" ]]; then
+        # -t takes names and glob patterns, so let model-test resolve the selection
+        if [[ -x ./model-test ]]; then
+            test_cases_per_run=$(./model-test --config="$CONFIG_FILE" --test-case="$TEST_CASE" --list-test-cases 2>/dev/null | wc -l | tr -d ' ')
+        else
+            test_cases_per_run=1
+        fi
+    elif command -v jq >/dev/null 2>&1; then
+        test_cases_per_run=$(jq 'if type=="array" then length else (.test_cases | length) end' "$CONFIG_FILE" 2>/dev/null || echo 0)
+    fi
+
+    local model_entries=""
+    for model in $(echo "$TESTED_MODELS" | tr ',' '\n'); do
+        local entry="    {\"name\": \"$model\", \"file_prefix\": \"$(sanitize_model_name "$model")\"}"
+        if [[ -n "$model_entries" ]]; then
+            model_entries="$model_entries,
+$entry"
+        else
+            model_entries="$entry"
+        fi
+    done
+
+    local tag_entries=""
+    local tag
+    for tag in ${TAGS[@]+"${TAGS[@]}"}; do
+        local entry="\"${tag%%=*}\": \"${tag#*=}\""
+        if [[ -n "$tag_entries" ]]; then
+            tag_entries="$tag_entries, $entry"
+        else
+            tag_entries="$entry"
+        fi
+    done
+
+    cat > "$manifest_file" << EOF
+{
+  "batch_id": "$BATCH_ID",
+  "created_at": "$(date -u +%Y-%m-%dT%H:%M:%SZ)",
+  "config_file": "$CONFIG_FILE",
+  "suite_hash": "$suite_hash",
+  "tags": {$tag_entries},
+  "test_case_filter": "$TEST_CASE",
+  "runs_per_model": $TEST_RUNS,
+  "test_cases_per_run": $test_cases_per_run,
+  "telemetry_hook": "$(json_escape "$TELEMETRY_HOOK")",
+  "telemetry_interval": "$TELEMETRY_INTERVAL",
+  "hooks_file": "$(json_escape "$HOOKS_FILE")",
+  "endpoint_health": [
+$ENDPOINT_HEALTH
+  ],
+  "models": [
+$model_entries
+  ]
+}
+EOF
+
+    log_message "Batch manifest written to $manifest_file"
+}
+
 # Function to generate summary report
 generate_summary() {
     local summary_file="$BATCH_DIR/summary_report.json"
@@ -585,9 +936,9 @@ print_final_summary() {
 
 # Main execution
 main() {
-    # Create batch directory with timestamp
-    local timestamp=$(date '+%Y%m%d_%H%M%S')
-    BATCH_DIR="results/batch_test_$timestamp"
+    # Create batch directory named by a ULID so concurrent batches never collide
+    BATCH_ID=$(generate_ulid)
+    BATCH_DIR="results/batch_test_$BATCH_ID"
     LOG_FILE="$BATCH_DIR/test_execution.log"
     
     # Create directories
@@ -622,6 +973,14 @@ main() {
     
     # Store tested models for summary
     TESTED_MODELS="$models"
+
+    # Check every endpoint up front so a multi-hour batch does not run against dead ones
+    if ! check_endpoint_health "$models"; then
+        exit 1
+    fi
+
+    # Record what this batch is expected to contain
+    write_batch_manifest
     
     # Test each model multiple times
     local total_models=$(echo "$models" | tr ',' '\n' | wc -l)
@@ -639,6 +998,21 @@ main() {
         
         local model_successful_runs=0
         
+        if [[ "$SKIP_UNHEALTHY" == "true" ]] && [[ ",$UNHEALTHY_MODELS," == *",$model,"* ]]; then
+            print_warning "Skipping $model: endpoint failed the health check"
+            log_message "Skipped model $model: endpoint failed the health check"
+            current_run=$((current_run + TEST_RUNS))
+            echo ""
+            continue
+        fi
+
+        # A failed pre_model hook (e.g. the server did not restart) skips the model
+        if ! run_model_hooks "$model" "pre_model"; then
+            current_run=$((current_run + TEST_RUNS))
+            echo ""
+            continue
+        fi
+
         # Run the test suite multiple times for this model
         for run in $(seq 1 $TEST_RUNS); do
             current_run=$((current_run + 1))
@@ -647,12 +1021,9 @@ main() {
             if run_model_test "$model" "$run"; then
                 model_successful_runs=$((model_successful_runs + 1))
             fi
-            
-            # Add small delay between runs to ensure unique timestamps
-            if [[ "$DRY_RUN" != "true" && $run -lt $TEST_RUNS ]]; then
-                sleep 2
-            fi
         done
+
+        run_model_hooks "$model" "post_model" || true
         
         # Report model completion
         print_success "Model $model completed: $model_successful_runs/$TEST_RUNS successful runs"
@@ -0,0 +1,139 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"model-test/models"
+)
+
+// ValidateArgumentSchema checks a tool call's arguments against the tool's declared JSON
+// schema: value types, undeclared properties and missing required properties, in nested
+// objects and arrays too. Enum values are left to ValidateEnumArguments. It reports
+// whether the tool is known (unknown tools are not checked) and each violation.
+func (st *ShoppingTools) ValidateArgumentSchema(toolName string, arguments string) (bool, []models.SchemaViolation) {
+	var schema map[string]interface{}
+	for _, tool := range st.GetToolDefinitions() {
+		if tool.Function.Name == toolName {
+			schema = tool.Function.Parameters
+			break
+		}
+	}
+	if schema == nil {
+		return false, nil
+	}
+
+	// An empty argument string is how some servers send a call without arguments
+	if strings.TrimSpace(arguments) == "" {
+		arguments = "{}"
+	}
+	var args interface{}
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+		return true, []models.SchemaViolation{{Kind: models.SchemaInvalidJSON, Message: "arguments are not valid JSON"}}
+	}
+	if _, ok := args.(map[string]interface{}); !ok {
+		return true, []models.SchemaViolation{{Kind: models.SchemaInvalidJSON, Message: fmt.Sprintf("arguments are %s, not an object", jsonType(args))}}
+	}
+
+	return true, validateSchemaValue(schema, args, "")
+}
+
+// validateSchemaValue checks one value against its schema, recursing into object
+// properties and array items
+func validateSchemaValue(schema map[string]interface{}, value interface{}, path string) []models.SchemaViolation {
+	if expected, ok := schema["type"].(string); ok && !hasJSONType(value, expected) {
+		return []models.SchemaViolation{{
+			Kind:    models.SchemaWrongType,
+			Path:    path,
+			Message: fmt.Sprintf("expected %s, got %s", expected, jsonType(value)),
+		}}
+	}
+
+	var violations []models.SchemaViolation
+	switch v := value.(type) {
+	case map[string]interface{}:
+		properties, _ := schema["properties"].(map[string]interface{})
+		for _, name := range sortedNames(v) {
+			property, declared := properties[name].(map[string]interface{})
+			if !declared {
+				violations = append(violations, models.SchemaViolation{
+					Kind:    models.SchemaUnknownProperty,
+					Path:    joinSchemaPath(path, name),
+					Message: "property is not declared by the schema",
+				})
+				continue
+			}
+			violations = append(violations, validateSchemaValue(property, v[name], joinSchemaPath(path, name))...)
+		}
+		required, _ := schema["required"].([]string)
+		for _, name := range required {
+			if _, present := v[name]; !present {
+				violations = append(violations, models.SchemaViolation{
+					Kind:    models.SchemaMissingRequired,
+					Path:    joinSchemaPath(path, name),
+					Message: "required property is missing",
+				})
+			}
+		}
+	case []interface{}:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				violations = append(violations, validateSchemaValue(items, item, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+	}
+	return violations
+}
+
+// hasJSONType reports whether a decoded JSON value has the schema type
+func hasJSONType(value interface{}, schemaType string) bool {
+	switch schemaType {
+	case "integer":
+		number, ok := value.(float64)
+		return ok && number == math.Trunc(number)
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	default:
+		return jsonType(value) == schemaType
+	}
+}
+
+// jsonType names the JSON type of a decoded value
+func jsonType(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+// joinSchemaPath appends a property name to a path within the arguments
+func joinSchemaPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// sortedNames returns an object's property names in order, so violations are stable
+func sortedNames(object map[string]interface{}) []string {
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
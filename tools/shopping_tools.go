@@ -1,21 +1,23 @@
 package tools
 
 import (
+	"encoding/json"
+	"fmt"
+
+	"model-test/models"
+
 	"github.com/openai/openai-go"
 	"github.com/openai/openai-go/packages/param"
 	"github.com/openai/openai-go/shared"
 )
 
-// ShoppingTools provides the shopping cart tool definitions and mock implementations
-type ShoppingTools struct {
-	cart map[string]int // product_name -> quantity
-}
+// ShoppingTools provides the shopping cart tool definitions. It holds no state: tool
+// calls run against the product and cart services of each test's sandbox.
+type ShoppingTools struct{}
 
 // NewShoppingTools creates a new instance of shopping tools
 func NewShoppingTools() *ShoppingTools {
-	return &ShoppingTools{
-		cart: make(map[string]int),
-	}
+	return &ShoppingTools{}
 }
 
 // GetToolDefinitions returns the tool definitions for OpenAI function calling
@@ -37,6 +39,11 @@ func (st *ShoppingTools) GetToolDefinitions() []openai.ChatCompletionToolParam {
 							"type":        "string",
 							"description": "Product category (electronics, clothing, books, home, sports, beauty, toys, food)",
 						},
+						"sort_by": map[string]interface{}{
+							"type":        "string",
+							"description": "How to order the results (default: relevance)",
+							"enum":        []string{"price", "rating", "relevance"},
+						},
 						"limit": map[string]interface{}{
 							"type":        "integer",
 							"description": "Maximum number of results to return (default: 10)",
@@ -105,5 +112,191 @@ func (st *ShoppingTools) GetToolDefinitions() []openai.ChatCompletionToolParam {
 				},
 			},
 		},
+		{
+			Type: "function",
+			Function: shared.FunctionDefinitionParam{
+				Name:        "create_order",
+				Description: param.NewOpt("Place an order directly for a list of products, bypassing the shopping cart"),
+				Parameters: shared.FunctionParameters{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"items": map[string]interface{}{
+							"type":        "array",
+							"description": "Products to order",
+							"minItems":    1,
+							"items": map[string]interface{}{
+								"type": "object",
+								"properties": map[string]interface{}{
+									"product_name": map[string]interface{}{
+										"type":        "string",
+										"description": "The name of the product to order",
+									},
+									"quantity": map[string]interface{}{
+										"type":        "integer",
+										"description": "Quantity to order",
+										"minimum":     1,
+									},
+								},
+								"required": []string{"product_name", "quantity"},
+							},
+						},
+						"shipping_address": map[string]interface{}{
+							"type":        "object",
+							"description": "Delivery address for the order",
+							"properties": map[string]interface{}{
+								"street": map[string]interface{}{
+									"type":        "string",
+									"description": "Street address",
+								},
+								"city": map[string]interface{}{
+									"type":        "string",
+									"description": "City",
+								},
+								"postal_code": map[string]interface{}{
+									"type":        "string",
+									"description": "Postal or ZIP code",
+								},
+								"country": map[string]interface{}{
+									"type":        "string",
+									"description": "Country",
+								},
+							},
+							"required": []string{"street", "city"},
+						},
+					},
+					"required": []string{"items"},
+				},
+			},
+		},
+		{
+			Type: "function",
+			Function: shared.FunctionDefinitionParam{
+				Name:        "schedule_delivery",
+				Description: param.NewOpt("Schedule the delivery date and time window for the current order"),
+				Parameters: shared.FunctionParameters{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"delivery_date": map[string]interface{}{
+							"type":        "string",
+							"format":      "date",
+							"description": "Delivery date in YYYY-MM-DD format",
+						},
+						"time_window": map[string]interface{}{
+							"type":        "string",
+							"description": "Preferred delivery time window",
+							"enum":        []string{"morning", "afternoon", "evening"},
+						},
+					},
+					"required": []string{"delivery_date"},
+				},
+			},
+		},
+	}
+}
+
+// GetToolDefinitionsForVersion returns the tool definitions reworded by a tool spec
+// version, along with a map from each renamed tool name back to its canonical name.
+// A nil version returns the canonical definitions.
+func (st *ShoppingTools) GetToolDefinitionsForVersion(version *models.ToolSpecVersion) ([]openai.ChatCompletionToolParam, map[string]string, error) {
+	definitions := st.GetToolDefinitions()
+	aliases := make(map[string]string)
+	if version == nil {
+		return definitions, aliases, nil
+	}
+
+	canonical := make(map[string]int)
+	for i, tool := range definitions {
+		canonical[tool.Function.Name] = i
+	}
+
+	for toolName, override := range version.Tools {
+		index, ok := canonical[toolName]
+		if !ok {
+			return nil, nil, fmt.Errorf("version '%s' overrides unknown tool '%s'", version.Name, toolName)
+		}
+		function := &definitions[index].Function
+
+		if override.Description != "" {
+			function.Description = param.NewOpt(override.Description)
+		}
+
+		properties, _ := function.Parameters["properties"].(map[string]interface{})
+		for paramName, description := range override.Parameters {
+			property, ok := properties[paramName].(map[string]interface{})
+			if !ok {
+				return nil, nil, fmt.Errorf("version '%s' overrides unknown parameter '%s' of tool '%s'", version.Name, paramName, toolName)
+			}
+			property["description"] = description
+		}
+
+		if override.Name != "" && override.Name != toolName {
+			function.Name = override.Name
+			aliases[override.Name] = toolName
+		}
+	}
+
+	// Renamed tools must not collide with each other or with tools that kept their name
+	seen := make(map[string]bool)
+	for _, tool := range definitions {
+		if seen[tool.Function.Name] {
+			return nil, nil, fmt.Errorf("version '%s' defines tool '%s' more than once", version.Name, tool.Function.Name)
+		}
+		seen[tool.Function.Name] = true
+	}
+
+	return definitions, aliases, nil
+}
+
+// ValidateEnumArguments checks the enum-constrained arguments of a tool call against
+// the tool schema. It returns the number of enum arguments present in the call and a
+// description of each value that falls outside its allowed set.
+func (st *ShoppingTools) ValidateEnumArguments(toolName string, arguments string) (int, []string) {
+	properties := st.getToolProperties(toolName)
+	if properties == nil {
+		return 0, nil
+	}
+
+	var args map[string]interface{}
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+		return 0, nil
+	}
+
+	checked := 0
+	var violations []string
+	for argName, value := range args {
+		property, ok := properties[argName].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		allowed, ok := property["enum"].([]string)
+		if !ok {
+			continue
+		}
+
+		checked++
+		valid := false
+		for _, option := range allowed {
+			if fmt.Sprintf("%v", value) == option {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			violations = append(violations, fmt.Sprintf("%s=%v", argName, value))
+		}
+	}
+
+	return checked, violations
+}
+
+// getToolProperties returns the top-level parameter properties for the named tool
+func (st *ShoppingTools) getToolProperties(toolName string) map[string]interface{} {
+	for _, tool := range st.GetToolDefinitions() {
+		if tool.Function.Name != toolName {
+			continue
+		}
+		properties, _ := tool.Function.Parameters["properties"].(map[string]interface{})
+		return properties
 	}
+	return nil
 }